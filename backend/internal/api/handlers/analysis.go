@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mifi/lossless-cut/backend/internal/services"
+	"go.uber.org/zap"
+)
+
+type AnalysisHandler struct {
+	services *services.Services
+	logger   *zap.Logger
+}
+
+func NewAnalysisHandler(services *services.Services, logger *zap.Logger) *AnalysisHandler {
+	return &AnalysisHandler{
+		services: services,
+		logger:   logger,
+	}
+}
+
+// DetectIntro locates a project's marked intro/outro segment in other projects
+func (h *AnalysisHandler) DetectIntro(c *gin.Context) {
+	projectID := c.Param("id")
+
+	var req services.IntroDetectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.services.Analysis.DetectIntro(c.Request.Context(), projectID, req)
+	if err != nil {
+		serviceError(c, h.logger, "failed to detect intro", err, zap.String("projectId", projectID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// Detect runs scene/black/silence detection on a video, optionally adding the
+// detected ranges to a project as segments
+func (h *AnalysisHandler) Detect(c *gin.Context) {
+	videoID := c.Param("id")
+
+	var req services.VideoDetectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.services.Analysis.DetectSegments(c.Request.Context(), videoID, req)
+	if err != nil {
+		serviceError(c, h.logger, "failed to run detection", err, zap.String("videoId", videoID))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListPresets returns the available detection sensitivity presets
+func (h *AnalysisHandler) ListPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"presets": services.ListDetectionPresets(),
+		"default": services.DefaultDetectionPreset,
+	})
+}
+
+// Keyframes returns the keyframe timestamps of a video. With ?near=<seconds> it
+// returns the keyframes around that time instead, to snap a cut point to.
+func (h *AnalysisHandler) Keyframes(c *gin.Context) {
+	videoID := c.Param("id")
+
+	if near := c.Query("near"); near != "" {
+		t, err := strconv.ParseFloat(near, 64)
+		if err != nil || t < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "near must be a non-negative number of seconds"})
+			return
+		}
+
+		snap, err := h.services.Analysis.SnapToKeyframe(c.Request.Context(), videoID, t)
+		if err != nil {
+			h.logger.Error("Failed to snap to keyframe", zap.String("videoId", videoID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, snap)
+		return
+	}
+
+	keyframes, err := h.services.Analysis.GetKeyframes(c.Request.Context(), videoID)
+	if err != nil {
+		h.logger.Error("Failed to get keyframes", zap.String("videoId", videoID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keyframes": keyframes})
+}
+
+// Loudness measures the EBU R128 loudness of a video, or of ?start=&end= seconds of it
+func (h *AnalysisHandler) Loudness(c *gin.Context) {
+	videoID := c.Param("id")
+
+	times := []float64{0, 0}
+	for i, name := range []string{"start", "end"} {
+		if raw := c.Query(name); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil || v < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be a non-negative number of seconds"})
+				return
+			}
+			times[i] = v
+		}
+	}
+	start, end := times[0], times[1]
+	if end > 0 && end <= start {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end must be after start"})
+		return
+	}
+
+	analysis, err := h.services.Analysis.AnalyzeLoudness(c.Request.Context(), videoID, start, end)
+	if err != nil {
+		h.logger.Error("Failed to analyze loudness", zap.String("videoId", videoID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// SegmentLoudness measures the EBU R128 loudness of a project segment
+func (h *AnalysisHandler) SegmentLoudness(c *gin.Context) {
+	projectID := c.Param("id")
+	segmentID := c.Param("segmentId")
+
+	analysis, err := h.services.Analysis.AnalyzeSegmentLoudness(c.Request.Context(), projectID, segmentID)
+	if err != nil {
+		h.logger.Error("Failed to analyze segment loudness",
+			zap.String("projectId", projectID),
+			zap.String("segmentId", segmentID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
+
+// SuggestChapters suggests a chapter list for a project from detected scenes
+func (h *AnalysisHandler) SuggestChapters(c *gin.Context) {
+	projectID := c.Param("id")
+
+	var req services.ChapterSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chapters, err := h.services.Analysis.SuggestChapters(c.Request.Context(), projectID, req)
+	if err != nil {
+		serviceError(c, h.logger, "failed to suggest chapters", err, zap.String("projectId", projectID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chapters": chapters})
+}
+
+// RemoveSilence splits a project's video into speech segments around detected silences
+func (h *AnalysisHandler) RemoveSilence(c *gin.Context) {
+	projectID := c.Param("id")
+
+	var req services.SilenceRemovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	segments, err := h.services.Analysis.RemoveSilence(c.Request.Context(), projectID, req)
+	if err != nil {
+		serviceError(c, h.logger, "failed to remove silence", err, zap.String("projectId", projectID))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"segments": segments})
+}
+
+// Split creates a project that splits a video into parts at black frames, a fixed
+// interval or an estimated file size
+func (h *AnalysisHandler) Split(c *gin.Context) {
+	videoID := c.Param("id")
+
+	var req services.SplitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.services.Analysis.Split(c.Request.Context(), videoID, req)
+	if err != nil {
+		serviceError(c, h.logger, "failed to split video", err, zap.String("videoId", videoID))
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/services"
+	"go.uber.org/zap"
+)
+
+type ChecksumHandler struct {
+	services *services.Services
+	logger   *zap.Logger
+}
+
+func NewChecksumHandler(services *services.Services, logger *zap.Logger) *ChecksumHandler {
+	return &ChecksumHandler{
+		services: services,
+		logger:   logger,
+	}
+}
+
+// checksumRequest is the optional body of checksum and verify requests
+type checksumRequest struct {
+	SHA256 string `json:"sha256"` // Expected checksum
+}
+
+// bindChecksumRequest reads the optional request body
+func bindChecksumRequest(c *gin.Context) (checksumRequest, bool) {
+	var req checksumRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return req, false
+	}
+	return req, true
+}
+
+// respondOperation answers with a started checksum operation, poll it on
+// /api/operations/:id for progress and the result
+func (h *ChecksumHandler) respondOperation(c *gin.Context, operation *models.Operation, err error) {
+	if err != nil {
+		h.logger.Warn("Failed to start checksum", zap.String("path", c.Request.URL.Path), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// ChecksumVideo computes and stores the SHA-256 of a video.
+// Body (optional): {"sha256": "..."} to also compare with
+func (h *ChecksumHandler) ChecksumVideo(c *gin.Context) {
+	req, ok := bindChecksumRequest(c)
+	if !ok {
+		return
+	}
+	operation, err := h.services.Operation.ChecksumVideo(c.Param("id"), req.SHA256)
+	h.respondOperation(c, operation, err)
+}
+
+// VerifyVideo checks a video against the given or stored SHA-256.
+// Body (optional): {"sha256": "..."}
+func (h *ChecksumHandler) VerifyVideo(c *gin.Context) {
+	req, ok := bindChecksumRequest(c)
+	if !ok {
+		return
+	}
+	operation, err := h.services.Operation.VerifyVideo(c.Param("id"), req.SHA256)
+	h.respondOperation(c, operation, err)
+}
+
+// ChecksumOutput computes the SHA-256 of an exported file and saves it next to it.
+// Body (optional): {"sha256": "..."} to also compare with
+func (h *ChecksumHandler) ChecksumOutput(c *gin.Context) {
+	req, ok := bindChecksumRequest(c)
+	if !ok {
+		return
+	}
+	operation, err := h.services.Operation.ChecksumOutput(c.Param("filename"), req.SHA256)
+	h.respondOperation(c, operation, err)
+}
+
+// VerifyOutput checks an exported file against the given or saved SHA-256.
+// Body (optional): {"sha256": "..."}
+func (h *ChecksumHandler) VerifyOutput(c *gin.Context) {
+	req, ok := bindChecksumRequest(c)
+	if !ok {
+		return
+	}
+	operation, err := h.services.Operation.VerifyOutput(c.Param("filename"), req.SHA256)
+	h.respondOperation(c, operation, err)
+}
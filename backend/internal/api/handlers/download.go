@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mifi/lossless-cut/backend/internal/api/middleware"
 	"github.com/mifi/lossless-cut/backend/internal/services"
 	"go.uber.org/zap"
 )
@@ -28,6 +29,13 @@ func (h *DownloadHandler) Start(c *gin.Context) {
 		return
 	}
 
+	// Browser cookies are those of the server's user, so only admins pick a browser;
+	// download.cookies_from_browser applies to everyone
+	if req.CookiesFromBrowser != "" && !c.GetBool(middleware.AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cookies_from_browser requires admin access"})
+		return
+	}
+
 	download, err := h.services.Download.StartDownload(c.Request.Context(), req)
 	if err != nil {
 		h.logger.Error("Failed to start download", zap.Error(err))
@@ -76,14 +84,30 @@ func (h *DownloadHandler) Cancel(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "download cancelled"})
 }
 
-// ClearAll deletes all download history
+// Resume queues a failed, interrupted or cancelled download again, continuing its
+// partial file
+func (h *DownloadHandler) Resume(c *gin.Context) {
+	id := c.Param("id")
+
+	download, err := h.services.Download.ResumeDownload(id)
+	if err != nil {
+		h.logger.Error("Failed to resume download", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, download)
+}
+
+// ClearAll moves all download history to the trash
 func (h *DownloadHandler) ClearAll(c *gin.Context) {
-	if err := h.services.Storage.ClearAllDownloads(); err != nil {
+	entry, err := h.services.Storage.ClearAllDownloads()
+	if err != nil {
 		h.logger.Error("Failed to clear downloads", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	h.logger.Info("Cleared all download history")
-	c.JSON(http.StatusOK, gin.H{"message": "all downloads cleared"})
+	c.JSON(http.StatusOK, gin.H{"message": "all downloads cleared", "trash": entry})
 }
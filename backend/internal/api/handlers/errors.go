@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mifi/lossless-cut/backend/internal/services"
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+// serviceError answers a failed service call: errors of the request with 400, 403 or
+// 409 and their message, missing records with 404, and anything else with 500 and the
+// generic message, e.g. "failed to retry operation". The error is logged.
+func serviceError(c *gin.Context, logger *zap.Logger, message string, err error, fields ...zap.Field) {
+	fields = append(fields, zap.Error(err))
+	logMessage := strings.ToUpper(message[:1]) + message[1:]
+	switch {
+	case errors.Is(err, services.ErrInvalid):
+		logger.Warn(logMessage, fields...)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrForbidden):
+		logger.Warn(logMessage, fields...)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrConflict):
+		logger.Warn(logMessage, fields...)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, storage.ErrNotFound):
+		logger.Warn(logMessage, fields...)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logger.Error(logMessage, fields...)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -26,10 +26,35 @@ func (h *OperationHandler) GetStatus(c *gin.Context) {
 
 	operation, err := h.services.Operation.GetStatus(operationID)
 	if err != nil {
-		h.logger.Error("Failed to get operation status", zap.String("id", operationID), zap.Error(err))
-		c.JSON(http.StatusNotFound, gin.H{"error": "operation not found"})
+		serviceError(c, h.logger, "failed to get operation status", err, zap.String("id", operationID))
 		return
 	}
 
 	c.JSON(http.StatusOK, operation)
 }
+
+// Retry re-runs a failed export, reusing the segments it already completed
+func (h *OperationHandler) Retry(c *gin.Context) {
+	operationID := c.Param("id")
+
+	operation, err := h.services.Operation.Retry(operationID)
+	if err != nil {
+		serviceError(c, h.logger, "failed to retry operation", err, zap.String("id", operationID))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// Cancel stops a running export and removes its partial outputs
+func (h *OperationHandler) Cancel(c *gin.Context) {
+	operationID := c.Param("id")
+
+	operation, err := h.services.Operation.Cancel(operationID)
+	if err != nil {
+		serviceError(c, h.logger, "failed to cancel operation", err, zap.String("id", operationID))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, operation)
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mifi/lossless-cut/backend/internal/api/middleware"
 	"github.com/mifi/lossless-cut/backend/internal/models"
 	"github.com/mifi/lossless-cut/backend/internal/services"
 	"go.uber.org/zap"
@@ -156,6 +159,12 @@ func (h *ProjectHandler) Export(c *gin.Context) {
 		return
 	}
 
+	// Custom FFmpeg arguments are an admin-only escape hatch
+	if len(req.ExtraArgs) > 0 && !c.GetBool(middleware.AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "extra_args requires admin access"})
+		return
+	}
+
 	project, err := h.services.Project.Get(projectID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
@@ -164,10 +173,71 @@ func (h *ProjectHandler) Export(c *gin.Context) {
 
 	operation, err := h.services.Operation.Export(project, req)
 	if err != nil {
-		h.logger.Error("Failed to export project", zap.String("projectId", projectID), zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export project"})
+		serviceError(c, h.logger, "failed to export project", err, zap.String("projectId", projectID))
 		return
 	}
 
+	// A dry run is already complete and carries its planned commands
+	if req.DryRun {
+		c.JSON(http.StatusOK, operation)
+		return
+	}
 	c.JSON(http.StatusAccepted, operation)
 }
+
+// Preview streams a low-resolution concatenation of the segments an export would
+// include, so the cut list can be checked before running the export.
+// Query: segments (comma-separated segment IDs, default all), height (default 360)
+func (h *ProjectHandler) Preview(c *gin.Context) {
+	projectID := c.Param("id")
+
+	var segmentIDs []string
+	if ids := c.Query("segments"); ids != "" {
+		segmentIDs = strings.Split(ids, ",")
+	}
+
+	height := 0
+	if value := c.Query("height"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid height"})
+			return
+		}
+		height = parsed
+	}
+
+	project, err := h.services.Project.Get(projectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+		return
+	}
+
+	opts, err := h.services.Operation.PlanPreview(project, segmentIDs, height)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "video/mp4")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	// The transcode stops when the client disconnects and the request context ends
+	if err := h.services.Operation.StreamPreview(c.Request.Context(), opts, c.Writer); err != nil {
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		h.logger.Error("Failed to stream preview", zap.String("projectId", projectID), zap.Error(err))
+		if !c.Writer.Written() {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render preview"})
+		}
+	}
+}
+
+// ListExportPresets returns the available export encoding presets
+func (h *ProjectHandler) ListExportPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"presets":          services.ListExportPresets(),
+		"advanced_options": services.AdvancedOptionNames(),
+	})
+}
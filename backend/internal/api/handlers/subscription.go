@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mifi/lossless-cut/backend/internal/services"
+	"go.uber.org/zap"
+)
+
+type SubscriptionHandler struct {
+	services *services.Services
+	logger   *zap.Logger
+}
+
+func NewSubscriptionHandler(services *services.Services, logger *zap.Logger) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		services: services,
+		logger:   logger,
+	}
+}
+
+// Create subscribes to an RSS, Atom, podcast or YouTube channel feed
+func (h *SubscriptionHandler) Create(c *gin.Context) {
+	var req services.SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription, downloads, err := h.services.Subscription.Create(req)
+	if err != nil {
+		h.logger.Error("Failed to create subscription", zap.String("url", req.URL), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"subscription": subscription, "downloads": downloads})
+}
+
+// List returns all subscriptions
+func (h *SubscriptionHandler) List(c *gin.Context) {
+	subscriptions, err := h.services.Subscription.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// Get retrieves a subscription
+func (h *SubscriptionHandler) Get(c *gin.Context) {
+	subscription, err := h.services.Subscription.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// Update changes the filters or enabled state of a subscription
+func (h *SubscriptionHandler) Update(c *gin.Context) {
+	var req services.SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription, err := h.services.Subscription.Update(c.Param("id"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// Delete unsubscribes from a feed
+func (h *SubscriptionHandler) Delete(c *gin.Context) {
+	if err := h.services.Subscription.Delete(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "subscription deleted"})
+}
+
+// Check polls a subscription now instead of waiting for the next scheduled check
+func (h *SubscriptionHandler) Check(c *gin.Context) {
+	subscription, downloads, err := h.services.Subscription.Check(c.Param("id"))
+	if err != nil {
+		if subscription == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscription": subscription, "downloads": downloads})
+}
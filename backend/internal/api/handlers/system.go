@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mifi/lossless-cut/backend/internal/api/middleware"
 	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/models"
 	"github.com/mifi/lossless-cut/backend/internal/services"
 	"go.uber.org/zap"
 )
@@ -40,12 +44,24 @@ func NewSystemHandler(cfg *config.Config, services *services.Services, logger *z
 	return h
 }
 
-// sessionCleanupLoop checks for expired sessions and cleans up data
+// purgeInterval is how often expired trash entries and failed exports are deleted
+// while running
+const purgeInterval = 10 * time.Minute
+
+// sessionCleanupLoop checks for expired sessions and cleans up data. Cleaned up data
+// goes to the trash, which is purged here too once its entries expire, along with
+// failed exports past storage.failed_retention_days.
 func (h *SystemHandler) sessionCleanupLoop() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
+	lastPurge := time.Now()
 	for range ticker.C {
+		if time.Since(lastPurge) >= purgeInterval {
+			lastPurge = time.Now()
+			h.services.PurgeExpired()
+		}
+
 		h.sessLock.Lock()
 		now := time.Now()
 		for id, session := range h.sessions {
@@ -60,7 +76,7 @@ func (h *SystemHandler) sessionCleanupLoop() {
 				// Only cleanup if no other active sessions
 				if len(h.sessions) == 0 {
 					go func() {
-						if err := h.services.Storage.ClearEverything(); err != nil {
+						if _, err := h.services.Storage.ClearEverything(); err != nil {
 							h.logger.Error("Auto-cleanup failed", zap.Error(err))
 						} else {
 							h.logger.Info("Auto-cleanup completed successfully")
@@ -79,14 +95,76 @@ func (h *SystemHandler) Info(c *gin.Context) {
 		"version": "1.0.0",
 		"ffmpeg":  h.config.FFmpeg.Path,
 		"ytdlp":   h.config.YtDlp.Path,
+		// Running and queued ffmpeg/ffprobe processes
+		"ffmpeg_processes": h.services.FFmpeg.Stats(),
 	})
 }
 
-// ClearAll deletes all videos, downloads, projects, and outputs
+// FFmpeg reports the versions, encoders, decoders, hardware acceleration methods and
+// filters of the installed FFmpeg build. Admins can re-detect them with ?refresh=true.
+func (h *SystemHandler) FFmpeg(c *gin.Context) {
+	refresh := c.Query("refresh") == "true" && c.GetBool(middleware.AdminKey)
+
+	caps, err := h.services.Operation.FFmpegCapabilities(c.Request.Context(), refresh)
+	if err != nil {
+		h.logger.Error("Failed to discover FFmpeg capabilities", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query ffmpeg"})
+		return
+	}
+
+	c.JSON(http.StatusOK, caps)
+}
+
+// YtDlp reports the installed yt-dlp version and where it runs from
+func (h *SystemHandler) YtDlp(c *gin.Context) {
+	info, err := h.services.Download.YtdlpVersion(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to query yt-dlp version", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query yt-dlp"})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// YtDlpUpdate updates yt-dlp to the latest release. Outdated extractors are the most
+// common reason downloads fail.
+func (h *SystemHandler) YtDlpUpdate(c *gin.Context) {
+	if !c.GetBool(middleware.AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "updating yt-dlp requires admin access"})
+		return
+	}
+
+	update, err := h.services.Download.UpdateYtdlp(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to update yt-dlp", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, update)
+}
+
+// Config lists every config key with the environment variable that overrides it.
+// Current values are only included for admins, secrets never.
+func (h *SystemHandler) Config(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"settings": h.config.Settings(c.GetBool(middleware.AdminKey)),
+	})
+}
+
+// ClearAll moves all videos, downloads, projects, and outputs to the trash. Admin
+// only, since it clears the data of every session.
 func (h *SystemHandler) ClearAll(c *gin.Context) {
+	if !c.GetBool(middleware.AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "clearing all data requires admin access"})
+		return
+	}
+
 	h.logger.Info("Clearing all data via API request")
 
-	if err := h.services.Storage.ClearEverything(); err != nil {
+	entry, err := h.services.Storage.ClearEverything()
+	if err != nil {
 		h.logger.Error("Failed to clear all data", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear data"})
 		return
@@ -96,9 +174,112 @@ func (h *SystemHandler) ClearAll(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "All videos, downloads, projects, and history have been cleared",
 		"counter_reset": true,
+		"trash":         entry, // null when the trash is disabled
+	})
+}
+
+// maxUsageHistoryDays bounds the range of the usage history
+const maxUsageHistoryDays = 365
+
+// UsageHistory returns daily upload, download, export and deletion counts and byte
+// totals, plus totals over the range. Admin only.
+// Query: days (default 30)
+func (h *SystemHandler) UsageHistory(c *gin.Context) {
+	if !c.GetBool(middleware.AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "usage history requires admin access"})
+		return
+	}
+
+	days := 30
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxUsageHistoryDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 1 and %d", maxUsageHistoryDays)})
+			return
+		}
+		days = parsed
+	}
+
+	history, err := h.services.Storage.UsageHistory(days)
+	if err != nil {
+		h.logger.Error("Failed to load usage history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load usage history"})
+		return
+	}
+
+	totals := make(map[models.UsageKind]models.UsageCounter)
+	for _, day := range history {
+		for kind, counter := range day.Counters {
+			total := totals[kind]
+			total.Count += counter.Count
+			total.Bytes += counter.Bytes
+			totals[kind] = total
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":   history,
+		"totals": totals,
 	})
 }
 
+// ListTrash returns the cleared data that can still be restored. Admin only, like
+// the trash changes.
+func (h *SystemHandler) ListTrash(c *gin.Context) {
+	if !c.GetBool(middleware.AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "listing trash requires admin access"})
+		return
+	}
+
+	trash, err := h.services.Storage.ListTrash()
+	if err != nil {
+		h.logger.Error("Failed to list trash", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"trash": trash})
+}
+
+// RestoreTrash moves the data of a trash entry back in place. Admin only, like the
+// other trash changes, since it brings back every session's cleared data.
+func (h *SystemHandler) RestoreTrash(c *gin.Context) {
+	if !c.GetBool(middleware.AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "restoring trash requires admin access"})
+		return
+	}
+
+	id := c.Param("id")
+
+	restored, skipped, err := h.services.Storage.RestoreTrash(id)
+	if err != nil {
+		h.logger.Error("Failed to restore trash", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"restored": restored,
+		"skipped":  skipped, // Files whose original path is in use again, kept in the trash
+	})
+}
+
+// DeleteTrash permanently deletes a trash entry. Admin only, since it can't be undone.
+func (h *SystemHandler) DeleteTrash(c *gin.Context) {
+	if !c.GetBool(middleware.AdminKey) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "deleting trash requires admin access"})
+		return
+	}
+
+	id := c.Param("id")
+	if err := h.services.Storage.DeleteTrash(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "trash entry deleted"})
+}
+
 // SessionStart creates or updates a session with auto-cleanup option
 func (h *SystemHandler) SessionStart(c *gin.Context) {
 	var req struct {
@@ -176,7 +357,7 @@ func (h *SystemHandler) SessionEnd(c *gin.Context) {
 			zap.String("sessionId", req.SessionID),
 		)
 
-		if err := h.services.Storage.ClearEverything(); err != nil {
+		if _, err := h.services.Storage.ClearEverything(); err != nil {
 			h.logger.Error("Session cleanup failed", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "cleanup failed"})
 			return
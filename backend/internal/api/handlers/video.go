@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"mime"
 	"net/http"
 	"os"
@@ -44,6 +46,13 @@ func (h *VideoHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	// The client may send the checksum of its file to confirm it arrived intact
+	expectedSum, err := services.NormalizeChecksum(c.PostForm("sha256"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Generate unique filename
 	ext := filepath.Ext(file.Filename)
 	filename := uuid.New().String() + ext
@@ -55,6 +64,12 @@ func (h *VideoHandler) Upload(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
 		return
 	}
+	if err := h.services.Storage.StoreFile(destPath); err != nil {
+		h.logger.Error("Failed to store uploaded file", zap.Error(err))
+		h.services.Storage.DeleteFile(destPath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+		return
+	}
 
 	// Create video record
 	video, err := h.services.Video.CreateFromUpload(file.Filename, destPath)
@@ -64,16 +79,179 @@ func (h *VideoHandler) Upload(c *gin.Context) {
 		return
 	}
 
+	h.services.Storage.RecordUsage(models.UsageUploads, 1, video.FileSize)
+
+	response := models.UploadResponse{VideoID: video.ID, Video: video}
+	checksum, err := h.services.Video.ImportChecksum(video, expectedSum)
+	if err != nil {
+		h.logger.Warn("Failed to start upload checksum", zap.String("id", video.ID), zap.Error(err))
+	} else if checksum != nil {
+		response.ChecksumOperationID = checksum.ID
+	}
+
 	h.logger.Info("Video uploaded successfully",
 		zap.String("id", video.ID),
 		zap.String("filename", file.Filename),
 		zap.Int64("size", file.Size),
 	)
 
-	c.JSON(http.StatusCreated, models.UploadResponse{
-		VideoID: video.ID,
-		Video:   video,
-	})
+	c.JSON(http.StatusCreated, response)
+}
+
+// ImportRequest names a file on the server to import as a video
+type ImportRequest struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// Import registers a file in one of the configured import directories as a video,
+// without uploading or copying it
+func (h *VideoHandler) Import(c *gin.Context) {
+	var req ImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	video, created, err := h.services.Video.ImportPath(req.Path)
+	if err != nil {
+		serviceError(c, h.logger, "failed to import video", err, zap.String("path", req.Path))
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+		h.logger.Info("Video imported successfully", zap.String("id", video.ID), zap.String("path", video.FilePath))
+	}
+	c.JSON(status, models.UploadResponse{VideoID: video.ID, Video: video})
+}
+
+// UploadSubtitle attaches a sidecar subtitle file (.srt, .vtt, .ass, .ssa) to a video
+func (h *VideoHandler) UploadSubtitle(c *gin.Context) {
+	videoID := c.Param("id")
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no file provided"})
+		return
+	}
+
+	if file.Size > h.config.Server.MaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+		return
+	}
+
+	if _, err := h.services.Video.GetVideo(videoID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "video not found"})
+		return
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	destPath := h.services.Storage.GetSubtitlePath(uuid.New().String() + ext)
+
+	if err := c.SaveUploadedFile(file, destPath); err != nil {
+		h.logger.Error("Failed to save uploaded subtitle file", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
+		return
+	}
+	if err := h.services.Storage.StoreFile(destPath); err != nil {
+		h.logger.Error("Failed to store uploaded subtitle file", zap.Error(err))
+		h.services.Storage.DeleteFile(destPath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+		return
+	}
+
+	subtitle, err := h.services.Video.AddSubtitle(videoID, file.Filename, destPath, c.PostForm("language"))
+	if err != nil {
+		h.services.Storage.DeleteFile(destPath)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, subtitle)
+}
+
+// UploadWatermark attaches a PNG image to a video for watermarking exports
+func (h *VideoHandler) UploadWatermark(c *gin.Context) {
+	videoID := c.Param("id")
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no file provided"})
+		return
+	}
+
+	if file.Size > h.config.Server.MaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+		return
+	}
+
+	if _, err := h.services.Video.GetVideo(videoID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "video not found"})
+		return
+	}
+
+	destPath := h.services.Storage.GetVideoPath(uuid.New().String() + ".png")
+
+	if err := c.SaveUploadedFile(file, destPath); err != nil {
+		h.logger.Error("Failed to save uploaded watermark", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
+		return
+	}
+	if err := h.services.Storage.StoreFile(destPath); err != nil {
+		h.logger.Error("Failed to store uploaded watermark", zap.Error(err))
+		h.services.Storage.DeleteFile(destPath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store file"})
+		return
+	}
+
+	watermark, err := h.services.Video.AddWatermark(videoID, file.Filename, destPath)
+	if err != nil {
+		h.services.Storage.DeleteFile(destPath)
+		serviceError(c, h.logger, "failed to add watermark", err, zap.String("videoId", videoID))
+		return
+	}
+
+	c.JSON(http.StatusCreated, watermark)
+}
+
+// ExtractSubtitles converts an embedded text subtitle stream to .srt/.vtt files in
+// the outputs directory, optionally clipped to a project's segments
+func (h *VideoHandler) ExtractSubtitles(c *gin.Context) {
+	videoID := c.Param("id")
+
+	streamIndex, err := strconv.Atoi(c.Param("streamIndex"))
+	if err != nil || streamIndex < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid stream index"})
+		return
+	}
+
+	// The body is optional, without it the whole track is extracted as SRT
+	var req services.SubtitleExtractRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project *models.Project
+	if req.ProjectID != "" {
+		project, err = h.services.Project.Get(req.ProjectID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+	}
+
+	files, err := h.services.Video.ExtractSubtitles(c.Request.Context(), videoID, streamIndex, project, req)
+	if err != nil {
+		serviceError(c, h.logger, "failed to extract subtitles", err,
+			zap.String("videoId", videoID),
+			zap.Int("streamIndex", streamIndex),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"files": files})
 }
 
 func (h *VideoHandler) Download(c *gin.Context) {
@@ -289,8 +467,38 @@ func getContentType(filePath string) string {
 func (h *VideoHandler) Waveform(c *gin.Context) {
 	videoID := c.Param("id")
 
+	// ?stream=N selects the audio stream, ?split_channels=true draws each channel
+	var req services.WaveformRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Zoomable peaks instead of the image
+	if c.Query("format") == "json" {
+		zoom, err := strconv.Atoi(c.DefaultQuery("zoom", "1000"))
+		if err == nil {
+			err = services.ValidateWaveformZoom(zoom)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid zoom: " + err.Error()})
+			return
+		}
+
+		peaks, err := h.services.Video.WaveformPeaks(c.Request.Context(), videoID, req, zoom)
+		if err != nil {
+			h.logger.Error("Failed to generate waveform peaks", zap.String("videoId", videoID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Cache-Control", "public, max-age=86400") // Cache for 1 day
+		c.JSON(http.StatusOK, peaks)
+		return
+	}
+
 	// Generate waveform
-	waveformPath, err := h.services.Video.GenerateWaveform(videoID)
+	waveformPath, err := h.services.Video.GenerateWaveform(videoID, req)
 	if err != nil {
 		h.logger.Error("Failed to generate waveform", zap.String("videoId", videoID), zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate waveform"})
@@ -303,6 +511,109 @@ func (h *VideoHandler) Waveform(c *gin.Context) {
 	c.File(waveformPath)
 }
 
+// Spectrogram serves the spectrogram PNG of a video's audio, generating it on first
+// request. Takes the same stream and split_channels options as Waveform.
+func (h *VideoHandler) Spectrogram(c *gin.Context) {
+	videoID := c.Param("id")
+
+	var req services.WaveformRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spectrogramPath, err := h.services.Video.GenerateSpectrogram(c.Request.Context(), videoID, req)
+	if err != nil {
+		h.logger.Error("Failed to generate spectrogram", zap.String("videoId", videoID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate spectrogram"})
+		return
+	}
+
+	c.Header("Content-Type", "image/png")
+	c.Header("Cache-Control", "public, max-age=86400") // Cache for 1 day
+	c.File(spectrogramPath)
+}
+
+// Storyboard serves the WebVTT file mapping times to tiles of the video's storyboard
+// sprite, for seek bar hover previews. Both are generated on first request.
+func (h *VideoHandler) Storyboard(c *gin.Context) {
+	videoID := c.Param("id")
+
+	_, vttPath, err := h.services.Video.Storyboard(c.Request.Context(), videoID)
+	if err != nil {
+		serviceError(c, h.logger, "failed to generate storyboard", err, zap.String("videoId", videoID))
+		return
+	}
+
+	c.Header("Content-Type", "text/vtt; charset=utf-8")
+	c.Header("Cache-Control", "public, max-age=86400") // Cache for 1 day
+	c.File(vttPath)
+}
+
+// StoryboardSprite serves the tiled thumbnail image of the video's storyboard
+func (h *VideoHandler) StoryboardSprite(c *gin.Context) {
+	videoID := c.Param("id")
+
+	spritePath, _, err := h.services.Video.Storyboard(c.Request.Context(), videoID)
+	if err != nil {
+		serviceError(c, h.logger, "failed to generate storyboard", err, zap.String("videoId", videoID))
+		return
+	}
+
+	c.Header("Content-Type", "image/jpeg")
+	c.Header("Cache-Control", "public, max-age=86400") // Cache for 1 day
+	c.File(spritePath)
+}
+
+// Thumbnails returns evenly spaced frames of the video for a timeline filmstrip,
+// ?count=N of them or one every ?interval=S seconds, ?width in pixels
+func (h *VideoHandler) Thumbnails(c *gin.Context) {
+	videoID := c.Param("id")
+
+	var req services.ThumbnailRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	set, err := h.services.Video.Thumbnails(c.Request.Context(), videoID, req)
+	if err != nil {
+		serviceError(c, h.logger, "failed to extract thumbnails", err, zap.String("videoId", videoID))
+		return
+	}
+
+	c.JSON(http.StatusOK, set)
+}
+
+// Thumbnail serves one frame of a filmstrip returned by Thumbnails
+func (h *VideoHandler) Thumbnail(c *gin.Context) {
+	path, err := h.services.Video.ThumbnailPath(c.Param("id"), c.Param("set"), c.Param("filename"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "image/jpeg")
+	c.Header("Cache-Control", "public, max-age=86400") // Cache for 1 day
+	c.File(path)
+}
+
+// Poster serves the thumbnail of a downloaded video as the site shows it
+func (h *VideoHandler) Poster(c *gin.Context) {
+	video, err := h.services.Video.GetVideo(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "video not found"})
+		return
+	}
+	if video.Source == nil || video.Source.ThumbnailPath == "" || !h.services.Storage.FileExists(video.Source.ThumbnailPath) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "video has no poster"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400") // Cache for 1 day
+	c.File(video.Source.ThumbnailPath)
+}
+
 func (h *VideoHandler) Delete(c *gin.Context) {
 	videoID := c.Param("id")
 
@@ -315,10 +626,94 @@ func (h *VideoHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "video deleted"})
 }
 
+// Remux copies the video into another container in the background.
+// Body: {"format": "mp4"}; poll the returned operation for the output file
+func (h *VideoHandler) Remux(c *gin.Context) {
+	videoID := c.Param("id")
+
+	var req services.RemuxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operation, err := h.services.Operation.Remux(videoID, req)
+	if err != nil {
+		h.logger.Warn("Failed to start remux", zap.String("videoId", videoID), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// ExtractAudio writes the video's audio to outputs in the background, in full or
+// one file per project segment. The body is optional, without it the first audio
+// stream is copied in full.
+func (h *VideoHandler) ExtractAudio(c *gin.Context) {
+	videoID := c.Param("id")
+
+	var req services.ExtractAudioRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project *models.Project
+	if req.ProjectID != "" {
+		var err error
+		project, err = h.services.Project.Get(req.ProjectID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+	}
+
+	operation, err := h.services.Operation.ExtractAudio(videoID, project, req)
+	if err != nil {
+		serviceError(c, h.logger, "failed to start audio extraction", err, zap.String("videoId", videoID))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// RotateRequest represents the request body for lossless rotation
+type RotateRequest struct {
+	Rotation *int `json:"rotation" binding:"required"` // Degrees clockwise: 0, 90, 180 or 270
+}
+
+// Rotate sets the video's display rotation without re-encoding it
+func (h *VideoHandler) Rotate(c *gin.Context) {
+	videoID := c.Param("id")
+
+	var req RotateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !services.ValidRotation(*req.Rotation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rotation must be 0, 90, 180 or 270"})
+		return
+	}
+
+	video, err := h.services.Video.Rotate(c.Request.Context(), videoID, *req.Rotation)
+	if err != nil {
+		serviceError(c, h.logger, "failed to rotate video", err,
+			zap.String("videoId", videoID),
+			zap.Int("rotation", *req.Rotation),
+		)
+		return
+	}
+
+	c.JSON(http.StatusOK, video)
+}
+
 // ScreenshotRequest represents the request body for screenshot capture
 type ScreenshotRequest struct {
 	Timestamp float64 `json:"timestamp" binding:"required"`
 	Quality   int     `json:"quality"` // 1-31, lower is better quality
+	Count     int     `json:"count"`   // Frames centered on timestamp, 0 or 1 = single screenshot
 }
 
 func (h *VideoHandler) Screenshot(c *gin.Context) {
@@ -330,6 +725,36 @@ func (h *VideoHandler) Screenshot(c *gin.Context) {
 		return
 	}
 
+	if req.Count < 0 || req.Count > services.MaxScreenshotBurst {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("count must be between 1 and %d", services.MaxScreenshotBurst)})
+		return
+	}
+
+	if req.Count > 1 {
+		frames, err := h.services.Video.CaptureScreenshotBurst(videoID, req.Timestamp, req.Count)
+		if err != nil {
+			h.logger.Error("Failed to capture screenshot burst",
+				zap.String("videoId", videoID),
+				zap.Float64("timestamp", req.Timestamp),
+				zap.Int("count", req.Count),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to capture screenshots"})
+			return
+		}
+
+		files := make([]gin.H, len(frames))
+		for i, frame := range frames {
+			files[i] = gin.H{
+				"filename":  frame.Filename,
+				"url":       "/api/screenshots/" + frame.Filename,
+				"timestamp": frame.Timestamp,
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"files": files})
+		return
+	}
+
 	// Capture screenshot
 	filename, err := h.services.Video.CaptureScreenshot(videoID, req.Timestamp)
 	if err != nil {
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AdminTokenHeader is the request header carrying the admin token
+	AdminTokenHeader = "X-Admin-Token"
+	// AdminKey is set to true in the gin context for requests with a valid admin token
+	AdminKey = "admin"
+)
+
+// Admin marks requests carrying the configured admin token. When no token is
+// configured, admin-only features are disabled for everyone.
+func Admin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(AdminTokenHeader)
+		if token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			c.Set(AdminKey, true)
+		}
+
+		c.Next()
+	}
+}
@@ -18,12 +18,13 @@ func NewRouter(services *services.Services, cfg *config.Config, logger *zap.Logg
 	// Middleware
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logger(logger))
+	router.Use(middleware.Admin(cfg.Server.AdminToken))
 
 	// CORS
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = cfg.Server.CorsOrigins
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", middleware.AdminTokenHeader}
 	router.Use(cors.New(corsConfig))
 
 	// Health check
@@ -34,13 +35,25 @@ func NewRouter(services *services.Services, cfg *config.Config, logger *zap.Logg
 	// API routes
 	api := router.Group("/api")
 	{
+		analysisHandler := handlers.NewAnalysisHandler(services, logger)
+		projectHandler := handlers.NewProjectHandler(services, logger)
+		checksumHandler := handlers.NewChecksumHandler(services, logger)
+
 		// System endpoints
 		system := api.Group("/system")
 		{
 			systemHandler := handlers.NewSystemHandler(cfg, services, logger)
 			system.GET("/info", systemHandler.Info)
 			system.GET("/stats", systemHandler.GetStats)
+			system.GET("/stats/history", systemHandler.UsageHistory)
+			system.GET("/config", systemHandler.Config)
+			system.GET("/ffmpeg", systemHandler.FFmpeg)
+			system.GET("/ytdlp", systemHandler.YtDlp)
+			system.POST("/ytdlp/update", systemHandler.YtDlpUpdate)
 			system.DELETE("/clear-all", systemHandler.ClearAll)
+			system.GET("/trash", systemHandler.ListTrash)
+			system.POST("/trash/:id/restore", systemHandler.RestoreTrash)
+			system.DELETE("/trash/:id", systemHandler.DeleteTrash)
 			system.POST("/session/start", systemHandler.SessionStart)
 			system.POST("/session/heartbeat", systemHandler.SessionHeartbeat)
 			system.POST("/session/end", systemHandler.SessionEnd)
@@ -49,13 +62,18 @@ func NewRouter(services *services.Services, cfg *config.Config, logger *zap.Logg
 		// Project endpoints
 		projects := api.Group("/projects")
 		{
-			projectHandler := handlers.NewProjectHandler(services, logger)
 			projects.POST("", projectHandler.Create)
 			projects.GET("", projectHandler.List)
 			projects.GET("/:id", projectHandler.Get)
 			projects.PUT("/:id", projectHandler.Update)
 			projects.DELETE("/:id", projectHandler.Delete)
 			projects.POST("/:id/export", projectHandler.Export)
+			projects.GET("/:id/preview", projectHandler.Preview)
+
+			// Analysis endpoints
+			projects.POST("/:id/detect-intro", analysisHandler.DetectIntro)
+			projects.POST("/:id/suggest-chapters", analysisHandler.SuggestChapters)
+			projects.POST("/:id/remove-silence", analysisHandler.RemoveSilence)
 
 			// Segment endpoints
 			segments := projects.Group("/:id/segments")
@@ -63,21 +81,53 @@ func NewRouter(services *services.Services, cfg *config.Config, logger *zap.Logg
 				segments.POST("", projectHandler.AddSegment)
 				segments.PUT("/:segmentId", projectHandler.UpdateSegment)
 				segments.DELETE("/:segmentId", projectHandler.DeleteSegment)
+				segments.GET("/:segmentId/loudness", analysisHandler.SegmentLoudness)
 			}
 		}
 
+		// Export endpoints
+		export := api.Group("/export")
+		{
+			export.GET("/presets", projectHandler.ListExportPresets)
+		}
+
 		// Video endpoints
 		videos := api.Group("/videos")
 		{
 			videoHandler := handlers.NewVideoHandler(services, cfg, logger)
 			videos.POST("/upload", videoHandler.Upload)
+			videos.POST("/import", videoHandler.Import)
 			videos.POST("/download", videoHandler.Download)
 			videos.GET("/:id/stream", videoHandler.Stream)
 			videos.GET("/:id/waveform", videoHandler.Waveform)
+			videos.GET("/:id/spectrogram", videoHandler.Spectrogram)
+			videos.GET("/:id/storyboard", videoHandler.Storyboard)
+			videos.GET("/:id/storyboard/sprite", videoHandler.StoryboardSprite)
+			videos.GET("/:id/thumbnails", videoHandler.Thumbnails)
+			videos.GET("/:id/thumbnails/:set/:filename", videoHandler.Thumbnail)
+			videos.GET("/:id/poster", videoHandler.Poster)
 			videos.POST("/:id/screenshot", videoHandler.Screenshot)
+			videos.POST("/:id/rotate", videoHandler.Rotate)
+			videos.POST("/:id/remux", videoHandler.Remux)
+			videos.POST("/:id/extract-audio", videoHandler.ExtractAudio)
+			videos.POST("/:id/subtitles", videoHandler.UploadSubtitle)
+			videos.POST("/:id/subtitles/:streamIndex/extract", videoHandler.ExtractSubtitles)
+			videos.POST("/:id/watermarks", videoHandler.UploadWatermark)
+			videos.POST("/:id/detect", analysisHandler.Detect)
+			videos.POST("/:id/split", analysisHandler.Split)
+			videos.GET("/:id/keyframes", analysisHandler.Keyframes)
+			videos.GET("/:id/loudness", analysisHandler.Loudness)
+			videos.POST("/:id/checksum", checksumHandler.ChecksumVideo)
+			videos.POST("/:id/verify", checksumHandler.VerifyVideo)
 			videos.DELETE("/:id", videoHandler.Delete)
 		}
 
+		// Analysis endpoints
+		analysis := api.Group("/analysis")
+		{
+			analysis.GET("/presets", analysisHandler.ListPresets)
+		}
+
 		// Screenshot downloads
 		api.GET("/screenshots/:filename", func(c *gin.Context) {
 			filename := c.Param("filename")
@@ -103,6 +153,19 @@ func NewRouter(services *services.Services, cfg *config.Config, logger *zap.Logg
 			downloads.DELETE("", downloadHandler.ClearAll)
 			downloads.GET("/:id", downloadHandler.Get)
 			downloads.POST("/:id/cancel", downloadHandler.Cancel)
+			downloads.POST("/:id/resume", downloadHandler.Resume)
+		}
+
+		// Feed subscription endpoints (automatic downloads of new feed items)
+		subscriptions := api.Group("/subscriptions")
+		{
+			subscriptionHandler := handlers.NewSubscriptionHandler(services, logger)
+			subscriptions.POST("", subscriptionHandler.Create)
+			subscriptions.GET("", subscriptionHandler.List)
+			subscriptions.GET("/:id", subscriptionHandler.Get)
+			subscriptions.PUT("/:id", subscriptionHandler.Update)
+			subscriptions.DELETE("/:id", subscriptionHandler.Delete)
+			subscriptions.POST("/:id/check", subscriptionHandler.Check)
 		}
 
 		// Operation endpoints (for checking export/processing status)
@@ -110,14 +173,20 @@ func NewRouter(services *services.Services, cfg *config.Config, logger *zap.Logg
 		{
 			operationHandler := handlers.NewOperationHandler(services, logger)
 			operations.GET("/:id", operationHandler.GetStatus)
+			operations.POST("/:id/retry", operationHandler.Retry)
+			operations.POST("/:id/cancel", operationHandler.Cancel)
 		}
 
+		// Output file checksums
+		api.POST("/outputs/:filename/checksum", checksumHandler.ChecksumOutput)
+		api.POST("/outputs/:filename/verify", checksumHandler.VerifyOutput)
+
 		// Output file downloads (exported videos) - optimized with better headers
 		api.GET("/outputs/:filename", func(c *gin.Context) {
 			filename := c.Param("filename")
 			filepath := services.Storage.GetOutputPath(filename)
 
-			if !services.Storage.FileExists(filepath) {
+			if services.Storage.FetchFile(filepath) != nil {
 				logger.Warn("Output file not found", zap.String("filename", filename))
 				c.JSON(404, gin.H{"error": "file not found"})
 				return
@@ -4,15 +4,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server  ServerConfig  `mapstructure:"server"`
-	Storage StorageConfig `mapstructure:"storage"`
-	FFmpeg  FFmpegConfig  `mapstructure:"ffmpeg"`
-	YtDlp   YtDlpConfig   `mapstructure:"ytdlp"`
+	Server   ServerConfig   `mapstructure:"server"`
+	Storage  StorageConfig  `mapstructure:"storage"`
+	FFmpeg   FFmpegConfig   `mapstructure:"ffmpeg"`
+	YtDlp    YtDlpConfig    `mapstructure:"ytdlp"`
+	Download DownloadConfig `mapstructure:"download"`
+	Torrent  TorrentConfig  `mapstructure:"torrent"`
+	Feeds    FeedsConfig    `mapstructure:"feeds"`
+	Import   ImportConfig   `mapstructure:"import"`
+	S3       S3Config       `mapstructure:"s3"`
+	Remote   RemoteConfig   `mapstructure:"remote"`
 }
 
 type ServerConfig struct {
@@ -21,17 +28,34 @@ type ServerConfig struct {
 	MaxUploadSize int64    `mapstructure:"max_upload_size"`
 	Production    bool     `mapstructure:"production"`
 	CorsOrigins   []string `mapstructure:"cors_origins"`
+	AdminToken    string   `mapstructure:"admin_token"` // Enables admin-only features when sent as X-Admin-Token
 }
 
 type StorageConfig struct {
-	BasePath        string `mapstructure:"base_path"`
-	AutoCleanup     bool   `mapstructure:"auto_cleanup"`
-	CleanupAfterDays int   `mapstructure:"cleanup_after_days"`
+	BasePath            string `mapstructure:"base_path"`
+	AutoCleanup         bool   `mapstructure:"auto_cleanup"` // Trash videos, with their projects, and outputs older than cleanup_after_days
+	CleanupAfterDays    int    `mapstructure:"cleanup_after_days"`
+	ResumeInterrupted   bool   `mapstructure:"resume_interrupted"`    // Restart interrupted downloads and exports on startup
+	DownloadNaming      string `mapstructure:"download_naming"`       // "sequence" (video1.mp4) or "slug" (title_2024-01-31.mp4)
+	TrashRetentionDays  int    `mapstructure:"trash_retention_days"`  // How long cleared data can be restored, 0 deletes it right away
+	FailedRetentionDays int    `mapstructure:"failed_retention_days"` // How long failed exports and downloads can be retried, 0 keeps them
+	ComputeChecksums    bool   `mapstructure:"compute_checksums"`     // SHA-256 of every upload, download and export, for later verification
+	Metadata            string `mapstructure:"metadata"`              // Where video, download and project records are kept: "bolt" (metadata.db) or "json" (a file each)
+	Media               string `mapstructure:"media"`                 // Where uploads, downloaded videos, outputs and waveforms are kept: "local" or "s3" (media_bucket, with the s3 credentials)
+	MediaBucket         string `mapstructure:"media_bucket"`          // Bucket of media with "s3"
+	MediaPrefix         string `mapstructure:"media_prefix"`          // Key prefix of media in the bucket, e.g. "losslesscut/"
 }
 
 type FFmpegConfig struct {
-	Path    string `mapstructure:"path"`
-	Threads int    `mapstructure:"threads"`
+	Path         string `mapstructure:"path"`
+	Threads      int    `mapstructure:"threads"`
+	MaxParallel  int    `mapstructure:"max_parallel"`  // Concurrent FFmpeg processes per export
+	MaxProcesses int    `mapstructure:"max_processes"` // Concurrent ffmpeg/ffprobe processes server-wide, 0 = unlimited
+	Nice         int    `mapstructure:"nice"`          // Scheduling niceness of FFmpeg processes (0-19)
+	CPULimit     int    `mapstructure:"cpu_limit"`     // CPU quota of all FFmpeg processes in percent of one core, 0 = unlimited
+	CgroupPath   string `mapstructure:"cgroup_path"`   // cgroup v2 directory enforcing cpu_limit
+	HWAccel      string `mapstructure:"hwaccel"`       // Smart cut re-encoding on the GPU: "nvenc", "qsv" or "vaapi"
+	VAAPIDevice  string `mapstructure:"vaapi_device"`  // DRM render node used by vaapi
 }
 
 type YtDlpConfig struct {
@@ -39,6 +63,59 @@ type YtDlpConfig struct {
 	MaxQuality string `mapstructure:"max_quality"`
 }
 
+// DownloadConfig configures URL downloads and their default client, per download
+// requests can override the client
+type DownloadConfig struct {
+	Impersonate         string   `mapstructure:"impersonate"`           // yt-dlp --impersonate target, e.g. "chrome"
+	UserAgent           string   `mapstructure:"user_agent"`            // For yt-dlp and direct HTTP downloads
+	Headers             []string `mapstructure:"headers"`               // Extra request headers as "Name: value"
+	CookiesFile         string   `mapstructure:"cookies_file"`          // Netscape cookies.txt passed to yt-dlp --cookies
+	CookiesFromBrowser  string   `mapstructure:"cookies_from_browser"`  // yt-dlp --cookies-from-browser, e.g. "firefox" or "chrome:Profile 1"
+	CookieSecret        string   `mapstructure:"cookie_secret"`         // Encrypts cookies sent with downloads, empty generates a key file under base_path
+	RateLimit           string   `mapstructure:"rate_limit"`            // Bandwidth cap of each download in bytes per second, e.g. "5M", empty = unlimited
+	MaxConcurrent       int      `mapstructure:"max_concurrent"`        // Downloads running at once, others queue; 0 = unlimited
+	MaxRetries          int      `mapstructure:"max_retries"`           // Retries of downloads failing for a transient reason, 0 disables them
+	RetryBackoffSeconds int      `mapstructure:"retry_backoff_seconds"` // Wait before the first retry, doubling with every further one
+}
+
+type TorrentConfig struct {
+	Enabled    bool `mapstructure:"enabled"`     // Accept magnet links and .torrent URLs as downloads
+	ListenPort int  `mapstructure:"listen_port"` // Peer port, 0 picks a free one
+}
+
+type FeedsConfig struct {
+	PollIntervalMinutes int `mapstructure:"poll_interval_minutes"` // How often subscriptions are checked, 0 disables polling
+}
+
+// ImportConfig configures importing videos from server directories in place, e.g. a
+// NAS mount, instead of uploading them
+type ImportConfig struct {
+	Paths                []string `mapstructure:"paths"`                  // Directories POST /api/videos/import may import from, empty disables it
+	WatchDir             string   `mapstructure:"watch_dir"`              // Files dropped here are imported automatically, empty disables watching
+	WatchIntervalSeconds int      `mapstructure:"watch_interval_seconds"` // How often watch_dir is scanned for new files
+}
+
+// S3Config holds the credentials s3:// download URLs are fetched with, and media is
+// stored with under storage.media "s3", from AWS or an S3-compatible store
+type S3Config struct {
+	Endpoint        string   `mapstructure:"endpoint"`      // Empty = AWS, e.g. "https://minio.example.com:9000" for compatible stores
+	Region          string   `mapstructure:"region"`        // Signing region, e.g. "eu-central-1" ("auto" for R2)
+	AccessKeyID     string   `mapstructure:"access_key_id"` // Empty fetches objects unsigned, i.e. only public ones
+	SecretAccessKey string   `mapstructure:"secret_access_key"`
+	SessionToken    string   `mapstructure:"session_token"`    // For temporary credentials
+	PathStyle       bool     `mapstructure:"path_style"`       // Bucket in the path instead of the host name, needed by most compatible stores
+	DownloadBuckets []string `mapstructure:"download_buckets"` // Buckets s3:// downloads may read with the credentials, never storage.media_bucket
+}
+
+// RemoteConfig configures downloads of ftp://, ftps:// and sftp:// URLs, e.g. from
+// the FTP drop of a camera or recorder
+type RemoteConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`       // Accept ftp://, ftps:// and sftp:// URLs as downloads
+	CurlPath    string   `mapstructure:"curl_path"`     // curl binary fetching them, built with sftp support for sftp:// URLs
+	SSHKeyFile  string   `mapstructure:"ssh_key_file"`  // Private key for sftp:// logins without a password
+	SSHKeyHosts []string `mapstructure:"ssh_key_hosts"` // Hosts, as host or host:port, ssh_key_file may log in to
+}
+
 func Load(configPath string) (*Config, error) {
 	v := viper.New()
 
@@ -57,9 +134,16 @@ func Load(configPath string) (*Config, error) {
 		v.AddConfigPath(filepath.Join(os.Getenv("HOME"), ".losslesscut"))
 	}
 
-	// Read environment variables
-	v.SetEnvPrefix("LOSSLESSCUT")
+	// Read environment variables, e.g. LOSSLESSCUT_SERVER_PORT for server.port
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
+	if err := bindEnv(v); err != nil {
+		return nil, err
+	}
+	if err := validateEnv(); err != nil {
+		return nil, err
+	}
 
 	// Read config file
 	if err := v.ReadInConfig(); err != nil {
@@ -80,6 +164,10 @@ func Load(configPath string) (*Config, error) {
 	}
 	cfg.Storage.BasePath = os.ExpandEnv(cfg.Storage.BasePath)
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
@@ -90,17 +178,73 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.max_upload_size", 10737418240) // 10GB
 	v.SetDefault("server.production", false)
 	v.SetDefault("server.cors_origins", []string{"*"})
+	v.SetDefault("server.admin_token", "") // Admin features disabled
 
 	// Storage defaults
 	v.SetDefault("storage.base_path", "/var/losslesscut")
 	v.SetDefault("storage.auto_cleanup", true)
 	v.SetDefault("storage.cleanup_after_days", 7)
+	v.SetDefault("storage.resume_interrupted", false)
+	v.SetDefault("storage.download_naming", "sequence")
+	v.SetDefault("storage.trash_retention_days", 7)
+	v.SetDefault("storage.failed_retention_days", 7)
+	v.SetDefault("storage.compute_checksums", false)
+	v.SetDefault("storage.metadata", "bolt")
+	v.SetDefault("storage.media", "local")
+	v.SetDefault("storage.media_bucket", "")
+	v.SetDefault("storage.media_prefix", "")
 
 	// FFmpeg defaults
 	v.SetDefault("ffmpeg.path", "ffmpeg")
 	v.SetDefault("ffmpeg.threads", 0) // auto
+	v.SetDefault("ffmpeg.max_parallel", 2)
+	v.SetDefault("ffmpeg.max_processes", 4)
+	v.SetDefault("ffmpeg.nice", 0)
+	v.SetDefault("ffmpeg.cpu_limit", 0) // Unlimited
+	v.SetDefault("ffmpeg.cgroup_path", "/sys/fs/cgroup/losslesscut-ffmpeg")
+	v.SetDefault("ffmpeg.hwaccel", "") // Software encoding
+	v.SetDefault("ffmpeg.vaapi_device", "/dev/dri/renderD128")
 
 	// yt-dlp defaults
 	v.SetDefault("ytdlp.path", "yt-dlp")
 	v.SetDefault("ytdlp.max_quality", "1080p")
+
+	// URL download client defaults
+	v.SetDefault("download.impersonate", "")
+	v.SetDefault("download.user_agent", "")
+	v.SetDefault("download.headers", []string{})
+	v.SetDefault("download.cookies_file", "")
+	v.SetDefault("download.cookies_from_browser", "")
+	v.SetDefault("download.cookie_secret", "")
+	v.SetDefault("download.rate_limit", "") // Unlimited
+	v.SetDefault("download.max_concurrent", 3)
+	v.SetDefault("download.max_retries", 3)
+	v.SetDefault("download.retry_backoff_seconds", 30)
+
+	// Torrent defaults
+	v.SetDefault("torrent.enabled", false)
+	v.SetDefault("torrent.listen_port", 42069)
+
+	// Feed subscription defaults
+	v.SetDefault("feeds.poll_interval_minutes", 60)
+
+	// Server path import defaults
+	v.SetDefault("import.paths", []string{}) // Disabled
+	v.SetDefault("import.watch_dir", "")     // Disabled
+	v.SetDefault("import.watch_interval_seconds", 30)
+
+	// s3:// download defaults
+	v.SetDefault("s3.endpoint", "") // AWS
+	v.SetDefault("s3.region", "us-east-1")
+	v.SetDefault("s3.access_key_id", "")
+	v.SetDefault("s3.secret_access_key", "")
+	v.SetDefault("s3.session_token", "")
+	v.SetDefault("s3.path_style", false)
+	v.SetDefault("s3.download_buckets", []string{})
+
+	// FTP/SFTP download defaults
+	v.SetDefault("remote.enabled", false)
+	v.SetDefault("remote.curl_path", "curl")
+	v.SetDefault("remote.ssh_key_file", "")
+	v.SetDefault("remote.ssh_key_hosts", []string{})
 }
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is the prefix of the environment variables that override config keys,
+// e.g. LOSSLESSCUT_SERVER_MAX_UPLOAD_SIZE for server.max_upload_size
+const EnvPrefix = "LOSSLESSCUT"
+
+// secretKeys are never included in config dumps
+var secretKeys = map[string]bool{
+	"server.admin_token":     true,
+	"download.headers":       true, // May hold credentials
+	"download.cookie_secret": true,
+	"s3.secret_access_key":   true,
+	"s3.session_token":       true,
+}
+
+// Setting describes a config key and the environment variable overriding it
+type Setting struct {
+	Key   string      `json:"key"`
+	Env   string      `json:"env"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// settingField is a leaf field of Config
+type settingField struct {
+	key   string
+	index []int
+	kind  reflect.Kind
+	typ   reflect.Type
+}
+
+// EnvName returns the environment variable name of a config key
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// settingFields lists the leaf fields of a config struct with their dotted keys
+func settingFields(t reflect.Type, prefix string, index []int) []settingField {
+	var fields []settingField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+
+		if field.Type.Kind() == reflect.Struct {
+			fields = append(fields, settingFields(field.Type, key, fieldIndex)...)
+			continue
+		}
+		fields = append(fields, settingField{key: key, index: fieldIndex, kind: field.Type.Kind(), typ: field.Type})
+	}
+	return fields
+}
+
+// bindEnv binds every config key to its environment variable. viper's AutomaticEnv
+// alone only sees nested keys that are already known from defaults or a config file.
+func bindEnv(v *viper.Viper) error {
+	for _, field := range settingFields(reflect.TypeOf(Config{}), "", nil) {
+		if err := v.BindEnv(field.key, EnvName(field.key)); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", EnvName(field.key), err)
+		}
+	}
+	return nil
+}
+
+// validateEnv checks that the config environment variables that are set parse as
+// the type of their key, so typos fail at startup instead of silently using zero values
+func validateEnv() error {
+	var problems []string
+	for _, field := range settingFields(reflect.TypeOf(Config{}), "", nil) {
+		name := EnvName(field.key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		var err error
+		switch field.kind {
+		case reflect.Int, reflect.Int64:
+			_, err = strconv.ParseInt(value, 10, 64)
+		case reflect.Float64:
+			_, err = strconv.ParseFloat(value, 64)
+		case reflect.Bool:
+			_, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid %s value %q", name, field.typ, value))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid environment configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Settings lists every config key with its environment variable and type. Values are
+// included if requested, except for secrets.
+func (c *Config) Settings(withValues bool) []Setting {
+	value := reflect.ValueOf(c).Elem()
+
+	var settings []Setting
+	for _, field := range settingFields(value.Type(), "", nil) {
+		setting := Setting{
+			Key:  field.key,
+			Env:  EnvName(field.key),
+			Type: field.typ.String(),
+		}
+		if field.kind == reflect.Slice {
+			setting.Type += " (comma-separated)"
+		}
+		if withValues && !secretKeys[field.key] {
+			setting.Value = value.FieldByIndex(field.index).Interface()
+		}
+		settings = append(settings, setting)
+	}
+	return settings
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestLoadFromEnv(t *testing.T) {
+	t.Setenv("LOSSLESSCUT_STORAGE_BASE_PATH", t.TempDir())
+	t.Setenv("LOSSLESSCUT_SERVER_MAX_UPLOAD_SIZE", "1024")
+	t.Setenv("LOSSLESSCUT_SERVER_CORS_ORIGINS", "https://a.example,https://b.example")
+	t.Setenv("LOSSLESSCUT_FFMPEG_MAX_PARALLEL", "4")
+	t.Setenv("LOSSLESSCUT_STORAGE_RESUME_INTERRUPTED", "true")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.MaxUploadSize != 1024 {
+		t.Errorf("MaxUploadSize = %d, want 1024", cfg.Server.MaxUploadSize)
+	}
+	if len(cfg.Server.CorsOrigins) != 2 || cfg.Server.CorsOrigins[1] != "https://b.example" {
+		t.Errorf("CorsOrigins = %v", cfg.Server.CorsOrigins)
+	}
+	if cfg.FFmpeg.MaxParallel != 4 || !cfg.Storage.ResumeInterrupted {
+		t.Errorf("FFmpeg.MaxParallel = %d, Storage.ResumeInterrupted = %v", cfg.FFmpeg.MaxParallel, cfg.Storage.ResumeInterrupted)
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	t.Setenv("LOSSLESSCUT_STORAGE_BASE_PATH", t.TempDir())
+	t.Setenv("LOSSLESSCUT_SERVER_PORT", "eighty")
+
+	if _, err := Load(""); err == nil {
+		t.Error("Load() with a non-numeric port: want error")
+	}
+}
+
+func TestSettings(t *testing.T) {
+	cfg := &Config{Server: ServerConfig{Port: 8080, AdminToken: "secret"}}
+
+	found := false
+	for _, setting := range cfg.Settings(true) {
+		switch setting.Key {
+		case "server.port":
+			found = true
+			if setting.Env != "LOSSLESSCUT_SERVER_PORT" || setting.Value != 8080 {
+				t.Errorf("server.port setting = %+v", setting)
+			}
+		case "server.admin_token":
+			if setting.Value != nil {
+				t.Error("admin token included in settings")
+			}
+		}
+	}
+	if !found {
+		t.Error("server.port missing from settings")
+	}
+
+	for _, setting := range cfg.Settings(false) {
+		if setting.Value != nil {
+			t.Errorf("%s has a value without withValues", setting.Key)
+		}
+	}
+}
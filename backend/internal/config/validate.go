@@ -0,0 +1,381 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxQualityPattern matches ytdlp.max_quality values such as "1080p" or "best"
+var maxQualityPattern = regexp.MustCompile(`^(best|\d{3,4}p)$`)
+
+// impersonatePattern matches yt-dlp impersonation targets: client[-version][:os[-version]]
+var impersonatePattern = regexp.MustCompile(`^[a-z]+(-[0-9.]+)?(:[a-z]+(-[0-9.]+)?)?$`)
+
+// cookieBrowsers are the browsers yt-dlp can read cookies from
+var cookieBrowsers = map[string]bool{
+	"brave": true, "chrome": true, "chromium": true, "edge": true, "firefox": true,
+	"opera": true, "safari": true, "vivaldi": true, "whale": true,
+}
+
+// s3RegionPattern matches S3 signing regions, e.g. "us-east-1" or "auto"
+var s3RegionPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// s3BucketPattern matches S3 bucket names
+var s3BucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// headerNamePattern matches HTTP header names (RFC 7230 tokens)
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// Validate checks the loaded configuration and reports every problem at once, so a
+// misconfigured server fails at startup instead of on its first requests
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Server
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		add("server.port: %d is not a valid port (1-65535)", c.Server.Port)
+	}
+	if c.Server.MaxUploadSize <= 0 {
+		add("server.max_upload_size: must be positive, got %d", c.Server.MaxUploadSize)
+	}
+	for _, origin := range c.Server.CorsOrigins {
+		if err := validateOrigin(origin); err != nil {
+			add("server.cors_origins: %v", err)
+		}
+	}
+
+	// Storage
+	if c.Storage.BasePath == "" {
+		add("storage.base_path: must be set")
+	} else if err := checkWritable(c.Storage.BasePath); err != nil {
+		add("storage.base_path: %v", err)
+	}
+	if c.Storage.CleanupAfterDays < 0 || (c.Storage.AutoCleanup && c.Storage.CleanupAfterDays == 0) {
+		add("storage.cleanup_after_days: must be at least 1 with auto_cleanup, got %d", c.Storage.CleanupAfterDays)
+	}
+	if c.Storage.TrashRetentionDays < 0 {
+		add("storage.trash_retention_days: must be 0 (no trash) or positive, got %d", c.Storage.TrashRetentionDays)
+	}
+	if c.Storage.FailedRetentionDays < 0 {
+		add("storage.failed_retention_days: must be 0 (keep) or positive, got %d", c.Storage.FailedRetentionDays)
+	}
+	switch c.Storage.DownloadNaming {
+	case "", "sequence", "slug":
+	default:
+		add("storage.download_naming: must be \"sequence\" or \"slug\", got %q", c.Storage.DownloadNaming)
+	}
+	switch c.Storage.Metadata {
+	case "", "bolt", "json":
+	default:
+		add("storage.metadata: must be \"bolt\" or \"json\", got %q", c.Storage.Metadata)
+	}
+	switch c.Storage.Media {
+	case "", "local":
+	case "s3":
+		if !s3BucketPattern.MatchString(c.Storage.MediaBucket) {
+			add("storage.media_bucket: %q is not a bucket name", c.Storage.MediaBucket)
+		}
+		if strings.HasPrefix(c.Storage.MediaPrefix, "/") {
+			add("storage.media_prefix: must not start with a slash, got %q", c.Storage.MediaPrefix)
+		}
+	default:
+		add("storage.media: must be \"local\" or \"s3\", got %q", c.Storage.Media)
+	}
+
+	// FFmpeg
+	if err := checkBinary(c.FFmpeg.Path); err != nil {
+		add("ffmpeg.path: %v", err)
+	}
+	if c.FFmpeg.Threads < 0 {
+		add("ffmpeg.threads: must be 0 (auto) or positive, got %d", c.FFmpeg.Threads)
+	}
+	if c.FFmpeg.MaxParallel < 1 || c.FFmpeg.MaxParallel > 64 {
+		add("ffmpeg.max_parallel: must be between 1 and 64, got %d", c.FFmpeg.MaxParallel)
+	}
+	if c.FFmpeg.MaxProcesses < 0 {
+		add("ffmpeg.max_processes: must be 0 (unlimited) or positive, got %d", c.FFmpeg.MaxProcesses)
+	}
+	if c.FFmpeg.Nice < 0 || c.FFmpeg.Nice > 19 {
+		add("ffmpeg.nice: must be between 0 and 19, got %d", c.FFmpeg.Nice)
+	}
+	if c.FFmpeg.CPULimit < 0 {
+		add("ffmpeg.cpu_limit: must be 0 (unlimited) or a positive percentage, got %d", c.FFmpeg.CPULimit)
+	} else if c.FFmpeg.CPULimit > 0 && !filepath.IsAbs(c.FFmpeg.CgroupPath) {
+		add("ffmpeg.cgroup_path: must be an absolute path with cpu_limit, got %q", c.FFmpeg.CgroupPath)
+	}
+	switch c.FFmpeg.HWAccel {
+	case "", "nvenc", "qsv", "vaapi":
+	default:
+		add("ffmpeg.hwaccel: must be \"nvenc\", \"qsv\", \"vaapi\" or empty, got %q", c.FFmpeg.HWAccel)
+	}
+
+	// yt-dlp
+	if err := checkBinary(c.YtDlp.Path); err != nil {
+		add("ytdlp.path: %v", err)
+	}
+	if c.YtDlp.MaxQuality != "" && !maxQualityPattern.MatchString(c.YtDlp.MaxQuality) {
+		add("ytdlp.max_quality: %q must be \"best\" or a height like \"1080p\"", c.YtDlp.MaxQuality)
+	}
+
+	// URL downloads
+	if c.Download.Impersonate != "" {
+		if err := ValidateImpersonate(c.Download.Impersonate); err != nil {
+			add("download.impersonate: %v", err)
+		}
+	}
+	if strings.ContainsAny(c.Download.UserAgent, "\r\n") {
+		add("download.user_agent: must be a single line")
+	}
+	for _, header := range c.Download.Headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			add("download.headers: %q is not \"Name: value\"", header)
+		} else if err := ValidateHeader(strings.TrimSpace(name), value); err != nil {
+			add("download.headers: %v", err)
+		}
+	}
+	if c.Download.CookiesFile != "" {
+		if info, err := os.Stat(c.Download.CookiesFile); err != nil || info.IsDir() {
+			add("download.cookies_file: %s is not a file", c.Download.CookiesFile)
+		}
+	}
+	if c.Download.CookiesFromBrowser != "" {
+		if err := ValidateCookiesFromBrowser(c.Download.CookiesFromBrowser); err != nil {
+			add("download.cookies_from_browser: %v", err)
+		}
+	}
+	if _, err := ParseRateLimit(c.Download.RateLimit); err != nil {
+		add("download.rate_limit: %v", err)
+	}
+	if c.Download.MaxConcurrent < 0 {
+		add("download.max_concurrent: must be 0 (unlimited) or positive, got %d", c.Download.MaxConcurrent)
+	}
+	if c.Download.MaxRetries < 0 {
+		add("download.max_retries: must be 0 or positive, got %d", c.Download.MaxRetries)
+	}
+	if c.Download.RetryBackoffSeconds < 0 {
+		add("download.retry_backoff_seconds: must be 0 or positive, got %d", c.Download.RetryBackoffSeconds)
+	}
+
+	// Torrent
+	if c.Torrent.ListenPort < 0 || c.Torrent.ListenPort > 65535 {
+		add("torrent.listen_port: %d is not a valid port (0-65535)", c.Torrent.ListenPort)
+	}
+
+	// Feeds
+	if c.Feeds.PollIntervalMinutes < 0 {
+		add("feeds.poll_interval_minutes: must be 0 (no polling) or positive, got %d", c.Feeds.PollIntervalMinutes)
+	}
+
+	// Import
+	for _, dir := range c.Import.Paths {
+		if err := checkDir(dir); err != nil {
+			add("import.paths: %v", err)
+		}
+	}
+	if c.Import.WatchDir != "" {
+		if err := checkDir(c.Import.WatchDir); err != nil {
+			add("import.watch_dir: %v", err)
+		}
+		if c.Import.WatchIntervalSeconds < 1 {
+			add("import.watch_interval_seconds: must be at least 1 with watch_dir, got %d", c.Import.WatchIntervalSeconds)
+		}
+	}
+
+	// S3
+	if c.S3.Endpoint != "" {
+		if u, err := url.Parse(c.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("s3.endpoint: %q must be an http(s) URL", c.S3.Endpoint)
+		}
+	}
+	if c.S3.Region != "" && !s3RegionPattern.MatchString(c.S3.Region) {
+		add("s3.region: %q is not a region name", c.S3.Region)
+	}
+	if (c.S3.AccessKeyID == "") != (c.S3.SecretAccessKey == "") {
+		add("s3.access_key_id: access_key_id and secret_access_key must be set together")
+	}
+	for _, bucket := range c.S3.DownloadBuckets {
+		switch {
+		case !s3BucketPattern.MatchString(bucket):
+			add("s3.download_buckets: %q is not a bucket name", bucket)
+		case bucket == c.Storage.MediaBucket:
+			add("s3.download_buckets: %q is the media bucket, which holds every session's files", bucket)
+		}
+	}
+
+	// FTP/SFTP downloads
+	if c.Remote.Enabled {
+		if err := checkBinary(c.Remote.CurlPath); err != nil {
+			add("remote.curl_path: %v", err)
+		}
+	}
+	if c.Remote.SSHKeyFile != "" {
+		if info, err := os.Stat(c.Remote.SSHKeyFile); err != nil || info.IsDir() {
+			add("remote.ssh_key_file: %s is not a file", c.Remote.SSHKeyFile)
+		}
+		if len(c.Remote.SSHKeyHosts) == 0 {
+			add("remote.ssh_key_hosts: list the hosts remote.ssh_key_file may log in to")
+		}
+	}
+	for _, host := range c.Remote.SSHKeyHosts {
+		if host == "" || strings.ContainsAny(host, "/@ ") {
+			add("remote.ssh_key_hosts: %q is not a host or host:port", host)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// validateOrigin checks a CORS origin: "*" or scheme://host[:port] without a path
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an origin like https://example.com", origin)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%q must not have a path, query or fragment", origin)
+	}
+	if u.Path == "/" {
+		return fmt.Errorf("%q must not end with a slash, browsers send origins without one", origin)
+	}
+	return nil
+}
+
+// ValidateImpersonate checks a yt-dlp impersonation target such as "chrome" or
+// "safari-17.0:macos-14"
+func ValidateImpersonate(target string) error {
+	if !impersonatePattern.MatchString(target) {
+		return fmt.Errorf("invalid impersonate target %q, e.g. \"chrome\" or \"safari-17.0:macos-14\"", target)
+	}
+	return nil
+}
+
+// ValidateCookiesFromBrowser checks a yt-dlp --cookies-from-browser value:
+// BROWSER[+KEYRING][:PROFILE][::CONTAINER]
+func ValidateCookiesFromBrowser(value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("cookies_from_browser must be a single line")
+	}
+	browser := strings.ToLower(value)
+	if i := strings.IndexAny(browser, "+:"); i >= 0 {
+		browser = browser[:i]
+	}
+	if !cookieBrowsers[browser] {
+		return fmt.Errorf("unsupported browser %q, e.g. \"firefox\" or \"chrome:Profile 1\"", browser)
+	}
+	return nil
+}
+
+// ParseRateLimit parses a bandwidth limit in bytes per second with an optional K, M
+// or G suffix (powers of 1024) as yt-dlp's --limit-rate takes it, e.g. "500K" or
+// "4.2M". Empty means unlimited and returns 0.
+func ParseRateLimit(limit string) (int64, error) {
+	if limit == "" {
+		return 0, nil
+	}
+	multiplier := 1.0
+	number := limit
+	switch strings.ToUpper(limit[len(limit)-1:]) {
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		number = limit[:len(limit)-1]
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value*multiplier < 1 {
+		return 0, fmt.Errorf("invalid rate limit %q, e.g. \"500K\" or \"4.2M\"", limit)
+	}
+	return int64(value * multiplier), nil
+}
+
+// ValidateHeader checks that an HTTP request header can be sent as is
+func ValidateHeader(name, value string) error {
+	if !headerNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid header name %q", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("header %s must be a single line", name)
+	}
+	return nil
+}
+
+// ParseHeaders parses "Name: value" headers, skipping malformed ones
+func ParseHeaders(headers []string) map[string]string {
+	parsed := make(map[string]string, len(headers))
+	for _, header := range headers {
+		if name, value, ok := strings.Cut(header, ":"); ok {
+			parsed[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return parsed
+}
+
+// checkWritable creates dir if needed and checks that files can be created in it
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("can't create %s: %w", dir, err)
+	}
+
+	file, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return nil
+}
+
+// checkDir checks that a path is an absolute, existing directory
+func checkDir(path string) error {
+	if !filepath.IsAbs(path) {
+		return fmt.Errorf("%s must be an absolute path", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%s does not exist", path)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	return nil
+}
+
+// checkBinary checks that an absolute binary path is an executable file. Bare names
+// are looked up in PATH when they are run and are not checked here.
+func checkBinary(path string) error {
+	if path == "" {
+		return fmt.Errorf("must be set")
+	}
+	if !filepath.IsAbs(path) {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%s does not exist", path)
+	}
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return fmt.Errorf("%s is not an executable file", path)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func validConfig(t *testing.T) *Config {
+	return &Config{
+		Server:  ServerConfig{Port: 8080, MaxUploadSize: 1 << 30, CorsOrigins: []string{"*", "https://example.com:8443"}},
+		Storage: StorageConfig{BasePath: t.TempDir(), AutoCleanup: true, CleanupAfterDays: 7},
+		FFmpeg:  FFmpegConfig{Path: "ffmpeg", MaxParallel: 2},
+		YtDlp:   YtDlpConfig{Path: "yt-dlp", MaxQuality: "1080p"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig(t).Validate(); err != nil {
+		t.Fatalf("Validate() of a valid config = %v", err)
+	}
+
+	cfg := validConfig(t)
+	cfg.Server.Port = 70000
+	cfg.Server.CorsOrigins = []string{"example.com", "https://example.com/"}
+	cfg.FFmpeg.Path = "/nonexistent/ffmpeg"
+	cfg.FFmpeg.MaxParallel = 0
+	cfg.FFmpeg.HWAccel = "cuda"
+	cfg.FFmpeg.MaxProcesses = -1
+	cfg.FFmpeg.Nice = 20
+	cfg.FFmpeg.CPULimit = 200
+	cfg.Storage.DownloadNaming = "title"
+	cfg.Storage.Metadata = "sqlite"
+	cfg.Storage.Media = "s3"
+	cfg.YtDlp.MaxQuality = "hd"
+	cfg.Download.Impersonate = "Chrome 120"
+	cfg.Download.Headers = []string{"Referer"}
+	cfg.Download.CookiesFromBrowser = "netscape"
+	cfg.Download.RateLimit = "fast"
+	cfg.Download.MaxConcurrent = -1
+	cfg.Download.MaxRetries = -1
+	cfg.Import.Paths = []string{"media"}
+	cfg.Import.WatchDir = "/nonexistent/watch"
+	cfg.S3.Endpoint = "minio:9000"
+	cfg.S3.AccessKeyID = "AKIAEXAMPLE"
+	cfg.S3.DownloadBuckets = []string{"Footage"}
+	cfg.Remote.SSHKeyFile = "/nonexistent/id_ed25519"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() of an invalid config: want error")
+	}
+
+	// Every problem is reported, not just the first
+	for _, key := range []string{"server.port", "example.com", "https://example.com/", "ffmpeg.path", "storage.download_naming", "storage.metadata", "storage.media_bucket", "ffmpeg.max_parallel", "ffmpeg.max_processes", "ffmpeg.hwaccel", "ffmpeg.nice", "ffmpeg.cgroup_path", "ytdlp.max_quality", "download.impersonate", "download.headers", "download.cookies_from_browser", "download.rate_limit", "download.max_concurrent", "download.max_retries", "import.paths", "import.watch_dir", "s3.endpoint", "s3.access_key_id", "s3.download_buckets", "remote.ssh_key_file", "remote.ssh_key_hosts"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("Validate() error does not mention %s:\n%v", key, err)
+		}
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	tests := map[string]int64{
+		"":     0,
+		"1000": 1000,
+		"500K": 500 << 10,
+		"4.5m": 4718592,
+		"1G":   1 << 30,
+	}
+	for limit, want := range tests {
+		if got, err := ParseRateLimit(limit); err != nil || got != want {
+			t.Errorf("ParseRateLimit(%q) = %d, %v, want %d", limit, got, err, want)
+		}
+	}
+	for _, limit := range []string{"fast", "-5M", "0", "M"} {
+		if _, err := ParseRateLimit(limit); err == nil {
+			t.Errorf("ParseRateLimit(%q): want error", limit)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// advancedOption describes an FFmpeg output option that any user may pass through
+type advancedOption struct {
+	perStream bool           // Takes a stream specifier, e.g. "-tag:v"
+	value     *regexp.Regexp // Allowed values, nil for options without a value
+	filter    bool           // The value is a filter graph, checked by validateFilterGraph
+}
+
+var (
+	fourCCValue = regexp.MustCompile(`^[A-Za-z0-9 ]{4}$`)
+	nameValue   = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+	intValue    = regexp.MustCompile(`^[0-9]{1,9}$`)
+)
+
+// advancedOptions are the output options allowed in an export's advanced_options.
+// They only change how streams are encoded, tagged or muxed; unlike extra_args
+// they can't name files, so they don't need admin access.
+var advancedOptions = map[string]advancedOption{
+	// Codec tags and brands, e.g. hvc1 for HEVC in MP4 on Apple devices
+	"-tag":   {perStream: true, value: fourCCValue},
+	"-brand": {value: fourCCValue},
+
+	// Color signalling
+	"-color_primaries": {value: nameValue},
+	"-color_trc":       {value: nameValue},
+	"-colorspace":      {value: nameValue},
+	"-color_range":     {value: nameValue},
+
+	// Encoder settings
+	"-profile": {perStream: true, value: nameValue},
+	"-level":   {value: regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)},
+	"-tune":    {value: nameValue},
+	"-g":       {value: intValue},
+	"-bf":      {value: intValue},
+	"-aspect":  {value: regexp.MustCompile(`^[0-9]+([:/][0-9]+|\.[0-9]+)?$`)},
+	"-strict":  {value: regexp.MustCompile(`^(very|strict|normal|unofficial|experimental|-?[0-2])$`)},
+
+	// Muxing
+	"-movflags":              {value: regexp.MustCompile(`^([+-]?[a-z_]+)+$`)},
+	"-bsf":                   {perStream: true, value: regexp.MustCompile(`^[a-z0-9_]+(=[A-Za-z0-9_.:=]+)?(,[a-z0-9_]+(=[A-Za-z0-9_.:=]+)?)*$`)},
+	"-disposition":           {perStream: true, value: regexp.MustCompile(`^(0|[+-]?[a-z_]+([+-][a-z_]+)*)$`)},
+	"-avoid_negative_ts":     {value: regexp.MustCompile(`^(auto|disabled|make_zero|make_non_negative)$`)},
+	"-max_muxing_queue_size": {value: intValue},
+	"-video_track_timescale": {value: intValue},
+	"-write_tmcd":            {value: regexp.MustCompile(`^[01]$`)},
+	"-shortest":              {},
+}
+
+// streamSpecifier matches the stream specifiers allowed on per-stream options
+var streamSpecifier = regexp.MustCompile(`^[vas](:[0-9]+)?$`)
+
+// AdvancedOptionNames returns the allowed advanced options, sorted
+func AdvancedOptionNames() []string {
+	return optionNames(advancedOptions)
+}
+
+func optionNames(options map[string]advancedOption) []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateAdvancedOptions checks options against the advanced options allowlist.
+// Valid options can be added to a command with WithExtraArgs.
+func ValidateAdvancedOptions(args []string) error {
+	return validateOptions(args, advancedOptions)
+}
+
+// validateOptions checks that args are only options of an allowlist, each followed
+// by a valid value if it takes one. Any other token, such as a positional file name
+// FFmpeg would take as another output, is rejected.
+func validateOptions(args []string, options map[string]advancedOption) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		name, specifier, _ := strings.Cut(arg, ":")
+		option, ok := options[name]
+		if !ok {
+			return fmt.Errorf("option not allowed: %s (allowed: %s)", arg, strings.Join(optionNames(options), " "))
+		}
+		if specifier != "" && (!option.perStream || !streamSpecifier.MatchString(specifier)) {
+			return fmt.Errorf("invalid stream specifier: %s", arg)
+		}
+
+		if option.value == nil && !option.filter {
+			continue
+		}
+		i++
+		if i == len(args) {
+			return fmt.Errorf("option %s needs a value", arg)
+		}
+		if option.filter {
+			if err := validateFilterGraph(args[i]); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", arg, err)
+			}
+			continue
+		}
+		if !option.value.MatchString(args[i]) {
+			return fmt.Errorf("invalid value for %s: %q", arg, args[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package ffmpeg
+
+import "testing"
+
+func TestValidateAdvancedOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"hevc tag", []string{"-tag:v", "hvc1"}, false},
+		{"color", []string{"-color_primaries", "bt709", "-color_trc", "bt709", "-colorspace", "bt709"}, false},
+		{"movflags", []string{"-movflags", "+faststart+frag_keyframe"}, false},
+		{"bitstream filter", []string{"-bsf:v", "h264_metadata=level=4.1"}, false},
+		{"per stream index", []string{"-disposition:a:1", "default"}, false},
+		{"flag without value", []string{"-shortest", "-g", "48"}, false},
+		{"empty", nil, false},
+		{"input", []string{"-i", "/etc/passwd"}, true},
+		{"unknown option", []string{"-filter_complex", "[0:v]null"}, true},
+		{"missing value", []string{"-tag:v"}, true},
+		{"bad fourcc", []string{"-tag:v", "hvc1x"}, true},
+		{"path value", []string{"-profile:v", "../high"}, true},
+		{"specifier on global option", []string{"-movflags:v", "+faststart"}, true},
+		{"bad specifier", []string{"-tag:d", "tmcd"}, true},
+		{"bare value", []string{"hvc1"}, true},
+	}
+	for _, tt := range tests {
+		err := ValidateAdvancedOptions(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateAdvancedOptions(%v) error = %v, wantErr %v", tt.name, tt.args, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const subtitleBurnKey contextKey = "ffmpeg-subtitle-burn"
+
+// SubtitleBurn selects the subtitles burned into re-encoded video
+type SubtitleBurn struct {
+	File  string // Subtitle file, or a media file with subtitle streams
+	Index int    // Index among the subtitle streams of File, 0 for subtitle files
+}
+
+// WithSubtitleBurn returns a context whose re-encoded segments get the subtitles
+// burned into the picture, for players that don't show soft subtitles
+func WithSubtitleBurn(ctx context.Context, burn SubtitleBurn) context.Context {
+	return context.WithValue(ctx, subtitleBurnKey, burn)
+}
+
+// contextSubtitleBurn returns the subtitles to burn set with WithSubtitleBurn, if any
+func contextSubtitleBurn(ctx context.Context) *SubtitleBurn {
+	if burn, ok := ctx.Value(subtitleBurnKey).(SubtitleBurn); ok {
+		return &burn
+	}
+	return nil
+}
+
+// subtitlesFilter returns a filter chain burning in the subtitles. A segment's frames
+// are timestamped from 0, so they are moved to their source time while the subtitles
+// are drawn and back afterwards.
+func subtitlesFilter(burn SubtitleBurn, start float64) string {
+	return fmt.Sprintf("setpts=PTS+%.6f/TB,subtitles=filename=%s:si=%d,setpts=PTS-STARTPTS",
+		start, escapeFilterValue(burn.File), burn.Index)
+}
+
+// escapeFilterValue escapes a filter option value, e.g. a file path, for use in a
+// filtergraph: once for the option parser and once for the graph parser
+func escapeFilterValue(value string) string {
+	option := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(value)
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(option)
+}
@@ -0,0 +1,71 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+)
+
+// cancelGracePeriod is how long a cancelled FFmpeg gets to finish after SIGINT before
+// it is killed
+const cancelGracePeriod = 5 * time.Second
+
+// process is an FFmpeg command run by the executor, cancel stops it
+type process struct {
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+}
+
+// gracefulCancel makes a command's context cancellation send SIGINT, so FFmpeg can
+// stop cleanly, and kill it if it is still running after cancelGracePeriod
+func gracefulCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		// Windows has no SIGINT for other processes, kill right away there
+		if err := cmd.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = cancelGracePeriod
+}
+
+// Processes returns the IDs of the running FFmpeg processes
+func (e *Executor) Processes() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ids := make([]string, 0, len(e.processes))
+	for id := range e.processes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Cancel stops a running FFmpeg process: it is interrupted, then killed if it doesn't
+// exit within a few seconds. The command running it returns a context.Canceled error.
+func (e *Executor) Cancel(processID string) error {
+	e.mu.Lock()
+	p, exists := e.processes[processID]
+	e.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("process not found: %s", processID)
+	}
+	p.cancel()
+	return nil
+}
+
+// CancelAll stops every running FFmpeg process, e.g. on shutdown
+func (e *Executor) CancelAll() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, p := range e.processes {
+		p.cancel()
+	}
+}
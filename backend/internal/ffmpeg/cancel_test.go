@@ -0,0 +1,77 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeFFmpeg writes a script that runs until it is interrupted (sleep exits on SIGINT)
+func fakeFFmpeg(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nexec sleep 30\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExecutorCancel(t *testing.T) {
+	e := NewExecutor(fakeFFmpeg(t), "ffprobe", zap.NewNop())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- e.Execute(context.Background(), ExecuteOptions{Args: []string{"-i", "in.mp4", "out.mp4"}})
+	}()
+
+	var processes []string
+	for deadline := time.Now().Add(5 * time.Second); len(processes) == 0; processes = e.Processes() {
+		if time.Now().After(deadline) {
+			t.Fatal("process didn't start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := e.Cancel(processes[0]); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Execute() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(cancelGracePeriod + 5*time.Second):
+		t.Fatal("cancelled process kept running")
+	}
+
+	if err := e.Cancel(processes[0]); err == nil {
+		t.Error("Cancel() of a finished process succeeded")
+	}
+}
+
+func TestRecordOutput(t *testing.T) {
+	var outputs []string
+	ctx := WithOutputRecorder(context.Background(), func(path string) {
+		outputs = append(outputs, path)
+	})
+
+	recordOutput(ctx, []string{"-i", "in.mp4", "out.mp4"})
+	recordOutput(ctx, []string{"-i", "in.mp4", "-f", "null", "-"})
+	recordOutput(ctx, []string{"-i", "in.mp4", "-f", "mp4", "pipe:1"})
+	recordOutput(context.Background(), []string{"-i", "in.mp4", "other.mp4"})
+
+	if want := []string{"out.mp4"}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("recorded outputs = %q, want %q", outputs, want)
+	}
+}
@@ -0,0 +1,210 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Codec is an encoder or decoder of the installed FFmpeg build
+type Codec struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // "video", "audio", "subtitle" or "data"
+	Description string `json:"description"`
+}
+
+// Capabilities describes what the installed FFmpeg build can do
+type Capabilities struct {
+	FFmpegVersion  string   `json:"ffmpeg_version"`
+	FFprobeVersion string   `json:"ffprobe_version"`
+	Encoders       []Codec  `json:"encoders"`
+	Decoders       []Codec  `json:"decoders"`
+	HWAccels       []string `json:"hwaccels"`
+	Filters        []string `json:"filters"`
+}
+
+// HasEncoder reports whether the build includes an encoder, e.g. "libx264"
+func (c *Capabilities) HasEncoder(name string) bool {
+	return hasCodec(c.Encoders, name)
+}
+
+// HasDecoder reports whether the build includes a decoder
+func (c *Capabilities) HasDecoder(name string) bool {
+	return hasCodec(c.Decoders, name)
+}
+
+// HasFilter reports whether the build includes a filter, e.g. "libvmaf"
+func (c *Capabilities) HasFilter(name string) bool {
+	i := sort.SearchStrings(c.Filters, name)
+	return i < len(c.Filters) && c.Filters[i] == name
+}
+
+// CheckEncoders returns an error when the build lacks the encoder of a video codec
+// or audio codec name, e.g. "libx264" and "mp3". Empty codecs aren't checked, and
+// codec names of the hardware families such as "hevc" leave the encoder to FFmpeg.
+func (c *Capabilities) CheckEncoders(videoCodec, audioCodec string) error {
+	if videoCodec != "" && !c.HasEncoder(videoCodec) {
+		if _, family := hwCodecFamilies[videoCodec]; !family || strings.HasPrefix(videoCodec, "lib") {
+			return fmt.Errorf("FFmpeg has no %s encoder", videoCodec)
+		}
+	}
+	if encoder := audioEncoders[audioCodec]; audioCodec != "" && audioCodec != "copy" && !c.HasEncoder(encoder) {
+		return fmt.Errorf("FFmpeg has no %s encoder for audio codec %s", encoder, audioCodec)
+	}
+	return nil
+}
+
+func hasCodec(codecs []Codec, name string) bool {
+	for _, codec := range codecs {
+		if codec.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Capabilities returns what the installed FFmpeg build can do. The result is
+// discovered once and cached, refresh discovers it again, e.g. after an upgrade.
+func (e *Executor) Capabilities(ctx context.Context, refresh bool) (*Capabilities, error) {
+	e.capsMu.Lock()
+	defer e.capsMu.Unlock()
+
+	if e.caps != nil && !refresh {
+		return e.caps, nil
+	}
+
+	caps, err := e.DiscoverCapabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.caps = caps
+	return caps, nil
+}
+
+// DiscoverCapabilities queries the versions, codecs, hardware acceleration methods
+// and filters of the installed ffmpeg and ffprobe
+func (e *Executor) DiscoverCapabilities(ctx context.Context) (*Capabilities, error) {
+	run := func(path string, args ...string) (string, error) {
+		output, err := e.probeOutput(ctx, exec.CommandContext(ctx, path, append([]string{"-hide_banner"}, args...)...))
+		if err != nil {
+			return "", fmt.Errorf("%s %s failed: %w", path, strings.Join(args, " "), err)
+		}
+		return string(output), nil
+	}
+
+	caps := &Capabilities{}
+
+	output, err := run(e.ffmpegPath, "-version")
+	if err != nil {
+		return nil, err
+	}
+	caps.FFmpegVersion = parseVersion(output)
+
+	if output, err = run(e.ffprobePath, "-version"); err != nil {
+		return nil, err
+	}
+	caps.FFprobeVersion = parseVersion(output)
+
+	if output, err = run(e.ffmpegPath, "-encoders"); err != nil {
+		return nil, err
+	}
+	caps.Encoders = parseCodecList(output)
+
+	if output, err = run(e.ffmpegPath, "-decoders"); err != nil {
+		return nil, err
+	}
+	caps.Decoders = parseCodecList(output)
+
+	if output, err = run(e.ffmpegPath, "-hwaccels"); err != nil {
+		return nil, err
+	}
+	caps.HWAccels = parseHWAccels(output)
+
+	if output, err = run(e.ffmpegPath, "-filters"); err != nil {
+		return nil, err
+	}
+	caps.Filters = parseFilters(output)
+
+	return caps, nil
+}
+
+// parseVersion returns the version from the first line of -version output,
+// e.g. "6.1.1-3ubuntu5" from "ffmpeg version 6.1.1-3ubuntu5 Copyright (c) ..."
+func parseVersion(output string) string {
+	line, _, _ := strings.Cut(output, "\n")
+	fields := strings.Fields(line)
+	for i, field := range fields {
+		if field == "version" && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+// codecTypes maps the first capability flag of -encoders/-decoders to a codec type
+var codecTypes = map[byte]string{
+	'V': "video",
+	'A': "audio",
+	'S': "subtitle",
+	'D': "data",
+}
+
+// parseCodecList parses the output of ffmpeg -encoders or -decoders: a legend, a
+// "------" line, then one "<flags> <name> <description>" line per codec
+func parseCodecList(output string) []Codec {
+	codecs := []Codec{}
+	inList := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !inList {
+			inList = strings.HasPrefix(line, "---")
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields[0]) != 6 {
+			continue
+		}
+		codecType, ok := codecTypes[fields[0][0]]
+		if !ok {
+			continue
+		}
+		codecs = append(codecs, Codec{
+			Name:        fields[1],
+			Type:        codecType,
+			Description: strings.Join(fields[2:], " "),
+		})
+	}
+	return codecs
+}
+
+// parseHWAccels parses the output of ffmpeg -hwaccels: a header line, then one
+// method per line
+func parseHWAccels(output string) []string {
+	hwaccels := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasSuffix(line, ":") {
+			continue
+		}
+		hwaccels = append(hwaccels, line)
+	}
+	return hwaccels
+}
+
+// parseFilters parses the output of ffmpeg -filters, whose lines after the legend
+// read "<flags> <name> <inputs>-><outputs> <description>". The names are sorted.
+func parseFilters(output string) []string {
+	filters := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.Contains(fields[2], "->") {
+			continue
+		}
+		filters = append(filters, fields[1])
+	}
+	sort.Strings(filters)
+	return filters
+}
@@ -0,0 +1,86 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	if got := parseVersion("ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc 13\n"); got != "6.1.1-3ubuntu5" {
+		t.Errorf("parseVersion() = %q", got)
+	}
+
+	encoders := parseCodecList(`Encoders:
+ V..... = Video
+ A..... = Audio
+ S..... = Subtitle
+ .F.... = Frame-level multithreading
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D h264_nvenc           NVIDIA NVENC H.264 encoder (codec h264)
+ A....D aac                  AAC (Advanced Audio Coding)
+ S..... srt                  SubRip subtitle (codec subrip)
+`)
+	wantEncoders := []Codec{
+		{Name: "libx264", Type: "video", Description: "libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)"},
+		{Name: "h264_nvenc", Type: "video", Description: "NVIDIA NVENC H.264 encoder (codec h264)"},
+		{Name: "aac", Type: "audio", Description: "AAC (Advanced Audio Coding)"},
+		{Name: "srt", Type: "subtitle", Description: "SubRip subtitle (codec subrip)"},
+	}
+	if !reflect.DeepEqual(encoders, wantEncoders) {
+		t.Errorf("parseCodecList() = %+v, want %+v", encoders, wantEncoders)
+	}
+
+	hwaccels := parseHWAccels("Hardware acceleration methods:\nvdpau\ncuda\nvaapi\n\n")
+	if !reflect.DeepEqual(hwaccels, []string{"vdpau", "cuda", "vaapi"}) {
+		t.Errorf("parseHWAccels() = %q", hwaccels)
+	}
+
+	filters := parseFilters(`Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  ..C = Command support
+  A = Audio input/output
+  | = Source or sink filter
+ ..C scale             V->V       Scale the input video size and/or convert the image format.
+ TSC loudnorm          A->A       EBU R128 loudness normalization
+ ... abuffer           |->A       Buffer audio frames, and make them accessible to the filterchain.
+`)
+	if !reflect.DeepEqual(filters, []string{"abuffer", "loudnorm", "scale"}) {
+		t.Errorf("parseFilters() = %q", filters)
+	}
+
+	caps := &Capabilities{Encoders: encoders, Filters: filters}
+	if !caps.HasEncoder("h264_nvenc") || caps.HasEncoder("libx265") {
+		t.Error("HasEncoder() does not match the parsed encoders")
+	}
+	if !caps.HasFilter("loudnorm") || caps.HasFilter("libvmaf") {
+		t.Error("HasFilter() does not match the parsed filters")
+	}
+}
+
+func TestCheckEncoders(t *testing.T) {
+	caps := &Capabilities{Encoders: []Codec{
+		{Name: "libx264", Type: "video"},
+		{Name: "prores_ks", Type: "video"},
+		{Name: "aac", Type: "audio"},
+	}}
+
+	tests := []struct {
+		video, audio string
+		wantErr      bool
+	}{
+		{"libx264", "aac", false},
+		{"prores_ks", "copy", false},
+		{"hevc", "", false},
+		{"", "aac", false},
+		{"libx265", "aac", true},
+		{"libsvtav1", "", true},
+		{"libx264", "mp3", true},
+	}
+	for _, tt := range tests {
+		if err := caps.CheckEncoders(tt.video, tt.audio); (err != nil) != tt.wantErr {
+			t.Errorf("CheckEncoders(%q, %q) error = %v, wantErr %v", tt.video, tt.audio, err, tt.wantErr)
+		}
+	}
+}
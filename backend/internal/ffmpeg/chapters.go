@@ -0,0 +1,56 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// OutputChapter is a chapter to write into an output, in output time
+type OutputChapter struct {
+	Start float64
+	End   float64
+	Title string
+}
+
+// ffmetadataEscaper escapes the characters FFMETADATA files treat specially
+var ffmetadataEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+
+// formatFFMetadataChapters renders chapters as an FFMETADATA file, with
+// millisecond precision
+func formatFFMetadataChapters(chapters []OutputChapter) string {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for _, chapter := range chapters {
+		fmt.Fprintf(&b, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			int64(chapter.Start*1000+0.5), int64(chapter.End*1000+0.5), ffmetadataEscaper.Replace(chapter.Title))
+	}
+	return b.String()
+}
+
+// chaptersInput writes chapters to an FFMETADATA file next to output, to be added as
+// an input. It returns the path and a cleanup func, or "" when there is nothing to
+// write: no chapters, or privacy mode, which drops chapters.
+func chaptersInput(ctx context.Context, output string, chapters []OutputChapter) (string, func(), error) {
+	if len(chapters) == 0 || privacyMode(ctx) {
+		return "", func() {}, nil
+	}
+
+	path := output + ".chapters.txt"
+	if err := os.WriteFile(path, []byte(formatFFMetadataChapters(chapters)), 0644); err != nil {
+		return "", nil, fmt.Errorf("failed to write chapters file: %w", err)
+	}
+	return path, func() { os.Remove(path) }, nil
+}
+
+// withChaptersFrom points the -map_chapters option of metadata args at input
+func withChaptersFrom(args []string, input int) []string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == "-map_chapters" {
+			args[i+1] = fmt.Sprint(input)
+			return args
+		}
+	}
+	return append(args, "-map_chapters", fmt.Sprint(input))
+}
@@ -0,0 +1,27 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormatFFMetadataChapters(t *testing.T) {
+	got := formatFFMetadataChapters([]OutputChapter{
+		{Start: 0, End: 12.5, Title: "Intro"},
+		{Start: 12.5, End: 30.0004, Title: "Q&A; a=b #1"},
+	})
+
+	want := ";FFMETADATA1\n" +
+		"\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=12500\ntitle=Intro\n" +
+		"\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=12500\nEND=30000\ntitle=Q&A\\; a\\=b \\#1\n"
+	if got != want {
+		t.Errorf("formatFFMetadataChapters() = %q, want %q", got, want)
+	}
+}
+
+func TestWithChaptersFrom(t *testing.T) {
+	got := withChaptersFrom([]string{"-map_metadata", "1", "-map_chapters", "-1"}, 2)
+	if want := []string{"-map_metadata", "1", "-map_chapters", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("withChaptersFrom() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package ffmpeg
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// mp4Codecs are the codecs MP4 holds in a standard way, by stream type. Opus, VP9,
+// FLAC and PCM are technically muxable but unsupported by most MP4 players.
+var mp4Codecs = map[string]map[string]bool{
+	"video": {
+		"h264": true, "hevc": true, "av1": true, "mpeg4": true, "mpeg2video": true,
+		"mjpeg": true, "png": true, // Cover art
+	},
+	"audio": {
+		"aac": true, "mp3": true, "ac3": true, "eac3": true, "alac": true, "mp2": true,
+	},
+	"subtitle": {
+		"mov_text": true,
+	},
+}
+
+// OutputProfile describes how outputs in a container are written
+type OutputProfile struct {
+	Format      string                     // Canonical format name, also the file extension
+	Muxer       string                     // FFmpeg muxer
+	MovFlags    bool                       // MP4-family muxer, gets -movflags such as +faststart
+	Attachments bool                       // Can store attachment streams, e.g. MKV fonts
+	DataStreams bool                       // Keeps data streams such as timecode tracks
+	MuxArgs     []string                   // Additional muxer options
+	Codecs      map[string]map[string]bool // Codecs that can be copied in by stream type, nil = any
+}
+
+// outputProfiles are the containers video outputs can be written in
+var outputProfiles = map[string]OutputProfile{
+	"mp4": {Format: "mp4", Muxer: "mp4", MovFlags: true, DataStreams: true, Codecs: mp4Codecs},
+	"m4v": {Format: "m4v", Muxer: "mp4", MovFlags: true, DataStreams: true, Codecs: mp4Codecs},
+	"mov": {Format: "mov", Muxer: "mov", MovFlags: true, DataStreams: true, Codecs: map[string]map[string]bool{
+		"video": unionCodecs(mp4Codecs["video"], map[string]bool{
+			"prores": true, "dnxhd": true, "rawvideo": true, "qtrle": true, "dvvideo": true,
+		}),
+		"audio": unionCodecs(mp4Codecs["audio"], map[string]bool{
+			"pcm_s16le": true, "pcm_s16be": true, "pcm_s24le": true, "pcm_s24be": true,
+			"pcm_s32le": true, "pcm_f32le": true,
+		}),
+		"subtitle": mp4Codecs["subtitle"],
+	}},
+	"mkv": {Format: "mkv", Muxer: "matroska", Attachments: true},
+	"webm": {Format: "webm", Muxer: "webm", Codecs: map[string]map[string]bool{
+		"video":    {"vp8": true, "vp9": true, "av1": true},
+		"audio":    {"opus": true, "vorbis": true},
+		"subtitle": {"webvtt": true},
+	}},
+	// Broadcast and Avid interchange, used by the mezzanine export presets
+	"mxf": {Format: "mxf", Muxer: "mxf", DataStreams: true, Codecs: map[string]map[string]bool{
+		"video": {"dnxhd": true, "prores": true, "mpeg2video": true, "h264": true},
+		"audio": {"pcm_s16le": true, "pcm_s24le": true},
+	}},
+	"ts": {Format: "ts", Muxer: "mpegts", Codecs: tsCodecs},
+	// Blu-ray style transport stream with 4-byte timestamp headers
+	"m2ts": {Format: "m2ts", Muxer: "mpegts", MuxArgs: []string{"-mpegts_m2ts_mode", "1"}, Codecs: tsCodecs},
+}
+
+// tsCodecs are the codecs the MPEG-TS muxer can carry
+var tsCodecs = map[string]map[string]bool{
+	"video": {
+		"h264": true, "hevc": true, "mpeg2video": true, "mpeg1video": true, "mpeg4": true,
+	},
+	"audio": {
+		"aac": true, "mp3": true, "mp2": true, "ac3": true, "eac3": true, "opus": true, "dts": true,
+	},
+	"subtitle": {
+		"dvb_subtitle": true, "dvb_teletext": true,
+	},
+}
+
+// formatAliases maps muxer names accepted as formats to their canonical format
+var formatAliases = map[string]string{
+	"matroska":  "mkv",
+	"mpegts":    "ts",
+	"quicktime": "mov",
+}
+
+// GetOutputProfile returns the profile of a video output format such as "mp4" or
+// "matroska", and whether it is supported
+func GetOutputProfile(format string) (OutputProfile, bool) {
+	format = strings.ToLower(format)
+	if canonical, ok := formatAliases[format]; ok {
+		format = canonical
+	}
+	profile, ok := outputProfiles[format]
+	return profile, ok
+}
+
+// OutputFormats returns the supported video output formats
+func OutputFormats() []string {
+	formats := make([]string, 0, len(outputProfiles))
+	for format := range outputProfiles {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// outputProfileFor returns the profile matching an output file's extension. Other
+// files, e.g. audio-only outputs, get an empty profile without muxer options.
+func outputProfileFor(output string) OutputProfile {
+	profile, _ := GetOutputProfile(strings.TrimPrefix(filepath.Ext(output), "."))
+	return profile
+}
+
+// muxArgs returns the muxer options for an output file: -movflags for MP4-family
+// containers and the profile's own options
+func muxArgs(ctx context.Context, output string) []string {
+	profile := outputProfileFor(output)
+
+	var args []string
+	if profile.MovFlags {
+		args = append(args, "-movflags", movFlags(ctx))
+	}
+	return append(args, profile.MuxArgs...)
+}
+
+// unionCodecs returns the union of codec sets
+func unionCodecs(sets ...map[string]bool) map[string]bool {
+	result := make(map[string]bool)
+	for _, set := range sets {
+		for codec := range set {
+			result[codec] = true
+		}
+	}
+	return result
+}
+
+// ContainerSupports reports whether a stream of the given type and codec can be
+// copied into the container. Data and attachment streams are not checked, and
+// unknown containers are assumed to hold anything.
+func ContainerSupports(format, codecType, codec string) bool {
+	profile, ok := GetOutputProfile(format)
+	if !ok || profile.Codecs == nil {
+		return true
+	}
+
+	allowed, ok := profile.Codecs[codecType]
+	return !ok || allowed[codec]
+}
+
+// remuxArgs returns the stream selection and muxer arguments for copying every
+// stream of input 0 into the container. MPEG-TS keeps the source timestamps, and
+// data streams (e.g. timecode tracks) are only kept by containers that hold them.
+func remuxArgs(ctx context.Context, format, output string) []string {
+	profile, _ := GetOutputProfile(format)
+
+	args := []string{"-map", "0"}
+	args = append(args, attachmentArgs(ctx, 0, output, true)...)
+	if !profile.DataStreams {
+		args = append(args, "-map", "-0:d?")
+	}
+	if profile.Muxer == "mpegts" {
+		args = append(args, "-copyts")
+	}
+	args = append(args, muxArgs(ctx, output)...)
+
+	return append(args, "-f", profile.Muxer)
+}
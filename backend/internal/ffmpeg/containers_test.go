@@ -0,0 +1,56 @@
+package ffmpeg
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestGetOutputProfile(t *testing.T) {
+	profile, ok := GetOutputProfile("Matroska")
+	if !ok || profile.Format != "mkv" || !profile.Attachments {
+		t.Errorf("matroska: profile = %+v, ok = %v", profile, ok)
+	}
+	if _, ok := GetOutputProfile("avi"); ok {
+		t.Error("expected avi to be unsupported")
+	}
+
+	if !ContainerSupports("mpegts", "video", "hevc") || ContainerSupports("ts", "subtitle", "subrip") {
+		t.Error("ts codec checks don't use the ts profile")
+	}
+}
+
+func TestMuxArgs(t *testing.T) {
+	ctx := context.Background()
+
+	tests := map[string]string{
+		"out.mp4":  "-movflags +faststart+use_metadata_tags",
+		"out.MOV":  "-movflags +faststart+use_metadata_tags",
+		"out.mkv":  "",
+		"out.ts":   "",
+		"out.m2ts": "-mpegts_m2ts_mode 1",
+		"out.flac": "",
+	}
+	for output, want := range tests {
+		if got := strings.Join(muxArgs(ctx, output), " "); got != want {
+			t.Errorf("muxArgs(%s) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestRemuxArgs(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		format, output, want string
+	}{
+		{"mp4", "out.mp4", "-map 0 -map -0:t -movflags +faststart+use_metadata_tags -f mp4"},
+		{"mkv", "out.mkv", "-map 0 -map -0:d? -f matroska"},
+		{"ts", "out.ts", "-map 0 -map -0:t -map -0:d? -copyts -f mpegts"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(remuxArgs(ctx, tt.format, tt.output), " "); got != tt.want {
+			t.Errorf("remuxArgs(%s) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
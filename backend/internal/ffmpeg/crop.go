@@ -0,0 +1,36 @@
+package ffmpeg
+
+import "fmt"
+
+// CropRect is a rectangle of the source picture to keep, in pixels
+type CropRect struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+// Validate checks that the rectangle is non-empty, starts inside the picture and has
+// even dimensions, which 4:2:0 pixel formats require
+func (r CropRect) Validate() error {
+	if r.Width <= 0 || r.Height <= 0 {
+		return fmt.Errorf("crop width and height must be positive")
+	}
+	if r.X < 0 || r.Y < 0 {
+		return fmt.Errorf("crop x and y must not be negative")
+	}
+	if r.Width%2 != 0 || r.Height%2 != 0 {
+		return fmt.Errorf("crop width and height must be even")
+	}
+	return nil
+}
+
+// Fits reports whether the rectangle lies within a width x height picture
+func (r CropRect) Fits(width, height int) bool {
+	return r.X+r.Width <= width && r.Y+r.Height <= height
+}
+
+// filter returns the crop filter keeping the rectangle
+func (r CropRect) filter() string {
+	return fmt.Sprintf("crop=%d:%d:%d:%d", r.Width, r.Height, r.X, r.Y)
+}
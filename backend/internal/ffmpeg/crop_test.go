@@ -0,0 +1,41 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCropRectValidate(t *testing.T) {
+	if err := (CropRect{X: 0, Y: 140, Width: 1920, Height: 800}).Validate(); err != nil {
+		t.Errorf("letterbox crop: %v", err)
+	}
+	for _, r := range []CropRect{
+		{Width: 0, Height: 800},
+		{X: -1, Width: 1920, Height: 800},
+		{Width: 1919, Height: 800},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", r)
+		}
+	}
+
+	if !(CropRect{Y: 140, Width: 1920, Height: 800}).Fits(1920, 1080) {
+		t.Error("expected crop to fit 1920x1080")
+	}
+	if (CropRect{Y: 300, Width: 1920, Height: 800}).Fits(1920, 1080) {
+		t.Error("expected crop below the picture not to fit")
+	}
+}
+
+func TestEncodeOptionsCropBeforeScale(t *testing.T) {
+	opts := EncodeOptions{Crop: &CropRect{Y: 140, Width: 1920, Height: 800}, AspectRatio: "1:1", Width: 720}
+
+	want := []string{
+		"crop=1920:800:0:140",
+		"crop=w='trunc(min(iw,ih*1/1)/2)*2':h='trunc(min(ih,iw*1/1)/2)*2'",
+		"scale=720:-2",
+	}
+	if got := opts.videoFilters(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("videoFilters() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// xfadeTransitions are the xfade transitions offered for crossfade merges
+var xfadeTransitions = map[string]bool{
+	"fade":        true,
+	"fadeblack":   true,
+	"fadewhite":   true,
+	"dissolve":    true,
+	"wipeleft":    true,
+	"wiperight":   true,
+	"wipeup":      true,
+	"wipedown":    true,
+	"slideleft":   true,
+	"slideright":  true,
+	"slideup":     true,
+	"slidedown":   true,
+	"circleopen":  true,
+	"circleclose": true,
+	"radial":      true,
+	"smoothleft":  true,
+	"smoothright": true,
+}
+
+// ValidCrossfadeTransition reports whether a crossfade transition is supported
+func ValidCrossfadeTransition(transition string) bool {
+	return transition == "" || xfadeTransitions[transition]
+}
+
+// CrossfadeOptions contains options for merging time ranges of one input with crossfades
+type CrossfadeOptions struct {
+	Input      string
+	Output     string
+	Segments   []struct{ Start, End float64 }
+	Duration   float64 // Crossfade length in seconds, must be shorter than every segment
+	Transition string  // xfade transition, default "fade"
+	HasAudio   bool    // Whether the input has an audio stream to crossfade
+	Encode     EncodeOptions
+	Chapters   []OutputChapter // Chapters of the joined output, if any
+	OnProgress ProgressCallback
+}
+
+// CrossfadeSegments cuts the segments from the input and joins them with video and
+// audio crossfades in a single re-encode. Each join overlaps the neighbouring
+// segments, so the output is (len(segments)-1)*Duration shorter than their sum.
+func (e *Executor) CrossfadeSegments(ctx context.Context, opts CrossfadeOptions) error {
+	if len(opts.Segments) < 2 {
+		return fmt.Errorf("crossfade needs at least 2 segments")
+	}
+
+	durations := make([]float64, len(opts.Segments))
+	total := 0.0
+	for i, seg := range opts.Segments {
+		durations[i] = seg.End - seg.Start
+		if durations[i] <= opts.Duration {
+			return fmt.Errorf("segment %d is shorter than the crossfade duration", i)
+		}
+		total += durations[i]
+	}
+	total -= float64(len(opts.Segments)-1) * opts.Duration
+
+	// Timecode overlays, burned subtitles and deinterlacing are per segment and can't
+	// be applied after the joins, and the graph has no watermark input
+	encode := opts.Encode
+	encode.Timecode = false
+	encode.Subtitles = nil
+	encode.Deinterlace = ""
+	encode.Watermark = nil
+
+	codecArgs, err := encode.codecArgs()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-hide_banner"}
+	for _, seg := range opts.Segments {
+		args = append(args,
+			"-ss", fmt.Sprintf("%.6f", seg.Start),
+			"-t", fmt.Sprintf("%.6f", seg.End-seg.Start),
+			"-i", opts.Input,
+		)
+	}
+	chaptersFile, cleanup, err := chaptersInput(ctx, opts.Output, opts.Chapters)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if chaptersFile != "" {
+		args = append(args, "-f", "ffmetadata", "-i", chaptersFile)
+	}
+
+	args = append(args,
+		"-filter_complex", buildCrossfadeFilter(durations, opts.Duration, opts.Transition, opts.HasAudio, encode.videoFilters(0)),
+		"-map", "[vout]",
+	)
+	if opts.HasAudio {
+		args = append(args, "-map", "[aout]")
+	}
+	args = append(args, codecArgs...)
+	// Source chapters don't line up with the joined timeline
+	metaArgs := metadataArgs(ctx, 0, false)
+	if chaptersFile != "" {
+		metaArgs = withChaptersFrom(metaArgs, len(opts.Segments))
+	}
+	args = append(args, metaArgs...)
+	args = append(args, muxArgs(ctx, opts.Output)...)
+	args = append(args,
+		"-y",
+		opts.Output,
+	)
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args:       args,
+		Duration:   total,
+		OnProgress: opts.OnProgress,
+	})
+}
+
+// buildCrossfadeFilter chains xfade (and acrossfade) filters over inputs 0..n-1 with
+// the given durations. Outputs are labelled [vout] and [aout].
+func buildCrossfadeFilter(durations []float64, fade float64, transition string, hasAudio bool, videoFilters []string) string {
+	if transition == "" {
+		transition = "fade"
+	}
+
+	var parts []string
+
+	// xfade needs matching timebases and timestamps starting at zero
+	for i := range durations {
+		parts = append(parts, fmt.Sprintf("[%d:v]settb=AVTB,setpts=PTS-STARTPTS[v%d]", i, i))
+		if hasAudio {
+			parts = append(parts, fmt.Sprintf("[%d:a]asetpts=PTS-STARTPTS[a%d]", i, i))
+		}
+	}
+
+	video, audio := "v0", "a0"
+	offset := 0.0
+	for i := 1; i < len(durations); i++ {
+		// Each transition starts fade seconds before the end of the output so far
+		offset += durations[i-1] - fade
+
+		parts = append(parts, fmt.Sprintf("[%s][v%d]xfade=transition=%s:duration=%g:offset=%g[vx%d]", video, i, transition, fade, offset, i))
+		video = fmt.Sprintf("vx%d", i)
+
+		if hasAudio {
+			parts = append(parts, fmt.Sprintf("[%s][a%d]acrossfade=d=%g[ax%d]", audio, i, fade, i))
+			audio = fmt.Sprintf("ax%d", i)
+		}
+	}
+
+	videoChain := "null"
+	if len(videoFilters) > 0 {
+		videoChain = strings.Join(videoFilters, ",")
+	}
+	parts = append(parts, fmt.Sprintf("[%s]%s[vout]", video, videoChain))
+	if hasAudio {
+		parts = append(parts, fmt.Sprintf("[%s]anull[aout]", audio))
+	}
+
+	return strings.Join(parts, ";")
+}
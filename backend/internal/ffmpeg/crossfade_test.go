@@ -0,0 +1,27 @@
+package ffmpeg
+
+import "testing"
+
+func TestBuildCrossfadeFilter(t *testing.T) {
+	got := buildCrossfadeFilter([]float64{10, 5, 8}, 1, "", true, []string{"scale=1280:-2"})
+	want := "[0:v]settb=AVTB,setpts=PTS-STARTPTS[v0];[0:a]asetpts=PTS-STARTPTS[a0];" +
+		"[1:v]settb=AVTB,setpts=PTS-STARTPTS[v1];[1:a]asetpts=PTS-STARTPTS[a1];" +
+		"[2:v]settb=AVTB,setpts=PTS-STARTPTS[v2];[2:a]asetpts=PTS-STARTPTS[a2];" +
+		"[v0][v1]xfade=transition=fade:duration=1:offset=9[vx1];[a0][a1]acrossfade=d=1[ax1];" +
+		"[vx1][v2]xfade=transition=fade:duration=1:offset=13[vx2];[ax1][a2]acrossfade=d=1[ax2];" +
+		"[vx2]scale=1280:-2[vout];[ax2]anull[aout]"
+
+	if got != want {
+		t.Errorf("buildCrossfadeFilter() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBuildCrossfadeFilterWithoutAudio(t *testing.T) {
+	got := buildCrossfadeFilter([]float64{4, 4}, 0.5, "wipeleft", false, nil)
+	want := "[0:v]settb=AVTB,setpts=PTS-STARTPTS[v0];[1:v]settb=AVTB,setpts=PTS-STARTPTS[v1];" +
+		"[v0][v1]xfade=transition=wipeleft:duration=0.5:offset=3.5[vx1];[vx1]null[vout]"
+
+	if got != want {
+		t.Errorf("buildCrossfadeFilter() =\n%s\nwant\n%s", got, want)
+	}
+}
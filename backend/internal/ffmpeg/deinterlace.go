@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Deinterlacing modes for re-encoded video
+const (
+	DeinterlaceAuto  = "auto"  // bwdif when ffprobe reports interlaced video, nothing otherwise
+	DeinterlaceYadif = "yadif" // Fast, good for most captures
+	DeinterlaceBwdif = "bwdif" // Slower, sharper motion
+)
+
+// ValidDeinterlace reports whether a deinterlacing mode is supported. Empty disables it.
+func ValidDeinterlace(mode string) bool {
+	switch mode {
+	case "", DeinterlaceAuto, DeinterlaceYadif, DeinterlaceBwdif:
+		return true
+	}
+	return false
+}
+
+// deinterlaceFilter returns the filter for a resolved deinterlacing mode, outputting
+// one frame per frame so the frame rate is kept. Unresolved "auto" only
+// deinterlaces frames flagged as interlaced.
+func deinterlaceFilter(mode string) string {
+	switch mode {
+	case DeinterlaceYadif, DeinterlaceBwdif:
+		return mode + "=mode=send_frame:deint=all"
+	case DeinterlaceAuto:
+		return DeinterlaceBwdif + "=mode=send_frame:deint=interlaced"
+	}
+	return ""
+}
+
+// resolveDeinterlace turns "auto" into a filter choice by probing the first video
+// stream of input. Other modes are returned unchanged.
+func (e *Executor) resolveDeinterlace(ctx context.Context, input, mode string) (string, error) {
+	if mode != DeinterlaceAuto {
+		return mode, nil
+	}
+
+	probe, err := e.Probe(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect interlacing: %w", err)
+	}
+	videoStreams := probe.GetVideoStreams()
+	if len(videoStreams) == 0 || !videoStreams[0].IsInterlaced() {
+		return "", nil
+	}
+
+	e.logger.Info("Interlaced video detected, deinterlacing",
+		zap.String("input", input),
+		zap.String("field_order", videoStreams[0].FieldOrder),
+	)
+	return DeinterlaceBwdif, nil
+}
@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeinterlaceFilter(t *testing.T) {
+	tests := map[string]string{
+		"":               "",
+		DeinterlaceYadif: "yadif=mode=send_frame:deint=all",
+		DeinterlaceBwdif: "bwdif=mode=send_frame:deint=all",
+		DeinterlaceAuto:  "bwdif=mode=send_frame:deint=interlaced",
+	}
+	for mode, want := range tests {
+		if got := deinterlaceFilter(mode); got != want {
+			t.Errorf("deinterlaceFilter(%q) = %q, want %q", mode, got, want)
+		}
+	}
+
+	if ValidDeinterlace("kerndeint") {
+		t.Error("expected unsupported filter to be invalid")
+	}
+}
+
+func TestEncodeOptionsDeinterlaceFirst(t *testing.T) {
+	opts := EncodeOptions{Deinterlace: DeinterlaceYadif, Filters: []string{"hflip"}, Width: 1280}
+
+	want := []string{"yadif=mode=send_frame:deint=all", "hflip", "scale=1280:-2"}
+	if got := opts.videoFilters(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("videoFilters() = %q, want %q", got, want)
+	}
+}
+
+func TestSmartCutArgsDeinterlace(t *testing.T) {
+	opts := SmartCutOptions{Input: "in.ts", Output: "out.mp4", VideoCodec: "libx264", AudioCodec: "aac", Quality: 18, Preset: "fast", Deinterlace: DeinterlaceBwdif}
+
+	args := strings.Join(smartCutArgs(context.Background(), opts, 5, nil), " ")
+	if !strings.Contains(args, "-vf bwdif=mode=send_frame:deint=all -c:v libx264") {
+		t.Errorf("smart cut args don't deinterlace: %s", args)
+	}
+
+	opts.VideoCodec = "copy"
+	if args := strings.Join(smartCutArgs(context.Background(), opts, 5, nil), " "); strings.Contains(args, "-vf") {
+		t.Errorf("stream copy can't be filtered: %s", args)
+	}
+}
+
+func TestGOPHeadArgsInterlaced(t *testing.T) {
+	opts := SmartCutOptions{Input: "in.ts", Start: 1.5, End: 10}
+	video := Stream{CodecName: "h264", PixFmt: "yuv420p", FieldOrder: "tt"}
+
+	head := strings.Join(gopHeadArgs(context.Background(), opts, video, "libx264", 2, "out.head.ts"), " ")
+	if !strings.Contains(head, "-flags +ildct+ilme") {
+		t.Errorf("interlaced head isn't field coded: %s", head)
+	}
+
+	video.FieldOrder = "progressive"
+	if head := strings.Join(gopHeadArgs(context.Background(), opts, video, "libx264", 2, "out.head.ts"), " "); strings.Contains(head, "ildct") {
+		t.Errorf("progressive head is field coded: %s", head)
+	}
+}
@@ -0,0 +1,77 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+const dryRunKey contextKey = "ffmpeg-dry-run"
+
+// PlannedCommand is an FFmpeg invocation recorded instead of run by a dry run
+type PlannedCommand struct {
+	Args       []string // Program and arguments, exactly as they would be executed
+	ConcatList string   // Contents of the concat demuxer list the command reads, if any
+	// Placeholder is the empty output file created for the command, "" when its
+	// output already existed and was left alone
+	Placeholder string
+}
+
+// DryRunRecorder receives the FFmpeg invocations of a dry run
+type DryRunRecorder func(command PlannedCommand)
+
+// WithDryRun returns a context whose FFmpeg invocations are reported to recorder
+// instead of being run. Each missing output file is created empty, so steps that
+// move or join intermediate files still go through; callers remove the reported
+// placeholders afterwards, and never files that existed before.
+// Read-only ffprobe calls on the source still run.
+func WithDryRun(ctx context.Context, recorder DryRunRecorder) context.Context {
+	return context.WithValue(ctx, dryRunKey, recorder)
+}
+
+// isDryRun reports whether FFmpeg invocations of ctx are only recorded
+func isDryRun(ctx context.Context) bool {
+	recorder, ok := ctx.Value(dryRunKey).(DryRunRecorder)
+	return ok && recorder != nil
+}
+
+// planCommand records a command for the context's dry run and creates its output
+// as an empty placeholder. It reports false when ctx is not a dry run.
+func planCommand(ctx context.Context, command []string) bool {
+	recorder, ok := ctx.Value(dryRunKey).(DryRunRecorder)
+	if !ok || recorder == nil {
+		return false
+	}
+
+	planned := PlannedCommand{Args: append([]string(nil), command...)}
+	if list := concatListPath(command); list != "" {
+		if data, err := os.ReadFile(list); err == nil {
+			planned.ConcatList = string(data)
+		}
+	}
+	if output := command[len(command)-1]; output != "-" && !strings.HasPrefix(output, "pipe:") {
+		// O_EXCL never truncates a file created in the meantime
+		if file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644); err == nil {
+			file.Close()
+			planned.Placeholder = output
+		}
+	}
+	recorder(planned)
+	return true
+}
+
+// concatListPath returns the input read with the concat demuxer, or ""
+func concatListPath(args []string) string {
+	concat := false
+	for i := 0; i+1 < len(args); i++ {
+		switch {
+		case args[i] == "-f" && args[i+1] == "concat":
+			concat = true
+		case args[i] == "-i" && concat:
+			return args[i+1]
+		case args[i] == "-i":
+			concat = false // -f only applies to the next input
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPlanCommand(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "out.mp4.concat.txt")
+	if err := os.WriteFile(list, []byte("file 'a.mp4'\nfile 'b.mp4'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "out.mp4")
+
+	var planned []PlannedCommand
+	ctx := WithDryRun(context.Background(), func(command PlannedCommand) {
+		planned = append(planned, command)
+	})
+
+	command := []string{"ffmpeg", "-f", "concat", "-safe", "0", "-i", list, "-c", "copy", "-y", output}
+	if !planCommand(ctx, command) {
+		t.Fatal("planCommand() = false in a dry run")
+	}
+
+	want := []PlannedCommand{{Args: command, ConcatList: "file 'a.mp4'\nfile 'b.mp4'\n", Placeholder: output}}
+	if !reflect.DeepEqual(planned, want) {
+		t.Errorf("planned = %+v, want %+v", planned, want)
+	}
+	if info, err := os.Stat(output); err != nil || info.Size() != 0 {
+		t.Errorf("output placeholder missing or not empty: %v", err)
+	}
+
+	// An existing output is neither truncated nor reported as a placeholder
+	if err := os.WriteFile(output, []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	planned = nil
+	planCommand(ctx, command)
+	if len(planned) != 1 || planned[0].Placeholder != "" {
+		t.Errorf("planned = %+v, want no placeholder", planned)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "video" {
+		t.Errorf("existing output = %q, want it untouched", data)
+	}
+}
+
+func TestPlanCommandNotDryRun(t *testing.T) {
+	if planCommand(context.Background(), []string{"ffmpeg", "-i", "in.mp4", "out.mp4"}) {
+		t.Error("planCommand() = true without a dry run")
+	}
+}
+
+func TestConcatListPath(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-f", "concat", "-safe", "0", "-i", "list.txt", "out.mp4"}, "list.txt"},
+		{[]string{"-i", "in.mp4", "-f", "mp4", "out.mp4"}, ""},
+		{[]string{"-i", "in.mp4", "-i", "mark.png", "out.mp4"}, ""},
+	}
+	for _, tt := range tests {
+		if got := concatListPath(tt.args); got != tt.want {
+			t.Errorf("concatListPath(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
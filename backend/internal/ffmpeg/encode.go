@@ -0,0 +1,298 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// EncodeOptions contains re-encoding settings for exports that trade losslessness
+// for compatibility
+type EncodeOptions struct {
+	VideoCodec   string    // FFmpeg encoder, default "libx264"
+	VideoBitrate string    // e.g. "5M", takes precedence over CRF
+	CRF          int       // Constant rate factor, default 18
+	Preset       string    // Encoder preset, default "fast"
+	VideoProfile string    // Encoder profile, e.g. "3" for ProRes HQ or "dnxhr_hq"
+	PixelFormat  string    // default "yuv420p" for maximum player compatibility
+	AspectRatio  string    // Center crop to this aspect ratio before scaling, e.g. "9:16"
+	Width        int       // Output width, 0 = keep (or derive from Height keeping aspect)
+	Height       int       // Output height, 0 = keep (or derive from Width keeping aspect)
+	FPS          float64   // Output frame rate, 0 = keep
+	Deinterlace  string    // "auto", "yadif" or "bwdif", applied before any other filter
+	Crop         *CropRect // Area of the source to keep, e.g. to remove letterboxing
+	Filters      []string  // Additional video filters, applied before scaling
+	AudioCodec   string    // "mp3", "aac", "flac", "opus", "pcm", "pcm24" or "copy", default "aac"
+	AudioBitrate string    // e.g. "192k"
+	SampleRate   int       // Audio sample rate in Hz, 0 = keep
+
+	Timecode         bool   // Burn the source timecode into the picture
+	TimecodePosition string // "top-left", "top-right", "bottom-left" (default) or "bottom-right"
+
+	Subtitles *SubtitleBurn // Subtitles to burn into the picture, see WithSubtitleBurn
+	Watermark *Watermark    // Image overlaid after all other filters, at output size
+
+	transpose int // Degrees clockwise to turn the picture, see encodeRotationArgs
+}
+
+// intraCodecs are intra-frame editing codecs whose quality is set by the profile,
+// so CRF, bitrate and preset don't apply
+var intraCodecs = map[string]bool{
+	"prores":    true,
+	"prores_ks": true,
+	"prores_aw": true,
+	"dnxhd":     true,
+}
+
+// parseAspectRatio parses "W:H" with positive integers
+func parseAspectRatio(ratio string) (int, int, bool) {
+	var w, h int
+	if _, err := fmt.Sscanf(ratio, "%d:%d", &w, &h); err != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	if fmt.Sprintf("%d:%d", w, h) != ratio {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+// ValidAspectRatio reports whether an aspect ratio crop value is supported
+func ValidAspectRatio(ratio string) bool {
+	_, _, ok := parseAspectRatio(ratio)
+	return ratio == "" || ok
+}
+
+// ValidPreset reports whether an encoder preset is supported: the x264 names, which
+// hardware encoders map to their own. Empty means "fast".
+func ValidPreset(preset string) bool {
+	_, ok := nvencPresets[preset]
+	return preset == "" || ok
+}
+
+// TargetVideoBitrate returns the video bitrate that makes an output of the given
+// duration fit in size bytes next to the audio, keeping a small margin for muxing overhead
+func TargetVideoBitrate(size int64, duration float64, audioBitrate string) string {
+	if duration <= 0 {
+		return ""
+	}
+
+	audioBps := 0.0
+	if audioBitrate != "" {
+		var value float64
+		var unit string
+		fmt.Sscanf(audioBitrate, "%f%s", &value, &unit)
+		switch strings.ToLower(unit) {
+		case "k":
+			value *= 1000
+		case "m":
+			value *= 1000000
+		}
+		audioBps = value
+	}
+
+	videoBps := float64(size)*8*0.97/duration - audioBps
+	// Don't go below a bitrate that still produces a watchable picture
+	if videoBps < 100000 {
+		videoBps = 100000
+	}
+
+	return fmt.Sprintf("%dk", int64(videoBps/1000))
+}
+
+// timecodePositions maps overlay positions to drawtext x/y expressions
+var timecodePositions = map[string]string{
+	"top-left":     "x=10:y=10",
+	"top-right":    "x=w-tw-10:y=10",
+	"bottom-left":  "x=10:y=h-th-10",
+	"bottom-right": "x=w-tw-10:y=h-th-10",
+}
+
+// ValidTimecodePosition reports whether a timecode overlay position is supported
+func ValidTimecodePosition(position string) bool {
+	_, ok := timecodePositions[position]
+	return position == "" || ok
+}
+
+// timecodeFilter returns a drawtext filter showing the source time of each frame.
+// Output timestamps start at 0, so the segment's source start is added back.
+func timecodeFilter(start float64, position string) string {
+	xy, ok := timecodePositions[position]
+	if !ok {
+		xy = timecodePositions["bottom-left"]
+	}
+	return fmt.Sprintf(`drawtext=text='%%{pts\:hms\:%.6f}':%s:fontsize=h/24:fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=6`, start, xy)
+}
+
+// withDefaults returns a copy of the options with defaults filled in
+func (o EncodeOptions) withDefaults() EncodeOptions {
+	if o.VideoCodec == "" {
+		o.VideoCodec = "libx264"
+	}
+	if o.CRF == 0 {
+		o.CRF = 18
+	}
+	if o.Preset == "" {
+		o.Preset = "fast"
+	}
+	if o.PixelFormat == "" {
+		o.PixelFormat = "yuv420p"
+	}
+	if o.AudioCodec == "" {
+		o.AudioCodec = "aac"
+	}
+	return o
+}
+
+// videoFilters returns the video filter chain for the options. start is the source
+// position of the first frame, used for the timecode overlay.
+func (o EncodeOptions) videoFilters(start float64) []string {
+	var filters []string
+
+	// Fields have to be merged before anything resizes or retimes the picture
+	if filter := deinterlaceFilter(o.Deinterlace); filter != "" {
+		filters = append(filters, filter)
+	}
+
+	// Crops are given in the rotated picture
+	if filter := transposeFilter(o.transpose); filter != "" {
+		filters = append(filters, filter)
+	}
+
+	if o.Crop != nil {
+		filters = append(filters, o.Crop.filter())
+	}
+
+	filters = append(filters, o.Filters...)
+
+	if w, h, ok := parseAspectRatio(o.AspectRatio); ok {
+		// Largest centered window with the target ratio, rounded to even dimensions
+		filters = append(filters, fmt.Sprintf("crop=w='trunc(min(iw,ih*%d/%d)/2)*2':h='trunc(min(ih,iw*%d/%d)/2)*2'", w, h, h, w))
+	}
+
+	if o.Width > 0 || o.Height > 0 {
+		width, height := o.Width, o.Height
+		// -2 keeps the aspect ratio while making sure the dimension is even
+		if width == 0 {
+			width = -2
+		}
+		if height == 0 {
+			height = -2
+		}
+		filters = append(filters, fmt.Sprintf("scale=%d:%d", width, height))
+	}
+
+	if o.FPS > 0 {
+		filters = append(filters, fmt.Sprintf("fps=%g", o.FPS))
+	}
+
+	// Rendered at the output size so the text stays sharp
+	if o.Subtitles != nil {
+		filters = append(filters, subtitlesFilter(*o.Subtitles, start))
+	}
+
+	// Drawn last so the text size doesn't depend on the source resolution
+	if o.Timecode {
+		filters = append(filters, timecodeFilter(start, o.TimecodePosition))
+	}
+
+	return filters
+}
+
+// videoArgs returns the FFmpeg arguments mapping and filtering the first video
+// stream. A watermark is read from the second input.
+func (o EncodeOptions) videoArgs(start float64) []string {
+	filters := o.videoFilters(start)
+
+	if o.Watermark != nil {
+		return []string{"-filter_complex", watermarkGraph(filters, *o.Watermark), "-map", "[v]"}
+	}
+
+	args := []string{"-map", "0:v:0?"} // First video track, if any
+	if len(filters) > 0 {
+		args = append(args, "-vf", strings.Join(filters, ","))
+	}
+	return args
+}
+
+// codecArgs returns the encoder arguments for the options, without filters
+func (o EncodeOptions) codecArgs() ([]string, error) {
+	o = o.withDefaults()
+
+	args := []string{"-c:v", o.VideoCodec}
+	if o.VideoProfile != "" {
+		args = append(args, "-profile:v", o.VideoProfile)
+	}
+	if !intraCodecs[o.VideoCodec] {
+		if o.VideoBitrate != "" {
+			args = append(args, "-b:v", o.VideoBitrate)
+		} else {
+			args = append(args, "-crf", fmt.Sprintf("%d", o.CRF))
+		}
+		args = append(args, "-preset", o.Preset)
+	}
+	args = append(args, "-pix_fmt", o.PixelFormat)
+
+	audioArgs, err := audioCodecArgs(o.AudioCodec, o.AudioBitrate)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, audioArgs...)
+	if o.SampleRate > 0 {
+		args = append(args, "-ar", fmt.Sprintf("%d", o.SampleRate))
+	}
+
+	return args, nil
+}
+
+// EncodeSegment cuts a segment and re-encodes it with the given options.
+// Input seeking is frame-accurate when re-encoding, so cut points are exact.
+func (e *Executor) EncodeSegment(ctx context.Context, input, output string, start, end float64, opts EncodeOptions, onProgress ProgressCallback) error {
+	duration := end - start
+
+	if opts.Subtitles == nil {
+		opts.Subtitles = contextSubtitleBurn(ctx)
+	}
+	if opts.Watermark == nil {
+		opts.Watermark = contextWatermark(ctx)
+	}
+	deinterlace, err := e.resolveDeinterlace(ctx, input, opts.Deinterlace)
+	if err != nil {
+		return err
+	}
+	opts.Deinterlace = deinterlace
+	codecArgs, err := opts.codecArgs()
+	if err != nil {
+		return err
+	}
+	rotationInput, rotationOutput := e.encodeRotationArgs(ctx, &opts)
+
+	args := []string{"-hide_banner"}
+	args = append(args, rotationInput...) // Autorotation applies the override
+	args = append(args,
+		"-ss", fmt.Sprintf("%.6f", start),
+		"-i", input,
+	)
+	if opts.Watermark != nil {
+		args = append(args, "-i", opts.Watermark.File)
+	}
+	args = append(args, "-t", fmt.Sprintf("%.6f", duration))
+	args = append(args, opts.videoArgs(start)...)
+	args = append(args, "-map", "0:a?") // All audio tracks
+	args = append(args, attachmentArgs(ctx, 0, output, false)...)
+	args = append(args, metadataArgs(ctx, 0, true)...)
+	args = append(args, rotationOutput...)
+	args = append(args, codecArgs...)
+	args = append(args, "-avoid_negative_ts", "make_zero")
+	args = append(args, muxArgs(ctx, output)...)
+	args = append(args,
+		"-y",
+		output,
+	)
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args:       args,
+		Duration:   duration,
+		OnProgress: onProgress,
+	})
+}
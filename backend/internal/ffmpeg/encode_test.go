@@ -0,0 +1,85 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeOptionsVideoFilters(t *testing.T) {
+	opts := EncodeOptions{
+		Width:            1280,
+		FPS:              25,
+		Timecode:         true,
+		TimecodePosition: "top-right",
+	}
+
+	want := []string{
+		"scale=1280:-2",
+		"fps=25",
+		`drawtext=text='%{pts\:hms\:12.500000}':x=w-tw-10:y=10:fontsize=h/24:fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=6`,
+	}
+
+	if got := opts.videoFilters(12.5); !reflect.DeepEqual(got, want) {
+		t.Errorf("videoFilters() = %q, want %q", got, want)
+	}
+}
+
+func TestEncodeOptionsAspectRatioCrop(t *testing.T) {
+	opts := EncodeOptions{AspectRatio: "9:16", Width: 1080, Height: 1920}
+
+	want := []string{
+		"crop=w='trunc(min(iw,ih*9/16)/2)*2':h='trunc(min(ih,iw*16/9)/2)*2'",
+		"scale=1080:1920",
+	}
+
+	if got := opts.videoFilters(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("videoFilters() = %q, want %q", got, want)
+	}
+}
+
+func TestTargetVideoBitrate(t *testing.T) {
+	// 25MiB over 100s with 128k audio: 25*1048576*8*0.97/100 - 128000 = 1906788 bps
+	if got, want := TargetVideoBitrate(25*1024*1024, 100, "128k"), "1906k"; got != want {
+		t.Errorf("TargetVideoBitrate() = %q, want %q", got, want)
+	}
+
+	// Very long outputs are floored
+	if got, want := TargetVideoBitrate(1024*1024, 3600, "128k"), "100k"; got != want {
+		t.Errorf("TargetVideoBitrate() = %q, want %q", got, want)
+	}
+}
+
+func TestValidAspectRatio(t *testing.T) {
+	for ratio, want := range map[string]bool{"": true, "9:16": true, "1:1": true, "16/9": false, "0:1": false, "9:16x": false} {
+		if got := ValidAspectRatio(ratio); got != want {
+			t.Errorf("ValidAspectRatio(%q) = %v, want %v", ratio, got, want)
+		}
+	}
+}
+
+func TestEncodeOptionsSubtitles(t *testing.T) {
+	opts := EncodeOptions{Height: 720, Subtitles: &SubtitleBurn{File: "/data/videos/it's [1].mkv", Index: 1}}
+
+	want := []string{
+		"scale=-2:720",
+		`setpts=PTS+30.000000/TB,subtitles=filename=/data/videos/it\\\'s \[1\].mkv:si=1,setpts=PTS-STARTPTS`,
+	}
+
+	if got := opts.videoFilters(30); !reflect.DeepEqual(got, want) {
+		t.Errorf("videoFilters() = %q, want %q", got, want)
+	}
+}
+
+func TestEscapeFilterValue(t *testing.T) {
+	if got, want := escapeFilterValue(`C:\subs\a,b.srt`), `C\\:\\\\subs\\\\a\,b.srt`; got != want {
+		t.Errorf("escapeFilterValue() = %q, want %q", got, want)
+	}
+}
+
+func TestValidPreset(t *testing.T) {
+	for preset, want := range map[string]bool{"": true, "fast": true, "veryslow": true, "p4": false, "Fast": false} {
+		if got := ValidPreset(preset); got != want {
+			t.Errorf("ValidPreset(%q) = %v, want %v", preset, got, want)
+		}
+	}
+}
@@ -6,10 +6,8 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"math"
 	"os"
 	"os/exec"
-	"strconv"
 	"sync"
 
 	"go.uber.org/zap"
@@ -21,7 +19,16 @@ type Executor struct {
 	ffprobePath string
 	logger      *zap.Logger
 	mu          sync.Mutex
-	processes   map[string]*exec.Cmd
+	processes   map[string]*process
+
+	capsMu sync.Mutex // Serializes capability discovery
+	caps   *Capabilities
+
+	limits     Limits
+	cgroupOnce sync.Once
+	cgroupErr  error
+
+	processLimiter *ProcessLimiter // Shared cap on concurrent processes, nil = unlimited
 }
 
 // NewExecutor creates a new FFmpeg executor
@@ -37,7 +44,7 @@ func NewExecutor(ffmpegPath, ffprobePath string, logger *zap.Logger) *Executor {
 		ffmpegPath:  ffmpegPath,
 		ffprobePath: ffprobePath,
 		logger:      logger,
-		processes:   make(map[string]*exec.Cmd),
+		processes:   make(map[string]*process),
 	}
 }
 
@@ -50,41 +57,77 @@ type ExecuteOptions struct {
 	Duration   float64
 	OnProgress ProgressCallback
 	StdinData  io.Reader
+	Stdout     io.Writer // Receives stdout instead of discarding it, e.g. for streamed output
 }
 
 // Execute runs FFmpeg with the given arguments
 func (e *Executor) Execute(ctx context.Context, opts ExecuteOptions) error {
-	cmd := exec.CommandContext(ctx, e.ffmpegPath, opts.Args...)
+	_, err := e.ExecuteWithOutput(ctx, opts)
+	return err
+}
+
+// ExecuteWithOutput runs FFmpeg and returns its stderr output, which is where
+// filters such as ssim, libvmaf and the detection filters report their results
+func (e *Executor) ExecuteWithOutput(ctx context.Context, opts ExecuteOptions) (string, error) {
+	args := e.threadArgs(applyExtraArgs(ctx, opts.Args))
+	recordOutput(ctx, args)
+
+	// Cancel stops this process only, the caller's context is left alone
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Progress reports go to stdout, unless it carries streamed output
+	reportProgress := opts.Stdout == nil
+	if reportProgress {
+		args = append(append([]string(nil), progressArgs...), args...)
+	}
+	cmd := exec.CommandContext(ctx, e.ffmpegPath, args...)
 
 	// Log the command
 	e.logger.Info("Executing FFmpeg",
 		zap.String("command", cmd.String()),
 	)
+	recordCommand(ctx, cmd.String())
+	if planCommand(ctx, cmd.Args) {
+		return "", nil
+	}
 
 	// Set up stdin if provided
 	if opts.StdinData != nil {
 		cmd.Stdin = opts.StdinData
 	}
 
-	// Capture stderr for progress parsing
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	// Capture stderr for errors and filter output
+	var stderrBuf bytes.Buffer
+	cmd.Stderr = &stderrBuf
+
+	var stdoutPipe io.ReadCloser
+	if reportProgress {
+		pipe, err := cmd.StdoutPipe()
+		if err != nil {
+			return "", fmt.Errorf("failed to create stdout pipe: %w", err)
+		}
+		stdoutPipe = pipe
+	} else {
+		cmd.Stdout = opts.Stdout
 	}
 
-	// Capture stdout
-	var stdoutBuf bytes.Buffer
-	cmd.Stdout = &stdoutBuf
+	// Wait for a process slot, then start the command
+	release, err := e.acquire(ctx)
+	if err != nil {
+		return "", fmt.Errorf("waiting to start ffmpeg: %w", err)
+	}
+	defer release()
 
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	if err := e.start(cmd); err != nil {
+		return "", fmt.Errorf("failed to start ffmpeg: %w", err)
 	}
 
 	// Track the process
 	processID := fmt.Sprintf("%d", cmd.Process.Pid)
 	e.mu.Lock()
-	e.processes[processID] = cmd
+	e.processes[processID] = &process{cmd: cmd, cancel: cancel}
 	e.mu.Unlock()
 
 	defer func() {
@@ -93,24 +136,25 @@ func (e *Executor) Execute(ctx context.Context, opts ExecuteOptions) error {
 		e.mu.Unlock()
 	}()
 
-	// Parse progress in a goroutine
-	var stderrBuf bytes.Buffer
-	progressDone := make(chan struct{})
-
-	go func() {
-		defer close(progressDone)
-		e.parseProgress(stderrPipe, &stderrBuf, opts.Duration, opts.OnProgress)
-	}()
+	// Read progress reports until FFmpeg closes stdout
+	if reportProgress {
+		e.parseProgress(ctx, stdoutPipe, opts.Duration, opts.OnProgress)
+	}
 
 	// Wait for process to complete
 	err = cmd.Wait()
 
-	// Wait for progress parsing to finish
-	<-progressDone
+	stderrStr := stderrBuf.String()
 
+	if err != nil && ctx.Err() != nil {
+		e.logger.Info("FFmpeg cancelled", zap.String("processId", processID))
+		if parent.Err() != nil {
+			return stderrStr, fmt.Errorf("ffmpeg cancelled: %w", parent.Err())
+		}
+		return stderrStr, fmt.Errorf("ffmpeg cancelled: %w", context.Canceled)
+	}
 	if err != nil {
 		// Extract error message from stderr
-		stderrStr := stderrBuf.String()
 		errorMsg := ParseFFmpegError(stderrStr)
 
 		e.logger.Error("FFmpeg execution failed",
@@ -118,31 +162,37 @@ func (e *Executor) Execute(ctx context.Context, opts ExecuteOptions) error {
 			zap.String("stderr", errorMsg),
 		)
 
-		return fmt.Errorf("ffmpeg failed: %s", errorMsg)
+		return stderrStr, fmt.Errorf("ffmpeg failed: %s", errorMsg)
 	}
 
 	e.logger.Info("FFmpeg execution completed successfully")
-	return nil
+	return stderrStr, nil
 }
 
-// parseProgress reads stderr line by line and calls progress callback
-func (e *Executor) parseProgress(stderr io.Reader, stderrBuf *bytes.Buffer, duration float64, onProgress ProgressCallback) {
+// parseProgress reads -progress reports and passes them to the progress callback
+// and the context's stats recorder
+func (e *Executor) parseProgress(ctx context.Context, progress io.Reader, duration float64, onProgress ProgressCallback) {
 	parser := NewProgressParser(duration)
-	scanner := bufio.NewScanner(io.TeeReader(stderr, stderrBuf))
+	scanner := bufio.NewScanner(progress)
 
 	for scanner.Scan() {
-		line := scanner.Text()
+		stats, ok := parser.ParseLine(scanner.Text())
+		if !ok {
+			continue
+		}
 
+		recordStats(ctx, stats)
 		if onProgress != nil {
-			progress := parser.ParseLine(line)
-			if progress >= 0 {
-				onProgress(progress)
+			if fraction := parser.Fraction(stats); fraction >= 0 {
+				onProgress(fraction)
 			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		e.logger.Warn("Error reading FFmpeg stderr", zap.Error(err))
+		e.logger.Warn("Error reading FFmpeg progress", zap.Error(err))
+		// Keep draining so FFmpeg doesn't block on a full pipe
+		io.Copy(io.Discard, progress)
 	}
 }
 
@@ -154,26 +204,40 @@ func (e *Executor) CutVideo(ctx context.Context, input, output string, start, en
 	// 1. -ss BEFORE -i = INPUT SEEKING (very fast, seeks to keyframe)
 	// 2. -i input file
 	// 3. -t = duration to extract
-	// 4. -map 0 = copy all streams (video, audio, subtitles)
+	// 4. -map 0 = copy all streams (video, audio, subtitles, attachments if supported),
+	//    or only the streams selected with WithStreams
 	// 5. -c copy = lossless stream copy (no re-encoding)
 	// 6. -avoid_negative_ts make_zero = fix timestamp issues
-	// 7. -movflags +faststart = web-optimized MP4 (moov atom at start)
+	// 7. container mux flags, e.g. -movflags +faststart = web-optimized MP4 (moov atom at start)
 	//
 	// INPUT SEEKING (-ss before -i) is MUCH faster than output seeking
 	// because FFmpeg seeks directly to the keyframe without decoding.
 	// For lossless -c copy operations this gives near-instant results.
-	args := []string{
-		"-hide_banner",
+	rotation, err := e.copyRotationArgs(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-hide_banner"}
+	args = append(args, rotation...) // Display rotation override, if any
+	args = append(args,
 		"-ss", fmt.Sprintf("%.6f", start), // INPUT SEEKING (before -i) = FAST
 		"-i", input,
 		"-t", fmt.Sprintf("%.6f", duration), // Duration to extract
-		"-map", "0", // Copy all streams
+	)
+	mapArgs, mappedAll := streamMapArgs(ctx, 0) // All streams unless a selection was made
+	args = append(args, mapArgs...)
+	args = append(args, attachmentArgs(ctx, 0, output, mappedAll)...)
+	args = append(args, metadataArgs(ctx, 0, true)...) // Global tags and chapters
+	args = append(args,
 		"-c", "copy", // Lossless copy - no re-encoding
 		"-avoid_negative_ts", "make_zero", // Fix timestamp issues
-		"-movflags", "+faststart", // Web-optimized (moov atom at start)
+	)
+	args = append(args, muxArgs(ctx, output)...) // e.g. web-optimized MP4 (moov atom at start)
+	args = append(args,
 		"-y", // Overwrite output
 		output,
-	}
+	)
 
 	return e.Execute(ctx, ExecuteOptions{
 		Args:       args,
@@ -189,18 +253,31 @@ func (e *Executor) CutVideoAccurate(ctx context.Context, input, output string, s
 
 	// Frame-accurate cutting with output seeking
 	// This is slower but ensures exact frame boundaries
-	args := []string{
-		"-hide_banner",
+	rotation, err := e.copyRotationArgs(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-hide_banner"}
+	args = append(args, rotation...) // Display rotation override, if any
+	args = append(args,
 		"-i", input,
 		"-ss", fmt.Sprintf("%.6f", start), // OUTPUT SEEKING (after -i) = accurate
 		"-t", fmt.Sprintf("%.6f", duration), // Duration to extract
-		"-map", "0", // Copy all streams
+	)
+	mapArgs, mappedAll := streamMapArgs(ctx, 0) // All streams unless a selection was made
+	args = append(args, mapArgs...)
+	args = append(args, attachmentArgs(ctx, 0, output, mappedAll)...)
+	args = append(args, metadataArgs(ctx, 0, true)...) // Global tags and chapters
+	args = append(args,
 		"-c", "copy", // Lossless copy - no re-encoding
 		"-avoid_negative_ts", "make_zero", // Fix timestamp issues
-		"-movflags", "+faststart", // Web-optimized (moov atom at start)
+	)
+	args = append(args, muxArgs(ctx, output)...) // e.g. web-optimized MP4 (moov atom at start)
+	args = append(args,
 		"-y", // Overwrite output
 		output,
-	}
+	)
 
 	return e.Execute(ctx, ExecuteOptions{
 		Args:       args,
@@ -209,8 +286,11 @@ func (e *Executor) CutVideoAccurate(ctx context.Context, input, output string, s
 	})
 }
 
-// MergeVideos merges multiple video segments using concat demuxer (optimized)
-func (e *Executor) MergeVideos(ctx context.Context, inputs []string, output string, totalDuration float64, onProgress ProgressCallback) error {
+// MergeVideos merges multiple video segments using concat demuxer (optimized).
+// metadataSource, if set, is the file the segments were cut from; its global tags
+// and attachments are carried over to the merged output. chapters, if set, replace
+// the chapters of the output, e.g. one per merged segment.
+func (e *Executor) MergeVideos(ctx context.Context, inputs []string, output, metadataSource string, chapters []OutputChapter, totalDuration float64, onProgress ProgressCallback) error {
 	// Create concat file content and write to a temp file
 	// (using pipe:0 with concat demuxer is unreliable)
 	concatFile := output + ".concat.txt"
@@ -227,20 +307,54 @@ func (e *Executor) MergeVideos(ctx context.Context, inputs []string, output stri
 
 	// OPTIMIZED for LOSSLESS merging:
 	// - concat demuxer with -c copy = no re-encoding
-	// - movflags +faststart = web-optimized output
+	// - container mux flags, e.g. movflags +faststart = web-optimized MP4
 	// - map 0 = copy all streams
 	args := []string{
 		"-hide_banner",
 		"-f", "concat",
 		"-safe", "0",
 		"-i", concatFile, // Read concat file list from temp file
-		"-map", "0", // Copy all streams
+	}
+	if metadataSource != "" {
+		args = append(args, "-i", metadataSource)
+	}
+	chaptersFile, cleanup, err := chaptersInput(ctx, output, chapters)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if chaptersFile != "" {
+		args = append(args, "-f", "ffmetadata", "-i", chaptersFile)
+	}
+
+	args = append(args, "-map", "0") // Copy all streams
+	args = append(args, attachmentArgs(ctx, 0, output, true)...)
+	var metaArgs []string
+	if metadataSource != "" {
+		// Source chapters refer to the source timeline, not the merged one
+		args = append(args, attachmentArgs(ctx, 1, output, false)...)
+		metaArgs = metadataArgs(ctx, 1, false)
+	} else {
+		metaArgs = metadataArgs(ctx, 0, true)
+	}
+	if chaptersFile != "" {
+		// The chapters file is the last input
+		chaptersIndex := 1
+		if metadataSource != "" {
+			chaptersIndex = 2
+		}
+		metaArgs = withChaptersFrom(metaArgs, chaptersIndex)
+	}
+	args = append(args, metaArgs...)
+	args = append(args,
 		"-c", "copy", // Lossless copy - no re-encoding
 		"-avoid_negative_ts", "make_zero", // Fix timestamp issues
-		"-movflags", "+faststart", // Web-optimized MP4
+	)
+	args = append(args, muxArgs(ctx, output)...) // e.g. web-optimized MP4
+	args = append(args,
 		"-y",
 		output,
-	}
+	)
 
 	return e.Execute(ctx, ExecuteOptions{
 		Args:       args,
@@ -249,19 +363,24 @@ func (e *Executor) MergeVideos(ctx context.Context, inputs []string, output stri
 	})
 }
 
-// ConvertFormat converts video to different format
+// ConvertFormat remuxes input into another container (one of OutputFormats) without
+// re-encoding, keeping every stream the container can hold
 func (e *Executor) ConvertFormat(ctx context.Context, input, output, format string, duration float64, onProgress ProgressCallback) error {
+	if _, ok := GetOutputProfile(format); !ok {
+		return fmt.Errorf("unsupported container: %s", format)
+	}
+
 	args := []string{
 		"-hide_banner",
 		"-i", input,
-		"-c", "copy",
 	}
-
-	if format != "" {
-		args = append(args, "-f", format)
-	}
-
-	args = append(args, "-y", output)
+	args = append(args, remuxArgs(ctx, format, output)...)
+	args = append(args, metadataArgs(ctx, 0, true)...)
+	args = append(args,
+		"-c", "copy",
+		"-y",
+		output,
+	)
 
 	return e.Execute(ctx, ExecuteOptions{
 		Args:       args,
@@ -287,32 +406,222 @@ func (e *Executor) CaptureSnapshot(ctx context.Context, input, output string, ti
 	})
 }
 
-// ExtractAudio extracts audio track from video
-func (e *Executor) ExtractAudio(ctx context.Context, input, output string, duration float64, onProgress ProgressCallback) error {
+// ExtractAudio writes one audio stream of input, the stream-th (0 = first), to output
+// in full. codec is an audio codec name as for ExportAudio; "copy" keeps the source
+// encoding, see AudioCopyExtension for a container that holds it.
+func (e *Executor) ExtractAudio(ctx context.Context, input, output string, stream int, codec, bitrate string, duration float64, onProgress ProgressCallback) error {
+	codecArgs, err := audioCodecArgs(codec, bitrate)
+	if err != nil {
+		return err
+	}
+
 	args := []string{
 		"-hide_banner",
 		"-i", input,
+		"-map", fmt.Sprintf("0:a:%d", stream),
 		"-vn", // No video
-		"-acodec", "copy",
-		"-y",
-		output,
 	}
+	args = append(args, metadataArgs(ctx, 0, true)...)
+	args = append(args, codecArgs...)
+	args = append(args, "-y", output)
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args:       args,
+		Duration:   duration,
+		OnProgress: onProgress,
+	})
+}
+
+// audioEncoders maps user-facing audio codec names to FFmpeg encoders
+var audioEncoders = map[string]string{
+	"mp3":   "libmp3lame",
+	"aac":   "aac",
+	"flac":  "flac",
+	"opus":  "libopus",
+	"pcm":   "pcm_s16le",
+	"pcm24": "pcm_s24le",
+	"copy":  "copy",
+}
+
+// audioExtensions maps audio codec names to their usual file extension
+var audioExtensions = map[string]string{
+	"mp3":   "mp3",
+	"aac":   "m4a",
+	"flac":  "flac",
+	"opus":  "opus",
+	"pcm":   "wav",
+	"pcm24": "wav",
+	"copy":  "mka",
+}
+
+// audioDefaultBitrates are used for lossy codecs when no bitrate is given
+var audioDefaultBitrates = map[string]string{
+	"mp3":  "192k",
+	"aac":  "192k",
+	"opus": "128k",
+}
+
+// ValidAudioCodec reports whether an audio codec name is supported. Empty means the
+// default of the export.
+func ValidAudioCodec(codec string) bool {
+	_, ok := audioEncoders[codec]
+	return codec == "" || ok
+}
+
+// AudioCodecExtension returns the file extension for an audio codec name, or "" if unknown
+func AudioCodecExtension(codec string) string {
+	return audioExtensions[codec]
+}
+
+// AudioCopyExtension returns the file extension of a container that holds an audio
+// stream of the given FFprobe codec without re-encoding it
+func AudioCopyExtension(codecName string) string {
+	switch codecName {
+	case "aac", "alac":
+		return "m4a"
+	case "mp3":
+		return "mp3"
+	case "flac":
+		return "flac"
+	default:
+		// Matroska holds nearly every audio codec
+		return "mka"
+	}
+}
+
+// AudioReencodeCodec returns the audio codec name that encodes a stream of the given
+// FFprobe codec in that codec again, or "flac" when there is no encoder for it
+func AudioReencodeCodec(codecName string) string {
+	switch codecName {
+	case "aac", "mp3", "flac", "opus":
+		return codecName
+	case "pcm_s16le":
+		return "pcm"
+	case "pcm_s24le":
+		return "pcm24"
+	default:
+		return "flac"
+	}
+}
+
+// AudioExportOptions contains options for exporting the audio of a time range
+type AudioExportOptions struct {
+	Input      string
+	Output     string
+	Start      float64
+	End        float64
+	Codec      string // "mp3", "aac", "flac", "opus", "pcm", "pcm24" or "copy"
+	Stream     int    // Audio stream to export, 0 = first
+	Bitrate    string // e.g. "192k", ignored for lossless codecs
+	OnProgress ProgressCallback
+}
+
+// audioCodecArgs returns the encoding arguments for an audio codec name
+func audioCodecArgs(codec, bitrate string) ([]string, error) {
+	encoder, ok := audioEncoders[codec]
+	if !ok {
+		return nil, fmt.Errorf("unsupported audio codec: %s", codec)
+	}
+
+	args := []string{"-c:a", encoder}
+
+	// Only lossy codecs take a bitrate
+	defaultBitrate, lossy := audioDefaultBitrates[codec]
+	if !lossy {
+		return args, nil
+	}
+
+	if bitrate == "" {
+		bitrate = defaultBitrate
+	}
+	return append(args, "-b:a", bitrate), nil
+}
+
+// ExportAudio exports only the audio of a time range, re-encoding it to the given codec
+func (e *Executor) ExportAudio(ctx context.Context, opts AudioExportOptions) error {
+	duration := opts.End - opts.Start
+
+	codecArgs, err := audioCodecArgs(opts.Codec, opts.Bitrate)
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-ss", fmt.Sprintf("%.6f", opts.Start),
+		"-i", opts.Input,
+		"-t", fmt.Sprintf("%.6f", duration),
+		"-map", fmt.Sprintf("0:a:%d", opts.Stream),
+		"-vn",
+	}
+	args = append(args, metadataArgs(ctx, 0, true)...)
+	args = append(args, codecArgs...)
+	args = append(args, "-y", opts.Output)
 
 	return e.Execute(ctx, ExecuteOptions{
 		Args:       args,
 		Duration:   duration,
+		OnProgress: opts.OnProgress,
+	})
+}
+
+// MergeAudio concatenates audio files and encodes the result once, which avoids
+// the gaps lossy codecs introduce at every join when pieces are encoded separately
+func (e *Executor) MergeAudio(ctx context.Context, inputs []string, output, codec, bitrate string, totalDuration float64, onProgress ProgressCallback) error {
+	codecArgs, err := audioCodecArgs(codec, bitrate)
+	if err != nil {
+		return err
+	}
+
+	concatFile := output + ".concat.txt"
+	var concatContent bytes.Buffer
+	for _, input := range inputs {
+		concatContent.WriteString(fmt.Sprintf("file '%s'\n", input))
+	}
+
+	if err := os.WriteFile(concatFile, concatContent.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to create concat file: %w", err)
+	}
+	defer os.Remove(concatFile)
+
+	args := []string{
+		"-hide_banner",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", concatFile,
+		"-vn",
+	}
+	args = append(args, metadataArgs(ctx, 0, false)...)
+	args = append(args, codecArgs...)
+	args = append(args, "-y", output)
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args:       args,
+		Duration:   totalDuration,
 		OnProgress: onProgress,
 	})
 }
 
+// WaveformOptions selects what a waveform shows
+type WaveformOptions struct {
+	AudioStream   int  // Index among the audio streams, 0 = first
+	SplitChannels bool // One waveform per channel, stacked
+}
+
 // GenerateWaveform generates an audio waveform image using FFmpeg showwavespic filter
-func (e *Executor) GenerateWaveform(ctx context.Context, input, output string) error {
+func (e *Executor) GenerateWaveform(ctx context.Context, input, output string, opts WaveformOptions) error {
+	// Split channels get their own lane each, so the image is taller
+	height, split := 120, 0
+	if opts.SplitChannels {
+		height, split = 240, 1
+	}
+
 	// Generate a waveform image using FFmpeg's showwavespic filter
 	// This is very fast and produces a good looking waveform
 	args := []string{
 		"-hide_banner",
 		"-i", input,
-		"-filter_complex", "showwavespic=s=1920x120:colors=#667eea|#667eea:scale=sqrt:split_channels=0",
+		"-filter_complex", fmt.Sprintf("[0:a:%d]showwavespic=s=1920x%d:colors=#667eea|#667eea:scale=sqrt:split_channels=%d", opts.AudioStream, height, split),
 		"-frames:v", "1",
 		"-y",
 		output,
@@ -333,7 +642,14 @@ type SmartCutOptions struct {
 	AudioCodec string // "copy" for lossless, "aac" for re-encoding
 	Quality    int    // CRF value (0-51, lower = better quality)
 	Preset     string // "ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow"
-	OnProgress ProgressCallback
+	HWAccel    string // Re-encode whole segments on the GPU: "nvenc", "qsv" or "vaapi", empty for software
+	HWDevice   string // VAAPI render node, default DefaultVAAPIDevice
+	// Deinterlace re-encoded video: "auto", "yadif" or "bwdif". Deinterlaced frames
+	// can't be joined with copied interlaced GOPs, so the whole segment is re-encoded.
+	Deinterlace string
+	OnProgress  ProgressCallback
+
+	pixFmt string // Pixel format of software re-encodes, see matchPixFmt
 }
 
 // SmartCut performs intelligent cutting with minimal re-encoding
@@ -341,6 +657,16 @@ type SmartCutOptions struct {
 func (e *Executor) SmartCut(ctx context.Context, opts SmartCutOptions) error {
 	duration := opts.End - opts.Start
 
+	deinterlace, err := e.resolveDeinterlace(ctx, opts.Input, opts.Deinterlace)
+	if err != nil {
+		return err
+	}
+	opts.Deinterlace = deinterlace
+	if opts.Deinterlace != "" && opts.VideoCodec != "copy" {
+		e.logger.Info("Performing smart cut with deinterlacing", zap.String("filter", opts.Deinterlace))
+		return e.performSmartCut(ctx, opts, duration)
+	}
+
 	// First, try to determine if we can do lossless cutting
 	// by checking if start/end points are on keyframes
 	canLossless, err := e.canDoLosslessCut(ctx, opts.Input, opts.Start, opts.End)
@@ -355,69 +681,43 @@ func (e *Executor) SmartCut(ctx context.Context, opts SmartCutOptions) error {
 		return e.CutVideo(ctx, opts.Input, opts.Output, opts.Start, opts.End, opts.OnProgress)
 	}
 
-	// Smart cut with minimal re-encoding
-	e.logger.Info("Performing smart cut (minimal re-encoding)")
-	return e.performSmartCut(ctx, opts, duration)
-}
-
-// canDoLosslessCut checks if cut points are on keyframes
-func (e *Executor) canDoLosslessCut(ctx context.Context, input string, start, end float64) (bool, error) {
-	// Get keyframe information using ffprobe
-	args := []string{
-		"-hide_banner",
-		"-select_streams", "v:0",
-		"-show_frames",
-		"-show_entries", "frame=pkt_pts_time,key_frame",
-		"-of", "csv=p=0",
-		input,
+	// Re-encode only up to the first keyframe, copy the rest
+	handled, err := e.gopSmartCut(ctx, opts)
+	if handled && err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
-
-	cmd := exec.CommandContext(ctx, e.ffprobePath, args...)
-	output, err := cmd.Output()
 	if err != nil {
-		return false, fmt.Errorf("failed to get keyframe info: %w", err)
+		e.logger.Warn("GOP smart cut failed, re-encoding the whole segment", zap.Error(err))
 	}
 
-	// Parse keyframe times
-	lines := bytes.Split(output, []byte("\n"))
-	var keyframes []float64
-
-	for _, line := range lines {
-		if len(line) == 0 {
-			continue
-		}
-
-		parts := bytes.Split(line, []byte(","))
-		if len(parts) < 2 {
-			continue
-		}
-
-		timeStr := string(parts[0])
-		keyFrameStr := string(parts[1])
-
-		if keyFrameStr == "1" {
-			if time, err := strconv.ParseFloat(timeStr, 64); err == nil {
-				keyframes = append(keyframes, time)
-			}
-		}
-	}
+	// Re-encode the whole segment
+	e.logger.Info("Performing smart cut (minimal re-encoding)")
+	return e.performSmartCut(ctx, opts, duration)
+}
 
+// canDoLosslessCut checks if cut points are on keyframes, using the context's
+// keyframe index when it has one
+func (e *Executor) canDoLosslessCut(ctx context.Context, input string, start, end float64) (bool, error) {
 	// Check if start and end points are close to keyframes (within 0.1 seconds)
 	tolerance := 0.1
 
-	startNearKeyframe := false
-	endNearKeyframe := false
-
-	for _, kf := range keyframes {
-		if math.Abs(kf-start) <= tolerance {
-			startNearKeyframe = true
-		}
-		if math.Abs(kf-end) <= tolerance {
-			endNearKeyframe = true
+	keyframes, ok := contextKeyframes(ctx, input)
+	if !ok {
+		// Without an index, only the packets around both cut points are read
+		var err error
+		keyframes, err = e.probeKeyframes(ctx, input, [][2]float64{
+			{start - tolerance, start + tolerance},
+			{end - tolerance, end + tolerance},
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to get keyframe info: %w", err)
 		}
 	}
 
-	return startNearKeyframe && endNearKeyframe, nil
+	return nearKeyframe(keyframes, start, tolerance) && nearKeyframe(keyframes, end, tolerance), nil
 }
 
 // performSmartCut performs cutting with minimal re-encoding
@@ -435,28 +735,102 @@ func (e *Executor) performSmartCut(ctx context.Context, opts SmartCutOptions, du
 	if opts.Preset == "" {
 		opts.Preset = "fast" // Good balance of speed and efficiency
 	}
+	if opts.VideoCodec != "copy" {
+		opts.pixFmt = e.sourcePixFmt(ctx, opts.Input, opts.VideoCodec)
+	}
+
+	// Hardware encoding when the build supports it, software if it fails at runtime,
+	// e.g. because the server has no GPU
+	if opts.HWAccel != HWAccelNone && opts.VideoCodec != "copy" {
+		hw, ok := newHWEncoder(opts.HWAccel, opts.VideoCodec, opts.Quality, opts.Preset, opts.HWDevice)
+		switch {
+		case !ok:
+			e.logger.Info("No hardware encoder for codec, encoding in software",
+				zap.String("hwaccel", opts.HWAccel), zap.String("codec", opts.VideoCodec))
+		case !e.hwAvailable(ctx, hw):
+			e.logger.Warn("FFmpeg build lacks hardware encoder, encoding in software",
+				zap.String("hwaccel", opts.HWAccel), zap.String("encoder", hw.Encoder))
+		default:
+			err := e.Execute(ctx, ExecuteOptions{
+				Args:       smartCutArgs(ctx, opts, duration, &hw),
+				Duration:   duration,
+				OnProgress: opts.OnProgress,
+			})
+			if err == nil || ctx.Err() != nil {
+				return err
+			}
+			e.logger.Warn("Hardware encoding failed, retrying in software",
+				zap.String("encoder", hw.Encoder), zap.Error(err))
+		}
+	}
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args:       smartCutArgs(ctx, opts, duration, nil),
+		Duration:   duration,
+		OnProgress: opts.OnProgress,
+	})
+}
+
+// sourcePixFmt returns the pixel format an encoder re-encodes the first video stream
+// of input in, "" to leave it to the encoder
+func (e *Executor) sourcePixFmt(ctx context.Context, input, encoder string) string {
+	probe, err := e.Probe(ctx, input)
+	if err != nil {
+		e.logger.Warn("Failed to probe pixel format for smart cut", zap.Error(err))
+		return matchPixFmt(encoder, "")
+	}
+	source := ""
+	if videos := probe.GetVideoStreams(); len(videos) > 0 {
+		source = videos[0].PixFmt
+	}
+	return matchPixFmt(encoder, source)
+}
 
+// smartCutArgs builds the FFmpeg arguments of a smart cut with defaults filled in,
+// encoding video with hw if set. Streams other than video and audio are copied.
+func smartCutArgs(ctx context.Context, opts SmartCutOptions, duration float64, hw *hwEncoder) []string {
 	// Smart cut strategy:
 	// 1. Use input seeking for speed
 	// 2. Re-encode only the minimal necessary portion
 	// 3. Use high-quality settings but fast presets
-	args := []string{
-		"-hide_banner",
+	args := []string{"-hide_banner"}
+	if hw != nil {
+		args = append(args, hw.InputArgs...)
+	}
+	args = append(args,
 		"-ss", fmt.Sprintf("%.6f", opts.Start), // Input seeking
 		"-i", opts.Input,
 		"-t", fmt.Sprintf("%.6f", duration), // Duration
+	)
+	mapArgs, mappedAll := streamMapArgs(ctx, 0)
+	args = append(args, mapArgs...)
+	args = append(args, attachmentArgs(ctx, 0, opts.Output, mappedAll)...)
+	args = append(args, "-c", "copy") // Subtitles, data and attachments
+
+	if filter := deinterlaceFilter(opts.Deinterlace); filter != "" && opts.VideoCodec != "copy" {
+		args = append(args, "-vf", filter)
 	}
 
 	// Video codec settings
-	if opts.VideoCodec == "copy" {
+	switch {
+	case opts.VideoCodec == "copy":
 		args = append(args, "-c:v", "copy")
-	} else {
+	case hw != nil:
+		args = append(args, hw.OutputArgs...)
+	default:
 		args = append(args,
 			"-c:v", opts.VideoCodec,
 			"-crf", fmt.Sprintf("%d", opts.Quality),
 			"-preset", opts.Preset,
-			"-pix_fmt", "yuv420p", // Ensure compatibility
 		)
+		if opts.pixFmt != "" {
+			args = append(args, "-pix_fmt", opts.pixFmt) // The source's, when the encoder writes it
+		}
+	}
+
+	// Accept the user-facing audio codec names used by exports
+	if encoder, ok := audioEncoders[opts.AudioCodec]; ok {
+		opts.AudioCodec = encoder
 	}
 
 	// Audio codec settings
@@ -469,19 +843,15 @@ func (e *Executor) performSmartCut(ctx context.Context, opts SmartCutOptions, du
 		)
 	}
 
+	args = append(args, metadataArgs(ctx, 0, true)...)
+
 	// Additional optimizations
-	args = append(args,
-		"-avoid_negative_ts", "make_zero",
-		"-movflags", "+faststart", // Web optimization
+	args = append(args, "-avoid_negative_ts", "make_zero")
+	args = append(args, muxArgs(ctx, opts.Output)...) // e.g. web optimization
+	return append(args,
 		"-y",
 		opts.Output,
 	)
-
-	return e.Execute(ctx, ExecuteOptions{
-		Args:       args,
-		Duration:   duration,
-		OnProgress: opts.OnProgress,
-	})
 }
 
 // SmartCutSegments performs smart cutting on multiple segments
@@ -540,7 +910,7 @@ func (e *Executor) SmartCutSegments(ctx context.Context, input string, segments
 	}
 
 	// Merge all segments
-	return e.MergeVideos(ctx, tempFiles, output, totalDuration, onProgress)
+	return e.MergeVideos(ctx, tempFiles, output, input, nil, totalDuration, onProgress)
 }
 
 // GetFFmpegPath returns the FFmpeg binary path
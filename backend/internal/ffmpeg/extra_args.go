@@ -0,0 +1,227 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type contextKey string
+
+const (
+	extraArgsKey       contextKey = "ffmpeg-extra-args"
+	commandRecorderKey contextKey = "ffmpeg-command-recorder"
+	statsRecorderKey   contextKey = "ffmpeg-stats-recorder"
+	outputRecorderKey  contextKey = "ffmpeg-output-recorder"
+)
+
+// CommandRecorder receives the full command line of every FFmpeg invocation
+type CommandRecorder func(command string)
+
+// OutputRecorder receives the output file of every FFmpeg invocation writing one
+type OutputRecorder func(path string)
+
+// WithExtraArgs returns a context whose FFmpeg invocations get args inserted right
+// before their output file. Args must have been checked with ValidateExtraArgs.
+func WithExtraArgs(ctx context.Context, args []string) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, extraArgsKey, args)
+}
+
+// WithCommandRecorder returns a context whose FFmpeg invocations are reported to recorder
+func WithCommandRecorder(ctx context.Context, recorder CommandRecorder) context.Context {
+	return context.WithValue(ctx, commandRecorderKey, recorder)
+}
+
+// WithStatsRecorder returns a context whose FFmpeg invocations report their progress
+// stats to recorder, e.g. to show the encoding speed
+func WithStatsRecorder(ctx context.Context, recorder StatsRecorder) context.Context {
+	return context.WithValue(ctx, statsRecorderKey, recorder)
+}
+
+// WithOutputRecorder returns a context whose FFmpeg invocations report the file they
+// write to recorder, e.g. to remove partial outputs of a cancelled export
+func WithOutputRecorder(ctx context.Context, recorder OutputRecorder) context.Context {
+	return context.WithValue(ctx, outputRecorderKey, recorder)
+}
+
+// applyExtraArgs inserts the context's extra args before the output (last) argument
+func applyExtraArgs(ctx context.Context, args []string) []string {
+	extra, ok := ctx.Value(extraArgsKey).([]string)
+	if !ok || len(extra) == 0 || len(args) == 0 {
+		return args
+	}
+
+	result := make([]string, 0, len(args)+len(extra))
+	result = append(result, args[:len(args)-1]...)
+	result = append(result, extra...)
+	return append(result, args[len(args)-1])
+}
+
+// recordCommand reports a command to the context's recorder, if any
+func recordCommand(ctx context.Context, command string) {
+	if recorder, ok := ctx.Value(commandRecorderKey).(CommandRecorder); ok && recorder != nil {
+		recorder(command)
+	}
+}
+
+// recordOutput reports the output (last) argument of a command to the context's
+// recorder, if any, unless it is a pipe
+func recordOutput(ctx context.Context, args []string) {
+	recorder, ok := ctx.Value(outputRecorderKey).(OutputRecorder)
+	if !ok || recorder == nil || len(args) == 0 {
+		return
+	}
+	if output := args[len(args)-1]; output != "-" && !strings.HasPrefix(output, "pipe:") {
+		recorder(output)
+	}
+}
+
+// recordStats reports progress stats to the context's recorder, if any
+func recordStats(ctx context.Context, stats Stats) {
+	if recorder, ok := ctx.Value(statsRecorderKey).(StatsRecorder); ok && recorder != nil {
+		recorder(stats)
+	}
+}
+
+// extraArgOptions are the output options allowed in an export's extra_args: the
+// advanced options, plus options that re-encode, filter or select streams. None of
+// them names a file, and every other token, such as a positional output, is rejected.
+var extraArgOptions = map[string]advancedOption{
+	// Encoding
+	"-c":                    {perStream: true, value: nameValue},
+	"-codec":                {perStream: true, value: nameValue},
+	"-b":                    {perStream: true, value: bitrateValue},
+	"-maxrate":              {perStream: true, value: bitrateValue},
+	"-minrate":              {perStream: true, value: bitrateValue},
+	"-bufsize":              {perStream: true, value: bitrateValue},
+	"-crf":                  {perStream: true, value: numberValue},
+	"-qp":                   {perStream: true, value: numberValue},
+	"-q":                    {perStream: true, value: numberValue},
+	"-preset":               {perStream: true, value: nameValue},
+	"-pix_fmt":              {perStream: true, value: nameValue},
+	"-r":                    {perStream: true, value: regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(/[0-9]+)?$`)},
+	"-s":                    {perStream: true, value: regexp.MustCompile(`^[0-9]{1,5}x[0-9]{1,5}$`)},
+	"-fps_mode":             {perStream: true, value: regexp.MustCompile(`^(passthrough|cfr|vfr|drop|auto)$`)},
+	"-ar":                   {perStream: true, value: intValue},
+	"-ac":                   {perStream: true, value: intValue},
+	"-sample_fmt":           {perStream: true, value: nameValue},
+	"-ch_layout":            {perStream: true, value: nameValue},
+	"-frames":               {perStream: true, value: intValue},
+	"-threads":              {value: intValue},
+	"-max_interleave_delta": {value: intValue},
+
+	// Filters
+	"-vf":     {filter: true},
+	"-af":     {filter: true},
+	"-filter": {perStream: true, filter: true},
+
+	// Stream selection
+	"-map":          {value: regexp.MustCompile(`^-?[0-9](:[vasdt](:[0-9]+)?|:[0-9]+)?\??$`)},
+	"-map_metadata": {value: regexp.MustCompile(`^(-1|[0-9])$`)},
+	"-map_chapters": {value: regexp.MustCompile(`^(-1|[0-9])$`)},
+	"-metadata":     {value: regexp.MustCompile(`^[A-Za-z0-9_.-]+=[^\x00\n\r]*$`)},
+	"-an":           {},
+	"-vn":           {},
+	"-sn":           {},
+	"-dn":           {},
+}
+
+var (
+	bitrateValue = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[kKMG]?$`)
+	numberValue  = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+)
+
+func init() {
+	for name, option := range advancedOptions {
+		extraArgOptions[name] = option
+	}
+}
+
+// allowedFilters are the filters allowed in extra_args filter graphs. They only
+// transform frames and samples; filters that read or write files, such as movie,
+// subtitles, drawtext or vidstabdetect, or accept runtime commands aren't listed.
+var allowedFilters = map[string]bool{
+	// Video
+	"scale": true, "crop": true, "pad": true, "fps": true, "format": true,
+	"setsar": true, "setdar": true, "setpts": true, "trim": true, "transpose": true,
+	"hflip": true, "vflip": true, "rotate": true, "yadif": true, "bwdif": true,
+	"hqdn3d": true, "nlmeans": true, "unsharp": true, "gblur": true, "boxblur": true,
+	"eq": true, "hue": true, "negate": true, "colorchannelmixer": true,
+	"colorspace": true, "zscale": true, "tonemap": true, "drawbox": true,
+	"fade": true, "vignette": true, "deband": true, "tpad": true, "null": true,
+	"minterpolate": true, "framerate": true, "tblend": true,
+
+	// Audio
+	"volume": true, "loudnorm": true, "dynaudnorm": true, "highpass": true,
+	"lowpass": true, "bass": true, "treble": true, "equalizer": true,
+	"acompressor": true, "afade": true, "aresample": true, "atempo": true,
+	"atrim": true, "asetpts": true, "pan": true, "channelmap": true, "aformat": true,
+	"afftdn": true, "silenceremove": true, "apad": true, "anull": true,
+}
+
+// validateFilterGraph checks that a filter graph only uses allowed filters. Commas
+// and semicolons inside quotes or escaped with a backslash don't separate filters.
+func validateFilterGraph(graph string) error {
+	if strings.TrimSpace(graph) == "" {
+		return fmt.Errorf("empty filter graph")
+	}
+
+	var filters []string
+	var current strings.Builder
+	quoted := false
+	for i := 0; i < len(graph); i++ {
+		c := graph[i]
+		switch {
+		case c == '\\' && i+1 < len(graph):
+			current.WriteByte(c)
+			i++
+			c = graph[i]
+		case c == '\'':
+			quoted = !quoted
+		case !quoted && (c == ',' || c == ';'):
+			filters = append(filters, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteByte(c)
+	}
+	filters = append(filters, current.String())
+
+	for _, filter := range filters {
+		filter = strings.TrimSpace(filter)
+		// Skip input pad labels, e.g. "[0:v]scale=640:-2"
+		for strings.HasPrefix(filter, "[") {
+			end := strings.Index(filter, "]")
+			if end < 0 {
+				return fmt.Errorf("unterminated label: %s", filter)
+			}
+			filter = strings.TrimSpace(filter[end+1:])
+		}
+		name := filter
+		if end := strings.IndexAny(filter, "=@["); end >= 0 {
+			name = filter[:end]
+		}
+		if !allowedFilters[strings.TrimSpace(name)] {
+			return fmt.Errorf("filter not allowed: %q", name)
+		}
+	}
+	return nil
+}
+
+// ValidateExtraArgs checks admin supplied FFmpeg arguments against the extra_args
+// allowlist, so they can't add inputs or outputs or access other files
+func ValidateExtraArgs(args []string) error {
+	for i, arg := range args {
+		if arg == "" {
+			return fmt.Errorf("argument %d is empty", i)
+		}
+		if strings.ContainsAny(arg, "\x00\n\r") {
+			return fmt.Errorf("argument %d contains control characters", i)
+		}
+	}
+	return validateOptions(args, extraArgOptions)
+}
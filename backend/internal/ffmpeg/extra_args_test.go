@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestValidateExtraArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"codec tag", []string{"-tag:v", "hvc1"}, false},
+		{"audio filter", []string{"-af", "highpass=f=200,bass=g=3"}, false},
+		{"scale filter", []string{"-vf", "scale=iw/2:ih/2"}, false},
+		{"extra input", []string{"-i", "other.mp4"}, true},
+		{"absolute path", []string{"-metadata", "/etc/passwd"}, true},
+		{"url", []string{"-metadata", "http://example.com"}, true},
+		{"movie filter", []string{"-vf", "movie=secret.mp4,overlay"}, true},
+		{"subtitles filter", []string{"-vf", "scale=640:-2,subtitles=x.srt"}, true},
+		{"drawtext textfile", []string{"-vf", "drawtext=textfile=x.txt"}, true},
+		{"filter script", []string{"-filter_script:v", "f.txt"}, true},
+		{"empty", []string{""}, true},
+		{"encoder settings", []string{"-c:v", "libx264", "-crf", "20", "-preset", "slow", "-pix_fmt", "yuv420p"}, false},
+		{"quoted filter args", []string{"-vf", "scale=w='min(iw,1280)':h=-2,fade=t=in:d=1"}, false},
+		{"labeled filter", []string{"-filter:a", "[0:a]volume=2"}, false},
+		{"metadata", []string{"-metadata", "title=Holiday"}, false},
+		{"positional output", []string{"/tmp/copy.mp4"}, true},
+		{"output after options", []string{"-c:v", "libx264", "copy.mp4"}, true},
+		{"overwrite", []string{"-y"}, true},
+		{"missing value", []string{"-crf"}, true},
+		{"invalid value", []string{"-crf", "/tmp/x"}, true},
+		{"unknown filter", []string{"-af", "volume=2,ladspa=f=x"}, true},
+		{"escaped quote", []string{"-vf", `scale=640:-2\',movie=x.mp4`}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExtraArgs(tt.args)
+			if tt.wantErr && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestApplyExtraArgs(t *testing.T) {
+	args := []string{"-i", "in.mp4", "-c", "copy", "out.mp4"}
+
+	if got := applyExtraArgs(context.Background(), args); !reflect.DeepEqual(got, args) {
+		t.Errorf("applyExtraArgs() without extra args = %v, want %v", got, args)
+	}
+
+	ctx := WithExtraArgs(context.Background(), []string{"-tag:v", "hvc1"})
+	want := []string{"-i", "in.mp4", "-c", "copy", "-tag:v", "hvc1", "out.mp4"}
+	if got := applyExtraArgs(ctx, args); !reflect.DeepEqual(got, want) {
+		t.Errorf("applyExtraArgs() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,228 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/cmplx"
+	"os/exec"
+
+	"go.uber.org/zap"
+)
+
+// Fingerprint parameters. Audio is decoded to mono 5512 Hz, analysed in
+// overlapping frames and reduced to one 32-bit sub-fingerprint per frame
+// (Haitsma-Kalker style band energy differences).
+const (
+	fingerprintSampleRate = 5512
+	fingerprintFrameSize  = 1024
+	fingerprintHopSize    = 256
+	fingerprintBands      = 33
+	fingerprintMinFreq    = 300.0
+	fingerprintMaxFreq    = 2500.0
+)
+
+// AudioFingerprint is a compact representation of an audio range that can be
+// searched for in other files
+type AudioFingerprint struct {
+	Start    float64  `json:"start"`    // Start time of the fingerprinted range in the source
+	Interval float64  `json:"interval"` // Seconds between consecutive sub-fingerprints
+	Hashes   []uint32 `json:"hashes"`
+}
+
+// Duration returns the length of audio covered by the fingerprint
+func (f *AudioFingerprint) Duration() float64 {
+	return float64(len(f.Hashes)) * f.Interval
+}
+
+// FingerprintMatch describes where a fingerprint was found in another file
+type FingerprintMatch struct {
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	BitError   float64 `json:"bit_error"`  // Fraction of differing bits (0 = identical, ~0.5 = unrelated)
+	Confidence float64 `json:"confidence"` // 1 - 2*BitError, clamped to 0-1
+}
+
+// ComputeAudioFingerprint fingerprints the audio of input between start and start+duration.
+// A duration of 0 fingerprints until the end of the file.
+func (e *Executor) ComputeAudioFingerprint(ctx context.Context, input string, start, duration float64) (*AudioFingerprint, error) {
+	args := []string{
+		"-hide_banner",
+		"-v", "error",
+		"-ss", fmt.Sprintf("%.6f", start),
+	}
+	if duration > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.6f", duration))
+	}
+	args = append(args,
+		"-i", input,
+		"-vn",
+		"-ac", "1",
+		"-ar", fmt.Sprintf("%d", fingerprintSampleRate),
+		"-f", "s16le",
+		"-",
+	)
+
+	cmd := exec.CommandContext(ctx, e.ffmpegPath, e.threadArgs(args)...)
+	e.logger.Info("Computing audio fingerprint",
+		zap.String("input", input),
+		zap.Float64("start", start),
+		zap.Float64("duration", duration),
+	)
+
+	output, err := e.output(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio for fingerprint: %w", err)
+	}
+
+	samples := make([]float64, len(output)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(output[i*2:]))) / 32768.0
+	}
+
+	hashes := fingerprintSamples(samples)
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("not enough audio to compute a fingerprint")
+	}
+
+	return &AudioFingerprint{
+		Start:    start,
+		Interval: float64(fingerprintHopSize) / fingerprintSampleRate,
+		Hashes:   hashes,
+	}, nil
+}
+
+// FindFingerprint searches haystack for the best alignment of needle. It returns
+// nil when the best alignment has a bit error rate above maxBitError.
+func FindFingerprint(haystack, needle *AudioFingerprint, maxBitError float64) *FingerprintMatch {
+	n := len(needle.Hashes)
+	if n == 0 || len(haystack.Hashes) < n {
+		return nil
+	}
+
+	bestOffset := -1
+	bestErrors := math.MaxInt
+
+	for offset := 0; offset+n <= len(haystack.Hashes); offset++ {
+		errors := 0
+		for i := 0; i < n && errors < bestErrors; i++ {
+			errors += bits.OnesCount32(haystack.Hashes[offset+i] ^ needle.Hashes[i])
+		}
+		if errors < bestErrors {
+			bestErrors = errors
+			bestOffset = offset
+		}
+	}
+
+	bitError := float64(bestErrors) / float64(n*32)
+	if bestOffset < 0 || bitError > maxBitError {
+		return nil
+	}
+
+	start := haystack.Start + float64(bestOffset)*haystack.Interval
+	confidence := math.Max(0, math.Min(1, 1-2*bitError))
+
+	return &FingerprintMatch{
+		Start:      start,
+		End:        start + needle.Duration(),
+		BitError:   bitError,
+		Confidence: confidence,
+	}
+}
+
+// fingerprintSamples computes one sub-fingerprint per hop from mono samples
+func fingerprintSamples(samples []float64) []uint32 {
+	if len(samples) < fingerprintFrameSize {
+		return nil
+	}
+
+	edges := fingerprintBandEdges()
+	window := make([]float64, fingerprintFrameSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(fingerprintFrameSize-1)) // Hann
+	}
+
+	var hashes []uint32
+	var prev []float64
+	frame := make([]complex128, fingerprintFrameSize)
+
+	for pos := 0; pos+fingerprintFrameSize <= len(samples); pos += fingerprintHopSize {
+		for i := range frame {
+			frame[i] = complex(samples[pos+i]*window[i], 0)
+		}
+		fft(frame)
+
+		energies := make([]float64, fingerprintBands)
+		for b := 0; b < fingerprintBands; b++ {
+			for k := edges[b]; k < edges[b+1]; k++ {
+				energies[b] += math.Pow(cmplx.Abs(frame[k]), 2)
+			}
+		}
+
+		if prev != nil {
+			var hash uint32
+			for b := 0; b < fingerprintBands-1; b++ {
+				diff := (energies[b] - energies[b+1]) - (prev[b] - prev[b+1])
+				if diff > 0 {
+					hash |= 1 << uint(b)
+				}
+			}
+			hashes = append(hashes, hash)
+		}
+		prev = energies
+	}
+
+	return hashes
+}
+
+// fingerprintBandEdges returns FFT bin boundaries for logarithmically spaced bands
+func fingerprintBandEdges() []int {
+	edges := make([]int, fingerprintBands+1)
+	ratio := math.Pow(fingerprintMaxFreq/fingerprintMinFreq, 1.0/fingerprintBands)
+	binWidth := float64(fingerprintSampleRate) / fingerprintFrameSize
+
+	for i := range edges {
+		freq := fingerprintMinFreq * math.Pow(ratio, float64(i))
+		edges[i] = int(math.Round(freq / binWidth))
+	}
+	// Make sure every band covers at least one bin
+	for i := 1; i < len(edges); i++ {
+		if edges[i] <= edges[i-1] {
+			edges[i] = edges[i-1] + 1
+		}
+	}
+
+	return edges
+}
+
+// fft is an in-place iterative radix-2 FFT. len(x) must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u := x[start+k]
+				v := x[start+k+size/2] * w
+				x[start+k] = u + v
+				x[start+k+size/2] = u - v
+				w *= step
+			}
+		}
+	}
+}
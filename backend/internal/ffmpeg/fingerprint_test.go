@@ -0,0 +1,41 @@
+package ffmpeg
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFindFingerprint(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	samples := make([]float64, fingerprintSampleRate*20)
+	for i := range samples {
+		samples[i] = rng.Float64()*2 - 1
+	}
+
+	interval := float64(fingerprintHopSize) / fingerprintSampleRate
+	haystack := &AudioFingerprint{Interval: interval, Hashes: fingerprintSamples(samples)}
+
+	// Fingerprint a 3 second excerpt starting on a hop boundary
+	offset := fingerprintHopSize * 100
+	needle := &AudioFingerprint{Interval: interval, Hashes: fingerprintSamples(samples[offset : offset+fingerprintSampleRate*3])}
+
+	match := FindFingerprint(haystack, needle, 0.3)
+	if match == nil {
+		t.Fatal("expected a match but got none")
+	}
+
+	want := float64(offset) / fingerprintSampleRate
+	if match.Start < want-interval || match.Start > want+interval {
+		t.Errorf("match.Start = %f, want %f", match.Start, want)
+	}
+
+	// Unrelated audio must not match
+	other := make([]float64, fingerprintSampleRate*3)
+	for i := range other {
+		other[i] = rng.Float64()*2 - 1
+	}
+	unrelated := &AudioFingerprint{Interval: interval, Hashes: fingerprintSamples(other)}
+	if match := FindFingerprint(haystack, unrelated, 0.3); match != nil {
+		t.Errorf("expected no match for unrelated audio, got %+v", match)
+	}
+}
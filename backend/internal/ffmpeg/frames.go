@@ -0,0 +1,81 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// FrameExportOptions describes an image sequence export of one range
+type FrameExportOptions struct {
+	Input     string
+	OutputDir string  // Frames are written here as 000001.<format>, 000002.<format>, ...
+	Start     float64 // Seconds
+	End       float64
+	Format    string  // "png" (default) or "jpg"
+	FPS       float64 // Frames per second to keep, 0 = every frame
+	MaxFrames int     // Stop after this many frames, 0 = no limit
+
+	OnProgress ProgressCallback
+}
+
+// ValidFrameFormat reports whether format is a supported image sequence format.
+// An empty format selects PNG.
+func ValidFrameFormat(format string) bool {
+	switch format {
+	case "", "png", "jpg", "jpeg":
+		return true
+	}
+	return false
+}
+
+// ExportFrames decodes the first video stream between Start and End and writes
+// every frame, or FPS frames per second, as numbered images
+func (e *Executor) ExportFrames(ctx context.Context, opts FrameExportOptions) error {
+	if opts.End <= opts.Start {
+		return fmt.Errorf("end must be after start")
+	}
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args:       frameExportArgs(opts),
+		Duration:   opts.End - opts.Start,
+		OnProgress: opts.OnProgress,
+	})
+}
+
+// frameExportArgs seeks before the input, which decodes from the previous keyframe
+// and drops frames up to Start, so the first image is the frame at Start
+func frameExportArgs(opts FrameExportOptions) []string {
+	ext := "png"
+	if opts.Format == "jpg" || opts.Format == "jpeg" {
+		ext = "jpg"
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-ss", fmt.Sprintf("%.6f", opts.Start),
+		"-i", opts.Input,
+		"-t", fmt.Sprintf("%.6f", opts.End-opts.Start),
+		"-map", "0:v:0",
+	}
+	if opts.FPS > 0 {
+		args = append(args, "-vf", fmt.Sprintf("fps=%g", opts.FPS))
+	} else {
+		// Keep each decoded frame once, even for variable frame rate sources
+		args = append(args, "-fps_mode", "passthrough")
+	}
+	if ext == "jpg" {
+		args = append(args, "-q:v", "2")
+	}
+	if opts.MaxFrames > 0 {
+		args = append(args, "-frames:v", strconv.Itoa(opts.MaxFrames))
+	}
+
+	return append(args,
+		"-f", "image2",
+		"-start_number", "1",
+		"-y",
+		filepath.Join(opts.OutputDir, "%06d."+ext),
+	)
+}
@@ -0,0 +1,47 @@
+package ffmpeg
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFrameExportArgs(t *testing.T) {
+	args := frameExportArgs(FrameExportOptions{
+		Input:     "in.mp4",
+		OutputDir: "frames",
+		Start:     10,
+		End:       12.5,
+		Format:    "jpeg",
+		FPS:       5,
+	})
+
+	want := []string{
+		"-hide_banner",
+		"-ss", "10.000000",
+		"-i", "in.mp4",
+		"-t", "2.500000",
+		"-map", "0:v:0",
+		"-vf", "fps=5",
+		"-q:v", "2",
+		"-f", "image2",
+		"-start_number", "1",
+		"-y",
+		filepath.Join("frames", "%06d.jpg"),
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("frameExportArgs() = %v, want %v", args, want)
+	}
+}
+
+func TestFrameExportArgsEveryFrame(t *testing.T) {
+	args := frameExportArgs(FrameExportOptions{Input: "in.mkv", OutputDir: "out", End: 1})
+
+	if !strings.Contains(strings.Join(args, " "), "-fps_mode passthrough") {
+		t.Errorf("frameExportArgs() = %v, want every frame passed through", args)
+	}
+	if got := args[len(args)-1]; got != filepath.Join("out", "%06d.png") {
+		t.Errorf("output pattern = %q, want PNG", got)
+	}
+}
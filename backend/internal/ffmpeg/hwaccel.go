@@ -0,0 +1,142 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Hardware acceleration methods for re-encoding
+const (
+	HWAccelNone  = ""
+	HWAccelNVENC = "nvenc" // NVIDIA GPUs
+	HWAccelQSV   = "qsv"   // Intel Quick Sync Video
+	HWAccelVAAPI = "vaapi" // Intel and AMD GPUs on Linux
+)
+
+// DefaultVAAPIDevice is the DRM render node used by VAAPI when none is configured
+const DefaultVAAPIDevice = "/dev/dri/renderD128"
+
+// hwDecoders are the -hwaccel decoding methods of each acceleration method
+var hwDecoders = map[string]string{
+	HWAccelNVENC: "cuda",
+	HWAccelQSV:   "qsv",
+	HWAccelVAAPI: "vaapi",
+}
+
+// hwCodecFamilies maps software encoders and codec names to the codec family of the
+// hardware encoders. Other codecs, e.g. ProRes, are always encoded in software.
+var hwCodecFamilies = map[string]string{
+	"":        "h264",
+	"h264":    "h264",
+	"libx264": "h264",
+	"hevc":    "hevc",
+	"h265":    "hevc",
+	"libx265": "hevc",
+}
+
+// nvencPresets maps x264 presets to the NVENC presets p1 (fastest) to p7 (best)
+var nvencPresets = map[string]string{
+	"ultrafast": "p1",
+	"superfast": "p2",
+	"veryfast":  "p3",
+	"faster":    "p3",
+	"fast":      "p4",
+	"medium":    "p5",
+	"slow":      "p6",
+	"slower":    "p7",
+	"veryslow":  "p7",
+}
+
+// qsvPresets maps x264 presets to the QSV presets, which start at veryfast
+var qsvPresets = map[string]string{
+	"ultrafast": "veryfast",
+	"superfast": "veryfast",
+	"veryfast":  "veryfast",
+	"faster":    "faster",
+	"fast":      "fast",
+	"medium":    "medium",
+	"slow":      "slow",
+	"slower":    "slower",
+	"veryslow":  "veryslow",
+}
+
+// hwEncoder is a hardware re-encode setup
+type hwEncoder struct {
+	Encoder    string   // e.g. "h264_nvenc"
+	Decoder    string   // -hwaccel method
+	InputArgs  []string // Placed before -i
+	OutputArgs []string // Encoder and quality settings
+}
+
+// newHWEncoder returns the hardware encoder setup for a software codec, with quality
+// as CRF-like value and an x264 preset name. ok is false if the codec has no
+// hardware encoder.
+func newHWEncoder(accel, codec string, quality int, preset, device string) (hwEncoder, bool) {
+	family, ok := hwCodecFamilies[codec]
+	decoder, known := hwDecoders[accel]
+	if !ok || !known {
+		return hwEncoder{}, false
+	}
+
+	hw := hwEncoder{
+		Encoder:   fmt.Sprintf("%s_%s", family, accel),
+		Decoder:   decoder,
+		InputArgs: []string{"-hwaccel", decoder},
+	}
+	switch accel {
+	case HWAccelNVENC:
+		nvPreset, ok := nvencPresets[preset]
+		if !ok {
+			nvPreset = "p4"
+		}
+		hw.OutputArgs = []string{
+			"-c:v", hw.Encoder,
+			"-rc", "vbr", "-cq", fmt.Sprintf("%d", quality), "-b:v", "0",
+			"-preset", nvPreset,
+			"-pix_fmt", "yuv420p",
+		}
+	case HWAccelQSV:
+		qsvPreset, ok := qsvPresets[preset]
+		if !ok {
+			qsvPreset = "medium"
+		}
+		hw.OutputArgs = []string{
+			"-c:v", hw.Encoder,
+			"-global_quality", fmt.Sprintf("%d", quality),
+			"-preset", qsvPreset,
+			"-pix_fmt", "nv12",
+		}
+	case HWAccelVAAPI:
+		if device == "" {
+			device = DefaultVAAPIDevice
+		}
+		// Decoded frames stay on the GPU, so no pixel format conversion
+		hw.InputArgs = append(hw.InputArgs, "-hwaccel_device", device, "-hwaccel_output_format", "vaapi")
+		hw.OutputArgs = []string{
+			"-c:v", hw.Encoder,
+			"-qp", fmt.Sprintf("%d", quality),
+		}
+	}
+	return hw, true
+}
+
+// hwAvailable reports whether the FFmpeg build has the encoder and decoding method
+// of a hardware setup. Whether a GPU is present only shows when it runs.
+func (e *Executor) hwAvailable(ctx context.Context, hw hwEncoder) bool {
+	caps, err := e.Capabilities(ctx, false)
+	if err != nil {
+		e.logger.Warn("Failed to detect FFmpeg capabilities", zap.Error(err))
+		return false
+	}
+	if !caps.HasEncoder(hw.Encoder) {
+		return false
+	}
+	for _, method := range caps.HWAccels {
+		if method == hw.Decoder {
+			return true
+		}
+	}
+	return false
+}
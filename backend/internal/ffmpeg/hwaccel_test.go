@@ -0,0 +1,57 @@
+package ffmpeg
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewHWEncoder(t *testing.T) {
+	hw, ok := newHWEncoder(HWAccelNVENC, "libx264", 20, "slow", "")
+	if !ok {
+		t.Fatal("newHWEncoder(nvenc, libx264): want encoder")
+	}
+	if hw.Encoder != "h264_nvenc" || !reflect.DeepEqual(hw.InputArgs, []string{"-hwaccel", "cuda"}) {
+		t.Errorf("nvenc encoder = %+v", hw)
+	}
+	if got := strings.Join(hw.OutputArgs, " "); got != "-c:v h264_nvenc -rc vbr -cq 20 -b:v 0 -preset p6 -pix_fmt yuv420p" {
+		t.Errorf("nvenc output args = %s", got)
+	}
+
+	hw, _ = newHWEncoder(HWAccelVAAPI, "libx265", 24, "fast", "")
+	if hw.Encoder != "hevc_vaapi" {
+		t.Errorf("vaapi encoder = %s, want hevc_vaapi", hw.Encoder)
+	}
+	if got := strings.Join(hw.InputArgs, " "); got != "-hwaccel vaapi -hwaccel_device "+DefaultVAAPIDevice+" -hwaccel_output_format vaapi" {
+		t.Errorf("vaapi input args = %s", got)
+	}
+
+	hw, _ = newHWEncoder(HWAccelQSV, "libx264", 18, "ultrafast", "")
+	if got := strings.Join(hw.OutputArgs, " "); got != "-c:v h264_qsv -global_quality 18 -preset veryfast -pix_fmt nv12" {
+		t.Errorf("qsv output args = %s", got)
+	}
+
+	// ProRes and other codecs stay in software
+	if _, ok := newHWEncoder(HWAccelNVENC, "prores_ks", 18, "fast", ""); ok {
+		t.Error("newHWEncoder(nvenc, prores_ks): want no hardware encoder")
+	}
+}
+
+func TestSmartCutArgsHardware(t *testing.T) {
+	opts := SmartCutOptions{Input: "in.mp4", Output: "out.mp4", Start: 1, VideoCodec: "libx264", AudioCodec: "aac", Quality: 18, Preset: "fast"}
+	hw, _ := newHWEncoder(HWAccelNVENC, opts.VideoCodec, opts.Quality, opts.Preset, "")
+
+	args := strings.Join(smartCutArgs(context.Background(), opts, 5, &hw), " ")
+	if !strings.HasPrefix(args, "-hide_banner -hwaccel cuda -ss 1.000000 -i in.mp4") {
+		t.Errorf("hardware args don't set -hwaccel before the input: %s", args)
+	}
+	if !strings.Contains(args, "-c:v h264_nvenc") || strings.Contains(args, "-crf") {
+		t.Errorf("hardware args don't use the hardware encoder: %s", args)
+	}
+
+	software := strings.Join(smartCutArgs(context.Background(), opts, 5, nil), " ")
+	if strings.Contains(software, "-hwaccel") || !strings.Contains(software, "-c:v libx264 -crf 18 -preset fast") {
+		t.Errorf("software args = %s", software)
+	}
+}
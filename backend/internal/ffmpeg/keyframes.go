@@ -0,0 +1,131 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// keyframesKey is the context key of a keyframe index, see WithKeyframes
+const keyframesKey contextKey = "ffmpeg-keyframes"
+
+// keyframeIndex holds the keyframe times of an input's first video stream
+type keyframeIndex struct {
+	input     string
+	keyframes []float64
+}
+
+// WithKeyframes returns a context whose smart cuts of input look its keyframes up in
+// keyframes (sorted times, as returned by GetKeyframes) instead of probing the file
+func WithKeyframes(ctx context.Context, input string, keyframes []float64) context.Context {
+	return context.WithValue(ctx, keyframesKey, keyframeIndex{input: input, keyframes: keyframes})
+}
+
+// contextKeyframes returns the keyframe index of input stored in the context, if any
+func contextKeyframes(ctx context.Context, input string) ([]float64, bool) {
+	index, ok := ctx.Value(keyframesKey).(keyframeIndex)
+	if !ok || index.input != input {
+		return nil, false
+	}
+	return index.keyframes, true
+}
+
+// GetKeyframesNear returns the sorted keyframe times of the first video stream
+// within window seconds of t, plus the last keyframe before that. Only the packets
+// around t are read, so it is fast even on multi-hour files.
+func (e *Executor) GetKeyframesNear(ctx context.Context, input string, t, window float64) ([]float64, error) {
+	return e.probeKeyframes(ctx, input, [][2]float64{{t - window, t + window}})
+}
+
+// probeKeyframes reads the keyframe times of the first video stream in the given
+// [start, end] intervals with ffprobe -read_intervals. ffprobe seeks to the keyframe
+// at or before each start, so that keyframe is included as well.
+func (e *Executor) probeKeyframes(ctx context.Context, input string, intervals [][2]float64) ([]float64, error) {
+	args := []string{
+		"-hide_banner",
+		"-read_intervals", readIntervals(intervals),
+		"-select_streams", "v:0",
+		"-show_entries", "packet=pts_time,flags",
+		"-of", "csv=p=0",
+		input,
+	}
+
+	output, err := e.probeOutput(ctx, exec.CommandContext(ctx, e.ffprobePath, args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyframes: %w", err)
+	}
+
+	// Packets are listed in decoding order, and overlapping intervals repeat them
+	keyframes := parsePacketKeyframes(string(output))
+	sort.Float64s(keyframes)
+	unique := keyframes[:0]
+	for i, kf := range keyframes {
+		if i == 0 || kf != keyframes[i-1] {
+			unique = append(unique, kf)
+		}
+	}
+	return unique, nil
+}
+
+// readIntervals formats intervals as an ffprobe -read_intervals value, starting
+// them at 0 at the earliest
+func readIntervals(intervals [][2]float64) string {
+	parts := make([]string, len(intervals))
+	for i, interval := range intervals {
+		parts[i] = fmt.Sprintf("%.6f%%%.6f", math.Max(interval[0], 0), interval[1])
+	}
+	return strings.Join(parts, ",")
+}
+
+// keyframesBetween returns the keyframes of a sorted index from start to end
+func keyframesBetween(keyframes []float64, start, end float64) []float64 {
+	from := sort.SearchFloat64s(keyframes, start)
+	to := sort.SearchFloat64s(keyframes, end)
+	if to < len(keyframes) && keyframes[to] == end {
+		to++
+	}
+	return keyframes[from:to]
+}
+
+// nearKeyframe reports whether t is within tolerance of a keyframe of a sorted index
+func nearKeyframe(keyframes []float64, t, tolerance float64) bool {
+	return len(keyframesBetween(keyframes, t-tolerance, t+tolerance)) > 0
+}
+
+// KeyframeSnap holds the keyframes around a timestamp, nil where there is none
+type KeyframeSnap struct {
+	Time     float64  `json:"time"`
+	Previous *float64 `json:"previous,omitempty"` // Last keyframe at or before Time
+	Next     *float64 `json:"next,omitempty"`     // First keyframe at or after Time
+	Nearest  *float64 `json:"nearest,omitempty"`  // Closer one of Previous and Next
+}
+
+// SnapToKeyframe finds the keyframes of a sorted index around t
+func SnapToKeyframe(keyframes []float64, t float64) KeyframeSnap {
+	snap := KeyframeSnap{Time: t}
+	i := sort.SearchFloat64s(keyframes, t)
+	if i < len(keyframes) {
+		next := keyframes[i]
+		snap.Next = &next
+		if next == t {
+			snap.Previous = &next
+		}
+	}
+	if snap.Previous == nil && i > 0 {
+		previous := keyframes[i-1]
+		snap.Previous = &previous
+	}
+
+	switch {
+	case snap.Previous == nil:
+		snap.Nearest = snap.Next
+	case snap.Next == nil || t-*snap.Previous <= *snap.Next-t:
+		snap.Nearest = snap.Previous
+	default:
+		snap.Nearest = snap.Next
+	}
+	return snap
+}
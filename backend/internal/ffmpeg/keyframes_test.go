@@ -0,0 +1,95 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestKeyframesBetween(t *testing.T) {
+	keyframes := []float64{0, 2, 4, 6, 8}
+
+	if got := keyframesBetween(keyframes, 2, 6); !reflect.DeepEqual(got, []float64{2, 4, 6}) {
+		t.Errorf("keyframesBetween(2, 6) = %v", got)
+	}
+	if got := keyframesBetween(keyframes, 2.5, 3.5); len(got) != 0 {
+		t.Errorf("keyframesBetween(2.5, 3.5) = %v", got)
+	}
+	if !nearKeyframe(keyframes, 4.05, 0.1) || nearKeyframe(keyframes, 4.5, 0.1) {
+		t.Error("nearKeyframe() doesn't respect the tolerance")
+	}
+}
+
+func TestSnapToKeyframe(t *testing.T) {
+	keyframes := []float64{0, 2, 4}
+	value := func(p *float64) float64 {
+		if p == nil {
+			return -1
+		}
+		return *p
+	}
+
+	tests := []struct {
+		t                       float64
+		previous, next, nearest float64 // -1 = none
+	}{
+		{2.5, 2, 4, 2},
+		{3.5, 2, 4, 4},
+		{4, 4, 4, 4},
+		{5, 4, -1, 4},
+	}
+	for _, tt := range tests {
+		snap := SnapToKeyframe(keyframes, tt.t)
+		if value(snap.Previous) != tt.previous || value(snap.Next) != tt.next || value(snap.Nearest) != tt.nearest {
+			t.Errorf("SnapToKeyframe(%v) = %v, %v, %v", tt.t, value(snap.Previous), value(snap.Next), value(snap.Nearest))
+		}
+	}
+
+	if snap := SnapToKeyframe(nil, 1); snap.Nearest != nil {
+		t.Errorf("SnapToKeyframe() without keyframes = %+v", snap)
+	}
+}
+
+func TestContextKeyframes(t *testing.T) {
+	ctx := WithKeyframes(context.Background(), "a.mp4", []float64{0, 2})
+
+	if keyframes, ok := contextKeyframes(ctx, "a.mp4"); !ok || len(keyframes) != 2 {
+		t.Errorf("contextKeyframes(a.mp4) = %v, %v", keyframes, ok)
+	}
+	if _, ok := contextKeyframes(ctx, "b.mp4"); ok {
+		t.Error("contextKeyframes() returned the index of another input")
+	}
+}
+
+func TestReadIntervals(t *testing.T) {
+	got := readIntervals([][2]float64{{-0.1, 0.1}, {59.9, 60.1}})
+	if want := "0.000000%0.100000,59.900000%60.100000"; got != want {
+		t.Errorf("readIntervals() = %q, want %q", got, want)
+	}
+}
+
+func TestGetKeyframesNear(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	// Overlapping intervals list packets twice, in decoding order
+	ffprobe := filepath.Join(t.TempDir(), "ffprobe")
+	script := "#!/bin/sh\nprintf '4.000000,K__\\n2.000000,K__\\n3.000000,___\\n4.000000,K__\\n'\n"
+	if err := os.WriteFile(ffprobe, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewExecutor("ffmpeg", ffprobe, zap.NewNop())
+	keyframes, err := e.GetKeyframesNear(context.Background(), "in.mp4", 3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{2, 4}; !reflect.DeepEqual(keyframes, want) {
+		t.Errorf("GetKeyframesNear() = %v, want %v", keyframes, want)
+	}
+}
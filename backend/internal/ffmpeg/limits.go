@@ -0,0 +1,114 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// cgroupPeriod is the cpu.max period in microseconds
+const cgroupPeriod = 100000
+
+// Limits restrict the resources FFmpeg processes use, so long exports leave CPU for
+// serving requests
+type Limits struct {
+	Threads    int    // -threads of every command, 0 lets FFmpeg decide
+	Nice       int    // Scheduling niceness of FFmpeg processes (0-19), 0 keeps the server's
+	CPUPercent int    // CPU quota of all FFmpeg processes combined, in percent of one core, 0 = unlimited
+	CgroupPath string // cgroup v2 directory enforcing CPUPercent
+}
+
+// SetLimits sets the resource limits of the processes the executor starts
+func (e *Executor) SetLimits(limits Limits) {
+	e.limits = limits
+}
+
+// threadArgs inserts -threads before the output (last) argument
+func (e *Executor) threadArgs(args []string) []string {
+	if e.limits.Threads <= 0 || len(args) == 0 {
+		return args
+	}
+
+	result := make([]string, 0, len(args)+2)
+	result = append(result, args[:len(args)-1]...)
+	result = append(result, "-threads", strconv.Itoa(e.limits.Threads))
+	return append(result, args[len(args)-1])
+}
+
+// start starts an FFmpeg process and applies the niceness and CPU limits to it.
+// Cancelling the command's context interrupts FFmpeg before killing it.
+func (e *Executor) start(cmd *exec.Cmd) error {
+	gracefulCancel(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	pid := cmd.Process.Pid
+	if e.limits.Nice > 0 {
+		if err := setNice(pid, e.limits.Nice); err != nil {
+			e.logger.Warn("Failed to lower FFmpeg priority", zap.Int("pid", pid), zap.Error(err))
+		}
+	}
+	if e.limits.CPUPercent > 0 {
+		if err := e.joinCgroup(pid); err != nil {
+			e.logger.Warn("Failed to limit FFmpeg CPU usage", zap.Int("pid", pid), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// output runs an FFmpeg process with the limits applied and returns its stdout
+func (e *Executor) output(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	release, err := e.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := e.start(cmd); err != nil {
+		return nil, err
+	}
+	err = cmd.Wait()
+	return stdout.Bytes(), err
+}
+
+// joinCgroup moves a process into the FFmpeg cgroup, creating it with the CPU quota
+// on first use
+func (e *Executor) joinCgroup(pid int) error {
+	e.cgroupOnce.Do(func() {
+		e.cgroupErr = setupCgroup(e.limits.CgroupPath, e.limits.CPUPercent)
+	})
+	if e.cgroupErr != nil {
+		return e.cgroupErr
+	}
+
+	procs := filepath.Join(e.limits.CgroupPath, "cgroup.procs")
+	if err := os.WriteFile(procs, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return fmt.Errorf("failed to add process to %s: %w", procs, err)
+	}
+	return nil
+}
+
+// setupCgroup creates a cgroup v2 directory whose processes share percent of a core
+func setupCgroup(path string, percent int) error {
+	if path == "" {
+		return fmt.Errorf("no cgroup path configured")
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup %s: %w", path, err)
+	}
+
+	quota := fmt.Sprintf("%d %d", percent*cgroupPeriod/100, cgroupPeriod)
+	if err := os.WriteFile(filepath.Join(path, "cpu.max"), []byte(quota), 0644); err != nil {
+		return fmt.Errorf("failed to set CPU quota of %s (is the cpu controller enabled?): %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestThreadArgs(t *testing.T) {
+	e := NewExecutor("ffmpeg", "ffprobe", zap.NewNop())
+	args := []string{"-i", "in.mp4", "-c", "copy", "out.mp4"}
+
+	if got := e.threadArgs(args); !reflect.DeepEqual(got, args) {
+		t.Errorf("threadArgs() without limit = %q", got)
+	}
+
+	e.SetLimits(Limits{Threads: 2})
+	want := []string{"-i", "in.mp4", "-c", "copy", "-threads", "2", "out.mp4"}
+	if got := e.threadArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("threadArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestSetupCgroup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := setupCgroup(path, 150); err != nil {
+		t.Fatalf("setupCgroup() error = %v", err)
+	}
+
+	quota, err := os.ReadFile(filepath.Join(path, "cpu.max"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(quota) != "150000 100000" {
+		t.Errorf("cpu.max = %q, want 1.5 cores", quota)
+	}
+}
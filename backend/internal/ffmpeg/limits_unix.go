@@ -0,0 +1,10 @@
+//go:build !windows
+
+package ffmpeg
+
+import "syscall"
+
+// setNice sets the scheduling niceness of a process
+func setNice(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package ffmpeg
+
+import "fmt"
+
+// setNice is not supported on Windows, where FFmpeg runs at normal priority
+func setNice(pid, nice int) error {
+	return fmt.Errorf("niceness is not supported on windows")
+}
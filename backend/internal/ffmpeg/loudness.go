@@ -0,0 +1,196 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoudnessTarget is the EBU R128 target for loudness normalization
+type LoudnessTarget struct {
+	Integrated float64 // LUFS, default -16
+	TruePeak   float64 // dBTP, default -1.5
+	Range      float64 // LU, default 11
+}
+
+// withDefaults returns a copy of the target with defaults filled in
+func (t LoudnessTarget) withDefaults() LoudnessTarget {
+	if t.Integrated == 0 {
+		t.Integrated = -16
+	}
+	if t.TruePeak == 0 {
+		t.TruePeak = -1.5
+	}
+	if t.Range == 0 {
+		t.Range = 11
+	}
+	return t
+}
+
+// LoudnessStats are the loudnorm measurements of an input
+type LoudnessStats struct {
+	InputI       float64 `json:"input_i"`
+	InputTP      float64 `json:"input_tp"`
+	InputLRA     float64 `json:"input_lra"`
+	InputThresh  float64 `json:"input_thresh"`
+	TargetOffset float64 `json:"target_offset"`
+}
+
+// loudnormFilter returns the loudnorm filter for the target. With stats from a first
+// measuring pass it normalizes linearly, which keeps the dynamics intact.
+func loudnormFilter(target LoudnessTarget, stats *LoudnessStats) string {
+	target = target.withDefaults()
+	filter := fmt.Sprintf("loudnorm=I=%g:TP=%g:LRA=%g", target.Integrated, target.TruePeak, target.Range)
+	if stats != nil {
+		filter += fmt.Sprintf(":measured_I=%g:measured_TP=%g:measured_LRA=%g:measured_thresh=%g:offset=%g:linear=true",
+			stats.InputI, stats.InputTP, stats.InputLRA, stats.InputThresh, stats.TargetOffset)
+	}
+	return filter
+}
+
+// LoudnessAnalysis is the EBU R128 loudness of a range of a file
+type LoudnessAnalysis struct {
+	Start      float64 `json:"start"`
+	End        float64 `json:"end,omitempty"` // 0 = end of file
+	Integrated float64 `json:"integrated"`    // Integrated loudness, LUFS
+	TruePeak   float64 `json:"true_peak"`     // dBTP
+	Range      float64 `json:"range"`         // Loudness range, LU
+	Threshold  float64 `json:"threshold"`     // Gating threshold, LUFS
+}
+
+// MeasureLoudness runs the first loudnorm pass over the first audio stream of the input
+func (e *Executor) MeasureLoudness(ctx context.Context, input string, target LoudnessTarget) (*LoudnessStats, error) {
+	output, err := e.ExecuteWithOutput(ctx, ExecuteOptions{Args: measureLoudnessArgs(input, 0, 0, target)})
+	if err != nil {
+		return nil, err
+	}
+	if isDryRun(ctx) {
+		// Nothing was measured, the normalizing pass is planned with neutral values
+		return &LoudnessStats{}, nil
+	}
+
+	return parseLoudnormOutput(output)
+}
+
+// AnalyzeLoudness measures the loudness of the first audio stream of input between
+// start and end (0 = end of file), e.g. to decide whether to normalize on export
+func (e *Executor) AnalyzeLoudness(ctx context.Context, input string, start, end float64) (*LoudnessAnalysis, error) {
+	if end > 0 && end <= start {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	// The target does not affect the measured input values
+	output, err := e.ExecuteWithOutput(ctx, ExecuteOptions{Args: measureLoudnessArgs(input, start, end, LoudnessTarget{})})
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := parseLoudnormOutput(output)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoudnessAnalysis{
+		Start:      start,
+		End:        end,
+		Integrated: stats.InputI,
+		TruePeak:   stats.InputTP,
+		Range:      stats.InputLRA,
+		Threshold:  stats.InputThresh,
+	}, nil
+}
+
+// measureLoudnessArgs returns a loudnorm measuring pass over start-end of input
+func measureLoudnessArgs(input string, start, end float64, target LoudnessTarget) []string {
+	args := []string{"-hide_banner"}
+	if start > 0 {
+		args = append(args, "-ss", fmt.Sprintf("%.6f", start))
+	}
+	if end > 0 {
+		args = append(args, "-to", fmt.Sprintf("%.6f", end))
+	}
+	return append(args,
+		"-i", input,
+		"-map", "0:a:0",
+		"-af", loudnormFilter(target, nil)+":print_format=json",
+		"-f", "null",
+		"-",
+	)
+}
+
+// NormalizeLoudness copies input to output with the first audio stream re-encoded
+// normalized to the target, using stats from MeasureLoudness. The other streams,
+// including further audio tracks the stats don't describe, are copied.
+func (e *Executor) NormalizeLoudness(ctx context.Context, input, output string, target LoudnessTarget, stats *LoudnessStats, audioCodec, audioBitrate string) error {
+	args, err := normalizeLoudnessArgs(ctx, input, output, target, stats, audioCodec, audioBitrate)
+	if err != nil {
+		return err
+	}
+	return e.Execute(ctx, ExecuteOptions{Args: args})
+}
+
+// normalizeLoudnessArgs returns the arguments of NormalizeLoudness
+func normalizeLoudnessArgs(ctx context.Context, input, output string, target LoudnessTarget, stats *LoudnessStats, audioCodec, audioBitrate string) ([]string, error) {
+	codecArgs, err := audioCodecArgs(audioCodec, audioBitrate)
+	if err != nil {
+		return nil, err
+	}
+	// The codec options apply to the normalized stream only
+	for i, arg := range codecArgs {
+		if arg == "-c:a" || arg == "-b:a" {
+			codecArgs[i] = arg + ":0"
+		}
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-i", input,
+		"-map", "0",
+		"-c", "copy",
+		// loudnorm resamples to 192kHz internally, so set the output rate explicitly
+		"-filter:a:0", loudnormFilter(target, stats),
+		"-ar:a:0", "48000",
+	}
+	args = append(args, metadataArgs(ctx, 0, true)...)
+	args = append(args, codecArgs...)
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".mp4", ".mov", ".m4a":
+		args = append(args, "-movflags", "+faststart")
+	}
+	return append(args, "-y", output), nil
+}
+
+// parseLoudnormOutput extracts the JSON block loudnorm prints at the end of stderr.
+// loudnorm reports its values as strings, e.g. "input_i" : "-23.54".
+func parseLoudnormOutput(output string) (*LoudnessStats, error) {
+	start := strings.LastIndex(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("loudnorm output not found")
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal([]byte(output[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse loudnorm output: %w", err)
+	}
+
+	values := make(map[string]float64)
+	for _, key := range []string{"input_i", "input_tp", "input_lra", "input_thresh", "target_offset"} {
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw[key]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid loudnorm value %s: %q", key, raw[key])
+		}
+		values[key] = value
+	}
+
+	return &LoudnessStats{
+		InputI:       values["input_i"],
+		InputTP:      values["input_tp"],
+		InputLRA:     values["input_lra"],
+		InputThresh:  values["input_thresh"],
+		TargetOffset: values["target_offset"],
+	}, nil
+}
@@ -0,0 +1,91 @@
+package ffmpeg
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLoudnormOutput(t *testing.T) {
+	output := `size=N/A time=00:00:10.00 bitrate=N/A speed= 412x
+[Parsed_loudnorm_0 @ 0x55d4c8a3c0c0]
+{
+	"input_i" : "-27.61",
+	"input_tp" : "-4.47",
+	"input_lra" : "18.06",
+	"input_thresh" : "-39.20",
+	"output_i" : "-16.58",
+	"output_tp" : "-1.50",
+	"output_lra" : "14.78",
+	"output_thresh" : "-27.71",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.58"
+}
+`
+
+	got, err := parseLoudnormOutput(output)
+	if err != nil {
+		t.Fatalf("parseLoudnormOutput() error = %v", err)
+	}
+
+	want := &LoudnessStats{InputI: -27.61, InputTP: -4.47, InputLRA: 18.06, InputThresh: -39.2, TargetOffset: 0.58}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLoudnormOutput() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseLoudnormOutputMissing(t *testing.T) {
+	if _, err := parseLoudnormOutput("Output file is empty, nothing was encoded"); err == nil {
+		t.Error("parseLoudnormOutput() expected error for missing JSON")
+	}
+}
+
+func TestLoudnormFilter(t *testing.T) {
+	stats := &LoudnessStats{InputI: -27.61, InputTP: -4.47, InputLRA: 18.06, InputThresh: -39.2, TargetOffset: 0.58}
+
+	want := "loudnorm=I=-23:TP=-1.5:LRA=11:measured_I=-27.61:measured_TP=-4.47:measured_LRA=18.06:measured_thresh=-39.2:offset=0.58:linear=true"
+	if got := loudnormFilter(LoudnessTarget{Integrated: -23}, stats); got != want {
+		t.Errorf("loudnormFilter() = %q, want %q", got, want)
+	}
+}
+
+func TestMeasureLoudnessArgs(t *testing.T) {
+	want := []string{
+		"-hide_banner",
+		"-ss", "10.500000",
+		"-to", "20.000000",
+		"-i", "in.mp4",
+		"-map", "0:a:0",
+		"-af", "loudnorm=I=-16:TP=-1.5:LRA=11:print_format=json",
+		"-f", "null",
+		"-",
+	}
+	if got := measureLoudnessArgs("in.mp4", 10.5, 20, LoudnessTarget{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("measureLoudnessArgs() = %v, want %v", got, want)
+	}
+
+	// Whole file
+	if got := measureLoudnessArgs("in.mp4", 0, 0, LoudnessTarget{}); got[1] != "-i" {
+		t.Errorf("measureLoudnessArgs() whole file = %v", got)
+	}
+}
+
+func TestNormalizeLoudnessArgs(t *testing.T) {
+	stats := &LoudnessStats{InputI: -27.61, InputTP: -4.47, InputLRA: 18.06, InputThresh: -39.2, TargetOffset: 0.58}
+	args, err := normalizeLoudnessArgs(context.Background(), "piece.webm", "out.webm", LoudnessTarget{}, stats, "opus", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every stream is kept, only the first audio stream is re-encoded
+	got := strings.Join(args, " ")
+	for _, want := range []string{"-map 0 -c copy -filter:a:0 loudnorm=", "-ar:a:0 48000", "-c:a:0 libopus -b:a:0 128k"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("normalizeLoudnessArgs() = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "0:v?") || strings.Contains(got, "-c:a ") {
+		t.Errorf("normalizeLoudnessArgs() = %q, maps or encodes streams by type", got)
+	}
+}
@@ -0,0 +1,158 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	dropMetadataKey contextKey = "ffmpeg-drop-metadata"
+	privacyKey      contextKey = "ffmpeg-privacy"
+	metadataTagsKey contextKey = "ffmpeg-metadata-tags"
+)
+
+// metadataTagKeyPattern matches container tag names such as title or creation_time
+var metadataTagKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,63}$`)
+
+// maxMetadataTagLength is the longest tag value accepted
+const maxMetadataTagLength = 4096
+
+// WithoutMetadata returns a context whose cut, encode and merge commands drop global
+// metadata, chapters and attachments instead of carrying them over from the source
+func WithoutMetadata(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dropMetadataKey, true)
+}
+
+// WithPrivacy returns a context whose export commands remove everything that could
+// identify the recording: global tags (GPS location, device make and model, creation
+// time), chapters, attachments, per-stream tags such as handler names, and the
+// encoder version tags FFmpeg itself writes.
+func WithPrivacy(ctx context.Context) context.Context {
+	return context.WithValue(WithoutMetadata(ctx), privacyKey, true)
+}
+
+// WithMetadataTags returns a context whose cut, encode and merge commands set the
+// given global tags, e.g. title or artist. They are written on top of the source
+// tags, and also when source metadata is stripped. Tags must have been checked with
+// ValidateMetadataTags.
+func WithMetadataTags(ctx context.Context, tags map[string]string) context.Context {
+	return context.WithValue(ctx, metadataTagsKey, tags)
+}
+
+// ValidateMetadataTags checks user supplied container tags. creation_time must be
+// an RFC 3339 time or "now".
+func ValidateMetadataTags(tags map[string]string) error {
+	for key, value := range tags {
+		if !metadataTagKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid metadata tag name: %q", key)
+		}
+		if len(value) > maxMetadataTagLength || strings.ContainsRune(value, 0) {
+			return fmt.Errorf("invalid value for metadata tag %s", key)
+		}
+		if strings.EqualFold(key, "creation_time") && value != "now" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return fmt.Errorf("creation_time must be an RFC 3339 time or \"now\"")
+			}
+		}
+	}
+	return nil
+}
+
+// metadataTagArgs returns the -metadata options for the context's tags, sorted by name
+func metadataTagArgs(ctx context.Context) []string {
+	tags, _ := ctx.Value(metadataTagsKey).(map[string]string)
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, key := range keys {
+		args = append(args, "-metadata", key+"="+tags[key])
+	}
+	return args
+}
+
+// keepMetadata reports whether commands run with ctx should preserve metadata
+func keepMetadata(ctx context.Context) bool {
+	drop, _ := ctx.Value(dropMetadataKey).(bool)
+	return !drop
+}
+
+// privacyMode reports whether commands run with ctx should strip all metadata
+func privacyMode(ctx context.Context) bool {
+	privacy, _ := ctx.Value(privacyKey).(bool)
+	return privacy
+}
+
+// supportsAttachments reports whether the output container can store attachment
+// streams such as MKV fonts and cover art files
+func supportsAttachments(output string) bool {
+	switch strings.ToLower(filepath.Ext(output)) {
+	case ".mka", ".mks":
+		return true
+	}
+	return outputProfileFor(output).Attachments
+}
+
+// metadataArgs maps global tags, and chapters if set, from the given input, or drops them.
+// In privacy mode per-stream tags are dropped as well. Tags set with WithMetadataTags
+// are added in every case.
+func metadataArgs(ctx context.Context, input int, chapters bool) []string {
+	return append(sourceMetadataArgs(ctx, input, chapters), metadataTagArgs(ctx)...)
+}
+
+// sourceMetadataArgs maps or drops the metadata of the given input
+func sourceMetadataArgs(ctx context.Context, input int, chapters bool) []string {
+	if privacyMode(ctx) {
+		return []string{
+			"-map_metadata", "-1",
+			"-map_metadata:s:v", "-1",
+			"-map_metadata:s:a", "-1",
+			"-map_metadata:s:s", "-1",
+			"-map_chapters", "-1",
+			"-fflags", "+bitexact", // No muxer version tags or creation time
+			"-flags:v", "+bitexact",
+			"-flags:a", "+bitexact",
+		}
+	}
+	if !keepMetadata(ctx) {
+		return []string{"-map_metadata", "-1", "-map_chapters", "-1"}
+	}
+
+	chaptersInput := "-1"
+	if chapters {
+		chaptersInput = fmt.Sprint(input)
+	}
+	return []string{"-map_metadata", fmt.Sprint(input), "-map_chapters", chaptersInput}
+}
+
+// attachmentArgs maps the attachments of an input when the output can hold them.
+// mappedAll is set when a plain "-map N" already selected every stream of the input,
+// in which case attachments are excluded again for containers that would reject them.
+func attachmentArgs(ctx context.Context, input int, output string, mappedAll bool) []string {
+	keep := keepMetadata(ctx) && supportsAttachments(output)
+	switch {
+	case mappedAll && !keep:
+		return []string{"-map", fmt.Sprintf("-%d:t", input)}
+	case !mappedAll && keep:
+		return []string{"-map", fmt.Sprintf("%d:t?", input)}
+	}
+	return nil
+}
+
+// movFlags returns the MP4/MOV muxer flags. Custom global tags are only written to
+// MP4 when use_metadata_tags is set.
+func movFlags(ctx context.Context) string {
+	if keepMetadata(ctx) || len(metadataTagArgs(ctx)) > 0 {
+		return "+faststart+use_metadata_tags"
+	}
+	return "+faststart"
+}
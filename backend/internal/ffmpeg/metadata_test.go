@@ -0,0 +1,100 @@
+package ffmpeg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMetadataArgs(t *testing.T) {
+	ctx := context.Background()
+
+	if got, want := metadataArgs(ctx, 1, false), []string{"-map_metadata", "1", "-map_chapters", "-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("metadataArgs() = %v, want %v", got, want)
+	}
+	if got, want := metadataArgs(WithoutMetadata(ctx), 0, true), []string{"-map_metadata", "-1", "-map_chapters", "-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("metadataArgs() without metadata = %v, want %v", got, want)
+	}
+	privacy := metadataArgs(WithPrivacy(ctx), 0, true)
+	if len(privacy) < 4 || privacy[0] != "-map_metadata" || privacy[1] != "-1" {
+		t.Errorf("metadataArgs() in privacy mode = %v, want global metadata dropped", privacy)
+	}
+	for _, want := range []string{"-map_metadata:s:v", "-map_metadata:s:a", "+bitexact"} {
+		found := false
+		for _, arg := range privacy {
+			found = found || arg == want
+		}
+		if !found {
+			t.Errorf("metadataArgs() in privacy mode = %v, missing %s", privacy, want)
+		}
+	}
+	if keepMetadata(WithPrivacy(ctx)) {
+		t.Error("privacy mode keeps metadata")
+	}
+
+	if got := movFlags(WithoutMetadata(ctx)); got != "+faststart" {
+		t.Errorf("movFlags() without metadata = %q", got)
+	}
+}
+
+func TestAttachmentArgs(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		output    string
+		mappedAll bool
+		want      []string
+	}{
+		{"mkv keeps mapped attachments", ctx, "out.mkv", true, nil},
+		{"mkv maps attachments", ctx, "out.MKV", false, []string{"-map", "0:t?"}},
+		{"mp4 excludes attachments", ctx, "out.mp4", true, []string{"-map", "-0:t"}},
+		{"mp4 without map", ctx, "out.mp4", false, nil},
+		{"stripped mkv", WithoutMetadata(ctx), "out.mkv", true, []string{"-map", "-0:t"}},
+	}
+
+	for _, tt := range tests {
+		if got := attachmentArgs(tt.ctx, 0, tt.output, tt.mappedAll); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: attachmentArgs() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMetadataTagArgs(t *testing.T) {
+	ctx := WithMetadataTags(WithoutMetadata(context.Background()), map[string]string{
+		"title":  "My clip",
+		"artist": "Someone",
+	})
+
+	want := []string{"-map_metadata", "-1", "-map_chapters", "-1", "-metadata", "artist=Someone", "-metadata", "title=My clip"}
+	if got := metadataArgs(ctx, 0, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("metadataArgs() with tags = %v, want %v", got, want)
+	}
+	// Custom tags need use_metadata_tags to end up in MP4 files
+	if got := movFlags(ctx); got != "+faststart+use_metadata_tags" {
+		t.Errorf("movFlags() with tags = %q", got)
+	}
+}
+
+func TestValidateMetadataTags(t *testing.T) {
+	valid := map[string]string{"title": "a=b", "comment": "", "creation_time": "2024-05-01T12:00:00Z"}
+	if err := ValidateMetadataTags(valid); err != nil {
+		t.Errorf("ValidateMetadataTags(%v) = %v", valid, err)
+	}
+	if err := ValidateMetadataTags(map[string]string{"creation_time": "now"}); err != nil {
+		t.Errorf("ValidateMetadataTags(now) = %v", err)
+	}
+
+	for _, tags := range []map[string]string{
+		{"": "x"},
+		{"bad key": "x"},
+		{"title=x": "y"},
+		{"title": "nul\x00"},
+		{"creation_time": "yesterday"},
+	} {
+		if err := ValidateMetadataTags(tags); err == nil {
+			t.Errorf("ValidateMetadataTags(%q): want error", tags)
+		}
+	}
+}
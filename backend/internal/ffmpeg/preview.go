@@ -0,0 +1,89 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPreviewHeight is the height of export previews when none is requested
+const DefaultPreviewHeight = 360
+
+// PreviewSegment is a time range of the source included in a preview
+type PreviewSegment struct {
+	Start float64
+	End   float64
+}
+
+// PreviewOptions describes a preview of a planned export
+type PreviewOptions struct {
+	InputPath string
+	Segments  []PreviewSegment
+	Height    int // Output height, capped at the source height
+}
+
+// buildConcatList returns an ffconcat script that plays the segments of input in order
+func buildConcatList(input string, segments []PreviewSegment) string {
+	quoted := "'" + strings.ReplaceAll(input, "'", `'\''`) + "'"
+
+	var b strings.Builder
+	b.WriteString("ffconcat version 1.0\n")
+	for _, seg := range segments {
+		fmt.Fprintf(&b, "file %s\n", quoted)
+		fmt.Fprintf(&b, "inpoint %.6f\n", seg.Start)
+		fmt.Fprintf(&b, "outpoint %.6f\n", seg.End)
+	}
+	return b.String()
+}
+
+// previewArgs builds the arguments of a preview transcode reading the concat script
+// from stdin and writing fragmented MP4 to stdout, so it can be played while encoding
+func previewArgs(height int) []string {
+	if height <= 0 {
+		height = DefaultPreviewHeight
+	}
+
+	return []string{
+		"-hide_banner",
+		"-f", "concat",
+		"-safe", "0",
+		"-protocol_whitelist", "file,pipe",
+		"-i", "pipe:0",
+		"-map", "0:v:0?",
+		"-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale=-2:'min(%d,ih)'", height),
+		"-c:v", "libx264",
+		"-preset", "ultrafast",
+		"-tune", "zerolatency",
+		"-crf", "30",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", "96k",
+		"-ac", "2",
+		"-f", "mp4",
+		"-movflags", "frag_keyframe+empty_moov+default_base_moof",
+		"pipe:1",
+	}
+}
+
+// StreamPreview transcodes the segments of a planned export into a low-resolution
+// video and writes it to w as it is encoded
+func (e *Executor) StreamPreview(ctx context.Context, opts PreviewOptions, w io.Writer) error {
+	if len(opts.Segments) == 0 {
+		return fmt.Errorf("no segments to preview")
+	}
+
+	// Concat scripts resolve relative paths against the script, which is stdin here
+	input, err := filepath.Abs(opts.InputPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve input path: %w", err)
+	}
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args:      previewArgs(opts.Height),
+		StdinData: strings.NewReader(buildConcatList(input, opts.Segments)),
+		Stdout:    w,
+	})
+}
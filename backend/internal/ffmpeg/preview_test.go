@@ -0,0 +1,27 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildConcatList(t *testing.T) {
+	list := buildConcatList("/data/it's.mp4", []PreviewSegment{{Start: 1.5, End: 4}, {Start: 10, End: 12.25}})
+
+	want := "ffconcat version 1.0\n" +
+		"file '/data/it'\\''s.mp4'\ninpoint 1.500000\noutpoint 4.000000\n" +
+		"file '/data/it'\\''s.mp4'\ninpoint 10.000000\noutpoint 12.250000\n"
+	if list != want {
+		t.Errorf("buildConcatList() =\n%s\nwant\n%s", list, want)
+	}
+}
+
+func TestPreviewArgs(t *testing.T) {
+	args := strings.Join(previewArgs(0), " ")
+	if !strings.Contains(args, "scale=-2:'min(360,ih)'") {
+		t.Errorf("default height not applied: %s", args)
+	}
+	if !strings.HasSuffix(args, "-f mp4 -movflags frag_keyframe+empty_moov+default_base_moof pipe:1") {
+		t.Errorf("preview is not streamable fragmented MP4: %s", args)
+	}
+}
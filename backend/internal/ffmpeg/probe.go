@@ -42,6 +42,8 @@ type Stream struct {
 	SampleAspectRatio  string  `json:"sample_aspect_ratio,omitempty"`
 	DisplayAspectRatio string  `json:"display_aspect_ratio,omitempty"`
 	PixFmt             string  `json:"pix_fmt,omitempty"`
+	Profile            string  `json:"profile,omitempty"`
+	FieldOrder         string  `json:"field_order,omitempty"` // progressive, tt, bb, tb, bt or unknown
 	Level              int     `json:"level,omitempty"`
 	ColorRange         string  `json:"color_range,omitempty"`
 	ColorSpace         string  `json:"color_space,omitempty"`
@@ -61,6 +63,13 @@ type Stream struct {
 	NbFrames           string  `json:"nb_frames,omitempty"`
 	Disposition        Disposition `json:"disposition"`
 	Tags               Tags    `json:"tags,omitempty"`
+	SideDataList       []SideData `json:"side_data_list,omitempty"`
+}
+
+// SideData is a side data entry of a stream, e.g. its display matrix
+type SideData struct {
+	SideDataType string  `json:"side_data_type"`
+	Rotation     float64 `json:"rotation,omitempty"` // Display matrix rotation, degrees counter-clockwise
 }
 
 // Disposition contains stream disposition flags
@@ -95,6 +104,13 @@ type Tags map[string]string
 
 // Probe extracts metadata from a media file using FFprobe
 func (e *Executor) Probe(ctx context.Context, filePath string) (*ProbeResult, error) {
+	// Wait for a process slot first, so time spent queued doesn't count against the timeout
+	release, err := e.acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("waiting to start ffprobe: %w", err)
+	}
+	defer release()
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -155,6 +171,15 @@ func (p *ProbeResult) GetVideoStreams() []Stream {
 	return videos
 }
 
+// IsInterlaced reports whether ffprobe found the stream to be field coded
+func (s Stream) IsInterlaced() bool {
+	switch s.FieldOrder {
+	case "tt", "bb", "tb", "bt":
+		return true
+	}
+	return false
+}
+
 // GetAudioStreams returns all audio streams
 func (p *ProbeResult) GetAudioStreams() []Stream {
 	var audios []Stream
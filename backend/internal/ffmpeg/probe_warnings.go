@@ -0,0 +1,100 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Probe warning codes
+const (
+	WarningVariableFrameRate = "variable_frame_rate"
+	WarningMissingDuration   = "missing_duration"
+	WarningDataStream        = "data_stream"
+)
+
+// Suggested ways around a probe warning
+const (
+	SuggestSmartCut = "smart_cut" // Re-encode around cut points instead of copying
+	SuggestRemux    = "remux"     // Remux the file before editing it
+)
+
+// ProbeWarning is a property of a file that commonly breaks lossless cutting or
+// merging
+type ProbeWarning struct {
+	Code       string
+	Stream     int // Index of the affected stream, -1 for the whole file
+	Message    string
+	Suggestion string
+}
+
+// Warnings returns the properties of the probed file that are likely to cause
+// trouble when cutting or concatenating it
+func (p *ProbeResult) Warnings() []ProbeWarning {
+	var warnings []ProbeWarning
+
+	if duration, err := p.GetDuration(); err != nil || duration <= 0 {
+		warnings = append(warnings, ProbeWarning{
+			Code:       WarningMissingDuration,
+			Stream:     -1,
+			Message:    "The container doesn't store a duration, so seeking and progress are unreliable",
+			Suggestion: SuggestRemux,
+		})
+	}
+
+	for _, stream := range p.Streams {
+		switch {
+		case stream.CodecType == "video" && stream.Disposition.AttachedPic == 0 && stream.IsVariableFrameRate():
+			warnings = append(warnings, ProbeWarning{
+				Code:       WarningVariableFrameRate,
+				Stream:     stream.Index,
+				Message:    fmt.Sprintf("Video stream %d has a variable frame rate, lossless cuts may drift or stutter", stream.Index),
+				Suggestion: SuggestSmartCut,
+			})
+		case stream.CodecType == "data":
+			name := stream.CodecTagString
+			if name == "" || strings.HasPrefix(name, "[") {
+				name = stream.CodecName
+			}
+			warnings = append(warnings, ProbeWarning{
+				Code:       WarningDataStream,
+				Stream:     stream.Index,
+				Message:    fmt.Sprintf("Data stream %d (%s) often breaks cutting and merging", stream.Index, name),
+				Suggestion: SuggestRemux,
+			})
+		}
+	}
+
+	return warnings
+}
+
+// IsVariableFrameRate reports whether the stream's average frame rate differs from
+// its base frame rate. Interlaced streams whose base rate counts fields are not
+// variable.
+func (s Stream) IsVariableFrameRate() bool {
+	base, ok1 := parseRate(s.RFrameRate)
+	avg, ok2 := parseRate(s.AvgFrameRate)
+	if !ok1 || !ok2 {
+		return false
+	}
+	if s.IsInterlaced() && math.Abs(base-2*avg)/base < 0.01 {
+		return false
+	}
+	return math.Abs(base-avg)/base >= 0.01
+}
+
+// parseRate parses an FFprobe rate such as "30000/1001". ok is false for unknown
+// rates like "0/0".
+func parseRate(rate string) (float64, bool) {
+	num, den, found := strings.Cut(rate, "/")
+	if !found {
+		den = "1"
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || n <= 0 || d <= 0 {
+		return 0, false
+	}
+	return n / d, true
+}
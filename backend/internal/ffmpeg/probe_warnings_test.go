@@ -0,0 +1,50 @@
+package ffmpeg
+
+import "testing"
+
+func TestProbeWarnings(t *testing.T) {
+	probe := &ProbeResult{
+		Format: Format{Duration: "N/A"},
+		Streams: []Stream{
+			{Index: 0, CodecType: "video", RFrameRate: "60/1", AvgFrameRate: "2997/100"},
+			{Index: 1, CodecType: "audio", RFrameRate: "0/0", AvgFrameRate: "0/0"},
+			{Index: 2, CodecType: "data", CodecName: "bin_data", CodecTagString: "tmcd"},
+		},
+	}
+
+	warnings := probe.Warnings()
+	want := []struct {
+		code   string
+		stream int
+	}{
+		{WarningMissingDuration, -1},
+		{WarningVariableFrameRate, 0},
+		{WarningDataStream, 2},
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("got %d warnings, want %d: %+v", len(warnings), len(want), warnings)
+	}
+	for i, w := range want {
+		if warnings[i].Code != w.code || warnings[i].Stream != w.stream {
+			t.Errorf("warning %d = %+v, want %s on stream %d", i, warnings[i], w.code, w.stream)
+		}
+	}
+}
+
+func TestIsVariableFrameRate(t *testing.T) {
+	tests := []struct {
+		stream Stream
+		want   bool
+	}{
+		{Stream{RFrameRate: "30000/1001", AvgFrameRate: "30000/1001"}, false},
+		{Stream{RFrameRate: "30/1", AvgFrameRate: "1739/60"}, true},
+		{Stream{RFrameRate: "50/1", AvgFrameRate: "25/1", FieldOrder: "tt"}, false}, // Field rate
+		{Stream{RFrameRate: "50/1", AvgFrameRate: "25/1"}, true},
+		{Stream{RFrameRate: "25/1", AvgFrameRate: "0/0"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.stream.IsVariableFrameRate(); got != tt.want {
+			t.Errorf("IsVariableFrameRate(%s, %s) = %v, want %v", tt.stream.RFrameRate, tt.stream.AvgFrameRate, got, tt.want)
+		}
+	}
+}
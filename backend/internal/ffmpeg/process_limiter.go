@@ -0,0 +1,92 @@
+package ffmpeg
+
+import (
+	"context"
+	"os/exec"
+	"sync/atomic"
+)
+
+// ProcessLimiter caps how many ffmpeg and ffprobe processes run at once across the
+// executors sharing it; further processes queue until a slot frees up
+type ProcessLimiter struct {
+	slots   chan struct{}
+	running atomic.Int64
+	queued  atomic.Int64
+}
+
+// ProcessStats is a snapshot of a ProcessLimiter
+type ProcessStats struct {
+	Max     int   `json:"max"` // 0 = unlimited
+	Running int64 `json:"running"`
+	Queued  int64 `json:"queued"`
+}
+
+// NewProcessLimiter creates a limiter allowing max concurrent processes, 0 = unlimited
+func NewProcessLimiter(max int) *ProcessLimiter {
+	l := &ProcessLimiter{}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// Acquire waits for a free slot, the returned function releases it. It fails when
+// ctx is done before a slot frees up.
+func (l *ProcessLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l.slots != nil {
+		l.queued.Add(1)
+		select {
+		case l.slots <- struct{}{}:
+			l.queued.Add(-1)
+		case <-ctx.Done():
+			l.queued.Add(-1)
+			return nil, ctx.Err()
+		}
+	}
+
+	l.running.Add(1)
+	var released atomic.Bool
+	return func() {
+		if !released.CompareAndSwap(false, true) {
+			return
+		}
+		l.running.Add(-1)
+		if l.slots != nil {
+			<-l.slots
+		}
+	}, nil
+}
+
+// Stats reports the limit and the running and queued processes
+func (l *ProcessLimiter) Stats() ProcessStats {
+	return ProcessStats{
+		Max:     cap(l.slots),
+		Running: l.running.Load(),
+		Queued:  l.queued.Load(),
+	}
+}
+
+// SetProcessLimiter makes the executor share limiter with other executors, so the
+// limit applies to all of them together
+func (e *Executor) SetProcessLimiter(limiter *ProcessLimiter) {
+	e.processLimiter = limiter
+}
+
+// acquire waits for a process slot of the executor's limiter, if it has one
+func (e *Executor) acquire(ctx context.Context) (func(), error) {
+	if e.processLimiter == nil {
+		return func() {}, nil
+	}
+	return e.processLimiter.Acquire(ctx)
+}
+
+// probeOutput runs an ffprobe (or other short-lived) command within the process
+// limit and returns its stdout
+func (e *Executor) probeOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	release, err := e.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return cmd.Output()
+}
@@ -0,0 +1,77 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProcessLimiter(t *testing.T) {
+	limiter := NewProcessLimiter(2)
+	ctx := context.Background()
+
+	first, err := limiter.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limiter.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		release, err := limiter.Acquire(ctx)
+		if err == nil {
+			release()
+		}
+		close(acquired)
+	}()
+
+	// The third process queues until a slot frees up
+	deadline := time.Now().Add(time.Second)
+	for limiter.Stats().Queued != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stats := limiter.Stats(); stats != (ProcessStats{Max: 2, Running: 2, Queued: 1}) {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+
+	first()
+	first() // Releasing twice frees one slot only
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("queued process didn't start after a release")
+	}
+	if stats := limiter.Stats(); stats.Running != 1 || stats.Queued != 0 {
+		t.Errorf("Stats() after release = %+v", stats)
+	}
+}
+
+func TestProcessLimiterCancel(t *testing.T) {
+	limiter := NewProcessLimiter(1)
+	if _, err := limiter.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx); err == nil {
+		t.Error("Acquire() succeeded while the only slot is taken")
+	}
+	if queued := limiter.Stats().Queued; queued != 0 {
+		t.Errorf("Queued = %d after cancel", queued)
+	}
+}
+
+func TestProcessLimiterUnlimited(t *testing.T) {
+	limiter := NewProcessLimiter(0)
+	for i := 0; i < 10; i++ {
+		if _, err := limiter.Acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := limiter.Stats(); stats.Max != 0 || stats.Running != 10 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
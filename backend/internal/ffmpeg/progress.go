@@ -1,15 +1,31 @@
 package ffmpeg
 
 import (
-	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-// ProgressParser parses FFmpeg stderr output for progress information
+// Stats is a progress report of a running FFmpeg process
+type Stats struct {
+	OutTime float64 // Seconds of output written
+	Frame   int64   // Frames written
+	FPS     float64 // Frames encoded per second
+	Speed   float64 // Processing speed as a multiple of realtime, e.g. 4.5 for 4.5x
+	Size    int64   // Bytes of output written
+	Done    bool    // Last report, the process is finishing
+}
+
+// StatsRecorder receives the progress reports of FFmpeg invocations
+type StatsRecorder func(stats Stats)
+
+// progressArgs make FFmpeg write key=value progress reports to stdout instead of
+// stats lines to stderr
+var progressArgs = []string{"-progress", "pipe:1", "-nostats"}
+
+// ProgressParser parses the key=value reports FFmpeg writes with -progress
 type ProgressParser struct {
 	duration float64
+	current  Stats
 }
 
 // NewProgressParser creates a new progress parser
@@ -19,72 +35,59 @@ func NewProgressParser(duration float64) *ProgressParser {
 	}
 }
 
-// ParseLine parses a single line of FFmpeg output and returns progress (0-1)
-// Returns -1 if line doesn't contain progress information
-func (p *ProgressParser) ParseLine(line string) float64 {
-	// Match video progress: "frame=  123 fps= 45 q=28.0 size=  1024kB time=00:01:23.45 bitrate= 123.4kbits/s"
-	videoPattern := regexp.MustCompile(`frame=\s*\S+\s+fps=\s*\S+\s+q=\s*\S+\s+(?:size|Lsize)=\s*\S+\s+time=\s*(\S+)\s+`)
-	matches := videoPattern.FindStringSubmatch(line)
-
-	if len(matches) == 0 {
-		// Match audio-only progress: "size=  233422kB time=01:45:50.68 bitrate= 301.1kbits/s"
-		audioPattern := regexp.MustCompile(`(?:size|Lsize)=\s*\S+\s+time=\s*(\S+)\s+`)
-		matches = audioPattern.FindStringSubmatch(line)
-	}
-
-	if len(matches) < 2 {
-		return -1 // No progress found
+// ParseLine parses a single line of -progress output. Reports span several lines and
+// end with a "progress" key; ok is true when a line completes a report.
+func (p *ProgressParser) ParseLine(line string) (stats Stats, ok bool) {
+	key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+	if !found {
+		return Stats{}, false
 	}
+	value = strings.TrimSpace(value)
 
-	timeStr := matches[1]
-	currentTime, err := parseFFmpegTime(timeStr)
-	if err != nil {
-		return -1
-	}
-
-	// Handle negative time (sometimes FFmpeg outputs this)
-	if currentTime < 0 {
-		return -1
+	switch key {
+	case "out_time_us", "out_time_ms": // Both are in microseconds
+		if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+			p.current.OutTime = float64(us) / 1e6
+		}
+	case "frame":
+		if frame, err := strconv.ParseInt(value, 10, 64); err == nil {
+			p.current.Frame = frame
+		}
+	case "total_size":
+		if size, err := strconv.ParseInt(value, 10, 64); err == nil {
+			p.current.Size = size
+		}
+	case "fps":
+		if fps, err := strconv.ParseFloat(value, 64); err == nil {
+			p.current.FPS = fps
+		}
+	case "speed":
+		if speed, err := strconv.ParseFloat(strings.TrimSuffix(value, "x"), 64); err == nil {
+			p.current.Speed = speed
+		}
+	case "progress":
+		p.current.Done = value == "end"
+		return p.current, true
 	}
+	return Stats{}, false
+}
 
-	// Calculate progress
+// Fraction returns the progress of a report (0-1), or -1 if the duration is unknown
+func (p *ProgressParser) Fraction(stats Stats) float64 {
 	if p.duration <= 0 {
 		return -1
 	}
+	if stats.Done {
+		return 1
+	}
 
-	progress := currentTime / p.duration
+	progress := stats.OutTime / p.duration
 	if progress > 1 {
 		progress = 1
 	}
-
 	return progress
 }
 
-// parseFFmpegTime parses FFmpeg time format (HH:MM:SS.MS) to seconds
-func parseFFmpegTime(timeStr string) (float64, error) {
-	// Match format: [-]HH:MM:SS.MS
-	pattern := regexp.MustCompile(`^(-?)(\d+):(\d+):(\d+)\.(\d+)$`)
-	matches := pattern.FindStringSubmatch(timeStr)
-
-	if len(matches) != 6 {
-		return 0, fmt.Errorf("invalid time format: %s", timeStr)
-	}
-
-	sign := matches[1]
-	hours, _ := strconv.Atoi(matches[2])
-	minutes, _ := strconv.Atoi(matches[3])
-	seconds, _ := strconv.Atoi(matches[4])
-	centiseconds, _ := strconv.Atoi(matches[5])
-
-	totalSeconds := float64(hours*3600 + minutes*60 + seconds) + float64(centiseconds)/100.0
-
-	if sign == "-" {
-		totalSeconds = -totalSeconds
-	}
-
-	return totalSeconds, nil
-}
-
 // ParseFFmpegError extracts error message from FFmpeg stderr output
 func ParseFFmpegError(stderr string) string {
 	// Look for common FFmpeg error patterns
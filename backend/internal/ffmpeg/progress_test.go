@@ -1,91 +1,61 @@
 package ffmpeg
 
 import (
+	"strings"
 	"testing"
 )
 
-func TestParseFFmpegTime(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected float64
-		wantErr  bool
-	}{
-		{"00:00:05.50", 5.5, false},
-		{"00:01:30.00", 90.0, false},
-		{"01:23:45.67", 5025.67, false},
-		{"-00:00:06.46", -6.46, false},
-		{"invalid", 0, true},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			result, err := parseFFmpegTime(tt.input)
-
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("expected error but got none")
-				}
-				return
-			}
+func TestProgressParser_ParseLine(t *testing.T) {
+	parser := NewProgressParser(100.0)
 
-			if err != nil {
-				t.Errorf("unexpected error: %v", err)
-				return
-			}
+	report := `frame=1234
+fps=48.50
+stream_0_0_q=28.0
+bitrate=1234.5kbits/s
+total_size=1048576
+out_time_us=50000000
+out_time_ms=50000000
+out_time=00:00:50.000000
+dup_frames=0
+drop_frames=0
+speed=2.05x
+progress=continue`
 
-			if result != tt.expected {
-				t.Errorf("parseFFmpegTime(%q) = %f, want %f", tt.input, result, tt.expected)
-			}
-		})
+	var reports []Stats
+	for _, line := range strings.Split(report, "\n") {
+		if stats, ok := parser.ParseLine(line); ok {
+			reports = append(reports, stats)
+		}
+	}
+	if len(reports) != 1 {
+		t.Fatalf("ParseLine() completed %d reports, want 1", len(reports))
 	}
-}
-
-func TestProgressParser_ParseLine(t *testing.T) {
-	parser := NewProgressParser(100.0)
 
-	tests := []struct {
-		name     string
-		line     string
-		expected float64
-	}{
-		{
-			name:     "video progress",
-			line:     "frame=  123 fps= 45 q=28.0 size=  1024kB time=00:00:50.00 bitrate= 123.4kbits/s",
-			expected: 0.5,
-		},
-		{
-			name:     "audio progress",
-			line:     "size=  233422kB time=00:01:45.00 bitrate= 301.1kbits/s speed= 353x",
-			expected: 1.05, // Will be capped at 1.0
-		},
-		{
-			name:     "no progress",
-			line:     "Random FFmpeg output without time",
-			expected: -1,
-		},
-		{
-			name:     "negative time",
-			line:     "frame=  123 fps= 45 q=28.0 size=  1024kB time=-00:00:06.46 bitrate= 123.4kbits/s",
-			expected: -1,
-		},
+	want := Stats{OutTime: 50, Frame: 1234, FPS: 48.5, Speed: 2.05, Size: 1048576}
+	if reports[0] != want {
+		t.Errorf("report = %+v, want %+v", reports[0], want)
+	}
+	if fraction := parser.Fraction(reports[0]); fraction != 0.5 {
+		t.Errorf("Fraction() = %v, want 0.5", fraction)
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := parser.ParseLine(tt.line)
+	// Values not known yet are reported as N/A and keep their previous value
+	parser.ParseLine("out_time_us=N/A")
+	parser.ParseLine("speed=N/A")
+	last, ok := parser.ParseLine("progress=end")
+	if !ok || !last.Done || last.OutTime != 50 || last.Speed != 2.05 {
+		t.Errorf("final report = %+v, %v", last, ok)
+	}
+	if fraction := parser.Fraction(last); fraction != 1 {
+		t.Errorf("Fraction() of the final report = %v, want 1", fraction)
+	}
 
-			// For positive expected values, check if result is close (allow for capping at 1.0)
-			if tt.expected >= 0 {
-				if result < 0 {
-					t.Errorf("ParseLine() returned %f, expected positive value", result)
-				}
-				if tt.expected > 1.0 && result > 1.0 {
-					t.Errorf("ParseLine() should cap at 1.0, got %f", result)
-				}
-			} else if result != -1 {
-				t.Errorf("ParseLine() = %f, want %f", result, tt.expected)
-			}
-		})
+	// Past the expected duration is capped
+	if fraction := parser.Fraction(Stats{OutTime: 150}); fraction != 1 {
+		t.Errorf("Fraction() past the duration = %v, want 1", fraction)
+	}
+	if fraction := NewProgressParser(0).Fraction(Stats{OutTime: 5}); fraction != -1 {
+		t.Errorf("Fraction() without duration = %v, want -1", fraction)
 	}
 }
 
@@ -0,0 +1,144 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// Quality metrics an export can be verified with
+const (
+	QualitySSIM = "ssim"
+	QualityVMAF = "vmaf"
+	QualityBoth = "both"
+)
+
+// ValidQualityMetric reports whether a quality metric is supported. Empty means SSIM.
+func ValidQualityMetric(metric string) bool {
+	switch metric {
+	case "", QualitySSIM, QualityVMAF, QualityBoth:
+		return true
+	}
+	return false
+}
+
+// QualityMetrics contains objective quality scores of an output compared to its source
+type QualityMetrics struct {
+	SSIM float64 `json:"ssim,omitempty"` // 0.0-1.0, 1.0 = identical
+	VMAF float64 `json:"vmaf,omitempty"` // 0-100, higher = better
+}
+
+// QualityOptions contains options for quality comparison
+type QualityOptions struct {
+	ReferenceStart float64 // Start of the compared range in the source file
+	DistortedStart float64 // Start of the compared range in the output file
+	Duration       float64 // Length of the compared range
+	SSIM           bool
+	VMAF           bool // Requires an FFmpeg build with libvmaf
+}
+
+var (
+	ssimPattern = regexp.MustCompile(`SSIM .*All:\s*([\d.]+)`)
+	vmafPattern = regexp.MustCompile(`VMAF score[:=]\s*([\d.]+)`)
+)
+
+// CompareQuality computes SSIM and/or VMAF between a range of the reference (source)
+// file and the corresponding range of the distorted (exported) file
+func (e *Executor) CompareQuality(ctx context.Context, reference, distorted string, opts QualityOptions) (*QualityMetrics, error) {
+	if !opts.SSIM && !opts.VMAF {
+		opts.SSIM = true
+	}
+
+	filter := buildQualityFilter(opts.SSIM, opts.VMAF)
+
+	args := []string{
+		"-hide_banner",
+		"-ss", fmt.Sprintf("%.6f", opts.DistortedStart),
+		"-t", fmt.Sprintf("%.6f", opts.Duration),
+		"-i", distorted,
+		"-ss", fmt.Sprintf("%.6f", opts.ReferenceStart),
+		"-t", fmt.Sprintf("%.6f", opts.Duration),
+		"-i", reference,
+		"-filter_complex", filter,
+		"-an",
+		"-f", "null",
+		"-",
+	}
+
+	e.logger.Info("Comparing quality",
+		zap.String("reference", reference),
+		zap.String("distorted", distorted),
+		zap.Bool("ssim", opts.SSIM),
+		zap.Bool("vmaf", opts.VMAF),
+	)
+
+	output, err := e.ExecuteWithOutput(ctx, ExecuteOptions{
+		Args:     args,
+		Duration: opts.Duration,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare quality: %w", err)
+	}
+
+	metrics, err := parseQualityOutput(output, opts.SSIM, opts.VMAF)
+	if err != nil {
+		return nil, err
+	}
+
+	e.logger.Info("Quality comparison completed",
+		zap.Float64("ssim", metrics.SSIM),
+		zap.Float64("vmaf", metrics.VMAF),
+	)
+
+	return metrics, nil
+}
+
+// buildQualityFilter builds a filter graph comparing input 0 (distorted) with input 1 (reference).
+// The distorted stream is scaled to the reference size so re-encodes with a different
+// resolution can still be compared.
+func buildQualityFilter(ssim, vmaf bool) string {
+	base := "[0:v]setpts=PTS-STARTPTS[d0];[1:v]setpts=PTS-STARTPTS[r0];[d0][r0]scale2ref=flags=bicubic[dist][ref]"
+
+	switch {
+	case ssim && vmaf:
+		return base + ";[dist]split[d1][d2];[ref]split[r1][r2];[d1][r1]ssim;[d2][r2]libvmaf"
+	case vmaf:
+		return base + ";[dist][ref]libvmaf"
+	default:
+		return base + ";[dist][ref]ssim"
+	}
+}
+
+// parseQualityOutput extracts SSIM and VMAF summary scores from FFmpeg stderr
+func parseQualityOutput(output string, ssim, vmaf bool) (*QualityMetrics, error) {
+	metrics := &QualityMetrics{}
+
+	if ssim {
+		matches := ssimPattern.FindAllStringSubmatch(output, -1)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no SSIM score found in ffmpeg output")
+		}
+		value, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSIM score: %w", err)
+		}
+		metrics.SSIM = value
+	}
+
+	if vmaf {
+		matches := vmafPattern.FindAllStringSubmatch(output, -1)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no VMAF score found in ffmpeg output (is ffmpeg built with libvmaf?)")
+		}
+		value, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse VMAF score: %w", err)
+		}
+		metrics.VMAF = value
+	}
+
+	return metrics, nil
+}
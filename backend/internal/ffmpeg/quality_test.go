@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+func TestParseQualityOutput(t *testing.T) {
+	output := "frame=  250 fps=120 q=-0.0 Lsize=N/A time=00:00:10.00 bitrate=N/A speed=4.8x\n" +
+		"[Parsed_ssim_6 @ 0x55d0c8] SSIM Y:0.991234 (20.571) U:0.995 (23.1) V:0.996 (24.0) All:0.993015 (21.558)\n" +
+		"[Parsed_libvmaf_7 @ 0x55d0c9] VMAF score: 94.871203\n"
+
+	tests := []struct {
+		name    string
+		ssim    bool
+		vmaf    bool
+		input   string
+		want    QualityMetrics
+		wantErr bool
+	}{
+		{"ssim only", true, false, output, QualityMetrics{SSIM: 0.993015}, false},
+		{"vmaf only", false, true, output, QualityMetrics{VMAF: 94.871203}, false},
+		{"both", true, true, output, QualityMetrics{SSIM: 0.993015, VMAF: 94.871203}, false},
+		{"missing vmaf", true, true, "SSIM Y:0.9 All:0.95 (13.0)", QualityMetrics{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseQualityOutput(tt.input, tt.ssim, tt.vmaf)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if *result != tt.want {
+				t.Errorf("parseQualityOutput() = %+v, want %+v", *result, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidQualityMetric(t *testing.T) {
+	for _, metric := range []string{"", QualitySSIM, QualityVMAF, QualityBoth} {
+		if !ValidQualityMetric(metric) {
+			t.Errorf("ValidQualityMetric(%q) = false, want true", metric)
+		}
+	}
+	for _, metric := range []string{"psnr", "SSIM"} {
+		if ValidQualityMetric(metric) {
+			t.Errorf("ValidQualityMetric(%q) = true, want false", metric)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const rotationKey contextKey = "ffmpeg-rotation"
+
+// ErrDisplayRotation is returned when a rotation without re-encoding is asked of an
+// FFmpeg build without -display_rotation
+var ErrDisplayRotation = errors.New("rotating without re-encoding needs FFmpeg 6.1 or later, re-encode to rotate")
+
+// ValidRotation reports whether degrees is a supported display rotation
+func ValidRotation(degrees int) bool {
+	switch degrees {
+	case 0, 90, 180, 270:
+		return true
+	}
+	return false
+}
+
+// WithRotation returns a context whose cut and encode commands set the display
+// rotation of the video to degrees clockwise. Stream copies only get a new display
+// matrix, re-encodes are rotated by FFmpeg's autorotation.
+func WithRotation(ctx context.Context, degrees int) context.Context {
+	return context.WithValue(ctx, rotationKey, degrees)
+}
+
+// DisplayRotation returns the display rotation of a stream in degrees clockwise,
+// from its display matrix or the rotate tag older FFprobe versions report
+func (s Stream) DisplayRotation() int {
+	degrees := 0.0
+	for _, sideData := range s.SideDataList {
+		if sideData.SideDataType == "Display Matrix" {
+			degrees = -sideData.Rotation
+		}
+	}
+	if degrees == 0 {
+		if tag, err := strconv.ParseFloat(s.Tags["rotate"], 64); err == nil {
+			degrees = tag
+		}
+	}
+	return ((int(math.Round(degrees)) % 360) + 360) % 360
+}
+
+// rotationArgs returns the input options overriding the display rotation of the
+// first video stream, if set. Requires FFmpeg 6.1 or later.
+func rotationArgs(ctx context.Context) []string {
+	degrees, ok := ctx.Value(rotationKey).(int)
+	if !ok {
+		return nil
+	}
+	// display_rotation is counter-clockwise
+	return []string{"-display_rotation:v:0", fmt.Sprint((360 - degrees) % 360)}
+}
+
+// SupportsDisplayRotation reports whether the build has -display_rotation, added
+// in FFmpeg 6.1. Builds without a release version, e.g. git snapshots
+// "N-113000-g1234abcd", are assumed to be recent enough.
+func (c *Capabilities) SupportsDisplayRotation() bool {
+	var major, minor int
+	n, _ := fmt.Sscanf(strings.TrimPrefix(c.FFmpegVersion, "n"), "%d.%d", &major, &minor)
+	if n == 0 {
+		return true
+	}
+	return major > 6 || (major == 6 && minor >= 1)
+}
+
+// supportsDisplayRotation reports whether the installed FFmpeg has -display_rotation.
+// If its capabilities can't be discovered, commands are run and fail there.
+func (e *Executor) supportsDisplayRotation(ctx context.Context) bool {
+	caps, err := e.Capabilities(ctx, false)
+	return err != nil || caps.SupportsDisplayRotation()
+}
+
+// copyRotationArgs returns rotationArgs for a stream copy, which FFmpeg before 6.1
+// can't rotate
+func (e *Executor) copyRotationArgs(ctx context.Context) ([]string, error) {
+	args := rotationArgs(ctx)
+	if args != nil && !e.supportsDisplayRotation(ctx) {
+		return nil, ErrDisplayRotation
+	}
+	return args, nil
+}
+
+// encodeRotationArgs returns rotationArgs for a re-encode. FFmpeg before 6.1 turns
+// the decoded picture with transpose instead: autorotation is disabled, the set
+// rotation replaces the source's, and the old rotate tag is cleared on the output.
+func (e *Executor) encodeRotationArgs(ctx context.Context, opts *EncodeOptions) (inputArgs, outputArgs []string) {
+	degrees, ok := ctx.Value(rotationKey).(int)
+	if !ok || e.supportsDisplayRotation(ctx) {
+		return rotationArgs(ctx), nil
+	}
+	opts.transpose = degrees
+	return []string{"-noautorotate"}, []string{"-metadata:s:v:0", "rotate=0"}
+}
+
+// transposeFilter returns the filter turning the picture degrees clockwise, "" for 0
+func transposeFilter(degrees int) string {
+	switch degrees {
+	case 90:
+		return "transpose=clock"
+	case 180:
+		return "hflip,vflip"
+	case 270:
+		return "transpose=cclock"
+	}
+	return ""
+}
+
+// Rotate remuxes input to output with the display rotation set to degrees clockwise,
+// without re-encoding. Players rotate the picture on playback.
+func (e *Executor) Rotate(ctx context.Context, input, output string, degrees int) error {
+	if !ValidRotation(degrees) {
+		return fmt.Errorf("invalid rotation %d, use 0, 90, 180 or 270", degrees)
+	}
+
+	rotation, err := e.copyRotationArgs(WithRotation(ctx, degrees))
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-hide_banner"}
+	args = append(args, rotation...)
+	args = append(args,
+		"-i", input,
+		"-map", "0",
+		"-map_metadata", "0",
+		"-map_chapters", "0",
+		"-c", "copy",
+	)
+	args = append(args, muxArgs(ctx, output)...)
+	args = append(args,
+		"-y",
+		output,
+	)
+
+	return e.Execute(ctx, ExecuteOptions{Args: args})
+}
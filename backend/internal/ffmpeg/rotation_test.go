@@ -0,0 +1,93 @@
+package ffmpeg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRotationArgs(t *testing.T) {
+	if args := rotationArgs(context.Background()); args != nil {
+		t.Errorf("expected no args without rotation, got %v", args)
+	}
+
+	tests := map[int]string{0: "0", 90: "270", 180: "180", 270: "90"}
+	for degrees, want := range tests {
+		got := rotationArgs(WithRotation(context.Background(), degrees))
+		if !reflect.DeepEqual(got, []string{"-display_rotation:v:0", want}) {
+			t.Errorf("rotation %d: got %v", degrees, got)
+		}
+	}
+}
+
+func TestValidRotation(t *testing.T) {
+	for _, degrees := range []int{0, 90, 180, 270} {
+		if !ValidRotation(degrees) {
+			t.Errorf("expected %d to be valid", degrees)
+		}
+	}
+	for _, degrees := range []int{-90, 45, 360} {
+		if ValidRotation(degrees) {
+			t.Errorf("expected %d to be invalid", degrees)
+		}
+	}
+}
+
+func TestDisplayRotation(t *testing.T) {
+	tests := []struct {
+		stream Stream
+		want   int
+	}{
+		{Stream{}, 0},
+		{Stream{SideDataList: []SideData{{SideDataType: "Display Matrix", Rotation: -90}}}, 90},
+		{Stream{SideDataList: []SideData{{SideDataType: "Display Matrix", Rotation: 90}}}, 270},
+		{Stream{SideDataList: []SideData{{SideDataType: "Display Matrix", Rotation: 180}}}, 180},
+		{Stream{Tags: Tags{"rotate": "90"}}, 90},
+	}
+	for _, tt := range tests {
+		if got := tt.stream.DisplayRotation(); got != tt.want {
+			t.Errorf("DisplayRotation(%+v) = %d, want %d", tt.stream, got, tt.want)
+		}
+	}
+}
+
+func TestSupportsDisplayRotation(t *testing.T) {
+	tests := map[string]bool{
+		"6.1.1-3ubuntu5":         true,
+		"7.0":                    true,
+		"n6.1":                   true,
+		"6.0":                    false,
+		"5.1.4-0+deb12u1":        false,
+		"4.4.2-0ubuntu0.22.04.1": false,
+		"N-113000-g1234abcd":     true,
+	}
+	for version, want := range tests {
+		caps := &Capabilities{FFmpegVersion: version}
+		if got := caps.SupportsDisplayRotation(); got != want {
+			t.Errorf("SupportsDisplayRotation() of %s = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestEncodeRotationFallback(t *testing.T) {
+	e := &Executor{caps: &Capabilities{FFmpegVersion: "5.1"}}
+	ctx := WithRotation(context.Background(), 90)
+
+	if _, err := e.copyRotationArgs(ctx); err != ErrDisplayRotation {
+		t.Errorf("copyRotationArgs() with FFmpeg 5.1 = %v, want ErrDisplayRotation", err)
+	}
+
+	var opts EncodeOptions
+	input, output := e.encodeRotationArgs(ctx, &opts)
+	if !reflect.DeepEqual(input, []string{"-noautorotate"}) || !reflect.DeepEqual(output, []string{"-metadata:s:v:0", "rotate=0"}) {
+		t.Errorf("encodeRotationArgs() = %v, %v", input, output)
+	}
+	if filters := opts.videoFilters(0); !reflect.DeepEqual(filters, []string{"transpose=clock"}) {
+		t.Errorf("videoFilters() = %v, want the picture turned", filters)
+	}
+
+	e.caps.FFmpegVersion = "6.1"
+	if input, output := e.encodeRotationArgs(ctx, &EncodeOptions{}); !reflect.DeepEqual(input, []string{"-display_rotation:v:0", "270"}) || output != nil {
+		t.Errorf("encodeRotationArgs() with FFmpeg 6.1 = %v, %v", input, output)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -26,37 +27,22 @@ type SceneDetectionOptions struct {
 	Mode           string  `json:"mode"`             // "all", "black", "silent", "keyframe"
 }
 
-// DetectScenes detects scenes in a video using FFmpeg
+// DetectScenes detects scene changes in the first video stream. Each change is
+// returned as a "cut" scene whose Start and End are the time of the change and
+// whose Confidence is the scene score.
 func (e *Executor) DetectScenes(ctx context.Context, input string, opts SceneDetectionOptions) ([]Scene, error) {
-	args := []string{
-		"-hide_banner",
-		"-i", input,
-		"-vf", fmt.Sprintf("select='gt(scene,%f)+gt(scene,%f)'", opts.Threshold, opts.Threshold),
-		"-f", "null",
-		"-showframes",
-	}
-
-	// Add scene length filter if specified
-	if opts.MinSceneLength > 0 {
-		filter := fmt.Sprintf("select='gt(scene,%f)+gt(scene,%f),if(gt(t\\-prev_t,t),lt(t\\-prev_t+%f),st)'", opts.Threshold, opts.Threshold, opts.MinSceneLength)
-		args = append(args[:len(args)-1], "-vf", filter)
-	}
-
-	cmd := exec.CommandContext(ctx, e.ffmpegPath, args...)
-
 	e.logger.Info("Detecting scenes",
 		zap.String("input", input),
 		zap.Float64("threshold", opts.Threshold),
 		zap.String("mode", opts.Mode),
 	)
 
-	output, err := cmd.Output()
+	output, err := e.ExecuteWithOutput(ctx, ExecuteOptions{Args: sceneDetectionArgs(input, opts.Threshold)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect scenes: %w", err)
 	}
 
-	// Parse scene changes from FFmpeg output
-	scenes := parseSceneOutput(string(output))
+	scenes := filterShortScenes(parseSceneOutput(output), opts.MinSceneLength)
 
 	e.logger.Info("Scene detection completed",
 		zap.Int("scenes_found", len(scenes)),
@@ -65,25 +51,17 @@ func (e *Executor) DetectScenes(ctx context.Context, input string, opts SceneDet
 	return scenes, nil
 }
 
-// DetectBlackScenes detects black frames in video
-func (e *Executor) DetectBlackScenes(ctx context.Context, input string, minDuration float64) ([]Scene, error) {
-	args := []string{
-		"-hide_banner",
-		"-i", input,
-		"-vf", fmt.Sprintf("blackdetect=d=%f:pix_sat=0.00", minDuration),
-		"-f", "null",
-		"-showframes",
-	}
-
-	cmd := exec.CommandContext(ctx, e.ffmpegPath, args...)
+// DetectBlackScenes detects black frames in video.
+// pixelThreshold is the luminance (0.0-1.0) below which a pixel counts as black, 0 = FFmpeg default.
+func (e *Executor) DetectBlackScenes(ctx context.Context, input string, minDuration, pixelThreshold float64) ([]Scene, error) {
 	e.logger.Info("Detecting black scenes", zap.String("input", input))
 
-	output, err := cmd.Output()
+	output, err := e.ExecuteWithOutput(ctx, ExecuteOptions{Args: blackDetectionArgs(input, minDuration, pixelThreshold)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect black scenes: %w", err)
 	}
 
-	scenes := parseBlackSceneOutput(string(output))
+	scenes := parseBlackSceneOutput(output)
 
 	e.logger.Info("Black scene detection completed",
 		zap.Int("scenes_found", len(scenes)),
@@ -92,24 +70,17 @@ func (e *Executor) DetectBlackScenes(ctx context.Context, input string, minDurat
 	return scenes, nil
 }
 
-// DetectSilentScenes detects silent portions in audio
-func (e *Executor) DetectSilentScenes(ctx context.Context, input string, minDuration float64) ([]Scene, error) {
-	args := []string{
-		"-hide_banner",
-		"-i", input,
-		"-af", "silencedetect=noise=-30dB:duration=1",
-		"-f", "null",
-	}
-
-	cmd := exec.CommandContext(ctx, e.ffmpegPath, args...)
+// DetectSilentScenes detects silent portions in audio.
+// noiseDB is the level (e.g. -30) below which audio counts as silence, 0 = -30dB.
+func (e *Executor) DetectSilentScenes(ctx context.Context, input string, minDuration, noiseDB float64) ([]Scene, error) {
 	e.logger.Info("Detecting silent scenes", zap.String("input", input))
 
-	output, err := cmd.Output()
+	output, err := e.ExecuteWithOutput(ctx, ExecuteOptions{Args: silenceDetectionArgs(input, minDuration, noiseDB)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect silent scenes: %w", err)
 	}
 
-	scenes := parseSilentSceneOutput(string(output))
+	scenes := parseSilentSceneOutput(output)
 
 	e.logger.Info("Silent scene detection completed",
 		zap.Int("scenes_found", len(scenes)),
@@ -118,13 +89,83 @@ func (e *Executor) DetectSilentScenes(ctx context.Context, input string, minDura
 	return scenes, nil
 }
 
-// GetKeyframes extracts keyframe information from video
+// sceneDetectionArgs keeps only frames whose scene score exceeds threshold and logs
+// their metadata, which includes pts_time and lavfi.scene_score
+func sceneDetectionArgs(input string, threshold float64) []string {
+	return []string{
+		"-hide_banner",
+		"-i", input,
+		"-map", "0:v:0",
+		"-vf", fmt.Sprintf("select='gt(scene,%g)',metadata=print", threshold),
+		"-an",
+		"-f", "null",
+		"-",
+	}
+}
+
+// blackDetectionArgs runs blackdetect over the first video stream
+func blackDetectionArgs(input string, minDuration, pixelThreshold float64) []string {
+	filter := fmt.Sprintf("blackdetect=d=%g", minDuration)
+	if pixelThreshold > 0 {
+		filter += fmt.Sprintf(":pix_th=%g", pixelThreshold)
+	}
+
+	return []string{
+		"-hide_banner",
+		"-i", input,
+		"-map", "0:v:0",
+		"-vf", filter,
+		"-an",
+		"-f", "null",
+		"-",
+	}
+}
+
+// silenceDetectionArgs runs silencedetect over the first audio stream
+func silenceDetectionArgs(input string, minDuration, noiseDB float64) []string {
+	if minDuration <= 0 {
+		minDuration = 1
+	}
+	if noiseDB == 0 {
+		noiseDB = -30
+	}
+
+	return []string{
+		"-hide_banner",
+		"-i", input,
+		"-map", "0:a:0",
+		"-af", fmt.Sprintf("silencedetect=noise=%gdB:d=%g", noiseDB, minDuration),
+		"-vn",
+		"-f", "null",
+		"-",
+	}
+}
+
+// filterShortScenes drops scene changes that come less than minLength seconds
+// after the start of the video or the previous kept change
+func filterShortScenes(scenes []Scene, minLength float64) []Scene {
+	if minLength <= 0 {
+		return scenes
+	}
+
+	kept := make([]Scene, 0, len(scenes))
+	last := 0.0
+	for _, scene := range scenes {
+		if scene.Start-last >= minLength {
+			kept = append(kept, scene)
+			last = scene.Start
+		}
+	}
+	return kept
+}
+
+// GetKeyframes returns the sorted keyframe times of the first video stream. Only
+// packet flags are read, so the whole file is scanned without decoding it.
 func (e *Executor) GetKeyframes(ctx context.Context, input string) ([]float64, error) {
 	args := []string{
 		"-hide_banner",
 		"-select_streams", "v:0",
-		"-show_frames",
-		"-show_entries", "frame=pkt_pts_time,key_frame",
+		"-show_entries", "packet=pts_time,flags",
 		"-of", "csv=p=0",
 		input,
 	}
@@ -132,12 +173,14 @@ func (e *Executor) GetKeyframes(ctx context.Context, input string) ([]float64, e
 	cmd := exec.CommandContext(ctx, e.ffprobePath, args...)
 	e.logger.Info("Extracting keyframes", zap.String("input", input))
 
-	output, err := cmd.Output()
+	output, err := e.probeOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract keyframes: %w", err)
 	}
 
-	keyframes := parseKeyframeOutput(string(output))
+	// Packets are listed in decoding order
+	keyframes := parsePacketKeyframes(string(output))
+	sort.Float64s(keyframes)
 
 	e.logger.Info("Keyframe extraction completed",
 		zap.Int("keyframes_found", len(keyframes)),
@@ -146,117 +189,97 @@ func (e *Executor) GetKeyframes(ctx context.Context, input string) ([]float64, e
 	return keyframes, nil
 }
 
-// parseSceneOutput parses FFmpeg scene detection output
+// parseSceneOutput parses the frame metadata printed by metadata=print, e.g.
+//
+//	[Parsed_metadata_1 @ 0x55d0c8b3a940] frame:0    pts:180     pts_time:7.5
+//	[Parsed_metadata_1 @ 0x55d0c8b3a940] lavfi.scene_score=0.512345
 func parseSceneOutput(output string) []Scene {
 	var scenes []Scene
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		if strings.Contains(line, "scene:") {
-			// Parse scene information from FFmpeg log
-			parts := strings.Fields(line)
-			if len(parts) >= 4 {
-				start, err1 := strconv.ParseFloat(parts[1], 64)
-				if err1 == nil {
-					end, err2 := strconv.ParseFloat(parts[3], 64)
-					if err2 == nil {
-						scenes = append(scenes, Scene{
-							Start:      start,
-							End:        end,
-							Duration:   end - start,
-							Type:       "cut",
-							Confidence: 0.8, // Default confidence
-						})
-					}
-				}
-			}
+
+	for _, line := range strings.Split(output, "\n") {
+		if t, ok := logValue(line, "pts_time:"); ok {
+			scenes = append(scenes, Scene{
+				Start: t,
+				End:   t,
+				Type:  "cut",
+			})
+		} else if score, ok := logValue(line, "lavfi.scene_score="); ok && len(scenes) > 0 {
+			scenes[len(scenes)-1].Confidence = score
 		}
 	}
 
 	return scenes
 }
 
-// parseBlackSceneOutput parses blackdetect output
+// parseBlackSceneOutput parses blackdetect output, e.g.
+//
+//	[blackdetect @ 0x55d0c8b3a940] black_start:0 black_end:2.04 black_duration:2.04
 func parseBlackSceneOutput(output string) []Scene {
 	var scenes []Scene
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		if strings.Contains(line, "black_start:") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				if start, err := strconv.ParseFloat(parts[1], 64); err == nil {
-					scenes = append(scenes, Scene{
-						Start:      start,
-						Type:       "black",
-						Confidence: 0.9,
-					})
-				}
-			}
-		} else if strings.Contains(line, "black_end:") {
-			if len(scenes) > 0 {
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					if end, err := strconv.ParseFloat(parts[1], 64); err == nil {
-						scenes[len(scenes)-1].End = end
-						scenes[len(scenes)-1].Duration = end - scenes[len(scenes)-1].Start
-					}
-				}
-			}
+
+	for _, line := range strings.Split(output, "\n") {
+		start, ok1 := logValue(line, "black_start:")
+		end, ok2 := logValue(line, "black_end:")
+		if !ok1 || !ok2 {
+			continue
 		}
+		scenes = append(scenes, Scene{
+			Start:      start,
+			End:        end,
+			Duration:   end - start,
+			Type:       "black",
+			Confidence: 0.9,
+		})
 	}
 
 	return scenes
 }
 
-// parseSilentSceneOutput parses silencedetect output
+// parseSilentSceneOutput parses silencedetect output, which reports the start and
+// end of each silence on separate lines, e.g.
+//
+//	[silencedetect @ 0x55d0c8b3a940] silence_start: 10.5
+//	[silencedetect @ 0x55d0c8b3a940] silence_end: 12.3 | silence_duration: 1.8
+//
+// A silence still running when the input ends has no end on older FFmpeg
+// versions; its End is left at 0.
 func parseSilentSceneOutput(output string) []Scene {
 	var scenes []Scene
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		if strings.Contains(line, "silence_start:") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				if start, err := strconv.ParseFloat(parts[1], 64); err == nil {
-					scenes = append(scenes, Scene{
-						Start:      start,
-						Type:       "silent",
-						Confidence: 0.8,
-					})
-				}
-			}
-		} else if strings.Contains(line, "silence_end:") {
-			if len(scenes) > 0 {
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					if end, err := strconv.ParseFloat(parts[1], 64); err == nil {
-						scenes[len(scenes)-1].End = end
-						scenes[len(scenes)-1].Duration = end - scenes[len(scenes)-1].Start
-					}
-				}
-			}
+	open := false
+
+	for _, line := range strings.Split(output, "\n") {
+		if start, ok := logValue(line, "silence_start:"); ok {
+			scenes = append(scenes, Scene{
+				Start:      start,
+				Type:       "silent",
+				Confidence: 0.8,
+			})
+			open = true
+		} else if end, ok := logValue(line, "silence_end:"); ok && open {
+			scene := &scenes[len(scenes)-1]
+			scene.End = end
+			scene.Duration = end - scene.Start
+			open = false
 		}
 	}
 
 	return scenes
 }
 
-// parseKeyframeOutput parses keyframe CSV output
-func parseKeyframeOutput(output string) []float64 {
-	var keyframes []float64
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		if strings.Contains(line, ",1,") { // key_frame = 1
-			parts := strings.Split(line, ",")
-			if len(parts) >= 1 {
-				if time, err := strconv.ParseFloat(parts[0], 64); err == nil {
-					keyframes = append(keyframes, time)
-				}
-			}
-		}
+// logValue returns the number following key in a filter log line. Values may be
+// separated from the key by spaces, as in "silence_start: 10.5".
+func logValue(line, key string) (float64, bool) {
+	i := strings.Index(line, key)
+	if i < 0 {
+		return 0, false
 	}
-
-	return keyframes
+	fields := strings.Fields(line[i+len(key):])
+	if len(fields) == 0 {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
 }
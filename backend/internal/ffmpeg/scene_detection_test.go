@@ -0,0 +1,94 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSceneOutput(t *testing.T) {
+	output := `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'input.mp4':
+  Duration: 00:01:00.00, start: 0.000000, bitrate: 1205 kb/s
+[Parsed_metadata_1 @ 0x55d0c8b3a940] frame:0    pts:90090   pts_time:3.337
+[Parsed_metadata_1 @ 0x55d0c8b3a940] lavfi.scene_score=0.512345
+[Parsed_metadata_1 @ 0x55d0c8b3a940] frame:1    pts:1081080 pts_time:40.04
+[Parsed_metadata_1 @ 0x55d0c8b3a940] lavfi.scene_score=0.998000
+[out#0/null @ 0x55d0c8b3c480] video:1kB audio:0kB subtitle:0kB other streams:0kB global headers:0kB muxing overhead: unknown
+`
+
+	want := []Scene{
+		{Start: 3.337, End: 3.337, Type: "cut", Confidence: 0.512345},
+		{Start: 40.04, End: 40.04, Type: "cut", Confidence: 0.998},
+	}
+	if got := parseSceneOutput(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSceneOutput() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseBlackSceneOutput(t *testing.T) {
+	output := `[blackdetect @ 0x7f8b5c004b80] black_start:0 black_end:2.04 black_duration:2.04
+frame= 1500 fps=750 q=-0.0 size=N/A time=00:00:50.00 bitrate=N/A speed=  25x
+[blackdetect @ 0x7f8b5c004b80] black_start:58.5 black_end:60 black_duration:1.5
+`
+
+	want := []Scene{
+		{Start: 0, End: 2.04, Duration: 2.04, Type: "black", Confidence: 0.9},
+		{Start: 58.5, End: 60, Duration: 1.5, Type: "black", Confidence: 0.9},
+	}
+	if got := parseBlackSceneOutput(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBlackSceneOutput() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSilentSceneOutput(t *testing.T) {
+	output := `[silencedetect @ 0x5626f1f3e1c0] silence_start: 10.5
+[silencedetect @ 0x5626f1f3e1c0] silence_end: 12.25 | silence_duration: 1.75
+[silencedetect @ 0x5626f1f3e1c0] silence_start: -0.00133333
+[silencedetect @ 0x5626f1f3e1c0] silence_end: 3 | silence_duration: 3.00133
+[silencedetect @ 0x5626f1f3e1c0] silence_start: 55
+`
+
+	got := parseSilentSceneOutput(output)
+	want := []Scene{
+		{Start: 10.5, End: 12.25, Duration: 1.75, Type: "silent", Confidence: 0.8},
+		{Start: -0.00133333, End: 3, Duration: 3.00133333, Type: "silent", Confidence: 0.8},
+		{Start: 55, Type: "silent", Confidence: 0.8}, // Runs until the end of the input
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseSilentSceneOutput() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Start != want[i].Start || got[i].End != want[i].End || got[i].Type != want[i].Type {
+			t.Errorf("scene %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFilterShortScenes(t *testing.T) {
+	scenes := []Scene{{Start: 0.5}, {Start: 3}, {Start: 4}, {Start: 6}}
+
+	got := filterShortScenes(scenes, 2)
+	want := []Scene{{Start: 3}, {Start: 6}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterShortScenes() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDetectionArgs(t *testing.T) {
+	args := sceneDetectionArgs("in.mp4", 0.4)
+	if got := args[len(args)-1]; got != "-" {
+		t.Errorf("scene detection output = %q, want null output last", got)
+	}
+	if !reflect.DeepEqual(args[5:8], []string{"-vf", "select='gt(scene,0.4)',metadata=print", "-an"}) {
+		t.Errorf("sceneDetectionArgs() = %v", args)
+	}
+
+	args = blackDetectionArgs("in.mp4", 2, 0.1)
+	if args[6] != "blackdetect=d=2:pix_th=0.1" {
+		t.Errorf("blackDetectionArgs() filter = %q", args[6])
+	}
+
+	args = silenceDetectionArgs("in.mp4", 0, 0)
+	if args[6] != "silencedetect=noise=-30dB:d=1" {
+		t.Errorf("silenceDetectionArgs() filter = %q", args[6])
+	}
+}
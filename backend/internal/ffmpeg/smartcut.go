@@ -0,0 +1,315 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// gopCutTolerance is how close to a keyframe a cut point must be to count as on it
+const gopCutTolerance = 0.01
+
+// gopCutEncoders are the software encoders producing streams that can be joined with
+// stream copies of each source codec
+var gopCutEncoders = map[string]string{
+	"h264": "libx264",
+	"hevc": "libx265",
+}
+
+// gopCutProfiles map the profiles ffprobe reports to those of each encoder. Profiles
+// missing here can't be matched, e.g. HEVC range extensions.
+var gopCutProfiles = map[string]map[string]string{
+	"libx264": {
+		"Constrained Baseline":  "baseline",
+		"Baseline":              "baseline",
+		"Main":                  "main",
+		"High":                  "high",
+		"High 10":               "high10",
+		"High 4:2:2":            "high422",
+		"High 4:4:4 Predictive": "high444",
+	},
+	"libx265": {
+		"Main":    "main",
+		"Main 10": "main10",
+	},
+}
+
+// gopCutPixFmts are the pixel formats each encoder writes
+var gopCutPixFmts = map[string][]string{
+	"libx264": {"yuv420p", "yuvj420p", "yuv422p", "yuvj422p", "yuv444p", "yuvj444p", "yuv420p10le", "yuv422p10le", "yuv444p10le"},
+	"libx265": {"yuv420p", "yuvj420p", "yuv420p10le"},
+}
+
+// gopCutAnnexB are the bitstream filters that put the source's parameter sets in
+// band before each copied keyframe, so decoders switch from the head's
+var gopCutAnnexB = map[string]string{
+	"libx264": "h264_mp4toannexb",
+	"libx265": "hevc_mp4toannexb",
+}
+
+// matchPixFmt returns the pixel format an encoder writes for a source's: the same
+// one when the encoder writes it, yuv420p when it doesn't, and "" for encoders whose
+// formats aren't known, which then pick their own
+func matchPixFmt(encoder, source string) string {
+	pixFmts, known := gopCutPixFmts[encoder]
+	if !known {
+		return ""
+	}
+	for _, pixFmt := range pixFmts {
+		if pixFmt == source {
+			return source
+		}
+	}
+	return "yuv420p"
+}
+
+// videoKeyframesBetween returns the keyframe times of the first video stream from
+// about start to end, from the context's keyframe index or by reading packet flags,
+// so nothing is decoded.
+func (e *Executor) videoKeyframesBetween(ctx context.Context, input string, start, end float64) ([]float64, error) {
+	if keyframes, ok := contextKeyframes(ctx, input); ok {
+		return keyframesBetween(keyframes, start-gopCutTolerance, end), nil
+	}
+	return e.probeKeyframes(ctx, input, [][2]float64{{start, end}})
+}
+
+// parsePacketKeyframes parses "pts_time,flags" packet lines, keeping the times of
+// keyframe packets (flags starting with K)
+func parsePacketKeyframes(output string) []float64 {
+	var keyframes []float64
+	for _, line := range strings.Split(output, "\n") {
+		timeStr, flags, ok := strings.Cut(strings.TrimSpace(line), ",")
+		if !ok || !strings.HasPrefix(flags, "K") {
+			continue
+		}
+		if t, err := strconv.ParseFloat(timeStr, 64); err == nil {
+			keyframes = append(keyframes, t)
+		}
+	}
+	return keyframes
+}
+
+// gopCutBoundary returns where a GOP smart cut switches from re-encoding to stream
+// copy: the first keyframe after start, or the keyframe start is on.
+// ok is false when there is no keyframe before end, so the whole segment must be
+// re-encoded.
+func gopCutBoundary(keyframes []float64, start, end float64) (float64, bool) {
+	for _, kf := range keyframes {
+		if kf >= end-gopCutTolerance {
+			break
+		}
+		if kf >= start-gopCutTolerance {
+			return kf, true
+		}
+	}
+	return 0, false
+}
+
+// gopSmartCut cuts like LosslessCut's smart cut: only the partial GOP from start to
+// the next keyframe is re-encoded, the rest is stream copied, and both are joined.
+// handled is false when the source can't be cut this way, e.g. its codec has no
+// matching encoder or no keyframe falls inside the segment; the caller then
+// re-encodes the whole segment, as it does when an error is returned.
+func (e *Executor) gopSmartCut(ctx context.Context, opts SmartCutOptions) (handled bool, err error) {
+	probe, err := e.Probe(ctx, opts.Input)
+	if err != nil {
+		return false, err
+	}
+	videoStreams := probe.GetVideoStreams()
+	if len(videoStreams) == 0 {
+		return false, nil
+	}
+	video := videoStreams[0]
+	if !streamSelected(ctx, video.Index) {
+		// The head re-encodes the first video stream, it must be the one kept
+		return false, nil
+	}
+
+	encoder, ok := gopCutEncoders[video.CodecName]
+	if !ok || (opts.VideoCodec != "" && opts.VideoCodec != encoder && opts.VideoCodec != video.CodecName) {
+		// Only a re-encode to the source codec can be joined with copied GOPs
+		return false, nil
+	}
+	if _, ok := gopHeadEncoding(video, encoder); !ok {
+		// A head the decoder can't continue from with the copied GOPs would corrupt
+		// the output, cut accurately with stream copies instead
+		e.logger.Info("Source encoding can't be matched for a smart cut, cutting accurately",
+			zap.String("profile", video.Profile),
+			zap.Int("level", video.Level),
+			zap.String("pixFmt", video.PixFmt),
+		)
+		return true, e.CutVideoAccurate(ctx, opts.Input, opts.Output, opts.Start, opts.End, opts.OnProgress)
+	}
+
+	keyframes, err := e.videoKeyframesBetween(ctx, opts.Input, opts.Start, opts.End)
+	if err != nil {
+		return false, err
+	}
+	boundary, ok := gopCutBoundary(keyframes, opts.Start, opts.End)
+	if !ok {
+		return false, nil
+	}
+	if math.Abs(boundary-opts.Start) <= gopCutTolerance {
+		e.logger.Info("Smart cut starts on a keyframe, copying the whole segment")
+		return true, e.CutVideo(ctx, opts.Input, opts.Output, boundary, opts.End, opts.OnProgress)
+	}
+
+	e.logger.Info("Performing GOP smart cut",
+		zap.Float64("start", opts.Start),
+		zap.Float64("keyframe", boundary),
+		zap.Float64("end", opts.End),
+	)
+
+	// The parts use the output container, which holds every kept stream. The output
+	// takes the head's parameter sets, the body carries the source's in band.
+	ext := filepath.Ext(opts.Output)
+	base := strings.TrimSuffix(opts.Output, ext)
+	head := base + ".head" + ext
+	body := base + ".body" + ext
+	defer os.Remove(head)
+	defer os.Remove(body)
+
+	duration := opts.End - opts.Start
+	headShare := (boundary - opts.Start) / duration
+	progress := func(offset, share float64) ProgressCallback {
+		return func(p float64) {
+			if opts.OnProgress != nil {
+				opts.OnProgress(offset + p*share)
+			}
+		}
+	}
+
+	if err := e.Execute(ctx, ExecuteOptions{
+		Args:       gopHeadArgs(ctx, opts, video, encoder, boundary, head),
+		Duration:   boundary - opts.Start,
+		OnProgress: progress(0, headShare*0.9),
+	}); err != nil {
+		return true, fmt.Errorf("failed to encode smart cut start: %w", err)
+	}
+
+	if err := e.Execute(ctx, ExecuteOptions{
+		Args:       gopBodyArgs(ctx, encoder, boundary, opts.End, opts.Input, body),
+		Duration:   opts.End - boundary,
+		OnProgress: progress(headShare*0.9, (1-headShare)*0.9),
+	}); err != nil {
+		return true, fmt.Errorf("failed to copy smart cut remainder: %w", err)
+	}
+
+	if err := e.MergeVideos(ctx, []string{head, body}, opts.Output, opts.Input, nil, duration, progress(0.9, 0.1)); err != nil {
+		return true, fmt.Errorf("failed to join smart cut parts: %w", err)
+	}
+	return true, nil
+}
+
+// gopHeadEncoding returns the encoder arguments matching the source's profile, level,
+// pixel format and colors, so decoders continue from the head into the copied GOPs.
+// ok is false when the encoder can't produce such a stream.
+func gopHeadEncoding(video Stream, encoder string) (args []string, ok bool) {
+	profile, ok := gopCutProfiles[encoder][video.Profile]
+	if !ok || video.Level <= 0 {
+		return nil, false
+	}
+	pixFmtOK := false
+	for _, pixFmt := range gopCutPixFmts[encoder] {
+		pixFmtOK = pixFmtOK || pixFmt == video.PixFmt
+	}
+	if !pixFmtOK {
+		return nil, false
+	}
+
+	args = []string{"-pix_fmt", video.PixFmt, "-profile:v", profile}
+	switch encoder {
+	case "libx264":
+		// ffprobe reports H.264 levels times 10
+		args = append(args, "-level:v", fmt.Sprintf("%d.%d", video.Level/10, video.Level%10))
+	case "libx265":
+		// and HEVC levels times 30
+		args = append(args, "-x265-params", "level-idc="+strconv.FormatFloat(float64(video.Level)/30, 'f', 1, 64))
+	}
+	if video.ColorRange == "tv" || video.ColorRange == "pc" {
+		args = append(args, "-color_range", video.ColorRange)
+	}
+	if video.ColorSpace != "" && video.ColorSpace != "unknown" {
+		args = append(args, "-colorspace", video.ColorSpace)
+	}
+	return args, true
+}
+
+// gopPartMapArgs maps the kept streams of the source into a part of a GOP smart cut.
+// Attachments are left out, the join takes them from the source.
+func gopPartMapArgs(ctx context.Context) []string {
+	args, mappedAll := streamMapArgs(ctx, 0)
+	if mappedAll {
+		args = append(args, "-map", "-0:t")
+	}
+	return args
+}
+
+// gopHeadArgs re-encodes the first video stream from start to the keyframe at
+// boundary with the source's codec, encoding settings and interlacing, so the result
+// can be joined with the copied remainder. The other kept streams are copied.
+func gopHeadArgs(ctx context.Context, opts SmartCutOptions, video Stream, encoder string, boundary float64, output string) []string {
+	quality := opts.Quality
+	if quality == 0 {
+		quality = 18
+	}
+	preset := opts.Preset
+	if preset == "" {
+		preset = "fast"
+	}
+
+	args := []string{
+		"-hide_banner",
+		"-ss", fmt.Sprintf("%.6f", opts.Start),
+		"-i", opts.Input,
+		"-t", fmt.Sprintf("%.6f", boundary-opts.Start),
+	}
+	args = append(args, gopPartMapArgs(ctx)...)
+	args = append(args,
+		"-c", "copy",
+		"-c:v:0", encoder,
+		"-crf", strconv.Itoa(quality),
+		"-preset", preset,
+	)
+	if encoding, ok := gopHeadEncoding(video, encoder); ok {
+		args = append(args, encoding...)
+	}
+	// Keep interlaced sources field coded, a progressive head shows combing next to
+	// the copied interlaced GOPs
+	if video.IsInterlaced() && encoder == "libx264" {
+		args = append(args, "-flags", "+ildct+ilme")
+	}
+
+	return append(args,
+		"-avoid_negative_ts", "make_zero",
+		"-y",
+		output,
+	)
+}
+
+// gopBodyArgs stream copies the kept streams from the keyframe at boundary to end,
+// with the source's parameter sets in band for the decoder to switch to
+func gopBodyArgs(ctx context.Context, encoder string, boundary, end float64, input, output string) []string {
+	args := []string{
+		"-hide_banner",
+		"-ss", fmt.Sprintf("%.6f", boundary),
+		"-i", input,
+		"-t", fmt.Sprintf("%.6f", end-boundary),
+	}
+	args = append(args, gopPartMapArgs(ctx)...)
+	args = append(args, "-c", "copy")
+	if bsf, ok := gopCutAnnexB[encoder]; ok {
+		args = append(args, "-bsf:v:0", bsf)
+	}
+	return append(args,
+		"-avoid_negative_ts", "make_zero",
+		"-y",
+		output,
+	)
+}
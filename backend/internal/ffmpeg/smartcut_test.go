@@ -0,0 +1,169 @@
+package ffmpeg
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParsePacketKeyframes(t *testing.T) {
+	output := "0.000000,K__\n0.033367,___\n2.002000,K_\n2.035367,__\nN/A,K__\n"
+	if got := parsePacketKeyframes(output); !reflect.DeepEqual(got, []float64{0, 2.002}) {
+		t.Errorf("parsePacketKeyframes() = %v", got)
+	}
+}
+
+func TestGOPCutBoundary(t *testing.T) {
+	keyframes := []float64{0, 2, 4, 6}
+
+	tests := []struct {
+		start, end float64
+		want       float64
+		ok         bool
+	}{
+		{1.5, 10, 2, true},     // Re-encode 1.5-2, copy the rest
+		{2.05, 10, 4, true},    // Just past a keyframe
+		{4, 10, 4, true},       // On a keyframe, copy everything
+		{3.995, 10, 4, true},   // Within tolerance of a keyframe
+		{4.5, 5.5, 0, false},   // No keyframe inside, re-encode everything
+		{4.5, 6.005, 0, false}, // Keyframe at the very end doesn't help
+	}
+	for _, tt := range tests {
+		got, ok := gopCutBoundary(keyframes, tt.start, tt.end)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("gopCutBoundary(%v, %v) = %v, %v; want %v, %v", tt.start, tt.end, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGOPCutArgs(t *testing.T) {
+	opts := SmartCutOptions{Input: "in.mp4", Start: 1.5, End: 10}
+	video := Stream{CodecName: "h264", Profile: "High", Level: 40, PixFmt: "yuv420p", TimeBase: "1/90000"}
+
+	head := strings.Join(gopHeadArgs(context.Background(), opts, video, "libx264", 2, "out.head.mp4"), " ")
+	for _, want := range []string{"-ss 1.500000 -i in.mp4 -t 0.500000 -map 0 -map -0:t", "-c copy -c:v:0 libx264 -crf 18 -preset fast -pix_fmt yuv420p -profile:v high -level:v 4.0"} {
+		if !strings.Contains(head, want) {
+			t.Errorf("head args %q do not contain %q", head, want)
+		}
+	}
+
+	body := strings.Join(gopBodyArgs(context.Background(), "libx264", 2, 10, "in.mp4", "out.body.mp4"), " ")
+	if !strings.Contains(body, "-ss 2.000000 -i in.mp4 -t 8.000000 -map 0 -map -0:t -c copy -bsf:v:0 h264_mp4toannexb") {
+		t.Errorf("body args = %s", body)
+	}
+
+	// Selected streams are mapped in both parts
+	ctx := WithStreams(context.Background(), []int{0, 2, 3}, "")
+	head = strings.Join(gopHeadArgs(ctx, opts, video, "libx264", 2, "out.head.mp4"), " ")
+	body = strings.Join(gopBodyArgs(ctx, "libx264", 2, 10, "in.mp4", "out.body.mp4"), " ")
+	for _, args := range []string{head, body} {
+		if !strings.Contains(args, "-map 0:0 -map 0:2 -map 0:3 -c copy") {
+			t.Errorf("args %q don't map the selected streams", args)
+		}
+	}
+}
+
+func TestMatchPixFmt(t *testing.T) {
+	tests := []struct {
+		encoder, source, want string
+	}{
+		{"libx264", "yuv422p10le", "yuv422p10le"},
+		{"libx265", "yuv444p", "yuv420p"},
+		{"libx264", "", "yuv420p"},
+		{"libvpx-vp9", "yuv420p", ""},
+	}
+	for _, tt := range tests {
+		if got := matchPixFmt(tt.encoder, tt.source); got != tt.want {
+			t.Errorf("matchPixFmt(%s, %q) = %q, want %q", tt.encoder, tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestGOPHeadEncoding(t *testing.T) {
+	tests := []struct {
+		video   Stream
+		encoder string
+		want    string // Empty if the encoding can't be matched
+	}{
+		{Stream{Profile: "Constrained Baseline", Level: 31, PixFmt: "yuv420p"}, "libx264", "-pix_fmt yuv420p -profile:v baseline -level:v 3.1"},
+		{Stream{Profile: "High 10", Level: 51, PixFmt: "yuv420p10le", ColorRange: "tv", ColorSpace: "bt709"}, "libx264", "-pix_fmt yuv420p10le -profile:v high10 -level:v 5.1 -color_range tv -colorspace bt709"},
+		{Stream{Profile: "Main 10", Level: 153, PixFmt: "yuv420p10le"}, "libx265", "-pix_fmt yuv420p10le -profile:v main10 -x265-params level-idc=5.1"},
+		{Stream{Profile: "High", Level: 40, PixFmt: "nv12"}, "libx264", ""},     // Pixel format the encoder doesn't write
+		{Stream{Profile: "Rext", Level: 120, PixFmt: "yuv420p"}, "libx265", ""}, // Unknown profile
+		{Stream{Profile: "High", Level: -99, PixFmt: "yuv420p"}, "libx264", ""}, // Unknown level
+		{Stream{Profile: "Main", Level: 93, PixFmt: "yuv422p"}, "libx265", ""},  // 4:2:2 needs a range extension profile
+	}
+	for _, tt := range tests {
+		args, ok := gopHeadEncoding(tt.video, tt.encoder)
+		if got := strings.Join(args, " "); got != tt.want || ok != (tt.want != "") {
+			t.Errorf("gopHeadEncoding(%+v, %s) = %q, %v; want %q", tt.video, tt.encoder, got, ok, tt.want)
+		}
+	}
+}
+
+func TestGOPSmartCutFixture(t *testing.T) {
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.mp4")
+	output := filepath.Join(dir, "out.mp4")
+
+	// Keyframes every 2 seconds, in a profile and level x264 doesn't pick by default
+	fixture := exec.Command("ffmpeg", "-hide_banner", "-v", "error",
+		"-f", "lavfi", "-i", "testsrc2=duration=6:size=320x240:rate=25",
+		"-f", "lavfi", "-i", "sine=duration=6",
+		"-c:v", "libx264", "-profile:v", "main", "-level:v", "3.1", "-pix_fmt", "yuv420p", "-g", "50", "-keyint_min", "50", "-sc_threshold", "0",
+		"-c:a", "aac", "-shortest", input)
+	if out, err := fixture.CombinedOutput(); err != nil {
+		t.Skipf("failed to create fixture, FFmpeg may lack libx264: %v: %s", err, out)
+	}
+
+	e := NewExecutor("ffmpeg", "ffprobe", zap.NewNop())
+	if handled, err := e.gopSmartCut(ctx, SmartCutOptions{Input: input, Output: output, Start: 1.3, End: 5}); !handled || err != nil {
+		t.Fatalf("gopSmartCut() = %v, %v", handled, err)
+	}
+
+	probe, err := e.Probe(ctx, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	videos := probe.GetVideoStreams()
+	if len(videos) != 1 || len(probe.GetAudioStreams()) != 1 {
+		t.Fatalf("output streams = %+v", probe.Streams)
+	}
+	if v := videos[0]; v.Profile != "Main" || v.Level != 31 || v.PixFmt != "yuv420p" {
+		t.Errorf("output video = %s level %d %s, want the source's Main level 31 yuv420p", v.Profile, v.Level, v.PixFmt)
+	}
+	if duration, _ := probe.GetDuration(); duration < 3.5 || duration > 3.9 {
+		t.Errorf("output duration = %v, want about 3.7", duration)
+	}
+
+	// The copied GOPs decode after the re-encoded head
+	decode := exec.Command("ffmpeg", "-hide_banner", "-v", "error", "-i", output, "-f", "null", "-")
+	if out, err := decode.CombinedOutput(); err != nil || len(out) > 0 {
+		t.Errorf("decoding the output failed: %v: %s", err, out)
+	}
+}
+
+func TestSmartCutArgsStreams(t *testing.T) {
+	opts := SmartCutOptions{Input: "in.mkv", Output: "out.mkv", VideoCodec: "libx264", AudioCodec: "aac", Quality: 18, Preset: "fast", pixFmt: "yuv422p"}
+
+	args := strings.Join(smartCutArgs(context.Background(), opts, 5, nil), " ")
+	for _, want := range []string{"-t 5.000000 -map 0 -c copy", "-c:v libx264 -crf 18 -preset fast -pix_fmt yuv422p", "-c:a aac"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("smart cut args %q do not contain %q", args, want)
+		}
+	}
+	if strings.Contains(args, "yuv420p") {
+		t.Errorf("smart cut args force yuv420p: %s", args)
+	}
+}
@@ -0,0 +1,69 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// MaxSnapshotBurst is the largest number of frames CaptureSnapshotBurst captures
+const MaxSnapshotBurst = 30
+
+// CaptureSnapshotBurst writes count consecutive JPEG frames centered on timestamp
+// in one run, e.g. to pick the sharpest thumbnail near a cut point. pattern is an
+// image sequence output such as "shot_%02d", numbered from 1. It returns the
+// approximate time of each frame; near the end of the input fewer frames may be
+// written.
+func (e *Executor) CaptureSnapshotBurst(ctx context.Context, input, pattern string, timestamp float64, count, quality int) ([]float64, error) {
+	if count < 1 || count > MaxSnapshotBurst {
+		return nil, fmt.Errorf("burst count must be between 1 and %d", MaxSnapshotBurst)
+	}
+
+	probe, err := e.Probe(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	videoStreams := probe.GetVideoStreams()
+	if len(videoStreams) == 0 {
+		return nil, fmt.Errorf("input has no video stream")
+	}
+	frameRate, ok := parseRate(videoStreams[0].AvgFrameRate)
+	if !ok {
+		if frameRate, ok = parseRate(videoStreams[0].RFrameRate); !ok {
+			frameRate = 25
+		}
+	}
+
+	start := burstStart(timestamp, frameRate, count)
+	if err := e.Execute(ctx, ExecuteOptions{Args: snapshotBurstArgs(input, pattern, start, count, quality)}); err != nil {
+		return nil, err
+	}
+
+	times := make([]float64, count)
+	for i := range times {
+		times[i] = start + float64(i)/frameRate
+	}
+	return times, nil
+}
+
+// burstStart returns the time of the first of count frames centered on timestamp
+func burstStart(timestamp, frameRate float64, count int) float64 {
+	return math.Max(0, timestamp-float64(count/2)/frameRate)
+}
+
+// snapshotBurstArgs decodes from start and writes count frames as numbered JPEGs
+func snapshotBurstArgs(input, pattern string, start float64, count, quality int) []string {
+	return []string{
+		"-hide_banner",
+		"-ss", fmt.Sprintf("%.3f", start),
+		"-i", input,
+		"-map", "0:v:0",
+		"-frames:v", fmt.Sprintf("%d", count),
+		"-q:v", fmt.Sprintf("%d", quality),
+		"-f", "image2",
+		"-c:v", "mjpeg",
+		"-start_number", "1",
+		"-y",
+		pattern,
+	}
+}
@@ -0,0 +1,43 @@
+package ffmpeg
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBurstStart(t *testing.T) {
+	tests := []struct {
+		timestamp, frameRate float64
+		count                int
+		want                 float64
+	}{
+		{10, 25, 5, 9.92}, // Two frames before, two after
+		{10, 30, 1, 10},
+		{0.02, 25, 9, 0}, // Clamped to the start of the input
+	}
+	for _, tt := range tests {
+		if got := burstStart(tt.timestamp, tt.frameRate, tt.count); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("burstStart(%g, %g, %d) = %g, want %g", tt.timestamp, tt.frameRate, tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotBurstArgs(t *testing.T) {
+	want := []string{
+		"-hide_banner",
+		"-ss", "9.920",
+		"-i", "in.mp4",
+		"-map", "0:v:0",
+		"-frames:v", "5",
+		"-q:v", "2",
+		"-f", "image2",
+		"-c:v", "mjpeg",
+		"-start_number", "1",
+		"-y",
+		"shot_%02d",
+	}
+	if got := snapshotBurstArgs("in.mp4", "shot_%02d", 9.92, 5, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshotBurstArgs() = %v, want %v", got, want)
+	}
+}
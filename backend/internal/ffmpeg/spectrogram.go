@@ -0,0 +1,41 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+)
+
+// Spectrogram image size. There is no legend, so the image spans exactly the
+// duration of the file and lines up with the waveform.
+const (
+	spectrogramWidth  = 1920
+	spectrogramHeight = 512
+)
+
+// GenerateSpectrogram renders the audio spectrum over time as a PNG using FFmpeg's
+// showspectrumpic filter. opts selects the audio stream and whether each channel
+// gets its own band.
+func (e *Executor) GenerateSpectrogram(ctx context.Context, input, output string, opts WaveformOptions) error {
+	args := []string{
+		"-hide_banner",
+		"-i", input,
+		"-filter_complex", spectrogramFilter(opts),
+		"-frames:v", "1",
+		"-y",
+		output,
+	}
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args: args,
+	})
+}
+
+// spectrogramFilter builds the showspectrumpic filter graph for opts
+func spectrogramFilter(opts WaveformOptions) string {
+	mode := "combined"
+	if opts.SplitChannels {
+		mode = "separate"
+	}
+	return fmt.Sprintf("[0:a:%d]showspectrumpic=s=%dx%d:mode=%s:color=intensity:scale=log:legend=0",
+		opts.AudioStream, spectrogramWidth, spectrogramHeight, mode)
+}
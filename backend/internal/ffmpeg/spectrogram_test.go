@@ -0,0 +1,18 @@
+package ffmpeg
+
+import "testing"
+
+func TestSpectrogramFilter(t *testing.T) {
+	tests := []struct {
+		opts WaveformOptions
+		want string
+	}{
+		{WaveformOptions{}, "[0:a:0]showspectrumpic=s=1920x512:mode=combined:color=intensity:scale=log:legend=0"},
+		{WaveformOptions{AudioStream: 1, SplitChannels: true}, "[0:a:1]showspectrumpic=s=1920x512:mode=separate:color=intensity:scale=log:legend=0"},
+	}
+	for _, tt := range tests {
+		if got := spectrogramFilter(tt.opts); got != tt.want {
+			t.Errorf("spectrogramFilter(%+v) = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+const (
+	storyboardMaxTiles    = 100 // Longer videos get a larger interval instead of more tiles
+	storyboardColumns     = 10
+	storyboardTileWidth   = 160
+	storyboardMinInterval = 1.0 // Seconds
+)
+
+// Storyboard is the layout of a thumbnail sprite: Count tiles of TileWidth x
+// TileHeight, one every Interval seconds, in rows of Columns
+type Storyboard struct {
+	Interval   float64 `json:"interval"`
+	Count      int     `json:"count"`
+	Columns    int     `json:"columns"`
+	Rows       int     `json:"rows"`
+	TileWidth  int     `json:"tile_width"`
+	TileHeight int     `json:"tile_height"`
+}
+
+// NewStoryboard lays out the storyboard of a video of the given duration and size
+func NewStoryboard(duration float64, width, height int) Storyboard {
+	interval := math.Max(storyboardMinInterval, math.Ceil(duration/storyboardMaxTiles))
+	count := int(math.Ceil(duration / interval))
+	if count < 1 {
+		count = 1
+	}
+	columns := storyboardColumns
+	if count < columns {
+		columns = count
+	}
+
+	// Keep the aspect ratio, with an even height as encoders require
+	tileHeight := storyboardTileWidth * 9 / 16
+	if width > 0 && height > 0 {
+		tileHeight = int(math.Round(float64(storyboardTileWidth*height)/float64(width)/2)) * 2
+	}
+
+	return Storyboard{
+		Interval:   interval,
+		Count:      count,
+		Columns:    columns,
+		Rows:       (count + columns - 1) / columns,
+		TileWidth:  storyboardTileWidth,
+		TileHeight: tileHeight,
+	}
+}
+
+// TilePosition returns the top left corner of tile i in the sprite
+func (sb Storyboard) TilePosition(i int) (x, y int) {
+	return (i % sb.Columns) * sb.TileWidth, (i / sb.Columns) * sb.TileHeight
+}
+
+// GenerateStoryboard renders the storyboard sprite of a video as a JPEG image in one
+// pass. Only keyframes are decoded, so tiles show the keyframe nearest their time.
+func (e *Executor) GenerateStoryboard(ctx context.Context, input, output string, sb Storyboard) error {
+	args := []string{
+		"-hide_banner",
+		"-skip_frame", "nokey",
+		"-i", input,
+		"-an", "-sn",
+		"-vf", fmt.Sprintf("fps=1/%g,scale=%d:%d,tile=%dx%d", sb.Interval, sb.TileWidth, sb.TileHeight, sb.Columns, sb.Rows),
+		"-frames:v", "1",
+		"-q:v", "4",
+		"-y",
+		output,
+	}
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args: args,
+	})
+}
@@ -0,0 +1,26 @@
+package ffmpeg
+
+import "testing"
+
+func TestNewStoryboard(t *testing.T) {
+	tests := []struct {
+		duration      float64
+		width, height int
+		want          Storyboard
+	}{
+		{30, 1920, 1080, Storyboard{Interval: 1, Count: 30, Columns: 10, Rows: 3, TileWidth: 160, TileHeight: 90}},
+		{3600, 1280, 720, Storyboard{Interval: 36, Count: 100, Columns: 10, Rows: 10, TileWidth: 160, TileHeight: 90}},
+		{5.5, 720, 1280, Storyboard{Interval: 1, Count: 6, Columns: 6, Rows: 1, TileWidth: 160, TileHeight: 284}},
+		{0, 0, 0, Storyboard{Interval: 1, Count: 1, Columns: 1, Rows: 1, TileWidth: 160, TileHeight: 90}},
+	}
+	for _, tt := range tests {
+		if got := NewStoryboard(tt.duration, tt.width, tt.height); got != tt.want {
+			t.Errorf("NewStoryboard(%v, %d, %d) = %+v, want %+v", tt.duration, tt.width, tt.height, got, tt.want)
+		}
+	}
+
+	sb := NewStoryboard(30, 1920, 1080)
+	if x, y := sb.TilePosition(12); x != 320 || y != 90 {
+		t.Errorf("TilePosition(12) = %d, %d", x, y)
+	}
+}
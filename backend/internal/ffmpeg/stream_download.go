@@ -0,0 +1,182 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// streamProtocols are the protocols a manifest and its segments may be read with,
+// so a playlist can't point FFmpeg at local files
+const streamProtocols = "http,https,tcp,tls,crypto,httpproxy"
+
+// StreamDownloadOptions describes an HLS or DASH stream saved by DownloadStream
+type StreamDownloadOptions struct {
+	URL        string            // .m3u8 or .mpd manifest
+	Output     string            // File the streams are copied into
+	UserAgent  string            // Sent with every request, empty for FFmpeg's own
+	Headers    map[string]string // Extra request headers, e.g. Referer or Authorization
+	MaxHeight  int               // Tallest video variant to pick, 0 for the best
+	OnProgress ProgressCallback  // Called when the stream's duration is known
+}
+
+// streamVariant is a stream of a manifest as FFprobe lists it, one per variant
+type streamVariant struct {
+	Index     int    `json:"index"`
+	CodecType string `json:"codec_type"`
+	Height    int    `json:"height,omitempty"`
+	BitRate   string `json:"bit_rate,omitempty"`
+	Tags      struct {
+		VariantBitRate string `json:"variant_bitrate,omitempty"` // HLS
+	} `json:"tags,omitempty"`
+}
+
+// bitRate returns the bit rate of a variant, 0 if unknown
+func (v streamVariant) bitRate() int64 {
+	for _, rate := range []string{v.Tags.VariantBitRate, v.BitRate} {
+		if n, err := strconv.ParseInt(rate, 10, 64); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// streamProbe is what FFprobe reports about a manifest
+type streamProbe struct {
+	Streams []streamVariant `json:"streams"`
+	Format  struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// DownloadStream copies the best video variant no taller than MaxHeight and the best
+// audio stream of an HLS or DASH manifest into Output without re-encoding. Progress
+// reports also reach the context's stats recorder, with the bytes written so far.
+func (e *Executor) DownloadStream(ctx context.Context, opts StreamDownloadOptions) error {
+	// Live streams and some servers report no duration, progress is then unknown
+	probe, err := e.probeStream(ctx, opts)
+	if err != nil {
+		e.logger.Warn("Failed to probe stream, copying its first variant", zap.Error(err))
+		probe = &streamProbe{}
+	}
+	duration, _ := strconv.ParseFloat(probe.Format.Duration, 64)
+
+	video, audio := pickStreamVariants(probe.Streams, opts.MaxHeight)
+	return e.Execute(ctx, ExecuteOptions{
+		Args:       streamDownloadArgs(opts, video, audio),
+		Duration:   duration,
+		OnProgress: opts.OnProgress,
+	})
+}
+
+// pickStreamVariants returns the index of the tallest video variant no taller than
+// maxHeight, the one with the highest bit rate among equally tall ones, and of the
+// audio stream with the highest bit rate. With every variant taller, the shortest is
+// picked. Indexes are -1 when there is no such stream.
+func pickStreamVariants(streams []streamVariant, maxHeight int) (int, int) {
+	fits := func(v streamVariant) bool { return maxHeight <= 0 || v.Height <= maxHeight }
+	better := func(a, b streamVariant) bool {
+		if fits(a) != fits(b) {
+			return fits(a)
+		}
+		if a.Height != b.Height {
+			// Taller among the fitting ones, shorter among the too tall ones
+			return (a.Height > b.Height) == fits(a)
+		}
+		return a.bitRate() > b.bitRate()
+	}
+
+	video, audio := -1, -1
+	var bestVideo, bestAudio streamVariant
+	for _, stream := range streams {
+		switch stream.CodecType {
+		case "video":
+			if video < 0 || better(stream, bestVideo) {
+				video, bestVideo = stream.Index, stream
+			}
+		case "audio":
+			if audio < 0 || stream.bitRate() > bestAudio.bitRate() {
+				audio, bestAudio = stream.Index, stream
+			}
+		}
+	}
+	return video, audio
+}
+
+// streamInputArgs are the request options and input of a stream
+func streamInputArgs(opts StreamDownloadOptions) []string {
+	args := []string{"-protocol_whitelist", streamProtocols}
+	if opts.UserAgent != "" {
+		args = append(args, "-user_agent", opts.UserAgent)
+	}
+	if len(opts.Headers) > 0 {
+		names := make([]string, 0, len(opts.Headers))
+		for name := range opts.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var headers strings.Builder
+		for _, name := range names {
+			fmt.Fprintf(&headers, "%s: %s\r\n", name, opts.Headers[name])
+		}
+		args = append(args, "-headers", headers.String())
+	}
+	return append(args, "-i", opts.URL)
+}
+
+// streamDownloadArgs copies the video and audio stream at the given input indexes of
+// a manifest, the first video and audio stream for -1. Subtitles and data streams
+// are left out.
+func streamDownloadArgs(opts StreamDownloadOptions, video, audio int) []string {
+	args := []string{"-hide_banner"}
+	args = append(args, streamInputArgs(opts)...)
+	if video >= 0 {
+		args = append(args, "-map", fmt.Sprintf("0:%d", video))
+	} else {
+		args = append(args, "-map", "0:v:0?")
+	}
+	if audio >= 0 {
+		args = append(args, "-map", fmt.Sprintf("0:%d", audio))
+	} else {
+		args = append(args, "-map", "0:a:0?")
+	}
+	return append(args,
+		"-c", "copy",
+		"-sn",
+		"-dn",
+		"-movflags", "+faststart",
+		"-y",
+		opts.Output,
+	)
+}
+
+// probeStream asks FFprobe for the duration and the variant streams of a manifest
+func (e *Executor) probeStream(ctx context.Context, opts StreamDownloadOptions) (*streamProbe, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	args := []string{
+		"-v", "error",
+		"-show_entries", "format=duration:stream=index,codec_type,height,bit_rate:stream_tags=variant_bitrate",
+		"-of", "json",
+	}
+	args = append(args, streamInputArgs(opts)...)
+	output, err := exec.CommandContext(ctx, e.ffprobePath, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe streamProbe
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	return &probe, nil
+}
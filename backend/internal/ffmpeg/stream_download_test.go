@@ -0,0 +1,77 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStreamDownloadArgs(t *testing.T) {
+	args := streamDownloadArgs(StreamDownloadOptions{
+		URL:       "https://cdn.example.com/live/master.m3u8",
+		Output:    "/data/downloads/stream.mp4",
+		UserAgent: "Mozilla/5.0",
+		Headers:   map[string]string{"Referer": "https://example.com/", "Authorization": "Bearer abc"},
+	}, 3, 5)
+	want := []string{
+		"-hide_banner",
+		"-protocol_whitelist", streamProtocols,
+		"-user_agent", "Mozilla/5.0",
+		"-headers", "Authorization: Bearer abc\r\nReferer: https://example.com/\r\n",
+		"-i", "https://cdn.example.com/live/master.m3u8",
+		"-map", "0:3",
+		"-map", "0:5",
+		"-c", "copy",
+		"-sn",
+		"-dn",
+		"-movflags", "+faststart",
+		"-y",
+		"/data/downloads/stream.mp4",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("streamDownloadArgs() = %q, want %q", args, want)
+	}
+
+	// Without client settings only the protocols are restricted
+	args = streamInputArgs(StreamDownloadOptions{URL: "https://cdn.example.com/a.mpd"})
+	want = []string{"-protocol_whitelist", streamProtocols, "-i", "https://cdn.example.com/a.mpd"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("streamInputArgs() = %q, want %q", args, want)
+	}
+}
+
+func TestPickStreamVariants(t *testing.T) {
+	variant := func(index int, codecType string, height int, rate string) streamVariant {
+		v := streamVariant{Index: index, CodecType: codecType, Height: height}
+		v.Tags.VariantBitRate = rate
+		return v
+	}
+	streams := []streamVariant{
+		variant(0, "video", 360, "800000"),
+		variant(1, "audio", 0, "800000"),
+		variant(2, "video", 1080, "5000000"),
+		variant(3, "audio", 0, "5000000"),
+		variant(4, "video", 720, "2500000"),
+		variant(5, "video", 720, "3000000"),
+		variant(6, "subtitle", 0, ""),
+	}
+
+	tests := []struct {
+		name      string
+		streams   []streamVariant
+		maxHeight int
+		video     int
+		audio     int
+	}{
+		{"best", streams, 0, 2, 3},
+		{"capped", streams, 720, 5, 3},
+		{"all too tall", streams, 240, 0, 3},
+		{"audio only", streams[1:2], 720, -1, 1},
+		{"unprobed", nil, 720, -1, -1},
+	}
+	for _, tt := range tests {
+		video, audio := pickStreamVariants(tt.streams, tt.maxHeight)
+		if video != tt.video || audio != tt.audio {
+			t.Errorf("%s: pickStreamVariants() = %d, %d, want %d, %d", tt.name, video, audio, tt.video, tt.audio)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+)
+
+const streamsKey contextKey = "ffmpeg-streams"
+
+// Stream selection shortcuts, see WithStreams
+const (
+	StreamsVideoOnly      = "video_only"
+	StreamsStripSubtitles = "strip_subtitles"
+)
+
+// streamSelection is the set of source streams kept by lossless cuts
+type streamSelection struct {
+	indexes  []int
+	shortcut string
+}
+
+// WithStreams returns a context whose lossless cut commands keep only the given
+// source stream indexes, or the streams picked by a shortcut: StreamsVideoOnly keeps
+// the video streams, StreamsStripSubtitles everything but subtitles. Without it
+// every stream is copied.
+func WithStreams(ctx context.Context, indexes []int, shortcut string) context.Context {
+	return context.WithValue(ctx, streamsKey, streamSelection{indexes: indexes, shortcut: shortcut})
+}
+
+// ValidStreamShortcut reports whether s is a known stream shortcut or empty
+func ValidStreamShortcut(s string) bool {
+	switch s {
+	case "", StreamsVideoOnly, StreamsStripSubtitles:
+		return true
+	}
+	return false
+}
+
+// streamSelected reports whether the source stream with an index is kept, also for
+// shortcuts, which always keep the video streams
+func streamSelected(ctx context.Context, index int) bool {
+	selection, _ := ctx.Value(streamsKey).(streamSelection)
+	if len(selection.indexes) == 0 {
+		return true
+	}
+	for _, selected := range selection.indexes {
+		if selected == index {
+			return true
+		}
+	}
+	return false
+}
+
+// streamMapArgs maps the selected streams of an input. mappedAll is set when every
+// stream was mapped with a plain "-map N", see attachmentArgs.
+func streamMapArgs(ctx context.Context, input int) (args []string, mappedAll bool) {
+	selection, _ := ctx.Value(streamsKey).(streamSelection)
+
+	switch {
+	case len(selection.indexes) > 0:
+		for _, index := range selection.indexes {
+			args = append(args, "-map", fmt.Sprintf("%d:%d", input, index))
+		}
+		return args, false
+	case selection.shortcut == StreamsVideoOnly:
+		return []string{"-map", fmt.Sprintf("%d:v", input)}, false
+	case selection.shortcut == StreamsStripSubtitles:
+		return []string{"-map", fmt.Sprint(input), "-map", fmt.Sprintf("-%d:s", input)}, true
+	}
+	return []string{"-map", fmt.Sprint(input)}, true // Copy all streams
+}
@@ -0,0 +1,27 @@
+package ffmpeg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStreamMapArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		want          []string
+		wantMappedAll bool
+	}{
+		{"default", context.Background(), []string{"-map", "0"}, true},
+		{"indexes", WithStreams(context.Background(), []int{0, 2}, ""), []string{"-map", "0:0", "-map", "0:2"}, false},
+		{"video only", WithStreams(context.Background(), nil, StreamsVideoOnly), []string{"-map", "0:v"}, false},
+		{"strip subtitles", WithStreams(context.Background(), nil, StreamsStripSubtitles), []string{"-map", "0", "-map", "-0:s"}, true},
+	}
+	for _, tt := range tests {
+		args, mappedAll := streamMapArgs(tt.ctx, 0)
+		if !reflect.DeepEqual(args, tt.want) || mappedAll != tt.wantMappedAll {
+			t.Errorf("%s: streamMapArgs() = %v, %v, want %v, %v", tt.name, args, mappedAll, tt.want, tt.wantMappedAll)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+)
+
+// textSubtitleCodecs are subtitle codecs FFmpeg can convert to SRT/WebVTT.
+// Bitmap subtitles (PGS, DVD, DVB) need OCR and are not supported.
+var textSubtitleCodecs = map[string]bool{
+	"subrip":    true,
+	"srt":       true,
+	"ass":       true,
+	"ssa":       true,
+	"mov_text":  true,
+	"webvtt":    true,
+	"text":      true,
+	"microdvd":  true,
+	"subviewer": true,
+	"sami":      true,
+	"realtext":  true,
+	"mpl2":      true,
+	"vplayer":   true,
+	"jacosub":   true,
+	"pjs":       true,
+	"stl":       true,
+}
+
+// IsTextSubtitleCodec reports whether a subtitle codec can be converted to text formats
+func IsTextSubtitleCodec(codec string) bool {
+	return textSubtitleCodecs[codec]
+}
+
+// ExtractSubtitles converts the subtitle stream with the given absolute index to a
+// text subtitle file. The output format follows the extension (.srt or .vtt).
+func (e *Executor) ExtractSubtitles(ctx context.Context, input string, streamIndex int, output string) error {
+	args := []string{
+		"-hide_banner",
+		"-i", input,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		"-y",
+		output,
+	}
+
+	return e.Execute(ctx, ExecuteOptions{Args: args})
+}
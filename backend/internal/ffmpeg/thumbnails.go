@@ -0,0 +1,32 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ExtractThumbnails writes one JPEG frame every interval seconds, scaled to width,
+// in a single pass. pattern is an image sequence output such as "thumb_%04d.jpg",
+// numbered from 1. Only keyframes are decoded, so each frame is the keyframe nearest
+// its time.
+func (e *Executor) ExtractThumbnails(ctx context.Context, input, pattern string, interval float64, width int, duration float64) error {
+	args := []string{
+		"-hide_banner",
+		"-skip_frame", "nokey",
+		"-i", input,
+		"-an", "-sn",
+		"-vf", fmt.Sprintf("fps=1/%g,scale=%d:-2", interval, width),
+		"-q:v", "4",
+		// One frame per interval of the probed duration, however long the file is
+		"-frames:v", strconv.Itoa(int(math.Ceil(duration / interval))),
+		"-y",
+		pattern,
+	}
+
+	return e.Execute(ctx, ExecuteOptions{
+		Args:     args,
+		Duration: duration,
+	})
+}
@@ -0,0 +1,66 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const watermarkKey contextKey = "ffmpeg-watermark"
+
+// Watermark is an image composited over re-encoded video
+type Watermark struct {
+	File     string  // PNG image, transparency is kept
+	Position string  // "top-left", "top-right", "bottom-left", "bottom-right" (default) or "center"
+	Opacity  float64 // 0-1, 0 keeps the image as is
+}
+
+// watermarkPositions maps overlay positions to overlay x/y expressions
+var watermarkPositions = map[string]string{
+	"top-left":     "x=10:y=10",
+	"top-right":    "x=W-w-10:y=10",
+	"bottom-left":  "x=10:y=H-h-10",
+	"bottom-right": "x=W-w-10:y=H-h-10",
+	"center":       "x=(W-w)/2:y=(H-h)/2",
+}
+
+// WithWatermark returns a context whose re-encoded segments get the image overlaid
+func WithWatermark(ctx context.Context, watermark Watermark) context.Context {
+	return context.WithValue(ctx, watermarkKey, watermark)
+}
+
+// contextWatermark returns the watermark set with WithWatermark, if any
+func contextWatermark(ctx context.Context) *Watermark {
+	if watermark, ok := ctx.Value(watermarkKey).(Watermark); ok {
+		return &watermark
+	}
+	return nil
+}
+
+// ValidWatermarkPosition reports whether a watermark position is supported
+func ValidWatermarkPosition(position string) bool {
+	_, ok := watermarkPositions[position]
+	return position == "" || ok
+}
+
+// watermarkGraph returns a filter graph applying filters to the first video stream of
+// input 0 and overlaying the image of input 1 on the result, labelled [v]. The
+// overlay repeats the single image frame for the whole segment.
+func watermarkGraph(filters []string, watermark Watermark) string {
+	chain := "null"
+	if len(filters) > 0 {
+		chain = strings.Join(filters, ",")
+	}
+
+	image := "format=rgba"
+	if watermark.Opacity > 0 && watermark.Opacity < 1 {
+		image += fmt.Sprintf(",colorchannelmixer=aa=%g", watermark.Opacity)
+	}
+
+	xy, ok := watermarkPositions[watermark.Position]
+	if !ok {
+		xy = watermarkPositions["bottom-right"]
+	}
+
+	return fmt.Sprintf("[0:v:0]%s[base];[1:v]%s[mark];[base][mark]overlay=%s:format=auto[v]", chain, image, xy)
+}
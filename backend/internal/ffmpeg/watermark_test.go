@@ -0,0 +1,36 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWatermarkGraph(t *testing.T) {
+	got := watermarkGraph([]string{"scale=1280:-2"}, Watermark{File: "logo.png", Position: "top-left", Opacity: 0.5})
+	want := "[0:v:0]scale=1280:-2[base];[1:v]format=rgba,colorchannelmixer=aa=0.5[mark];[base][mark]overlay=x=10:y=10:format=auto[v]"
+	if got != want {
+		t.Errorf("watermarkGraph() = %q, want %q", got, want)
+	}
+
+	got = watermarkGraph(nil, Watermark{File: "logo.png"})
+	want = "[0:v:0]null[base];[1:v]format=rgba[mark];[base][mark]overlay=x=W-w-10:y=H-h-10:format=auto[v]"
+	if got != want {
+		t.Errorf("watermarkGraph() = %q, want %q", got, want)
+	}
+}
+
+func TestEncodeOptionsVideoArgs(t *testing.T) {
+	opts := EncodeOptions{FPS: 30}
+	if got, want := opts.videoArgs(0), []string{"-map", "0:v:0?", "-vf", "fps=30"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("videoArgs() = %q, want %q", got, want)
+	}
+
+	opts.Watermark = &Watermark{File: "logo.png", Position: "center"}
+	want := []string{
+		"-filter_complex", "[0:v:0]fps=30[base];[1:v]format=rgba[mark];[base][mark]overlay=x=(W-w)/2:y=(H-h)/2:format=auto[v]",
+		"-map", "[v]",
+	}
+	if got := opts.videoArgs(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("videoArgs() = %q, want %q", got, want)
+	}
+}
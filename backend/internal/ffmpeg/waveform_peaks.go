@@ -0,0 +1,146 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	// waveformSampleRate is the rate audio is decoded at for peaks, plenty for the
+	// finest zoom level of multi-minute videos
+	waveformSampleRate = 8000
+	// waveformPrecision rounds peaks to keep the JSON small
+	waveformPrecision = 10000
+)
+
+// WaveformZoomLevels are the peak counts computed per video, from overview to detail
+var WaveformZoomLevels = []int{100, 1000, 10000}
+
+// WaveformPeaks holds the minimum and maximum sample (-1 to 1) of consecutive equal
+// parts of the audio
+type WaveformPeaks struct {
+	Min []float64 `json:"min"`
+	Max []float64 `json:"max"`
+}
+
+// Waveform holds the peaks of a video's audio at each zoom level
+type Waveform struct {
+	Duration float64                `json:"duration"`
+	Levels   map[int]*WaveformPeaks `json:"levels"` // By peak count, see WaveformZoomLevels
+}
+
+// GenerateWaveformPeaks decodes an audio stream (index among the audio streams) to
+// mono PCM and computes its peaks at every zoom level. Samples are reduced while
+// decoding, so memory use doesn't grow with the video's length.
+func (e *Executor) GenerateWaveformPeaks(ctx context.Context, input string, audioStream int, duration float64) (*Waveform, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("unknown duration")
+	}
+
+	finest := WaveformZoomLevels[len(WaveformZoomLevels)-1]
+	writer := newPeakWriter(int(math.Ceil(duration * waveformSampleRate / float64(finest))))
+
+	args := []string{
+		"-hide_banner",
+		"-i", input,
+		"-map", fmt.Sprintf("0:a:%d", audioStream),
+		"-ac", "1",
+		"-ar", fmt.Sprintf("%d", waveformSampleRate),
+		"-f", "s16le",
+		"-",
+	}
+	if err := e.Execute(ctx, ExecuteOptions{Args: args, Stdout: writer}); err != nil {
+		return nil, err
+	}
+
+	peaks := writer.Peaks()
+	waveform := &Waveform{Duration: duration, Levels: make(map[int]*WaveformPeaks)}
+	for _, level := range WaveformZoomLevels {
+		waveform.Levels[level] = downsamplePeaks(peaks, level)
+	}
+	return waveform, nil
+}
+
+// peakWriter reduces s16le samples written to it to the min and max of every
+// bucketSize samples
+type peakWriter struct {
+	bucketSize int
+	peaks      WaveformPeaks
+	count      int // Samples in the current bucket
+	min, max   float64
+	odd        []byte // Half of a sample split between writes
+}
+
+func newPeakWriter(bucketSize int) *peakWriter {
+	if bucketSize < 1 {
+		bucketSize = 1
+	}
+	return &peakWriter{bucketSize: bucketSize}
+}
+
+func (w *peakWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(w.odd) > 0 {
+		p = append(w.odd, p...)
+		w.odd = nil
+	}
+
+	for len(p) >= 2 {
+		sample := float64(int16(binary.LittleEndian.Uint16(p))) / 32768
+		p = p[2:]
+
+		if w.count == 0 || sample < w.min {
+			w.min = sample
+		}
+		if w.count == 0 || sample > w.max {
+			w.max = sample
+		}
+		w.count++
+		if w.count == w.bucketSize {
+			w.flush()
+		}
+	}
+	if len(p) == 1 {
+		w.odd = []byte{p[0]}
+	}
+	return n, nil
+}
+
+func (w *peakWriter) flush() {
+	w.peaks.Min = append(w.peaks.Min, roundPeak(w.min))
+	w.peaks.Max = append(w.peaks.Max, roundPeak(w.max))
+	w.count = 0
+}
+
+// Peaks returns the peaks of all samples written, including a partial last bucket
+func (w *peakWriter) Peaks() *WaveformPeaks {
+	if w.count > 0 {
+		w.flush()
+	}
+	return &w.peaks
+}
+
+func roundPeak(v float64) float64 {
+	return math.Round(v*waveformPrecision) / waveformPrecision
+}
+
+// downsamplePeaks merges peaks into at most n buckets
+func downsamplePeaks(peaks *WaveformPeaks, n int) *WaveformPeaks {
+	total := len(peaks.Min)
+	if total <= n {
+		return peaks
+	}
+
+	result := &WaveformPeaks{Min: make([]float64, n), Max: make([]float64, n)}
+	for i := 0; i < n; i++ {
+		from, to := i*total/n, (i+1)*total/n
+		result.Min[i], result.Max[i] = peaks.Min[from], peaks.Max[from]
+		for j := from + 1; j < to; j++ {
+			result.Min[i] = math.Min(result.Min[i], peaks.Min[j])
+			result.Max[i] = math.Max(result.Max[i], peaks.Max[j])
+		}
+	}
+	return result
+}
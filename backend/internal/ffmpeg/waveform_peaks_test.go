@@ -0,0 +1,44 @@
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestPeakWriter(t *testing.T) {
+	samples := []int16{0, 16384, -16384, 8192, -32768, 0, 32767}
+	data := make([]byte, 0, len(samples)*2)
+	for _, s := range samples {
+		data = binary.LittleEndian.AppendUint16(data, uint16(s))
+	}
+
+	w := newPeakWriter(3)
+	// Split writes in the middle of a sample
+	w.Write(data[:3])
+	w.Write(data[3:9])
+	w.Write(data[9:])
+
+	peaks := w.Peaks()
+	if want := []float64{-0.5, -1, 1}; !reflect.DeepEqual(peaks.Min, want) {
+		t.Errorf("Min = %v, want %v", peaks.Min, want)
+	}
+	if want := []float64{0.5, 0.25, 1}; !reflect.DeepEqual(peaks.Max, want) {
+		t.Errorf("Max = %v, want %v", peaks.Max, want)
+	}
+}
+
+func TestDownsamplePeaks(t *testing.T) {
+	peaks := &WaveformPeaks{
+		Min: []float64{-0.1, -0.5, -0.2, -0.3},
+		Max: []float64{0.1, 0.2, 0.9, 0.3},
+	}
+
+	got := downsamplePeaks(peaks, 2)
+	if !reflect.DeepEqual(got.Min, []float64{-0.5, -0.3}) || !reflect.DeepEqual(got.Max, []float64{0.2, 0.9}) {
+		t.Errorf("downsamplePeaks(2) = %+v", got)
+	}
+	if got := downsamplePeaks(peaks, 10); got != peaks {
+		t.Errorf("downsamplePeaks() to more buckets = %+v", got)
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -28,18 +30,68 @@ type Segment struct {
 
 // Video represents an uploaded or downloaded video
 type Video struct {
-	ID          string        `json:"id"`
-	FileName    string        `json:"file_name"`
-	OriginalURL string        `json:"original_url,omitempty"` // For yt-dlp downloads
-	FilePath    string        `json:"file_path"`
-	FileSize    int64         `json:"file_size"`
-	Duration    float64       `json:"duration"`
-	Width       int           `json:"width"`
-	Height      int           `json:"height"`
-	Codec       string        `json:"codec"`
-	Format      string        `json:"format"`
-	Metadata    VideoMetadata `json:"metadata"`
-	CreatedAt   time.Time     `json:"created_at"`
+	ID          string         `json:"id"`
+	FileName    string         `json:"file_name"`
+	OriginalURL string         `json:"original_url,omitempty"` // For yt-dlp downloads
+	FilePath    string         `json:"file_path"`
+	FileSize    int64          `json:"file_size"`
+	Duration    float64        `json:"duration"`
+	Width       int            `json:"width"`
+	Height      int            `json:"height"`
+	Codec       string         `json:"codec"`
+	Format      string         `json:"format"`
+	Metadata    VideoMetadata  `json:"metadata"`
+	Subtitles   []SubtitleFile `json:"subtitles,omitempty"`  // Sidecar subtitle files
+	Watermarks  []ImageFile    `json:"watermarks,omitempty"` // Uploaded overlay images
+	Rotation    int            `json:"rotation,omitempty"`   // Display rotation set with the rotate endpoint, degrees clockwise
+	SHA256      string         `json:"sha256,omitempty"`     // File checksum, once computed
+	Warnings    []MediaWarning `json:"warnings,omitempty"`   // Probe findings that commonly break lossless editing
+	External    bool           `json:"external,omitempty"`   // Imported in place from a server directory, the file is never modified or deleted
+	Source      *VideoSource   `json:"source,omitempty"`     // What the site of a yt-dlp download reports about the video
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// VideoSource is the metadata of a downloaded video on the site it came from
+type VideoSource struct {
+	WebpageURL    string `json:"webpage_url,omitempty"`
+	Uploader      string `json:"uploader,omitempty"`
+	UploadDate    string `json:"upload_date,omitempty"` // YYYY-MM-DD
+	Description   string `json:"description,omitempty"`
+	Thumbnail     string `json:"thumbnail,omitempty"`      // URL of the site's thumbnail
+	ThumbnailPath string `json:"thumbnail_path,omitempty"` // Stored copy of it, served by /api/videos/:id/poster
+}
+
+// KeyframeIndex lists the keyframes of a video's first video stream, built once and
+// reused by smart cuts, snapping and the timeline while the file is unchanged
+type KeyframeIndex struct {
+	VideoID   string    `json:"video_id"`
+	FileHash  string    `json:"file_hash"` // Content hash of the file the index was built from
+	Keyframes []float64 `json:"keyframes"` // Sorted presentation times in seconds
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SubtitleFile is a sidecar subtitle file attached to a video
+type SubtitleFile struct {
+	ID       string `json:"id"`
+	FileName string `json:"file_name"`
+	FilePath string `json:"file_path"`
+	Language string `json:"language,omitempty"`
+}
+
+// MediaWarning flags a property of a video, such as a variable frame rate or a
+// timecode data stream, that commonly breaks lossless cutting or merging
+type MediaWarning struct {
+	Code       string `json:"code"`             // "variable_frame_rate", "missing_duration" or "data_stream"
+	Stream     *int   `json:"stream,omitempty"` // Affected stream index, unset for the whole file
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"` // "smart_cut" or "remux"
+}
+
+// ImageFile is an uploaded image attached to a video, e.g. a watermark
+type ImageFile struct {
+	ID       string `json:"id"`
+	FileName string `json:"file_name"`
+	FilePath string `json:"file_path"`
 }
 
 // VideoMetadata contains FFprobe metadata
@@ -62,6 +114,8 @@ type Stream struct {
 	Channels   int     `json:"channels,omitempty"`
 	Language   string  `json:"language,omitempty"`
 	Title      string  `json:"title,omitempty"`
+	FieldOrder string  `json:"field_order,omitempty"` // Video field order from ffprobe, e.g. "progressive" or "tt" (interlaced)
+	Rotation   int     `json:"rotation,omitempty"`    // Display rotation of a video stream, degrees clockwise
 }
 
 // Format represents the container format
@@ -86,26 +140,76 @@ type Chapter struct {
 
 // Operation represents a processing operation
 type Operation struct {
-	ID          string          `json:"id"`
-	Type        OperationType   `json:"type"`
-	ProjectID   string          `json:"project_id"`
-	Status      OperationStatus `json:"status"`
-	Progress    float64         `json:"progress"`
-	Error       string          `json:"error,omitempty"`
-	OutputFiles []string        `json:"output_files,omitempty"`
-	CreatedAt   time.Time       `json:"created_at"`
-	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	ID          string           `json:"id"`
+	Type        OperationType    `json:"type"`
+	ProjectID   string           `json:"project_id"`
+	Status      OperationStatus  `json:"status"`
+	Progress    float64          `json:"progress"`
+	Speed       float64          `json:"speed,omitempty"` // Speed of the running FFmpeg command, as a multiple of realtime
+	ETA         float64          `json:"eta,omitempty"`   // Estimated seconds until the operation completes
+	Error       string           `json:"error,omitempty"`
+	OutputFiles []string         `json:"output_files,omitempty"`
+	Manifest    string           `json:"manifest,omitempty"` // Export manifest describing the output files
+	Quality     []QualityResult  `json:"quality,omitempty"`
+	Warnings    []string         `json:"warnings,omitempty"` // Output validation problems
+	Commands    []string         `json:"commands,omitempty"` // FFmpeg commands run by the operation
+	Plan        []PlannedCommand `json:"plan,omitempty"`     // Commands a dry run would have run
+	Checksum    *ChecksumResult  `json:"checksum,omitempty"` // Result of checksum and verify operations
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
+
+// PlannedCommand is an FFmpeg command recorded by a dry run export
+type PlannedCommand struct {
+	Args       []string `json:"args"`                  // Program and arguments
+	ConcatList string   `json:"concat_list,omitempty"` // Concat demuxer list the command reads
+}
+
+// ExportJob holds what is needed to run an export again. It is saved in the export's
+// workspace next to the completed segment artifacts, so a retry can skip them.
+type ExportJob struct {
+	OperationID string        `json:"operation_id"`
+	Project     Project       `json:"project"` // Snapshot at export time
+	Request     ExportRequest `json:"request"`
+	CreatedAt   time.Time     `json:"created_at"`
+
+	// Set when the export failed. A job without status on startup was interrupted.
+	Status   OperationStatus `json:"status,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	FailedAt *time.Time      `json:"failed_at,omitempty"`
+}
+
+// QualityResult holds objective quality scores of an exported segment compared to the source
+type QualityResult struct {
+	OutputFile string  `json:"output_file"`
+	SegmentID  string  `json:"segment_id"`
+	SSIM       float64 `json:"ssim,omitempty"`
+	VMAF       float64 `json:"vmaf,omitempty"`
+	Error      string  `json:"error,omitempty"`
 }
 
 type OperationType string
 
 const (
-	OperationTypeCut      OperationType = "cut"
-	OperationTypeMerge    OperationType = "merge"
-	OperationTypeExport   OperationType = "export"
-	OperationTypeSnapshot OperationType = "snapshot"
+	OperationTypeCut          OperationType = "cut"
+	OperationTypeMerge        OperationType = "merge"
+	OperationTypeExport       OperationType = "export"
+	OperationTypeSnapshot     OperationType = "snapshot"
+	OperationTypeChecksum     OperationType = "checksum"
+	OperationTypeVerify       OperationType = "verify"
+	OperationTypeRemux        OperationType = "remux"
+	OperationTypeExtractAudio OperationType = "extract_audio"
 )
 
+// ChecksumResult is the SHA-256 of a file, and for verifications whether it matches
+// the expected checksum
+type ChecksumResult struct {
+	File     string `json:"file"`
+	SHA256   string `json:"sha256"`
+	Expected string `json:"expected,omitempty"`
+	Match    *bool  `json:"match,omitempty"`
+}
+
 type OperationStatus string
 
 const (
@@ -113,6 +217,17 @@ const (
 	OperationStatusProcessing OperationStatus = "processing"
 	OperationStatusCompleted  OperationStatus = "completed"
 	OperationStatusFailed     OperationStatus = "failed"
+
+	// OperationStatusCompletedWithWarnings means the outputs were written but failed
+	// validation, see Operation.Warnings
+	OperationStatusCompletedWithWarnings OperationStatus = "completed_with_warnings"
+
+	// OperationStatusInterrupted means the server stopped while the operation ran
+	OperationStatusInterrupted OperationStatus = "interrupted"
+
+	// OperationStatusCancelled means the operation was stopped on request and its
+	// partial outputs removed
+	OperationStatusCancelled OperationStatus = "cancelled"
 )
 
 // DownloadRequest represents a yt-dlp download request
@@ -125,40 +240,359 @@ type DownloadRequest struct {
 type UploadResponse struct {
 	VideoID string `json:"video_id"`
 	Video   *Video `json:"video"`
+
+	// Operation computing the file's checksum, when one was started
+	ChecksumOperationID string `json:"checksum_operation_id,omitempty"`
 }
 
 // ExportRequest represents an export request
 type ExportRequest struct {
 	Format         string   `json:"format,omitempty"`
 	OutputName     string   `json:"output_name,omitempty"`
-	SegmentIDs     []string `json:"segment_ids,omitempty"` // If empty, export all
+	OutputTemplate string   `json:"output_template,omitempty"` // e.g. "{title}_{index}_{label}_{start}_{date}"
+	SegmentIDs     []string `json:"segment_ids,omitempty"`     // If empty, export all
 	MergeSegments  bool     `json:"merge_segments,omitempty"`
 	ExportSeparate bool     `json:"export_separate,omitempty"` // Export each segment as separate file
 	ExportChapters bool     `json:"export_chapters,omitempty"` // Export segments as chapters
 	ChaptersFormat string   `json:"chapters_format,omitempty"` // "txt", "xml", "json"
+	VerifyQuality  bool     `json:"verify_quality,omitempty"`  // Compare outputs against the source after export
+	QualityMetric  string   `json:"quality_metric,omitempty"`  // "ssim" (default), "vmaf", "both"
+	CutSubtitles   bool     `json:"cut_subtitles,omitempty"`   // Write re-timed .srt files next to each video output
+
+	// Image sequence export: the frames of each segment as numbered images in one zip
+	ExportFrames bool    `json:"export_frames,omitempty"`
+	FrameFormat  string  `json:"frame_format,omitempty"` // "png" (default) or "jpg"
+	FrameFPS     float64 `json:"frame_fps,omitempty"`    // Frames per second to keep, 0 = every frame
+
+	// Audio-only export
+	AudioOnly    bool   `json:"audio_only,omitempty"`
+	AudioCodec   string `json:"audio_codec,omitempty"`   // "mp3", "aac", "flac", "opus" or "copy" (default "mp3", "aac" when re-encoding)
+	AudioBitrate string `json:"audio_bitrate,omitempty"` // e.g. "192k", ignored for flac/copy
+
+	// Cut mode: "lossless" or "keyframe" (default, fast stream copy seeking to the keyframe
+	// before the start), "accurate" (stream copy seeking on the output, slower), "smart"
+	// (lossless when cut points are on keyframes, re-encode otherwise) or "reencode"
+	// (frame-accurate full re-encode)
+	CutMode string `json:"cut_mode,omitempty"`
+
+	// Re-encoding (default is lossless stream copy), also used by "smart" and "reencode" cut modes
+	Reencode        bool    `json:"reencode,omitempty"`      // Same as cut_mode "reencode"
+	VideoCodec      string  `json:"video_codec,omitempty"`   // FFmpeg encoder, default "libx264"
+	VideoBitrate    string  `json:"video_bitrate,omitempty"` // e.g. "5M", takes precedence over CRF
+	CRF             int     `json:"crf,omitempty"`           // default 18
+	Preset          string  `json:"preset,omitempty"`        // Encoder preset, default "fast"
+	Width           int     `json:"width,omitempty"`         // Scale to this width, 0 = keep; aspect ratio kept if only one is set
+	Height          int     `json:"height,omitempty"`
+	FPS             float64 `json:"fps,omitempty"`               // 0 = keep
+	VideoProfile    string  `json:"video_profile,omitempty"`     // Encoder profile, e.g. "3" (ProRes HQ) or "dnxhr_hq"
+	PixelFormat     string  `json:"pixel_format,omitempty"`      // default "yuv420p"
+	AudioSampleRate int     `json:"audio_sample_rate,omitempty"` // Hz, 0 = keep
+	AspectRatio     string  `json:"aspect_ratio,omitempty"`      // Center crop to e.g. "9:16" before scaling
+	TargetSize      int64   `json:"target_size,omitempty"`       // Bytes per output file, sets the video bitrate
+	Deinterlace     string  `json:"deinterlace,omitempty"`       // "auto" (when probed as interlaced), "yadif" or "bwdif"
+	// Area of the source picture to keep, e.g. to remove letterboxes. Like width and
+	// height it implies cut_mode "reencode".
+	Crop *CropRect `json:"crop,omitempty"`
+
+	// Named encoding target (see GET /api/export/presets), fills unset re-encoding fields
+	ExportPreset string `json:"export_preset,omitempty"`
+
+	// Loudness normalization of each merged piece before concatenation (re-encodes audio only)
+	NormalizeAudio bool    `json:"normalize_audio,omitempty"`
+	LoudnessTarget float64 `json:"loudness_target,omitempty"` // Integrated LUFS, default -16
+
+	// Crossfade merge: joins merged segments with xfade/acrossfade, implies cut_mode "reencode"
+	Crossfade           float64 `json:"crossfade,omitempty"`            // Seconds, 0 = hard cuts
+	CrossfadeTransition string  `json:"crossfade_transition,omitempty"` // xfade transition, default "fade"
+
+	// Burned-in source timecode, implies cut_mode "reencode"
+	BurnTimecode     bool   `json:"burn_timecode,omitempty"`
+	TimecodePosition string `json:"timecode_position,omitempty"` // "top-left", "top-right", "bottom-left" (default), "bottom-right"
+
+	// Burned-in subtitles, implies cut_mode "reencode": an embedded text subtitle stream
+	// (absolute stream index) or the ID of an uploaded sidecar subtitle file
+	BurnSubtitleStream *int   `json:"burn_subtitle_stream,omitempty"`
+	BurnSubtitleFile   string `json:"burn_subtitle_file,omitempty"`
+
+	// Image overlay, implies cut_mode "reencode"
+	Watermark *WatermarkOptions `json:"watermark,omitempty"`
+
+	// Source global tags, chapters and MKV attachments are kept by default
+	StripMetadata bool `json:"strip_metadata,omitempty"`
+	// Display rotation of the outputs in degrees clockwise (0, 90, 180, 270), set in the
+	// display matrix without re-encoding. Not supported by cut_mode "smart".
+	Rotation *int `json:"rotation,omitempty"`
+
+	// Global tags to write, e.g. {"title": "...", "artist": "...", "creation_time": "now"}.
+	// Set on top of the kept source tags, and also when they are stripped.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Privacy mode removes all metadata, including GPS, device tags, creation time,
+	// handler names and encoder tags, for clips published from personal devices
+	PrivacyMode bool `json:"privacy_mode,omitempty"`
+
+	// Source streams kept by lossless cuts, default all (-map 0)
+	Streams StreamSelection `json:"streams,omitempty"`
+
+	// Allowlisted FFmpeg output options, e.g. ["-tag:v", "hvc1"], inserted before the
+	// output of every export command like extra_args but available to everyone
+	AdvancedOptions []string `json:"advanced_options,omitempty"`
+	// Extra FFmpeg output options inserted before the output of every export command
+	// (admin only), from a larger allowlist than advanced_options, e.g. ["-c:v", "libx264"]
+	ExtraArgs []string `json:"extra_args,omitempty"`
+
+	// Plan the export without running FFmpeg: the operation is returned completed with
+	// the commands that would run, and no outputs
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// WatermarkOptions places an uploaded watermark image on exported video
+type WatermarkOptions struct {
+	Image    string  `json:"image"`              // ID of a watermark uploaded to the video
+	Position string  `json:"position,omitempty"` // "top-left", "top-right", "bottom-left", "bottom-right" (default), "center"
+	Opacity  float64 `json:"opacity,omitempty"`  // 0-1, default 1
+}
+
+// CropRect is a rectangle of the source picture in pixels, from its top-left corner
+type CropRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// StreamSelection picks the source streams an export keeps. In JSON it is a list of
+// stream indexes, e.g. [0, 2], or a shortcut ("video_only" keeps the video streams,
+// "strip_subtitles" everything but subtitles), either as a string or in the list.
+type StreamSelection struct {
+	Indexes  []int
+	Shortcut string
+}
+
+// IsZero reports whether no selection was made, i.e. all streams are kept
+func (s StreamSelection) IsZero() bool {
+	return len(s.Indexes) == 0 && s.Shortcut == ""
+}
+
+// MarshalJSON encodes the selection as its shortcut or index list
+func (s StreamSelection) MarshalJSON() ([]byte, error) {
+	if s.Shortcut != "" {
+		return json.Marshal(s.Shortcut)
+	}
+	if s.Indexes == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(s.Indexes)
+}
+
+// UnmarshalJSON accepts a shortcut string or a list of indexes and/or a shortcut
+func (s *StreamSelection) UnmarshalJSON(data []byte) error {
+	*s = StreamSelection{}
+
+	var shortcut string
+	if err := json.Unmarshal(data, &shortcut); err == nil {
+		s.Shortcut = shortcut
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("streams must be a list of stream indexes or a shortcut")
+	}
+	for _, item := range items {
+		var index int
+		if err := json.Unmarshal(item, &index); err == nil {
+			s.Indexes = append(s.Indexes, index)
+			continue
+		}
+		if err := json.Unmarshal(item, &shortcut); err != nil {
+			return fmt.Errorf("invalid stream selection %s", item)
+		}
+		if s.Shortcut != "" {
+			return fmt.Errorf("only one stream shortcut can be used")
+		}
+		s.Shortcut = shortcut
+	}
+	return nil
+}
+
+// ExportManifest describes the files written by an export. It is saved next to the
+// outputs as <name>_manifest.json.
+type ExportManifest struct {
+	OperationID string         `json:"operation_id"`
+	ProjectID   string         `json:"project_id"`
+	ProjectName string         `json:"project_name"`
+	VideoID     string         `json:"video_id"`
+	Source      string         `json:"source"` // Source file name
+	CreatedAt   time.Time      `json:"created_at"`
+	Files       []ManifestFile `json:"files"`
+}
+
+// ManifestFile is one output file of an export and the source ranges it contains
+type ManifestFile struct {
+	File     string            `json:"file"`
+	Type     string            `json:"type"`             // "video", "audio", "frames", "subtitles" or "chapters"
+	SHA256   string            `json:"sha256,omitempty"` // Set when storage.compute_checksums is enabled
+	Segments []ManifestSegment `json:"segments"`
+}
+
+// ManifestSegment is a source range placed in an output file
+type ManifestSegment struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name,omitempty"`
+	Start       float64 `json:"start"`
+	End         float64 `json:"end"`
+	OutputStart float64 `json:"output_start"` // Position of the segment in the output
 }
 
 // Download represents a video download from URL
 type Download struct {
-	ID        string         `json:"id"`
-	URL       string         `json:"url"`
-	Title     string         `json:"title,omitempty"`
-	Duration  float64        `json:"duration,omitempty"`
-	Status    DownloadStatus `json:"status"`
-	Progress  float64        `json:"progress"`
-	FilePath  string         `json:"file_path,omitempty"`
-	VideoID   string         `json:"video_id,omitempty"`
-	Error     string         `json:"error,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
+	ID              string             `json:"id"`
+	URL             string             `json:"url"`
+	Format          string             `json:"format,omitempty"` // Requested yt-dlp format, kept to resume the download
+	SessionID       string             `json:"session_id,omitempty"`
+	Client          *DownloadClient    `json:"client,omitempty"`    // Requested client settings, kept to resume the download
+	Subtitles       *DownloadSubtitles `json:"subtitles,omitempty"` // Requested subtitles, kept to resume the download
+	Title           string             `json:"title,omitempty"`
+	Duration        float64            `json:"duration,omitempty"`
+	Status          DownloadStatus     `json:"status"`
+	Progress        float64            `json:"progress"`
+	FilePath        string             `json:"file_path,omitempty"`
+	VideoID         string             `json:"video_id,omitempty"`
+	VideoIDs        []string           `json:"video_ids,omitempty"`        // All imported videos when a download has several, e.g. torrents
+	Playlist        bool               `json:"playlist,omitempty"`         // Downloads every item of a playlist as a child download
+	ChildIDs        []string           `json:"child_ids,omitempty"`        // Item downloads of a playlist
+	ParentID        string             `json:"parent_id,omitempty"`        // Playlist download this item belongs to
+	SponsorBlock    []string           `json:"sponsorblock,omitempty"`     // SponsorBlock categories left out of the created project
+	ChapterProject  bool               `json:"chapter_project,omitempty"`  // Create a project with one segment per chapter
+	CreateProject   bool               `json:"create_project,omitempty"`   // Create an empty project for each downloaded video
+	ProjectName     string             `json:"project_name,omitempty"`     // Name template of that project
+	ProjectIDs      []string           `json:"project_ids,omitempty"`      // Projects created for the video, from SponsorBlock segments, chapters or create_project
+	CookieJar       string             `json:"cookie_jar,omitempty"`       // Encrypted cookies sent with the download, shared by playlist items, or the login of an FTP/SFTP download
+	SealedHeaders   string             `json:"sealed_headers,omitempty"`   // Request headers of Client, e.g. Authorization, encrypted like cookie jars
+	RateLimit       string             `json:"rate_limit,omitempty"`       // Bandwidth cap asked for, e.g. "2M"; the configured cap still applies
+	DownloadedBytes int64              `json:"downloaded_bytes,omitempty"` // Bytes downloaded so far; a direct download continues from here on resume
+	TotalBytes      int64              `json:"total_bytes,omitempty"`      // Size of the download, 0 when unknown
+	Speed           float64            `json:"speed,omitempty"`            // Bytes per second while downloading
+	ETA             float64            `json:"eta,omitempty"`              // Seconds left while downloading, 0 when unknown
+	OutputName      string             `json:"output_name,omitempty"`      // Base name of yt-dlp's output files, kept so a resume continues its .part files
+	Attempts        []DownloadAttempt  `json:"attempts,omitempty"`         // Failed attempts that were retried automatically
+	NextRetryAt     *time.Time         `json:"next_retry_at,omitempty"`    // When a retrying download is queued again
+	StartAt         *time.Time         `json:"start_at,omitempty"`         // When a scheduled download starts, or a recurring one runs next
+	Cron            string             `json:"cron,omitempty"`             // Recurring schedule, every run is a download of its own
+	RunIDs          []string           `json:"run_ids,omitempty"`          // Downloads started by a recurring schedule
+	ScheduleID      string             `json:"schedule_id,omitempty"`      // Recurring schedule that started this download
+	Section         *DownloadSection   `json:"section,omitempty"`          // Only this time range of the video is downloaded
+	SHA256          string             `json:"sha256,omitempty"`           // Expected SHA-256 of a direct download, checked when it completes
+	MD5             string             `json:"md5,omitempty"`              // Expected MD5 of a direct download
+	Priority        int                `json:"priority,omitempty"`         // Higher priority downloads leave the queue first
+	QueuePosition   int                `json:"queue_position,omitempty"`   // 1-based place in the queue while queued
+	Error           string             `json:"error,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// DownloadClient is how a download presents itself to the site, for sites that block
+// the default client
+type DownloadClient struct {
+	Impersonate string            `json:"impersonate,omitempty"` // yt-dlp --impersonate target, e.g. "chrome" or "safari-17.0:macos-14"
+	UserAgent   string            `json:"user_agent,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"` // Extra request headers
+
+	CookiesFromBrowser string `json:"cookies_from_browser,omitempty"` // yt-dlp --cookies-from-browser, e.g. "firefox"
+
+	ExtraArgs   []string `json:"extra_args,omitempty"` // Allowlisted yt-dlp options, e.g. ["--extractor-args", "youtube:player_client=android"]
+	CookiesFile string   `json:"-"`                    // Decrypted cookies.txt of the running download
+}
+
+// DownloadSection is the time range of a video a download fetches, in seconds
+type DownloadSection struct {
+	Start    float64 `json:"start"`
+	End      float64 `json:"end,omitempty"`      // 0 = to the end
+	Accurate bool    `json:"accurate,omitempty"` // Re-encode around the cuts instead of starting at the keyframe before start
+}
+
+// DownloadAttempt is a failed attempt of a download that was retried
+type DownloadAttempt struct {
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// DownloadSubtitles selects the subtitles yt-dlp downloads with a video
+type DownloadSubtitles struct {
+	Languages []string `json:"languages,omitempty"` // e.g. ["en", "de"] or ["all"], default English
+	Auto      bool     `json:"auto,omitempty"`      // Also download automatically generated subtitles
 }
 
 type DownloadStatus string
 
 const (
+	DownloadStatusScheduled   DownloadStatus = "scheduled" // Waiting for its start_at time or next cron run
+	DownloadStatusQueued      DownloadStatus = "queued"    // Waiting for a free download slot
 	DownloadStatusPending     DownloadStatus = "pending"
 	DownloadStatusDownloading DownloadStatus = "downloading"
 	DownloadStatusCompleted   DownloadStatus = "completed"
 	DownloadStatusFailed      DownloadStatus = "failed"
 	DownloadStatusCancelled   DownloadStatus = "cancelled"
+	DownloadStatusInterrupted DownloadStatus = "interrupted" // The server stopped during the download
+	DownloadStatusRetrying    DownloadStatus = "retrying"    // Failed for a transient reason, waiting to be retried
 )
+
+// Resumable reports whether a download in this status may still run, on its own or
+// resumed, so its partial files and cookies are worth keeping
+func (s DownloadStatus) Resumable() bool {
+	switch s {
+	case DownloadStatusCompleted, DownloadStatusCancelled:
+		return false
+	default:
+		return true
+	}
+}
+
+// TrashEntry is a batch of data moved to the trash by a clear operation. It can be
+// restored until it expires.
+type TrashEntry struct {
+	ID        string    `json:"id"`
+	Reason    string    `json:"reason"` // The operation that trashed the data, e.g. "clear-all"
+	Files     int       `json:"files"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UsageKind is a tracked kind of storage activity
+type UsageKind string
+
+const (
+	UsageUploads   UsageKind = "uploads"
+	UsageDownloads UsageKind = "downloads"
+	UsageExports   UsageKind = "exports"   // Counted per export, bytes of all output files
+	UsageDeletions UsageKind = "deletions" // Videos deleted or moved to the trash, bytes of their files
+)
+
+// UsageCounter is a count and byte total of one kind of activity
+type UsageCounter struct {
+	Count int64 `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// UsageDay holds the activity counters of one day (UTC)
+type UsageDay struct {
+	Date     string                     `json:"date"` // YYYY-MM-DD
+	Counters map[UsageKind]UsageCounter `json:"counters"`
+}
+
+// Subscription is a feed (RSS, Atom, podcast or YouTube channel) whose new items
+// are downloaded automatically
+type Subscription struct {
+	ID          string     `json:"id"`
+	URL         string     `json:"url"`                    // Feed URL, YouTube channel and playlist URLs are converted to their feed
+	Name        string     `json:"name"`                   // Feed title
+	TitleFilter string     `json:"title_filter,omitempty"` // Regexp item titles must match
+	MaxDuration float64    `json:"max_duration,omitempty"` // Seconds, longer items are skipped
+	Format      string     `json:"format,omitempty"`       // yt-dlp format of the downloads
+	Enabled     bool       `json:"enabled"`
+	SeenItems   []string   `json:"seen_items,omitempty"` // IDs of items already handled, newest last
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
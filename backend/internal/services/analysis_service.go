@@ -0,0 +1,626 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+// AnalysisService runs media analysis (fingerprinting, detection) on videos and projects
+type AnalysisService struct {
+	storage        *storage.Manager
+	projectService *ProjectService
+	config         *config.Config
+	logger         *zap.Logger
+	ffmpeg         *ffmpeg.Executor
+}
+
+// NewAnalysisService creates a new analysis service
+func NewAnalysisService(storage *storage.Manager, projectService *ProjectService, cfg *config.Config, logger *zap.Logger) *AnalysisService {
+	return &AnalysisService{
+		storage:        storage,
+		projectService: projectService,
+		config:         cfg,
+		logger:         logger,
+		ffmpeg:         newFFmpegExecutor(cfg, logger),
+	}
+}
+
+// IntroDetectionRequest represents a request to find a marked intro/outro in other projects
+type IntroDetectionRequest struct {
+	SegmentID    string   `json:"segment_id" binding:"required"`  // Segment marking the intro in the source project
+	ProjectIDs   []string `json:"project_ids" binding:"required"` // Projects (episodes) to search
+	Position     string   `json:"position,omitempty"`             // "intro" (default) searches from the start, "outro" from the end
+	SearchWindow float64  `json:"search_window,omitempty"`        // Seconds to search, default 600 (0 or less = default)
+	MaxBitError  float64  `json:"max_bit_error,omitempty"`        // Match threshold, default 0.3
+	AddSegments  bool     `json:"add_segments,omitempty"`         // Add the found ranges as segments to the target projects
+}
+
+// IntroDetectionResult is the outcome for a single target project
+type IntroDetectionResult struct {
+	ProjectID string                   `json:"project_id"`
+	VideoID   string                   `json:"video_id"`
+	Found     bool                     `json:"found"`
+	Match     *ffmpeg.FingerprintMatch `json:"match,omitempty"`
+	Segment   *models.Segment          `json:"segment,omitempty"`
+	Error     string                   `json:"error,omitempty"`
+}
+
+// DetectIntro fingerprints the marked segment of a project and locates the same audio
+// in each target project, optionally adding skip segments for the matches
+func (s *AnalysisService) DetectIntro(ctx context.Context, projectID string, req IntroDetectionRequest) ([]IntroDetectionResult, error) {
+	project, err := s.projectService.Get(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reference *models.Segment
+	for i := range project.Segments {
+		if project.Segments[i].ID == req.SegmentID {
+			reference = &project.Segments[i]
+			break
+		}
+	}
+	if reference == nil {
+		return nil, notFoundf("segment not found: %s", req.SegmentID)
+	}
+	if reference.End == nil || *reference.End <= reference.Start {
+		return nil, invalidf("reference segment must have an end time")
+	}
+
+	video, err := s.storage.FetchVideo(project.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+
+	position := req.Position
+	if position == "" {
+		position = "intro"
+	}
+	if position != "intro" && position != "outro" {
+		return nil, invalidf("invalid position: %s", position)
+	}
+
+	searchWindow := req.SearchWindow
+	if searchWindow <= 0 {
+		searchWindow = 600
+	}
+	maxBitError := req.MaxBitError
+	if maxBitError <= 0 {
+		maxBitError = 0.3
+	}
+
+	needle, err := s.ffmpeg.ComputeAudioFingerprint(ctx, video.FilePath, reference.Start, *reference.End-reference.Start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint reference segment: %w", err)
+	}
+
+	name := "Intro"
+	if position == "outro" {
+		name = "Outro"
+	}
+
+	results := make([]IntroDetectionResult, 0, len(req.ProjectIDs))
+	for _, targetID := range req.ProjectIDs {
+		result := IntroDetectionResult{ProjectID: targetID}
+
+		target, err := s.projectService.Get(targetID)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.VideoID = target.VideoID
+
+		targetVideo, err := s.storage.FetchVideo(target.VideoID)
+		if err != nil {
+			result.Error = fmt.Sprintf("video not found: %v", err)
+			results = append(results, result)
+			continue
+		}
+
+		searchStart := 0.0
+		if position == "outro" && targetVideo.Duration > searchWindow {
+			searchStart = targetVideo.Duration - searchWindow
+		}
+
+		haystack, err := s.ffmpeg.ComputeAudioFingerprint(ctx, targetVideo.FilePath, searchStart, searchWindow)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		match := ffmpeg.FindFingerprint(haystack, needle, maxBitError)
+		if match == nil {
+			results = append(results, result)
+			continue
+		}
+
+		result.Found = true
+		result.Match = match
+
+		end := match.End
+		segment := models.Segment{
+			ID:    uuid.New().String(),
+			Name:  name,
+			Start: match.Start,
+			End:   &end,
+			Tags:  map[string]string{"skip": position},
+		}
+		result.Segment = &segment
+
+		if req.AddSegments {
+			if err := s.projectService.AddSegment(targetID, segment); err != nil {
+				result.Error = fmt.Sprintf("failed to add segment: %v", err)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	s.logger.Info("Intro detection completed",
+		zap.String("projectId", projectID),
+		zap.String("position", position),
+		zap.Int("targets", len(req.ProjectIDs)),
+	)
+
+	return results, nil
+}
+
+// DetectionRequest represents a scene/black/silence detection request
+type DetectionRequest struct {
+	Mode   string `json:"mode,omitempty"`   // "scene" (default), "black", "silence", "all"
+	Preset string `json:"preset,omitempty"` // Named sensitivity preset, default "balanced"
+
+	// Explicit overrides of the preset values (0 = use preset)
+	Threshold      float64 `json:"threshold,omitempty"`        // Scene score threshold
+	MinSceneLength float64 `json:"min_scene_length,omitempty"` // Seconds
+	MinDuration    float64 `json:"min_duration,omitempty"`     // Black/silence minimum duration in seconds
+	NoiseDB        float64 `json:"noise_db,omitempty"`         // Silence noise floor, e.g. -30
+}
+
+// DetectionResult contains the detected ranges and the effective settings used
+type DetectionResult struct {
+	VideoID string          `json:"video_id"`
+	Mode    string          `json:"mode"`
+	Preset  DetectionPreset `json:"preset"`
+	Scenes  []ffmpeg.Scene  `json:"scenes"`
+	Cached  bool            `json:"cached"`
+
+	Segments []models.Segment `json:"segments,omitempty"` // Segments written to the project, if any
+}
+
+// VideoDetectionRequest runs detection on a video and optionally writes the
+// detected ranges into one of its projects as segments
+type VideoDetectionRequest struct {
+	DetectionRequest
+	ProjectID string `json:"project_id,omitempty"` // Project to add segments to
+	Apply     string `json:"apply,omitempty"`      // "append" (default) or "replace" project segments
+}
+
+// Detect runs scene, black frame and/or silence detection on a video using a preset
+// with optional per-request overrides
+func (s *AnalysisService) Detect(ctx context.Context, videoID string, req DetectionRequest) (*DetectionResult, error) {
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+
+	preset, err := GetDetectionPreset(req.Preset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply overrides on top of the preset so the response reflects what was actually used
+	if req.Threshold > 0 {
+		preset.SceneThreshold = req.Threshold
+	}
+	if req.MinSceneLength > 0 {
+		preset.MinSceneLength = req.MinSceneLength
+	}
+	if req.MinDuration > 0 {
+		preset.BlackMinDuration = req.MinDuration
+		preset.SilenceMinDuration = req.MinDuration
+	}
+	if req.NoiseDB != 0 {
+		preset.SilenceNoiseDB = req.NoiseDB
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = "scene"
+	}
+	if mode != "scene" && mode != "black" && mode != "silence" && mode != "all" {
+		return nil, invalidf("invalid detection mode: %s", mode)
+	}
+
+	result := &DetectionResult{
+		VideoID: videoID,
+		Mode:    mode,
+		Preset:  preset,
+		Scenes:  []ffmpeg.Scene{},
+	}
+
+	// Reuse earlier results for the same file and settings. Results cached before
+	// detection read FFmpeg's log output were always empty, hence the v2 key.
+	fileHash := s.fileHash(video)
+	cacheKey := fmt.Sprintf("detect:v2:%s:%g:%g:%g:%g:%g:%g", mode,
+		preset.SceneThreshold, preset.MinSceneLength,
+		preset.BlackMinDuration, preset.BlackPixelThreshold,
+		preset.SilenceNoiseDB, preset.SilenceMinDuration)
+	if fileHash != "" {
+		if found, err := s.storage.GetAnalysisCache(videoID, fileHash, cacheKey, &result.Scenes); err != nil {
+			s.logger.Warn("Failed to read analysis cache", zap.String("videoId", videoID), zap.Error(err))
+		} else if found {
+			result.Cached = true
+			return result, nil
+		}
+	}
+
+	if mode == "scene" || mode == "all" {
+		scenes, err := s.ffmpeg.DetectScenes(ctx, video.FilePath, ffmpeg.SceneDetectionOptions{
+			Threshold:      preset.SceneThreshold,
+			MinSceneLength: preset.MinSceneLength,
+			Mode:           mode,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Scenes = append(result.Scenes, scenes...)
+	}
+
+	if mode == "black" || mode == "all" {
+		scenes, err := s.ffmpeg.DetectBlackScenes(ctx, video.FilePath, preset.BlackMinDuration, preset.BlackPixelThreshold)
+		if err != nil {
+			return nil, err
+		}
+		result.Scenes = append(result.Scenes, scenes...)
+	}
+
+	if mode == "silence" || mode == "all" {
+		scenes, err := s.ffmpeg.DetectSilentScenes(ctx, video.FilePath, preset.SilenceMinDuration, preset.SilenceNoiseDB)
+		if err != nil {
+			return nil, err
+		}
+		result.Scenes = append(result.Scenes, scenes...)
+	}
+
+	if fileHash != "" {
+		if err := s.storage.SaveAnalysisCache(videoID, fileHash, cacheKey, result.Scenes); err != nil {
+			s.logger.Warn("Failed to save analysis cache", zap.String("videoId", videoID), zap.Error(err))
+		}
+	}
+
+	s.logger.Info("Detection completed",
+		zap.String("videoId", videoID),
+		zap.String("mode", mode),
+		zap.String("preset", preset.Name),
+		zap.Int("scenes", len(result.Scenes)),
+	)
+
+	return result, nil
+}
+
+// DetectSegments runs detection on a video and, when a project is given, turns
+// the result into segments of that project
+func (s *AnalysisService) DetectSegments(ctx context.Context, videoID string, req VideoDetectionRequest) (*DetectionResult, error) {
+	if req.ProjectID == "" {
+		return s.Detect(ctx, videoID, req.DetectionRequest)
+	}
+
+	if req.Apply != "" && req.Apply != "append" && req.Apply != "replace" {
+		return nil, invalidf("invalid apply mode: %s", req.Apply)
+	}
+
+	project, err := s.projectService.Get(req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.VideoID != videoID {
+		return nil, invalidf("project %s does not belong to video %s", req.ProjectID, videoID)
+	}
+
+	video, err := s.storage.GetVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+
+	result, err := s.Detect(ctx, videoID, req.DetectionRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Detection takes a while, the segments go into the project as it is now
+	result.Segments = scenesToSegments(result.Scenes, video.Duration)
+	project, err = s.projectService.Modify(req.ProjectID, func(project *models.Project) error {
+		if req.Apply == "replace" {
+			project.Segments = result.Segments
+		} else {
+			project.Segments = append(project.Segments, result.Segments...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Added detected segments",
+		zap.String("projectId", project.ID),
+		zap.Int("segments", len(result.Segments)),
+	)
+
+	return result, nil
+}
+
+// scenesToSegments turns detected ranges into project segments. Scene cuts split
+// the whole video into consecutive segments; black frames and silences become
+// one segment each, so they can be removed or kept on their own.
+func scenesToSegments(scenes []ffmpeg.Scene, duration float64) []models.Segment {
+	var cuts []float64
+	var ranges []ffmpeg.Scene
+	for _, scene := range scenes {
+		if scene.Type == "cut" {
+			if scene.Start > 0 && (duration <= 0 || scene.Start < duration) {
+				cuts = append(cuts, scene.Start)
+			}
+		} else if scene.End > scene.Start {
+			ranges = append(ranges, scene)
+		}
+	}
+	sort.Float64s(cuts)
+
+	segments := make([]models.Segment, 0, len(cuts)+len(ranges)+1)
+
+	if len(cuts) > 0 {
+		start := 0.0
+		for i, cut := range append(cuts, duration) {
+			if cut <= start && i < len(cuts) {
+				continue // Duplicate cut point
+			}
+			segment := models.Segment{
+				ID:    uuid.New().String(),
+				Name:  fmt.Sprintf("Scene %d", len(segments)+1),
+				Start: start,
+			}
+			if cut > start {
+				end := cut
+				segment.End = &end
+			}
+			segments = append(segments, segment)
+			start = cut
+		}
+	}
+
+	counts := map[string]int{}
+	for _, scene := range ranges {
+		counts[scene.Type]++
+		end := scene.End
+		segments = append(segments, models.Segment{
+			ID:    uuid.New().String(),
+			Name:  fmt.Sprintf("%s %d", sceneTypeName(scene.Type), counts[scene.Type]),
+			Start: scene.Start,
+			End:   &end,
+		})
+	}
+
+	return segments
+}
+
+// sceneTypeName returns the segment name prefix for a detected range type
+func sceneTypeName(sceneType string) string {
+	switch sceneType {
+	case "black":
+		return "Black"
+	case "silent":
+		return "Silence"
+	default:
+		return "Detected"
+	}
+}
+
+// GetKeyframes returns the keyframe timestamps of a video from its keyframe index
+func (s *AnalysisService) GetKeyframes(ctx context.Context, videoID string) ([]float64, error) {
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+
+	return loadKeyframeIndex(ctx, s.storage, s.ffmpeg, s.logger, video)
+}
+
+// SnapToKeyframe returns the keyframes of a video around t, to snap cut points to
+func (s *AnalysisService) SnapToKeyframe(ctx context.Context, videoID string, t float64) (ffmpeg.KeyframeSnap, error) {
+	keyframes, err := s.GetKeyframes(ctx, videoID)
+	if err != nil {
+		return ffmpeg.KeyframeSnap{}, err
+	}
+	return ffmpeg.SnapToKeyframe(keyframes, t), nil
+}
+
+// AnalyzeLoudness measures the EBU R128 loudness of a video between start and end
+// (0 = end of file). Results are cached per file and range.
+func (s *AnalysisService) AnalyzeLoudness(ctx context.Context, videoID string, start, end float64) (*ffmpeg.LoudnessAnalysis, error) {
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	if start < 0 || end < 0 {
+		return nil, fmt.Errorf("start and end must not be negative")
+	}
+
+	fileHash := s.fileHash(video)
+	cacheKey := fmt.Sprintf("loudness:%g:%g", start, end)
+	if fileHash != "" {
+		var cached ffmpeg.LoudnessAnalysis
+		if found, err := s.storage.GetAnalysisCache(videoID, fileHash, cacheKey, &cached); err != nil {
+			s.logger.Warn("Failed to read analysis cache", zap.String("videoId", videoID), zap.Error(err))
+		} else if found {
+			return &cached, nil
+		}
+	}
+
+	analysis, err := s.ffmpeg.AnalyzeLoudness(ctx, video.FilePath, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze loudness: %w", err)
+	}
+
+	if fileHash != "" {
+		if err := s.storage.SaveAnalysisCache(videoID, fileHash, cacheKey, analysis); err != nil {
+			s.logger.Warn("Failed to save analysis cache", zap.String("videoId", videoID), zap.Error(err))
+		}
+	}
+
+	s.logger.Info("Loudness analysis completed",
+		zap.String("videoId", videoID),
+		zap.Float64("integrated", analysis.Integrated),
+		zap.Float64("true_peak", analysis.TruePeak),
+	)
+
+	return analysis, nil
+}
+
+// AnalyzeSegmentLoudness measures the loudness of a project segment. A segment
+// without an end is measured to the end of the video.
+func (s *AnalysisService) AnalyzeSegmentLoudness(ctx context.Context, projectID, segmentID string) (*ffmpeg.LoudnessAnalysis, error) {
+	project, err := s.projectService.Get(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, segment := range project.Segments {
+		if segment.ID != segmentID {
+			continue
+		}
+		end := 0.0
+		if segment.End != nil {
+			end = *segment.End
+		}
+		return s.AnalyzeLoudness(ctx, project.VideoID, segment.Start, end)
+	}
+
+	return nil, fmt.Errorf("segment not found: %s", segmentID)
+}
+
+// fileHash returns the content hash used to key cached analysis, or "" if the
+// file cannot be hashed (caching is then skipped)
+func (s *AnalysisService) fileHash(video *models.Video) string {
+	hash, err := s.storage.ComputeFileHash(video.FilePath)
+	if err != nil {
+		s.logger.Warn("Failed to hash video file, analysis cache disabled",
+			zap.String("videoId", video.ID),
+			zap.Error(err),
+		)
+		return ""
+	}
+	return hash
+}
+
+// ChapterSuggestionRequest represents a request to suggest chapters for a project from detection
+type ChapterSuggestionRequest struct {
+	DetectionRequest
+	MinChapterLength float64 `json:"min_chapter_length,omitempty"` // Seconds, default 60
+	Apply            string  `json:"apply,omitempty"`              // "" = suggest only, "append" or "replace" project segments
+}
+
+// SuggestChapters detects boundaries in a project's video and turns them into an
+// auto-numbered chapter list respecting a minimum chapter length. Applied chapters
+// become project segments, so they can be exported with the existing chapters formats.
+func (s *AnalysisService) SuggestChapters(ctx context.Context, projectID string, req ChapterSuggestionRequest) ([]models.Segment, error) {
+	project, err := s.projectService.Get(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Apply != "" && req.Apply != "append" && req.Apply != "replace" {
+		return nil, invalidf("invalid apply mode: %s", req.Apply)
+	}
+
+	video, err := s.storage.GetVideo(project.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	if video.Duration <= 0 {
+		return nil, invalidf("video duration unknown")
+	}
+
+	detection, err := s.Detect(ctx, project.VideoID, req.DetectionRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	minLength := req.MinChapterLength
+	if minLength <= 0 {
+		minLength = 60
+	}
+
+	boundaries := suggestChapterBoundaries(detection.Scenes, video.Duration, minLength)
+
+	chapters := make([]models.Segment, 0, len(boundaries)+1)
+	start := 0.0
+	for i, boundary := range append(boundaries, video.Duration) {
+		end := boundary
+		chapters = append(chapters, models.Segment{
+			ID:    uuid.New().String(),
+			Name:  fmt.Sprintf("Chapter %d", i+1),
+			Start: start,
+			End:   &end,
+		})
+		start = boundary
+	}
+
+	if req.Apply != "" {
+		_, err := s.projectService.Modify(projectID, func(project *models.Project) error {
+			if req.Apply == "replace" {
+				project.Segments = chapters
+			} else {
+				project.Segments = append(project.Segments, chapters...)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("Suggested chapters",
+		zap.String("projectId", projectID),
+		zap.Int("chapters", len(chapters)),
+		zap.String("apply", req.Apply),
+	)
+
+	return chapters, nil
+}
+
+// suggestChapterBoundaries picks chapter start times from detected ranges so that no
+// chapter (including the first and last) is shorter than minLength
+func suggestChapterBoundaries(scenes []ffmpeg.Scene, duration, minLength float64) []float64 {
+	var candidates []float64
+	for _, scene := range scenes {
+		// Cuts mark a single point; black frames and silences are split in the middle
+		t := scene.Start
+		if scene.Type != "cut" && scene.End > scene.Start {
+			t = (scene.Start + scene.End) / 2
+		}
+		candidates = append(candidates, t)
+	}
+	sort.Float64s(candidates)
+
+	var boundaries []float64
+	last := 0.0
+	for _, t := range candidates {
+		if t-last >= minLength && duration-t >= minLength {
+			boundaries = append(boundaries, t)
+			last = t
+		}
+	}
+
+	return boundaries
+}
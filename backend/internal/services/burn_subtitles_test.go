@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestSubtitleBurn(t *testing.T) {
+	video := &models.Video{
+		FilePath: "/videos/movie.mkv",
+		Metadata: models.VideoMetadata{Streams: []models.Stream{
+			{Index: 0, CodecType: "video", CodecName: "h264"},
+			{Index: 1, CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle"},
+			{Index: 2, CodecType: "audio", CodecName: "aac"},
+			{Index: 3, CodecType: "subtitle", CodecName: "subrip"},
+		}},
+		Subtitles: []models.SubtitleFile{{ID: "sub1", FilePath: "/videos/sub1.srt"}},
+	}
+	stream := func(i int) *int { return &i }
+
+	burn, err := subtitleBurn(video, models.ExportRequest{BurnSubtitleStream: stream(3)})
+	if want := (ffmpeg.SubtitleBurn{File: "/videos/movie.mkv", Index: 1}); err != nil || burn != want {
+		t.Errorf("embedded stream: got %+v, %v, want %+v", burn, err, want)
+	}
+
+	burn, err = subtitleBurn(video, models.ExportRequest{BurnSubtitleFile: "sub1"})
+	if want := (ffmpeg.SubtitleBurn{File: "/videos/sub1.srt"}); err != nil || burn != want {
+		t.Errorf("sidecar: got %+v, %v, want %+v", burn, err, want)
+	}
+
+	for _, request := range []models.ExportRequest{
+		{BurnSubtitleStream: stream(1)}, // Bitmap subtitles
+		{BurnSubtitleStream: stream(2)}, // Not a subtitle stream
+		{BurnSubtitleFile: "missing"},
+	} {
+		if _, err := subtitleBurn(video, request); err == nil {
+			t.Errorf("subtitleBurn(%+v): want error", request)
+		}
+	}
+}
+
+func TestValidateBurnSubtitles(t *testing.T) {
+	stream := 3
+	request := models.ExportRequest{BurnSubtitleStream: &stream}
+	if err := validateExportRequest(request); err != nil || cutMode(request) != CutModeReencode {
+		t.Errorf("burned subtitles: err = %v, cut mode = %s, want re-encode", err, cutMode(request))
+	}
+
+	for _, request := range []models.ExportRequest{
+		{BurnSubtitleStream: &stream, BurnSubtitleFile: "sub1"},
+		{BurnSubtitleStream: &stream, CutMode: CutModeLossless},
+		{BurnSubtitleFile: "sub1", Crossfade: 1},
+	} {
+		if err := validateExportRequest(request); err == nil {
+			t.Errorf("validateExportRequest(%+v): want error", request)
+		}
+	}
+}
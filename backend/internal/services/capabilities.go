@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+)
+
+// capabilitiesTimeout bounds the ffmpeg runs discovering capabilities
+const capabilitiesTimeout = 30 * time.Second
+
+// FFmpegCapabilities returns what the installed FFmpeg build can do. The result is
+// discovered once and cached, refresh discovers it again, e.g. after an upgrade.
+func (s *OperationService) FFmpegCapabilities(ctx context.Context, refresh bool) (*ffmpeg.Capabilities, error) {
+	ctx, cancel := context.WithTimeout(ctx, capabilitiesTimeout)
+	defer cancel()
+
+	return s.ffmpeg.Capabilities(ctx, refresh)
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// sha256Pattern matches a hex SHA-256 checksum
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// NormalizeChecksum validates an optional expected checksum and lowercases it
+func NormalizeChecksum(sum string) (string, error) {
+	sum = strings.TrimSpace(sum)
+	if sum == "" {
+		return "", nil
+	}
+	if !sha256Pattern.MatchString(sum) {
+		return "", fmt.Errorf("sha256 must be 64 hex characters")
+	}
+	return strings.ToLower(sum), nil
+}
+
+// ChecksumVideo computes the SHA-256 of a video file in the background and stores it
+// on the video record. With expected set, the result is also compared to it, e.g. to
+// confirm an upload arrived intact.
+func (s *OperationService) ChecksumVideo(videoID, expected string) (*models.Operation, error) {
+	expected, err := NormalizeChecksum(expected)
+	if err != nil {
+		return nil, err
+	}
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+
+	return s.startChecksum(models.OperationTypeChecksum, video.FilePath, expected, func(sum string) {
+		// Reload the record, it may have changed while the file was hashed
+		video, err := s.storage.GetVideo(videoID)
+		if err != nil {
+			return
+		}
+		video.SHA256 = sum
+		if err := s.storage.SaveVideo(video); err != nil {
+			s.logger.Warn("Failed to save video checksum", zap.String("videoId", videoID), zap.Error(err))
+		}
+	}), nil
+}
+
+// VerifyVideo hashes a video file again and compares it to expected, or to the
+// checksum stored on the video when expected is empty
+func (s *OperationService) VerifyVideo(videoID, expected string) (*models.Operation, error) {
+	expected, err := NormalizeChecksum(expected)
+	if err != nil {
+		return nil, err
+	}
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	if expected == "" {
+		expected = video.SHA256
+	}
+	if expected == "" {
+		return nil, fmt.Errorf("no checksum stored for video %s, compute one first or pass sha256", videoID)
+	}
+
+	return s.startChecksum(models.OperationTypeVerify, video.FilePath, expected, nil), nil
+}
+
+// ChecksumOutput computes the SHA-256 of an exported file in the background and saves
+// it next to the file
+func (s *OperationService) ChecksumOutput(filename, expected string) (*models.Operation, error) {
+	expected, err := NormalizeChecksum(expected)
+	if err != nil {
+		return nil, err
+	}
+	path, err := s.outputPath(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startChecksum(models.OperationTypeChecksum, path, expected, func(sum string) {
+		if err := s.storage.SaveChecksumSidecar(path, sum); err != nil {
+			s.logger.Warn("Failed to save output checksum", zap.String("path", path), zap.Error(err))
+		}
+	}), nil
+}
+
+// VerifyOutput hashes an exported file again and compares it to expected, or to the
+// checksum saved next to it when expected is empty
+func (s *OperationService) VerifyOutput(filename, expected string) (*models.Operation, error) {
+	expected, err := NormalizeChecksum(expected)
+	if err != nil {
+		return nil, err
+	}
+	path, err := s.outputPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	if expected == "" {
+		if expected, err = s.storage.ReadChecksumSidecar(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.startChecksum(models.OperationTypeVerify, path, expected, nil), nil
+}
+
+// outputPath returns the path of an existing file in the outputs directory
+func (s *OperationService) outputPath(filename string) (string, error) {
+	if filename == "" || filename != filepath.Base(filename) || strings.HasPrefix(filename, ".") {
+		return "", fmt.Errorf("invalid output filename: %s", filename)
+	}
+	path := s.storage.GetOutputPath(filename)
+	if s.storage.FetchFile(path) != nil {
+		return "", fmt.Errorf("output file not found: %s", filename)
+	}
+	return path, nil
+}
+
+// startChecksum registers a checksum operation and hashes path in the background.
+// onSum is called with the checksum once it is computed.
+func (s *OperationService) startChecksum(operationType models.OperationType, path, expected string, onSum func(sum string)) *models.Operation {
+	operation := &models.Operation{
+		ID:        uuid.New().String(),
+		Type:      operationType,
+		Status:    models.OperationStatusPending,
+		Checksum:  &models.ChecksumResult{File: filepath.Base(path), Expected: expected},
+		CreatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	s.operations[operation.ID] = operation
+	snapshot := *operation
+	s.mu.Unlock()
+
+	go s.runChecksum(operation, path, onSum)
+
+	return &snapshot
+}
+
+func (s *OperationService) runChecksum(operation *models.Operation, path string, onSum func(sum string)) {
+	s.mu.Lock()
+	operation.Status = models.OperationStatusProcessing
+	s.mu.Unlock()
+
+	sum, err := s.storage.ComputeSHA256(path, func(progress float64) {
+		s.mu.Lock()
+		operation.Progress = progress * 100
+		s.mu.Unlock()
+	})
+	if err != nil {
+		s.mu.Lock()
+		operation.Status = models.OperationStatusFailed
+		operation.Error = err.Error()
+		s.mu.Unlock()
+		s.logger.Error("Checksum failed", zap.String("operationId", operation.ID), zap.Error(err))
+		return
+	}
+
+	if onSum != nil {
+		onSum(sum)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	result := *operation.Checksum
+	result.SHA256 = sum
+	operation.Status = models.OperationStatusCompleted
+	if result.Expected != "" {
+		match := sum == result.Expected
+		result.Match = &match
+		if !match {
+			operation.Status = models.OperationStatusCompletedWithWarnings
+			operation.Warnings = []string{fmt.Sprintf("%s: checksum %s does not match expected %s", result.File, sum, result.Expected)}
+		}
+	}
+	// Replace rather than modify the result, snapshots share the pointer
+	operation.Checksum = &result
+	operation.Progress = 100
+	operation.CompletedAt = &now
+	s.mu.Unlock()
+
+	s.logger.Info("Checksum completed",
+		zap.String("operationId", operation.ID),
+		zap.String("file", result.File),
+		zap.String("sha256", sum),
+		zap.Bool("mismatch", result.Match != nil && !*result.Match),
+	)
+}
+
+// checksumOutputs hashes the files of a finished export and saves each checksum next
+// to its file. It returns the checksums by path for the export manifest.
+func (s *OperationService) checksumOutputs(paths []string) map[string]string {
+	sums := make(map[string]string, len(paths))
+	for _, path := range paths {
+		sum, err := s.storage.ComputeSHA256(path, nil)
+		if err != nil {
+			s.logger.Warn("Failed to checksum export output", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		if err := s.storage.SaveChecksumSidecar(path, sum); err != nil {
+			s.logger.Warn("Failed to save output checksum", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		sums[path] = sum
+	}
+	return sums
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// selectContainer checks the output container of exports that copy streams against
+// the source streams. Without an explicit format exports default to MP4 and switch
+// to MKV when a stream can't be stored in MP4; an explicit format that can't hold a
+// stream is an error.
+func selectContainer(streams []models.Stream, request models.ExportRequest) (models.ExportRequest, error) {
+	// Re-encoded and audio-only outputs are encoded for their container
+	if request.AudioOnly || cutMode(request) == CutModeReencode {
+		return request, nil
+	}
+
+	format := request.Format
+	if format == "" {
+		format = "mp4"
+	}
+
+	for _, stream := range streams {
+		if ffmpeg.ContainerSupports(format, stream.CodecType, stream.CodecName) {
+			continue
+		}
+
+		if request.Format != "" {
+			return request, invalidf("%s can't hold %s stream %d (%s) without re-encoding, export as mkv or use cut_mode \"reencode\"",
+				format, stream.CodecType, stream.Index, stream.CodecName)
+		}
+
+		request.Format = "mkv"
+		return request, nil
+	}
+
+	return request, nil
+}
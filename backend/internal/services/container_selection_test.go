@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestSelectContainer(t *testing.T) {
+	h264AAC := []models.Stream{
+		{Index: 0, CodecType: "video", CodecName: "h264"},
+		{Index: 1, CodecType: "audio", CodecName: "aac"},
+	}
+	vp9Opus := []models.Stream{
+		{Index: 0, CodecType: "video", CodecName: "vp9"},
+		{Index: 1, CodecType: "audio", CodecName: "opus"},
+	}
+
+	request, err := selectContainer(h264AAC, models.ExportRequest{})
+	if err != nil || request.Format != "" {
+		t.Errorf("h264/aac: format = %q, err = %v, want default mp4", request.Format, err)
+	}
+
+	request, err = selectContainer(vp9Opus, models.ExportRequest{})
+	if err != nil || request.Format != "mkv" {
+		t.Errorf("vp9/opus: format = %q, err = %v, want mkv", request.Format, err)
+	}
+
+	if _, err := selectContainer(vp9Opus, models.ExportRequest{Format: "mp4"}); err == nil {
+		t.Error("vp9/opus into explicit mp4: want error")
+	}
+
+	if request, err := selectContainer(vp9Opus, models.ExportRequest{Format: "webm"}); err != nil || request.Format != "webm" {
+		t.Errorf("vp9/opus into webm: format = %q, err = %v", request.Format, err)
+	}
+
+	pcm := []models.Stream{{Index: 1, CodecType: "audio", CodecName: "pcm_s24le"}}
+	if request, err := selectContainer(pcm, models.ExportRequest{Format: "mov"}); err != nil || request.Format != "mov" {
+		t.Errorf("pcm into mov: format = %q, err = %v", request.Format, err)
+	}
+
+	// Re-encoding produces streams that fit the container
+	if _, err := selectContainer(vp9Opus, models.ExportRequest{Format: "mp4", CutMode: CutModeReencode}); err != nil {
+		t.Errorf("re-encode into mp4: %v", err)
+	}
+}
+
+func TestValidateExportFormat(t *testing.T) {
+	if err := validateExportRequest(models.ExportRequest{Format: "m2ts"}); err != nil {
+		t.Errorf("m2ts: %v", err)
+	}
+	if err := validateExportRequest(models.ExportRequest{Format: "avi"}); err == nil {
+		t.Error("avi: want unsupported format error")
+	}
+	for _, preset := range ListExportPresets() {
+		if err := validateExportRequest(models.ExportRequest{Format: preset.Format, CutMode: CutModeReencode}); err != nil {
+			t.Errorf("preset %s: %v", preset.Name, err)
+		}
+	}
+	// Audio-only exports name their own formats
+	if err := validateExportRequest(models.ExportRequest{Format: "flac", AudioOnly: true}); err != nil {
+		t.Errorf("audio-only flac: %v", err)
+	}
+}
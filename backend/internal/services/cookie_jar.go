@@ -0,0 +1,222 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// maxCookiesSize caps the cookies.txt a download request can send
+const maxCookiesSize = 1 << 20
+
+// cookieKeyFile holds the generated key when no cookie_secret is configured
+const cookieKeyFile = "cookies.key"
+
+// validateCookies checks that cookies sent with a download are a Netscape
+// cookies.txt, as exported by browser extensions
+func validateCookies(cookies string) error {
+	if len(cookies) > maxCookiesSize {
+		return fmt.Errorf("cookies exceed %d bytes", maxCookiesSize)
+	}
+	for _, line := range strings.Split(cookies, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if len(strings.Split(line, "\t")) != 7 {
+			return fmt.Errorf("cookies must be a Netscape cookies.txt file")
+		}
+	}
+	return nil
+}
+
+// encryptCookies seals cookies with AES-256-GCM, prefixed with the nonce
+func encryptCookies(key, cookies []byte) ([]byte, error) {
+	gcm, err := cookieCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, cookies, nil), nil
+}
+
+// decryptCookies opens cookies sealed by encryptCookies
+func decryptCookies(key, data []byte) ([]byte, error) {
+	gcm, err := cookieCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("cookie jar is truncated")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	cookies, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt cookie jar: %w", err)
+	}
+	return cookies, nil
+}
+
+func cookieCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// cookieKey returns the key encrypting cookie jars: derived from the configured
+// secret, or else a random key generated once and kept in the cookies directory
+func (s *DownloadService) cookieKey() ([]byte, error) {
+	if err := os.MkdirAll(s.storage.CookiesDir(), 0700); err != nil {
+		return nil, err
+	}
+	if s.config.Download.CookieSecret != "" {
+		sum := sha256.Sum256([]byte(s.config.Download.CookieSecret))
+		return sum[:], nil
+	}
+
+	path := filepath.Join(s.storage.CookiesDir(), cookieKeyFile)
+	if key, err := os.ReadFile(path); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to save cookie key: %w", err)
+	}
+	return key, nil
+}
+
+// saveCookieJar encrypts the cookies sent with a download into its cookie jar
+func (s *DownloadService) saveCookieJar(jarID, cookies string) error {
+	key, err := s.cookieKey()
+	if err != nil {
+		return err
+	}
+	data, err := encryptCookies(key, []byte(cookies))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.storage.GetCookieJarPath(jarID), data, 0600)
+}
+
+// openCookieJar decrypts a cookie jar into a private temporary cookies.txt for
+// yt-dlp. The returned function removes it again.
+func (s *DownloadService) openCookieJar(jarID string) (string, func(), error) {
+	key, err := s.cookieKey()
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(s.storage.GetCookieJarPath(jarID))
+	if err != nil {
+		return "", nil, fmt.Errorf("cookie jar not found: %w", err)
+	}
+	cookies, err := decryptCookies(key, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	file, err := os.CreateTemp(s.storage.CookiesDir(), "run-*.txt")
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	if _, err := file.Write(cookies); err != nil {
+		os.Remove(file.Name())
+		return "", nil, err
+	}
+	return file.Name(), func() { os.Remove(file.Name()) }, nil
+}
+
+// sealHeaders encrypts the request headers of a download with the cookie key, to
+// be kept in its record
+func (s *DownloadService) sealHeaders(headers map[string]string) (string, error) {
+	key, err := s.cookieKey()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := encryptCookies(key, data)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openHeaders decrypts request headers sealed by sealHeaders
+func (s *DownloadService) openHeaders(sealed string) (map[string]string, error) {
+	key, err := s.cookieKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("sealed headers are corrupt: %w", err)
+	}
+	if data, err = decryptCookies(key, data); err != nil {
+		return nil, err
+	}
+	var headers map[string]string
+	if err := json.Unmarshal(data, &headers); err != nil {
+		return nil, fmt.Errorf("sealed headers are corrupt: %w", err)
+	}
+	return headers, nil
+}
+
+// deleteCookieJar removes the cookie jar of a download that is done with it, unless
+// another download still waits for or runs with it: the runs of a recurring
+// schedule share its jar, playlist items their playlist's. The last of them to
+// finish deletes it.
+func (s *DownloadService) deleteCookieJar(download *models.Download) {
+	jarID := download.CookieJar
+	if jarID == "" || s.cookieJarInUse(jarID, download.ID) {
+		return
+	}
+	if err := os.Remove(s.storage.GetCookieJarPath(jarID)); err != nil && !os.IsNotExist(err) {
+		s.logger.Warn("Failed to delete cookie jar", zap.String("jar", jarID), zap.Error(err))
+	}
+}
+
+// cookieJarInUse reports whether a download other than exceptID is scheduled,
+// queued, running or resumable with a cookie jar. When downloads can't be listed
+// the jar is kept, startup cleanup removes it once unused.
+func (s *DownloadService) cookieJarInUse(jarID, exceptID string) bool {
+	downloads, err := s.storage.ListDownloads()
+	if err != nil {
+		s.logger.Warn("Failed to check cookie jar use", zap.String("jar", jarID), zap.Error(err))
+		return true
+	}
+	for _, download := range downloads {
+		if download.ID == exceptID || download.CookieJar != jarID {
+			continue
+		}
+		switch download.Status {
+		case models.DownloadStatusScheduled, models.DownloadStatusQueued, models.DownloadStatusPending,
+			models.DownloadStatusDownloading, models.DownloadStatusRetrying, models.DownloadStatusInterrupted:
+			return true
+		}
+	}
+	return false
+}
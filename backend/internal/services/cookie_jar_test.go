@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+func TestCookieEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	cookies := []byte("# Netscape HTTP Cookie File\n.youtube.com\tTRUE\t/\tTRUE\t0\tSID\tsecret\n")
+
+	sealed, err := encryptCookies(key, cookies)
+	if err != nil {
+		t.Fatalf("encryptCookies() = %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Error("sealed cookies contain the plaintext")
+	}
+
+	opened, err := decryptCookies(key, sealed)
+	if err != nil || !bytes.Equal(opened, cookies) {
+		t.Errorf("decryptCookies() = %q, %v, want the original cookies", opened, err)
+	}
+
+	if _, err := decryptCookies(bytes.Repeat([]byte{8}, 32), sealed); err == nil {
+		t.Error("expected decrypting with another key to fail")
+	}
+}
+
+func TestSealHeaders(t *testing.T) {
+	m := storage.NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	s := NewDownloadService(m, nil, nil, &config.Config{}, zap.NewNop())
+
+	headers := map[string]string{"Authorization": "Bearer secret-token"}
+	sealed, err := s.sealHeaders(headers)
+	if err != nil {
+		t.Fatalf("sealHeaders() = %v", err)
+	}
+	if strings.Contains(sealed, "secret-token") {
+		t.Error("sealed headers contain the plaintext")
+	}
+	opened, err := s.openHeaders(sealed)
+	if err != nil || !reflect.DeepEqual(opened, headers) {
+		t.Errorf("openHeaders() = %v, %v, want %v", opened, err, headers)
+	}
+	if _, err := s.openHeaders("not base64!"); err == nil {
+		t.Error("openHeaders() of corrupt headers succeeded")
+	}
+}
+
+func TestValidateCookies(t *testing.T) {
+	valid := "# Netscape HTTP Cookie File\n\n.example.com\tTRUE\t/\tFALSE\t1735689600\tsession\tabc\n"
+	if err := validateCookies(valid); err != nil {
+		t.Errorf("validateCookies() = %v", err)
+	}
+	if err := validateCookies("session=abc; other=def"); err == nil {
+		t.Error("expected an error for a Cookie header instead of cookies.txt")
+	}
+}
+
+func TestDeleteCookieJarSharedBySchedule(t *testing.T) {
+	m := storage.NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	s := NewDownloadService(m, nil, nil, &config.Config{}, zap.NewNop())
+	if err := s.saveCookieJar("jar1", "# Netscape HTTP Cookie File\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	schedule := &models.Download{Status: models.DownloadStatusCancelled, CookieJar: "jar1"}
+	run := &models.Download{Status: models.DownloadStatusQueued, CookieJar: "jar1", ScheduleID: "schedule"}
+	for _, download := range []*models.Download{schedule, run} {
+		if err := m.CreateDownload(download); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The cancelled schedule's run still needs the cookies
+	s.deleteCookieJar(schedule)
+	if !m.FileExists(m.GetCookieJarPath("jar1")) {
+		t.Fatal("cookie jar of a queued run was deleted")
+	}
+
+	// The last run to finish deletes them
+	run.Status = models.DownloadStatusCompleted
+	if err := m.UpdateDownload(run); err != nil {
+		t.Fatal(err)
+	}
+	s.deleteCookieJar(run)
+	if m.FileExists(m.GetCookieJarPath("jar1")) {
+		t.Error("cookie jar was kept after the last run")
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression: minute, hour, day of month,
+// month and day of week
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+
+	// Cron matches either day field when both are restricted
+	anyDay, anyWeekday bool
+}
+
+// cronMacros are the shorthands cron expressions can use
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// maxCronSearch bounds how far ahead next looks for a match, for expressions like
+// "0 0 30 2 *" that never match
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+// parseCron parses a cron expression such as "30 2 * * 1-5" or "@daily". Fields
+// take numbers, *, ranges, lists and steps like */15.
+func parseCron(expr string) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.TrimSpace(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: needs 5 fields, minute hour day month weekday", expr)
+	}
+
+	var schedule cronSchedule
+	var err error
+	ranges := []struct {
+		set      *map[int]bool
+		min, max int
+	}{
+		{&schedule.minutes, 0, 59},
+		{&schedule.hours, 0, 23},
+		{&schedule.days, 1, 31},
+		{&schedule.months, 1, 12},
+		{&schedule.weekdays, 0, 7},
+	}
+	for i, r := range ranges {
+		if *r.set, err = parseCronField(fields[i], r.min, r.max); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+	// Sunday is 0 or 7
+	if schedule.weekdays[7] {
+		schedule.weekdays[0] = true
+	}
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+	return &schedule, nil
+}
+
+// parseCronField parses one field of a cron expression into the values it matches
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(first); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(last); err != nil {
+					return nil, fmt.Errorf("invalid range %q", part)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// matchesDay reports whether the schedule runs on t's day. Like cron, a restricted
+// day of month and day of week both match.
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	day, weekday := c.days[t.Day()], c.weekdays[int(t.Weekday())]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// next returns the first time after after the schedule matches, in after's time
+// zone, or false when it never does
+func (c *cronSchedule) next(after time.Time) (time.Time, bool) {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(maxCronSearch)
+
+	for t.Before(limit) {
+		switch {
+		case !c.months[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
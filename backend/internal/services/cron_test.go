@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// A Wednesday
+	after := time.Date(2024, 1, 31, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 2, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)}, // Sunday
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, // 1st or Friday
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0,45 10 * * *", time.Date(2024, 1, 31, 10, 45, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got, ok := schedule.next(after); !ok || !got.Equal(tt.want) {
+			t.Errorf("next(%q) = %v, %v, want %v", tt.expr, got, ok, tt.want)
+		}
+	}
+
+	never, err := parseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := never.next(after); ok {
+		t.Error("expected February 30th to never match")
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
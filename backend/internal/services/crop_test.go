@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestCropAndScaleForceReencode(t *testing.T) {
+	for _, request := range []models.ExportRequest{
+		{Crop: &models.CropRect{Y: 140, Width: 1920, Height: 800}},
+		{Width: 1280},
+		{Height: 720},
+	} {
+		if mode := cutMode(request); mode != CutModeReencode {
+			t.Errorf("%+v: cut mode = %s, want %s", request, mode, CutModeReencode)
+		}
+		if err := validateExportRequest(request); err != nil {
+			t.Errorf("%+v: %v", request, err)
+		}
+	}
+
+	for _, request := range []models.ExportRequest{
+		{Width: 1280, CutMode: CutModeSmart},
+		{Crop: &models.CropRect{Width: 1920, Height: 800}, CutMode: CutModeLossless},
+		{Crop: &models.CropRect{Width: 1921, Height: 800}},
+		{Width: -1},
+	} {
+		if err := validateExportRequest(request); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%+v: got %v, expected an invalid request error", request, err)
+		}
+	}
+}
+
+func TestValidateCrop(t *testing.T) {
+	video := &models.Video{Metadata: models.VideoMetadata{Streams: []models.Stream{
+		{CodecType: "audio"},
+		{CodecType: "video", Width: 1920, Height: 1080},
+	}}}
+
+	letterbox := models.ExportRequest{Crop: &models.CropRect{Y: 140, Width: 1920, Height: 800}}
+	if err := validateCrop(video, letterbox); err != nil {
+		t.Errorf("letterbox crop: %v", err)
+	}
+
+	// Rotated sideways the picture is 1080 wide
+	video.Rotation = 90
+	if err := validateCrop(video, letterbox); err == nil {
+		t.Error("expected crop wider than the rotated picture to fail")
+	}
+
+	// The request's rotation replaces the video's
+	upright := 0
+	letterbox.Rotation = &upright
+	if err := validateCrop(video, letterbox); err != nil {
+		t.Errorf("crop of the unrotated picture: %v", err)
+	}
+
+	// So does the display matrix of a phone video
+	video.Rotation = 0
+	video.Metadata.Streams[1].Rotation = 270
+	letterbox.Rotation = nil
+	if err := validateCrop(video, letterbox); err == nil {
+		t.Error("expected crop wider than the picture rotated by its display matrix to fail")
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+)
+
+func TestScenesToSegments(t *testing.T) {
+	scenes := []ffmpeg.Scene{
+		{Start: 40, Type: "cut"},
+		{Start: 12.5, Type: "cut"},
+		{Start: 12.5, Type: "cut"}, // Duplicate from "all" mode
+		{Start: 30, End: 31.5, Type: "black"},
+		{Start: 50, End: 52, Type: "silent"},
+	}
+
+	segments := scenesToSegments(scenes, 60)
+
+	want := []struct {
+		name       string
+		start, end float64
+	}{
+		{"Scene 1", 0, 12.5},
+		{"Scene 2", 12.5, 40},
+		{"Scene 3", 40, 60},
+		{"Black 1", 30, 31.5},
+		{"Silence 1", 50, 52},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("got %d segments, want %d: %+v", len(segments), len(want), segments)
+	}
+	for i, w := range want {
+		s := segments[i]
+		if s.Name != w.name || s.Start != w.start || s.End == nil || *s.End != w.end {
+			t.Errorf("segment %d = %s %g-%v, want %s %g-%g", i, s.Name, s.Start, s.End, w.name, w.start, w.end)
+		}
+	}
+}
+
+func TestScenesToSegmentsUnknownDuration(t *testing.T) {
+	segments := scenesToSegments([]ffmpeg.Scene{{Start: 5, Type: "cut"}}, 0)
+
+	if len(segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segments))
+	}
+	if segments[1].Start != 5 || segments[1].End != nil {
+		t.Errorf("last segment = %g-%v, want an open end at 5", segments[1].Start, segments[1].End)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import "sort"
+
+// DefaultDetectionPreset is used when a detection request names no preset
+const DefaultDetectionPreset = "balanced"
+
+// DetectionPreset maps a named sensitivity level to tuned thresholds for each detector
+type DetectionPreset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// Scene change detection
+	SceneThreshold float64 `json:"scene_threshold"`  // Scene score (0.0-1.0), lower = more cuts
+	MinSceneLength float64 `json:"min_scene_length"` // Seconds
+
+	// Black frame detection
+	BlackMinDuration    float64 `json:"black_min_duration"`    // Seconds
+	BlackPixelThreshold float64 `json:"black_pixel_threshold"` // Luminance (0.0-1.0)
+
+	// Silence detection
+	SilenceNoiseDB     float64 `json:"silence_noise_db"`     // dB, e.g. -30
+	SilenceMinDuration float64 `json:"silence_min_duration"` // Seconds
+}
+
+// detectionPresets are the built-in presets, keyed by name
+var detectionPresets = map[string]DetectionPreset{
+	"aggressive": {
+		Name:                "aggressive",
+		Description:         "Finds as many boundaries as possible, including subtle cuts and short pauses",
+		SceneThreshold:      0.2,
+		MinSceneLength:      1.0,
+		BlackMinDuration:    0.1,
+		BlackPixelThreshold: 0.15,
+		SilenceNoiseDB:      -35,
+		SilenceMinDuration:  0.3,
+	},
+	"balanced": {
+		Name:                "balanced",
+		Description:         "Good default for most recordings",
+		SceneThreshold:      0.3,
+		MinSceneLength:      2.0,
+		BlackMinDuration:    0.5,
+		BlackPixelThreshold: 0.10,
+		SilenceNoiseDB:      -30,
+		SilenceMinDuration:  1.0,
+	},
+	"conservative": {
+		Name:                "conservative",
+		Description:         "Only reports obvious hard cuts, long black frames and long silences",
+		SceneThreshold:      0.45,
+		MinSceneLength:      5.0,
+		BlackMinDuration:    1.0,
+		BlackPixelThreshold: 0.08,
+		SilenceNoiseDB:      -45,
+		SilenceMinDuration:  2.0,
+	},
+	"music-video": {
+		Name:                "music-video",
+		Description:         "Fast-paced editing with continuous audio: short scenes, near-digital silence only",
+		SceneThreshold:      0.25,
+		MinSceneLength:      0.5,
+		BlackMinDuration:    0.2,
+		BlackPixelThreshold: 0.10,
+		SilenceNoiseDB:      -50,
+		SilenceMinDuration:  2.0,
+	},
+}
+
+// GetDetectionPreset returns a preset by name. An empty name returns the default preset.
+func GetDetectionPreset(name string) (DetectionPreset, error) {
+	if name == "" {
+		name = DefaultDetectionPreset
+	}
+
+	preset, ok := detectionPresets[name]
+	if !ok {
+		return DetectionPreset{}, invalidf("unknown detection preset: %s", name)
+	}
+
+	return preset, nil
+}
+
+// ListDetectionPresets returns all presets sorted by name
+func ListDetectionPresets() []DetectionPreset {
+	presets := make([]DetectionPreset, 0, len(detectionPresets))
+	for _, preset := range detectionPresets {
+		presets = append(presets, preset)
+	}
+
+	sort.Slice(presets, func(i, j int) bool {
+		return presets[i].Name < presets[j].Name
+	})
+
+	return presets
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxExtraArgs caps the extra yt-dlp arguments of one download
+const maxExtraArgs = 32
+
+// maxExtraArgLength caps the length of one extra yt-dlp argument
+const maxExtraArgLength = 1024
+
+// extraArgOptions are the yt-dlp options a download request may add, and whether
+// they take a value. They tune extraction, format selection and networking for
+// particular sites. Options that run commands, read or write files, or change where
+// and how the output is written aren't allowed.
+var extraArgOptions = map[string]bool{
+	"--extractor-args":        true,
+	"--extractor-retries":     true,
+	"--format-sort":           true,
+	"-S":                      true,
+	"--format-sort-force":     false,
+	"--prefer-free-formats":   false,
+	"--check-formats":         false,
+	"--match-filters":         true,
+	"--age-limit":             true,
+	"--referer":               true,
+	"--geo-bypass":            false,
+	"--geo-bypass-country":    true,
+	"--xff":                   true,
+	"--sleep-requests":        true,
+	"--sleep-interval":        true,
+	"--max-sleep-interval":    true,
+	"--concurrent-fragments":  true,
+	"-N":                      true,
+	"--retries":               true,
+	"-R":                      true,
+	"--fragment-retries":      true,
+	"--socket-timeout":        true,
+	"--http-chunk-size":       true,
+	"--throttled-rate":        true,
+	"--force-ipv4":            false,
+	"-4":                      false,
+	"--force-ipv6":            false,
+	"-6":                      false,
+	"--legacy-server-connect": false,
+	"--live-from-start":       false,
+	"--wait-for-video":        true,
+	"--hls-use-mpegts":        false,
+}
+
+// validateExtraArgs checks the extra yt-dlp arguments of a download request against
+// extraArgOptions. Values follow their option as the next argument or, for long
+// options, after "=".
+func validateExtraArgs(args []string) error {
+	if len(args) > maxExtraArgs {
+		return fmt.Errorf("at most %d extra_args are allowed", maxExtraArgs)
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) > maxExtraArgLength {
+			return fmt.Errorf("extra_args must be at most %d characters each", maxExtraArgLength)
+		}
+		if strings.ContainsAny(arg, "\x00\r\n") {
+			return fmt.Errorf("extra_args must be single lines")
+		}
+
+		name, _, inline := strings.Cut(arg, "=")
+		if !strings.HasPrefix(name, "--") {
+			name, inline = arg, false
+		}
+		takesValue, ok := extraArgOptions[name]
+		if !ok {
+			return fmt.Errorf("yt-dlp option %s is not allowed in extra_args", name)
+		}
+		switch {
+		case takesValue && !inline:
+			if i+1 == len(args) {
+				return fmt.Errorf("yt-dlp option %s needs a value", name)
+			}
+			i++
+			if len(args[i]) > maxExtraArgLength || strings.ContainsAny(args[i], "\x00\r\n") {
+				return fmt.Errorf("invalid value for yt-dlp option %s", name)
+			}
+		case !takesValue && inline:
+			return fmt.Errorf("yt-dlp option %s takes no value", name)
+		}
+	}
+	return nil
+}
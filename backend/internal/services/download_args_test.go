@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestValidateExtraArgs(t *testing.T) {
+	valid := [][]string{
+		nil,
+		{"--extractor-args", "youtube:player_client=android"},
+		{"--format-sort=res:720,codec:h264"},
+		{"-S", "+size", "-N", "4", "--force-ipv4"},
+	}
+	for _, args := range valid {
+		if err := validateExtraArgs(args); err != nil {
+			t.Errorf("validateExtraArgs(%q) = %v", args, err)
+		}
+	}
+
+	invalid := [][]string{
+		{"--exec", "rm -rf /"},
+		{"-o", "/etc/passwd"},
+		{"--config-locations=/tmp/evil.conf"},
+		{"--extractor-args"},
+		{"--force-ipv4=yes"},
+		{"-Sres"},
+		{"--referer", "https://example.com/\n--exec"},
+		{"https://example.com/other-video"},
+	}
+	for _, args := range invalid {
+		if err := validateExtraArgs(args); err == nil {
+			t.Errorf("validateExtraArgs(%q) succeeded, want an error", args)
+		}
+	}
+
+	// A value that looks like a disallowed option is taken as the value
+	if err := validateExtraArgs([]string{"--match-filters", "--exec"}); err != nil {
+		t.Errorf("validateExtraArgs() with an option-like value = %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// downloadCancelGracePeriod is how long a cancelled yt-dlp or curl gets to stop
+// after SIGINT, e.g. to stop the FFmpeg it runs, before it is killed
+const downloadCancelGracePeriod = 5 * time.Second
+
+// interruptOnCancel makes a download command's context cancellation send SIGINT and
+// kill it if it is still running after downloadCancelGracePeriod
+func interruptOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		// Windows has no SIGINT for other processes, kill right away there
+		if err := cmd.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = downloadCancelGracePeriod
+}
+
+// startRun returns the context of a download's run, which CancelDownload cancels.
+// The returned function ends the run.
+func (s *DownloadService) startRun(id string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	s.cancels[id] = cancel
+	s.mu.Unlock()
+
+	return ctx, func() {
+		s.mu.Lock()
+		delete(s.cancels, id)
+		s.releaseDownloadNames(id)
+		s.mu.Unlock()
+		cancel()
+	}
+}
+
+// stopRun cancels the context of a running download, stopping its process or request
+func (s *DownloadService) stopRun(id string) {
+	s.mu.Lock()
+	cancel, running := s.cancels[id]
+	s.mu.Unlock()
+
+	if running {
+		cancel()
+	}
+}
+
+// removeYtdlpFiles removes what yt-dlp wrote for the download named name: .part and
+// .ytdl files, downloaded formats waiting to be merged and subtitles
+func removeYtdlpFiles(dir, name string) {
+	files, _ := filepath.Glob(filepath.Join(dir, name+".*"))
+	for _, path := range files {
+		os.Remove(path)
+	}
+}
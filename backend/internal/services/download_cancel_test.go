@@ -0,0 +1,30 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveYtdlpFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"video1.mp4.part", "video1.f137.mp4.part", "video1.mp4.ytdl", "video1.en.vtt", "video10.mp4", "video2.mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removeYtdlpFiles(dir, "video1")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, entry := range entries {
+		left = append(left, entry.Name())
+	}
+	if len(left) != 2 || left[0] != "video10.mp4" || left[1] != "video2.mp4" {
+		t.Errorf("files left = %v, want [video10.mp4 video2.mp4]", left)
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// VideoInfoChapter is a chapter reported by yt-dlp, e.g. from a YouTube description
+type VideoInfoChapter struct {
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Title     string  `json:"title"`
+}
+
+// infoChapters converts yt-dlp chapters to video metadata chapters, in milliseconds
+func infoChapters(chapters []VideoInfoChapter) []models.Chapter {
+	converted := make([]models.Chapter, 0, len(chapters))
+	for i, chapter := range chapters {
+		if chapter.EndTime <= chapter.StartTime {
+			continue
+		}
+		converted = append(converted, models.Chapter{
+			ID:        i,
+			TimeBase:  "1/1000",
+			Start:     int64(chapter.StartTime * 1000),
+			End:       int64(chapter.EndTime * 1000),
+			StartTime: chapter.StartTime,
+			EndTime:   chapter.EndTime,
+			Title:     chapter.Title,
+		})
+	}
+	return converted
+}
+
+// chapterSegments returns one segment per chapter, named after it
+func chapterSegments(chapters []models.Chapter) []models.Segment {
+	segments := make([]models.Segment, 0, len(chapters))
+	for i, chapter := range chapters {
+		name := chapter.Title
+		if name == "" {
+			name = fmt.Sprintf("Chapter %d", i+1)
+		}
+		end := chapter.EndTime
+		segments = append(segments, models.Segment{
+			ID:    uuid.New().String(),
+			Name:  name,
+			Start: chapter.StartTime,
+			End:   &end,
+		})
+	}
+	return segments
+}
+
+// importChapters stores the chapters yt-dlp reported in a downloaded video's
+// metadata, unless the file has chapters of its own, and creates a project with one
+// segment per chapter when the download asked for it
+func (s *DownloadService) importChapters(download *models.Download, video *models.Video, info *VideoInfo) {
+	if len(video.Metadata.Chapters) == 0 {
+		video.Metadata.Chapters = infoChapters(info.Chapters)
+	}
+	if len(video.Metadata.Chapters) == 0 {
+		return
+	}
+	if err := s.storage.SaveVideo(video); err != nil {
+		s.logger.Warn("Failed to save video chapters", zap.String("videoId", video.ID), zap.Error(err))
+		return
+	}
+
+	if !download.ChapterProject {
+		return
+	}
+	project, err := s.projectService.Create(fmt.Sprintf("%s (chapters)", download.Title), video.ID)
+	if err != nil {
+		s.logger.Warn("Failed to create chapter project", zap.String("id", download.ID), zap.Error(err))
+		return
+	}
+	project.Segments = chapterSegments(video.Metadata.Chapters)
+	if err := s.projectService.Save(project); err != nil {
+		s.logger.Warn("Failed to save chapter project", zap.String("id", download.ID), zap.Error(err))
+		return
+	}
+	download.ProjectIDs = append(download.ProjectIDs, project.ID)
+
+	s.logger.Info("Created chapter project",
+		zap.String("id", download.ID),
+		zap.String("projectId", project.ID),
+		zap.Int("chapters", len(project.Segments)),
+	)
+}
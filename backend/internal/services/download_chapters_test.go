@@ -0,0 +1,28 @@
+package services
+
+import "testing"
+
+func TestInfoChapters(t *testing.T) {
+	chapters := infoChapters([]VideoInfoChapter{
+		{StartTime: 0, EndTime: 62.5, Title: "Intro"},
+		{StartTime: 62.5, EndTime: 62.5, Title: "Empty"},
+		{StartTime: 62.5, EndTime: 300},
+	})
+	if len(chapters) != 2 {
+		t.Fatalf("got %d chapters, want 2: %+v", len(chapters), chapters)
+	}
+	if chapters[0].End != 62500 || chapters[0].TimeBase != "1/1000" || chapters[0].Title != "Intro" {
+		t.Errorf("chapter 0 = %+v", chapters[0])
+	}
+
+	segments := chapterSegments(chapters)
+	if len(segments) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segments))
+	}
+	if segments[0].Name != "Intro" || segments[0].Start != 0 || *segments[0].End != 62.5 {
+		t.Errorf("segment 0 = %+v", segments[0])
+	}
+	if segments[1].Name != "Chapter 2" || segments[1].Start != 62.5 || *segments[1].End != 300 {
+		t.Errorf("segment 1 = %+v", segments[1])
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// defaultUserAgent is sent by direct HTTP downloads without a configured user agent,
+// some CDNs reject Go's default one
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// validateDownloadClient checks the client settings of a download request
+func validateDownloadClient(client models.DownloadClient) error {
+	if client.Impersonate != "" {
+		if err := config.ValidateImpersonate(client.Impersonate); err != nil {
+			return err
+		}
+	}
+	if strings.ContainsAny(client.UserAgent, "\r\n") {
+		return fmt.Errorf("user_agent must be a single line")
+	}
+	for name, value := range client.Headers {
+		if err := config.ValidateHeader(name, value); err != nil {
+			return err
+		}
+	}
+	if client.CookiesFromBrowser != "" {
+		if err := config.ValidateCookiesFromBrowser(client.CookiesFromBrowser); err != nil {
+			return err
+		}
+	}
+	return validateExtraArgs(client.ExtraArgs)
+}
+
+// downloadClient returns the client settings of a download: the configured defaults,
+// overridden by those of the request. Request headers are added to the configured
+// ones and replace those with the same name. Extra arguments only come from the request.
+func downloadClient(cfg config.DownloadConfig, req models.DownloadClient) models.DownloadClient {
+	client := models.DownloadClient{
+		Impersonate: cfg.Impersonate,
+		UserAgent:   cfg.UserAgent,
+		Headers:     config.ParseHeaders(cfg.Headers),
+
+		CookiesFromBrowser: cfg.CookiesFromBrowser,
+		CookiesFile:        cfg.CookiesFile,
+
+		ExtraArgs: req.ExtraArgs,
+	}
+	if req.CookiesFromBrowser != "" {
+		client.CookiesFromBrowser = req.CookiesFromBrowser
+	}
+	if req.CookiesFile != "" {
+		client.CookiesFile = req.CookiesFile
+	}
+	if req.Impersonate != "" {
+		client.Impersonate = req.Impersonate
+	}
+	if req.UserAgent != "" {
+		client.UserAgent = req.UserAgent
+	}
+	for name, value := range req.Headers {
+		for existing := range client.Headers {
+			if strings.EqualFold(existing, name) {
+				delete(client.Headers, existing)
+			}
+		}
+		if client.Headers == nil {
+			client.Headers = make(map[string]string)
+		}
+		client.Headers[name] = value
+	}
+	return client
+}
+
+// ytdlpClientArgs returns the yt-dlp arguments applying client settings
+func ytdlpClientArgs(client models.DownloadClient) []string {
+	var args []string
+	if client.Impersonate != "" {
+		args = append(args, "--impersonate", client.Impersonate)
+	}
+	if client.UserAgent != "" {
+		args = append(args, "--user-agent", client.UserAgent)
+	}
+	for _, name := range sortedHeaderNames(client.Headers) {
+		args = append(args, "--add-header", name+":"+client.Headers[name])
+	}
+	if client.CookiesFile != "" {
+		args = append(args, "--cookies", client.CookiesFile)
+	}
+	if client.CookiesFromBrowser != "" {
+		args = append(args, "--cookies-from-browser", client.CookiesFromBrowser)
+	}
+	return append(args, client.ExtraArgs...)
+}
+
+// applyDownloadClient sets the headers of a direct HTTP download request.
+// Impersonation needs yt-dlp's TLS fingerprinting and doesn't apply here.
+func applyDownloadClient(httpReq *http.Request, client models.DownloadClient) {
+	userAgent := client.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	httpReq.Header.Set("User-Agent", userAgent)
+	for _, name := range sortedHeaderNames(client.Headers) {
+		httpReq.Header.Set(name, client.Headers[name])
+	}
+}
+
+func sortedHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestDownloadClient(t *testing.T) {
+	cfg := config.DownloadConfig{
+		Impersonate: "chrome",
+		UserAgent:   "Configured/1.0",
+		Headers:     []string{"Referer: https://example.com/", "X-Token: abc"},
+	}
+
+	client := downloadClient(cfg, models.DownloadClient{
+		Impersonate: "safari-17.0:macos-14",
+		Headers:     map[string]string{"x-token": "override"},
+	})
+	want := models.DownloadClient{
+		Impersonate: "safari-17.0:macos-14",
+		UserAgent:   "Configured/1.0",
+		Headers:     map[string]string{"Referer": "https://example.com/", "x-token": "override"},
+	}
+	if !reflect.DeepEqual(client, want) {
+		t.Fatalf("downloadClient() = %+v, want %+v", client, want)
+	}
+
+	args := ytdlpClientArgs(client)
+	wantArgs := []string{
+		"--impersonate", "safari-17.0:macos-14",
+		"--user-agent", "Configured/1.0",
+		"--add-header", "Referer:https://example.com/",
+		"--add-header", "x-token:override",
+	}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("ytdlpClientArgs() = %q, want %q", args, wantArgs)
+	}
+
+	// The running download's cookies file and the configured browser are both passed
+	cookies := downloadClient(config.DownloadConfig{CookiesFromBrowser: "firefox"}, models.DownloadClient{CookiesFile: "/tmp/run-1.txt"})
+	wantArgs = []string{"--cookies", "/tmp/run-1.txt", "--cookies-from-browser", "firefox"}
+	if args := ytdlpClientArgs(cookies); !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("ytdlpClientArgs() with cookies = %q, want %q", args, wantArgs)
+	}
+
+	// Direct downloads fall back to a browser user agent
+	httpReq, _ := http.NewRequest("GET", "https://example.com/video.mp4", nil)
+	applyDownloadClient(httpReq, models.DownloadClient{})
+	if httpReq.Header.Get("User-Agent") != defaultUserAgent {
+		t.Errorf("User-Agent = %q, want the default", httpReq.Header.Get("User-Agent"))
+	}
+}
+
+func TestValidateDownloadClient(t *testing.T) {
+	valid := models.DownloadClient{Impersonate: "chrome-124", UserAgent: "Agent/1.0", Headers: map[string]string{"Accept-Language": "de"}}
+	if err := validateDownloadClient(valid); err != nil {
+		t.Errorf("validateDownloadClient(%+v) = %v", valid, err)
+	}
+
+	for _, client := range []models.DownloadClient{
+		{Impersonate: "Chrome 124"},
+		{UserAgent: "Agent\r\nX-Injected: 1"},
+		{Headers: map[string]string{"Bad Header": "x"}},
+		{CookiesFromBrowser: "netscape"},
+		{Headers: map[string]string{"X-Ok": "a\nb"}},
+	} {
+		if err := validateDownloadClient(client); err == nil {
+			t.Errorf("validateDownloadClient(%+v): want error", client)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// maxPosterSize caps the thumbnail downloaded with a video
+const maxPosterSize = 10 << 20
+
+// posterExtensions are the image types of site thumbnails, by content type
+var posterExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// videoSource returns the site metadata of a downloaded video, nil when yt-dlp
+// reported none
+func videoSource(info *VideoInfo) *models.VideoSource {
+	source := &models.VideoSource{
+		WebpageURL:  info.WebpageURL,
+		Uploader:    info.Uploader,
+		Description: info.Description,
+		Thumbnail:   info.Thumbnail,
+	}
+	if date, err := time.Parse("20060102", info.UploadDate); err == nil {
+		source.UploadDate = date.Format("2006-01-02")
+	}
+	if *source == (models.VideoSource{}) {
+		return nil
+	}
+	return source
+}
+
+// importSourceMetadata stores the site metadata of a downloaded video with it and
+// downloads its thumbnail. Failures are logged, the video itself was downloaded fine.
+func (s *DownloadService) importSourceMetadata(ctx context.Context, video *models.Video, info *VideoInfo) {
+	video.Source = videoSource(info)
+	if video.Source == nil {
+		return
+	}
+
+	if info.Thumbnail != "" {
+		path, err := s.downloadPoster(ctx, video.ID, info.Thumbnail)
+		if err != nil {
+			s.logger.Warn("Failed to download thumbnail", zap.String("videoId", video.ID), zap.Error(err))
+		} else {
+			video.Source.ThumbnailPath = path
+		}
+	}
+
+	if err := s.storage.SaveVideo(video); err != nil {
+		s.logger.Warn("Failed to save video metadata", zap.String("videoId", video.ID), zap.Error(err))
+	}
+}
+
+// downloadPoster downloads a video's thumbnail into the posters directory
+func (s *DownloadService) downloadPoster(ctx context.Context, videoID, thumbnailURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, thumbnailURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("thumbnail returned HTTP %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	ext, ok := posterExtensions[mediaType]
+	if !ok {
+		return "", fmt.Errorf("thumbnail is %q, not an image", resp.Header.Get("Content-Type"))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPosterSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxPosterSize {
+		return "", fmt.Errorf("thumbnail exceeds %d bytes", maxPosterSize)
+	}
+
+	path := filepath.Join(s.storage.PostersDir(), videoID+ext)
+	if err := os.MkdirAll(s.storage.PostersDir(), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
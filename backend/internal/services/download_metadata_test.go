@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestVideoSource(t *testing.T) {
+	info := &VideoInfo{
+		Title:       "Talk",
+		Uploader:    "Conference",
+		UploadDate:  "20240131",
+		Description: "Recorded live",
+		Thumbnail:   "https://i.ytimg.com/vi/abc/maxresdefault.webp",
+		WebpageURL:  "https://www.youtube.com/watch?v=abc",
+	}
+	want := models.VideoSource{
+		WebpageURL:  "https://www.youtube.com/watch?v=abc",
+		Uploader:    "Conference",
+		UploadDate:  "2024-01-31",
+		Description: "Recorded live",
+		Thumbnail:   "https://i.ytimg.com/vi/abc/maxresdefault.webp",
+	}
+	if got := videoSource(info); got == nil || *got != want {
+		t.Errorf("videoSource() = %+v, want %+v", got, want)
+	}
+
+	// Sites reporting nothing beyond a title leave the video without a source
+	if got := videoSource(&VideoInfo{Title: "clip", UploadDate: "unknown"}); got != nil {
+		t.Errorf("videoSource() without metadata = %+v, want nil", got)
+	}
+}
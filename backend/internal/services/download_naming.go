@@ -0,0 +1,129 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// Download naming schemes (storage.download_naming)
+const (
+	DownloadNamingSequence = "sequence" // video1.mp4, video2.mp4, ...
+	DownloadNamingSlug     = "slug"     // my-video-title_2024-01-31.mp4
+)
+
+// maxSlugLength keeps slug-based names readable and well below filesystem limits
+const maxSlugLength = 60
+
+// sessionScope returns the sequence scope and file name tag of a session. Session IDs
+// are client-chosen, so they are hashed into a short safe tag.
+func sessionScope(sessionID string) string {
+	if sessionID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:3])
+}
+
+// slugify turns a title into a lowercase file name component of letters, digits and
+// dashes. It returns "" when nothing usable is left.
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+
+	slug := []rune(b.String())
+	if len(slug) > maxSlugLength {
+		slug = []rune(strings.TrimRight(string(slug[:maxSlugLength]), "-"))
+	}
+	return string(slug)
+}
+
+// downloadBaseName returns the file name, without extension, of a download.
+// Sequence names are numbered per session and tagged with it, so concurrent users
+// don't share a counter; slug names use the title and date.
+func downloadBaseName(scheme, sessionID, title string, nextNumber func(scope string) int, date time.Time) string {
+	if scheme == DownloadNamingSlug {
+		if slug := slugify(title); slug != "" {
+			return slug + "_" + date.Format("2006-01-02")
+		}
+	}
+
+	scope := sessionScope(sessionID)
+	name := fmt.Sprintf("video%d", nextNumber(scope))
+	if scope != "" {
+		name += "_" + scope
+	}
+	return name
+}
+
+// downloadNameTaken reports whether a file in dir uses name: a video named
+// name.ext, or the partial files of one being downloaded. Sidecars such as
+// name.info.json or name.en.vtt don't keep a name.
+func downloadNameTaken(dir, name string) bool {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		file := entry.Name()
+		if !strings.HasPrefix(file, name+".") {
+			continue
+		}
+		if ext := filepath.Ext(file); ext == ".part" || ext == ".ytdl" || file == name+ext {
+			return true
+		}
+	}
+	return false
+}
+
+// reserveDownloadName returns base, or base with a "-N" suffix, such that no file in
+// dir and no other running download uses it. The name stays reserved for the
+// download's run, releaseDownloadNames frees it once its file is there.
+func (s *DownloadService) reserveDownloadName(dir, base, downloadID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reservedNames == nil {
+		s.reservedNames = make(map[string]string)
+	}
+
+	name := base
+	for i := 2; ; i++ {
+		if _, reserved := s.reservedNames[name]; !reserved && !downloadNameTaken(dir, name) {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	s.reservedNames[name] = downloadID
+	return name
+}
+
+// releaseDownloadNames frees the names reserved by a download, called with s.mu held
+func (s *DownloadService) releaseDownloadNames(downloadID string) {
+	for name, id := range s.reservedNames {
+		if id == downloadID {
+			delete(s.reservedNames, name)
+		}
+	}
+}
+
+// downloadName picks a unique file name, without extension, for a download
+func (s *DownloadService) downloadName(dir string, download *models.Download, req DownloadRequest, title string) string {
+	base := downloadBaseName(s.config.Storage.DownloadNaming, req.SessionID, title, s.storage.NextSequence, time.Now())
+	return s.reserveDownloadName(dir, base, download.ID)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"My Video: Part 2 (HD)!": "my-video-part-2-hd",
+		"  Ünïcode — Título  ":   "ünïcode-título",
+		"../../etc/passwd":       "etc-passwd",
+		"???":                    "",
+	}
+	for title, want := range tests {
+		if got := slugify(title); got != want {
+			t.Errorf("slugify(%q) = %q, want %q", title, got, want)
+		}
+	}
+
+	long := slugify("a very long title that keeps going and going well past the limit of sixty runes")
+	if len([]rune(long)) > maxSlugLength || long[len(long)-1] == '-' {
+		t.Errorf("slugify() of a long title = %q", long)
+	}
+}
+
+func TestDownloadBaseName(t *testing.T) {
+	counters := map[string]int{}
+	next := func(scope string) int {
+		counters[scope]++
+		return counters[scope]
+	}
+	date := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	if got := downloadBaseName(DownloadNamingSequence, "", "Title", next, date); got != "video1" {
+		t.Errorf("global sequence = %q, want video1", got)
+	}
+
+	// Sessions count independently and are tagged, so their names don't collide
+	a := downloadBaseName(DownloadNamingSequence, "sess_a", "Title", next, date)
+	b := downloadBaseName(DownloadNamingSequence, "sess_b", "Title", next, date)
+	if a == b || a != "video1_"+sessionScope("sess_a") {
+		t.Errorf("session names = %q, %q", a, b)
+	}
+
+	if got := downloadBaseName(DownloadNamingSlug, "sess_a", "My Clip", next, date); got != "my-clip_2024-01-31" {
+		t.Errorf("slug name = %q", got)
+	}
+	if got := downloadBaseName(DownloadNamingSlug, "", "!!!", next, date); got != "video2" {
+		t.Errorf("slug name without usable title = %q, want sequence fallback video2", got)
+	}
+}
+
+func TestReserveDownloadName(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"clip.mp4", "partial.f137.mp4.part", "gone.info.json", "gone.en.vtt"} {
+		if err := os.WriteFile(filepath.Join(dir, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &DownloadService{}
+	if got := s.reserveDownloadName(dir, "clip", "d1"); got != "clip-2" {
+		t.Errorf("name taken by a file: got %q, want clip-2", got)
+	}
+	if got := s.reserveDownloadName(dir, "clip", "d2"); got != "clip-3" {
+		t.Errorf("name reserved by a running download: got %q, want clip-3", got)
+	}
+	if got := s.reserveDownloadName(dir, "partial", "d3"); got != "partial-2" {
+		t.Errorf("name taken by a partial download: got %q, want partial-2", got)
+	}
+	if got := s.reserveDownloadName(dir, "gone", "d4"); got != "gone" {
+		t.Errorf("name with sidecars only: got %q, want gone", got)
+	}
+
+	// Names are free again once their download's run ended
+	s.releaseDownloadNames("d2")
+	if got := s.reserveDownloadName(dir, "clip", "d5"); got != "clip-3" {
+		t.Errorf("released name: got %q, want clip-3", got)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ytdlpProgressRegex matches yt-dlp progress lines such as
+// "[download]  45.2% of ~123.45MiB at  1.23MiB/s ETA 00:12 (frag 3/10)"
+var ytdlpProgressRegex = regexp.MustCompile(`\[download\]\s+(\d+\.?\d*)%(?:\s+of\s+~?\s*(\S+))?(?:\s+at\s+(\S+?)/s)?(?:\s+ETA\s+(\S+))?`)
+
+// ytdlpProgress is one progress update of yt-dlp
+type ytdlpProgress struct {
+	Percent    float64
+	TotalBytes int64   // 0 when unknown
+	Speed      float64 // Bytes per second, 0 when unknown
+	ETA        float64 // Seconds, 0 when unknown
+}
+
+// parseYtdlpProgress parses a yt-dlp progress line
+func parseYtdlpProgress(line string) (ytdlpProgress, bool) {
+	matches := ytdlpProgressRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return ytdlpProgress{}, false
+	}
+	percent, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return ytdlpProgress{}, false
+	}
+
+	progress := ytdlpProgress{Percent: percent}
+	if size, ok := parseByteSize(matches[2]); ok {
+		progress.TotalBytes = int64(size)
+	}
+	if speed, ok := parseByteSize(matches[3]); ok {
+		progress.Speed = speed
+	}
+	if eta, ok := parseETA(matches[4]); ok {
+		progress.ETA = eta
+	}
+	return progress, true
+}
+
+// sizeUnits are the multipliers of the units yt-dlp prints sizes in
+var sizeUnits = map[string]float64{
+	"B": 1, "KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40,
+	"KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12,
+}
+
+// parseByteSize parses a size like "123.45MiB" into bytes
+func parseByteSize(value string) (float64, bool) {
+	i := strings.IndexFunc(value, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i <= 0 {
+		return 0, false
+	}
+	multiplier, ok := sizeUnits[value[i:]]
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil {
+		return 0, false
+	}
+	return number * multiplier, true
+}
+
+// parseETA parses a duration like "00:12" or "01:02:03" into seconds
+func parseETA(value string) (float64, bool) {
+	if value == "" {
+		return 0, false
+	}
+	seconds := 0.0
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, false
+		}
+		seconds = seconds*60 + float64(n)
+	}
+	return seconds, true
+}
+
+// speedMeter smooths the transfer rate of a download over its progress updates
+type speedMeter struct {
+	speed float64 // Bytes per second
+}
+
+// speedSmoothing is the weight of the latest interval in the smoothed rate
+const speedSmoothing = 0.3
+
+// update adds the bytes transferred in elapsed and returns the smoothed rate
+func (m *speedMeter) update(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return m.speed
+	}
+	rate := float64(bytes) / elapsed.Seconds()
+	if m.speed == 0 {
+		m.speed = rate
+	} else {
+		m.speed = speedSmoothing*rate + (1-speedSmoothing)*m.speed
+	}
+	return m.speed
+}
+
+// downloadETA returns the seconds left to transfer remaining bytes at speed, 0 when
+// unknown
+func downloadETA(remaining int64, speed float64) float64 {
+	if remaining <= 0 || speed <= 0 {
+		return 0
+	}
+	return math.Round(float64(remaining) / speed)
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseYtdlpProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		want ytdlpProgress
+		ok   bool
+	}{
+		{"[download]  45.2% of 100.00MiB at  2.00MiB/s ETA 00:27", ytdlpProgress{Percent: 45.2, TotalBytes: 100 << 20, Speed: 2 << 20, ETA: 27}, true},
+		{"[download]  10.0% of ~  1.50GiB at 512.00KiB/s ETA 01:02:03 (frag 3/40)", ytdlpProgress{Percent: 10, TotalBytes: 1.5 * (1 << 30), Speed: 512 << 10, ETA: 3723}, true},
+		{"[download]   0.5% of 10.00MiB at Unknown B/s ETA Unknown", ytdlpProgress{Percent: 0.5, TotalBytes: 10 << 20}, true},
+		{"[download] 100% of 10.00MiB in 00:00:05 at 2.00MiB/s", ytdlpProgress{Percent: 100, TotalBytes: 10 << 20}, true},
+		{"[download] Destination: video1.mp4", ytdlpProgress{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseYtdlpProgress(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseYtdlpProgress(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSpeedMeter(t *testing.T) {
+	var meter speedMeter
+	if got := meter.update(1000, time.Second); got != 1000 {
+		t.Errorf("first update = %v, want 1000", got)
+	}
+	// The rate moves towards the latest interval's
+	if got := meter.update(2000, time.Second); got != 1300 {
+		t.Errorf("second update = %v, want 1300", got)
+	}
+	if got := downloadETA(2600, 1300); got != 2 {
+		t.Errorf("downloadETA() = %v, want 2", got)
+	}
+	if got := downloadETA(2600, 0); got != 0 {
+		t.Errorf("downloadETA() without a speed = %v, want 0", got)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// defaultProjectName names the project created for a downloaded video
+const defaultProjectName = "{title}"
+
+const maxProjectNameLength = 200
+
+// validateProjectName checks the project name template of a download
+func validateProjectName(template string) error {
+	if utf8.RuneCountInString(template) > maxProjectNameLength {
+		return fmt.Errorf("project_name must be at most %d characters", maxProjectNameLength)
+	}
+	if strings.ContainsAny(template, "\x00\r\n") {
+		return fmt.Errorf("project_name must be a single line")
+	}
+	return nil
+}
+
+// projectName renders the name of the project created for a downloaded video.
+// Supported placeholders: {title}, {uploader}, {upload_date}, {date}.
+func projectName(template string, title string, video *models.Video, date time.Time) string {
+	if template == "" {
+		template = defaultProjectName
+	}
+	if title == "" {
+		title = strings.TrimSuffix(video.FileName, filepath.Ext(video.FileName))
+	}
+	var uploader, uploadDate string
+	if video.Source != nil {
+		uploader = video.Source.Uploader
+		uploadDate = video.Source.UploadDate
+	}
+
+	name := strings.NewReplacer(
+		"{title}", title,
+		"{uploader}", uploader,
+		"{upload_date}", uploadDate,
+		"{date}", date.Format("2006-01-02"),
+	).Replace(template)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return title
+	}
+	return name
+}
+
+// importDownload creates a video from a downloaded file, which stays where it was
+// downloaded. The file is written to the media store first, so instances that
+// don't have it can fetch it like an upload.
+func (s *DownloadService) importDownload(filename, path string) (*models.Video, error) {
+	if err := s.storage.StoreFile(path); err != nil {
+		return nil, err
+	}
+	return s.videoService.CreateFromUpload(filename, path)
+}
+
+// createDownloadProject creates an empty project for a downloaded video when the
+// download asked for one, so it can be cut right away
+func (s *DownloadService) createDownloadProject(download *models.Download, video *models.Video, title string) {
+	if !download.CreateProject {
+		return
+	}
+	project, err := s.projectService.Create(projectName(download.ProjectName, title, video, time.Now()), video.ID)
+	if err != nil {
+		s.logger.Warn("Failed to create download project", zap.String("id", download.ID), zap.Error(err))
+		return
+	}
+	download.ProjectIDs = append(download.ProjectIDs, project.ID)
+
+	s.logger.Info("Created download project",
+		zap.String("id", download.ID),
+		zap.String("projectId", project.ID),
+		zap.String("videoId", video.ID),
+	)
+}
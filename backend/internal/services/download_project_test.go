@@ -0,0 +1,50 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestProjectName(t *testing.T) {
+	date := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	video := &models.Video{
+		FileName: "talk.mp4",
+		Source:   &models.VideoSource{Uploader: "Conference", UploadDate: "2024-01-31"},
+	}
+
+	tests := []struct {
+		template string
+		title    string
+		video    *models.Video
+		want     string
+	}{
+		{"", "Keynote", video, "Keynote"},
+		{"{uploader} - {title} ({upload_date})", "Keynote", video, "Conference - Keynote (2024-01-31)"},
+		{"Cut {date}", "Keynote", video, "Cut 2024-03-05"},
+		// Direct downloads have no title or source, the file name stands in
+		{"{title}", "", &models.Video{FileName: "clip.webm"}, "clip"},
+		// A template rendering to nothing falls back to the title
+		{"{uploader}", "clip", &models.Video{FileName: "clip.webm"}, "clip"},
+	}
+	for _, tt := range tests {
+		if got := projectName(tt.template, tt.title, tt.video, date); got != tt.want {
+			t.Errorf("projectName(%q, %q) = %q, want %q", tt.template, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestValidateProjectName(t *testing.T) {
+	for _, name := range []string{"", "{title}", "{uploader} - {title}"} {
+		if err := validateProjectName(name); err != nil {
+			t.Errorf("validateProjectName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"a\nb", strings.Repeat("x", maxProjectNameLength+1)} {
+		if err := validateProjectName(name); err == nil {
+			t.Errorf("validateProjectName(%q) accepted", name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultYtdlpFormat prefers MP4 video and M4A audio, which every browser plays
+const defaultYtdlpFormat = "bestvideo[ext=mp4]+bestaudio[ext=m4a]/best[ext=mp4]/best"
+
+// formatSelectorPattern matches a single yt-dlp format selector with its filters,
+// e.g. "bv*[ext=mp4]" or "137"
+var formatSelectorPattern = regexp.MustCompile(`^[A-Za-z0-9_.*=-]+(\[[^\[\]]+\])*$`)
+
+// formatMinHeightPattern finds filters asking for at least a height, e.g. "[height>=2160]"
+var formatMinHeightPattern = regexp.MustCompile(`\b(?:height|res)\s*(>=|>|=)\s*\??\s*(\d+)`)
+
+// maxQualityHeight returns the height ytdlp.max_quality caps downloads at, 0 for none
+func maxQualityHeight(quality string) int {
+	height, err := strconv.Atoi(strings.TrimSuffix(quality, "p"))
+	if err != nil || quality == "best" {
+		return 0
+	}
+	return height
+}
+
+// ytdlpFormat returns the yt-dlp format selection of a download, with every selector
+// limited to maxHeight when it is set. Formats without a known height, such as
+// audio-only ones, stay eligible. Grouping and comma-separated selections can't be
+// capped reliably and are rejected under a cap.
+func ytdlpFormat(format string, maxHeight int) (string, error) {
+	if format == "" {
+		format = defaultYtdlpFormat
+	}
+	if maxHeight <= 0 {
+		return format, nil
+	}
+
+	for _, match := range formatMinHeightPattern.FindAllStringSubmatch(format, -1) {
+		height, _ := strconv.Atoi(match[2])
+		if height > maxHeight || (match[1] == ">" && height >= maxHeight) {
+			return "", fmt.Errorf("format %q asks for more than the %dp this server allows", format, maxHeight)
+		}
+	}
+
+	filter := fmt.Sprintf("[height<=?%d]", maxHeight)
+	var capped strings.Builder
+	start, depth := 0, 0
+	for i, r := range format + "/" {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth > 0:
+		case r == '/' || r == '+':
+			selector := strings.TrimSpace(format[start:i])
+			if !formatSelectorPattern.MatchString(selector) {
+				return "", fmt.Errorf("format %q can't be limited to %dp, use only / and + between selectors", format, maxHeight)
+			}
+			capped.WriteString(selector + filter)
+			if i < len(format) {
+				capped.WriteRune(r)
+			}
+			start = i + 1
+		}
+	}
+	if depth != 0 {
+		return "", fmt.Errorf("format %q has unbalanced brackets", format)
+	}
+	return capped.String(), nil
+}
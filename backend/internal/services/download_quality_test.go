@@ -0,0 +1,47 @@
+package services
+
+import "testing"
+
+func TestMaxQualityHeight(t *testing.T) {
+	for quality, want := range map[string]int{"1080p": 1080, "720p": 720, "best": 0, "": 0} {
+		if got := maxQualityHeight(quality); got != want {
+			t.Errorf("maxQualityHeight(%q) = %d, want %d", quality, got, want)
+		}
+	}
+}
+
+func TestYtdlpFormat(t *testing.T) {
+	tests := []struct {
+		format    string
+		maxHeight int
+		want      string
+	}{
+		{"", 0, defaultYtdlpFormat},
+		{"bv*+ba", 0, "bv*+ba"},
+		{"", 1080, "bestvideo[ext=mp4][height<=?1080]+bestaudio[ext=m4a][height<=?1080]/best[ext=mp4][height<=?1080]/best[height<=?1080]"},
+		{"137+140", 720, "137[height<=?720]+140[height<=?720]"},
+		{"bv*[height<=2160]+ba/b", 1080, "bv*[height<=2160][height<=?1080]+ba[height<=?1080]/b[height<=?1080]"},
+		{"best[height>=720]", 1080, "best[height>=720][height<=?1080]"},
+		// Slashes inside a filter don't separate selectors
+		{"b[url*=https://]", 480, "b[url*=https://][height<=?480]"},
+	}
+	for _, tt := range tests {
+		got, err := ytdlpFormat(tt.format, tt.maxHeight)
+		if err != nil || got != tt.want {
+			t.Errorf("ytdlpFormat(%q, %d) = %q, %v; want %q", tt.format, tt.maxHeight, got, err, tt.want)
+		}
+	}
+
+	for _, format := range []string{
+		"best[height>=2160]",
+		"bv[height=1440]",
+		"bv[height>1080]",
+		"(bv+ba)/b",
+		"bv,ba",
+		"b[ext=mp4",
+	} {
+		if got, err := ytdlpFormat(format, 1080); err == nil {
+			t.Errorf("ytdlpFormat(%q, 1080) = %q, want error", format, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// queuedDownload is a download waiting for a free download slot
+type queuedDownload struct {
+	id       string
+	req      DownloadRequest
+	priority int
+}
+
+// downloadQueue orders waiting downloads by priority, highest first, and by arrival
+// within the same priority
+type downloadQueue struct {
+	items []queuedDownload
+}
+
+// push adds a download behind the queued downloads of the same or a higher priority
+func (q *downloadQueue) push(item queuedDownload) {
+	i := len(q.items)
+	for j, queued := range q.items {
+		if queued.priority < item.priority {
+			i = j
+			break
+		}
+	}
+	q.items = append(q.items, queuedDownload{})
+	copy(q.items[i+1:], q.items[i:])
+	q.items[i] = item
+}
+
+// pop takes the next download off the queue
+func (q *downloadQueue) pop() (queuedDownload, bool) {
+	if len(q.items) == 0 {
+		return queuedDownload{}, false
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	return item, true
+}
+
+// remove takes a download off the queue, reporting whether it was queued
+func (q *downloadQueue) remove(id string) bool {
+	for i, item := range q.items {
+		if item.id == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue queues a download and starts it right away when a slot is free
+func (s *DownloadService) enqueue(download *models.Download, req DownloadRequest) {
+	s.mu.Lock()
+	download.Status = models.DownloadStatusQueued
+	s.downloads[download.ID] = download
+	s.queue.push(queuedDownload{id: download.ID, req: req, priority: download.Priority})
+	s.mu.Unlock()
+
+	s.dispatch()
+}
+
+// dispatch starts queued downloads while fewer than download.max_concurrent run and
+// publishes the queue positions of the ones still waiting
+func (s *DownloadService) dispatch() {
+	max := s.config.Download.MaxConcurrent
+
+	s.mu.Lock()
+	var start []queuedDownload
+	for max <= 0 || s.running < max {
+		item, ok := s.queue.pop()
+		if !ok {
+			break
+		}
+		s.running++
+		start = append(start, item)
+	}
+	waiting := make([]*models.Download, 0, len(s.queue.items))
+	for i, item := range s.queue.items {
+		download := s.downloads[item.id]
+		download.QueuePosition = i + 1
+		waiting = append(waiting, download)
+	}
+	s.mu.Unlock()
+
+	for _, download := range waiting {
+		s.storage.UpdateDownload(download)
+	}
+	for _, item := range start {
+		go s.runQueued(item)
+	}
+}
+
+// runQueued runs a download that got a slot and hands the slot on when it ends. A
+// download failing for a transient reason is queued again after a backoff; its
+// cookies are kept until it has no attempt left.
+func (s *DownloadService) runQueued(item queuedDownload) {
+	defer func() {
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
+		s.dispatch()
+	}()
+
+	s.mu.Lock()
+	download := s.downloads[item.id]
+	s.mu.Unlock()
+
+	s.runDownload(item.id, item.req)
+
+	if delay, ok := s.scheduleRetry(download); ok {
+		s.mu.Lock()
+		s.downloads[download.ID] = download
+		s.mu.Unlock()
+		time.AfterFunc(delay, func() { s.retryDownload(download, item.req) })
+		return
+	}
+	s.deleteCookieJar(download)
+}
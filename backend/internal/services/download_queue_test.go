@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestDownloadQueueOrder(t *testing.T) {
+	var q downloadQueue
+	q.push(queuedDownload{id: "a"})
+	q.push(queuedDownload{id: "b"})
+	q.push(queuedDownload{id: "urgent", priority: 10})
+	q.push(queuedDownload{id: "c"})
+	q.push(queuedDownload{id: "later", priority: -1})
+	q.push(queuedDownload{id: "urgent2", priority: 10})
+
+	if !q.remove("b") || q.remove("missing") {
+		t.Fatal("remove() reported the wrong downloads")
+	}
+
+	var order []string
+	for {
+		item, ok := q.pop()
+		if !ok {
+			break
+		}
+		order = append(order, item.id)
+	}
+	want := []string{"urgent", "urgent2", "a", "c", "later"}
+	if len(order) != len(want) {
+		t.Fatalf("queue order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("queue order = %v, want %v", order, want)
+		}
+	}
+}
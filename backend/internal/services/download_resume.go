@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// resumeRequest rebuilds the request a stored download was started with
+func resumeRequest(download *models.Download) DownloadRequest {
+	req := DownloadRequest{
+		URL:            download.URL,
+		Format:         download.Format,
+		SessionID:      download.SessionID,
+		Playlist:       download.Playlist,
+		Subtitles:      download.Subtitles,
+		ChapterProject: download.ChapterProject,
+		CreateProject:  download.CreateProject,
+		ProjectName:    download.ProjectName,
+		RateLimit:      download.RateLimit,
+		Priority:       download.Priority,
+		Section:        download.Section,
+		SHA256:         download.SHA256,
+		MD5:            download.MD5,
+	}
+	if len(download.SponsorBlock) > 0 {
+		req.SponsorBlock = true
+		req.SponsorBlockCategories = download.SponsorBlock
+	}
+	if download.Client != nil {
+		req.DownloadClient = *download.Client
+	}
+	return req
+}
+
+// ResumeDownload queues a failed, interrupted or cancelled download again. Direct
+// downloads continue their partial file with a range request and yt-dlp continues
+// its .part files, so only the missing bytes are downloaded.
+func (s *DownloadService) ResumeDownload(id string) (*models.Download, error) {
+	download, err := s.GetDownload(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch download.Status {
+	case models.DownloadStatusFailed, models.DownloadStatusInterrupted, models.DownloadStatusCancelled:
+	default:
+		return nil, fmt.Errorf("only failed, interrupted or cancelled downloads can be resumed, download is %s", download.Status)
+	}
+	if download.ParentID != "" {
+		return nil, fmt.Errorf("playlist items are resumed with their playlist")
+	}
+	if download.Cron != "" {
+		return nil, fmt.Errorf("recurring schedules can't be resumed, create a new one")
+	}
+	if download.CookieJar != "" {
+		if _, err := os.Stat(s.storage.GetCookieJarPath(download.CookieJar)); err != nil {
+			return nil, fmt.Errorf("the cookies of this download were deleted when it ended, start it again with cookies")
+		}
+	}
+
+	// A manual resume starts a fresh round of automatic retries
+	download.Error = ""
+	download.Attempts = nil
+	s.enqueue(download, resumeRequest(download))
+	return download, nil
+}
+
+// resumeOffset returns where the body of a response to a request for the bytes
+// from offset on starts in the file: offset when the server honored the range, 0
+// when it sent the whole file. It fails for a range the partial file can't continue.
+func resumeOffset(resp *http.Response, offset int64) (int64, error) {
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, nil
+	}
+	start, ok := parseContentRangeStart(resp.Header.Get("Content-Range"))
+	if !ok || start != offset {
+		return 0, fmt.Errorf("server sent the range %q, not the bytes from %d on", resp.Header.Get("Content-Range"), offset)
+	}
+	return offset, nil
+}
+
+// parseContentRangeStart returns the first byte of a Content-Range header such as
+// "bytes 100-199/200"
+func parseContentRangeStart(header string) (int64, bool) {
+	rest, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	first, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	return start, err == nil && start >= 0
+}
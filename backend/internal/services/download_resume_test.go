@@ -0,0 +1,38 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResumeOffset(t *testing.T) {
+	response := func(status int, contentRange string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		if contentRange != "" {
+			resp.Header.Set("Content-Range", contentRange)
+		}
+		return resp
+	}
+
+	tests := []struct {
+		name    string
+		resp    *http.Response
+		offset  int64
+		want    int64
+		wantErr bool
+	}{
+		{"whole file", response(http.StatusOK, ""), 100, 0, false},
+		{"range honored", response(http.StatusPartialContent, "bytes 100-199/200"), 100, 100, false},
+		{"unknown size", response(http.StatusPartialContent, "bytes 100-199/*"), 100, 100, false},
+		{"other range", response(http.StatusPartialContent, "bytes 0-199/200"), 100, 0, true},
+		{"missing header", response(http.StatusPartialContent, ""), 100, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resumeOffset(tt.resp, tt.offset)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("resumeOffset() = %d, %v, want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
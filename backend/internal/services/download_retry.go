@@ -0,0 +1,89 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// maxRetryBackoff caps the wait between attempts of a download
+const maxRetryBackoff = 10 * time.Minute
+
+// transientDownloadError matches errors a later attempt may not run into: network
+// failures, server errors and rate limiting, from Go's HTTP client or yt-dlp
+var transientDownloadError = regexp.MustCompile(`(?i)(http( error)? (429|5\d\d)\b|too many requests|timed? ?out|connection (reset|refused|aborted)|broken pipe|unexpected eof|temporary failure|no such host|tls handshake|network is unreachable)`)
+
+// isTransientDownloadError reports whether a failed download is worth retrying
+func isTransientDownloadError(message string) bool {
+	return transientDownloadError.MatchString(message)
+}
+
+// ytdlpErrorLine returns the last error yt-dlp reported on stderr
+func ytdlpErrorLine(stderr []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(stderr)), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], "ERROR:") {
+			return strings.TrimSpace(lines[i])
+		}
+	}
+	return ""
+}
+
+// retryBackoff returns the wait before retrying after the given failed attempt,
+// doubling from base with every attempt
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return delay
+}
+
+// scheduleRetry records the failed attempt of a download whose error is transient,
+// while it has retries left, and returns how long to wait before the next attempt
+func (s *DownloadService) scheduleRetry(download *models.Download) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if download.Status != models.DownloadStatusFailed || !isTransientDownloadError(download.Error) {
+		return 0, false
+	}
+	if len(download.Attempts) >= s.config.Download.MaxRetries {
+		return 0, false
+	}
+
+	download.Attempts = append(download.Attempts, models.DownloadAttempt{Error: download.Error, FailedAt: time.Now()})
+	delay := retryBackoff(time.Duration(s.config.Download.RetryBackoffSeconds)*time.Second, len(download.Attempts))
+	next := time.Now().Add(delay)
+	download.Status = models.DownloadStatusRetrying
+	download.NextRetryAt = &next
+	s.storage.UpdateDownload(download)
+
+	s.logger.Warn("Download failed, retrying",
+		zap.String("id", download.ID),
+		zap.Int("attempt", len(download.Attempts)),
+		zap.Duration("backoff", delay),
+		zap.String("error", download.Error),
+	)
+	return delay, true
+}
+
+// retryDownload queues a download again once its backoff passed, unless it was
+// cancelled meanwhile
+func (s *DownloadService) retryDownload(download *models.Download, req DownloadRequest) {
+	s.mu.Lock()
+	retrying := download.Status == models.DownloadStatusRetrying
+	if retrying {
+		download.Error = ""
+		download.NextRetryAt = nil
+	}
+	s.mu.Unlock()
+	if retrying {
+		s.enqueue(download, req)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsTransientDownloadError(t *testing.T) {
+	transient := []string{
+		"HTTP 503: 503 Service Unavailable",
+		"ERROR: [youtube] abc: Unable to download webpage: HTTP Error 429: Too Many Requests",
+		`Get "https://example.com/a.mp4": dial tcp: lookup example.com: no such host`,
+		"read tcp 10.0.0.2:443: read: connection reset by peer",
+		"unexpected EOF",
+		"ERROR: Read timed out.",
+	}
+	for _, message := range transient {
+		if !isTransientDownloadError(message) {
+			t.Errorf("isTransientDownloadError(%q) = false, want true", message)
+		}
+	}
+
+	permanent := []string{
+		"HTTP 404: 404 Not Found",
+		"URL returned a web page, not a video",
+		"ERROR: [youtube] abc: Private video",
+		"failed to import video: no video stream",
+	}
+	for _, message := range permanent {
+		if isTransientDownloadError(message) {
+			t.Errorf("isTransientDownloadError(%q) = true, want false", message)
+		}
+	}
+}
+
+func TestYtdlpErrorLine(t *testing.T) {
+	stderr := "WARNING: [youtube] falling back\nERROR: [youtube] abc: HTTP Error 503: Service Unavailable\n"
+	if got := ytdlpErrorLine([]byte(stderr)); got != "ERROR: [youtube] abc: HTTP Error 503: Service Unavailable" {
+		t.Errorf("ytdlpErrorLine() = %q", got)
+	}
+	if got := ytdlpErrorLine([]byte("WARNING: nothing failed\n")); got != "" {
+		t.Errorf("ytdlpErrorLine() = %q, want none", got)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	base := 10 * time.Second
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{10, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		if got := retryBackoff(base, tt.attempt); got != tt.want {
+			t.Errorf("retryBackoff(%v, %d) = %v, want %v", base, tt.attempt, got, tt.want)
+		}
+	}
+}
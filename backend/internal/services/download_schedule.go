@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+// schedulerInterval is how often scheduled downloads are checked for being due
+const schedulerInterval = 30 * time.Second
+
+// scheduleStart returns when a download request asks to start: nil to start now,
+// the start_at time, or the first run of its cron expression from start_at on
+func scheduleStart(req DownloadRequest, now time.Time) (*time.Time, error) {
+	if req.Cron == "" {
+		if req.StartAt == nil || !req.StartAt.After(now) {
+			return nil, nil
+		}
+		start := *req.StartAt
+		return &start, nil
+	}
+
+	schedule, err := parseCron(req.Cron)
+	if err != nil {
+		return nil, err
+	}
+	from := now
+	if req.StartAt != nil && req.StartAt.After(now) {
+		from = req.StartAt.Add(-time.Minute)
+	}
+	next, ok := schedule.next(from)
+	if !ok {
+		return nil, fmt.Errorf("cron expression %q never matches", req.Cron)
+	}
+	return &next, nil
+}
+
+// RunScheduler starts scheduled downloads once they are due until ctx is done
+func (s *DownloadService) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.startDue(now)
+		}
+	}
+}
+
+// startDue queues the scheduled downloads due at now. A one-off download is queued
+// itself; a recurring one queues a new download per run and moves on to its next
+// run. Runs missed while the server was down start once.
+func (s *DownloadService) startDue(now time.Time) {
+	downloads, err := s.storage.ListDownloads()
+	if err != nil {
+		s.logger.Warn("Failed to list scheduled downloads", zap.Error(err))
+		return
+	}
+
+	for _, download := range downloads {
+		if download.Status != models.DownloadStatusScheduled || download.StartAt == nil || download.StartAt.After(now) {
+			continue
+		}
+
+		if download.Cron == "" {
+			s.logger.Info("Starting scheduled download", zap.String("id", download.ID))
+			s.enqueue(download, resumeRequest(download))
+			continue
+		}
+
+		run := &models.Download{
+			URL:            download.URL,
+			Format:         download.Format,
+			SessionID:      download.SessionID,
+			Client:         download.Client,
+			Playlist:       download.Playlist,
+			Subtitles:      download.Subtitles,
+			SponsorBlock:   download.SponsorBlock,
+			ChapterProject: download.ChapterProject,
+			CreateProject:  download.CreateProject,
+			ProjectName:    download.ProjectName,
+			CookieJar:      download.CookieJar,
+			SealedHeaders:  download.SealedHeaders,
+			RateLimit:      download.RateLimit,
+			Priority:       download.Priority,
+			ScheduleID:     download.ID,
+			Section:        download.Section,
+			SHA256:         download.SHA256,
+			MD5:            download.MD5,
+		}
+		if err := s.storage.CreateDownload(run); err != nil {
+			s.logger.Warn("Failed to create scheduled run", zap.String("id", download.ID), zap.Error(err))
+			continue
+		}
+		s.logger.Info("Starting scheduled run", zap.String("id", download.ID), zap.String("runId", run.ID))
+		s.enqueue(run, resumeRequest(run))
+
+		// The schedule may have been cancelled since it was listed, which sticks
+		_, err := s.storage.ModifyDownload(download.ID, func(download *models.Download) error {
+			download.RunIDs = append(download.RunIDs, run.ID)
+			if download.Status != models.DownloadStatusScheduled {
+				return nil
+			}
+			download.StartAt = nil
+			if schedule, err := parseCron(download.Cron); err == nil {
+				if next, ok := schedule.next(now); ok {
+					download.StartAt = &next
+				}
+			}
+			if download.StartAt == nil {
+				download.Status = models.DownloadStatusCompleted
+			}
+			return nil
+		})
+		if err != nil {
+			s.logger.Warn("Failed to update schedule", zap.String("id", download.ID), zap.Error(err))
+		}
+	}
+}
+
+// cancelScheduled cancels a download waiting for its start time, or a recurring
+// schedule; runs it already started continue
+func (s *DownloadService) cancelScheduled(id string) error {
+	errNotScheduled := fmt.Errorf("download not found or already completed")
+	download, err := s.storage.ModifyDownload(id, func(download *models.Download) error {
+		if download.Status != models.DownloadStatusScheduled {
+			return errNotScheduled
+		}
+		download.Status = models.DownloadStatusCancelled
+		download.StartAt = nil
+		return nil
+	})
+	if errors.Is(err, errNotScheduled) || errors.Is(err, storage.ErrNotFound) {
+		return errNotScheduled
+	}
+	if err != nil {
+		return err
+	}
+	// Runs still waiting or running keep the jar, the last one deletes it
+	s.deleteCookieJar(download)
+	return nil
+}
+
+// scheduledURLsDone returns the URLs earlier runs of a recurring schedule downloaded,
+// so playlist runs only download new items
+func (s *DownloadService) scheduledURLsDone(scheduleID string) map[string]bool {
+	done := make(map[string]bool)
+	downloads, err := s.storage.ListDownloads()
+	if err != nil {
+		return done
+	}
+	for _, download := range downloads {
+		if download.ScheduleID == scheduleID && !download.Playlist && download.Status == models.DownloadStatusCompleted {
+			done[download.URL] = true
+		}
+	}
+	return done
+}
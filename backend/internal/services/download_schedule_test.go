@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleStart(t *testing.T) {
+	now := time.Date(2024, 1, 31, 10, 17, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	later := time.Date(2024, 2, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		req  DownloadRequest
+		want *time.Time
+	}{
+		{"now", DownloadRequest{}, nil},
+		{"start_at passed", DownloadRequest{StartAt: &past}, nil},
+		{"start_at", DownloadRequest{StartAt: &later}, &later},
+		{"cron", DownloadRequest{Cron: "0 3 * * *"}, timePtr(time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC))},
+		{"cron from start_at", DownloadRequest{Cron: "0 12 * * *", StartAt: &later}, &later},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := scheduleStart(tt.req, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("scheduleStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := scheduleStart(DownloadRequest{Cron: "0 0 30 2 *"}, now); err == nil {
+		t.Error("expected an error for a cron expression that never matches")
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// validateDownloadSection checks the time range of a download request
+func validateDownloadSection(section *models.DownloadSection) error {
+	if section == nil {
+		return nil
+	}
+	if section.Start < 0 {
+		return fmt.Errorf("section start must not be negative")
+	}
+	if section.End != 0 && section.End <= section.Start {
+		return fmt.Errorf("section end must be after its start")
+	}
+	return nil
+}
+
+// ytdlpSectionArgs returns the yt-dlp arguments downloading only a time range of
+// the video. Without accurate cuts it starts at the keyframe before the start.
+func ytdlpSectionArgs(section *models.DownloadSection) []string {
+	if section == nil {
+		return nil
+	}
+	end := "inf"
+	if section.End > 0 {
+		end = strconv.FormatFloat(section.End, 'f', -1, 64)
+	}
+	args := []string{"--download-sections", fmt.Sprintf("*%s-%s", strconv.FormatFloat(section.Start, 'f', -1, 64), end)}
+	if section.Accurate {
+		args = append(args, "--force-keyframes-at-cuts")
+	}
+	return args
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestYtdlpSectionArgs(t *testing.T) {
+	tests := []struct {
+		section *models.DownloadSection
+		want    []string
+	}{
+		{nil, nil},
+		{&models.DownloadSection{Start: 90, End: 210.5}, []string{"--download-sections", "*90-210.5"}},
+		{&models.DownloadSection{Start: 60}, []string{"--download-sections", "*60-inf"}},
+		{&models.DownloadSection{Start: 0, End: 30, Accurate: true}, []string{"--download-sections", "*0-30", "--force-keyframes-at-cuts"}},
+	}
+	for _, tt := range tests {
+		if got := ytdlpSectionArgs(tt.section); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ytdlpSectionArgs(%+v) = %v, want %v", tt.section, got, tt.want)
+		}
+	}
+}
+
+func TestValidateDownloadSection(t *testing.T) {
+	valid := []*models.DownloadSection{nil, {Start: 0, End: 10}, {Start: 30}}
+	for _, section := range valid {
+		if err := validateDownloadSection(section); err != nil {
+			t.Errorf("validateDownloadSection(%+v) = %v", section, err)
+		}
+	}
+	invalid := []*models.DownloadSection{{Start: -1, End: 10}, {Start: 20, End: 10}, {Start: 10, End: 10}}
+	for _, section := range invalid {
+		if err := validateDownloadSection(section); err == nil {
+			t.Errorf("validateDownloadSection(%+v) succeeded, want an error", section)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,12 +13,16 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/anacrolix/torrent"
+	"github.com/google/uuid"
 	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
 	"github.com/mifi/lossless-cut/backend/internal/models"
 	"github.com/mifi/lossless-cut/backend/internal/storage"
 	"go.uber.org/zap"
@@ -25,68 +30,316 @@ import (
 
 // DownloadService handles video downloads using yt-dlp
 type DownloadService struct {
-	storage      *storage.Manager
-	videoService *VideoService
-	config       *config.Config
-	logger       *zap.Logger
-	mu           sync.Mutex
-	downloads    map[string]*models.Download
+	storage        *storage.Manager
+	videoService   *VideoService
+	projectService *ProjectService
+	config         *config.Config
+	logger         *zap.Logger
+	mu             sync.Mutex
+	downloads      map[string]*models.Download
+	reservedNames  map[string]string // File names picked by running downloads, to their ID
+	queue          downloadQueue     // Downloads waiting for one of download.max_concurrent slots
+	running        int
+	cancels        map[string]context.CancelFunc // Stop the process or request of running downloads
+	updateMu       sync.Mutex                    // Serializes yt-dlp updates
+	ffmpeg         *ffmpeg.Executor              // Saves HLS and DASH streams
+	torrentMu      sync.Mutex                    // Guards torrents
+	torrents       *torrent.Client               // Downloads torrents, started on first use
 }
 
 // NewDownloadService creates a new download service
-func NewDownloadService(storage *storage.Manager, videoService *VideoService, cfg *config.Config, logger *zap.Logger) *DownloadService {
+func NewDownloadService(storage *storage.Manager, videoService *VideoService, projectService *ProjectService, cfg *config.Config, logger *zap.Logger) *DownloadService {
 	return &DownloadService{
-		storage:      storage,
-		videoService: videoService,
-		config:       cfg,
-		logger:       logger,
-		downloads:    make(map[string]*models.Download),
+		storage:        storage,
+		videoService:   videoService,
+		projectService: projectService,
+		config:         cfg,
+		logger:         logger,
+		downloads:      make(map[string]*models.Download),
+		cancels:        make(map[string]context.CancelFunc),
+		ffmpeg:         newFFmpegExecutor(cfg, logger),
 	}
 }
 
 // DownloadRequest represents a download request
 type DownloadRequest struct {
-	URL    string `json:"url" binding:"required"`
-	Format string `json:"format,omitempty"` // e.g., "best", "bestvideo+bestaudio", specific format ID
+	URL       string `json:"url" binding:"required"`
+	Format    string `json:"format,omitempty"`     // e.g., "best", "bestvideo+bestaudio", specific format ID
+	SessionID string `json:"session_id,omitempty"` // Numbers sequence-named files per session
+	Playlist  bool   `json:"playlist,omitempty"`   // Download every item of a playlist URL as its own video
+
+	// Subtitles to download with the video and attach to it (yt-dlp downloads only)
+	Subtitles *models.DownloadSubtitles `json:"subtitles,omitempty"`
+
+	// Create a project leaving out the SponsorBlock ranges of a YouTube video,
+	// in the given categories (default sponsor, selfpromo and interaction)
+	SponsorBlock           bool     `json:"sponsorblock,omitempty"`
+	SponsorBlockCategories []string `json:"sponsorblock_categories,omitempty"`
+
+	// Create a project with one segment per chapter the site reports
+	ChapterProject bool `json:"chapter_project,omitempty"`
+
+	// Create an empty project for each downloaded video, ready to cut. project_name
+	// is its name template with {title}, {uploader}, {upload_date} and {date}
+	// placeholders (default "{title}").
+	CreateProject bool   `json:"create_project,omitempty"`
+	ProjectName   string `json:"project_name,omitempty"`
+
+	// Netscape cookies.txt for login-gated videos, stored encrypted until the download ends
+	Cookies string `json:"cookies,omitempty"`
+
+	// Bandwidth cap in bytes per second, with optional K, M or G suffix. Can only
+	// lower the configured download.rate_limit.
+	RateLimit string `json:"rate_limit,omitempty"`
+
+	// Queue priority, higher first; downloads of the same priority run in order
+	Priority int `json:"priority,omitempty"`
+
+	// Start later instead of now, or every time a cron expression such as "0 3 * * *"
+	// matches (server time, from start_at on). Recurring playlist runs skip the items
+	// earlier runs downloaded.
+	StartAt *time.Time `json:"start_at,omitempty"`
+	Cron    string     `json:"cron,omitempty"`
+
+	// Only download this time range, e.g. a clip of a long stream (yt-dlp downloads)
+	Section *models.DownloadSection `json:"section,omitempty"`
+
+	// Expected checksums of a direct download as hex. The file is also checked against
+	// its Content-Length and any Digest the server sends; a mismatch fails the download.
+	SHA256 string `json:"sha256,omitempty"`
+	MD5    string `json:"md5,omitempty"`
+
+	// Client settings overriding the configured ones, for sites blocking the default client
+	models.DownloadClient
 }
 
 // StartDownload initiates a video download
 func (s *DownloadService) StartDownload(ctx context.Context, req DownloadRequest) (*models.Download, error) {
+	if isTorrentURL(req.URL) && !s.config.Torrent.Enabled {
+		return nil, fmt.Errorf("torrent downloads are disabled")
+	}
+	if err := validateDownloadClient(req.DownloadClient); err != nil {
+		return nil, err
+	}
+	if err := validateDownloadSubtitles(req.Subtitles); err != nil {
+		return nil, err
+	}
+	if err := validateDownloadSection(req.Section); err != nil {
+		return nil, err
+	}
+	if err := validateProjectName(req.ProjectName); err != nil {
+		return nil, err
+	}
+	sha256Sum, err := NormalizeChecksum(req.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	md5Sum, err := normalizeMD5(req.MD5)
+	if err != nil {
+		return nil, err
+	}
+	req.SHA256, req.MD5 = sha256Sum, md5Sum
+	if (req.SHA256 != "" || req.MD5 != "") && (req.Playlist || req.Section != nil || isTorrentURL(req.URL) || isRemoteURL(req.URL) || isManifestURL(req.URL)) {
+		return nil, fmt.Errorf("checksums can only be verified for direct downloads of a single file")
+	}
+	if isManifestURL(req.URL) && (req.Cookies != "" || req.CookiesFromBrowser != "") {
+		return nil, fmt.Errorf("cookies are not supported for HLS and DASH manifests, pass them in headers instead")
+	}
+	if req.Format != "" {
+		if _, err := ytdlpFormat(req.Format, maxQualityHeight(s.config.YtDlp.MaxQuality)); err != nil {
+			return nil, err
+		}
+	}
+	if req.Section != nil && (isTorrentURL(req.URL) || isS3URL(req.URL) || req.SponsorBlock) {
+		return nil, fmt.Errorf("a section can't be downloaded from torrents, s3:// URLs or with SponsorBlock")
+	}
+	var login string
+	if isRemoteURL(req.URL) {
+		if !s.config.Remote.Enabled {
+			return nil, fmt.Errorf("ftp and sftp downloads are disabled")
+		}
+		if req.Playlist || req.Section != nil || req.SponsorBlock || req.Cookies != "" {
+			return nil, fmt.Errorf("ftp and sftp downloads fetch a single file, without playlist, section, sponsorblock or cookies")
+		}
+		var err error
+		if req.URL, login, err = remoteLogin(req.URL); err != nil {
+			return nil, err
+		}
+		if login == "" && strings.HasPrefix(strings.ToLower(req.URL), "sftp://") && remoteSSHKey(s.config.Remote, req.URL) == "" {
+			return nil, fmt.Errorf("sftp:// URLs need a password, the server's SSH key only logs in to the hosts in remote.ssh_key_hosts")
+		}
+	}
+	if isS3URL(req.URL) {
+		if req.Playlist {
+			return nil, fmt.Errorf("playlist mode is not supported for s3:// URLs")
+		}
+		if _, err := s3ObjectURL(s.config.S3, req.URL); err != nil {
+			return nil, err
+		}
+		if err := checkS3Bucket(s.config, req.URL); err != nil {
+			return nil, err
+		}
+	}
+	sponsorBlock, err := sponsorBlockOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateCookies(req.Cookies); err != nil {
+		return nil, err
+	}
+	if _, err := downloadRateLimit(s.config.Download.RateLimit, req.RateLimit); err != nil {
+		return nil, err
+	}
+	startAt, err := scheduleStart(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	// Create download record
 	download := &models.Download{
-		URL:    req.URL,
-		Status: models.DownloadStatusPending,
+		URL:            req.URL,
+		Format:         req.Format,
+		SessionID:      req.SessionID,
+		Playlist:       req.Playlist,
+		Subtitles:      req.Subtitles,
+		SponsorBlock:   sponsorBlock,
+		ChapterProject: req.ChapterProject,
+		CreateProject:  req.CreateProject,
+		ProjectName:    req.ProjectName,
+		RateLimit:      req.RateLimit,
+		Priority:       req.Priority,
+		Cron:           req.Cron,
+		StartAt:        startAt,
+		Section:        req.Section,
+		SHA256:         req.SHA256,
+		MD5:            req.MD5,
+		Status:         models.DownloadStatusQueued,
+	}
+	if req.Playlist && isTorrentURL(req.URL) {
+		return nil, fmt.Errorf("playlist mode is not supported for torrents")
+	}
+	if req.Impersonate != "" || req.UserAgent != "" || len(req.Headers) > 0 || req.CookiesFromBrowser != "" || len(req.ExtraArgs) > 0 {
+		client := req.DownloadClient
+		download.Client = &client
+	}
+	// Headers often carry credentials, so the record only keeps them encrypted
+	if len(req.Headers) > 0 {
+		sealed, err := s.sealHeaders(req.Headers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store headers: %w", err)
+		}
+		download.SealedHeaders = sealed
+		download.Client.Headers = nil
+	}
+	if req.Cookies != "" {
+		download.CookieJar = uuid.New().String()
+		if err := s.saveCookieJar(download.CookieJar, req.Cookies); err != nil {
+			return nil, fmt.Errorf("failed to store cookies: %w", err)
+		}
+	}
+	// An FTP/SFTP password is kept encrypted like cookies, as a curl config
+	if login != "" {
+		download.CookieJar = uuid.New().String()
+		if err := s.saveCookieJar(download.CookieJar, curlLoginConfig(login)); err != nil {
+			return nil, fmt.Errorf("failed to store login: %w", err)
+		}
+	}
+
+	if startAt != nil {
+		download.Status = models.DownloadStatusScheduled
 	}
 
 	if err := s.storage.CreateDownload(download); err != nil {
 		return nil, fmt.Errorf("failed to create download record: %w", err)
 	}
 
-	s.mu.Lock()
-	s.downloads[download.ID] = download
-	s.mu.Unlock()
+	// The scheduler starts it when it is due
+	if startAt != nil {
+		s.logger.Info("Scheduled download", zap.String("id", download.ID), zap.Time("startAt", *startAt), zap.String("cron", req.Cron))
+		return download, nil
+	}
 
-	// Start download in background
-	videoNumber := s.storage.GetNextVideoNumber()
-	go s.runDownload(download.ID, req, videoNumber)
+	// Start download in background once a slot is free
+	s.enqueue(download, req)
 
 	return download, nil
 }
 
+// Recover marks downloads a previous run left queued, pending or downloading as
+// interrupted. With resume set they are queued again instead, in their old order.
+func (s *DownloadService) Recover(resume bool) {
+	downloads, err := s.storage.ListDownloads()
+	if err != nil {
+		s.logger.Warn("Failed to list downloads", zap.Error(err))
+		return
+	}
+
+	sort.SliceStable(downloads, func(i, j int) bool { return downloads[i].CreatedAt.Before(downloads[j].CreatedAt) })
+
+	for _, download := range downloads {
+		switch download.Status {
+		case models.DownloadStatusQueued, models.DownloadStatusPending, models.DownloadStatusDownloading, models.DownloadStatusRetrying:
+		default:
+			continue
+		}
+		// Playlist items are resumed by their playlist
+		if resume && download.ParentID != "" {
+			continue
+		}
+		s.logger.Info("Found interrupted download", zap.String("id", download.ID), zap.Bool("resume", resume))
+
+		// Partial files and cookies are kept, so it can be resumed later
+		if !resume {
+			download.Status = models.DownloadStatusInterrupted
+			download.QueuePosition = 0
+			download.NextRetryAt = nil
+			download.Error = "interrupted by server restart"
+			s.storage.UpdateDownload(download)
+			continue
+		}
+
+		// Progress is kept, the download continues its partial file
+		download.Error = ""
+		download.NextRetryAt = nil
+		s.enqueue(download, resumeRequest(download))
+	}
+}
+
 // GetDownload retrieves download status
 func (s *DownloadService) GetDownload(id string) (*models.Download, error) {
 	s.mu.Lock()
 	download, exists := s.downloads[id]
+	var snapshot models.Download
+	if exists {
+		// The run keeps changing the download under the lock, callers get a copy
+		snapshot = *download
+	}
 	s.mu.Unlock()
 
 	if exists {
-		return download, nil
+		return &snapshot, nil
 	}
 
 	// Try loading from storage
 	return s.storage.GetDownload(id)
 }
 
+// setDownload changes a download that CancelDownload and the API may be reading at
+// the same time under the lock and saves it
+func (s *DownloadService) setDownload(download *models.Download, change func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change()
+	return s.storage.UpdateDownload(download)
+}
+
+// downloadStatus reads the status of a download that CancelDownload may be changing
+func (s *DownloadService) downloadStatus(download *models.Download) models.DownloadStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return download.Status
+}
+
 // ListDownloads returns all downloads
 func (s *DownloadService) ListDownloads() ([]*models.Download, error) {
 	return s.storage.ListDownloads()
@@ -96,17 +349,48 @@ func (s *DownloadService) ListDownloads() ([]*models.Download, error) {
 func (s *DownloadService) CancelDownload(id string) error {
 	s.mu.Lock()
 	download, exists := s.downloads[id]
+	waiting := exists && (s.queue.remove(id) || download.Status == models.DownloadStatusRetrying)
+	if waiting {
+		delete(s.downloads, id)
+	}
 	s.mu.Unlock()
 
 	if !exists {
-		return fmt.Errorf("download not found or already completed")
+		return s.cancelScheduled(id)
 	}
 
-	download.Status = models.DownloadStatusCancelled
-	if err := s.storage.UpdateDownload(download); err != nil {
+	var childIDs []string
+	if err := s.setDownload(download, func() {
+		download.Status = models.DownloadStatusCancelled
+		download.QueuePosition = 0
+		download.NextRetryAt = nil
+		childIDs = download.ChildIDs
+	}); err != nil {
 		return err
 	}
 
+	// A download that isn't running only has to leave the queue or skip its retry
+	if waiting {
+		s.deleteCookieJar(download)
+		s.dispatch()
+		return nil
+	}
+
+	s.stopRun(id)
+
+	// Stop the playlist item being downloaded, the remaining ones are skipped
+	for _, childID := range childIDs {
+		s.mu.Lock()
+		child, running := s.downloads[childID]
+		s.mu.Unlock()
+		if running {
+			s.setDownload(child, func() {
+				child.Status = models.DownloadStatusCancelled
+			})
+			s.stopRun(childID)
+		}
+	}
+
 	return nil
 }
 
@@ -138,29 +422,117 @@ func (s *DownloadService) isDirectVideoURL(urlStr string) bool {
 }
 
 // runDownload executes the actual download
-func (s *DownloadService) runDownload(downloadID string, req DownloadRequest, videoNumber int) {
+func (s *DownloadService) runDownload(downloadID string, req DownloadRequest) {
 	s.mu.Lock()
 	download := s.downloads[downloadID]
 	s.mu.Unlock()
 
-	download.Status = models.DownloadStatusDownloading
-	s.storage.UpdateDownload(download)
+	// Registered first so a cancel after the status check below stops ctx
+	ctx, done := s.startRun(downloadID)
+	defer done()
+
+	// Cancelled between leaving the queue and starting
+	cancelled := false
+	s.setDownload(download, func() {
+		cancelled = download.Status == models.DownloadStatusCancelled
+		if !cancelled {
+			download.Status = models.DownloadStatusDownloading
+			download.QueuePosition = 0
+		}
+	})
+	if cancelled {
+		return
+	}
+
+	// Cookies are decrypted for the run only; playlist items share their playlist's
+	if download.CookieJar != "" && req.CookiesFile == "" {
+		path, cleanup, err := s.openCookieJar(download.CookieJar)
+		if err != nil {
+			s.logger.Error("Failed to open cookie jar", zap.String("id", download.ID), zap.Error(err))
+			download.Status = models.DownloadStatusFailed
+			download.Error = err.Error()
+			s.storage.UpdateDownload(download)
+			return
+		}
+		defer cleanup()
+		req.CookiesFile = path
+	}
+	if download.SealedHeaders != "" && len(req.Headers) == 0 {
+		headers, err := s.openHeaders(download.SealedHeaders)
+		if err != nil {
+			s.logger.Error("Failed to decrypt headers", zap.String("id", download.ID), zap.Error(err))
+			download.Status = models.DownloadStatusFailed
+			download.Error = err.Error()
+			s.storage.UpdateDownload(download)
+			return
+		}
+		req.Headers = headers
+	}
+
+	// Magnet links and .torrent files go to the torrent client
+	if isTorrentURL(req.URL) {
+		s.runTorrentDownload(ctx, download, req)
+		return
+	}
+
+	if req.Playlist {
+		s.runPlaylistDownload(ctx, download, req)
+		return
+	}
+
+	// Sections are cut by yt-dlp, which also downloads direct links
+	if req.Section != nil {
+		s.runYtdlpDownload(ctx, download, req)
+		return
+	}
+
+	// FTP and SFTP files are fetched with curl
+	if isRemoteURL(req.URL) {
+		s.runRemoteDownload(ctx, download, req)
+		return
+	}
+
+	// HLS and DASH manifests are downloaded by yt-dlp, or FFmpeg if it fails
+	if isManifestURL(req.URL) {
+		s.runManifestDownload(ctx, download, req)
+		return
+	}
+
+	// Objects in S3 buckets are fetched with the configured credentials
+	if isS3URL(req.URL) {
+		objectURL, err := s3ObjectURL(s.config.S3, req.URL)
+		if err != nil {
+			download.Status = models.DownloadStatusFailed
+			download.Error = err.Error()
+			s.storage.UpdateDownload(download)
+			return
+		}
+		s.runDirectDownload(ctx, download, req, objectURL.String())
+		return
+	}
+
+	// Cloud drive share links resolve to a direct download of the file
+	if direct, ok := resolveShareLink(req.URL); ok {
+		s.runDirectDownload(ctx, download, req, direct)
+		return
+	}
 
 	// Check if this is a direct video URL (not YouTube/etc)
 	if s.isDirectVideoURL(req.URL) {
-		s.runDirectDownload(download, req, videoNumber)
+		s.runDirectDownload(ctx, download, req, req.URL)
 		return
 	}
 
 	// Use yt-dlp for YouTube and other supported sites
-	s.runYtdlpDownload(download, req, videoNumber)
+	s.runYtdlpDownload(ctx, download, req)
 }
 
-// runDirectDownload downloads a video directly from URL using HTTP
-func (s *DownloadService) runDirectDownload(download *models.Download, req DownloadRequest, videoNumber int) {
+// runDirectDownload downloads a video directly from sourceURL using HTTP. sourceURL
+// differs from the requested URL when a share link was resolved.
+func (s *DownloadService) runDirectDownload(ctx context.Context, download *models.Download, req DownloadRequest, sourceURL string) {
 	s.logger.Info("Starting direct HTTP download",
 		zap.String("id", download.ID),
-		zap.String("url", req.URL),
+		zap.String("url", sourceURL),
 	)
 
 	// Determine output path
@@ -173,21 +545,13 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 		return
 	}
 
-	// Extract extension from URL or use .mp4 as default
-	ext := s.getExtensionFromURL(req.URL)
-	outputPath := filepath.Join(outputDir, fmt.Sprintf("video%d%s", videoNumber, ext))
-
-	// Extract filename for title
-	download.Title = s.getTitleFromURL(req.URL)
-	s.storage.UpdateDownload(download)
-
 	// Create HTTP client with timeout
 	client := &http.Client{
 		Timeout: 30 * time.Minute, // Long timeout for large files
 	}
 
 	// Create request
-	httpReq, err := http.NewRequest("GET", req.URL, nil)
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", sourceURL, nil)
 	if err != nil {
 		s.logger.Error("Failed to create HTTP request", zap.Error(err))
 		download.Status = models.DownloadStatusFailed
@@ -196,15 +560,37 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 		return
 	}
 
-	// Add headers to mimic browser
-	httpReq.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	// Add headers to mimic browser, configured and requested headers (e.g.
+	// Authorization or signed cookies) take precedence
 	httpReq.Header.Set("Accept", "*/*")
 	httpReq.Header.Set("Accept-Language", "en-US,en;q=0.9")
-	httpReq.Header.Set("Referer", req.URL)
+	if !isS3URL(req.URL) {
+		httpReq.Header.Set("Referer", req.URL)
+	}
+	applyDownloadClient(httpReq, s.client(req))
+
+	// A resumed download asks for the bytes its partial file is missing
+	var offset int64
+	if download.FilePath != "" {
+		if info, err := os.Stat(download.FilePath); err == nil {
+			offset = info.Size()
+		}
+	}
+	if offset > 0 {
+		httpReq.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	if isS3URL(req.URL) {
+		signS3Request(httpReq, s.config.S3, time.Now())
+	}
 
 	// Execute request
 	resp, err := client.Do(httpReq)
 	if err != nil {
+		// CancelDownload already saved the download as cancelled
+		if ctx.Err() != nil {
+			s.logger.Info("Download cancelled", zap.String("id", download.ID))
+			return
+		}
 		s.logger.Error("HTTP request failed", zap.Error(err))
 		download.Status = models.DownloadStatusFailed
 		download.Error = err.Error()
@@ -213,6 +599,15 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 	}
 	defer resp.Body.Close()
 
+	// The file changed since, or the partial file already holds all of it; start over
+	if offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		s.logger.Info("Partial download can't be continued, starting over", zap.String("id", download.ID))
+		resp.Body.Close()
+		os.Remove(download.FilePath)
+		s.runDirectDownload(ctx, download, req, sourceURL)
+		return
+	}
+
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
 		s.logger.Error("HTTP request returned error status",
 			zap.Int("status", resp.StatusCode),
@@ -223,11 +618,59 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 		return
 	}
 
-	// Get content length for progress
+	// Private or unresolvable share links serve a web page instead of the file
+	if isWebPage(resp) {
+		if sourceURL != req.URL {
+			s.logger.Info("Share link did not resolve to a file, trying yt-dlp", zap.String("id", download.ID))
+			resp.Body.Close()
+			s.runYtdlpDownload(ctx, download, req)
+			return
+		}
+		download.Status = models.DownloadStatusFailed
+		download.Error = "URL returned a web page, not a video"
+		s.storage.UpdateDownload(download)
+		return
+	}
+
+	start, err := resumeOffset(resp, offset)
+	if err != nil {
+		s.logger.Error("Partial download can't be continued", zap.String("id", download.ID), zap.Error(err))
+		download.Status = models.DownloadStatusFailed
+		download.Error = err.Error()
+		s.storage.UpdateDownload(download)
+		return
+	}
+
+	// A resumed download keeps its file, a new one picks a name. Extract filename for
+	// title, preferring the name the server suggests. Share links and CDNs often
+	// have no file name in the URL.
+	outputPath := download.FilePath
+	if outputPath == "" {
+		fileURL := sourceURL
+		if filename := responseFilename(resp); filename != "" {
+			fileURL = (&url.URL{Path: "/" + filepath.Base(filename)}).String()
+		}
+		download.Title = s.getTitleFromURL(fileURL)
+
+		// Extension of the file name or URL, else of the Content-Type, or .mp4
+		ext := directExtension(resp, fileURL, sourceURL)
+		outputPath = filepath.Join(outputDir, s.downloadName(outputDir, download, req, download.Title)+ext)
+	}
+
+	// Get content length for progress, of the whole file when continuing it
 	contentLength := resp.ContentLength
+	if contentLength > 0 {
+		contentLength += start
+	}
 
-	// Create output file
-	outFile, err := os.Create(outputPath)
+	// Create output file, or append to the partial one. The path is stored up front
+	// so the download can be resumed when it doesn't finish.
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if start > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+		s.logger.Info("Resuming direct download", zap.String("id", download.ID), zap.Int64("offset", start))
+	}
+	outFile, err := os.OpenFile(outputPath, flags, 0644)
 	if err != nil {
 		s.logger.Error("Failed to create output file", zap.Error(err))
 		download.Status = models.DownloadStatusFailed
@@ -236,22 +679,37 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 		return
 	}
 	defer outFile.Close()
+	download.FilePath = outputPath
+	download.DownloadedBytes = start
+	s.storage.UpdateDownload(download)
+
+	// Throttle so background downloads leave bandwidth for streaming
+	var body io.Reader = resp.Body
+	if rate, _ := downloadRateLimit(s.config.Download.RateLimit, req.RateLimit); rate > 0 {
+		body = newRateLimitedReader(resp.Body, rate)
+	}
 
 	// Download with progress tracking
-	var downloaded int64
+	downloaded := start
 	buf := make([]byte, 256*1024) // 256KB buffer for faster downloads
 	lastProgressUpdate := time.Now()
+	lastDownloaded := downloaded
+	var meter speedMeter
+	download.TotalBytes = contentLength
 
 	for {
-		if download.Status == models.DownloadStatusCancelled {
+		if ctx.Err() != nil {
 			s.logger.Info("Download cancelled", zap.String("id", download.ID))
 			outFile.Close()
 			os.Remove(outputPath)
-			s.storage.UpdateDownload(download)
+			s.setDownload(download, func() {
+				download.FilePath = ""
+				download.DownloadedBytes = 0
+			})
 			return
 		}
 
-		n, err := resp.Body.Read(buf)
+		n, err := body.Read(buf)
 		if n > 0 {
 			_, writeErr := outFile.Write(buf[:n])
 			if writeErr != nil {
@@ -264,10 +722,18 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 			downloaded += int64(n)
 
 			// Update progress every 500ms to avoid too many updates
-			if contentLength > 0 && time.Since(lastProgressUpdate) > 500*time.Millisecond {
-				download.Progress = float64(downloaded) / float64(contentLength) * 100
-				s.storage.UpdateDownload(download)
+			if elapsed := time.Since(lastProgressUpdate); elapsed > 500*time.Millisecond {
+				speed := meter.update(downloaded-lastDownloaded, elapsed)
+				s.setDownload(download, func() {
+					download.Speed = speed
+					download.DownloadedBytes = downloaded
+					if contentLength > 0 {
+						download.Progress = float64(downloaded) / float64(contentLength) * 100
+						download.ETA = downloadETA(contentLength-downloaded, speed)
+					}
+				})
 				lastProgressUpdate = time.Now()
+				lastDownloaded = downloaded
 
 				s.logger.Debug("Download progress",
 					zap.String("id", download.ID),
@@ -281,16 +747,59 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 		if err == io.EOF {
 			break
 		}
+		// Cancelling aborts the request, the next round removes the partial file
+		if err != nil && ctx.Err() != nil {
+			continue
+		}
 		if err != nil {
 			s.logger.Error("Failed to read response body", zap.Error(err))
 			download.Status = models.DownloadStatusFailed
+			download.DownloadedBytes = downloaded
 			download.Error = err.Error()
 			s.storage.UpdateDownload(download)
 			return
 		}
 	}
 
-	download.FilePath = outputPath
+	// A connection closed early leaves the partial file for a retry to continue
+	if contentLength > 0 && downloaded != contentLength {
+		download.Status = models.DownloadStatusFailed
+		download.DownloadedBytes = downloaded
+		if downloaded < contentLength {
+			download.Error = fmt.Sprintf("unexpected EOF after %d of %d bytes", downloaded, contentLength)
+		} else {
+			download.Error = fmt.Sprintf("received %d bytes, more than the %d announced", downloaded, contentLength)
+		}
+		s.storage.UpdateDownload(download)
+		return
+	}
+
+	// Check the file against the requested checksums and those the server sent
+	expected := downloadDigests{SHA256: download.SHA256, MD5: download.MD5}
+	served := responseDigests(resp)
+	var digests downloadDigests
+	if expected != (downloadDigests{}) || served != (downloadDigests{}) {
+		digests, err = fileDigests(outputPath)
+		if err == nil {
+			err = checkDigests(digests, expected, served)
+		}
+		if err != nil {
+			s.logger.Error("Download verification failed", zap.String("id", download.ID), zap.Error(err))
+			outFile.Close()
+			os.Remove(outputPath)
+			download.Status = models.DownloadStatusFailed
+			download.FilePath = ""
+			download.DownloadedBytes = 0
+			download.Error = err.Error()
+			s.storage.UpdateDownload(download)
+			return
+		}
+	}
+
+	download.DownloadedBytes = downloaded
+	download.TotalBytes = downloaded
+	download.Speed = 0
+	download.ETA = 0
 
 	s.logger.Info("Direct download completed",
 		zap.String("id", download.ID),
@@ -300,7 +809,7 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 
 	// Import the downloaded video
 	filename := filepath.Base(outputPath)
-	video, err := s.videoService.CreateFromUpload(filename, outputPath)
+	video, err := s.importDownload(filename, outputPath)
 	if err != nil {
 		s.logger.Error("Failed to import downloaded video", zap.Error(err))
 		download.Status = models.DownloadStatusFailed
@@ -310,11 +819,23 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 	}
 
 	video.OriginalURL = download.URL
-
-	download.VideoID = video.ID
-	download.Status = models.DownloadStatusCompleted
-	download.Progress = 100.0
-	s.storage.UpdateDownload(download)
+	s.setDownload(download, func() {
+		s.createDownloadProject(download, video, download.Title)
+		download.VideoID = video.ID
+		download.Status = models.DownloadStatusCompleted
+		download.Progress = 100.0
+	})
+	s.storage.RecordUsage(models.UsageDownloads, 1, video.FileSize)
+
+	// A verified download already has its checksum
+	if digests.SHA256 != "" {
+		video.SHA256 = digests.SHA256
+		if err := s.storage.SaveVideo(video); err != nil {
+			s.logger.Warn("Failed to save video checksum", zap.String("videoId", video.ID), zap.Error(err))
+		}
+	} else {
+		s.importChecksum(video)
+	}
 
 	s.logger.Info("Download completed and imported",
 		zap.String("id", download.ID),
@@ -328,23 +849,32 @@ func (s *DownloadService) runDirectDownload(download *models.Download, req Downl
 	s.mu.Unlock()
 }
 
-// getExtensionFromURL extracts file extension from URL
+// validExts are the video file extensions a direct download can keep
+var validExts = map[string]bool{
+	".mp4": true, ".mov": true, ".mkv": true, ".webm": true,
+	".avi": true, ".wmv": true, ".flv": true, ".m4v": true,
+	".3gp": true, ".ts": true, ".m2ts": true,
+}
+
+// getExtensionFromURL extracts file extension from URL, .mp4 if it has none
 func (s *DownloadService) getExtensionFromURL(urlStr string) string {
+	if ext := urlExtension(urlStr); ext != "" {
+		return ext
+	}
+	return ".mp4"
+}
+
+// urlExtension returns the video extension of a URL's path or of the file name in
+// its response-content-disposition parameter, "" if there is none
+func urlExtension(urlStr string) string {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
-		return ".mp4"
+		return ""
 	}
 
 	path := parsedURL.Path
 	ext := strings.ToLower(filepath.Ext(path))
 
-	// Valid video extensions
-	validExts := map[string]bool{
-		".mp4": true, ".mov": true, ".mkv": true, ".webm": true,
-		".avi": true, ".wmv": true, ".flv": true, ".m4v": true,
-		".3gp": true, ".ts": true, ".m2ts": true,
-	}
-
 	if validExts[ext] {
 		return ext
 	}
@@ -360,7 +890,7 @@ func (s *DownloadService) getExtensionFromURL(urlStr string) string {
 		}
 	}
 
-	return ".mp4"
+	return ""
 }
 
 // getTitleFromURL extracts a title from the URL
@@ -398,10 +928,24 @@ func (s *DownloadService) getTitleFromURL(urlStr string) string {
 }
 
 // runYtdlpDownload downloads using yt-dlp for YouTube and similar sites
-func (s *DownloadService) runYtdlpDownload(download *models.Download, req DownloadRequest, videoNumber int) {
+func (s *DownloadService) runYtdlpDownload(ctx context.Context, download *models.Download, req DownloadRequest) {
+	// yt-dlp picks and merges formats, its files can't match a given checksum
+	if download.SHA256 != "" || download.MD5 != "" {
+		download.Status = models.DownloadStatusFailed
+		download.Error = "checksums can only be verified for direct downloads, this URL is downloaded with yt-dlp"
+		s.storage.UpdateDownload(download)
+		return
+	}
+
 	// Get video info first
-	info, err := s.getVideoInfo(req.URL)
+	client := s.client(req)
+	info, err := s.getVideoInfo(ctx, req.URL, client)
 	if err != nil {
+		// CancelDownload already saved the download as cancelled
+		if ctx.Err() != nil {
+			s.logger.Info("Download cancelled", zap.String("id", download.ID))
+			return
+		}
 		s.logger.Error("Failed to get video info", zap.Error(err))
 		download.Status = models.DownloadStatusFailed
 		download.Error = err.Error()
@@ -423,13 +967,20 @@ func (s *DownloadService) runYtdlpDownload(download *models.Download, req Downlo
 		return
 	}
 
-	// Use simple sequential naming for easier FFmpeg management
+	// Use simple file names (no spaces or quotes) for easier FFmpeg management
 	// For yt-dlp, we need to specify the extension in the template
 	// yt-dlp will use the actual video extension (.mp4, .webm, .mkv, etc.)
-	outputTemplate := filepath.Join(outputDir, fmt.Sprintf("video%d.%%(ext)s", videoNumber))
+	// A resumed download keeps its name, so yt-dlp finds its .part files.
+	name := download.OutputName
+	if name == "" {
+		name = s.downloadName(outputDir, download, req, info.Title)
+		download.OutputName = name
+		s.storage.UpdateDownload(download)
+	}
+	outputTemplate := filepath.Join(outputDir, name+".%(ext)s")
 
 	s.logger.Info("Downloading video with simple naming",
-		zap.Int("videoNumber", videoNumber),
+		zap.String("name", name),
 		zap.String("outputTemplate", outputTemplate),
 		zap.String("title", info.Title),
 	)
@@ -439,20 +990,32 @@ func (s *DownloadService) runYtdlpDownload(download *models.Download, req Downlo
 		"--newline",
 		"--no-playlist",
 		"--progress",
+		"--continue",
 		"-o", outputTemplate,
 	}
 
-	// Add format if specified
-	if req.Format != "" {
-		args = append(args, "-f", req.Format)
-	} else {
-		args = append(args, "-f", "bestvideo[ext=mp4]+bestaudio[ext=m4a]/best[ext=mp4]/best")
+	// The requested format, or the default, limited to ytdlp.max_quality
+	format, err := ytdlpFormat(req.Format, maxQualityHeight(s.config.YtDlp.MaxQuality))
+	if err != nil {
+		download.Status = models.DownloadStatusFailed
+		download.Error = err.Error()
+		s.storage.UpdateDownload(download)
+		return
+	}
+	args = append(args, "-f", format)
+
+	if rate, _ := downloadRateLimit(s.config.Download.RateLimit, req.RateLimit); rate > 0 {
+		args = append(args, "--limit-rate", strconv.FormatInt(rate, 10))
 	}
 
+	args = append(args, ytdlpSectionArgs(req.Section)...)
+	args = append(args, ytdlpSubtitleArgs(req.Subtitles)...)
+	args = append(args, ytdlpClientArgs(client)...)
 	args = append(args, req.URL)
 
-	// Execute yt-dlp
-	cmd := exec.Command("yt-dlp", args...)
+	// Execute yt-dlp, cancelling the download interrupts it
+	cmd := exec.CommandContext(ctx, s.ytdlpPath(), args...)
+	interruptOnCancel(cmd)
 
 	// Create pipes for output
 	stdout, err := cmd.StdoutPipe()
@@ -484,34 +1047,54 @@ func (s *DownloadService) runYtdlpDownload(download *models.Download, req Downlo
 	// Parse progress from stdout
 	go s.parseDownloadProgress(stdout, download)
 
-	// Log stderr
+	// Log stderr, keeping yt-dlp's last error as the reason of a failure
+	var ytdlpError string
+	stderrDone := make(chan struct{})
 	go func() {
+		defer close(stderrDone)
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
-			s.logger.Debug("yt-dlp stderr", zap.String("line", scanner.Text()))
+			line := scanner.Text()
+			s.logger.Debug("yt-dlp stderr", zap.String("line", line))
+			if strings.HasPrefix(line, "ERROR:") {
+				ytdlpError = line
+			}
 		}
 	}()
 
 	// Wait for completion
+	<-stderrDone
 	if err := cmd.Wait(); err != nil {
-		if download.Status == models.DownloadStatusCancelled {
+		if ctx.Err() != nil {
 			s.logger.Info("Download cancelled", zap.String("id", download.ID))
-			s.storage.UpdateDownload(download)
+			removeYtdlpFiles(outputDir, name)
+			s.setDownload(download, func() {
+				download.OutputName = ""
+			})
 			return
 		}
 
-		s.logger.Error("yt-dlp failed", zap.Error(err))
+		s.logger.Error("yt-dlp failed", zap.Error(err), zap.String("stderr", ytdlpError))
 		download.Status = models.DownloadStatusFailed
 		download.Error = err.Error()
+		if ytdlpError != "" {
+			download.Error = ytdlpError
+		}
 		s.storage.UpdateDownload(download)
 		return
 	}
 
 	// Find the downloaded file
 	// yt-dlp saves with the actual extension (.mp4, .webm, .mkv, etc.)
-	// Look for video{N}.* where * is any extension
-	pattern := filepath.Join(outputDir, fmt.Sprintf("video%d.*", videoNumber))
-	files, err := filepath.Glob(pattern)
+	// Look for {name}.* where * is any extension, skipping subtitles like {name}.en.vtt
+	pattern := filepath.Join(outputDir, name+".*")
+	matches, err := filepath.Glob(pattern)
+	var files []string
+	for _, match := range matches {
+		if !isSubtitleFile(match) {
+			files = append(files, match)
+		}
+	}
 
 	if err != nil {
 		s.logger.Error("Failed to glob for downloaded file",
@@ -527,7 +1110,7 @@ func (s *DownloadService) runYtdlpDownload(download *models.Download, req Downlo
 	if len(files) == 0 {
 		s.logger.Error("Downloaded file not found",
 			zap.String("pattern", pattern),
-			zap.Int("videoNumber", videoNumber),
+			zap.String("name", name),
 		)
 		download.Status = models.DownloadStatusFailed
 		download.Error = "downloaded file not found"
@@ -540,13 +1123,13 @@ func (s *DownloadService) runYtdlpDownload(download *models.Download, req Downlo
 
 	s.logger.Info("Found downloaded file",
 		zap.String("file", downloadedFile),
-		zap.Int("videoNumber", videoNumber),
+		zap.String("name", name),
 		zap.String("extension", filepath.Ext(downloadedFile)),
 	)
 
 	// Import the downloaded video
 	filename := filepath.Base(downloadedFile)
-	video, err := s.videoService.CreateFromUpload(filename, downloadedFile)
+	video, err := s.importDownload(filename, downloadedFile)
 	if err != nil {
 		s.logger.Error("Failed to import downloaded video", zap.Error(err))
 		download.Status = models.DownloadStatusFailed
@@ -555,13 +1138,33 @@ func (s *DownloadService) runYtdlpDownload(download *models.Download, req Downlo
 		return
 	}
 
-	// Set the original URL
+	// Set the original URL and what the site reports about the video
 	video.OriginalURL = download.URL
+	s.importSourceMetadata(ctx, video, info)
 
-	download.VideoID = video.ID
-	download.Status = models.DownloadStatusCompleted
-	download.Progress = 100.0
-	s.storage.UpdateDownload(download)
+	// Chapters are saved with the video, so before attaching subtitles to it. The
+	// site's chapter times don't apply to a section.
+	if req.Section == nil {
+		s.importChapters(download, video, info)
+	}
+	if req.Subtitles != nil {
+		s.importSubtitles(video, outputDir, name)
+	}
+	if len(download.SponsorBlock) > 0 {
+		s.importSponsorBlock(download, video, info)
+	}
+	s.setDownload(download, func() {
+		s.createDownloadProject(download, video, info.Title)
+		download.VideoID = video.ID
+		download.Status = models.DownloadStatusCompleted
+		download.Progress = 100.0
+		download.DownloadedBytes = video.FileSize
+		download.TotalBytes = video.FileSize
+		download.Speed = 0
+		download.ETA = 0
+	})
+	s.storage.RecordUsage(models.UsageDownloads, 1, video.FileSize)
+	s.importChecksum(video)
 
 	s.logger.Info("Download completed and imported",
 		zap.String("id", download.ID),
@@ -578,37 +1181,54 @@ func (s *DownloadService) runYtdlpDownload(download *models.Download, req Downlo
 // parseDownloadProgress parses yt-dlp progress output
 func (s *DownloadService) parseDownloadProgress(stdout io.ReadCloser, download *models.Download) {
 	scanner := bufio.NewScanner(stdout)
-	// Regex to match: [download]  45.2% of 123.45MiB at 1.23MiB/s ETA 00:12
-	progressRegex := regexp.MustCompile(`\[download\]\s+(\d+\.?\d*)%`)
 
 	for scanner.Scan() {
-		line := scanner.Text()
-
-		if matches := progressRegex.FindStringSubmatch(line); len(matches) > 1 {
-			if progress, err := strconv.ParseFloat(matches[1], 64); err == nil {
-				download.Progress = progress
-				s.storage.UpdateDownload(download)
-				s.logger.Debug("Download progress",
-					zap.String("id", download.ID),
-					zap.Float64("progress", progress),
-				)
-			}
+		if progress, ok := parseYtdlpProgress(scanner.Text()); ok {
+			s.setDownload(download, func() {
+				download.Progress = progress.Percent
+				download.Speed = progress.Speed
+				download.ETA = progress.ETA
+				if progress.TotalBytes > 0 {
+					download.TotalBytes = progress.TotalBytes
+					download.DownloadedBytes = int64(progress.Percent / 100 * float64(progress.TotalBytes))
+				}
+			})
+			s.logger.Debug("Download progress",
+				zap.String("id", download.ID),
+				zap.Float64("progress", progress.Percent),
+				zap.Float64("speed", progress.Speed),
+			)
 		}
 	}
 }
 
 // VideoInfo represents basic video information from yt-dlp
 type VideoInfo struct {
-	Title    string  `json:"title"`
-	Duration float64 `json:"duration"`
-	Format   string  `json:"format"`
+	ID           string             `json:"id"`
+	ExtractorKey string             `json:"extractor_key"` // e.g. "Youtube"
+	Title        string             `json:"title"`
+	Duration     float64            `json:"duration"`
+	Format       string             `json:"format"`
+	Chapters     []VideoInfoChapter `json:"chapters"`
+	Thumbnail    string             `json:"thumbnail"`
+	Uploader     string             `json:"uploader"`
+	UploadDate   string             `json:"upload_date"` // YYYYMMDD
+	Description  string             `json:"description"`
+	WebpageURL   string             `json:"webpage_url"`
 }
 
 // getVideoInfo retrieves video information without downloading
-func (s *DownloadService) getVideoInfo(url string) (*VideoInfo, error) {
-	cmd := exec.Command("yt-dlp", "--dump-json", "--no-playlist", url)
+func (s *DownloadService) getVideoInfo(ctx context.Context, url string, client models.DownloadClient) (*VideoInfo, error) {
+	args := append([]string{"--dump-json", "--no-playlist"}, ytdlpClientArgs(client)...)
+	cmd := exec.CommandContext(ctx, s.ytdlpPath(), append(args, url)...)
 	output, err := cmd.Output()
 	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if line := ytdlpErrorLine(exitErr.Stderr); line != "" {
+				return nil, fmt.Errorf("failed to get video info: %s", line)
+			}
+		}
 		return nil, fmt.Errorf("failed to get video info: %w", err)
 	}
 
@@ -633,3 +1253,16 @@ func sanitizeFilename(name string) string {
 
 	return strings.TrimSpace(sanitized)
 }
+
+// client returns the client settings of a download request
+func (s *DownloadService) client(req DownloadRequest) models.DownloadClient {
+	return downloadClient(s.config.Download, req.DownloadClient)
+}
+
+// importChecksum starts computing the checksum of a downloaded video when checksums
+// are enabled
+func (s *DownloadService) importChecksum(video *models.Video) {
+	if _, err := s.videoService.ImportChecksum(video, ""); err != nil {
+		s.logger.Warn("Failed to start checksum of downloaded video", zap.String("videoId", video.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// validateDownloadSubtitles checks the subtitle languages of a download request
+func validateDownloadSubtitles(subtitles *models.DownloadSubtitles) error {
+	if subtitles == nil {
+		return nil
+	}
+	for _, language := range subtitles.Languages {
+		if language != "all" && !languagePattern.MatchString(language) {
+			return fmt.Errorf("invalid subtitle language: %s", language)
+		}
+	}
+	return nil
+}
+
+// ytdlpSubtitleArgs returns the yt-dlp arguments writing the requested subtitles
+// next to the video, preferring formats that can be attached to it
+func ytdlpSubtitleArgs(subtitles *models.DownloadSubtitles) []string {
+	if subtitles == nil {
+		return nil
+	}
+	args := []string{"--write-subs"}
+	if subtitles.Auto {
+		args = append(args, "--write-auto-subs")
+	}
+	if len(subtitles.Languages) > 0 {
+		args = append(args, "--sub-langs", strings.Join(subtitles.Languages, ","))
+	}
+	return append(args, "--sub-format", "vtt/srt/best")
+}
+
+// isSubtitleFile reports whether a downloaded file is a sidecar subtitle file
+func isSubtitleFile(path string) bool {
+	return subtitleExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// downloadedSubtitles returns the subtitle files yt-dlp wrote for the download named
+// name, which it names like name.en.vtt, keyed by path with their language
+func downloadedSubtitles(dir, name string) map[string]string {
+	files, _ := filepath.Glob(filepath.Join(dir, name+".*.*"))
+
+	subtitles := make(map[string]string)
+	for _, path := range files {
+		if !isSubtitleFile(path) {
+			continue
+		}
+		language := strings.TrimPrefix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), name+".")
+		if !languagePattern.MatchString(language) {
+			language = ""
+		}
+		subtitles[path] = language
+	}
+	return subtitles
+}
+
+// importSubtitles attaches the subtitle files downloaded with a video to it. They
+// are moved to where uploaded subtitles are kept, since the downloads directory
+// only keeps files a video is stored as. Failures are logged, the video itself
+// was downloaded fine.
+func (s *DownloadService) importSubtitles(video *models.Video, dir, name string) {
+	subtitles := downloadedSubtitles(dir, name)
+
+	paths := make([]string, 0, len(subtitles))
+	for path := range subtitles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		destPath := s.storage.GetSubtitlePath(uuid.New().String() + strings.ToLower(filepath.Ext(path)))
+		err := os.Rename(path, destPath)
+		if err == nil {
+			if storeErr := s.storage.StoreFile(destPath); storeErr != nil {
+				s.logger.Warn("Failed to store downloaded subtitles", zap.String("file", destPath), zap.Error(storeErr))
+			}
+			if _, err = s.videoService.AddSubtitle(video.ID, filepath.Base(path), destPath, subtitles[path]); err != nil {
+				s.storage.DeleteFile(destPath)
+			}
+		}
+		if err != nil {
+			s.logger.Warn("Failed to attach downloaded subtitles",
+				zap.String("videoId", video.ID),
+				zap.String("file", path),
+				zap.Error(err),
+			)
+		}
+	}
+}
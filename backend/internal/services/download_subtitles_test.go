@@ -0,0 +1,49 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestYtdlpSubtitleArgs(t *testing.T) {
+	if args := ytdlpSubtitleArgs(nil); args != nil {
+		t.Errorf("no subtitles: args = %v, want none", args)
+	}
+
+	got := ytdlpSubtitleArgs(&models.DownloadSubtitles{Languages: []string{"en", "pt-BR"}, Auto: true})
+	want := []string{"--write-subs", "--write-auto-subs", "--sub-langs", "en,pt-BR", "--sub-format", "vtt/srt/best"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %v, want %v", got, want)
+	}
+}
+
+func TestValidateDownloadSubtitles(t *testing.T) {
+	if err := validateDownloadSubtitles(&models.DownloadSubtitles{Languages: []string{"all"}}); err != nil {
+		t.Errorf("all: unexpected error %v", err)
+	}
+	if err := validateDownloadSubtitles(&models.DownloadSubtitles{Languages: []string{"en,--exec"}}); err == nil {
+		t.Error("expected an error for an invalid language")
+	}
+}
+
+func TestDownloadedSubtitles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"video3.mp4", "video3.en.vtt", "video3.pt-BR.srt", "video3.live_chat.json", "video30.en.vtt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := downloadedSubtitles(dir, "video3")
+	want := map[string]string{
+		filepath.Join(dir, "video3.en.vtt"):    "en",
+		filepath.Join(dir, "video3.pt-BR.srt"): "pt-BR",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("downloadedSubtitles() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// md5Pattern matches a hex MD5 checksum
+var md5Pattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// downloadDigests are checksums of a downloaded file as lowercase hex, empty when unknown
+type downloadDigests struct {
+	SHA256 string
+	MD5    string
+}
+
+// normalizeMD5 validates an optional expected MD5 checksum and lowercases it
+func normalizeMD5(sum string) (string, error) {
+	sum = strings.TrimSpace(sum)
+	if sum == "" {
+		return "", nil
+	}
+	if !md5Pattern.MatchString(sum) {
+		return "", fmt.Errorf("md5 must be 32 hex characters")
+	}
+	return strings.ToLower(sum), nil
+}
+
+// responseDigests returns the checksums a server reports for the file it sends, from
+// the Digest (RFC 3230), Content-Digest (RFC 9530) and Content-MD5 headers. They
+// cover the response body, so only a complete response can be checked against them.
+func responseDigests(resp *http.Response) downloadDigests {
+	var digests downloadDigests
+	if resp.StatusCode != http.StatusOK {
+		return digests
+	}
+
+	var fields []string
+	for _, name := range []string{"Digest", "Content-Digest"} {
+		for _, value := range resp.Header.Values(name) {
+			fields = append(fields, strings.Split(value, ",")...)
+		}
+	}
+	if value := resp.Header.Get("Content-MD5"); value != "" {
+		fields = append(fields, "md5="+value)
+	}
+
+	for _, field := range fields {
+		algorithm, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		// Content-Digest wraps the base64 value in colons
+		sum, err := base64.StdEncoding.DecodeString(strings.Trim(value, ":"))
+		if err != nil {
+			continue
+		}
+		switch strings.ToLower(algorithm) {
+		case "sha-256":
+			if len(sum) == sha256.Size {
+				digests.SHA256 = hex.EncodeToString(sum)
+			}
+		case "md5":
+			if len(sum) == md5.Size {
+				digests.MD5 = hex.EncodeToString(sum)
+			}
+		}
+	}
+	return digests
+}
+
+// fileDigests computes the SHA-256 and MD5 of a file in one pass
+func fileDigests(path string) (downloadDigests, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return downloadDigests{}, err
+	}
+	defer file.Close()
+
+	sha, sum := sha256.New(), md5.New()
+	if _, err := io.Copy(io.MultiWriter(sha, sum), file); err != nil {
+		return downloadDigests{}, err
+	}
+	return downloadDigests{SHA256: hex.EncodeToString(sha.Sum(nil)), MD5: hex.EncodeToString(sum.Sum(nil))}, nil
+}
+
+// checkDigests compares the checksums of a file to every expected one that is set
+func checkDigests(actual downloadDigests, expected ...downloadDigests) error {
+	for _, want := range expected {
+		if want.SHA256 != "" && want.SHA256 != actual.SHA256 {
+			return fmt.Errorf("checksum mismatch: sha256 is %s, expected %s", actual.SHA256, want.SHA256)
+		}
+		if want.MD5 != "" && want.MD5 != actual.MD5 {
+			return fmt.Errorf("checksum mismatch: md5 is %s, expected %s", actual.MD5, want.MD5)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Checksums of "hello world"
+const (
+	helloSHA256 = "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	helloMD5    = "5eb63bbbe01eeed093cb22bb8f5acdc3"
+)
+
+func TestNormalizeMD5(t *testing.T) {
+	if got, err := normalizeMD5(" 5EB63BBBE01EEED093CB22BB8F5ACDC3 "); err != nil || got != helloMD5 {
+		t.Errorf("normalizeMD5() = %q, %v", got, err)
+	}
+	if got, err := normalizeMD5(""); err != nil || got != "" {
+		t.Errorf("normalizeMD5(\"\") = %q, %v", got, err)
+	}
+	if _, err := normalizeMD5(helloSHA256); err == nil {
+		t.Error("normalizeMD5() accepted a SHA-256")
+	}
+}
+
+func TestResponseDigests(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("Digest", "SHA-256=uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=, unixsum=30637")
+	resp.Header.Set("Content-MD5", "XrY7u+Ae7tCTyyK7j1rNww==")
+	want := downloadDigests{SHA256: helloSHA256, MD5: helloMD5}
+	if got := responseDigests(resp); got != want {
+		t.Errorf("responseDigests() = %+v, want %+v", got, want)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	resp.Header.Set("Content-Digest", "sha-256=:uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek=:")
+	if got := responseDigests(resp); got.SHA256 != helloSHA256 {
+		t.Errorf("responseDigests() with Content-Digest = %+v", got)
+	}
+
+	// A range response only covers part of the file
+	resp.StatusCode = http.StatusPartialContent
+	if got := responseDigests(resp); got != (downloadDigests{}) {
+		t.Errorf("responseDigests() of a partial response = %+v, want none", got)
+	}
+}
+
+func TestFileDigests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	digests, err := fileDigests(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digests.SHA256 != helloSHA256 || digests.MD5 != helloMD5 {
+		t.Errorf("fileDigests() = %+v", digests)
+	}
+
+	if err := checkDigests(digests, downloadDigests{SHA256: helloSHA256}, downloadDigests{MD5: helloMD5}, downloadDigests{}); err != nil {
+		t.Errorf("checkDigests() = %v", err)
+	}
+	if err := checkDigests(digests, downloadDigests{MD5: "00000000000000000000000000000000"}); err == nil {
+		t.Error("checkDigests() accepted a wrong md5")
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+)
+
+// Errors caused by the request rather than the server. The API answers errors
+// wrapping ErrInvalid with 400, ErrForbidden with 403 and ErrConflict with 409, and
+// their message.
+var (
+	ErrInvalid   = errors.New("invalid request")
+	ErrForbidden = errors.New("not allowed")
+	ErrConflict  = errors.New("conflicts with the current state")
+)
+
+// requestError is an error of the request, of kind ErrInvalid, ErrForbidden,
+// ErrConflict or storage.ErrNotFound, keeping the message of err
+type requestError struct {
+	kind error
+	err  error
+}
+
+func (e *requestError) Error() string   { return e.err.Error() }
+func (e *requestError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// invalidf formats an error of an invalid request
+func invalidf(format string, args ...interface{}) error {
+	return &requestError{kind: ErrInvalid, err: fmt.Errorf(format, args...)}
+}
+
+// invalid marks err, e.g. of a validator of another package, as an error of an
+// invalid request
+func invalid(err error) error {
+	return &requestError{kind: ErrInvalid, err: err}
+}
+
+// forbiddenf formats an error of a request for something the configuration doesn't
+// allow, e.g. importing a file outside the import directories
+func forbiddenf(format string, args ...interface{}) error {
+	return &requestError{kind: ErrForbidden, err: fmt.Errorf(format, args...)}
+}
+
+// notFoundf formats an error of a request for something that doesn't exist, other
+// than records, e.g. a file on the server
+func notFoundf(format string, args ...interface{}) error {
+	return &requestError{kind: storage.ErrNotFound, err: fmt.Errorf(format, args...)}
+}
+
+// conflictf formats an error of a request conflicting with the current state, e.g.
+// retrying an export that is still running
+func conflictf(format string, args ...interface{}) error {
+	return &requestError{kind: ErrConflict, err: fmt.Errorf(format, args...)}
+}
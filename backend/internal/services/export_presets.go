@@ -0,0 +1,307 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// ExportPreset is a named re-encoding target. Applying it to an export request fills
+// in the container and encoding fields the request leaves empty.
+type ExportPreset struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+
+	Format          string `json:"format"` // Output container
+	VideoCodec      string `json:"video_codec"`
+	VideoProfile    string `json:"video_profile,omitempty"`
+	PixelFormat     string `json:"pixel_format,omitempty"`
+	AudioCodec      string `json:"audio_codec"`
+	AudioBitrate    string `json:"audio_bitrate,omitempty"`
+	AudioSampleRate int    `json:"audio_sample_rate,omitempty"`
+
+	// Framing and platform limits
+	AspectRatio string  `json:"aspect_ratio,omitempty"` // Center crop, e.g. "9:16"
+	Width       int     `json:"width,omitempty"`
+	Height      int     `json:"height,omitempty"`
+	MaxDuration float64 `json:"max_duration,omitempty"` // Seconds per output file
+	TargetSize  int64   `json:"target_size,omitempty"`  // Bytes per output file, sets the video bitrate
+}
+
+// exportPresets are the built-in presets, keyed by name
+var exportPresets = map[string]ExportPreset{
+	// Mezzanine codecs for editing in professional NLEs
+	"prores-proxy": {
+		Name:            "prores-proxy",
+		Description:     "Apple ProRes 422 Proxy in MOV with 24-bit PCM audio, for offline editing",
+		Category:        "mezzanine",
+		Format:          "mov",
+		VideoCodec:      "prores_ks",
+		VideoProfile:    "0",
+		PixelFormat:     "yuv422p10le",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+	"prores-lt": {
+		Name:            "prores-lt",
+		Description:     "Apple ProRes 422 LT in MOV with 24-bit PCM audio",
+		Category:        "mezzanine",
+		Format:          "mov",
+		VideoCodec:      "prores_ks",
+		VideoProfile:    "1",
+		PixelFormat:     "yuv422p10le",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+	"prores-422": {
+		Name:            "prores-422",
+		Description:     "Apple ProRes 422 in MOV with 24-bit PCM audio",
+		Category:        "mezzanine",
+		Format:          "mov",
+		VideoCodec:      "prores_ks",
+		VideoProfile:    "2",
+		PixelFormat:     "yuv422p10le",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+	"prores-422-hq": {
+		Name:            "prores-422-hq",
+		Description:     "Apple ProRes 422 HQ in MOV with 24-bit PCM audio",
+		Category:        "mezzanine",
+		Format:          "mov",
+		VideoCodec:      "prores_ks",
+		VideoProfile:    "3",
+		PixelFormat:     "yuv422p10le",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+	"prores-4444": {
+		Name:            "prores-4444",
+		Description:     "Apple ProRes 4444 in MOV with 24-bit PCM audio, keeps alpha channels",
+		Category:        "mezzanine",
+		Format:          "mov",
+		VideoCodec:      "prores_ks",
+		VideoProfile:    "4",
+		PixelFormat:     "yuva444p10le",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+	"dnxhr-lb": {
+		Name:            "dnxhr-lb",
+		Description:     "Avid DNxHR LB in MXF with 24-bit PCM audio, for offline editing",
+		Category:        "mezzanine",
+		Format:          "mxf",
+		VideoCodec:      "dnxhd",
+		VideoProfile:    "dnxhr_lb",
+		PixelFormat:     "yuv422p",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+	"dnxhr-sq": {
+		Name:            "dnxhr-sq",
+		Description:     "Avid DNxHR SQ in MXF with 24-bit PCM audio",
+		Category:        "mezzanine",
+		Format:          "mxf",
+		VideoCodec:      "dnxhd",
+		VideoProfile:    "dnxhr_sq",
+		PixelFormat:     "yuv422p",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+	"dnxhr-hq": {
+		Name:            "dnxhr-hq",
+		Description:     "Avid DNxHR HQ in MXF with 24-bit PCM audio",
+		Category:        "mezzanine",
+		Format:          "mxf",
+		VideoCodec:      "dnxhd",
+		VideoProfile:    "dnxhr_hq",
+		PixelFormat:     "yuv422p",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+	"dnxhr-hqx": {
+		Name:            "dnxhr-hqx",
+		Description:     "Avid DNxHR HQX (10-bit) in MXF with 24-bit PCM audio",
+		Category:        "mezzanine",
+		Format:          "mxf",
+		VideoCodec:      "dnxhd",
+		VideoProfile:    "dnxhr_hqx",
+		PixelFormat:     "yuv422p10le",
+		AudioCodec:      "pcm24",
+		AudioSampleRate: 48000,
+	},
+
+	// Social media platforms
+	"youtube-shorts": {
+		Name:         "youtube-shorts",
+		Description:  "YouTube Shorts: vertical 9:16 1080x1920 H.264, up to 60 seconds",
+		Category:     "social",
+		Format:       "mp4",
+		VideoCodec:   "libx264",
+		AudioCodec:   "aac",
+		AudioBitrate: "192k",
+		AspectRatio:  "9:16",
+		Width:        1080,
+		Height:       1920,
+		MaxDuration:  60,
+	},
+	"instagram-reels": {
+		Name:         "instagram-reels",
+		Description:  "Instagram Reels: vertical 9:16 1080x1920 H.264, up to 90 seconds",
+		Category:     "social",
+		Format:       "mp4",
+		VideoCodec:   "libx264",
+		AudioCodec:   "aac",
+		AudioBitrate: "192k",
+		AspectRatio:  "9:16",
+		Width:        1080,
+		Height:       1920,
+		MaxDuration:  90,
+	},
+	"tiktok": {
+		Name:         "tiktok",
+		Description:  "TikTok: vertical 9:16 1080x1920 H.264, up to 10 minutes",
+		Category:     "social",
+		Format:       "mp4",
+		VideoCodec:   "libx264",
+		AudioCodec:   "aac",
+		AudioBitrate: "192k",
+		AspectRatio:  "9:16",
+		Width:        1080,
+		Height:       1920,
+		MaxDuration:  600,
+	},
+	"twitter": {
+		Name:         "twitter",
+		Description:  "Twitter/X: 1280px wide H.264, up to 2:20",
+		Category:     "social",
+		Format:       "mp4",
+		VideoCodec:   "libx264",
+		AudioCodec:   "aac",
+		AudioBitrate: "128k",
+		Width:        1280,
+		MaxDuration:  140,
+	},
+	"discord": {
+		Name:         "discord",
+		Description:  "Discord: 720p H.264 sized to fit the 25MB upload limit",
+		Category:     "social",
+		Format:       "mp4",
+		VideoCodec:   "libx264",
+		AudioCodec:   "aac",
+		AudioBitrate: "128k",
+		Height:       720,
+		TargetSize:   25 * 1024 * 1024,
+	},
+}
+
+// GetExportPreset returns a preset by name
+func GetExportPreset(name string) (ExportPreset, error) {
+	preset, ok := exportPresets[name]
+	if !ok {
+		return ExportPreset{}, invalidf("unknown export preset: %s", name)
+	}
+
+	return preset, nil
+}
+
+// ListExportPresets returns all presets sorted by category and name
+func ListExportPresets() []ExportPreset {
+	presets := make([]ExportPreset, 0, len(exportPresets))
+	for _, preset := range exportPresets {
+		presets = append(presets, preset)
+	}
+
+	sort.Slice(presets, func(i, j int) bool {
+		if presets[i].Category != presets[j].Category {
+			return presets[i].Category < presets[j].Category
+		}
+		return presets[i].Name < presets[j].Name
+	})
+
+	return presets
+}
+
+// applyExportPreset fills the empty container and encoding fields of a request from
+// its preset. Presets always re-encode, so an unset cut mode becomes "reencode".
+func applyExportPreset(request models.ExportRequest) (models.ExportRequest, error) {
+	if request.ExportPreset == "" {
+		return request, nil
+	}
+
+	preset, err := GetExportPreset(request.ExportPreset)
+	if err != nil {
+		return request, err
+	}
+
+	if request.CutMode == "" {
+		request.CutMode = CutModeReencode
+	}
+	if request.Format == "" {
+		request.Format = preset.Format
+	}
+	if request.VideoCodec == "" {
+		request.VideoCodec = preset.VideoCodec
+	}
+	if request.VideoProfile == "" {
+		request.VideoProfile = preset.VideoProfile
+	}
+	if request.PixelFormat == "" {
+		request.PixelFormat = preset.PixelFormat
+	}
+	if request.AudioCodec == "" {
+		request.AudioCodec = preset.AudioCodec
+	}
+	if request.AudioBitrate == "" {
+		request.AudioBitrate = preset.AudioBitrate
+	}
+	if request.AudioSampleRate == 0 {
+		request.AudioSampleRate = preset.AudioSampleRate
+	}
+	if request.AspectRatio == "" {
+		request.AspectRatio = preset.AspectRatio
+	}
+	if request.Width == 0 && request.Height == 0 {
+		request.Width = preset.Width
+		request.Height = preset.Height
+	}
+	if request.TargetSize == 0 {
+		request.TargetSize = preset.TargetSize
+	}
+
+	return request, nil
+}
+
+// validatePresetLimits checks the exported segments against the duration limit of the
+// request's preset. Merged outputs are checked as a whole, separate files per segment.
+func validatePresetLimits(segments []models.Segment, request models.ExportRequest) error {
+	if request.ExportPreset == "" {
+		return nil
+	}
+
+	preset, err := GetExportPreset(request.ExportPreset)
+	if err != nil || preset.MaxDuration <= 0 {
+		return err
+	}
+
+	total := 0.0
+	for _, seg := range segments {
+		end := segmentEnd(seg)
+		duration := end - seg.Start
+		total += duration
+
+		if (len(segments) == 1 || request.ExportSeparate) && duration > preset.MaxDuration {
+			return invalidf("segment %q is %.1fs long, preset %s allows at most %.0fs", seg.Name, duration, preset.Name, preset.MaxDuration)
+		}
+	}
+
+	if len(segments) > 1 && (request.MergeSegments || !request.ExportSeparate) {
+		total -= float64(len(segments)-1) * request.Crossfade
+		if total > preset.MaxDuration {
+			return invalidf("merged output is %.1fs long, preset %s allows at most %.0fs", total, preset.Name, preset.MaxDuration)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// maxPreviewHeight keeps previews cheap enough to transcode in realtime
+const maxPreviewHeight = 720
+
+// PlanPreview resolves the segments an export of the project would include into a
+// preview of them. Errors are reported before anything is streamed.
+func (s *OperationService) PlanPreview(project *models.Project, segmentIDs []string, height int) (ffmpeg.PreviewOptions, error) {
+	if height == 0 {
+		height = ffmpeg.DefaultPreviewHeight
+	}
+	if height < 144 || height > maxPreviewHeight {
+		return ffmpeg.PreviewOptions{}, fmt.Errorf("preview height must be between 144 and %d", maxPreviewHeight)
+	}
+
+	video, err := s.storage.FetchVideo(project.VideoID)
+	if err != nil {
+		return ffmpeg.PreviewOptions{}, fmt.Errorf("video not found: %w", err)
+	}
+
+	segments, err := selectSegments(project, models.ExportRequest{SegmentIDs: segmentIDs}, s.videoDuration(context.Background(), video))
+	if err != nil {
+		return ffmpeg.PreviewOptions{}, err
+	}
+
+	opts := ffmpeg.PreviewOptions{InputPath: video.FilePath, Height: height}
+	for _, seg := range segments {
+		if end := segmentEnd(seg); end > seg.Start {
+			opts.Segments = append(opts.Segments, ffmpeg.PreviewSegment{Start: seg.Start, End: end})
+		}
+	}
+	if len(opts.Segments) == 0 {
+		return ffmpeg.PreviewOptions{}, fmt.Errorf("no segments to preview")
+	}
+	return opts, nil
+}
+
+// StreamPreview writes a low-resolution preview of the planned segments to w
+func (s *OperationService) StreamPreview(ctx context.Context, opts ffmpeg.PreviewOptions, w io.Writer) error {
+	return s.ffmpeg.StreamPreview(ctx, opts, w)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// ExtractAudioRequest is the body of an audio extraction request
+type ExtractAudioRequest struct {
+	Codec   string `json:"codec,omitempty"`   // "copy" (default) keeps the source encoding, or "mp3", "aac", "flac", "opus", "pcm", "pcm24"
+	Bitrate string `json:"bitrate,omitempty"` // e.g. "192k", for lossy codecs
+	Stream  int    `json:"stream,omitempty"`  // Audio stream, 0 = first
+
+	// With a project of the video, one file per segment instead of the full length
+	ProjectID  string   `json:"project_id,omitempty"`
+	SegmentIDs []string `json:"segment_ids,omitempty"` // If empty, all segments of the project
+}
+
+// audioExtraction is one file of an audio extraction
+type audioExtraction struct {
+	path       string
+	start, end float64
+}
+
+// ExtractAudio writes a video's audio to the outputs directory in the background,
+// in full or one file per project segment. Copied audio goes into a container
+// that fits the source codec: m4a for AAC, mka for most others.
+func (s *OperationService) ExtractAudio(videoID string, project *models.Project, req ExtractAudioRequest) (*models.Operation, error) {
+	codec := req.Codec
+	if codec == "" {
+		codec = "copy"
+	}
+	ext := ffmpeg.AudioCodecExtension(codec)
+	if ext == "" || req.Stream < 0 {
+		return nil, invalidf("unsupported audio codec %q or stream %d", req.Codec, req.Stream)
+	}
+
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	if codec == "copy" {
+		source, err := audioStream(video.Metadata.Streams, req.Stream)
+		if err != nil {
+			return nil, err
+		}
+		ext = ffmpeg.AudioCopyExtension(source.CodecName)
+	}
+
+	duration := s.videoDuration(context.Background(), video)
+	now := time.Now()
+
+	var files []audioExtraction
+	if project == nil {
+		if duration <= 0 {
+			return nil, invalidf("video duration unknown")
+		}
+		namer := newOutputNamer(s.storage.OutputsDir(), models.ExportRequest{}, &models.Project{Name: video.FileName}, video, nil, now)
+		files = append(files, audioExtraction{path: s.storage.GetOutputPath(namer.Suffixed("", ext)), end: duration})
+	} else {
+		if project.VideoID != videoID {
+			return nil, invalidf("project %s does not belong to video %s", project.ID, videoID)
+		}
+		request := models.ExportRequest{SegmentIDs: req.SegmentIDs}
+		segments, err := selectSegments(project, request, duration)
+		if err != nil {
+			return nil, err
+		}
+		if len(segments) == 0 {
+			return nil, invalidf("no segments to extract")
+		}
+		namer := newOutputNamer(s.storage.OutputsDir(), request, project, video, segments, now)
+		for i, seg := range segments {
+			name := namer.Single(seg, ext)
+			if len(segments) > 1 {
+				name = namer.Segment(i, seg, ext)
+			}
+			files = append(files, audioExtraction{path: s.storage.GetOutputPath(name), start: seg.Start, end: segmentEnd(seg)})
+		}
+	}
+
+	operation := &models.Operation{
+		ID:        uuid.New().String(),
+		Type:      models.OperationTypeExtractAudio,
+		ProjectID: req.ProjectID,
+		Status:    models.OperationStatusPending,
+		CreatedAt: now,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.operations[operation.ID] = operation
+	s.cancels[operation.ID] = cancel
+	snapshot := *operation
+	s.mu.Unlock()
+
+	go s.runExtractAudio(ctx, operation, video, files, codec, req, project == nil)
+
+	return &snapshot, nil
+}
+
+// audioStream returns the index-th audio stream (0 = first) of a video
+func audioStream(streams []models.Stream, index int) (models.Stream, error) {
+	n := 0
+	for _, stream := range streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		if n == index {
+			return stream, nil
+		}
+		n++
+	}
+	return models.Stream{}, invalidf("video has no audio stream %d", index)
+}
+
+func (s *OperationService) runExtractAudio(ctx context.Context, operation *models.Operation, video *models.Video, files []audioExtraction, codec string, req ExtractAudioRequest, full bool) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, operation.ID)
+		s.mu.Unlock()
+	}()
+
+	s.mu.Lock()
+	operation.Status = models.OperationStatusProcessing
+	s.mu.Unlock()
+
+	extractCtx := ffmpeg.WithCommandRecorder(ctx, func(command string) {
+		s.mu.Lock()
+		operation.Commands = append(operation.Commands, command)
+		s.mu.Unlock()
+	})
+	extractCtx = ffmpeg.WithStatsRecorder(extractCtx, func(stats ffmpeg.Stats) {
+		s.mu.Lock()
+		operation.Speed = stats.Speed
+		s.mu.Unlock()
+	})
+
+	total := 0.0
+	for _, file := range files {
+		total += file.end - file.start
+	}
+
+	started := time.Now()
+	done := 0.0
+	var outputs []string
+	var err error
+	for i, file := range files {
+		length := file.end - file.start
+		onProgress := func(progress float64) {
+			overall := (done + progress*length) / total
+			s.mu.Lock()
+			operation.Progress = overall * 100
+			operation.ETA = estimateRemaining(time.Since(started), overall)
+			s.mu.Unlock()
+		}
+
+		if full {
+			err = s.ffmpeg.ExtractAudio(extractCtx, video.FilePath, file.path, req.Stream, codec, req.Bitrate, length, onProgress)
+		} else {
+			err = s.ffmpeg.ExportAudio(extractCtx, ffmpeg.AudioExportOptions{
+				Input:      video.FilePath,
+				Output:     file.path,
+				Start:      file.start,
+				End:        file.end,
+				Codec:      codec,
+				Bitrate:    req.Bitrate,
+				Stream:     req.Stream,
+				OnProgress: onProgress,
+			})
+		}
+		outputs = append(outputs, file.path)
+		if err != nil {
+			err = fmt.Errorf("failed to extract audio file %d: %w", i+1, err)
+			break
+		}
+		done += length
+	}
+	if err == nil && ctx.Err() == nil {
+		err = s.storage.StoreFiles(outputs)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	operation.Speed = 0
+	operation.ETA = 0
+	operation.CompletedAt = &now
+
+	switch {
+	case ctx.Err() != nil:
+		removeFiles(outputs)
+		operation.Status = models.OperationStatusCancelled
+		operation.Error = "cancelled"
+		s.logger.Info("Audio extraction cancelled", zap.String("operationId", operation.ID))
+	case err != nil:
+		removeFiles(outputs)
+		operation.Status = models.OperationStatusFailed
+		operation.Error = err.Error()
+		s.logger.Error("Audio extraction failed", zap.String("operationId", operation.ID), zap.Error(err))
+	default:
+		operation.Status = models.OperationStatusCompleted
+		operation.Progress = 100
+		operation.OutputFiles = outputs
+		s.logger.Info("Audio extraction completed",
+			zap.String("operationId", operation.ID),
+			zap.String("videoId", video.ID),
+			zap.Strings("outputs", outputs),
+		)
+	}
+}
+
+// removeFiles deletes the outputs of a failed or cancelled operation
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestAudioStream(t *testing.T) {
+	streams := []models.Stream{
+		{Index: 0, CodecType: "video", CodecName: "h264"},
+		{Index: 1, CodecType: "audio", CodecName: "aac"},
+		{Index: 2, CodecType: "subtitle", CodecName: "mov_text"},
+		{Index: 3, CodecType: "audio", CodecName: "ac3"},
+	}
+
+	stream, err := audioStream(streams, 1)
+	if err != nil {
+		t.Fatalf("audioStream() error = %v", err)
+	}
+	if stream.Index != 3 {
+		t.Errorf("audioStream(1) = stream %d, want 3", stream.Index)
+	}
+
+	if _, err := audioStream(streams, 2); err == nil {
+		t.Error("audioStream(2) expected error for a missing stream")
+	}
+}
+
+func TestExtractAudioRejectsCodec(t *testing.T) {
+	s := &OperationService{}
+	if _, err := s.ExtractAudio("video", nil, ExtractAudioRequest{Codec: "wma"}); err == nil {
+		t.Error("ExtractAudio() expected error for an unsupported codec")
+	}
+	if _, err := s.ExtractAudio("video", nil, ExtractAudioRequest{Stream: -1}); err == nil {
+		t.Error("ExtractAudio() expected error for a negative stream")
+	}
+}
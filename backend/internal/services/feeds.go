@@ -0,0 +1,155 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// feedItem is an entry of a feed
+type feedItem struct {
+	ID       string
+	Title    string
+	URL      string  // What is downloaded: the media enclosure, or the item's page
+	Duration float64 // Seconds, 0 when the feed doesn't say
+}
+
+// rssFeed is an RSS 2.0 feed, including podcast extensions
+type rssFeed struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Items []struct {
+			Title     string `xml:"title"`
+			Link      string `xml:"link"`
+			GUID      string `xml:"guid"`
+			Enclosure struct {
+				URL string `xml:"url,attr"`
+			} `xml:"enclosure"`
+			Duration string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// atomFeed is an Atom feed, which is also the format of YouTube channel feeds
+type atomFeed struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// parseFeed parses an RSS or Atom feed into its title and items, newest first as
+// feeds list them
+func parseFeed(data []byte) (string, []feedItem, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return "", nil, fmt.Errorf("not a feed: %w", err)
+	}
+
+	switch root.XMLName.Local {
+	case "rss":
+		var feed rssFeed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return "", nil, fmt.Errorf("invalid RSS feed: %w", err)
+		}
+
+		items := make([]feedItem, 0, len(feed.Channel.Items))
+		for _, entry := range feed.Channel.Items {
+			item := feedItem{
+				ID:       strings.TrimSpace(entry.GUID),
+				Title:    strings.TrimSpace(entry.Title),
+				URL:      strings.TrimSpace(entry.Enclosure.URL),
+				Duration: parseFeedDuration(entry.Duration),
+			}
+			if item.URL == "" {
+				item.URL = strings.TrimSpace(entry.Link)
+			}
+			if item.ID == "" {
+				item.ID = item.URL
+			}
+			if item.URL != "" {
+				items = append(items, item)
+			}
+		}
+		return strings.TrimSpace(feed.Channel.Title), items, nil
+
+	case "feed":
+		var feed atomFeed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return "", nil, fmt.Errorf("invalid Atom feed: %w", err)
+		}
+
+		items := make([]feedItem, 0, len(feed.Entries))
+		for _, entry := range feed.Entries {
+			item := feedItem{ID: strings.TrimSpace(entry.ID), Title: strings.TrimSpace(entry.Title)}
+			for _, link := range entry.Links {
+				// Podcast media beats the entry page
+				if link.Rel == "enclosure" || (item.URL == "" && (link.Rel == "" || link.Rel == "alternate")) {
+					item.URL = link.Href
+				}
+			}
+			if item.ID == "" {
+				item.ID = item.URL
+			}
+			if item.URL != "" {
+				items = append(items, item)
+			}
+		}
+		return strings.TrimSpace(feed.Title), items, nil
+	}
+
+	return "", nil, fmt.Errorf("unsupported feed format <%s>", root.XMLName.Local)
+}
+
+// parseFeedDuration parses podcast durations: seconds, MM:SS or HH:MM:SS
+func parseFeedDuration(value string) float64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	seconds := 0.0
+	for _, part := range strings.Split(value, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
+
+// youtubeFeedURL returns the feed of a YouTube channel or playlist URL. Other URLs,
+// including feed URLs, are returned unchanged.
+func youtubeFeedURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."), "m.")
+	if host != "youtube.com" {
+		return raw
+	}
+
+	if rest, ok := strings.CutPrefix(u.Path, "/channel/"); ok {
+		if id := strings.Split(rest, "/")[0]; id != "" {
+			return "https://www.youtube.com/feeds/videos.xml?channel_id=" + url.QueryEscape(id)
+		}
+	}
+	if u.Path == "/playlist" {
+		if id := u.Query().Get("list"); id != "" {
+			return "https://www.youtube.com/feeds/videos.xml?playlist_id=" + url.QueryEscape(id)
+		}
+	}
+	return raw
+}
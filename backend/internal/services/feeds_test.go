@@ -0,0 +1,99 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestParseFeed(t *testing.T) {
+	rss := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>Weekly Show</title>
+    <item>
+      <title>Episode 2</title>
+      <guid>ep-2</guid>
+      <link>https://example.com/ep2</link>
+      <enclosure url="https://cdn.example.com/ep2.mp4" type="video/mp4"/>
+      <itunes:duration>1:02:03</itunes:duration>
+    </item>
+    <item>
+      <title>Episode 1</title>
+      <link>https://example.com/ep1</link>
+    </item>
+  </channel>
+</rss>`
+
+	title, items, err := parseFeed([]byte(rss))
+	if err != nil || title != "Weekly Show" || len(items) != 2 {
+		t.Fatalf("parseFeed(rss) = %q, %d items, %v", title, len(items), err)
+	}
+	if items[0] != (feedItem{ID: "ep-2", Title: "Episode 2", URL: "https://cdn.example.com/ep2.mp4", Duration: 3723}) {
+		t.Errorf("rss item with enclosure = %+v", items[0])
+	}
+	if items[1].ID != "https://example.com/ep1" || items[1].URL != "https://example.com/ep1" {
+		t.Errorf("rss item without guid or enclosure = %+v", items[1])
+	}
+
+	atom := `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:yt="http://www.youtube.com/xml/schemas/2015">
+  <title>Some Channel</title>
+  <entry>
+    <id>yt:video:abc</id>
+    <title>New upload</title>
+    <link rel="alternate" href="https://www.youtube.com/watch?v=abc"/>
+  </entry>
+</feed>`
+
+	title, items, err = parseFeed([]byte(atom))
+	if err != nil || title != "Some Channel" || len(items) != 1 {
+		t.Fatalf("parseFeed(atom) = %q, %d items, %v", title, len(items), err)
+	}
+	if items[0].ID != "yt:video:abc" || items[0].URL != "https://www.youtube.com/watch?v=abc" {
+		t.Errorf("atom item = %+v", items[0])
+	}
+
+	if _, _, err := parseFeed([]byte("<html><body>not a feed</body></html>")); err == nil {
+		t.Error("parseFeed(html): want error")
+	}
+}
+
+func TestParseFeedDuration(t *testing.T) {
+	tests := map[string]float64{"": 0, "95": 95, "12:30": 750, "1:00:00": 3600, "n/a": 0}
+	for value, want := range tests {
+		if got := parseFeedDuration(value); got != want {
+			t.Errorf("parseFeedDuration(%q) = %g, want %g", value, got, want)
+		}
+	}
+}
+
+func TestYoutubeFeedURL(t *testing.T) {
+	tests := map[string]string{
+		"https://www.youtube.com/channel/UC123/videos":     "https://www.youtube.com/feeds/videos.xml?channel_id=UC123",
+		"https://m.youtube.com/playlist?list=PL456":        "https://www.youtube.com/feeds/videos.xml?playlist_id=PL456",
+		"https://example.com/podcast.rss":                  "https://example.com/podcast.rss",
+		"https://www.youtube.com/feeds/videos.xml?user=me": "https://www.youtube.com/feeds/videos.xml?user=me",
+	}
+	for raw, want := range tests {
+		if got := youtubeFeedURL(raw); got != want {
+			t.Errorf("youtubeFeedURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestClaimFeedItems(t *testing.T) {
+	s := &SubscriptionService{pending: make(map[string]bool)}
+	subscription := &models.Subscription{ID: "sub", SeenItems: []string{"a"}}
+	// Newest first, as feeds list them
+	items := []feedItem{{ID: "c"}, {ID: "b"}, {ID: "a"}}
+
+	claimed := s.claim(subscription, items)
+	if len(claimed) != 2 || claimed[0].ID != "b" || claimed[1].ID != "c" {
+		t.Fatalf("claim() = %+v, want b and c in publication order", claimed)
+	}
+	// A concurrent check skips the items the first one is downloading
+	if claimed := s.claim(subscription, items); len(claimed) != 0 {
+		t.Errorf("second claim() = %+v, want none", claimed)
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// exportFrames writes the frames of each segment as numbered images and packs them
+// into one zip output, with a folder per segment when there are several
+func (s *OperationService) exportFrames(ctx context.Context, inputPath, workspace string, namer *outputNamer, segments []models.Segment, request models.ExportRequest, onProgress ffmpeg.ProgressCallback) (outputSource, error) {
+	framesDir := filepath.Join(workspace, "frames")
+	// Frames of an earlier attempt may be incomplete
+	if err := os.RemoveAll(framesDir); err != nil {
+		return outputSource{}, fmt.Errorf("failed to clear frames: %w", err)
+	}
+
+	totalDuration := 0.0
+	for _, seg := range segments {
+		totalDuration += segmentEnd(seg) - seg.Start
+	}
+
+	done := 0.0
+	remaining := maxExportFrames
+	for i, seg := range segments {
+		dir := framesDir
+		if len(segments) > 1 {
+			dir = filepath.Join(framesDir, fmt.Sprintf("segment_%02d", i+1))
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return outputSource{}, fmt.Errorf("failed to create frames directory: %w", err)
+		}
+
+		duration := segmentEnd(seg) - seg.Start
+		if err := s.ffmpeg.ExportFrames(ctx, ffmpeg.FrameExportOptions{
+			Input:     inputPath,
+			OutputDir: dir,
+			Start:     seg.Start,
+			End:       segmentEnd(seg),
+			Format:    request.FrameFormat,
+			FPS:       request.FrameFPS,
+			// The source's real frame rate may exceed the one validation assumed,
+			// a frame over the limit shows it was reached
+			MaxFrames: remaining + 1,
+			OnProgress: func(p float64) {
+				if onProgress != nil && totalDuration > 0 {
+					onProgress((done + p*duration) / totalDuration)
+				}
+			},
+		}); err != nil {
+			return outputSource{}, fmt.Errorf("failed to export frames of segment %d: %w", i, err)
+		}
+		done += duration
+
+		written, _ := os.ReadDir(dir)
+		remaining -= len(written)
+		if remaining < 0 {
+			return outputSource{}, fmt.Errorf("export_frames reached the limit of %d frames, set a lower frame_fps", maxExportFrames)
+		}
+	}
+
+	// Only publish the archive once it is complete
+	archivePath := filepath.Join(workspace, "frames.zip")
+	if err := zipDirectory(framesDir, archivePath); err != nil {
+		return outputSource{}, fmt.Errorf("failed to zip frames: %w", err)
+	}
+	outputPath := s.storage.GetOutputPath(namer.Suffixed("_frames", "zip"))
+	if err := os.Rename(archivePath, outputPath); err != nil {
+		return outputSource{}, fmt.Errorf("failed to move frames to output: %w", err)
+	}
+
+	return outputSource{Path: outputPath, Segments: segments}, nil
+}
+
+// zipDirectory writes the files below dir to a zip archive at path. Images are
+// already compressed, so they are stored as is.
+func zipDirectory(dir, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	err = filepath.WalkDir(dir, func(name string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:   filepath.ToSlash(rel),
+			Method: zip.Store,
+		})
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(w, src)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestZipDirectory(t *testing.T) {
+	dir := t.TempDir()
+	frames := filepath.Join(dir, "frames")
+	for _, name := range []string{"segment_01/000001.png", "segment_01/000002.png", "segment_02/000001.png"} {
+		path := filepath.Join(frames, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archivePath := filepath.Join(dir, "frames.zip")
+	if err := zipDirectory(frames, archivePath); err != nil {
+		t.Fatalf("zipDirectory() error = %v", err)
+	}
+
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+	want := []string{"segment_01/000001.png", "segment_01/000002.png", "segment_02/000001.png"}
+	if len(names) != len(want) {
+		t.Fatalf("archive contains %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("archive entry %d = %q, want %q", i, names[i], want[i])
+		}
+	}
+}
+
+func TestValidateFrameExport(t *testing.T) {
+	tests := []struct {
+		name    string
+		request models.ExportRequest
+		wantErr bool
+	}{
+		{"png every frame", models.ExportRequest{ExportFrames: true}, false},
+		{"jpg decimated", models.ExportRequest{ExportFrames: true, FrameFormat: "jpg", FrameFPS: 2}, false},
+		{"format ignored", models.ExportRequest{ExportFrames: true, Format: "png"}, false},
+		{"bad frame format", models.ExportRequest{ExportFrames: true, FrameFormat: "gif"}, true},
+		{"negative fps", models.ExportRequest{ExportFrames: true, FrameFPS: -1}, true},
+		{"audio only", models.ExportRequest{ExportFrames: true, AudioOnly: true}, true},
+		{"merged", models.ExportRequest{ExportFrames: true, MergeSegments: true}, true},
+	}
+	for _, tt := range tests {
+		err := validateExportRequest(tt.request)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateExportRequest() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateFrameCount(t *testing.T) {
+	end := func(t float64) *float64 { return &t }
+	long := []models.Segment{{Start: 0, End: end(120)}, {Start: 200, End: end(300)}}
+	tests := []struct {
+		name     string
+		segments []models.Segment
+		fps      float64
+		wantErr  bool
+	}{
+		{"every frame of a short range", []models.Segment{{Start: 10, End: end(70)}}, 0, false},
+		{"every frame of a long range", long, 0, true},
+		{"decimated long range", long, 2, false},
+		{"too many decimated frames", long, 50, true},
+	}
+	for _, tt := range tests {
+		err := validateFrameCount(tt.segments, models.ExportRequest{ExportFrames: true, FrameFPS: tt.fps})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateFrameCount() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
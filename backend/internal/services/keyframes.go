@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+// loadKeyframeIndex returns the sorted keyframe times of a video. The index is built
+// with a packet scan on first use and stored, so later smart cuts, snapping and the
+// timeline reuse it as long as the video file is unchanged.
+func loadKeyframeIndex(ctx context.Context, store *storage.Manager, executor *ffmpeg.Executor, logger *zap.Logger, video *models.Video) ([]float64, error) {
+	fileHash, err := store.ComputeFileHash(video.FilePath)
+	if err != nil {
+		logger.Warn("Failed to hash video file, keyframe index not cached", zap.String("videoId", video.ID), zap.Error(err))
+		return executor.GetKeyframes(ctx, video.FilePath)
+	}
+
+	index, err := store.GetKeyframeIndex(video.ID, fileHash)
+	if err != nil {
+		logger.Warn("Failed to read keyframe index", zap.String("videoId", video.ID), zap.Error(err))
+	}
+	if index != nil {
+		return index.Keyframes, nil
+	}
+
+	keyframes, err := executor.GetKeyframes(ctx, video.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.SaveKeyframeIndex(&models.KeyframeIndex{
+		VideoID:   video.ID,
+		FileHash:  fileHash,
+		Keyframes: keyframes,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		logger.Warn("Failed to save keyframe index", zap.String("videoId", video.ID), zap.Error(err))
+	}
+	return keyframes, nil
+}
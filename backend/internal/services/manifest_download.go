@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// isManifestURL reports whether a download URL is an HLS (.m3u8) or DASH (.mpd)
+// manifest served over HTTP
+func isManifestURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(u.Path))
+	return ext == ".m3u8" || ext == ".mpd"
+}
+
+// runManifestDownload downloads an HLS or DASH stream with yt-dlp, like any other
+// site, and falls back to FFmpeg if yt-dlp fails. FFmpeg can't honor a format,
+// subtitles, SponsorBlock, extra arguments or a rate limit, so downloads with any of
+// them keep yt-dlp's failure instead of silently losing them.
+func (s *DownloadService) runManifestDownload(ctx context.Context, download *models.Download, req DownloadRequest) {
+	s.runYtdlpDownload(ctx, download, req)
+	if ctx.Err() != nil || s.downloadStatus(download) != models.DownloadStatusFailed {
+		return
+	}
+	if option := ytdlpOnlyOption(s.config, download, req); option != "" {
+		s.logger.Info("Not falling back to FFmpeg, it can't honor the download's "+option, zap.String("id", download.ID))
+		return
+	}
+
+	s.logger.Warn("yt-dlp failed on manifest, falling back to FFmpeg",
+		zap.String("id", download.ID),
+		zap.String("error", download.Error),
+	)
+	outputDir := s.storage.GetDownloadPath()
+	retry := false
+	s.setDownload(download, func() {
+		// A download cancelled in the meantime stays cancelled
+		if download.Status != models.DownloadStatusFailed {
+			return
+		}
+		if download.OutputName != "" {
+			removeYtdlpFiles(outputDir, download.OutputName)
+			download.OutputName = ""
+		}
+		download.Status = models.DownloadStatusDownloading
+		download.Error = ""
+		download.Progress = 0
+		retry = true
+	})
+	if retry {
+		s.runStreamDownload(ctx, download, req)
+	}
+}
+
+// ytdlpOnlyOption returns the option of a download only yt-dlp honors, "" if it has none
+func ytdlpOnlyOption(cfg *config.Config, download *models.Download, req DownloadRequest) string {
+	switch {
+	case req.Format != "":
+		return "format"
+	case len(ytdlpSubtitleArgs(req.Subtitles)) > 0:
+		return "subtitles"
+	case len(download.SponsorBlock) > 0:
+		return "sponsorblock"
+	case len(req.ExtraArgs) > 0:
+		return "extra_args"
+	}
+	if rate, _ := downloadRateLimit(cfg.Download.RateLimit, req.RateLimit); rate > 0 {
+		return "rate_limit"
+	}
+	return ""
+}
+
+// runStreamDownload saves an HLS or DASH stream with FFmpeg, copying its best video
+// variant within ytdlp.max_quality and its best audio stream into an MP4, and
+// imports it. FFmpeg can't continue a partial file, so a resumed download starts over.
+func (s *DownloadService) runStreamDownload(ctx context.Context, download *models.Download, req DownloadRequest) {
+	fail := func(err error) {
+		s.logger.Error("Stream download failed", zap.String("id", download.ID), zap.Error(err))
+		download.Status = models.DownloadStatusFailed
+		download.Error = err.Error()
+		download.Speed = 0
+		download.ETA = 0
+		s.storage.UpdateDownload(download)
+	}
+
+	outputDir := s.storage.GetDownloadPath()
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fail(err)
+		return
+	}
+
+	outputPath := download.FilePath
+	if outputPath == "" {
+		download.Title = s.getTitleFromURL(req.URL)
+		outputPath = filepath.Join(outputDir, s.downloadName(outputDir, download, req, download.Title)+".mp4")
+		download.FilePath = outputPath
+	}
+	download.DownloadedBytes = 0
+	s.storage.UpdateDownload(download)
+
+	// Bytes and speed come from FFmpeg's stats, the share done from the stream's
+	// duration when it has one
+	started := time.Now()
+	lastUpdate := started
+	var lastSize int64
+	var meter speedMeter
+	statsCtx := ffmpeg.WithStatsRecorder(ctx, func(stats ffmpeg.Stats) {
+		if elapsed := time.Since(lastUpdate); elapsed >= 500*time.Millisecond {
+			download.Speed = meter.update(stats.Size-lastSize, elapsed)
+			download.DownloadedBytes = stats.Size
+			lastSize = stats.Size
+			lastUpdate = time.Now()
+			s.storage.UpdateDownload(download)
+		}
+	})
+
+	client := s.client(req)
+	s.logger.Info("Starting stream download", zap.String("id", download.ID), zap.String("url", req.URL))
+	err := s.ffmpeg.DownloadStream(statsCtx, ffmpeg.StreamDownloadOptions{
+		URL:       req.URL,
+		Output:    outputPath,
+		UserAgent: client.UserAgent,
+		Headers:   client.Headers,
+		MaxHeight: maxQualityHeight(s.config.YtDlp.MaxQuality),
+		OnProgress: func(progress float64) {
+			download.Progress = progress * 100
+			if progress > 0 && progress < 1 {
+				elapsed := time.Since(started).Seconds()
+				download.ETA = elapsed * (1 - progress) / progress
+			}
+		},
+	})
+	if err != nil || ctx.Err() != nil {
+		if ctx.Err() != nil {
+			s.logger.Info("Download cancelled", zap.String("id", download.ID))
+			os.Remove(outputPath)
+			download.FilePath = ""
+			download.DownloadedBytes = 0
+			s.storage.UpdateDownload(download)
+			return
+		}
+		os.Remove(outputPath)
+		fail(err)
+		return
+	}
+
+	size, err := s.storage.GetFileSize(outputPath)
+	if err != nil {
+		fail(err)
+		return
+	}
+	download.DownloadedBytes = size
+	download.TotalBytes = size
+	download.Speed = 0
+	download.ETA = 0
+
+	video, err := s.importDownload(filepath.Base(outputPath), outputPath)
+	if err != nil {
+		fail(fmt.Errorf("failed to import video: %w", err))
+		return
+	}
+	video.OriginalURL = download.URL
+	s.createDownloadProject(download, video, download.Title)
+
+	download.VideoID = video.ID
+	download.Status = models.DownloadStatusCompleted
+	download.Progress = 100.0
+	s.storage.UpdateDownload(download)
+	s.storage.RecordUsage(models.UsageDownloads, 1, video.FileSize)
+	s.importChecksum(video)
+
+	s.logger.Info("Stream download completed and imported",
+		zap.String("id", download.ID),
+		zap.String("file", outputPath),
+		zap.String("video_id", video.ID),
+	)
+
+	// Clean up from memory
+	s.mu.Lock()
+	delete(s.downloads, download.ID)
+	s.mu.Unlock()
+}
@@ -0,0 +1,21 @@
+package services
+
+import "testing"
+
+func TestIsManifestURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://cdn.example.com/live/master.m3u8":            true,
+		"https://cdn.example.com/vod/index.M3U8?token=abc":    true,
+		"http://media.example.com/dash/stream.mpd":            true,
+		"https://cdn.example.com/video.mp4":                   false,
+		"https://www.youtube.com/watch?v=abc":                 false,
+		"https://cdn.example.com/player?src=master.m3u8":      false,
+		"ftp://files.example.com/recordings/master.m3u8":      false,
+		"file:///var/lib/losslesscut/downloads/playlist.m3u8": false,
+	}
+	for raw, want := range tests {
+		if got := isManifestURL(raw); got != want {
+			t.Errorf("isManifestURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -3,9 +3,13 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +17,7 @@ import (
 	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
 	"github.com/mifi/lossless-cut/backend/internal/models"
 	"github.com/mifi/lossless-cut/backend/internal/storage"
+	"github.com/mifi/lossless-cut/backend/internal/subtitles"
 	"go.uber.org/zap"
 )
 
@@ -21,7 +26,9 @@ type OperationService struct {
 	config     *config.Config
 	logger     *zap.Logger
 	ffmpeg     *ffmpeg.Executor
+	mu         sync.Mutex // Guards operations and fields updated from concurrent FFmpeg runs
 	operations map[string]*models.Operation
+	cancels    map[string]context.CancelFunc // Stops running exports, by operation ID
 }
 
 func NewOperationService(storage *storage.Manager, cfg *config.Config, logger *zap.Logger) *OperationService {
@@ -29,12 +36,82 @@ func NewOperationService(storage *storage.Manager, cfg *config.Config, logger *z
 		storage:    storage,
 		config:     cfg,
 		logger:     logger,
-		ffmpeg:     ffmpeg.NewExecutor(cfg.FFmpeg.Path, "ffprobe", logger),
+		ffmpeg:     newFFmpegExecutor(cfg, logger),
 		operations: make(map[string]*models.Operation),
+		cancels:    make(map[string]context.CancelFunc),
 	}
 }
 
 func (s *OperationService) Export(project *models.Project, request models.ExportRequest) (*models.Operation, error) {
+	request, err := applyExportPreset(request)
+	if err != nil {
+		return nil, err
+	}
+	// Aliases such as "matroska" become the format's file extension
+	if profile, ok := ffmpeg.GetOutputProfile(request.Format); ok && !request.AudioOnly {
+		request.Format = profile.Format
+	}
+
+	if err := validateExportRequest(request); err != nil {
+		return nil, err
+	}
+	if err := s.checkEncoders(context.Background(), request); err != nil {
+		return nil, err
+	}
+
+	video, err := s.storage.GetVideo(project.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+
+	// Open-ended segments run until the end of the video
+	segments, err := selectSegments(project, request, s.videoDuration(context.Background(), video))
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePresetLimits(segments, request); err != nil {
+		return nil, err
+	}
+	if request.ExportFrames {
+		if err := validateFrameCount(segments, request); err != nil {
+			return nil, err
+		}
+	}
+
+	if burnSubtitles(request) {
+		if _, err := subtitleBurn(video, request); err != nil {
+			return nil, err
+		}
+	}
+	if request.Watermark != nil {
+		if _, err := watermark(video, *request.Watermark); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateCrop(video, request); err != nil {
+		return nil, err
+	}
+
+	// Stream copies need a container that can hold every kept source stream
+	streams, err := selectedStreams(video.Metadata.Streams, request.Streams)
+	if err != nil {
+		return nil, err
+	}
+	if request.Watermark != nil && !hasVideoStream(streams) {
+		return nil, invalidf("a watermark needs a video stream")
+	}
+	selected, err := selectContainer(streams, request)
+	if err != nil {
+		return nil, err
+	}
+	if selected.Format != request.Format {
+		s.logger.Info("Switching export container to fit source streams",
+			zap.String("projectId", project.ID),
+			zap.String("format", selected.Format),
+		)
+	}
+	request = selected
+
 	operation := &models.Operation{
 		ID:        uuid.New().String(),
 		Type:      models.OperationTypeExport,
@@ -44,222 +121,1798 @@ func (s *OperationService) Export(project *models.Project, request models.Export
 		CreatedAt: time.Now(),
 	}
 
-	// Store operation
-	s.operations[operation.ID] = operation
-
-	// Run export in background
-	go s.runExport(operation, project, request)
+	// Dry runs are planned right away and not kept
+	if request.DryRun {
+		s.runExport(operation, project, request)
+		if operation.Status == models.OperationStatusFailed {
+			return nil, errors.New(operation.Error)
+		}
+		return operation, nil
+	}
+
+	// Persist the job so a failed export can be retried from its completed segments
+	if err := s.storage.SaveExportJob(&models.ExportJob{
+		OperationID: operation.ID,
+		Project:     *project,
+		Request:     request,
+		CreatedAt:   operation.CreatedAt,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Store operation
+	s.mu.Lock()
+	s.operations[operation.ID] = operation
+	snapshot := *operation
+	s.mu.Unlock()
+
+	// Run export in background
+	go s.runExport(operation, project, request)
+
+	return &snapshot, nil
+}
+
+// Retry runs a failed export again. Segments completed by the previous attempt are
+// reused from the operation's workspace, so the export resumes where it failed.
+func (s *OperationService) Retry(operationID string) (*models.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Read under the lock, ExpireFailed deletes the workspaces of old failures
+	job, err := s.storage.GetExportJob(operationID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("no resumable export for operation %s: %w", operationID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	operation, exists := s.operations[operationID]
+	if exists && (operation.Status == models.OperationStatusPending || operation.Status == models.OperationStatusProcessing) {
+		return nil, conflictf("operation is still running: %s", operationID)
+	}
+
+	// A job without status is in progress, so a restart during the retry shows as interrupted
+	job.Status = ""
+	job.Error = ""
+	job.FailedAt = nil
+	if err := s.storage.SaveExportJob(job); err != nil {
+		return nil, err
+	}
+	if !exists {
+		// Operations don't survive restarts, recreate it from the job
+		operation = &models.Operation{
+			ID:        job.OperationID,
+			Type:      models.OperationTypeExport,
+			ProjectID: job.Project.ID,
+			CreatedAt: job.CreatedAt,
+		}
+		s.operations[operationID] = operation
+	}
+
+	operation.Status = models.OperationStatusPending
+	operation.Progress = 0
+	operation.Speed = 0
+	operation.ETA = 0
+	operation.Error = ""
+	operation.OutputFiles = nil
+	operation.Quality = nil
+	operation.Warnings = nil
+	operation.Commands = nil
+	operation.CompletedAt = nil
+	snapshot := *operation
+
+	s.logger.Info("Retrying export", zap.String("operationId", operationID))
+
+	go s.runExport(operation, &job.Project, job.Request)
+
+	return &snapshot, nil
+}
+
+// Cancel stops a running export. Its FFmpeg processes are interrupted and its partial
+// outputs removed; the operation shows as cancelled once they have stopped.
+func (s *OperationService) Cancel(operationID string) (*models.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.operations[operationID]; !exists {
+		return nil, notFoundf("operation not found: %s", operationID)
+	}
+	cancel, running := s.cancels[operationID]
+	if !running {
+		return nil, conflictf("operation is not running: %s", operationID)
+	}
+	cancel()
+
+	s.logger.Info("Cancelling operation", zap.String("operationId", operationID))
+	snapshot := *s.operations[operationID]
+	snapshot.Commands = append([]string(nil), snapshot.Commands...)
+	return &snapshot, nil
+}
+
+// cancelExport finishes a cancelled export, removing the files it wrote and its
+// workspace, so it can't be retried or resumed either
+func (s *OperationService) cancelExport(operation *models.Operation, outputs []string) {
+	for _, path := range outputs {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("Failed to remove partial export output", zap.String("path", path), zap.Error(err))
+		}
+		os.Remove(storage.ChecksumSidecarPath(path))
+	}
+	if err := s.storage.DeleteWorkspace(operation.ID); err != nil {
+		s.logger.Warn("Failed to delete export workspace", zap.String("operationId", operation.ID), zap.Error(err))
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	operation.Status = models.OperationStatusCancelled
+	operation.Error = "cancelled"
+	operation.Speed = 0
+	operation.ETA = 0
+	operation.CompletedAt = &now
+	s.mu.Unlock()
+
+	s.logger.Info("Export cancelled", zap.String("operationId", operation.ID), zap.Int("removedFiles", len(outputs)))
+}
+
+// Recover restores the exports of a previous run from their workspaces. Failed exports
+// are listed as failed and unfinished ones as interrupted; with resume set, the
+// interrupted ones are retried from their completed segments.
+func (s *OperationService) Recover(resume bool) {
+	jobs, err := s.storage.ListExportJobs()
+	if err != nil {
+		s.logger.Warn("Failed to list export jobs", zap.Error(err))
+		return
+	}
+
+	for _, job := range jobs {
+		operation := &models.Operation{
+			ID:        job.OperationID,
+			Type:      models.OperationTypeExport,
+			ProjectID: job.Project.ID,
+			Status:    models.OperationStatusInterrupted,
+			Error:     "interrupted by server restart",
+			CreatedAt: job.CreatedAt,
+		}
+		if job.Status == models.OperationStatusFailed {
+			operation.Status = models.OperationStatusFailed
+			operation.Error = job.Error
+		}
+
+		s.mu.Lock()
+		s.operations[operation.ID] = operation
+		s.mu.Unlock()
+
+		if operation.Status != models.OperationStatusInterrupted {
+			continue
+		}
+		s.logger.Info("Found interrupted export", zap.String("operationId", operation.ID), zap.Bool("resume", resume))
+		if resume {
+			if _, err := s.Retry(operation.ID); err != nil {
+				s.logger.Warn("Failed to resume export", zap.String("operationId", operation.ID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Running reports whether an export or another operation is in progress
+func (s *OperationService) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.cancels) > 0
+}
+
+// ExpireFailed deletes the workspaces of exports that failed more than
+// storage.failed_retention_days ago. Their operations stay listed as failed but
+// can't be retried anymore.
+func (s *OperationService) ExpireFailed() {
+	if s.config.Storage.FailedRetentionDays <= 0 {
+		return
+	}
+
+	// Under the lock, so a retry doesn't lose its workspace while it starts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired, err := s.storage.ExpireFailedWorkspaces(time.Duration(s.config.Storage.FailedRetentionDays) * 24 * time.Hour)
+	if err != nil {
+		s.logger.Warn("Failed to expire failed exports", zap.Error(err))
+		return
+	}
+	if len(expired) > 0 {
+		s.logger.Info("Deleted workspaces of expired failed exports", zap.Strings("operationIds", expired))
+	}
+}
+
+// saveJobFailure records the failure of an export in its job, so it isn't taken for
+// an interrupted export on the next startup
+func (s *OperationService) saveJobFailure(operation *models.Operation) {
+	job, err := s.storage.GetExportJob(operation.ID)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	job.Status = models.OperationStatusFailed
+	job.Error = operation.Error
+	job.FailedAt = &now
+	if err := s.storage.SaveExportJob(job); err != nil {
+		s.logger.Warn("Failed to save export job status", zap.String("operationId", operation.ID), zap.Error(err))
+	}
+}
+
+// failExport marks an export failed. Operations are only updated under the lock,
+// GetOperation and Cancel read them while the export runs.
+func (s *OperationService) failExport(operation *models.Operation, message string) {
+	s.mu.Lock()
+	operation.Status = models.OperationStatusFailed
+	operation.Error = message
+	operation.Speed = 0
+	operation.ETA = 0
+	s.mu.Unlock()
+}
+
+func (s *OperationService) runExport(operation *models.Operation, project *models.Project, request models.ExportRequest) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	operation.Status = models.OperationStatusProcessing
+	s.cancels[operation.ID] = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, operation.ID)
+		s.mu.Unlock()
+		cancel()
+	}()
+	defer func() {
+		s.mu.Lock()
+		failed := *operation
+		s.mu.Unlock()
+		if failed.Status == models.OperationStatusFailed {
+			s.saveJobFailure(&failed)
+		}
+	}()
+
+	// Get actual video file path from metadata
+	video, err := s.storage.FetchVideo(project.VideoID)
+	if err != nil {
+		s.failExport(operation, fmt.Sprintf("video not found: %v", err))
+		s.logger.Error("Failed to get video for export",
+			zap.String("videoId", project.VideoID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	inputPath := video.FilePath
+	s.logger.Info("Starting export",
+		zap.String("operationId", operation.ID),
+		zap.String("inputPath", inputPath),
+		zap.String("videoId", project.VideoID),
+		zap.Bool("mergeSegments", request.MergeSegments),
+		zap.Bool("exportSeparate", request.ExportSeparate),
+		zap.String("cutMode", cutMode(request)),
+	)
+
+	// Determine segments to export
+	segments, err := selectSegments(project, request, s.videoDuration(ctx, video))
+	if err != nil {
+		s.failExport(operation, err.Error())
+		return
+	}
+
+	if len(segments) == 0 {
+		s.failExport(operation, "no segments to export")
+		return
+	}
+
+	// Output file names, dated by the original attempt so retries produce the same names
+	namer := newOutputNamer(s.storage.OutputsDir(), request, project, video, segments, operation.CreatedAt)
+
+	// Completed intermediate artifacts are kept here until the export succeeds
+	workspace, err := s.storage.CreateWorkspace(operation.ID)
+	if err != nil {
+		s.failExport(operation, err.Error())
+		return
+	}
+
+	format := request.Format
+	if format == "" {
+		format = "mp4"
+	}
+
+	// Progress callback, estimating the remaining time from the rate so far
+	started := time.Now()
+	onProgress := func(progress float64) {
+		s.mu.Lock()
+		operation.Progress = progress * 100
+		operation.ETA = estimateRemaining(time.Since(started), progress)
+		s.mu.Unlock()
+		s.logger.Debug("Export progress",
+			zap.String("operationId", operation.ID),
+			zap.Float64("progress", progress*100),
+		)
+	}
+
+	var outputFiles []string
+	var outputSources []outputSource // Video outputs
+	var audioSources []outputSource
+	var manifestFiles []models.ManifestFile
+	var exportErr error
+
+	// Export commands get the extra arguments and are recorded on the operation
+	exportCtx := ffmpeg.WithCommandRecorder(ffmpeg.WithExtraArgs(ctx, exportArgs(request)), func(command string) {
+		s.mu.Lock()
+		operation.Commands = append(operation.Commands, command)
+		s.mu.Unlock()
+	})
+	exportCtx = ffmpeg.WithStatsRecorder(exportCtx, func(stats ffmpeg.Stats) {
+		s.mu.Lock()
+		operation.Speed = stats.Speed
+		s.mu.Unlock()
+	})
+
+	// Files written so far, removed if the export is cancelled
+	var written []string
+	exportCtx = ffmpeg.WithOutputRecorder(exportCtx, func(path string) {
+		s.mu.Lock()
+		written = append(written, path)
+		s.mu.Unlock()
+	})
+	// Smart cuts look keyframes up in the video's index instead of probing per segment
+	if cutMode(request) == CutModeSmart {
+		if keyframes, err := loadKeyframeIndex(ctx, s.storage, s.ffmpeg, s.logger, video); err != nil {
+			s.logger.Warn("Failed to load keyframe index", zap.String("videoId", video.ID), zap.Error(err))
+		} else {
+			exportCtx = ffmpeg.WithKeyframes(exportCtx, inputPath, keyframes)
+		}
+	}
+	if burnSubtitles(request) {
+		burn, err := subtitleBurn(video, request)
+		if err != nil {
+			// The sidecar may have been removed since the export was queued
+			s.failExport(operation, err.Error())
+			s.logger.Error("Failed to resolve subtitles to burn in", zap.String("videoId", video.ID), zap.Error(err))
+			return
+		}
+		exportCtx = ffmpeg.WithSubtitleBurn(exportCtx, burn)
+	}
+	if request.Watermark != nil {
+		mark, err := watermark(video, *request.Watermark)
+		if err != nil {
+			s.failExport(operation, err.Error())
+			s.logger.Error("Failed to resolve watermark", zap.String("videoId", video.ID), zap.Error(err))
+			return
+		}
+		exportCtx = ffmpeg.WithWatermark(exportCtx, mark)
+	}
+	if !request.Streams.IsZero() {
+		exportCtx = ffmpeg.WithStreams(exportCtx, request.Streams.Indexes, request.Streams.Shortcut)
+	}
+	if len(request.Metadata) > 0 {
+		exportCtx = ffmpeg.WithMetadataTags(exportCtx, request.Metadata)
+	}
+	if request.Rotation != nil {
+		exportCtx = ffmpeg.WithRotation(exportCtx, *request.Rotation)
+	}
+	switch {
+	case request.PrivacyMode:
+		exportCtx = ffmpeg.WithPrivacy(exportCtx)
+	case request.StripMetadata:
+		exportCtx = ffmpeg.WithoutMetadata(exportCtx)
+	}
+	// A dry run only removes files it created: its placeholders, and outputs they
+	// were moved to that weren't there before
+	var placeholders []string
+	existingOutputs := make(map[string]bool)
+	if request.DryRun {
+		if entries, err := os.ReadDir(s.storage.OutputsDir()); err == nil {
+			for _, entry := range entries {
+				existingOutputs[entry.Name()] = true
+			}
+		}
+		exportCtx = ffmpeg.WithDryRun(exportCtx, func(command ffmpeg.PlannedCommand) {
+			s.mu.Lock()
+			operation.Plan = append(operation.Plan, models.PlannedCommand{Args: command.Args, ConcatList: command.ConcatList})
+			if command.Placeholder != "" {
+				placeholders = append(placeholders, command.Placeholder)
+			}
+			s.mu.Unlock()
+		})
+	}
+
+	// Handle different export modes
+	if request.ExportFrames {
+		// Image sequences are not media outputs, so they skip output validation
+		var frames outputSource
+		frames, exportErr = s.exportFrames(exportCtx, inputPath, workspace, namer, segments, request, onProgress)
+		if exportErr == nil {
+			outputFiles = append(outputFiles, frames.Path)
+			manifestFiles = append(manifestFiles, manifestFile(frames, "frames"))
+		}
+	} else if request.AudioOnly {
+		// Audio-only outputs (not compared by quality verification, which is video-based)
+		audioSources, exportErr = s.exportAudio(exportCtx, inputPath, workspace, namer, segments, request, onProgress)
+		for _, source := range audioSources {
+			outputFiles = append(outputFiles, source.Path)
+			manifestFiles = append(manifestFiles, manifestFile(source, "audio"))
+		}
+	} else if len(segments) == 1 {
+		// Single segment - just cut it
+		seg := segments[0]
+		outputPath := s.storage.GetOutputPath(namer.Single(seg, format))
+		end := segmentEnd(seg)
+		exportErr = s.cutSegment(exportCtx, inputPath, outputPath, seg.Start, end, request, onProgress)
+		if exportErr == nil {
+			outputFiles = append(outputFiles, outputPath)
+			outputSources = append(outputSources, outputSource{Path: outputPath, Segments: segments})
+		}
+	} else {
+		// Multiple segments
+		if request.MergeSegments {
+			// Export merged file
+			mergedPath := s.storage.GetOutputPath(namer.Merged(segments, format, true))
+			exportErr = s.exportMergedSegments(exportCtx, inputPath, workspace, mergedPath, segments, request, onProgress)
+			if exportErr == nil {
+				outputFiles = append(outputFiles, mergedPath)
+				outputSources = append(outputSources, outputSource{Path: mergedPath, Segments: segments, Overlap: request.Crossfade})
+			}
+		}
+
+		if request.ExportSeparate && exportErr == nil {
+			// Export each segment separately
+			separateFiles, err := s.exportMultipleSegments(exportCtx, inputPath, workspace, namer, format, segments, request, onProgress)
+			if err != nil {
+				exportErr = err
+			} else {
+				outputFiles = append(outputFiles, separateFiles...)
+				for i, file := range separateFiles {
+					outputSources = append(outputSources, outputSource{Path: file, Segments: segments[i : i+1]})
+				}
+			}
+		}
+
+		// Handle chapters export, which a dry run doesn't write at all
+		if request.ExportChapters && exportErr == nil && !request.DryRun {
+			chaptersPath := s.storage.GetOutputPath(namer.Suffixed("_chapters", request.ChaptersFormat))
+			err := s.exportChapters(exportCtx, chaptersPath, segments)
+			if err != nil {
+				exportErr = err
+			} else {
+				outputFiles = append(outputFiles, chaptersPath)
+				manifestFiles = append(manifestFiles, manifestFile(outputSource{Path: chaptersPath, Segments: segments}, "chapters"))
+			}
+		}
+
+		// If neither merge nor separate was specified, default to merge
+		if !request.MergeSegments && !request.ExportSeparate && !request.ExportChapters {
+			mergedPath := s.storage.GetOutputPath(namer.Merged(segments, format, false))
+			exportErr = s.exportMergedSegments(exportCtx, inputPath, workspace, mergedPath, segments, request, onProgress)
+			if exportErr == nil {
+				outputFiles = append(outputFiles, mergedPath)
+				outputSources = append(outputSources, outputSource{Path: mergedPath, Segments: segments, Overlap: request.Crossfade})
+			}
+		}
+	}
+
+	if request.DryRun {
+		for _, path := range outputFiles {
+			if filepath.Dir(path) == s.storage.OutputsDir() && !existingOutputs[filepath.Base(path)] {
+				placeholders = append(placeholders, path)
+			}
+		}
+		s.finishDryRun(operation, placeholders, exportErr)
+		return
+	}
+	if ctx.Err() != nil {
+		s.cancelExport(operation, append(written, outputFiles...))
+		return
+	}
+	if exportErr != nil {
+		s.failExport(operation, exportErr.Error())
+		s.logger.Error("Export failed",
+			zap.String("operationId", operation.ID),
+			zap.Error(exportErr),
+		)
+		return
+	}
+
+	for _, source := range outputSources {
+		manifestFiles = append(manifestFiles, manifestFile(source, "video"))
+	}
+
+	// Optional subtitle files matching each video output
+	if request.CutSubtitles {
+		for _, source := range s.cutSubtitles(ctx, video, outputSources) {
+			outputFiles = append(outputFiles, source.Path)
+			manifestFiles = append(manifestFiles, manifestFile(source, "subtitles"))
+		}
+	}
+
+	// Optional checksums, saved next to each output and listed in the manifest
+	if s.config.Storage.ComputeChecksums {
+		sums := s.checksumOutputs(outputFiles)
+		for i := range manifestFiles {
+			manifestFiles[i].SHA256 = sums[s.storage.GetOutputPath(manifestFiles[i].File)]
+		}
+	}
+
+	// Manifest describing what each output contains, not an output itself
+	manifestPath := s.storage.GetOutputPath(namer.Suffixed("_manifest", "json"))
+	if err := s.writeManifest(manifestPath, operation, project, video, manifestFiles); err != nil {
+		s.logger.Warn("Failed to write export manifest", zap.String("path", manifestPath), zap.Error(err))
+		manifestPath = ""
+	} else if s.config.Storage.ComputeChecksums {
+		s.checksumOutputs([]string{manifestPath})
+	}
+
+	// Optional post-export quality verification
+	if request.VerifyQuality {
+		quality := s.verifyQuality(ctx, inputPath, outputSources, request.QualityMetric)
+		s.mu.Lock()
+		operation.Quality = quality
+		s.mu.Unlock()
+	}
+
+	// Check the outputs are readable and have the expected length and streams
+	sourceDuration := 0.0
+	sourceHasVideo, sourceHasAudio := true, true
+	if probe, err := s.ffmpeg.Probe(ctx, inputPath); err == nil {
+		sourceDuration, _ = probe.GetDuration()
+		sourceHasVideo = len(probe.GetVideoStreams()) > 0
+		sourceHasAudio = len(probe.GetAudioStreams()) > 0
+	}
+	mode := cutMode(request)
+	warnings := s.validateOutputs(ctx, outputSources, sourceDuration, mode, sourceHasVideo, sourceHasAudio)
+	warnings = append(warnings, s.validateOutputs(ctx, audioSources, sourceDuration, mode, false, true)...)
+
+	// Outputs kept in the media store can be downloaded from any instance
+	stored := append([]string(nil), outputFiles...)
+	if manifestPath != "" {
+		stored = append(stored, manifestPath)
+	}
+
+	if ctx.Err() != nil {
+		s.cancelExport(operation, append(written, stored...))
+		return
+	}
+
+	if err := s.storage.StoreFiles(stored); err != nil {
+		warnings = append(warnings, err.Error())
+	}
+	if err := s.storage.DeleteWorkspace(operation.ID); err != nil {
+		s.logger.Warn("Failed to delete export workspace", zap.String("operationId", operation.ID), zap.Error(err))
+	}
+
+	// Success
+	now := time.Now()
+	s.mu.Lock()
+	operation.Status = models.OperationStatusCompleted
+	if len(warnings) > 0 {
+		operation.Status = models.OperationStatusCompletedWithWarnings
+		operation.Warnings = warnings
+		s.logger.Warn("Export outputs failed validation",
+			zap.String("operationId", operation.ID),
+			zap.Strings("warnings", warnings),
+		)
+	}
+	operation.Progress = 100
+	operation.Speed = 0
+	operation.ETA = 0
+	operation.CompletedAt = &now
+	operation.OutputFiles = outputFiles
+	operation.Manifest = manifestPath
+	s.mu.Unlock()
+
+	var outputBytes int64
+	for _, path := range outputFiles {
+		if size, err := s.storage.GetFileSize(path); err == nil {
+			outputBytes += size
+		}
+	}
+	s.storage.RecordUsage(models.UsageExports, 1, outputBytes)
+
+	s.logger.Info("Export completed",
+		zap.String("operationId", operation.ID),
+		zap.Int("outputFilesCount", len(outputFiles)),
+		zap.Strings("outputFiles", outputFiles),
+	)
+}
+
+// finishDryRun removes the files a dry run export created and completes its
+// operation with the planned commands
+func (s *OperationService) finishDryRun(operation *models.Operation, placeholders []string, exportErr error) {
+	for _, path := range placeholders {
+		os.Remove(path)
+	}
+	if err := s.storage.DeleteWorkspace(operation.ID); err != nil {
+		s.logger.Warn("Failed to delete dry run workspace", zap.String("operationId", operation.ID), zap.Error(err))
+	}
+
+	if exportErr != nil {
+		s.failExport(operation, exportErr.Error())
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	operation.Status = models.OperationStatusCompleted
+	operation.Progress = 100
+	operation.CompletedAt = &now
+	s.mu.Unlock()
+	s.logger.Info("Planned export",
+		zap.String("operationId", operation.ID),
+		zap.Int("commands", len(operation.Plan)),
+	)
+}
+
+// estimateRemaining returns the seconds an operation needs to finish at the rate it
+// made progress so far, or 0 while too little is done to tell
+func estimateRemaining(elapsed time.Duration, progress float64) float64 {
+	if progress < 0.01 || progress >= 1 {
+		return 0
+	}
+	return math.Round(elapsed.Seconds() * (1 - progress) / progress)
+}
+
+// selectSegments returns the project segments an export request covers. Open-ended
+// segments are resolved to end at duration, the length of the video.
+func selectSegments(project *models.Project, request models.ExportRequest, duration float64) ([]models.Segment, error) {
+	filteredSegments := []models.Segment{}
+	for _, seg := range project.Segments {
+		selected := len(request.SegmentIDs) == 0
+		for _, id := range request.SegmentIDs {
+			if seg.ID == id {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			continue
+		}
+
+		if seg.End == nil {
+			if duration <= 0 {
+				return nil, invalidf("segment %q has no end and the video duration is unknown", seg.Name)
+			}
+			end := duration
+			seg.End = &end
+		}
+		filteredSegments = append(filteredSegments, seg)
+	}
+	return filteredSegments, nil
+}
+
+// segmentEnd returns the end of a segment. Segments from selectSegments always have
+// an end; an unresolved open-ended segment is treated as empty.
+func segmentEnd(seg models.Segment) float64 {
+	if seg.End == nil {
+		return seg.Start
+	}
+	return *seg.End
+}
+
+// videoDuration returns the length of a video, probing the file when it wasn't
+// recorded at upload. It returns 0 when the duration can't be determined.
+func (s *OperationService) videoDuration(ctx context.Context, video *models.Video) float64 {
+	if video.Duration > 0 {
+		return video.Duration
+	}
+
+	probe, err := s.ffmpeg.Probe(ctx, video.FilePath)
+	if err != nil {
+		s.logger.Warn("Failed to probe video duration", zap.String("videoId", video.ID), zap.Error(err))
+		return 0
+	}
+	duration, _ := probe.GetDuration()
+	return duration
+}
+
+// exportAudio exports only the audio of the segments, either merged into one file or
+// one file per segment, following the same merge/separate rules as video exports
+func (s *OperationService) exportAudio(ctx context.Context, inputPath, workspace string, namer *outputNamer, segments []models.Segment, request models.ExportRequest, onProgress ffmpeg.ProgressCallback) ([]outputSource, error) {
+	codec := request.AudioCodec
+	if codec == "" {
+		codec = "mp3"
+	}
+
+	format := request.Format
+	if format == "" {
+		format = ffmpeg.AudioCodecExtension(codec)
+	}
+	if format == "" {
+		return nil, fmt.Errorf("unsupported audio codec: %s", codec)
+	}
+
+	var outputs []outputSource
+
+	separate := len(segments) == 1 || request.ExportSeparate
+	merge := len(segments) > 1 && (request.MergeSegments || !request.ExportSeparate)
+
+	if merge {
+		// Cut each segment to lossless FLAC pieces, then encode once while merging
+		tempFiles := make([]string, len(segments))
+
+		totalDuration := 0.0
+		for i, seg := range segments {
+			tempFile := filepath.Join(workspace, fmt.Sprintf("audio_%d.flac", i))
+			tempFiles[i] = tempFile
+
+			end := segmentEnd(seg)
+			totalDuration += end - seg.Start
+
+			if err := s.producePiece(tempFile, func(partialPath string) error {
+				return s.ffmpeg.ExportAudio(ctx, ffmpeg.AudioExportOptions{
+					Input:  inputPath,
+					Output: partialPath,
+					Start:  seg.Start,
+					End:    end,
+					Codec:  "flac",
+				})
+			}); err != nil {
+				return nil, fmt.Errorf("failed to extract audio of segment %d: %w", i, err)
+			}
+
+			if request.NormalizeAudio {
+				normalizedFile := filepath.Join(workspace, fmt.Sprintf("audio_%d_normalized.flac", i))
+				if err := s.producePiece(normalizedFile, func(partialPath string) error {
+					return s.normalizePiece(ctx, tempFile, partialPath, "flac", request)
+				}); err != nil {
+					return nil, fmt.Errorf("failed to normalize audio of segment %d: %w", i, err)
+				}
+				tempFiles[i] = normalizedFile
+			}
+		}
+
+		// The FLAC pieces can't be copied as the source audio, so it is encoded again in
+		// the source's codec
+		mergeCodec, mergeFormat := codec, format
+		if codec == "copy" {
+			mergeCodec = s.sourceAudioCodec(ctx, inputPath)
+			if request.Format == "" {
+				mergeFormat = ffmpeg.AudioCodecExtension(mergeCodec)
+			}
+		}
+
+		mergedPath := s.storage.GetOutputPath(namer.Merged(segments, mergeFormat, request.ExportSeparate))
+		if err := s.ffmpeg.MergeAudio(ctx, tempFiles, mergedPath, mergeCodec, request.AudioBitrate, totalDuration, onProgress); err != nil {
+			return nil, fmt.Errorf("failed to merge audio: %w", err)
+		}
+		outputs = append(outputs, outputSource{Path: mergedPath, Segments: segments})
+	}
+
+	if separate {
+		for i, seg := range segments {
+			outputPath := s.storage.GetOutputPath(namer.Single(seg, format))
+			if len(segments) > 1 {
+				outputPath = s.storage.GetOutputPath(namer.Segment(i, seg, format))
+			}
+
+			end := segmentEnd(seg)
+
+			if err := s.ffmpeg.ExportAudio(ctx, ffmpeg.AudioExportOptions{
+				Input:      inputPath,
+				Output:     outputPath,
+				Start:      seg.Start,
+				End:        end,
+				Codec:      codec,
+				Bitrate:    request.AudioBitrate,
+				OnProgress: onProgress,
+			}); err != nil {
+				return outputs, fmt.Errorf("failed to export audio of segment %d: %w", i, err)
+			}
+
+			outputs = append(outputs, outputSource{Path: outputPath, Segments: segments[i : i+1]})
+		}
+	}
+
+	return outputs, nil
+}
+
+// outputSource maps an exported file to the source segments it contains, in order
+type outputSource struct {
+	Path     string
+	Segments []models.Segment
+	Overlap  float64 // Seconds consecutive segments overlap by (crossfade merges)
+}
+
+// verifyQuality compares each exported segment against the same range of the source.
+// Failures are recorded per segment and never fail the export itself.
+func (s *OperationService) verifyQuality(ctx context.Context, inputPath string, sources []outputSource, metric string) []models.QualityResult {
+	opts := ffmpeg.QualityOptions{
+		SSIM: metric == "" || metric == ffmpeg.QualitySSIM || metric == ffmpeg.QualityBoth,
+		VMAF: metric == ffmpeg.QualityVMAF || metric == ffmpeg.QualityBoth,
+	}
+
+	var results []models.QualityResult
+	for _, source := range sources {
+		// Segments are concatenated in order, so each one starts where the previous ended
+		// (minus any crossfade overlap)
+		offset := 0.0
+		for _, seg := range source.Segments {
+			end := segmentEnd(seg)
+
+			opts.ReferenceStart = seg.Start
+			opts.DistortedStart = offset
+			opts.Duration = end - seg.Start
+			offset += opts.Duration - source.Overlap
+
+			result := models.QualityResult{
+				OutputFile: source.Path,
+				SegmentID:  seg.ID,
+			}
+
+			metrics, err := s.ffmpeg.CompareQuality(ctx, inputPath, source.Path, opts)
+			if err != nil {
+				s.logger.Warn("Quality verification failed",
+					zap.String("outputFile", source.Path),
+					zap.String("segmentId", seg.ID),
+					zap.Error(err),
+				)
+				result.Error = err.Error()
+			} else {
+				result.SSIM = metrics.SSIM
+				result.VMAF = metrics.VMAF
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// subtitleTrack is a text subtitle track of the source video
+type subtitleTrack struct {
+	Label string // Language, or track identifier when unknown
+	Cues  []subtitles.Cue
+}
+
+// loadSubtitleTracks converts the video's embedded text subtitle streams and sidecar
+// subtitle files to cues. Tracks that fail to convert are skipped.
+func (s *OperationService) loadSubtitleTracks(ctx context.Context, video *models.Video) []subtitleTrack {
+	var tracks []subtitleTrack
+
+	load := func(input string, streamIndex int, label string) {
+		tempPath := s.storage.GetTempPath(fmt.Sprintf("subtitles_%s.srt", uuid.New().String()))
+		defer s.storage.DeleteFile(tempPath)
+
+		if err := s.ffmpeg.ExtractSubtitles(ctx, input, streamIndex, tempPath); err != nil {
+			s.logger.Warn("Failed to convert subtitles",
+				zap.String("input", input),
+				zap.Int("streamIndex", streamIndex),
+				zap.Error(err),
+			)
+			return
+		}
+
+		data, err := os.ReadFile(tempPath)
+		if err != nil {
+			s.logger.Warn("Failed to read converted subtitles", zap.String("path", tempPath), zap.Error(err))
+			return
+		}
+
+		tracks = append(tracks, subtitleTrack{Label: label, Cues: subtitles.ParseSRT(string(data))})
+	}
+
+	for _, stream := range video.Metadata.Streams {
+		if stream.CodecType != "subtitle" || !ffmpeg.IsTextSubtitleCodec(stream.CodecName) {
+			continue
+		}
+		label := stream.Language
+		if label == "" || label == "und" {
+			label = fmt.Sprintf("track%d", stream.Index)
+		}
+		load(video.FilePath, stream.Index, label)
+	}
+
+	for i, sidecar := range video.Subtitles {
+		label := sidecar.Language
+		if label == "" {
+			label = fmt.Sprintf("sidecar%d", i+1)
+		}
+		load(sidecar.FilePath, 0, label)
+	}
+
+	// Keep labels unique so tracks don't overwrite each other's files
+	seen := make(map[string]int)
+	for i := range tracks {
+		seen[tracks[i].Label]++
+		if n := seen[tracks[i].Label]; n > 1 {
+			tracks[i].Label = fmt.Sprintf("%s.%d", tracks[i].Label, n)
+		}
+	}
+
+	return tracks
+}
+
+// cutSubtitles writes an .srt file per subtitle track next to each video output,
+// holding only the cues inside the output's segments re-timed to the output timeline.
+// Failures are logged and never fail the export itself.
+func (s *OperationService) cutSubtitles(ctx context.Context, video *models.Video, sources []outputSource) []outputSource {
+	if len(sources) == 0 {
+		return nil
+	}
+
+	tracks := s.loadSubtitleTracks(ctx, video)
+	if len(tracks) == 0 {
+		s.logger.Info("No text subtitles to cut", zap.String("videoId", video.ID))
+		return nil
+	}
+
+	var files []outputSource
+	for _, source := range sources {
+		// Segments are concatenated in order, so each one starts where the previous ended
+		var ranges []subtitles.Range
+		offset := 0.0
+		for _, seg := range source.Segments {
+			end := segmentEnd(seg)
+			ranges = append(ranges, subtitles.Range{Start: seg.Start, End: end, Offset: offset})
+			offset += end - seg.Start - source.Overlap
+		}
+
+		basePath := strings.TrimSuffix(source.Path, filepath.Ext(source.Path))
+		for _, track := range tracks {
+			cues := subtitles.Clip(track.Cues, ranges)
+			if len(cues) == 0 {
+				continue
+			}
+
+			path := fmt.Sprintf("%s.%s.srt", basePath, track.Label)
+			if err := os.WriteFile(path, []byte(subtitles.FormatSRT(cues)), 0644); err != nil {
+				s.logger.Warn("Failed to write subtitles", zap.String("path", path), zap.Error(err))
+				continue
+			}
+			files = append(files, outputSource{Path: path, Segments: source.Segments, Overlap: source.Overlap})
+		}
+	}
+
+	return files
+}
+
+// manifestFile describes an output for the export manifest
+func manifestFile(source outputSource, fileType string) models.ManifestFile {
+	file := models.ManifestFile{
+		File:     filepath.Base(source.Path),
+		Type:     fileType,
+		Segments: make([]models.ManifestSegment, 0, len(source.Segments)),
+	}
+
+	offset := 0.0
+	for _, seg := range source.Segments {
+		end := segmentEnd(seg)
+
+		// Chapters files describe the segments' own timeline, not a cut output
+		outputStart := offset
+		if fileType == "chapters" {
+			outputStart = seg.Start
+		}
+
+		file.Segments = append(file.Segments, models.ManifestSegment{
+			ID:          seg.ID,
+			Name:        seg.Name,
+			Start:       seg.Start,
+			End:         end,
+			OutputStart: outputStart,
+		})
+		offset += end - seg.Start - source.Overlap
+	}
+
+	return file
+}
+
+// writeManifest saves the export manifest next to the outputs
+func (s *OperationService) writeManifest(path string, operation *models.Operation, project *models.Project, video *models.Video, files []models.ManifestFile) error {
+	manifest := models.ExportManifest{
+		OperationID: operation.ID,
+		ProjectID:   project.ID,
+		ProjectName: project.Name,
+		VideoID:     video.ID,
+		Source:      video.FileName,
+		CreatedAt:   time.Now(),
+		Files:       files,
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// Cut modes supported by exports
+const (
+	CutModeLossless = "lossless" // Stream copy from the keyframe before the start (fast)
+	CutModeKeyframe = "keyframe" // Same as CutModeLossless
+	CutModeAccurate = "accurate" // Stream copy seeking on the output (slower, closer to the cut points)
+	CutModeSmart    = "smart"
+	CutModeReencode = "reencode"
+)
+
+// validateExportRequest rejects export requests with invalid options before any work starts
+func validateExportRequest(request models.ExportRequest) error {
+	switch request.CutMode {
+	case "", CutModeLossless, CutModeKeyframe, CutModeAccurate, CutModeSmart, CutModeReencode:
+	default:
+		return invalidf("invalid cut mode: %s", request.CutMode)
+	}
+
+	if request.ExportFrames {
+		if err := validateFrameExport(request); err != nil {
+			return err
+		}
+	}
+	if request.Format != "" && !request.AudioOnly && !request.ExportFrames {
+		if _, ok := ffmpeg.GetOutputProfile(request.Format); !ok {
+			return invalidf("unsupported output format %q, use one of %s", request.Format, strings.Join(ffmpeg.OutputFormats(), ", "))
+		}
+	}
+	if request.ExportPreset != "" && cutMode(request) != CutModeReencode {
+		return invalidf("export_preset requires cut mode %q", CutModeReencode)
+	}
+	if request.Crossfade < 0 {
+		return invalidf("crossfade must not be negative")
+	}
+	if request.Crossfade > 0 && cutMode(request) != CutModeReencode {
+		return invalidf("crossfade requires cut mode %q", CutModeReencode)
+	}
+	if request.Crossfade > 0 && request.BurnTimecode {
+		return invalidf("burn_timecode can't be combined with crossfade")
+	}
+	if request.Crossfade > 0 && request.NormalizeAudio {
+		return invalidf("normalize_audio can't be combined with crossfade")
+	}
+	if request.LoudnessTarget > 0 || request.LoudnessTarget < -70 {
+		return invalidf("loudness_target must be between -70 and 0 LUFS")
+	}
+	if !ffmpeg.ValidCrossfadeTransition(request.CrossfadeTransition) {
+		return invalidf("invalid crossfade transition: %s", request.CrossfadeTransition)
+	}
+	if request.BurnTimecode && cutMode(request) != CutModeReencode {
+		return invalidf("burn_timecode requires cut mode %q", CutModeReencode)
+	}
+	if burnSubtitles(request) {
+		if request.BurnSubtitleStream != nil && request.BurnSubtitleFile != "" {
+			return invalidf("burn_subtitle_stream and burn_subtitle_file can't be combined")
+		}
+		if cutMode(request) != CutModeReencode {
+			return invalidf("burned subtitles require cut mode %q", CutModeReencode)
+		}
+		if request.Crossfade > 0 {
+			return invalidf("burned subtitles can't be combined with crossfade")
+		}
+	}
+	if request.Watermark != nil {
+		if request.Watermark.Image == "" {
+			return invalidf("watermark needs an image")
+		}
+		if !ffmpeg.ValidWatermarkPosition(request.Watermark.Position) {
+			return invalidf("invalid watermark position: %s", request.Watermark.Position)
+		}
+		if request.Watermark.Opacity < 0 || request.Watermark.Opacity > 1 {
+			return invalidf("watermark opacity must be between 0 and 1")
+		}
+		if cutMode(request) != CutModeReencode {
+			return invalidf("watermark requires cut mode %q", CutModeReencode)
+		}
+		if request.Crossfade > 0 {
+			return invalidf("watermark can't be combined with crossfade")
+		}
+	}
+	if !ffmpeg.ValidAspectRatio(request.AspectRatio) {
+		return invalidf("invalid aspect ratio: %s", request.AspectRatio)
+	}
+	if request.Width < 0 || request.Height < 0 {
+		return invalidf("width and height must not be negative")
+	}
+	if resizes(request) && cutMode(request) != CutModeReencode {
+		return invalidf("crop, width and height require cut mode %q", CutModeReencode)
+	}
+	if crop := cropRect(request); crop != nil {
+		if err := crop.Validate(); err != nil {
+			return invalid(err)
+		}
+	}
+	if request.TargetSize < 0 {
+		return invalidf("target_size must not be negative")
+	}
+	if request.TargetSize > 0 && cutMode(request) != CutModeReencode {
+		return invalidf("target_size requires cut mode %q", CutModeReencode)
+	}
+	if !ffmpeg.ValidTimecodePosition(request.TimecodePosition) {
+		return invalidf("invalid timecode position: %s", request.TimecodePosition)
+	}
+	if !ffmpeg.ValidDeinterlace(request.Deinterlace) {
+		return invalidf("invalid deinterlace mode: %s", request.Deinterlace)
+	}
+	if request.Deinterlace != "" {
+		if copiesStreams(cutMode(request)) {
+			return invalidf("deinterlace requires cut mode %q or %q", CutModeSmart, CutModeReencode)
+		}
+		if request.Crossfade > 0 {
+			return invalidf("deinterlace can't be combined with crossfade")
+		}
+	}
+	if !ffmpeg.ValidPreset(request.Preset) {
+		return invalidf("invalid preset %q, use one of ultrafast, superfast, veryfast, faster, fast, medium, slow, slower or veryslow", request.Preset)
+	}
+	if !ffmpeg.ValidAudioCodec(request.AudioCodec) {
+		return invalidf("unsupported audio codec %q, use mp3, aac, flac, opus, pcm, pcm24 or copy", request.AudioCodec)
+	}
+	if !ffmpeg.ValidQualityMetric(request.QualityMetric) {
+		return invalidf("invalid quality metric %q, use %s, %s or %s", request.QualityMetric, ffmpeg.QualitySSIM, ffmpeg.QualityVMAF, ffmpeg.QualityBoth)
+	}
+
+	if !request.Streams.IsZero() {
+		if err := validateStreamSelection(request); err != nil {
+			return err
+		}
+	}
+
+	if request.Rotation != nil {
+		if !ffmpeg.ValidRotation(*request.Rotation) {
+			return invalidf("invalid rotation %d, use 0, 90, 180 or 270", *request.Rotation)
+		}
+		if cutMode(request) == CutModeSmart {
+			return invalidf("rotation can't be combined with cut mode %q", CutModeSmart)
+		}
+		if request.Crossfade > 0 {
+			return invalidf("rotation can't be combined with crossfade")
+		}
+	}
+	if err := ffmpeg.ValidateMetadataTags(request.Metadata); err != nil {
+		return invalid(err)
+	}
+
+	if err := ffmpeg.ValidateAdvancedOptions(request.AdvancedOptions); err != nil {
+		return invalidf("invalid advanced_options: %w", err)
+	}
+	if err := ffmpeg.ValidateExtraArgs(request.ExtraArgs); err != nil {
+		return invalidf("invalid extra_args: %w", err)
+	}
 
-	return operation, nil
+	return nil
 }
 
-func (s *OperationService) runExport(operation *models.Operation, project *models.Project, request models.ExportRequest) {
-	operation.Status = models.OperationStatusProcessing
-	ctx := context.Background()
+// checkEncoders rejects exports that re-encode with a codec the FFmpeg build can't
+// encode. If its capabilities can't be detected the export goes ahead and fails
+// where it encodes.
+func (s *OperationService) checkEncoders(ctx context.Context, request models.ExportRequest) error {
+	var videoCodec, audioCodec string
+	switch {
+	case request.ExportFrames:
+		return nil
+	case request.AudioOnly:
+		audioCodec = request.AudioCodec
+		if audioCodec == "" {
+			audioCodec = "mp3"
+		}
+	case cutMode(request) == CutModeReencode:
+		videoCodec, audioCodec = request.VideoCodec, request.AudioCodec
+		if videoCodec == "" {
+			videoCodec = "libx264"
+		}
+		if audioCodec == "" {
+			audioCodec = "aac"
+		}
+	default:
+		return nil
+	}
 
-	// Get actual video file path from metadata
-	video, err := s.storage.GetVideo(project.VideoID)
+	caps, err := s.ffmpeg.Capabilities(ctx, false)
 	if err != nil {
-		operation.Status = models.OperationStatusFailed
-		operation.Error = fmt.Sprintf("video not found: %v", err)
-		s.logger.Error("Failed to get video for export",
-			zap.String("videoId", project.VideoID),
-			zap.Error(err),
-		)
-		return
+		s.logger.Warn("Failed to detect FFmpeg capabilities", zap.Error(err))
+		return nil
+	}
+	if err := caps.CheckEncoders(videoCodec, audioCodec); err != nil {
+		return invalid(err)
 	}
+	return nil
+}
 
-	inputPath := video.FilePath
-	s.logger.Info("Starting export",
-		zap.String("operationId", operation.ID),
-		zap.String("inputPath", inputPath),
-		zap.String("videoId", project.VideoID),
-		zap.Bool("mergeSegments", request.MergeSegments),
-		zap.Bool("exportSeparate", request.ExportSeparate),
-	)
+// exportArgs returns the options added before the output of every export command:
+// the advanced options, then the admin's extra args
+func exportArgs(request models.ExportRequest) []string {
+	if len(request.AdvancedOptions) == 0 {
+		return request.ExtraArgs
+	}
+	return append(append([]string(nil), request.AdvancedOptions...), request.ExtraArgs...)
+}
 
-	// Determine segments to export
-	segments := project.Segments
-	if len(request.SegmentIDs) > 0 {
-		// Filter to specified segments
-		filteredSegments := []models.Segment{}
-		for _, seg := range project.Segments {
-			for _, id := range request.SegmentIDs {
-				if seg.ID == id {
-					filteredSegments = append(filteredSegments, seg)
+// AdvancedOptionNames returns the FFmpeg options allowed in advanced_options
+func AdvancedOptionNames() []string {
+	return ffmpeg.AdvancedOptionNames()
+}
+
+// validateFrameExport checks the image sequence options of an export request
+func validateFrameExport(request models.ExportRequest) error {
+	if !ffmpeg.ValidFrameFormat(request.FrameFormat) {
+		return invalidf("invalid frame format %q, use png or jpg", request.FrameFormat)
+	}
+	if request.FrameFPS < 0 {
+		return invalidf("frame_fps must not be negative")
+	}
+	if request.AudioOnly {
+		return invalidf("export_frames can't be combined with audio_only")
+	}
+	if request.MergeSegments || request.ExportSeparate || request.ExportChapters || request.Crossfade > 0 {
+		return invalidf("export_frames can't be combined with merged, separate or chapters exports")
+	}
+	return nil
+}
+
+// maxExportFrames caps the images of a frames export. Keeping every frame, segments
+// are limited as if the source had maxFrameRate frames per second.
+const (
+	maxExportFrames = 10000
+	maxFrameRate    = 60
+)
+
+// validateFrameCount checks a frames export of segments writes at most
+// maxExportFrames images
+func validateFrameCount(segments []models.Segment, request models.ExportRequest) error {
+	total := 0.0
+	for _, seg := range segments {
+		total += segmentEnd(seg) - seg.Start
+	}
+
+	if request.FrameFPS == 0 {
+		if limit := float64(maxExportFrames) / maxFrameRate; total > limit {
+			return invalidf("export_frames keeps every frame of at most %.0f seconds, set frame_fps for %.0f seconds", limit, total)
+		}
+		return nil
+	}
+	if frames := math.Ceil(total * request.FrameFPS); frames > maxExportFrames {
+		return invalidf("export_frames would write %.0f frames, at most %d; lower frame_fps or shorten the segments", frames, maxExportFrames)
+	}
+	return nil
+}
+
+// validateStreamSelection checks the streams option of an export request
+func validateStreamSelection(request models.ExportRequest) error {
+	selection := request.Streams
+	if !ffmpeg.ValidStreamShortcut(selection.Shortcut) {
+		return invalidf("invalid stream shortcut: %s", selection.Shortcut)
+	}
+	if selection.Shortcut != "" && len(selection.Indexes) > 0 {
+		return invalidf("streams can't combine a shortcut with stream indexes")
+	}
+	if request.AudioOnly {
+		return invalidf("streams can't be combined with audio_only")
+	}
+	if !copiesStreams(cutMode(request)) {
+		return invalidf("streams requires cut mode %q or %q", CutModeLossless, CutModeAccurate)
+	}
+
+	seen := make(map[int]bool)
+	for _, index := range selection.Indexes {
+		if index < 0 || seen[index] {
+			return invalidf("invalid or duplicate stream index %d", index)
+		}
+		seen[index] = true
+	}
+	return nil
+}
+
+// selectedStreams returns the source streams kept by a stream selection, checking
+// the selected indexes exist
+func selectedStreams(streams []models.Stream, selection models.StreamSelection) ([]models.Stream, error) {
+	if selection.IsZero() {
+		return streams, nil
+	}
+
+	var selected []models.Stream
+	switch selection.Shortcut {
+	case ffmpeg.StreamsVideoOnly:
+		for _, stream := range streams {
+			if stream.CodecType == "video" {
+				selected = append(selected, stream)
+			}
+		}
+	case ffmpeg.StreamsStripSubtitles:
+		for _, stream := range streams {
+			if stream.CodecType != "subtitle" {
+				selected = append(selected, stream)
+			}
+		}
+	default:
+		for _, index := range selection.Indexes {
+			found := false
+			for _, stream := range streams {
+				if stream.Index == index {
+					selected = append(selected, stream)
+					found = true
 					break
 				}
 			}
+			if !found {
+				return nil, invalidf("stream %d not found in the source", index)
+			}
 		}
-		segments = filteredSegments
 	}
 
-	if len(segments) == 0 {
-		operation.Status = models.OperationStatusFailed
-		operation.Error = "no segments to export"
-		return
+	if len(selected) == 0 {
+		return nil, invalidf("stream selection keeps no streams")
 	}
+	return selected, nil
+}
 
-	// Build output filename
-	outputName := request.OutputName
-	if outputName == "" {
-		outputName = fmt.Sprintf("%s_export_%d", project.Name, time.Now().Unix())
+// cutMode returns the effective cut mode of an export request
+func cutMode(request models.ExportRequest) string {
+	if request.CutMode == CutModeKeyframe {
+		return CutModeLossless
 	}
+	if request.CutMode != "" {
+		return request.CutMode
+	}
+	if request.Reencode || request.BurnTimecode || burnSubtitles(request) || request.Crossfade > 0 || resizes(request) || request.Watermark != nil {
+		return CutModeReencode
+	}
+	return CutModeLossless
+}
 
-	format := request.Format
-	if format == "" {
-		format = "mp4"
+// copiesStreams reports whether a cut mode copies every segment without re-encoding
+func copiesStreams(mode string) bool {
+	return mode == CutModeLossless || mode == CutModeAccurate
+}
+
+// watermark resolves an export's watermark image against the video's uploads
+func watermark(video *models.Video, options models.WatermarkOptions) (ffmpeg.Watermark, error) {
+	for _, image := range video.Watermarks {
+		if image.ID == options.Image {
+			return ffmpeg.Watermark{File: image.FilePath, Position: options.Position, Opacity: options.Opacity}, nil
+		}
 	}
+	return ffmpeg.Watermark{}, invalidf("watermark %s not found", options.Image)
+}
 
-	// Progress callback
-	onProgress := func(progress float64) {
-		operation.Progress = progress * 100
-		s.logger.Debug("Export progress",
-			zap.String("operationId", operation.ID),
-			zap.Float64("progress", operation.Progress),
-		)
+// hasVideoStream reports whether streams include a video stream
+func hasVideoStream(streams []models.Stream) bool {
+	for _, stream := range streams {
+		if stream.CodecType == "video" {
+			return true
+		}
 	}
+	return false
+}
 
-	var outputFiles []string
-	var exportErr error
+// resizes reports whether an export request crops or scales the picture
+func resizes(request models.ExportRequest) bool {
+	return request.Crop != nil || request.Width > 0 || request.Height > 0
+}
 
-	// Handle different export modes
-	if len(segments) == 1 {
-		// Single segment - just cut it
-		outputPath := s.storage.GetOutputPath(fmt.Sprintf("%s.%s", outputName, format))
-		seg := segments[0]
-		end := seg.Start + 60.0
-		if seg.End != nil {
-			end = *seg.End
+// cropRect converts an export request's crop to executor options
+func cropRect(request models.ExportRequest) *ffmpeg.CropRect {
+	if request.Crop == nil {
+		return nil
+	}
+	return &ffmpeg.CropRect{X: request.Crop.X, Y: request.Crop.Y, Width: request.Crop.Width, Height: request.Crop.Height}
+}
+
+// validateCrop checks an export request's crop against the picture size of the
+// video's first video stream as the filter graph sees it, after autorotation by its
+// display matrix or the rotation the request overrides it with
+func validateCrop(video *models.Video, request models.ExportRequest) error {
+	crop := cropRect(request)
+	if crop == nil {
+		return nil
+	}
+	for _, stream := range video.Metadata.Streams {
+		if stream.CodecType != "video" {
+			continue
 		}
-		exportErr = s.ffmpeg.CutVideo(ctx, inputPath, outputPath, seg.Start, end, onProgress)
-		if exportErr == nil {
-			outputFiles = append(outputFiles, outputPath)
+		// Videos probed before streams had a rotation only know the one set on them
+		rotation := stream.Rotation
+		if rotation == 0 {
+			rotation = video.Rotation
 		}
-	} else {
-		// Multiple segments
-		if request.MergeSegments {
-			// Export merged file
-			mergedPath := s.storage.GetOutputPath(fmt.Sprintf("%s_merged.%s", outputName, format))
-			exportErr = s.exportMergedSegments(ctx, inputPath, mergedPath, segments, onProgress)
-			if exportErr == nil {
-				outputFiles = append(outputFiles, mergedPath)
-			}
+		if request.Rotation != nil {
+			rotation = *request.Rotation
 		}
-
-		if request.ExportSeparate && exportErr == nil {
-			// Export each segment separately
-			separateFiles, err := s.exportMultipleSegments(ctx, inputPath, outputName, format, segments, onProgress)
-			if err != nil {
-				exportErr = err
-			} else {
-				outputFiles = append(outputFiles, separateFiles...)
-			}
+		width, height := stream.Width, stream.Height
+		if rotation == 90 || rotation == 270 {
+			width, height = height, width
 		}
+		if width > 0 && height > 0 && !crop.Fits(width, height) {
+			return invalidf("crop %dx%d+%d+%d exceeds the %dx%d picture", crop.Width, crop.Height, crop.X, crop.Y, width, height)
+		}
+		return nil
+	}
+	return invalidf("crop needs a video stream")
+}
 
-		// Handle chapters export
-		if request.ExportChapters && exportErr == nil {
-			chaptersPath := s.storage.GetOutputPath(fmt.Sprintf("%s_chapters.%s", outputName, request.ChaptersFormat))
-			err := s.exportChapters(ctx, chaptersPath, segments)
-			if err != nil {
-				exportErr = err
-			} else {
-				outputFiles = append(outputFiles, chaptersPath)
+// burnSubtitles reports whether an export request burns subtitles into the video
+func burnSubtitles(request models.ExportRequest) bool {
+	return request.BurnSubtitleStream != nil || request.BurnSubtitleFile != ""
+}
+
+// subtitleBurn resolves the subtitles an export request burns in against the video:
+// an embedded stream by absolute index, or a sidecar file by ID
+func subtitleBurn(video *models.Video, request models.ExportRequest) (ffmpeg.SubtitleBurn, error) {
+	if request.BurnSubtitleFile != "" {
+		for _, sidecar := range video.Subtitles {
+			if sidecar.ID == request.BurnSubtitleFile {
+				return ffmpeg.SubtitleBurn{File: sidecar.FilePath}, nil
 			}
 		}
+		return ffmpeg.SubtitleBurn{}, invalidf("subtitle file not found: %s", request.BurnSubtitleFile)
+	}
 
-		// If neither merge nor separate was specified, default to merge
-		if !request.MergeSegments && !request.ExportSeparate && !request.ExportChapters {
-			mergedPath := s.storage.GetOutputPath(fmt.Sprintf("%s.%s", outputName, format))
-			exportErr = s.exportMergedSegments(ctx, inputPath, mergedPath, segments, onProgress)
-			if exportErr == nil {
-				outputFiles = append(outputFiles, mergedPath)
+	// The subtitles filter counts subtitle streams only
+	index := 0
+	for _, stream := range video.Metadata.Streams {
+		if stream.CodecType != "subtitle" {
+			continue
+		}
+		if stream.Index == *request.BurnSubtitleStream {
+			if !ffmpeg.IsTextSubtitleCodec(stream.CodecName) {
+				return ffmpeg.SubtitleBurn{}, invalidf("%s subtitles can't be burned in, use a text subtitle stream", stream.CodecName)
 			}
+			return ffmpeg.SubtitleBurn{File: video.FilePath, Index: index}, nil
 		}
+		index++
 	}
+	return ffmpeg.SubtitleBurn{}, invalidf("stream %d is not a subtitle stream", *request.BurnSubtitleStream)
+}
 
-	if exportErr != nil {
-		operation.Status = models.OperationStatusFailed
-		operation.Error = exportErr.Error()
-		s.logger.Error("Export failed",
-			zap.String("operationId", operation.ID),
-			zap.Error(exportErr),
-		)
-		return
+// cutSegment cuts one segment to outputPath using the requested cut mode
+func (s *OperationService) cutSegment(ctx context.Context, inputPath, outputPath string, start, end float64, request models.ExportRequest, onProgress ffmpeg.ProgressCallback) error {
+	if request.TargetSize > 0 && request.VideoBitrate == "" {
+		request.VideoBitrate = ffmpeg.TargetVideoBitrate(request.TargetSize, end-start, request.AudioBitrate)
 	}
 
-	// Success
-	now := time.Now()
-	operation.Status = models.OperationStatusCompleted
-	operation.Progress = 100
-	operation.CompletedAt = &now
-	operation.OutputFiles = outputFiles
+	switch cutMode(request) {
+	case CutModeReencode:
+		return s.ffmpeg.EncodeSegment(ctx, inputPath, outputPath, start, end, encodeOptions(request), onProgress)
+	case CutModeSmart:
+		return s.ffmpeg.SmartCut(ctx, ffmpeg.SmartCutOptions{
+			Input:       inputPath,
+			Output:      outputPath,
+			Start:       start,
+			End:         end,
+			VideoCodec:  request.VideoCodec,
+			AudioCodec:  request.AudioCodec,
+			Quality:     request.CRF,
+			Preset:      request.Preset,
+			HWAccel:     s.config.FFmpeg.HWAccel,
+			HWDevice:    s.config.FFmpeg.VAAPIDevice,
+			Deinterlace: request.Deinterlace,
+			OnProgress:  onProgress,
+		})
+	case CutModeAccurate:
+		return s.ffmpeg.CutVideoAccurate(ctx, inputPath, outputPath, start, end, onProgress)
+	default:
+		return s.ffmpeg.CutVideo(ctx, inputPath, outputPath, start, end, onProgress)
+	}
+}
 
-	s.logger.Info("Export completed",
-		zap.String("operationId", operation.ID),
-		zap.Int("outputFilesCount", len(outputFiles)),
-		zap.Strings("outputFiles", outputFiles),
-	)
+// encodeOptions converts the re-encoding fields of an export request to executor options
+func encodeOptions(request models.ExportRequest) ffmpeg.EncodeOptions {
+	return ffmpeg.EncodeOptions{
+		VideoCodec:   request.VideoCodec,
+		VideoBitrate: request.VideoBitrate,
+		CRF:          request.CRF,
+		Preset:       request.Preset,
+		VideoProfile: request.VideoProfile,
+		PixelFormat:  request.PixelFormat,
+		AspectRatio:  request.AspectRatio,
+		Width:        request.Width,
+		Height:       request.Height,
+		FPS:          request.FPS,
+		Deinterlace:  request.Deinterlace,
+		Crop:         cropRect(request),
+		AudioCodec:   request.AudioCodec,
+		AudioBitrate: request.AudioBitrate,
+		SampleRate:   request.AudioSampleRate,
+
+		Timecode:         request.BurnTimecode,
+		TimecodePosition: request.TimecodePosition,
+	}
 }
 
-func (s *OperationService) exportMergedSegments(ctx context.Context, inputPath, outputPath string, segments []models.Segment, onProgress ffmpeg.ProgressCallback) error {
-	// Cut each segment to temp files
+func (s *OperationService) exportMergedSegments(ctx context.Context, inputPath, workspace, outputPath string, segments []models.Segment, request models.ExportRequest, onProgress ffmpeg.ProgressCallback) error {
+	// A size target applies to the merged file, so every piece gets the bitrate of the whole
+	if request.TargetSize > 0 && request.VideoBitrate == "" {
+		total := 0.0
+		for _, seg := range segments {
+			end := segmentEnd(seg)
+			total += end - seg.Start
+		}
+		total -= float64(len(segments)-1) * request.Crossfade
+		request.VideoBitrate = ffmpeg.TargetVideoBitrate(request.TargetSize, total, request.AudioBitrate)
+	}
+
+	if request.Crossfade > 0 {
+		return s.exportCrossfadedSegments(ctx, inputPath, outputPath, segments, request, onProgress)
+	}
+
+	normalize := request.NormalizeAudio && s.hasAudio(ctx, inputPath)
+
+	// Cut each segment into the workspace, reusing pieces of a previous attempt.
+	// Pieces use the output container, which is known to hold the source streams.
 	tempFiles := make([]string, len(segments))
+	ext := filepath.Ext(outputPath)
 
 	for i, seg := range segments {
-		tempFile := s.storage.GetTempPath(fmt.Sprintf("segment_%d_%s.mp4", i, uuid.New().String()))
+		tempFile := filepath.Join(workspace, fmt.Sprintf("segment_%d%s", i, ext))
 		tempFiles[i] = tempFile
 
-		end := seg.Start + 60.0
-		if seg.End != nil {
-			end = *seg.End
-		}
+		end := segmentEnd(seg)
 
 		// Cut segment (no progress callback for individual segments)
-		if err := s.ffmpeg.CutVideo(ctx, inputPath, tempFile, seg.Start, end, nil); err != nil {
+		if err := s.producePiece(tempFile, func(partialPath string) error {
+			return s.cutSegment(ctx, inputPath, partialPath, seg.Start, end, request, nil)
+		}); err != nil {
 			return fmt.Errorf("failed to cut segment %d: %w", i, err)
 		}
+
+		if normalize {
+			codec := normalizeCodec(request, ext)
+			normalizedFile := filepath.Join(workspace, fmt.Sprintf("segment_%d_normalized%s", i, ext))
+			if err := s.producePiece(normalizedFile, func(partialPath string) error {
+				return s.normalizePiece(ctx, tempFile, partialPath, codec, request)
+			}); err != nil {
+				return fmt.Errorf("failed to normalize audio of segment %d: %w", i, err)
+			}
+			tempFiles[i] = normalizedFile
+		}
 	}
 
-	// Merge all segments
+	// Merge all segments. Copied pieces start at the keyframe before their segment,
+	// so chapters follow the pieces' real lengths.
+	durations := s.pieceDurations(ctx, tempFiles, segments)
 	totalDuration := 0.0
-	for _, seg := range segments {
-		end := seg.Start + 60.0
-		if seg.End != nil {
-			end = *seg.End
-		}
-		totalDuration += (end - seg.Start)
+	for _, duration := range durations {
+		totalDuration += duration
 	}
 
-	if err := s.ffmpeg.MergeVideos(ctx, tempFiles, outputPath, totalDuration, onProgress); err != nil {
+	if err := s.ffmpeg.MergeVideos(ctx, tempFiles, outputPath, inputPath, mergedChapters(segments, durations, 0), totalDuration, onProgress); err != nil {
 		return fmt.Errorf("failed to merge segments: %w", err)
 	}
 
-	// Clean up temp files
-	for _, tempFile := range tempFiles {
-		s.storage.DeleteFile(tempFile)
+	return nil
+}
+
+// pieceDurations returns the lengths of the cut pieces of a merge. Pieces that can't
+// be probed count with the length of their segment.
+func (s *OperationService) pieceDurations(ctx context.Context, pieces []string, segments []models.Segment) []float64 {
+	durations := make([]float64, len(pieces))
+	for i, piece := range pieces {
+		durations[i] = segmentEnd(segments[i]) - segments[i].Start
+
+		probe, err := s.ffmpeg.Probe(ctx, piece)
+		if err != nil {
+			s.logger.Warn("Failed to probe merged piece", zap.String("path", piece), zap.Error(err))
+			continue
+		}
+		if duration, err := probe.GetDuration(); err == nil && duration > 0 {
+			durations[i] = duration
+		}
 	}
+	return durations
+}
 
-	return nil
+// mergedChapters returns one chapter per segment on the timeline of the merged
+// output, named after the segment. durations are the lengths of the merged pieces,
+// nil when they are the segments' own. Joins overlap by overlap seconds
+// (crossfades), each chapter starting mid-transition.
+func mergedChapters(segments []models.Segment, durations []float64, overlap float64) []ffmpeg.OutputChapter {
+	chapters := make([]ffmpeg.OutputChapter, len(segments))
+	offset := 0.0
+	for i, seg := range segments {
+		duration := segmentEnd(seg) - seg.Start
+		if durations != nil {
+			duration = durations[i]
+		}
+
+		start, end := offset, offset+duration
+		if i > 0 {
+			start += overlap / 2
+		}
+		if i < len(segments)-1 {
+			end -= overlap / 2
+		}
+
+		name := seg.Name
+		if name == "" {
+			name = fmt.Sprintf("Chapter %d", i+1)
+		}
+		chapters[i] = ffmpeg.OutputChapter{Start: start, End: end, Title: name}
+
+		offset += duration - overlap
+	}
+	return chapters
 }
 
-func (s *OperationService) exportMultipleSegments(ctx context.Context, inputPath, outputBaseName, format string, segments []models.Segment, onProgress ffmpeg.ProgressCallback) ([]string, error) {
-	var outputFiles []string
+// producePiece creates an intermediate file of an export unless a previous attempt
+// already completed it. produce writes to a partial path that is only renamed into
+// place on success, so interrupted pieces are never reused.
+func (s *OperationService) producePiece(path string, produce func(partialPath string) error) error {
+	if _, err := os.Stat(path); err == nil {
+		s.logger.Debug("Reusing completed export piece", zap.String("path", path))
+		return nil
+	}
+
+	ext := filepath.Ext(path)
+	partialPath := strings.TrimSuffix(path, ext) + ".partial" + ext
+	if err := produce(partialPath); err != nil {
+		os.Remove(partialPath)
+		return err
+	}
+
+	return os.Rename(partialPath, path)
+}
+
+// hasAudio reports whether a media file has at least one audio stream
+func (s *OperationService) hasAudio(ctx context.Context, path string) bool {
+	probe, err := s.ffmpeg.Probe(ctx, path)
+	if err != nil {
+		s.logger.Warn("Failed to probe for audio streams", zap.String("path", path), zap.Error(err))
+		return false
+	}
+	return len(probe.GetAudioStreams()) > 0
+}
+
+// sourceAudioCodec returns the audio codec name that encodes audio like the first
+// audio stream of a file, FLAC if it can't be probed
+func (s *OperationService) sourceAudioCodec(ctx context.Context, path string) string {
+	probe, err := s.ffmpeg.Probe(ctx, path)
+	if err != nil {
+		s.logger.Warn("Failed to probe audio codec", zap.String("path", path), zap.Error(err))
+		return "flac"
+	}
+	streams := probe.GetAudioStreams()
+	if len(streams) == 0 {
+		return "flac"
+	}
+	return ffmpeg.AudioReencodeCodec(streams[0].CodecName)
+}
+
+// normalizeCodec returns the audio codec of normalized pieces: the requested one, or
+// one the output container holds. WebM only takes Opus and Vorbis.
+func normalizeCodec(request models.ExportRequest, ext string) string {
+	if request.AudioCodec != "" && request.AudioCodec != "copy" {
+		return request.AudioCodec
+	}
+	if strings.EqualFold(ext, ".webm") {
+		return "opus"
+	}
+	return "aac"
+}
+
+// normalizePiece measures the loudness of src and writes a normalized copy to dst,
+// re-encoding the audio with the given codec
+func (s *OperationService) normalizePiece(ctx context.Context, src, dst, audioCodec string, request models.ExportRequest) error {
+	target := ffmpeg.LoudnessTarget{Integrated: request.LoudnessTarget}
+	stats, err := s.ffmpeg.MeasureLoudness(ctx, src, target)
+	if err != nil {
+		return err
+	}
+
+	return s.ffmpeg.NormalizeLoudness(ctx, src, dst, target, stats, audioCodec, request.AudioBitrate)
+}
+
+// exportCrossfadedSegments merges segments in one re-encode, joining them with crossfades
+func (s *OperationService) exportCrossfadedSegments(ctx context.Context, inputPath, outputPath string, segments []models.Segment, request models.ExportRequest, onProgress ffmpeg.ProgressCallback) error {
+	probe, err := s.ffmpeg.Probe(ctx, inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to probe input: %w", err)
+	}
+
+	ranges := make([]struct{ Start, End float64 }, len(segments))
+	for i, seg := range segments {
+		end := segmentEnd(seg)
+		ranges[i].Start = seg.Start
+		ranges[i].End = end
+	}
+
+	if err := s.ffmpeg.CrossfadeSegments(ctx, ffmpeg.CrossfadeOptions{
+		Input:      inputPath,
+		Output:     outputPath,
+		Segments:   ranges,
+		Duration:   request.Crossfade,
+		Transition: request.CrossfadeTransition,
+		HasAudio:   len(probe.GetAudioStreams()) > 0,
+		Encode:     encodeOptions(request),
+		Chapters:   mergedChapters(segments, nil, request.Crossfade),
+		OnProgress: onProgress,
+	}); err != nil {
+		return fmt.Errorf("failed to crossfade segments: %w", err)
+	}
+
+	return nil
+}
 
+// exportMultipleSegments cuts each segment to its own file. Segments are independent,
+// so up to ffmpeg.max_parallel of them are cut concurrently; progress is the
+// duration-weighted average of all segments.
+func (s *OperationService) exportMultipleSegments(ctx context.Context, inputPath, workspace string, namer *outputNamer, format string, segments []models.Segment, request models.ExportRequest, onProgress ffmpeg.ProgressCallback) ([]string, error) {
+	outputFiles := make([]string, len(segments))
+	pieces := make([]string, len(segments))
+	durations := make([]float64, len(segments))
+	totalDuration := 0.0
 	for i, seg := range segments {
-		segmentName := fmt.Sprintf("%s_segment_%d.%s", outputBaseName, i+1, format)
-		outputPath := s.storage.GetOutputPath(segmentName)
+		outputFiles[i] = s.storage.GetOutputPath(namer.Segment(i, seg, format))
+		pieces[i] = filepath.Join(workspace, fmt.Sprintf("separate_%d.%s", i, format))
+
+		end := segmentEnd(seg)
+		durations[i] = end - seg.Start
+		totalDuration += durations[i]
+	}
+
+	parallel := s.config.FFmpeg.MaxParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	// The first failure cancels the remaining cuts
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		end := seg.Start + 60.0
-		if seg.End != nil {
-			end = *seg.End
+	var (
+		mu       sync.Mutex
+		progress = make([]float64, len(segments))
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	slots := make(chan struct{}, parallel)
+
+	reportProgress := func(i int, p float64) {
+		if onProgress == nil || totalDuration <= 0 {
+			return
 		}
+		mu.Lock()
+		progress[i] = p
+		done := 0.0
+		for j, segProgress := range progress {
+			done += segProgress * durations[j]
+		}
+		mu.Unlock()
+		onProgress(done / totalDuration)
+	}
 
-		if err := s.ffmpeg.CutVideo(ctx, inputPath, outputPath, seg.Start, end, onProgress); err != nil {
-			return outputFiles, fmt.Errorf("failed to export segment %d: %w", i, err)
+	for i, seg := range segments {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			<-slots
+			break
 		}
 
-		outputFiles = append(outputFiles, outputPath)
+		wg.Add(1)
+		go func(i int, seg models.Segment) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := s.producePiece(pieces[i], func(partialPath string) error {
+				return s.cutSegment(ctx, inputPath, partialPath, seg.Start, seg.Start+durations[i], request, func(p float64) {
+					reportProgress(i, p)
+				})
+			})
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to export segment %d: %w", i, err)
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+			reportProgress(i, 1)
+		}(i, seg)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		// Completed pieces stay in the workspace for a retry
+		return nil, firstErr
+	}
+
+	// Only publish the segment files once all of them are cut
+	for i, piece := range pieces {
+		if err := os.Rename(piece, outputFiles[i]); err != nil {
+			return nil, fmt.Errorf("failed to move segment %d to output: %w", i, err)
+		}
 	}
 
 	return outputFiles, nil
@@ -287,10 +1940,7 @@ func (s *OperationService) exportChapters(ctx context.Context, outputPath string
 func (s *OperationService) generateChaptersTXT(segments []models.Segment) string {
 	var content strings.Builder
 	for i, seg := range segments {
-		end := seg.Start + 60.0
-		if seg.End != nil {
-			end = *seg.End
-		}
+		end := segmentEnd(seg)
 
 		name := seg.Name
 		if name == "" {
@@ -314,10 +1964,7 @@ func (s *OperationService) generateChaptersXML(segments []models.Segment) string
 `)
 
 	for i, seg := range segments {
-		end := seg.Start + 60.0
-		if seg.End != nil {
-			end = *seg.End
-		}
+		end := segmentEnd(seg)
 
 		name := seg.Name
 		if name == "" {
@@ -346,10 +1993,7 @@ func (s *OperationService) generateChaptersJSON(segments []models.Segment) strin
 
 	var chapters []Chapter
 	for i, seg := range segments {
-		end := seg.Start + 60.0
-		if seg.End != nil {
-			end = *seg.End
-		}
+		end := segmentEnd(seg)
 
 		name := seg.Name
 		if name == "" {
@@ -368,9 +2012,16 @@ func (s *OperationService) generateChaptersJSON(segments []models.Segment) strin
 }
 
 func (s *OperationService) GetStatus(operationID string) (*models.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	operation, exists := s.operations[operationID]
 	if !exists {
-		return nil, fmt.Errorf("operation not found: %s", operationID)
+		return nil, notFoundf("operation not found: %s", operationID)
 	}
-	return operation, nil
+
+	// Return a snapshot, the export goroutine keeps updating the operation
+	snapshot := *operation
+	snapshot.Commands = append([]string(nil), operation.Commands...)
+	return &snapshot, nil
 }
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+func TestSelectSegments(t *testing.T) {
+	end := 20.0
+	project := &models.Project{Segments: []models.Segment{
+		{ID: "a", Name: "closed", Start: 10, End: &end},
+		{ID: "b", Name: "open", Start: 30},
+	}}
+
+	segments, err := selectSegments(project, models.ExportRequest{}, 95.5)
+	if err != nil {
+		t.Fatalf("selectSegments() error = %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("selectSegments() returned %d segments, want 2", len(segments))
+	}
+	if got := segmentEnd(segments[1]); got != 95.5 {
+		t.Errorf("open-ended segment ends at %g, want the video duration 95.5", got)
+	}
+	if project.Segments[1].End != nil {
+		t.Error("selectSegments() modified the project")
+	}
+
+	segments, err = selectSegments(project, models.ExportRequest{SegmentIDs: []string{"a"}}, 0)
+	if err != nil || len(segments) != 1 || segments[0].ID != "a" {
+		t.Errorf("selectSegments() by id = %v, %v", segments, err)
+	}
+
+	if _, err := selectSegments(project, models.ExportRequest{}, 0); err == nil {
+		t.Error("open-ended segment with unknown duration: want error")
+	}
+}
+
+func TestEstimateRemaining(t *testing.T) {
+	if got := estimateRemaining(30*time.Second, 0.25); got != 90 {
+		t.Errorf("estimateRemaining(30s, 25%%) = %v, want 90", got)
+	}
+	if got := estimateRemaining(time.Second, 0.001); got != 0 {
+		t.Errorf("estimateRemaining() right after the start = %v, want 0", got)
+	}
+	if got := estimateRemaining(time.Minute, 1); got != 0 {
+		t.Errorf("estimateRemaining() when done = %v, want 0", got)
+	}
+}
+
+func TestMergedChapters(t *testing.T) {
+	end1, end2, end3 := 10.0, 50.0, 100.0
+	segments := []models.Segment{
+		{Name: "Intro", Start: 0, End: &end1},
+		{Start: 30, End: &end2},
+		{Name: "Outro", Start: 90, End: &end3},
+	}
+
+	want := []ffmpeg.OutputChapter{
+		{Start: 0, End: 10, Title: "Intro"},
+		{Start: 10, End: 30, Title: "Chapter 2"},
+		{Start: 30, End: 40, Title: "Outro"},
+	}
+	if got := mergedChapters(segments, nil, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergedChapters() = %+v, want %+v", got, want)
+	}
+
+	// Copied pieces start at the keyframe before their segment
+	want = []ffmpeg.OutputChapter{
+		{Start: 0, End: 10, Title: "Intro"},
+		{Start: 10, End: 32.5, Title: "Chapter 2"},
+		{Start: 32.5, End: 44.5, Title: "Outro"},
+	}
+	if got := mergedChapters(segments, []float64{10, 22.5, 12}, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergedChapters() of cut pieces = %+v, want %+v", got, want)
+	}
+
+	// Crossfades shorten the output, chapters change mid-transition
+	want = []ffmpeg.OutputChapter{
+		{Start: 0, End: 9, Title: "Intro"},
+		{Start: 9, End: 27, Title: "Chapter 2"},
+		{Start: 27, End: 36, Title: "Outro"},
+	}
+	if got := mergedChapters(segments, nil, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergedChapters() with crossfade = %+v, want %+v", got, want)
+	}
+}
+
+func TestExportArgs(t *testing.T) {
+	request := models.ExportRequest{
+		AdvancedOptions: []string{"-tag:v", "hvc1"},
+		ExtraArgs:       []string{"-metadata", "comment=x"},
+	}
+	want := []string{"-tag:v", "hvc1", "-metadata", "comment=x"}
+	if got := exportArgs(request); !reflect.DeepEqual(got, want) {
+		t.Errorf("exportArgs() = %v, want %v", got, want)
+	}
+
+	request.AdvancedOptions = []string{"-i", "other.mp4"}
+	if err := validateExportRequest(request); err == nil {
+		t.Error("validateExportRequest() expected error for a disallowed advanced option")
+	}
+}
+
+func TestCutModeAliases(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"", CutModeLossless},
+		{CutModeKeyframe, CutModeLossless},
+		{CutModeAccurate, CutModeAccurate},
+		{CutModeSmart, CutModeSmart},
+	}
+	for _, tt := range tests {
+		request := models.ExportRequest{CutMode: tt.mode}
+		if err := validateExportRequest(request); err != nil {
+			t.Errorf("cut mode %q: unexpected error %v", tt.mode, err)
+		}
+		if got := cutMode(request); got != tt.want {
+			t.Errorf("cutMode(%q) = %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+
+	if err := validateExportRequest(models.ExportRequest{CutMode: CutModeAccurate, Deinterlace: "yadif"}); err == nil {
+		t.Error("expected deinterlace to be rejected with an accurate stream copy cut")
+	}
+
+	// Crossfades re-encode, an explicit copying or smart cut can't honor them
+	for _, mode := range []string{CutModeLossless, CutModeKeyframe, CutModeAccurate, CutModeSmart} {
+		if err := validateExportRequest(models.ExportRequest{CutMode: mode, Crossfade: 1}); err == nil {
+			t.Errorf("expected crossfade to be rejected with cut mode %q", mode)
+		}
+	}
+	if err := validateExportRequest(models.ExportRequest{Crossfade: 1}); err != nil {
+		t.Errorf("crossfade with the default cut mode: %v", err)
+	}
+}
+
+func TestCancelErrors(t *testing.T) {
+	s := &OperationService{
+		operations: map[string]*models.Operation{"done": {ID: "done", Status: models.OperationStatusCompleted}},
+		cancels:    make(map[string]context.CancelFunc),
+		logger:     zap.NewNop(),
+	}
+
+	if _, err := s.Cancel("unknown"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Cancel() of an unknown operation = %v, want not found", err)
+	}
+	if _, err := s.Cancel("done"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Cancel() of a finished operation = %v, want conflict", err)
+	}
+}
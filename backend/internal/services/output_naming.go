@@ -0,0 +1,229 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// Default output name templates, used when a request sets neither output_name nor output_template
+const (
+	defaultMergedTemplate  = "{title}_{date}_{time}"
+	defaultSegmentTemplate = "{title}_{index}_{label}_{start}_{date}_{time}"
+)
+
+// outputNamer renders the file names of one export from a template. Supported
+// placeholders: {name}, {project}, {title}, {date}, {time}, {index}, {label},
+// {start}, {end}.
+type outputNamer struct {
+	template string // User template, applies to every output
+	name     string // Explicit output name, keeps the classic naming scheme
+	project  string
+	title    string // Source file name without extension
+	date     time.Time
+	count    int // Number of segments, for zero-padding {index}
+	base     string
+	dir      string // Where the outputs are written, names of files in it are taken
+	used     map[string]bool
+}
+
+// newOutputNamer creates the namer for an export of segments started at date into dir
+func newOutputNamer(dir string, request models.ExportRequest, project *models.Project, video *models.Video, segments []models.Segment, date time.Time) *outputNamer {
+	n := &outputNamer{
+		dir:      dir,
+		template: request.OutputTemplate,
+		name:     request.OutputName,
+		project:  project.Name,
+		title:    strings.TrimSuffix(video.FileName, filepath.Ext(video.FileName)),
+		date:     date,
+		count:    len(segments),
+		used:     make(map[string]bool),
+	}
+	if n.title == "" {
+		n.title = project.Name
+	}
+
+	// Shared name of the export, used for chapters and the manifest
+	switch {
+	case n.name != "":
+		n.base = sanitizeFileName(n.name)
+	case n.template != "":
+		n.base = n.render(n.template, 0, nil, segments)
+	default:
+		n.base = n.render(defaultMergedTemplate, 0, nil, segments)
+	}
+
+	return n
+}
+
+// Base returns the shared name of the export
+func (n *outputNamer) Base() string {
+	return n.base
+}
+
+// Single returns the file name for an export of a single segment
+func (n *outputNamer) Single(seg models.Segment, ext string) string {
+	switch {
+	case n.template != "":
+		return n.unique(n.render(n.template, 1, &seg, nil), ext)
+	case n.name != "":
+		return n.unique(n.base, ext)
+	default:
+		return n.unique(n.render(defaultSegmentTemplate, 0, &seg, nil), ext)
+	}
+}
+
+// Merged returns the file name for segments merged into one file. suffix marks the
+// merged file when separate segment files are exported alongside it.
+func (n *outputNamer) Merged(segments []models.Segment, ext string, suffix bool) string {
+	name := n.base
+	if n.template != "" {
+		name = n.render(n.template, 0, nil, segments)
+	}
+	if suffix {
+		name += "_merged"
+	}
+	return n.unique(name, ext)
+}
+
+// Segment returns the file name for segment i (0-based) exported as a separate file
+func (n *outputNamer) Segment(i int, seg models.Segment, ext string) string {
+	switch {
+	case n.template != "":
+		return n.unique(n.render(n.template, i+1, &seg, nil), ext)
+	case n.name != "":
+		return n.unique(fmt.Sprintf("%s_segment_%d", n.base, i+1), ext)
+	default:
+		return n.unique(n.render(defaultSegmentTemplate, i+1, &seg, nil), ext)
+	}
+}
+
+// Suffixed returns a file name derived from the shared name, e.g. for chapters
+func (n *outputNamer) Suffixed(suffix, ext string) string {
+	return n.unique(n.base+suffix, ext)
+}
+
+// render fills a template for one output. index is 1-based, 0 for outputs that are
+// not a single segment; seg is nil for merged outputs, whose time range spans segments.
+func (n *outputNamer) render(template string, index int, seg *models.Segment, segments []models.Segment) string {
+	name := n.name
+	if name == "" {
+		name = n.project
+	}
+
+	var indexValue, label, start, end string
+	if index > 0 {
+		width := len(fmt.Sprintf("%d", n.count))
+		indexValue = fmt.Sprintf("%0*d", width, index)
+	}
+
+	if seg != nil {
+		label = seg.Name
+		start = formatNameTimecode(seg.Start)
+		if seg.End != nil {
+			end = formatNameTimecode(*seg.End)
+		}
+	} else if len(segments) > 0 {
+		label = "merged"
+		start = formatNameTimecode(segments[0].Start)
+		if last := segments[len(segments)-1]; last.End != nil {
+			end = formatNameTimecode(*last.End)
+		}
+	}
+
+	// A single pass, so placeholders inside values (e.g. segment names) stay literal
+	replacer := strings.NewReplacer(
+		"{name}", name,
+		"{project}", n.project,
+		"{title}", n.title,
+		"{date}", n.date.Format("2006-01-02"),
+		"{time}", n.date.Format("150405"),
+		"{index}", indexValue,
+		"{label}", label,
+		"{start}", start,
+		"{end}", end,
+	)
+
+	return sanitizeFileName(replacer.Replace(template))
+}
+
+// unique appends the extension and a counter if the name is already taken by
+// another output of the export or a file in the output directory, so earlier
+// outputs are never replaced
+func (n *outputNamer) unique(name, ext string) string {
+	if name == "" {
+		name = "export"
+	}
+
+	taken := func(filename string) bool {
+		if n.used[filename] {
+			return true
+		}
+		_, err := os.Lstat(filepath.Join(n.dir, filename))
+		return !os.IsNotExist(err)
+	}
+
+	filename := fmt.Sprintf("%s.%s", name, ext)
+	for i := 2; taken(filename); i++ {
+		filename = fmt.Sprintf("%s_%d.%s", name, i, ext)
+	}
+	n.used[filename] = true
+
+	return filename
+}
+
+// createOutput creates filename in dir for writing without ever replacing a file:
+// if the name is taken, a counter is added like unique does. The file is created
+// exclusively, so concurrent exports can't pick the same name.
+func createOutput(dir, filename string) (*os.File, error) {
+	ext := filepath.Ext(filename)
+	name := strings.TrimSuffix(filename, ext)
+	path := filepath.Join(dir, filename)
+	for i := 2; ; i++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			return file, err
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s_%d%s", name, i, ext))
+	}
+}
+
+// formatNameTimecode formats seconds for file names, e.g. 00h01m02.500s
+func formatNameTimecode(t float64) string {
+	totalMillis := int64(t*1000 + 0.5)
+	hours := totalMillis / 3600000
+	minutes := (totalMillis % 3600000) / 60000
+	seconds := (totalMillis % 60000) / 1000
+	millis := totalMillis % 1000
+
+	if millis == 0 {
+		return fmt.Sprintf("%02dh%02dm%02ds", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%02dh%02dm%02d.%03ds", hours, minutes, seconds, millis)
+}
+
+var (
+	unsafeFileNameChars = regexp.MustCompile(`[/\\:*?"<>|\x00-\x1f]`)
+	repeatedSeparators  = regexp.MustCompile(`[_ ]{2,}`)
+)
+
+// sanitizeFileName makes a rendered name safe to use as a file name. Separators
+// left over from empty placeholders are collapsed.
+func sanitizeFileName(name string) string {
+	name = unsafeFileNameChars.ReplaceAllString(name, "_")
+	name = repeatedSeparators.ReplaceAllStringFunc(name, func(s string) string {
+		return s[:1]
+	})
+	name = strings.Trim(name, "_-. ")
+
+	if runes := []rune(name); len(runes) > 200 {
+		name = strings.TrimRight(string(runes[:200]), "_-. ")
+	}
+
+	return name
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestOutputNamer(t *testing.T) {
+	end := 75.5
+	seg := models.Segment{ID: "a", Name: "Intro: part/1", Start: 62, End: &end}
+	namer := &outputNamer{
+		project: "Project",
+		title:   "holiday",
+		date:    time.Date(2024, 5, 1, 13, 4, 5, 0, time.UTC),
+		count:   12,
+		dir:     t.TempDir(),
+		used:    make(map[string]bool),
+	}
+
+	if got, want := namer.Segment(2, seg, "mp4"), "holiday_03_Intro_part_1_00h01m02s_2024-05-01_130405.mp4"; got != want {
+		t.Errorf("Segment() = %q, want %q", got, want)
+	}
+
+	unnamed := models.Segment{ID: "b", Start: 0, End: &end}
+	if got, want := namer.Segment(3, unnamed, "mp4"), "holiday_04_00h00m00s_2024-05-01_130405.mp4"; got != want {
+		t.Errorf("Segment() without label = %q, want %q", got, want)
+	}
+
+	namer.template = "{title}-{end}"
+	first := namer.Single(seg, "mkv")
+	second := namer.Single(seg, "mkv")
+	if first != "holiday-00h01m15.500s.mkv" || second != "holiday-00h01m15.500s_2.mkv" {
+		t.Errorf("Single() = %q, %q, want unique names", first, second)
+	}
+
+	// Files already in the output directory are kept
+	namer.base = "holiday"
+	if err := os.WriteFile(filepath.Join(namer.dir, "holiday_manifest.json"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := namer.Suffixed("_manifest", "json"); got != "holiday_manifest_2.json" {
+		t.Errorf("Suffixed() of a taken name = %q, want holiday_manifest_2.json", got)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := map[string]string{
+		"../../etc/passwd": "etc_passwd",
+		"a__b  c":          "a_b c",
+		"":                 "",
+	}
+
+	for input, want := range tests {
+		if got := sanitizeFileName(input); got != want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestCreateOutput(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "clip.srt"), []byte("earlier"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := createOutput(dir, "clip.srt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if filepath.Base(file.Name()) != "clip_2.srt" {
+		t.Errorf("createOutput() = %s, want clip_2.srt", file.Name())
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "clip.srt")); string(data) != "earlier" {
+		t.Errorf("existing output = %q, want it kept", data)
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"go.uber.org/zap"
+)
+
+// outputExpectation is what a media output of an export should contain
+type outputExpectation struct {
+	Duration  float64 // Seconds
+	Tolerance float64 // Allowed duration difference in seconds
+	Video     bool
+	Audio     bool
+}
+
+// validateOutputs probes every media output of an export and returns a warning for
+// each output that is unreadable, has the wrong duration or lacks expected streams.
+// sourceDuration clamps segments that run past the end of the source, 0 if unknown.
+func (s *OperationService) validateOutputs(ctx context.Context, sources []outputSource, sourceDuration float64, mode string, video, audio bool) []string {
+	var warnings []string
+	for _, source := range sources {
+		name := filepath.Base(source.Path)
+		expect := expectOutput(source, sourceDuration, mode)
+		expect.Video = video
+		expect.Audio = audio
+
+		if info, err := os.Stat(source.Path); err != nil || info.Size() == 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: output file is missing or empty", name))
+			continue
+		}
+
+		probe, err := s.ffmpeg.Probe(ctx, source.Path)
+		if err != nil {
+			s.logger.Warn("Failed to probe export output", zap.String("path", source.Path), zap.Error(err))
+			warnings = append(warnings, fmt.Sprintf("%s: output file is not readable", name))
+			continue
+		}
+
+		for _, problem := range checkOutput(probe, expect) {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", name, problem))
+		}
+	}
+
+	return warnings
+}
+
+// expectOutput returns the expected duration of an output and the tolerance for the
+// cut mode. Stream copy cuts snap to keyframes, so they may run up to a GOP longer.
+func expectOutput(source outputSource, sourceDuration float64, mode string) outputExpectation {
+	duration := 0.0
+	for _, seg := range source.Segments {
+		end := segmentEnd(seg)
+		if sourceDuration > 0 {
+			end = math.Min(end, sourceDuration)
+		}
+		duration += math.Max(end-seg.Start, 0)
+	}
+	if len(source.Segments) > 1 {
+		duration -= float64(len(source.Segments)-1) * source.Overlap
+	}
+
+	tolerance := math.Max(0.5, duration*0.02)
+	if copiesStreams(mode) {
+		tolerance = math.Max(2.0*float64(len(source.Segments)), duration*0.05)
+	}
+
+	return outputExpectation{Duration: duration, Tolerance: tolerance}
+}
+
+// checkOutput compares the probe result of an output with what was expected
+func checkOutput(probe *ffmpeg.ProbeResult, expect outputExpectation) []string {
+	var problems []string
+
+	if expect.Video && len(probe.GetVideoStreams()) == 0 {
+		problems = append(problems, "video stream is missing")
+	}
+	if expect.Audio && len(probe.GetAudioStreams()) == 0 {
+		problems = append(problems, "audio stream is missing")
+	}
+
+	duration, err := probe.GetDuration()
+	switch {
+	case err != nil:
+		problems = append(problems, "duration is unknown")
+	case math.Abs(duration-expect.Duration) > expect.Tolerance:
+		problems = append(problems, fmt.Sprintf("duration is %.2fs, expected %.2fs", duration, expect.Duration))
+	}
+
+	return problems
+}
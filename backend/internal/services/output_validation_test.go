@@ -0,0 +1,54 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestExpectOutput(t *testing.T) {
+	end1, end2 := 10.0, 130.0
+	source := outputSource{
+		Segments: []models.Segment{
+			{ID: "a", Start: 0, End: &end1},
+			{ID: "b", Start: 100, End: &end2}, // Runs past the end of the source
+		},
+		Overlap: 1,
+	}
+
+	expect := expectOutput(source, 120, CutModeReencode)
+	if math.Abs(expect.Duration-29) > 1e-9 {
+		t.Errorf("Duration = %g, want 29", expect.Duration)
+	}
+	if math.Abs(expect.Tolerance-0.58) > 1e-9 {
+		t.Errorf("Tolerance = %g, want 2%% of the duration", expect.Tolerance)
+	}
+
+	if lossless := expectOutput(source, 120, CutModeLossless); lossless.Tolerance != 4 {
+		t.Errorf("lossless Tolerance = %g, want 4", lossless.Tolerance)
+	}
+}
+
+func TestCheckOutput(t *testing.T) {
+	probe := &ffmpeg.ProbeResult{
+		Format:  ffmpeg.Format{Duration: "12.400000"},
+		Streams: []ffmpeg.Stream{{CodecType: "video"}},
+	}
+
+	if problems := checkOutput(probe, outputExpectation{Duration: 12, Tolerance: 0.5, Video: true}); len(problems) != 0 {
+		t.Errorf("checkOutput() = %v, want no problems", problems)
+	}
+
+	problems := checkOutput(probe, outputExpectation{Duration: 20, Tolerance: 0.5, Video: true, Audio: true})
+	want := []string{"audio stream is missing", "duration is 12.40s, expected 20.00s"}
+	if len(problems) != len(want) {
+		t.Fatalf("checkOutput() = %v, want %v", problems, want)
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, problems[i], want[i])
+		}
+	}
+}
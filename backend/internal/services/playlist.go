@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// maxPlaylistEntries caps how many items of a playlist one download fetches
+const maxPlaylistEntries = 200
+
+// PlaylistEntry is one item of a playlist as listed by yt-dlp --flat-playlist
+type PlaylistEntry struct {
+	URL        string  `json:"url"`
+	WebpageURL string  `json:"webpage_url"`
+	Title      string  `json:"title"`
+	Duration   float64 `json:"duration"`
+}
+
+// PlaylistInfo is a playlist and its items
+type PlaylistInfo struct {
+	Title   string          `json:"title"`
+	Entries []PlaylistEntry `json:"entries"`
+}
+
+// parsePlaylist parses the output of yt-dlp --flat-playlist --dump-single-json.
+// Entries without a URL, e.g. private or deleted videos, are left out.
+func parsePlaylist(output []byte) (*PlaylistInfo, error) {
+	var info PlaylistInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist: %w", err)
+	}
+
+	entries := info.Entries[:0]
+	for _, entry := range info.Entries {
+		if entry.WebpageURL != "" {
+			entry.URL = entry.WebpageURL
+		}
+		if entry.URL != "" {
+			entries = append(entries, entry)
+		}
+	}
+	info.Entries = entries
+
+	if len(info.Entries) == 0 {
+		return nil, fmt.Errorf("playlist has no downloadable entries")
+	}
+	return &info, nil
+}
+
+// storedChildren loads the saved state of a playlist's items, which their runs change
+// while the playlist reports its progress. Items that can't be loaded are left out.
+func (s *DownloadService) storedChildren(children []*models.Download) []*models.Download {
+	stored := make([]*models.Download, 0, len(children))
+	for _, child := range children {
+		if record, err := s.storage.GetDownload(child.ID); err == nil {
+			stored = append(stored, record)
+		}
+	}
+	return stored
+}
+
+// playlistProgress returns the aggregate progress of a playlist's item downloads.
+// Failed and cancelled items count as done.
+func playlistProgress(children []*models.Download) float64 {
+	if len(children) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, child := range children {
+		switch child.Status {
+		case models.DownloadStatusPending, models.DownloadStatusDownloading, models.DownloadStatusRetrying:
+			total += child.Progress
+		default:
+			total += 100
+		}
+	}
+	return total / float64(len(children))
+}
+
+// getPlaylistInfo lists the items of a playlist without downloading them
+func (s *DownloadService) getPlaylistInfo(ctx context.Context, url string, client models.DownloadClient) (*PlaylistInfo, error) {
+	args := []string{"--flat-playlist", "--dump-single-json", "--playlist-end", strconv.Itoa(maxPlaylistEntries)}
+	args = append(args, ytdlpClientArgs(client)...)
+	cmd := exec.CommandContext(ctx, s.ytdlpPath(), append(args, url)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list playlist: %w", err)
+	}
+	return parsePlaylist(output)
+}
+
+// runPlaylistDownload downloads every item of a playlist as a child download with
+// its own progress, one after another, and imports each as a video. The parent
+// download reports the aggregate progress and lists all imported videos. A resumed
+// playlist reuses its children and downloads only the ones that didn't complete.
+func (s *DownloadService) runPlaylistDownload(ctx context.Context, download *models.Download, req DownloadRequest) {
+	fail := func(err error) {
+		s.setDownload(download, func() {
+			if download.Status != models.DownloadStatusCancelled {
+				download.Status = models.DownloadStatusFailed
+				download.Error = err.Error()
+			}
+		})
+		s.logger.Error("Playlist download failed", zap.String("id", download.ID), zap.Error(err))
+	}
+
+	children, err := s.playlistChildren(ctx, download, req)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	// Publish the aggregate progress while the items download
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				progress := playlistProgress(s.storedChildren(children))
+				s.setDownload(download, func() {
+					download.Progress = progress
+				})
+			}
+		}
+	}()
+
+	for _, child := range children {
+		if child.Status == models.DownloadStatusCompleted {
+			continue
+		}
+		if s.downloadStatus(download) == models.DownloadStatusCancelled {
+			s.setDownload(child, func() {
+				child.Status = models.DownloadStatusCancelled
+			})
+			continue
+		}
+
+		childReq := req
+		childReq.URL = child.URL
+		childReq.Playlist = false
+
+		s.mu.Lock()
+		s.downloads[child.ID] = child
+		s.mu.Unlock()
+
+		// Items retry within the playlist's slot, so the playlist stays in order
+		for {
+			s.runDownload(child.ID, childReq)
+			delay, retry := s.scheduleRetry(child)
+			if !retry {
+				break
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+			s.mu.Lock()
+			retrying := child.Status == models.DownloadStatusRetrying
+			if retrying {
+				child.Error = ""
+				child.NextRetryAt = nil
+			}
+			s.mu.Unlock()
+			if !retrying {
+				break
+			}
+		}
+
+		s.mu.Lock()
+		delete(s.downloads, child.ID)
+		s.mu.Unlock()
+	}
+	close(done)
+
+	// The items are done, only this run and CancelDownload still change them
+	var videoIDs []string
+	failed := 0
+	first := -1
+	s.mu.Lock()
+	for i, child := range children {
+		if child.Status != models.DownloadStatusCompleted {
+			failed++
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		videoIDs = append(videoIDs, child.VideoID)
+	}
+	progress := playlistProgress(children)
+	s.mu.Unlock()
+
+	var cancelled bool
+	s.setDownload(download, func() {
+		download.VideoID = ""
+		download.FilePath = ""
+		if first >= 0 {
+			download.VideoID = children[first].VideoID
+			download.FilePath = children[first].FilePath
+		}
+		download.VideoIDs = videoIDs
+		download.Progress = progress
+		cancelled = download.Status == models.DownloadStatusCancelled
+	})
+
+	switch {
+	case cancelled:
+		s.logger.Info("Playlist download cancelled", zap.String("id", download.ID))
+	case len(children) == 0:
+		s.logger.Info("Playlist has no new items", zap.String("id", download.ID))
+		s.setDownload(download, func() {
+			download.Status = models.DownloadStatusCompleted
+			download.Progress = 100
+		})
+	case len(videoIDs) == 0:
+		fail(fmt.Errorf("none of the %d playlist items could be downloaded", len(children)))
+	default:
+		s.setDownload(download, func() {
+			download.Status = models.DownloadStatusCompleted
+			download.Error = ""
+			if failed > 0 {
+				download.Error = fmt.Sprintf("%d of %d playlist items failed", failed, len(children))
+			}
+		})
+
+		s.logger.Info("Playlist download completed",
+			zap.String("id", download.ID),
+			zap.Int("items", len(children)),
+			zap.Int("failed", failed),
+		)
+	}
+
+	// Clean up from memory
+	s.mu.Lock()
+	delete(s.downloads, download.ID)
+	s.mu.Unlock()
+}
+
+// playlistChildren returns the item downloads of a playlist, creating them from the
+// playlist's entries the first time
+func (s *DownloadService) playlistChildren(ctx context.Context, download *models.Download, req DownloadRequest) ([]*models.Download, error) {
+	if len(download.ChildIDs) > 0 {
+		children := make([]*models.Download, 0, len(download.ChildIDs))
+		for _, id := range download.ChildIDs {
+			child, err := s.storage.GetDownload(id)
+			if err != nil {
+				return nil, err
+			}
+			if child.Status != models.DownloadStatusCompleted {
+				child.Status = models.DownloadStatusPending
+				child.Progress = 0
+				child.Error = ""
+				s.storage.UpdateDownload(child)
+			}
+			children = append(children, child)
+		}
+		return children, nil
+	}
+
+	info, err := s.getPlaylistInfo(ctx, req.URL, s.client(req))
+	if err != nil {
+		return nil, err
+	}
+	s.setDownload(download, func() {
+		download.Title = info.Title
+	})
+
+	// Recurring runs only download what earlier runs didn't
+	var done map[string]bool
+	if download.ScheduleID != "" {
+		done = s.scheduledURLsDone(download.ScheduleID)
+	}
+
+	children := make([]*models.Download, 0, len(info.Entries))
+	for _, entry := range info.Entries {
+		if done[entry.URL] {
+			continue
+		}
+		child := &models.Download{
+			URL:            entry.URL,
+			Format:         download.Format,
+			SessionID:      download.SessionID,
+			Client:         download.Client,
+			Subtitles:      download.Subtitles,
+			SponsorBlock:   download.SponsorBlock,
+			ChapterProject: download.ChapterProject,
+			CreateProject:  download.CreateProject,
+			ProjectName:    download.ProjectName,
+			CookieJar:      download.CookieJar,
+			SealedHeaders:  download.SealedHeaders,
+			RateLimit:      download.RateLimit,
+			Title:          entry.Title,
+			Duration:       entry.Duration,
+			ParentID:       download.ID,
+			ScheduleID:     download.ScheduleID,
+			Section:        download.Section,
+			Status:         models.DownloadStatusPending,
+		}
+		if err := s.storage.CreateDownload(child); err != nil {
+			return nil, fmt.Errorf("failed to create download record: %w", err)
+		}
+		children = append(children, child)
+	}
+	s.setDownload(download, func() {
+		for _, child := range children {
+			download.ChildIDs = append(download.ChildIDs, child.ID)
+		}
+	})
+
+	s.logger.Info("Listed playlist",
+		zap.String("id", download.ID),
+		zap.String("title", info.Title),
+		zap.Int("items", len(children)),
+	)
+
+	return children, nil
+}
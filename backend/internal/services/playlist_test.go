@@ -0,0 +1,55 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestParsePlaylist(t *testing.T) {
+	output := []byte(`{
+		"_type": "playlist",
+		"title": "Conference talks",
+		"entries": [
+			{"_type": "url", "url": "https://www.youtube.com/watch?v=aaa", "title": "Keynote", "duration": 3600},
+			{"_type": "url", "url": "bbb", "webpage_url": "https://vimeo.com/bbb", "title": "Panel"},
+			{"_type": "url", "title": "[Private video]"}
+		]
+	}`)
+
+	info, err := parsePlaylist(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Title != "Conference talks" {
+		t.Errorf("title = %q, want %q", info.Title, "Conference talks")
+	}
+	if len(info.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(info.Entries), info.Entries)
+	}
+	if info.Entries[0].URL != "https://www.youtube.com/watch?v=aaa" || info.Entries[0].Duration != 3600 {
+		t.Errorf("entry 0 = %+v", info.Entries[0])
+	}
+	if info.Entries[1].URL != "https://vimeo.com/bbb" {
+		t.Errorf("entry 1 URL = %q, want the webpage URL", info.Entries[1].URL)
+	}
+
+	if _, err := parsePlaylist([]byte(`{"title": "Empty", "entries": []}`)); err == nil {
+		t.Error("expected an error for a playlist without entries")
+	}
+}
+
+func TestPlaylistProgress(t *testing.T) {
+	children := []*models.Download{
+		{Status: models.DownloadStatusCompleted, Progress: 100},
+		{Status: models.DownloadStatusDownloading, Progress: 50},
+		{Status: models.DownloadStatusPending},
+		{Status: models.DownloadStatusFailed, Progress: 10},
+	}
+	if got := playlistProgress(children); got != 62.5 {
+		t.Errorf("playlistProgress() = %g, want 62.5", got)
+	}
+	if got := playlistProgress(nil); got != 0 {
+		t.Errorf("playlistProgress(nil) = %g, want 0", got)
+	}
+}
@@ -1,10 +1,8 @@
 package services
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +14,7 @@ import (
 type ProjectService struct {
 	storage *storage.Manager
 	logger  *zap.Logger
+	mu      sync.Mutex // Serializes saves, so Modify doesn't lose concurrent changes
 }
 
 func NewProjectService(storage *storage.Manager, logger *zap.Logger) *ProjectService {
@@ -44,67 +43,47 @@ func (s *ProjectService) Create(name string, videoID string) (*models.Project, e
 }
 
 func (s *ProjectService) Get(id string) (*models.Project, error) {
-	path := s.storage.GetProjectPath(id)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("project not found: %s", id)
-		}
-		return nil, fmt.Errorf("failed to read project: %w", err)
-	}
-
-	var project models.Project
-	if err := json.Unmarshal(data, &project); err != nil {
-		return nil, fmt.Errorf("failed to parse project: %w", err)
-	}
-
-	return &project, nil
+	return s.storage.GetProject(id)
 }
 
 func (s *ProjectService) List() ([]*models.Project, error) {
-	projectsDir := s.storage.ProjectsDir()
-	entries, err := os.ReadDir(projectsDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read projects directory: %w", err)
-	}
+	return s.storage.ListProjects()
+}
 
-	var projects []*models.Project
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".llc" {
-			continue
-		}
+func (s *ProjectService) Save(project *models.Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save(project)
+}
 
-		projectID := entry.Name()[:len(entry.Name())-4] // Remove .llc extension
-		project, err := s.Get(projectID)
-		if err != nil {
-			s.logger.Warn("Failed to load project", zap.String("id", projectID), zap.Error(err))
-			continue
-		}
-		projects = append(projects, project)
-	}
+func (s *ProjectService) save(project *models.Project) error {
+	project.UpdatedAt = time.Now()
 
-	return projects, nil
+	return s.storage.SaveProject(project)
 }
 
-func (s *ProjectService) Save(project *models.Project) error {
-	project.UpdatedAt = time.Now()
+// Modify loads a project, changes it and saves it under the service lock, so changes
+// made since a long running caller first loaded it aren't lost. Nothing is saved
+// when modify fails.
+func (s *ProjectService) Modify(id string, modify func(project *models.Project) error) (*models.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	data, err := json.MarshalIndent(project, "", "  ")
+	project, err := s.Get(id)
 	if err != nil {
-		return fmt.Errorf("failed to marshal project: %w", err)
+		return nil, err
 	}
-
-	path := s.storage.GetProjectPath(project.ID)
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write project file: %w", err)
+	if err := modify(project); err != nil {
+		return nil, err
 	}
-
-	return nil
+	if err := s.save(project); err != nil {
+		return nil, fmt.Errorf("failed to save project: %w", err)
+	}
+	return project, nil
 }
 
 func (s *ProjectService) Delete(id string) error {
-	path := s.storage.GetProjectPath(id)
-	if err := s.storage.DeleteFile(path); err != nil {
+	if err := s.storage.DeleteProject(id); err != nil {
 		return fmt.Errorf("failed to delete project: %w", err)
 	}
 
@@ -113,56 +92,42 @@ func (s *ProjectService) Delete(id string) error {
 }
 
 func (s *ProjectService) AddSegment(projectID string, segment models.Segment) error {
-	project, err := s.Get(projectID)
-	if err != nil {
-		return err
-	}
-
 	if segment.ID == "" {
 		segment.ID = uuid.New().String()
 	}
 
-	project.Segments = append(project.Segments, segment)
-	return s.Save(project)
+	_, err := s.Modify(projectID, func(project *models.Project) error {
+		project.Segments = append(project.Segments, segment)
+		return nil
+	})
+	return err
 }
 
 func (s *ProjectService) UpdateSegment(projectID string, segmentID string, updates models.Segment) error {
-	project, err := s.Get(projectID)
-	if err != nil {
-		return err
-	}
-
-	found := false
-	for i, seg := range project.Segments {
-		if seg.ID == segmentID {
-			// Preserve ID
-			updates.ID = segmentID
-			project.Segments[i] = updates
-			found = true
-			break
+	_, err := s.Modify(projectID, func(project *models.Project) error {
+		for i, seg := range project.Segments {
+			if seg.ID == segmentID {
+				// Preserve ID
+				updates.ID = segmentID
+				project.Segments[i] = updates
+				return nil
+			}
 		}
-	}
-
-	if !found {
 		return fmt.Errorf("segment not found: %s", segmentID)
-	}
-
-	return s.Save(project)
+	})
+	return err
 }
 
 func (s *ProjectService) DeleteSegment(projectID string, segmentID string) error {
-	project, err := s.Get(projectID)
-	if err != nil {
-		return err
-	}
-
-	segments := make([]models.Segment, 0, len(project.Segments))
-	for _, seg := range project.Segments {
-		if seg.ID != segmentID {
-			segments = append(segments, seg)
+	_, err := s.Modify(projectID, func(project *models.Project) error {
+		segments := make([]models.Segment, 0, len(project.Segments))
+		for _, seg := range project.Segments {
+			if seg.ID != segmentID {
+				segments = append(segments, seg)
+			}
 		}
-	}
-
-	project.Segments = segments
-	return s.Save(project)
+		project.Segments = segments
+		return nil
+	})
+	return err
 }
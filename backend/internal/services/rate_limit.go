@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+)
+
+// downloadRateLimit returns the bandwidth cap of a download in bytes per second,
+// 0 = unlimited. A download can ask for less than the configured cap, not more.
+func downloadRateLimit(configured, requested string) (int64, error) {
+	limit, err := config.ParseRateLimit(configured)
+	if err != nil {
+		return 0, err
+	}
+	wanted, err := config.ParseRateLimit(requested)
+	if err != nil {
+		return 0, fmt.Errorf("rate_limit: %w", err)
+	}
+	if wanted > 0 && (limit == 0 || wanted < limit) {
+		return wanted, nil
+	}
+	return limit, nil
+}
+
+// rateLimitedReader is a token bucket around a reader: it reads at most rate bytes
+// per second on average, with bursts of up to one second's worth
+type rateLimitedReader struct {
+	r      io.Reader
+	rate   int64
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newRateLimitedReader(r io.Reader, rate int64) *rateLimitedReader {
+	return &rateLimitedReader{r: r, rate: rate, tokens: float64(rate), last: time.Now(), now: time.Now, sleep: time.Sleep}
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.rate {
+		p = p[:l.rate]
+	}
+	n, err := l.r.Read(p)
+	l.wait(n)
+	return n, err
+}
+
+// wait takes n tokens from the bucket and sleeps until the bucket is out of debt
+func (l *rateLimitedReader) wait(n int) {
+	now := l.now()
+	l.tokens = math.Min(float64(l.rate), l.tokens+now.Sub(l.last).Seconds()*float64(l.rate))
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		l.sleep(time.Duration(-l.tokens / float64(l.rate) * float64(time.Second)))
+	}
+}
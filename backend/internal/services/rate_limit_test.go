@@ -0,0 +1,51 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestDownloadRateLimit(t *testing.T) {
+	tests := []struct {
+		configured, requested string
+		want                  int64
+	}{
+		{"", "", 0},
+		{"", "1M", 1 << 20},
+		{"2M", "", 2 << 20},
+		{"2M", "1M", 1 << 20},
+		{"2M", "10M", 2 << 20}, // Can't exceed the configured cap
+	}
+	for _, tt := range tests {
+		if got, err := downloadRateLimit(tt.configured, tt.requested); err != nil || got != tt.want {
+			t.Errorf("downloadRateLimit(%q, %q) = %d, %v, want %d", tt.configured, tt.requested, got, err, tt.want)
+		}
+	}
+	if _, err := downloadRateLimit("", "fast"); err == nil {
+		t.Error("expected an error for an invalid rate limit")
+	}
+}
+
+func TestRateLimitedReader(t *testing.T) {
+	clock := time.Unix(0, 0)
+	var slept time.Duration
+
+	limited := newRateLimitedReader(bytes.NewReader(make([]byte, 5000)), 1000)
+	limited.last = clock
+	limited.now = func() time.Time { return clock }
+	limited.sleep = func(d time.Duration) {
+		slept += d
+		clock = clock.Add(d)
+	}
+
+	n, err := io.Copy(io.Discard, limited)
+	if err != nil || n != 5000 {
+		t.Fatalf("io.Copy() = %d, %v", n, err)
+	}
+	// The first second's worth is a burst, the remaining 4000 bytes take 4 seconds
+	if slept != 4*time.Second {
+		t.Errorf("slept %v, want 4s", slept)
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// remoteSchemes are the URL schemes downloaded with curl
+var remoteSchemes = map[string]bool{"ftp": true, "ftps": true, "sftp": true}
+
+// remoteExtensionPattern matches file extensions kept for downloaded files, so raw
+// recorder files like .MTS or .MXF keep theirs
+var remoteExtensionPattern = regexp.MustCompile(`^\.[a-z0-9]{1,5}$`)
+
+// isRemoteURL reports whether a download URL is an ftp://, ftps:// or sftp:// file
+func isRemoteURL(raw string) bool {
+	scheme, _, ok := strings.Cut(raw, "://")
+	return ok && remoteSchemes[strings.ToLower(scheme)]
+}
+
+// remoteLogin splits the login off an FTP/SFTP URL. The URL keeps the user name,
+// the returned login is "user:password" when the URL has a password, which is never
+// stored with the download.
+func remoteLogin(raw string) (string, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Host == "" || u.Path == "" || strings.HasSuffix(u.Path, "/") {
+		return "", "", fmt.Errorf("%s URLs must name a file, e.g. %s://host/path/video.mp4", u.Scheme, u.Scheme)
+	}
+
+	var login string
+	if password, ok := u.User.Password(); ok {
+		login = u.User.Username() + ":" + password
+		u.User = url.User(u.User.Username())
+	}
+	return u.String(), login, nil
+}
+
+// remoteSSHKey returns the configured SSH key for an sftp:// URL to one of the
+// hosts it may log in to, or "". Other hosts never get the server's identity.
+func remoteSSHKey(cfg config.RemoteConfig, raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || cfg.SSHKeyFile == "" || !strings.EqualFold(u.Scheme, "sftp") {
+		return ""
+	}
+	for _, host := range cfg.SSHKeyHosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return cfg.SSHKeyFile
+		}
+	}
+	return ""
+}
+
+// curlLoginConfig returns a curl config file holding a login, so it isn't visible in
+// the process list
+func curlLoginConfig(login string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(login)
+	return fmt.Sprintf("user = \"%s\"\n", escaped)
+}
+
+// curlArgs builds the curl arguments fetching a remote file to output, continuing
+// a partial file. Brackets and braces in the URL are taken literally, curl would
+// otherwise expand them into many transfers into the one output. configFile holds the login, if any, and sshKeyFile the key for
+// an sftp:// host from remoteSSHKey.
+func curlArgs(raw, output, configFile, sshKeyFile string, rateLimit int64) []string {
+	args := []string{"--show-error", "--globoff", "--connect-timeout", "30", "--continue-at", "-", "--output", output}
+	if configFile != "" {
+		args = append(args, "--config", configFile)
+	}
+	if sshKeyFile != "" && strings.HasPrefix(strings.ToLower(raw), "sftp://") {
+		args = append(args, "--key", sshKeyFile)
+	}
+	if rateLimit > 0 {
+		args = append(args, "--limit-rate", strconv.FormatInt(rateLimit, 10))
+	}
+	return append(args, raw)
+}
+
+// curlProgress is one line of curl's progress meter
+type curlProgress struct {
+	percent  float64
+	total    int64 // Bytes of this transfer, without the part a resumed file had
+	received int64
+	speed    float64 // Current bytes per second
+}
+
+// curlSizeUnits are the suffixes curl prints sizes with
+var curlSizeUnits = map[byte]float64{'k': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40, 'P': 1 << 50}
+
+// parseCurlSize parses a size of curl's progress meter like "512", "1234k" or "1.5G"
+func parseCurlSize(value string) (int64, bool) {
+	multiplier := 1.0
+	if unit, ok := curlSizeUnits[value[len(value)-1]]; ok {
+		multiplier = unit
+		value = value[:len(value)-1]
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, false
+	}
+	return int64(number * multiplier), true
+}
+
+// parseCurlProgress parses a line of curl's progress meter, e.g.
+// "  3  100M    3 3456k    0     0  1234k      0  0:01:22  0:00:02  0:01:20 1234k"
+func parseCurlProgress(line string) (curlProgress, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 12 {
+		return curlProgress{}, false
+	}
+	percent, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return curlProgress{}, false
+	}
+	total, ok1 := parseCurlSize(fields[1])
+	received, ok2 := parseCurlSize(fields[3])
+	speed, ok3 := parseCurlSize(fields[11])
+	if !ok1 || !ok2 || !ok3 {
+		return curlProgress{}, false
+	}
+	return curlProgress{percent: percent, total: total, received: received, speed: float64(speed)}, true
+}
+
+// scanCurlLines splits curl's stderr into lines, which its progress meter ends with
+// a carriage return
+func scanCurlLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// runRemoteDownload fetches an ftp://, ftps:// or sftp:// file with curl and imports
+// it. A resumed download continues its partial file.
+func (s *DownloadService) runRemoteDownload(ctx context.Context, download *models.Download, req DownloadRequest) {
+	fail := func(err error) {
+		s.logger.Error("Remote download failed", zap.String("id", download.ID), zap.Error(err))
+		s.setDownload(download, func() {
+			download.Status = models.DownloadStatusFailed
+			download.Error = err.Error()
+			download.Speed = 0
+			download.ETA = 0
+		})
+	}
+
+	outputDir := s.storage.GetDownloadPath()
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fail(err)
+		return
+	}
+
+	// A resumed download keeps its file, a new one is named after the remote file.
+	// The path is stored up front so the download can be resumed.
+	outputPath := download.FilePath
+	if outputPath == "" {
+		download.Title = s.getTitleFromURL(req.URL)
+		ext := ""
+		if u, err := url.Parse(req.URL); err == nil {
+			ext = strings.ToLower(filepath.Ext(u.Path))
+		}
+		if !remoteExtensionPattern.MatchString(ext) {
+			ext = s.getExtensionFromURL(req.URL)
+		}
+		outputPath = filepath.Join(outputDir, s.downloadName(outputDir, download, req, download.Title)+ext)
+		download.FilePath = outputPath
+	}
+	var offset int64
+	if info, err := os.Stat(outputPath); err == nil {
+		offset = info.Size()
+	}
+	download.DownloadedBytes = offset
+	s.storage.UpdateDownload(download)
+
+	rate, _ := downloadRateLimit(s.config.Download.RateLimit, req.RateLimit)
+	args := curlArgs(req.URL, outputPath, req.CookiesFile, remoteSSHKey(s.config.Remote, req.URL), rate)
+	cmd := exec.CommandContext(ctx, s.config.Remote.CurlPath, args...)
+	interruptOnCancel(cmd)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	s.logger.Info("Starting remote download",
+		zap.String("id", download.ID),
+		zap.String("url", req.URL),
+		zap.Int64("offset", offset),
+	)
+	if err := cmd.Start(); err != nil {
+		fail(fmt.Errorf("failed to start curl: %w", err))
+		return
+	}
+
+	// curl reports the part it transfers; a resumed file already had offset bytes
+	var lastError string
+	lastUpdate := time.Now()
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanCurlLines)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "curl:") {
+			lastError = line
+			continue
+		}
+		progress, ok := parseCurlProgress(line)
+		if !ok || time.Since(lastUpdate) < 500*time.Millisecond {
+			continue
+		}
+		lastUpdate = time.Now()
+		s.setDownload(download, func() {
+			download.DownloadedBytes = offset + progress.received
+			download.Speed = progress.speed
+			if progress.total > 0 {
+				download.TotalBytes = offset + progress.total
+				download.Progress = float64(download.DownloadedBytes) / float64(download.TotalBytes) * 100
+				download.ETA = downloadETA(download.TotalBytes-download.DownloadedBytes, download.Speed)
+			}
+		})
+	}
+
+	if err := cmd.Wait(); err != nil || ctx.Err() != nil {
+		// CancelDownload already saved the download as cancelled
+		if ctx.Err() != nil {
+			s.logger.Info("Download cancelled", zap.String("id", download.ID))
+			os.Remove(outputPath)
+			s.setDownload(download, func() {
+				download.FilePath = ""
+				download.DownloadedBytes = 0
+			})
+			return
+		}
+		if lastError != "" {
+			err = errors.New(lastError)
+		}
+		fail(err)
+		return
+	}
+
+	size, err := s.storage.GetFileSize(outputPath)
+	if err != nil {
+		fail(err)
+		return
+	}
+	video, err := s.importDownload(filepath.Base(outputPath), outputPath)
+	if err != nil {
+		fail(fmt.Errorf("failed to import video: %w", err))
+		return
+	}
+	video.OriginalURL = download.URL
+
+	s.setDownload(download, func() {
+		s.createDownloadProject(download, video, "")
+		download.VideoID = video.ID
+		download.Status = models.DownloadStatusCompleted
+		download.Progress = 100.0
+		download.DownloadedBytes = size
+		download.TotalBytes = size
+		download.Speed = 0
+		download.ETA = 0
+	})
+	s.storage.RecordUsage(models.UsageDownloads, 1, video.FileSize)
+	s.importChecksum(video)
+
+	s.logger.Info("Remote download completed and imported",
+		zap.String("id", download.ID),
+		zap.String("file", outputPath),
+		zap.String("video_id", video.ID),
+	)
+
+	// Clean up from memory
+	s.mu.Lock()
+	delete(s.downloads, download.ID)
+	s.mu.Unlock()
+}
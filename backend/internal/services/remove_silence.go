@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// SilenceRemovalRequest represents a request to turn a project's video into
+// segments that skip its silences, e.g. for podcast or jump-cut editing
+type SilenceRemovalRequest struct {
+	Preset     string  `json:"preset,omitempty"`      // Detection preset for the defaults below
+	NoiseDB    float64 `json:"noise_db,omitempty"`    // Silence noise floor, e.g. -30
+	MinSilence float64 `json:"min_silence,omitempty"` // Shortest silence to remove, seconds
+
+	PaddingBefore    float64 `json:"padding_before,omitempty"`     // Seconds of silence kept before speech
+	PaddingAfter     float64 `json:"padding_after,omitempty"`      // Seconds of silence kept after speech
+	MinSegmentLength float64 `json:"min_segment_length,omitempty"` // Speech shorter than this is dropped, seconds
+
+	Apply string `json:"apply,omitempty"` // "" = suggest only, "append" or "replace" project segments
+}
+
+// RemoveSilence detects the silences in a project's video and returns the speech
+// between them as segments, padded and filtered as requested. Exporting the
+// segments merged cuts the silences out.
+func (s *AnalysisService) RemoveSilence(ctx context.Context, projectID string, req SilenceRemovalRequest) ([]models.Segment, error) {
+	project, err := s.projectService.Get(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Apply != "" && req.Apply != "append" && req.Apply != "replace" {
+		return nil, invalidf("invalid apply mode: %s", req.Apply)
+	}
+	if req.PaddingBefore < 0 || req.PaddingAfter < 0 || req.MinSegmentLength < 0 {
+		return nil, invalidf("padding and minimum segment length must not be negative")
+	}
+
+	video, err := s.storage.GetVideo(project.VideoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	if video.Duration <= 0 {
+		return nil, invalidf("video duration unknown")
+	}
+
+	detection, err := s.Detect(ctx, project.VideoID, DetectionRequest{
+		Mode:        "silence",
+		Preset:      req.Preset,
+		MinDuration: req.MinSilence,
+		NoiseDB:     req.NoiseDB,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	segments := speechSegments(detection.Scenes, video.Duration, req.PaddingBefore, req.PaddingAfter, req.MinSegmentLength)
+
+	// Detection takes a while, the segments go into the project as it is now
+	if req.Apply != "" {
+		_, err := s.projectService.Modify(projectID, func(project *models.Project) error {
+			if req.Apply == "replace" {
+				project.Segments = segments
+			} else {
+				project.Segments = append(project.Segments, segments...)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("Removed silence",
+		zap.String("projectId", projectID),
+		zap.Int("silences", len(detection.Scenes)),
+		zap.Int("segments", len(segments)),
+		zap.String("apply", req.Apply),
+	)
+
+	return segments, nil
+}
+
+// speechSegments inverts silent ranges into the ranges between them. Ranges
+// shorter than minLength are dropped before padding, then padding extends each
+// range into the surrounding silence and overlapping ranges are merged.
+func speechSegments(silences []ffmpeg.Scene, duration, padBefore, padAfter, minLength float64) []models.Segment {
+	type span struct{ start, end float64 }
+
+	var speech []span
+	start := 0.0
+	for _, silence := range silences {
+		end := silence.End
+		if end <= silence.Start {
+			end = duration // Silence runs until the end of the input
+		}
+		if silence.Start-start > 0 && silence.Start-start >= minLength {
+			speech = append(speech, span{start, math.Min(silence.Start, duration)})
+		}
+		start = math.Max(start, end)
+	}
+	if duration-start > 0 && duration-start >= minLength {
+		speech = append(speech, span{start, duration})
+	}
+
+	var merged []span
+	for _, s := range speech {
+		s.start = math.Max(0, s.start-padBefore)
+		s.end = math.Min(duration, s.end+padAfter)
+		if len(merged) > 0 && s.start <= merged[len(merged)-1].end {
+			merged[len(merged)-1].end = s.end
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	segments := make([]models.Segment, 0, len(merged))
+	for i, s := range merged {
+		end := s.end
+		segments = append(segments, models.Segment{
+			ID:    uuid.New().String(),
+			Name:  fmt.Sprintf("Speech %d", i+1),
+			Start: s.start,
+			End:   &end,
+		})
+	}
+
+	return segments
+}
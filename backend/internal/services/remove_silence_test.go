@@ -0,0 +1,42 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+)
+
+func TestSpeechSegments(t *testing.T) {
+	silences := []ffmpeg.Scene{
+		{Start: -0.01, End: 2, Type: "silent"}, // Leading silence
+		{Start: 10, End: 12, Type: "silent"},
+		{Start: 12.3, End: 20, Type: "silent"}, // Too little speech before it
+		{Start: 20.5, End: 21, Type: "silent"}, // Padding merges around it
+		{Start: 28, Type: "silent"},            // Runs until the end
+	}
+
+	segments := speechSegments(silences, 30, 0.25, 0.5, 0.5)
+
+	want := [][2]float64{{1.75, 10.5}, {19.75, 28.5}}
+	if len(segments) != len(want) {
+		t.Fatalf("got %d segments, want %d: %+v", len(segments), len(want), segments)
+	}
+	for i, w := range want {
+		s := segments[i]
+		if math.Abs(s.Start-w[0]) > 1e-9 || s.End == nil || math.Abs(*s.End-w[1]) > 1e-9 {
+			t.Errorf("segment %d = %g-%v, want %g-%g", i, s.Start, s.End, w[0], w[1])
+		}
+	}
+	if segments[1].Name != "Speech 2" {
+		t.Errorf("segment name = %q, want Speech 2", segments[1].Name)
+	}
+}
+
+func TestSpeechSegmentsNoSilence(t *testing.T) {
+	segments := speechSegments(nil, 30, 0.2, 0.2, 0)
+
+	if len(segments) != 1 || segments[0].Start != 0 || *segments[0].End != 30 {
+		t.Errorf("speechSegments() = %+v, want the whole video", segments)
+	}
+}
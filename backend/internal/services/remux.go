@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// RemuxRequest is the body of a remux request
+type RemuxRequest struct {
+	Format string `json:"format" binding:"required"` // A video output format, e.g. "mp4" or "mkv"
+}
+
+// Remux copies a whole video into another container in the background, e.g. an MKV
+// download into MP4 so browsers can play it. The result is written to the outputs
+// directory; every stream must fit the new container without re-encoding.
+func (s *OperationService) Remux(videoID string, req RemuxRequest) (*models.Operation, error) {
+	profile, ok := ffmpeg.GetOutputProfile(req.Format)
+	if !ok {
+		return nil, fmt.Errorf("invalid remux format: %s", req.Format)
+	}
+	format := profile.Format
+
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	if err := checkRemuxStreams(video.Metadata.Streams, format); err != nil {
+		return nil, err
+	}
+
+	namer := newOutputNamer(s.storage.OutputsDir(), models.ExportRequest{}, &models.Project{Name: video.FileName}, video, nil, time.Now())
+	outputPath := s.storage.GetOutputPath(namer.Suffixed("", format))
+
+	operation := &models.Operation{
+		ID:        uuid.New().String(),
+		Type:      models.OperationTypeRemux,
+		Status:    models.OperationStatusPending,
+		CreatedAt: time.Now(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.operations[operation.ID] = operation
+	s.cancels[operation.ID] = cancel
+	snapshot := *operation
+	s.mu.Unlock()
+
+	go s.runRemux(ctx, operation, video, outputPath, format)
+
+	return &snapshot, nil
+}
+
+// checkRemuxStreams returns an error naming the first stream the container can't hold
+func checkRemuxStreams(streams []models.Stream, format string) error {
+	for _, stream := range streams {
+		if !ffmpeg.ContainerSupports(format, stream.CodecType, stream.CodecName) {
+			return fmt.Errorf("%s can't hold %s stream %d (%s) without re-encoding, remux to mkv instead",
+				format, stream.CodecType, stream.Index, stream.CodecName)
+		}
+	}
+	return nil
+}
+
+func (s *OperationService) runRemux(ctx context.Context, operation *models.Operation, video *models.Video, outputPath, format string) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.cancels, operation.ID)
+		s.mu.Unlock()
+	}()
+
+	s.mu.Lock()
+	operation.Status = models.OperationStatusProcessing
+	s.mu.Unlock()
+
+	remuxCtx := ffmpeg.WithCommandRecorder(ctx, func(command string) {
+		s.mu.Lock()
+		operation.Commands = append(operation.Commands, command)
+		s.mu.Unlock()
+	})
+	remuxCtx = ffmpeg.WithStatsRecorder(remuxCtx, func(stats ffmpeg.Stats) {
+		s.mu.Lock()
+		operation.Speed = stats.Speed
+		s.mu.Unlock()
+	})
+
+	started := time.Now()
+	err := s.ffmpeg.ConvertFormat(remuxCtx, video.FilePath, outputPath, format, s.videoDuration(ctx, video), func(progress float64) {
+		s.mu.Lock()
+		operation.Progress = progress * 100
+		operation.ETA = estimateRemaining(time.Since(started), progress)
+		s.mu.Unlock()
+	})
+	if err == nil && ctx.Err() == nil {
+		err = s.storage.StoreFile(outputPath)
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	operation.Speed = 0
+	operation.ETA = 0
+	operation.CompletedAt = &now
+
+	switch {
+	case ctx.Err() != nil:
+		os.Remove(outputPath)
+		operation.Status = models.OperationStatusCancelled
+		operation.Error = "cancelled"
+		s.logger.Info("Remux cancelled", zap.String("operationId", operation.ID))
+	case err != nil:
+		os.Remove(outputPath)
+		operation.Status = models.OperationStatusFailed
+		operation.Error = err.Error()
+		s.logger.Error("Remux failed", zap.String("operationId", operation.ID), zap.Error(err))
+	default:
+		operation.Status = models.OperationStatusCompleted
+		operation.Progress = 100
+		operation.OutputFiles = []string{outputPath}
+		s.logger.Info("Remux completed",
+			zap.String("operationId", operation.ID),
+			zap.String("videoId", video.ID),
+			zap.String("output", outputPath),
+		)
+	}
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestCheckRemuxStreams(t *testing.T) {
+	streams := []models.Stream{
+		{Index: 0, CodecType: "video", CodecName: "h264"},
+		{Index: 1, CodecType: "audio", CodecName: "aac"},
+		{Index: 2, CodecType: "subtitle", CodecName: "subrip"},
+	}
+
+	if err := checkRemuxStreams(streams, "mkv"); err != nil {
+		t.Errorf("mkv: %v", err)
+	}
+	if err := checkRemuxStreams(streams[:2], "mp4"); err != nil {
+		t.Errorf("mp4 without subtitles: %v", err)
+	}
+	if err := checkRemuxStreams(streams, "mp4"); err == nil {
+		t.Error("expected mp4 to reject subrip subtitles")
+	}
+}
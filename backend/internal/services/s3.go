@@ -0,0 +1,52 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/s3"
+)
+
+// isS3URL reports whether a download URL names an object as s3://bucket/key
+func isS3URL(raw string) bool {
+	return strings.HasPrefix(strings.ToLower(raw), "s3://")
+}
+
+// s3ObjectURL returns the HTTP URL of the object an s3://bucket/key URL names, on AWS
+// or the configured endpoint
+func s3ObjectURL(cfg config.S3Config, raw string) (*url.URL, error) {
+	bucket, key, _ := strings.Cut(raw[len("s3://"):], "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("s3 URLs must name an object as s3://bucket/key")
+	}
+	return s3.ObjectURL(cfg, bucket, key)
+}
+
+// checkS3Bucket checks that an s3:// download may read the bucket it names. Signed
+// requests carry the server's credentials, so they only go to s3.download_buckets,
+// and never to the media bucket, which holds every session's files.
+func checkS3Bucket(cfg *config.Config, raw string) error {
+	bucket, _, _ := strings.Cut(raw[len("s3://"):], "/")
+	if cfg.Storage.MediaBucket != "" && bucket == cfg.Storage.MediaBucket {
+		return fmt.Errorf("bucket %s can't be downloaded from", bucket)
+	}
+	if cfg.S3.AccessKeyID == "" {
+		return nil
+	}
+	for _, allowed := range cfg.S3.DownloadBuckets {
+		if bucket == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("bucket %s is not in s3.download_buckets", bucket)
+}
+
+// signS3Request signs a bodiless download request. Requests without configured
+// credentials stay unsigned and can only read public objects.
+func signS3Request(req *http.Request, cfg config.S3Config, now time.Time) {
+	s3.Sign(req, cfg, s3.EmptyPayloadHash, now)
+}
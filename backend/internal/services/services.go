@@ -1,30 +1,122 @@
 package services
 
 import (
+	"context"
+	"time"
+
 	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
 	"github.com/mifi/lossless-cut/backend/internal/storage"
 	"go.uber.org/zap"
 )
 
 // Services holds all application services
 type Services struct {
-	Project   *ProjectService
-	Video     *VideoService
-	Operation *OperationService
-	Download  *DownloadService
-	Storage   *storage.Manager
-	Logger    *zap.Logger
+	Project      *ProjectService
+	Video        *VideoService
+	Operation    *OperationService
+	Download     *DownloadService
+	Subscription *SubscriptionService
+	Analysis     *AnalysisService
+	Storage      *storage.Manager
+	FFmpeg       *ffmpeg.ProcessLimiter // Shared by every service's executor
+	Logger       *zap.Logger
+
+	config *config.Config
 }
 
 // NewServices creates a new services instance
 func NewServices(storageManager *storage.Manager, cfg *config.Config, logger *zap.Logger) *Services {
-	videoService := NewVideoService(storageManager, cfg, logger)
-	return &Services{
-		Project:   NewProjectService(storageManager, logger),
-		Video:     videoService,
-		Operation: NewOperationService(storageManager, cfg, logger),
-		Download:  NewDownloadService(storageManager, videoService, cfg, logger),
-		Storage:   storageManager,
-		Logger:    logger,
+	storageManager.SetTrashRetention(time.Duration(cfg.Storage.TrashRetentionDays) * 24 * time.Hour)
+	// JSON records keep working when the database can't be opened; they are imported
+	// the next time it opens
+	if err := storageManager.OpenMetadata(cfg.Storage.Metadata); err != nil {
+		logger.Error("Failed to open metadata database, using JSON files", zap.Error(err))
+	}
+	if err := storageManager.OpenMedia(cfg.Storage.Media, cfg.S3, cfg.Storage.MediaBucket, cfg.Storage.MediaPrefix); err != nil {
+		logger.Error("Failed to open media store, keeping media on local disk only", zap.Error(err))
+	}
+
+	operationService := NewOperationService(storageManager, cfg, logger)
+	videoService := NewVideoService(storageManager, operationService, cfg, logger)
+	projectService := NewProjectService(storageManager, logger)
+	downloadService := NewDownloadService(storageManager, videoService, projectService, cfg, logger)
+	analysisService := NewAnalysisService(storageManager, projectService, cfg, logger)
+
+	// One limiter for all executors, so max_processes holds server-wide
+	limiter := ffmpeg.NewProcessLimiter(cfg.FFmpeg.MaxProcesses)
+	for _, executor := range []*ffmpeg.Executor{operationService.ffmpeg, videoService.ffmpeg, analysisService.ffmpeg, downloadService.ffmpeg} {
+		executor.SetProcessLimiter(limiter)
+	}
+
+	services := &Services{
+		Project:      projectService,
+		Video:        videoService,
+		Operation:    operationService,
+		Download:     downloadService,
+		Subscription: NewSubscriptionService(storageManager, downloadService, cfg, logger),
+		Analysis:     analysisService,
+		Storage:      storageManager,
+		FFmpeg:       limiter,
+		config:       cfg,
+		Logger:       logger,
+	}
+
+	services.Recover(cfg.Storage.ResumeInterrupted)
+	go services.Subscription.Run(context.Background())
+	go services.Download.RunScheduler(context.Background())
+	go services.Video.WatchImports(context.Background())
+
+	return services
+}
+
+// newFFmpegExecutor creates an FFmpeg executor with the configured resource limits
+func newFFmpegExecutor(cfg *config.Config, logger *zap.Logger) *ffmpeg.Executor {
+	executor := ffmpeg.NewExecutor(cfg.FFmpeg.Path, "ffprobe", logger)
+	executor.SetLimits(ffmpeg.Limits{
+		Threads:    cfg.FFmpeg.Threads,
+		Nice:       cfg.FFmpeg.Nice,
+		CPUPercent: cfg.FFmpeg.CPULimit,
+		CgroupPath: cfg.FFmpeg.CgroupPath,
+	})
+	return executor
+}
+
+// Recover cleans up after a previous run that stopped with work in progress and
+// restores its downloads and exports, resuming them if resume is set
+func (s *Services) Recover(resume bool) {
+	failedRetention := time.Duration(s.config.Storage.FailedRetentionDays) * 24 * time.Hour
+	removed, err := s.Storage.CleanupInterrupted(failedRetention)
+	if err != nil {
+		s.Logger.Warn("Failed to clean up interrupted work", zap.Error(err))
+	} else if removed > 0 {
+		s.Logger.Info("Removed files left by interrupted work", zap.Int("count", removed))
+	}
+
+	s.PurgeExpired()
+
+	s.Download.Recover(resume)
+	s.Operation.Recover(resume)
+}
+
+// PurgeExpired deletes what is kept for a while only: expired trash entries, the
+// workspaces of exports that failed too long ago, the counters of idle sessions
+// and, with storage.auto_cleanup, media older than storage.cleanup_after_days,
+// which goes to the trash
+func (s *Services) PurgeExpired() {
+	if _, err := s.Storage.PurgeTrash(); err != nil {
+		s.Logger.Warn("Failed to purge expired trash", zap.Error(err))
+	}
+	if _, err := s.Storage.PurgeSessionCounters(); err != nil {
+		s.Logger.Warn("Failed to purge session counters", zap.Error(err))
+	}
+	s.Operation.ExpireFailed()
+
+	// Exports may be reading old videos
+	if s.config.Storage.AutoCleanup && !s.Operation.Running() {
+		cutoff := time.Now().AddDate(0, 0, -s.config.Storage.CleanupAfterDays)
+		if _, err := s.Storage.TrashOldMedia(cutoff); err != nil {
+			s.Logger.Warn("Failed to clean up old media", zap.Error(err))
+		}
 	}
 }
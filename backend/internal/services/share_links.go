@@ -0,0 +1,107 @@
+package services
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// driveFilePattern matches the file ID in Google Drive links like /file/d/<id>/view
+var driveFilePattern = regexp.MustCompile(`^/file/d/([A-Za-z0-9_-]+)`)
+
+// resolveShareLink turns a cloud drive share link into a URL that serves the file
+// itself. It returns false for other URLs and for links it can't resolve, such as
+// folders, which are left to yt-dlp's extractors.
+func resolveShareLink(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	switch {
+	case host == "drive.google.com" || host == "docs.google.com":
+		id := u.Query().Get("id")
+		if m := driveFilePattern.FindStringSubmatch(u.Path); m != nil {
+			id = m[1]
+		}
+		if id == "" {
+			return "", false
+		}
+		// confirm=t skips the virus scan page Drive shows for large files
+		return "https://drive.usercontent.google.com/download?id=" + url.QueryEscape(id) + "&export=download&confirm=t", true
+
+	case host == "dropbox.com":
+		if !strings.HasPrefix(u.Path, "/s/") && !strings.HasPrefix(u.Path, "/scl/fi/") {
+			return "", false
+		}
+		query := u.Query()
+		query.Set("dl", "1")
+		u.RawQuery = query.Encode()
+		return u.String(), true
+
+	case host == "1drv.ms" || host == "onedrive.live.com":
+		// The shares API serves the content of any sharing URL
+		encoded := base64.RawURLEncoding.EncodeToString([]byte(raw))
+		return "https://api.onedrive.com/v1.0/shares/u!" + encoded + "/root/content", true
+
+	case strings.HasSuffix(host, ".sharepoint.com") && strings.HasPrefix(u.Path, "/:"):
+		query := u.Query()
+		query.Set("download", "1")
+		u.RawQuery = query.Encode()
+		return u.String(), true
+	}
+
+	return "", false
+}
+
+// responseFilename returns the file name a response suggests in its
+// Content-Disposition header, or "" if there is none
+func responseFilename(resp *http.Response) string {
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	// mime decodes RFC 5987 filename* values into filename
+	return params["filename"]
+}
+
+// contentTypeExtensions maps video media types to file extensions
+var contentTypeExtensions = map[string]string{
+	"video/mp4":        ".mp4",
+	"video/quicktime":  ".mov",
+	"video/x-matroska": ".mkv",
+	"video/webm":       ".webm",
+	"video/x-msvideo":  ".avi",
+	"video/avi":        ".avi",
+	"video/x-ms-wmv":   ".wmv",
+	"video/x-flv":      ".flv",
+	"video/x-m4v":      ".m4v",
+	"video/3gpp":       ".3gp",
+	"video/mp2t":       ".ts",
+}
+
+// directExtension returns the extension of a direct download: the first video
+// extension of the given URLs, else the one of the response's Content-Type, else .mp4
+func directExtension(resp *http.Response, urls ...string) string {
+	for _, u := range urls {
+		if ext := urlExtension(u); ext != "" {
+			return ext
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if ext, ok := contentTypeExtensions[strings.ToLower(mediaType)]; ok {
+		return ext
+	}
+	return ".mp4"
+}
+
+// isWebPage reports whether a response is an HTML page rather than a media file,
+// e.g. a login or "file not found" page of a share link
+func isWebPage(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/html"
+}
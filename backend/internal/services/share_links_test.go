@@ -0,0 +1,69 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResolveShareLink(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://drive.google.com/file/d/1AbC_d-E/view?usp=sharing", "https://drive.usercontent.google.com/download?id=1AbC_d-E&export=download&confirm=t"},
+		{"https://drive.google.com/open?id=XYZ", "https://drive.usercontent.google.com/download?id=XYZ&export=download&confirm=t"},
+		{"https://www.dropbox.com/scl/fi/abc/clip.mp4?rlkey=k&dl=0", "https://www.dropbox.com/scl/fi/abc/clip.mp4?dl=1&rlkey=k"},
+		{"https://contoso.sharepoint.com/:v:/g/personal/me/EaBc?e=x", "https://contoso.sharepoint.com/:v:/g/personal/me/EaBc?download=1&e=x"},
+		{"https://drive.google.com/drive/folders/abc", ""},
+		{"https://www.dropbox.com/sh/abc/folder", ""},
+		{"https://www.youtube.com/watch?v=abc", ""},
+	}
+	for _, tt := range tests {
+		got, ok := resolveShareLink(tt.url)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("resolveShareLink(%q) = %q, %v, want %q", tt.url, got, ok, tt.want)
+		}
+	}
+
+	got, ok := resolveShareLink("https://1drv.ms/v/s!AbCd")
+	if !ok || !strings.HasPrefix(got, "https://api.onedrive.com/v1.0/shares/u!") || !strings.HasSuffix(got, "/root/content") {
+		t.Errorf("resolveShareLink(OneDrive) = %q, %v", got, ok)
+	}
+}
+
+func TestResponseFilename(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Content-Disposition", `attachment; filename="raw.mov"; filename*=UTF-8''Rohmaterial%20%C3%BC.mov`)
+	if got := responseFilename(resp); got != "Rohmaterial ü.mov" {
+		t.Errorf("responseFilename() = %q", got)
+	}
+
+	resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+	if !isWebPage(resp) {
+		t.Error("isWebPage() = false for text/html")
+	}
+}
+
+func TestDirectExtension(t *testing.T) {
+	tests := []struct {
+		contentType string
+		urls        []string
+		want        string
+	}{
+		{"video/webm", []string{"https://cdn.example.com/clip.mkv"}, ".mkv"},
+		// The suggested file name goes first, the URL covers names without a video extension
+		{"", []string{"/raw.MOV", "https://cdn.example.com/a.webm"}, ".mov"},
+		{"", []string{"/clip.bin", "https://cdn.example.com/a.webm"}, ".webm"},
+		{"video/quicktime", []string{"https://cdn.example.com/v/9f8e7d?sig=abc"}, ".mov"},
+		{"video/x-matroska; charset=binary", []string{"https://cdn.example.com/get"}, ".mkv"},
+		{"application/octet-stream", []string{"https://cdn.example.com/get"}, ".mp4"},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		resp.Header.Set("Content-Type", tt.contentType)
+		if got := directExtension(resp, tt.urls...); got != tt.want {
+			t.Errorf("directExtension(%q, %q) = %q, want %q", tt.contentType, tt.urls, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// splitSizeMargin keeps size-based parts below the limit, since lossless cuts
+// land on keyframes and the bitrate varies along the video
+const splitSizeMargin = 0.95
+
+// SplitRequest represents a request to split a video into a new project of
+// consecutive parts
+type SplitRequest struct {
+	By   string `json:"by" binding:"required"` // "black", "interval" or "size"
+	Name string `json:"name,omitempty"`        // Project name, default "<file> (split)"
+
+	Interval  float64 `json:"interval,omitempty"`    // Seconds per part, for "interval"
+	MaxSizeMB float64 `json:"max_size_mb,omitempty"` // Estimated megabytes per part, for "size"
+
+	// Black frame detection, for "black"
+	Preset           string  `json:"preset,omitempty"`
+	MinDuration      float64 `json:"min_duration,omitempty"`       // Shortest black range to split at, seconds
+	MinSegmentLength float64 `json:"min_segment_length,omitempty"` // Shortest part, seconds
+}
+
+// Split creates a project that covers a video with consecutive parts, split at
+// black frames, every Interval seconds or about every MaxSizeMB megabytes, e.g.
+// for uploading to platforms with length or size limits
+func (s *AnalysisService) Split(ctx context.Context, videoID string, req SplitRequest) (*models.Project, error) {
+	video, err := s.storage.GetVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	if video.Duration <= 0 {
+		return nil, invalidf("video duration unknown")
+	}
+
+	var boundaries []float64
+	switch req.By {
+	case "black":
+		detection, err := s.Detect(ctx, videoID, DetectionRequest{
+			Mode:        "black",
+			Preset:      req.Preset,
+			MinDuration: req.MinDuration,
+		})
+		if err != nil {
+			return nil, err
+		}
+		boundaries = suggestChapterBoundaries(detection.Scenes, video.Duration, req.MinSegmentLength)
+	case "interval":
+		if req.Interval <= 0 {
+			return nil, invalidf("interval must be positive")
+		}
+		boundaries = intervalBoundaries(video.Duration, req.Interval)
+	case "size":
+		if req.MaxSizeMB <= 0 {
+			return nil, invalidf("max_size_mb must be positive")
+		}
+		interval, err := sizeInterval(video.FileSize, video.Duration, req.MaxSizeMB)
+		if err != nil {
+			return nil, err
+		}
+		boundaries = intervalBoundaries(video.Duration, interval)
+	default:
+		return nil, invalidf("invalid split mode: %s", req.By)
+	}
+
+	name := req.Name
+	if name == "" {
+		name = fmt.Sprintf("%s (split)", video.FileName)
+	}
+	project, err := s.projectService.Create(name, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	project.Segments = splitSegments(boundaries, video.Duration)
+	if err := s.projectService.Save(project); err != nil {
+		return nil, fmt.Errorf("failed to save project: %w", err)
+	}
+
+	s.logger.Info("Split video",
+		zap.String("videoId", videoID),
+		zap.String("projectId", project.ID),
+		zap.String("by", req.By),
+		zap.Int("parts", len(project.Segments)),
+	)
+
+	return project, nil
+}
+
+// sizeInterval estimates how many seconds of the video fit in maxSizeMB from
+// its average bitrate
+func sizeInterval(fileSize int64, duration, maxSizeMB float64) (float64, error) {
+	if fileSize <= 0 {
+		return 0, invalidf("video file size unknown")
+	}
+	bytesPerSecond := float64(fileSize) / duration
+	return maxSizeMB * 1024 * 1024 * splitSizeMargin / bytesPerSecond, nil
+}
+
+// intervalBoundaries returns the multiples of interval inside the video
+func intervalBoundaries(duration, interval float64) []float64 {
+	var boundaries []float64
+	for i := 1; ; i++ {
+		t := float64(i) * interval
+		// Don't leave a sliver at the end from floating point error
+		if duration-t < 0.001 {
+			break
+		}
+		boundaries = append(boundaries, math.Round(t*1000)/1000)
+	}
+	return boundaries
+}
+
+// splitSegments turns sorted split points into consecutive numbered parts
+func splitSegments(boundaries []float64, duration float64) []models.Segment {
+	segments := make([]models.Segment, 0, len(boundaries)+1)
+	start := 0.0
+	for i, boundary := range append(boundaries, duration) {
+		end := boundary
+		segments = append(segments, models.Segment{
+			ID:    uuid.New().String(),
+			Name:  fmt.Sprintf("Part %d", i+1),
+			Start: start,
+			End:   &end,
+		})
+		start = boundary
+	}
+	return segments
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestIntervalBoundaries(t *testing.T) {
+	tests := []struct {
+		duration, interval float64
+		want               []float64
+	}{
+		{100, 30, []float64{30, 60, 90}},
+		{90, 30, []float64{30, 60}}, // No empty part at the end
+		{20, 30, nil},
+		{0.9, 0.3, []float64{0.3, 0.6}},
+	}
+	for _, tt := range tests {
+		if got := intervalBoundaries(tt.duration, tt.interval); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("intervalBoundaries(%g, %g) = %v, want %v", tt.duration, tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestSizeInterval(t *testing.T) {
+	// 100 MiB over 100 seconds is 1 MiB/s
+	got, err := sizeInterval(100*1024*1024, 100, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 20 * splitSizeMargin; math.Abs(got-want) > 1e-9 {
+		t.Errorf("sizeInterval() = %g, want %g", got, want)
+	}
+
+	if _, err := sizeInterval(0, 100, 20); err == nil {
+		t.Error("sizeInterval() expected error for unknown file size")
+	}
+}
+
+func TestSplitSegments(t *testing.T) {
+	segments := splitSegments([]float64{30, 60}, 75)
+
+	if len(segments) != 3 {
+		t.Fatalf("got %d segments, want 3", len(segments))
+	}
+	last := segments[2]
+	if last.Name != "Part 3" || last.Start != 60 || *last.End != 75 {
+		t.Errorf("last segment = %s %g-%g, want Part 3 60-75", last.Name, last.Start, *last.End)
+	}
+}
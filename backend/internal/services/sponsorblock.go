@@ -0,0 +1,185 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// sponsorBlockAPI returns the skippable segments SponsorBlock users submitted for a
+// YouTube video
+const sponsorBlockAPI = "https://sponsor.ajay.app/api/skipSegments"
+
+// sponsorBlockCategories are the SponsorBlock categories a download can remove
+var sponsorBlockCategories = map[string]bool{
+	"sponsor":        true,
+	"selfpromo":      true,
+	"interaction":    true,
+	"intro":          true,
+	"outro":          true,
+	"preview":        true,
+	"music_offtopic": true,
+	"filler":         true,
+}
+
+// defaultSponsorBlockCategories are removed when a download names no categories
+var defaultSponsorBlockCategories = []string{"sponsor", "selfpromo", "interaction"}
+
+// sponsorBlockSegment is one entry of a SponsorBlock API response
+type sponsorBlockSegment struct {
+	Segment    [2]float64 `json:"segment"`
+	Category   string     `json:"category"`
+	ActionType string     `json:"actionType"`
+}
+
+// sponsorBlockOptions checks the SponsorBlock categories of a download request and
+// fills in the defaults
+func sponsorBlockOptions(req DownloadRequest) ([]string, error) {
+	if !req.SponsorBlock {
+		if len(req.SponsorBlockCategories) > 0 {
+			return nil, fmt.Errorf("sponsorblock_categories requires sponsorblock")
+		}
+		return nil, nil
+	}
+	if len(req.SponsorBlockCategories) == 0 {
+		return defaultSponsorBlockCategories, nil
+	}
+	for _, category := range req.SponsorBlockCategories {
+		if !sponsorBlockCategories[category] {
+			return nil, fmt.Errorf("invalid SponsorBlock category: %s", category)
+		}
+	}
+	return req.SponsorBlockCategories, nil
+}
+
+// parseSponsorBlockRanges returns the ranges to skip from a SponsorBlock API
+// response, sorted and merged where they overlap. Highlights, chapters and muted
+// ranges aren't skipped.
+func parseSponsorBlockRanges(data []byte) ([][2]float64, error) {
+	var segments []sponsorBlockSegment
+	if err := json.Unmarshal(data, &segments); err != nil {
+		return nil, fmt.Errorf("failed to parse SponsorBlock segments: %w", err)
+	}
+
+	var ranges [][2]float64
+	for _, segment := range segments {
+		if segment.ActionType != "" && segment.ActionType != "skip" {
+			continue
+		}
+		if segment.Segment[1] > segment.Segment[0] {
+			ranges = append(ranges, segment.Segment)
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	var merged [][2]float64
+	for _, r := range ranges {
+		if len(merged) > 0 && r[0] <= merged[len(merged)-1][1] {
+			merged[len(merged)-1][1] = math.Max(merged[len(merged)-1][1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// sponsorFreeSegments returns the parts of a video between sorted, merged skip
+// ranges, so exporting the project leaves the skipped ranges out
+func sponsorFreeSegments(skips [][2]float64, duration float64) []models.Segment {
+	var segments []models.Segment
+	add := func(start, end float64) {
+		if end-start <= 0 {
+			return
+		}
+		segments = append(segments, models.Segment{
+			ID:    uuid.New().String(),
+			Name:  fmt.Sprintf("Content %d", len(segments)+1),
+			Start: start,
+			End:   &end,
+		})
+	}
+
+	start := 0.0
+	for _, skip := range skips {
+		add(start, math.Min(skip[0], duration))
+		start = math.Max(start, skip[1])
+	}
+	add(start, duration)
+	return segments
+}
+
+// fetchSponsorBlockRanges asks the SponsorBlock API for the skip ranges of a
+// YouTube video. A video nobody submitted segments for has none.
+func fetchSponsorBlockRanges(videoID string, categories []string) ([][2]float64, error) {
+	encoded, err := json.Marshal(categories)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"videoID": {videoID}, "categories": {string(encoded)}}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(sponsorBlockAPI + "?" + query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("SponsorBlock request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("SponsorBlock returned HTTP %d", resp.StatusCode)
+	}
+
+	var data json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to read SponsorBlock segments: %w", err)
+	}
+	return parseSponsorBlockRanges(data)
+}
+
+// importSponsorBlock creates a project for a downloaded YouTube video whose segments
+// leave out the ranges SponsorBlock marks in the download's categories. Failures are
+// logged, the video itself was downloaded fine.
+func (s *DownloadService) importSponsorBlock(download *models.Download, video *models.Video, info *VideoInfo) {
+	if info.ExtractorKey != "Youtube" || info.ID == "" {
+		s.logger.Info("SponsorBlock only covers YouTube videos", zap.String("id", download.ID))
+		return
+	}
+
+	skips, err := fetchSponsorBlockRanges(info.ID, download.SponsorBlock)
+	if err != nil {
+		s.logger.Warn("Failed to fetch SponsorBlock segments", zap.String("id", download.ID), zap.Error(err))
+		return
+	}
+	if len(skips) == 0 {
+		s.logger.Info("No SponsorBlock segments", zap.String("id", download.ID), zap.String("youtubeId", info.ID))
+		return
+	}
+
+	project, err := s.projectService.Create(fmt.Sprintf("%s (SponsorBlock)", download.Title), video.ID)
+	if err != nil {
+		s.logger.Warn("Failed to create SponsorBlock project", zap.String("id", download.ID), zap.Error(err))
+		return
+	}
+	project.Segments = sponsorFreeSegments(skips, video.Duration)
+	if err := s.projectService.Save(project); err != nil {
+		s.logger.Warn("Failed to save SponsorBlock project", zap.String("id", download.ID), zap.Error(err))
+		return
+	}
+	download.ProjectIDs = append(download.ProjectIDs, project.ID)
+
+	s.logger.Info("Created SponsorBlock project",
+		zap.String("id", download.ID),
+		zap.String("projectId", project.ID),
+		zap.Int("skipped", len(skips)),
+	)
+}
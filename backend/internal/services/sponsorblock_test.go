@@ -0,0 +1,55 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSponsorBlockRanges(t *testing.T) {
+	data := []byte(`[
+		{"segment": [120.5, 150], "category": "sponsor", "actionType": "skip"},
+		{"segment": [10, 25], "category": "intro", "actionType": "skip"},
+		{"segment": [140, 160], "category": "selfpromo", "actionType": "skip"},
+		{"segment": [300, 300], "category": "poi_highlight", "actionType": "poi"},
+		{"segment": [400, 420], "category": "music_offtopic", "actionType": "mute"}
+	]`)
+
+	got, err := parseSponsorBlockRanges(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := [][2]float64{{10, 25}, {120.5, 160}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSponsorBlockRanges() = %v, want %v", got, want)
+	}
+}
+
+func TestSponsorFreeSegments(t *testing.T) {
+	segments := sponsorFreeSegments([][2]float64{{0, 5}, {60, 90}, {290, 310}}, 300)
+
+	want := [][2]float64{{5, 60}, {90, 290}}
+	if len(segments) != len(want) {
+		t.Fatalf("got %d segments, want %d: %+v", len(segments), len(want), segments)
+	}
+	for i, seg := range segments {
+		if seg.Start != want[i][0] || *seg.End != want[i][1] {
+			t.Errorf("segment %d = %g-%g, want %g-%g", i, seg.Start, *seg.End, want[i][0], want[i][1])
+		}
+	}
+	if segments[1].Name != "Content 2" {
+		t.Errorf("segment name = %q, want %q", segments[1].Name, "Content 2")
+	}
+}
+
+func TestSponsorBlockOptions(t *testing.T) {
+	categories, err := sponsorBlockOptions(DownloadRequest{SponsorBlock: true})
+	if err != nil || !reflect.DeepEqual(categories, defaultSponsorBlockCategories) {
+		t.Errorf("defaults = %v, %v", categories, err)
+	}
+	if _, err := sponsorBlockOptions(DownloadRequest{SponsorBlock: true, SponsorBlockCategories: []string{"ads"}}); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+	if _, err := sponsorBlockOptions(DownloadRequest{SponsorBlockCategories: []string{"sponsor"}}); err == nil {
+		t.Error("expected an error for categories without sponsorblock")
+	}
+}
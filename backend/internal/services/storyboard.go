@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/subtitles"
+	"go.uber.org/zap"
+)
+
+// Storyboard returns the paths of a video's storyboard sprite and the WebVTT file
+// mapping times to its tiles, generating both on first use. Tiles are referenced as
+// /api/videos/<id>/storyboard/sprite#xywh=x,y,w,h.
+func (s *VideoService) Storyboard(ctx context.Context, videoID string) (spritePath, vttPath string, err error) {
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return "", "", fmt.Errorf("video not found: %w", err)
+	}
+	if video.Width == 0 || video.Height == 0 {
+		return "", "", invalidf("video has no video stream")
+	}
+
+	dir := s.storage.GetVideoThumbnailsDir(videoID)
+	spritePath = filepath.Join(dir, "storyboard.jpg")
+	vttPath = filepath.Join(dir, "storyboard.vtt")
+	if s.storage.FileExists(spritePath) && s.storage.FileExists(vttPath) {
+		return spritePath, vttPath, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create thumbnails directory: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	// Written to a partial file of its own first, so concurrent requests neither serve
+	// half a sprite nor write into each other's
+	sb := ffmpeg.NewStoryboard(video.Duration, video.Width, video.Height)
+	partial, err := os.CreateTemp(dir, "storyboard-*.partial.jpg")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create storyboard: %w", err)
+	}
+	partial.Close()
+	if err := s.ffmpeg.GenerateStoryboard(ctx, video.FilePath, partial.Name(), sb); err != nil {
+		os.Remove(partial.Name())
+		return "", "", fmt.Errorf("failed to generate storyboard: %w", err)
+	}
+	if err := os.Rename(partial.Name(), spritePath); err != nil {
+		os.Remove(partial.Name())
+		return "", "", fmt.Errorf("failed to save storyboard: %w", err)
+	}
+
+	vtt := storyboardVTT(sb, video.Duration, "/api/videos/"+videoID+"/storyboard/sprite")
+	if err := os.WriteFile(vttPath, []byte(vtt), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to save storyboard cues: %w", err)
+	}
+
+	s.logger.Info("Generated storyboard",
+		zap.String("videoID", videoID),
+		zap.Int("tiles", sb.Count),
+		zap.Float64("interval", sb.Interval),
+	)
+
+	return spritePath, vttPath, nil
+}
+
+// storyboardVTT maps each tile's time range to its area of the sprite at spriteURL
+func storyboardVTT(sb ffmpeg.Storyboard, duration float64, spriteURL string) string {
+	cues := make([]subtitles.Cue, sb.Count)
+	for i := range cues {
+		x, y := sb.TilePosition(i)
+		start := float64(i) * sb.Interval
+		end := start + sb.Interval
+		if duration > 0 && end > duration {
+			end = duration
+		}
+		cues[i] = subtitles.Cue{
+			Start: start,
+			End:   end,
+			Text:  fmt.Sprintf("%s#xywh=%d,%d,%d,%d", spriteURL, x, y, sb.TileWidth, sb.TileHeight),
+		}
+	}
+	return subtitles.FormatVTT(cues)
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+)
+
+func TestStoryboardVTT(t *testing.T) {
+	sb := ffmpeg.NewStoryboard(12.5, 1920, 1080)
+	vtt := storyboardVTT(sb, 12.5, "/sprite")
+
+	if !strings.HasPrefix(vtt, "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n/sprite#xywh=0,0,160,90\n") {
+		t.Errorf("unexpected first cue:\n%s", vtt)
+	}
+	// Tile 11 starts the second row, the last one ends with the video
+	if !strings.Contains(vtt, "00:00:11.000 --> 00:00:12.000\n/sprite#xywh=160,90,160,90\n") {
+		t.Errorf("missing cue of tile 11:\n%s", vtt)
+	}
+	if !strings.Contains(vtt, "00:00:12.000 --> 00:00:12.500\n/sprite#xywh=320,90,160,90\n") {
+		t.Errorf("last cue doesn't end with the video:\n%s", vtt)
+	}
+}
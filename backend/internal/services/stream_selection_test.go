@@ -0,0 +1,100 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestStreamSelectionJSON(t *testing.T) {
+	tests := []struct {
+		json string
+		want models.StreamSelection
+	}{
+		{`{"streams": [0, 2]}`, models.StreamSelection{Indexes: []int{0, 2}}},
+		{`{"streams": "video_only"}`, models.StreamSelection{Shortcut: "video_only"}},
+		{`{"streams": ["strip_subtitles"]}`, models.StreamSelection{Shortcut: "strip_subtitles"}},
+		{`{}`, models.StreamSelection{}},
+	}
+	for _, tt := range tests {
+		var request models.ExportRequest
+		if err := json.Unmarshal([]byte(tt.json), &request); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", tt.json, err)
+		}
+		if !reflect.DeepEqual(request.Streams, tt.want) {
+			t.Errorf("Unmarshal(%s) streams = %+v, want %+v", tt.json, request.Streams, tt.want)
+		}
+
+		// Stored export jobs must round-trip
+		data, err := json.Marshal(request)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		var again models.ExportRequest
+		if err := json.Unmarshal(data, &again); err != nil || !reflect.DeepEqual(again.Streams, tt.want) {
+			t.Errorf("round trip of %s = %+v, %v", tt.json, again.Streams, err)
+		}
+	}
+
+	var request models.ExportRequest
+	if err := json.Unmarshal([]byte(`{"streams": {"a": 1}}`), &request); err == nil {
+		t.Error("Unmarshal() of an object: want error")
+	}
+}
+
+func TestSelectedStreams(t *testing.T) {
+	streams := []models.Stream{
+		{Index: 0, CodecType: "video", CodecName: "h264"},
+		{Index: 1, CodecType: "audio", CodecName: "aac"},
+		{Index: 2, CodecType: "audio", CodecName: "ac3"},
+		{Index: 3, CodecType: "subtitle", CodecName: "subrip"},
+	}
+
+	got, err := selectedStreams(streams, models.StreamSelection{Indexes: []int{0, 2}})
+	if err != nil || len(got) != 2 || got[1].Index != 2 {
+		t.Errorf("indexes: got %+v, err = %v", got, err)
+	}
+
+	got, err = selectedStreams(streams, models.StreamSelection{Shortcut: "strip_subtitles"})
+	if err != nil || len(got) != 3 {
+		t.Errorf("strip_subtitles: got %+v, err = %v", got, err)
+	}
+
+	got, err = selectedStreams(streams, models.StreamSelection{Shortcut: "video_only"})
+	if err != nil || len(got) != 1 || got[0].CodecType != "video" {
+		t.Errorf("video_only: got %+v, err = %v", got, err)
+	}
+
+	if _, err := selectedStreams(streams, models.StreamSelection{Indexes: []int{7}}); err == nil {
+		t.Error("missing index: want error")
+	}
+
+	// Dropping the subtitle lets the rest be copied into mp4
+	request, err := selectContainer(got, models.ExportRequest{Format: "mp4"})
+	if err != nil || request.Format != "mp4" {
+		t.Errorf("selectContainer() = %q, %v", request.Format, err)
+	}
+}
+
+func TestValidateStreamSelection(t *testing.T) {
+	valid := models.ExportRequest{Streams: models.StreamSelection{Indexes: []int{0, 1}}}
+	if err := validateExportRequest(valid); err != nil {
+		t.Errorf("valid selection: %v", err)
+	}
+
+	invalid := []models.ExportRequest{
+		{Streams: models.StreamSelection{Shortcut: "audio_only"}},
+		{Streams: models.StreamSelection{Indexes: []int{0}, Shortcut: "video_only"}},
+		{Streams: models.StreamSelection{Indexes: []int{1, 1}}},
+		{Streams: models.StreamSelection{Indexes: []int{-1}}},
+		{Streams: models.StreamSelection{Indexes: []int{0}}, CutMode: CutModeReencode},
+		{Streams: models.StreamSelection{Indexes: []int{0}}, AudioOnly: true},
+	}
+	for _, request := range invalid {
+		if err := validateExportRequest(request); err == nil {
+			t.Errorf("validateExportRequest(%+v): want error", request.Streams)
+		}
+	}
+}
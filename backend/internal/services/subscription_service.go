@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+	"go.uber.org/zap"
+)
+
+// maxSeenItems bounds the item IDs remembered per subscription. Feeds only list
+// their latest items, so older IDs are never needed again.
+const maxSeenItems = 1000
+
+// maxFeedSize bounds the size of a fetched feed
+const maxFeedSize = 10 * 1024 * 1024
+
+// SubscriptionService polls feed subscriptions and downloads their new items
+type SubscriptionService struct {
+	storage   *storage.Manager
+	downloads *DownloadService
+	config    *config.Config
+	logger    *zap.Logger
+	client    *http.Client
+
+	// mu guards the changes of stored subscriptions and pending, the items checks are
+	// downloading by subscription and item ID, so an item is never enqueued twice.
+	// Feeds are fetched and items downloaded without holding it.
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// NewSubscriptionService creates a new subscription service
+func NewSubscriptionService(storage *storage.Manager, downloads *DownloadService, cfg *config.Config, logger *zap.Logger) *SubscriptionService {
+	return &SubscriptionService{
+		storage:   storage,
+		downloads: downloads,
+		config:    cfg,
+		logger:    logger,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		pending:   make(map[string]bool),
+	}
+}
+
+// SubscriptionRequest creates or updates a subscription
+type SubscriptionRequest struct {
+	URL              string  `json:"url"`
+	TitleFilter      string  `json:"title_filter,omitempty"`
+	MaxDuration      float64 `json:"max_duration,omitempty"`
+	Format           string  `json:"format,omitempty"`
+	Enabled          *bool   `json:"enabled,omitempty"`           // Defaults to true on create
+	DownloadExisting bool    `json:"download_existing,omitempty"` // Also download the items already in the feed
+}
+
+// validate checks the filters of a request
+func (r SubscriptionRequest) validate() error {
+	if _, err := regexp.Compile(r.TitleFilter); err != nil {
+		return fmt.Errorf("invalid title_filter: %w", err)
+	}
+	if r.MaxDuration < 0 {
+		return fmt.Errorf("max_duration must not be negative")
+	}
+	return nil
+}
+
+// Create subscribes to a feed. The items already in the feed are only downloaded
+// with DownloadExisting, otherwise only items published from now on are.
+func (s *SubscriptionService) Create(req SubscriptionRequest) (*models.Subscription, []*models.Download, error) {
+	if req.URL == "" {
+		return nil, nil, fmt.Errorf("url is required")
+	}
+	if err := req.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	feedURL := youtubeFeedURL(req.URL)
+	title, items, err := s.fetchFeed(feedURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subscription := &models.Subscription{
+		ID:          uuid.New().String(),
+		URL:         feedURL,
+		Name:        title,
+		TitleFilter: req.TitleFilter,
+		MaxDuration: req.MaxDuration,
+		Format:      req.Format,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		CreatedAt:   time.Now(),
+	}
+
+	if !req.DownloadExisting {
+		for i := len(items) - 1; i >= 0; i-- {
+			subscription.SeenItems = append(subscription.SeenItems, items[i].ID)
+		}
+		trimSeenItems(subscription)
+		items = nil
+	}
+
+	s.mu.Lock()
+	err = s.storage.SaveSubscription(subscription)
+	if err == nil {
+		items = s.claim(subscription, items)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	downloads := s.process(subscription, items)
+	if latest, err := s.storage.GetSubscription(subscription.ID); err == nil {
+		subscription = latest
+	}
+
+	s.logger.Info("Subscribed to feed",
+		zap.String("id", subscription.ID),
+		zap.String("url", feedURL),
+		zap.Int("downloads", len(downloads)),
+	)
+	return subscription, downloads, nil
+}
+
+// Update changes the filters or enabled state of a subscription
+func (s *SubscriptionService) Update(id string, req SubscriptionRequest) (*models.Subscription, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription, err := s.storage.GetSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.TitleFilter = req.TitleFilter
+	subscription.MaxDuration = req.MaxDuration
+	subscription.Format = req.Format
+	if req.Enabled != nil {
+		subscription.Enabled = *req.Enabled
+	}
+
+	if err := s.storage.SaveSubscription(subscription); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Get retrieves a subscription
+func (s *SubscriptionService) Get(id string) (*models.Subscription, error) {
+	return s.storage.GetSubscription(id)
+}
+
+// List returns all subscriptions
+func (s *SubscriptionService) List() ([]*models.Subscription, error) {
+	return s.storage.ListSubscriptions()
+}
+
+// Delete unsubscribes from a feed. Downloads already started are kept.
+func (s *SubscriptionService) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.storage.DeleteSubscription(id)
+}
+
+// Check polls a subscription's feed and starts downloads for its new items
+func (s *SubscriptionService) Check(id string) (*models.Subscription, []*models.Download, error) {
+	subscription, err := s.storage.GetSubscription(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, items, fetchErr := s.fetchFeed(subscription.URL)
+
+	// The subscription may have changed while the feed was fetched
+	s.mu.Lock()
+	subscription, err = s.storage.GetSubscription(id)
+	if err == nil {
+		now := time.Now()
+		subscription.LastChecked = &now
+		subscription.LastError = ""
+		if fetchErr != nil {
+			subscription.LastError = fetchErr.Error()
+		}
+		err = s.storage.SaveSubscription(subscription)
+	}
+	if err == nil && fetchErr == nil {
+		items = s.claim(subscription, items)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	if fetchErr != nil {
+		return subscription, nil, fetchErr
+	}
+
+	downloads := s.process(subscription, items)
+	if latest, err := s.storage.GetSubscription(id); err == nil {
+		subscription = latest
+	}
+	return subscription, downloads, nil
+}
+
+// Run polls the enabled subscriptions every feeds.poll_interval_minutes until ctx
+// is done. A zero interval disables polling.
+func (s *SubscriptionService) Run(ctx context.Context) {
+	interval := time.Duration(s.config.Feeds.PollIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkAll()
+		}
+	}
+}
+
+// checkAll checks every enabled subscription
+func (s *SubscriptionService) checkAll() {
+	subscriptions, err := s.storage.ListSubscriptions()
+	if err != nil {
+		s.logger.Warn("Failed to list subscriptions", zap.Error(err))
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		if !subscription.Enabled {
+			continue
+		}
+		if _, downloads, err := s.Check(subscription.ID); err != nil {
+			s.logger.Warn("Failed to check subscription", zap.String("id", subscription.ID), zap.Error(err))
+		} else if len(downloads) > 0 {
+			s.logger.Info("Downloading new feed items", zap.String("id", subscription.ID), zap.Int("count", len(downloads)))
+		}
+	}
+}
+
+// fetchFeed downloads and parses a feed
+func (s *SubscriptionService) fetchFeed(feedURL string) (string, []feedItem, error) {
+	resp, err := s.client.Get(feedURL)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("failed to fetch feed: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	return parseFeed(data)
+}
+
+// claim returns the items of a feed that were not seen before and no other check
+// is downloading, in publication order, and marks them pending. Callers hold mu.
+func (s *SubscriptionService) claim(subscription *models.Subscription, items []feedItem) []feedItem {
+	seen := make(map[string]bool, len(subscription.SeenItems))
+	for _, id := range subscription.SeenItems {
+		seen[id] = true
+	}
+
+	var claimed []feedItem
+	// Feeds list the newest items first, download in publication order
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		key := subscription.ID + "/" + item.ID
+		if seen[item.ID] || s.pending[key] {
+			continue
+		}
+		seen[item.ID] = true
+		s.pending[key] = true
+		claimed = append(claimed, item)
+	}
+	return claimed
+}
+
+// process starts downloads for the claimed items of a feed that pass the filters.
+// Filtered items are marked as seen, so they are not evaluated again, and so are
+// downloaded items once their download was created. Items whose download failed to
+// start are tried again by the next check.
+func (s *SubscriptionService) process(subscription *models.Subscription, items []feedItem) []*models.Download {
+	titleFilter, _ := regexp.Compile(subscription.TitleFilter)
+
+	var downloads []*models.Download
+	for _, item := range items {
+		if reason := s.skipReason(subscription, titleFilter, item); reason != "" {
+			s.logger.Info("Skipping feed item",
+				zap.String("subscriptionId", subscription.ID),
+				zap.String("title", item.Title),
+				zap.String("reason", reason),
+			)
+			s.release(subscription.ID, item.ID, true)
+			continue
+		}
+
+		download, err := s.downloads.StartDownload(context.Background(), DownloadRequest{URL: item.URL, Format: subscription.Format})
+		if err != nil {
+			s.logger.Warn("Failed to start feed item download", zap.String("url", item.URL), zap.Error(err))
+			s.release(subscription.ID, item.ID, false)
+			continue
+		}
+		downloads = append(downloads, download)
+		s.release(subscription.ID, item.ID, true)
+	}
+	return downloads
+}
+
+// release ends the processing of a claimed item, saving it as seen if it is done
+func (s *SubscriptionService) release(subscriptionID, itemID string, seen bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, subscriptionID+"/"+itemID)
+	if !seen {
+		return
+	}
+
+	// Deleted subscriptions keep the downloads already started
+	subscription, err := s.storage.GetSubscription(subscriptionID)
+	if err != nil {
+		return
+	}
+	subscription.SeenItems = append(subscription.SeenItems, itemID)
+	trimSeenItems(subscription)
+	if err := s.storage.SaveSubscription(subscription); err != nil {
+		s.logger.Warn("Failed to save seen feed item",
+			zap.String("subscriptionId", subscriptionID),
+			zap.String("itemId", itemID),
+			zap.Error(err),
+		)
+	}
+}
+
+// trimSeenItems keeps the latest maxSeenItems seen items of a subscription
+func trimSeenItems(subscription *models.Subscription) {
+	if len(subscription.SeenItems) > maxSeenItems {
+		subscription.SeenItems = subscription.SeenItems[len(subscription.SeenItems)-maxSeenItems:]
+	}
+}
+
+// skipReason returns why an item doesn't pass a subscription's filters, or "".
+// Durations missing from the feed are looked up with yt-dlp; items whose duration
+// stays unknown pass.
+func (s *SubscriptionService) skipReason(subscription *models.Subscription, titleFilter *regexp.Regexp, item feedItem) string {
+	if titleFilter != nil && !titleFilter.MatchString(item.Title) {
+		return "title does not match filter"
+	}
+
+	if subscription.MaxDuration > 0 {
+		duration := item.Duration
+		if duration == 0 {
+			if info, err := s.downloads.getVideoInfo(context.Background(), item.URL, s.downloads.client(DownloadRequest{})); err == nil {
+				duration = info.Duration
+			}
+		}
+		if duration > subscription.MaxDuration {
+			return fmt.Sprintf("duration %.0fs exceeds %.0fs", duration, subscription.MaxDuration)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mifi/lossless-cut/backend/internal/ffmpeg"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/subtitles"
+	"go.uber.org/zap"
+)
+
+// SubtitleExtractRequest represents a request to extract an embedded subtitle stream
+type SubtitleExtractRequest struct {
+	Format     string   `json:"format,omitempty"`      // "srt" (default) or "vtt"
+	ProjectID  string   `json:"project_id,omitempty"`  // Clip the subtitles to this project's segments
+	SegmentIDs []string `json:"segment_ids,omitempty"` // Segments to keep, default all
+	Separate   bool     `json:"separate,omitempty"`    // One file per segment instead of one for all segments
+}
+
+// SubtitleFile is an extracted subtitle file in the outputs directory
+type SubtitleFile struct {
+	File string `json:"file"`
+	URL  string `json:"url"`
+	Cues int    `json:"cues"`
+}
+
+// ExtractSubtitles converts the text subtitle stream with the given index to .srt or
+// .vtt files in the outputs directory. With a project the cues are clipped to its
+// segments and re-timed like an export of them: one file for the segments joined
+// together, or one per segment.
+func (s *VideoService) ExtractSubtitles(ctx context.Context, videoID string, streamIndex int, project *models.Project, req SubtitleExtractRequest) ([]SubtitleFile, error) {
+	format := req.Format
+	if format == "" {
+		format = "srt"
+	}
+	if format != "srt" && format != "vtt" {
+		return nil, invalidf("invalid subtitle format: %s", format)
+	}
+
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+
+	var stream *models.Stream
+	for i := range video.Metadata.Streams {
+		if video.Metadata.Streams[i].Index == streamIndex {
+			stream = &video.Metadata.Streams[i]
+			break
+		}
+	}
+	if stream == nil || stream.CodecType != "subtitle" {
+		return nil, invalidf("stream %d is not a subtitle stream", streamIndex)
+	}
+	if !ffmpeg.IsTextSubtitleCodec(stream.CodecName) {
+		return nil, invalidf("%s subtitles are images and can't be converted to text", stream.CodecName)
+	}
+
+	var segments []models.Segment
+	if project != nil {
+		if project.VideoID != videoID {
+			return nil, invalidf("project %s is not a project of this video", project.ID)
+		}
+		segments, err = selectSegments(project, models.ExportRequest{SegmentIDs: req.SegmentIDs}, video.Duration)
+		if err != nil {
+			return nil, err
+		}
+		if len(segments) == 0 {
+			return nil, invalidf("no segments to clip the subtitles to")
+		}
+	} else {
+		project = &models.Project{Name: video.FileName}
+	}
+
+	// Convert to SRT first, then clip and format the cues
+	tempPath := s.storage.GetTempPath(fmt.Sprintf("subtitles_%s.srt", uuid.New().String()))
+	defer s.storage.DeleteFile(tempPath)
+
+	if err := s.ffmpeg.ExtractSubtitles(ctx, video.FilePath, streamIndex, tempPath); err != nil {
+		return nil, fmt.Errorf("failed to convert subtitles: %w", err)
+	}
+	data, err := os.ReadFile(tempPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read converted subtitles: %w", err)
+	}
+	cues := subtitles.ParseSRT(string(data))
+
+	label := stream.Language
+	if label == "" || label == "und" {
+		label = fmt.Sprintf("track%d", stream.Index)
+	}
+	ext := label + "." + format
+	namer := newOutputNamer(s.storage.OutputsDir(), models.ExportRequest{}, project, video, segments, time.Now())
+
+	clips := subtitleClips(segments, req.Separate)
+	files := make([]SubtitleFile, 0, len(clips))
+	for i, ranges := range clips {
+		var name string
+		switch {
+		case len(segments) == 0:
+			name = namer.Suffixed("", ext)
+		case req.Separate:
+			name = namer.Segment(i, segments[i], ext)
+		default:
+			name = namer.Merged(segments, ext, false)
+		}
+
+		outCues := cues
+		if ranges != nil {
+			outCues = subtitles.Clip(cues, ranges)
+		}
+
+		content := subtitles.FormatSRT(outCues)
+		if format == "vtt" {
+			content = subtitles.FormatVTT(outCues)
+		}
+
+		// Earlier outputs with the same name are kept
+		file, err := createOutput(s.storage.OutputsDir(), name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create subtitles: %w", err)
+		}
+		path := file.Name()
+		_, err = file.WriteString(content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return nil, fmt.Errorf("failed to write subtitles: %w", err)
+		}
+		if err := s.storage.StoreFile(path); err != nil {
+			return nil, err
+		}
+		files = append(files, SubtitleFile{
+			File: filepath.Base(path),
+			URL:  "/api/outputs/" + filepath.Base(path),
+			Cues: len(outCues),
+		})
+	}
+
+	s.logger.Info("Extracted subtitles",
+		zap.String("videoId", videoID),
+		zap.Int("streamIndex", streamIndex),
+		zap.String("format", format),
+		zap.Int("files", len(files)),
+	)
+
+	return files, nil
+}
+
+// subtitleClips returns the source ranges of each extracted subtitle file: the whole
+// track without segments, else one file per segment or one for all segments joined
+// in order, as in a merged export
+func subtitleClips(segments []models.Segment, separate bool) [][]subtitles.Range {
+	if len(segments) == 0 {
+		return [][]subtitles.Range{nil}
+	}
+
+	if separate {
+		clips := make([][]subtitles.Range, len(segments))
+		for i, seg := range segments {
+			clips[i] = []subtitles.Range{{Start: seg.Start, End: segmentEnd(seg)}}
+		}
+		return clips
+	}
+
+	var ranges []subtitles.Range
+	offset := 0.0
+	for _, seg := range segments {
+		end := segmentEnd(seg)
+		ranges = append(ranges, subtitles.Range{Start: seg.Start, End: end, Offset: offset})
+		offset += end - seg.Start
+	}
+	return [][]subtitles.Range{ranges}
+}
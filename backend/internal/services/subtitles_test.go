@@ -0,0 +1,37 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"github.com/mifi/lossless-cut/backend/internal/subtitles"
+)
+
+func TestSubtitleClips(t *testing.T) {
+	end1, end2 := 10.0, 35.0
+	segments := []models.Segment{
+		{ID: "a", Start: 5, End: &end1},
+		{ID: "b", Start: 30, End: &end2},
+	}
+
+	if got := subtitleClips(nil, false); len(got) != 1 || got[0] != nil {
+		t.Errorf("subtitleClips() without segments = %v, want the whole track", got)
+	}
+
+	want := [][]subtitles.Range{{
+		{Start: 5, End: 10, Offset: 0},
+		{Start: 30, End: 35, Offset: 5},
+	}}
+	if got := subtitleClips(segments, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("subtitleClips() joined = %v, want %v", got, want)
+	}
+
+	want = [][]subtitles.Range{
+		{{Start: 5, End: 10}},
+		{{Start: 30, End: 35}},
+	}
+	if got := subtitleClips(segments, true); !reflect.DeepEqual(got, want) {
+		t.Errorf("subtitleClips() separate = %v, want %v", got, want)
+	}
+}
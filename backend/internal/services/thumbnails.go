@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultThumbnailCount = 20
+	maxThumbnailCount     = 200
+	defaultThumbnailWidth = 160
+	minThumbnailWidth     = 16
+	maxThumbnailWidth     = 640
+	// Frames kept of all videos' thumbnail sets; the least recently used sets are
+	// deleted beyond it
+	maxCachedThumbnails = 5000
+)
+
+// thumbnailNamePattern matches the directories and files of generated thumbnail sets
+var thumbnailNamePattern = regexp.MustCompile(`^(strip_\d+_\d+|thumb_\d+\.jpg)$`)
+
+// ThumbnailRequest selects evenly spaced frames of a video, either Count of them or
+// one every Interval seconds
+type ThumbnailRequest struct {
+	Count    int     `form:"count"`
+	Interval float64 `form:"interval"`
+	Width    int     `form:"width"` // Pixels, default 160
+}
+
+// Thumbnail is one frame of a filmstrip
+type Thumbnail struct {
+	Time float64 `json:"time"`
+	URL  string  `json:"url"`
+}
+
+// ThumbnailSet is a filmstrip of a video
+type ThumbnailSet struct {
+	Interval   float64     `json:"interval"`
+	Width      int         `json:"width"`
+	Thumbnails []Thumbnail `json:"thumbnails"`
+}
+
+// thumbnailInterval returns the seconds between the thumbnails of a request, rounded
+// to milliseconds so equal requests share their cached frames. Requests for more
+// than maxThumbnailCount thumbnails get that many.
+func thumbnailInterval(duration float64, req ThumbnailRequest) (float64, error) {
+	if duration <= 0 {
+		return 0, invalidf("video duration is unknown")
+	}
+
+	var interval float64
+	switch {
+	case req.Interval < 0 || req.Count < 0:
+		return 0, invalidf("count and interval must be positive")
+	case req.Interval > 0:
+		interval = req.Interval
+	default:
+		count := req.Count
+		if count == 0 {
+			count = defaultThumbnailCount
+		}
+		interval = duration / float64(count)
+	}
+
+	// The smallest interval is rounded up, rounding it down would add a thumbnail
+	smallest := math.Ceil(duration/maxThumbnailCount*1000) / 1000
+	return math.Max(math.Round(interval*1000)/1000, math.Max(smallest, 0.001)), nil
+}
+
+// thumbnailWidth returns the width of a request's thumbnails, clamped to the
+// supported range
+func thumbnailWidth(req ThumbnailRequest) int {
+	if req.Width == 0 {
+		return defaultThumbnailWidth
+	}
+	return min(max(req.Width, minThumbnailWidth), maxThumbnailWidth)
+}
+
+// Thumbnails returns evenly spaced frames of a video, extracting them with a single
+// FFmpeg run on first request and reusing them afterwards
+func (s *VideoService) Thumbnails(ctx context.Context, videoID string, req ThumbnailRequest) (*ThumbnailSet, error) {
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, err
+	}
+	if video.Width == 0 || video.Height == 0 {
+		return nil, invalidf("video has no video stream")
+	}
+
+	interval, err := thumbnailInterval(video.Duration, req)
+	if err != nil {
+		return nil, err
+	}
+	width := thumbnailWidth(req)
+
+	videoDir := s.storage.GetVideoThumbnailsDir(videoID)
+	setName := fmt.Sprintf("strip_%d_%d", int64(math.Round(interval*1000)), width)
+	setDir := filepath.Join(videoDir, setName)
+	if !s.storage.FileExists(setDir) {
+		if err := s.extractThumbnails(ctx, video.FilePath, videoDir, setDir, interval, width, video.Duration); err != nil {
+			return nil, err
+		}
+		s.pruneThumbnails(setDir)
+	} else {
+		// The modification time orders the sets by last use for pruning
+		now := time.Now()
+		os.Chtimes(setDir, now, now)
+	}
+
+	entries, err := os.ReadDir(setDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thumbnails: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	set := &ThumbnailSet{Interval: interval, Width: width, Thumbnails: []Thumbnail{}}
+	for i, name := range names {
+		t := float64(i) * interval
+		if t >= video.Duration {
+			break
+		}
+		set.Thumbnails = append(set.Thumbnails, Thumbnail{
+			Time: t,
+			URL:  fmt.Sprintf("/api/videos/%s/thumbnails/%s/%s", videoID, setName, name),
+		})
+	}
+	return set, nil
+}
+
+// extractThumbnails extracts a thumbnail set into a temporary directory that is
+// renamed to setDir once complete, so concurrent requests never see a partial set
+func (s *VideoService) extractThumbnails(ctx context.Context, input, videoDir, setDir string, interval float64, width int, duration float64) error {
+	if err := os.MkdirAll(videoDir, 0755); err != nil {
+		return fmt.Errorf("failed to create thumbnails directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp(videoDir, "partial-")
+	if err != nil {
+		return fmt.Errorf("failed to create thumbnails directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if err := s.ffmpeg.ExtractThumbnails(ctx, input, filepath.Join(tempDir, "thumb_%04d.jpg"), interval, width, duration); err != nil {
+		return fmt.Errorf("failed to extract thumbnails: %w", err)
+	}
+
+	// Another request may have finished the same set first, its frames are equal
+	if err := os.Rename(tempDir, setDir); err != nil && !s.storage.FileExists(setDir) {
+		return fmt.Errorf("failed to save thumbnails: %w", err)
+	}
+
+	s.logger.Info("Extracted thumbnails",
+		zap.String("path", setDir),
+		zap.Float64("interval", interval),
+		zap.Int("width", width),
+	)
+	return nil
+}
+
+// thumbnailSet is a cached thumbnail set found by pruneThumbnails
+type thumbnailSet struct {
+	path    string
+	frames  int
+	lastUse time.Time
+}
+
+// pruneThumbnails deletes the least recently used thumbnail sets of all videos while
+// they hold more than maxCachedThumbnails frames. The set just extracted is kept.
+func (s *VideoService) pruneThumbnails(keep string) {
+	videoDirs, err := os.ReadDir(s.storage.ThumbnailsDir())
+	if err != nil {
+		return
+	}
+
+	var sets []thumbnailSet
+	total := 0
+	for _, videoDir := range videoDirs {
+		dir := filepath.Join(s.storage.ThumbnailsDir(), videoDir.Name())
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || !entry.IsDir() || !thumbnailNamePattern.MatchString(entry.Name()) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			frames, _ := os.ReadDir(path)
+			sets = append(sets, thumbnailSet{path: path, frames: len(frames), lastUse: info.ModTime()})
+			total += len(frames)
+		}
+	}
+
+	sort.Slice(sets, func(i, j int) bool { return sets[i].lastUse.Before(sets[j].lastUse) })
+	for _, set := range sets {
+		if total <= maxCachedThumbnails {
+			break
+		}
+		if set.path == keep {
+			continue
+		}
+		if err := os.RemoveAll(set.path); err != nil {
+			s.logger.Warn("Failed to delete thumbnails", zap.String("path", set.path), zap.Error(err))
+			continue
+		}
+		total -= set.frames
+		s.logger.Info("Deleted least recently used thumbnails", zap.String("path", set.path))
+	}
+}
+
+// ThumbnailPath returns the path of a generated thumbnail, rejecting names that
+// don't belong to a thumbnail set
+func (s *VideoService) ThumbnailPath(videoID, set, filename string) (string, error) {
+	if !thumbnailNamePattern.MatchString(set) || !thumbnailNamePattern.MatchString(filename) {
+		return "", fmt.Errorf("invalid thumbnail name")
+	}
+	path := filepath.Join(s.storage.GetVideoThumbnailsDir(filepath.Base(videoID)), set, filename)
+	if !s.storage.FileExists(path) {
+		return "", fmt.Errorf("thumbnail not found")
+	}
+	return path, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import "testing"
+
+func TestThumbnailInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration float64
+		req      ThumbnailRequest
+		want     float64
+		wantErr  bool
+	}{
+		{"default count", 100, ThumbnailRequest{}, 5, false},
+		{"count", 10, ThumbnailRequest{Count: 3}, 3.333, false},
+		{"interval", 100, ThumbnailRequest{Interval: 2.5}, 2.5, false},
+		{"interval wins", 100, ThumbnailRequest{Count: 4, Interval: 10}, 10, false},
+		{"too many by count", 100, ThumbnailRequest{Count: 201}, 0.5, false},
+		{"too many by interval", 100, ThumbnailRequest{Interval: 0.1}, 0.5, false},
+		{"rounded up", 10, ThumbnailRequest{Count: 300}, 0.05, false},
+		{"negative", 100, ThumbnailRequest{Interval: -1}, 0, true},
+		{"unknown duration", 0, ThumbnailRequest{}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := thumbnailInterval(tt.duration, tt.req)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: thumbnailInterval() = %v, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestThumbnailWidth(t *testing.T) {
+	tests := []struct {
+		width int
+		want  int
+	}{
+		{0, defaultThumbnailWidth},
+		{320, 320},
+		{4, minThumbnailWidth},
+		{-10, minThumbnailWidth},
+		{4000, maxThumbnailWidth},
+	}
+	for _, tt := range tests {
+		if got := thumbnailWidth(ThumbnailRequest{Width: tt.width}); got != tt.want {
+			t.Errorf("thumbnailWidth(%d) = %d, want %d", tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	torrentstorage "github.com/anacrolix/torrent/storage"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// torrentVideoExtensions are the files imported from a torrent
+var torrentVideoExtensions = map[string]bool{
+	".mp4": true, ".mov": true, ".mkv": true, ".webm": true, ".avi": true, ".wmv": true,
+	".flv": true, ".m4v": true, ".3gp": true, ".ts": true, ".m2ts": true, ".mpg": true,
+	".mpeg": true, ".mts": true, ".ogv": true,
+}
+
+// torrentFileLimit caps the size of .torrent files fetched from a URL
+const torrentFileLimit = 10 << 20
+
+// torrentProgressInterval is how often the progress of a torrent is saved
+const torrentProgressInterval = time.Second
+
+// isTorrentURL reports whether a download URL is a magnet link or a .torrent file
+func isTorrentURL(raw string) bool {
+	if strings.HasPrefix(strings.ToLower(raw), "magnet:") {
+		return true
+	}
+	u, err := url.Parse(raw)
+	return err == nil && strings.EqualFold(filepath.Ext(u.Path), ".torrent")
+}
+
+// torrentVideoFiles returns the video files downloaded into dir, in path order
+func torrentVideoFiles(dir string) []string {
+	var files []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && torrentVideoExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// torrentClient returns the BitTorrent client of the torrent downloads, started on
+// first use. It doesn't seed, torrents are dropped once downloaded.
+func (s *DownloadService) torrentClient() (*torrent.Client, error) {
+	s.torrentMu.Lock()
+	defer s.torrentMu.Unlock()
+	if s.torrents != nil {
+		return s.torrents, nil
+	}
+
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.ListenPort = s.config.Torrent.ListenPort
+	cfg.Seed = false
+	cfg.NoDefaultPortForwarding = true
+	// Each torrent is stored in its own folder, piece states are only kept in memory
+	cfg.DataDir = filepath.Join(s.storage.GetDownloadPath(), "torrents")
+	cfg.DefaultStorage = torrentstorage.NewFileOpts(torrentstorage.NewFileClientOpts{
+		ClientBaseDir:   cfg.DataDir,
+		PieceCompletion: torrentstorage.NewMapPieceCompletion(),
+	})
+
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start torrent client: %w", err)
+	}
+	s.torrents = client
+	return client, nil
+}
+
+// torrentSpec parses a magnet link, or fetches and parses a .torrent URL with the
+// download's client headers
+func (s *DownloadService) torrentSpec(ctx context.Context, req DownloadRequest) (*torrent.TorrentSpec, error) {
+	if strings.HasPrefix(strings.ToLower(req.URL), "magnet:") {
+		return torrent.TorrentSpecFromMagnetUri(req.URL)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	applyDownloadClient(httpReq, s.client(req))
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch torrent file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch torrent file: %s", resp.Status)
+	}
+
+	info, err := metainfo.Load(io.LimitReader(resp.Body, torrentFileLimit))
+	if err != nil {
+		return nil, fmt.Errorf("invalid torrent file: %w", err)
+	}
+	return torrent.TorrentSpecFromMetaInfoErr(info)
+}
+
+// fetchTorrent downloads a torrent into dir, saving its progress, and returns the
+// video files it contains
+func (s *DownloadService) fetchTorrent(ctx context.Context, download *models.Download, req DownloadRequest, dir string) ([]string, error) {
+	client, err := s.torrentClient()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := s.torrentSpec(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	store := torrentstorage.NewFileOpts(torrentstorage.NewFileClientOpts{
+		ClientBaseDir:   dir,
+		PieceCompletion: torrentstorage.NewMapPieceCompletion(),
+	})
+	defer store.Close()
+	spec.Storage = store
+	t, added, err := client.AddTorrentSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		// The client holds a torrent once, its other download owns it
+		return nil, fmt.Errorf("torrent is already being downloaded")
+	}
+	defer t.Drop()
+
+	s.logger.Info("Starting torrent download", zap.String("id", download.ID), zap.String("dir", dir))
+	select {
+	case <-t.GotInfo():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	total := t.Length()
+	name := t.Name()
+	s.setDownload(download, func() {
+		download.Title = strings.TrimSuffix(name, filepath.Ext(name))
+		download.TotalBytes = total
+	})
+	t.DownloadAll()
+
+	ticker := time.NewTicker(torrentProgressInterval)
+	defer ticker.Stop()
+	var meter speedMeter
+	last, lastTime := t.BytesCompleted(), time.Now()
+	for !t.Complete().Bool() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.Complete().On():
+		case now := <-ticker.C:
+			completed := t.BytesCompleted()
+			speed := meter.update(completed-last, now.Sub(lastTime))
+			last, lastTime = completed, now
+			s.setDownload(download, func() {
+				download.DownloadedBytes = completed
+				download.Speed = speed
+				download.ETA = downloadETA(total-completed, speed)
+				if total > 0 {
+					download.Progress = float64(completed) / float64(total) * 100
+				}
+			})
+		}
+	}
+	return torrentVideoFiles(dir), nil
+}
+
+// runTorrentDownload downloads a magnet link or .torrent URL into its own folder and
+// imports every video file it contains. The download links to the first video; all
+// are listed in VideoIDs. Nothing of a failed or cancelled torrent is kept: its
+// folder is removed and videos imported before a failing one are deleted again.
+func (s *DownloadService) runTorrentDownload(ctx context.Context, download *models.Download, req DownloadRequest) {
+	dir := filepath.Join(s.storage.GetDownloadPath(), "torrents", download.ID)
+	fail := func(err error) {
+		os.RemoveAll(dir)
+		s.logger.Error("Torrent download failed", zap.String("id", download.ID), zap.Error(err))
+		s.setDownload(download, func() {
+			download.Status = models.DownloadStatusFailed
+			download.Error = err.Error()
+			download.Speed = 0
+			download.ETA = 0
+		})
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fail(err)
+		return
+	}
+
+	files, err := s.fetchTorrent(ctx, download, req, dir)
+	if ctx.Err() != nil {
+		// CancelDownload already saved the download as cancelled
+		os.RemoveAll(dir)
+		s.logger.Info("Download cancelled", zap.String("id", download.ID))
+		return
+	}
+	if err == nil && len(files) == 0 {
+		err = errors.New("torrent contains no video files")
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	var videos []*models.Video
+	for _, path := range files {
+		video, err := s.importDownload(filepath.Base(path), path)
+		if err != nil {
+			s.rollbackTorrent(download, videos)
+			fail(fmt.Errorf("failed to import %s: %w", filepath.Base(path), err))
+			return
+		}
+		video.OriginalURL = download.URL
+		videos = append(videos, video)
+		s.setDownload(download, func() {
+			s.createDownloadProject(download, video, "")
+		})
+	}
+
+	s.setDownload(download, func() {
+		download.VideoID = videos[0].ID
+		download.FilePath = files[0]
+		download.VideoIDs = nil
+		for _, video := range videos {
+			download.VideoIDs = append(download.VideoIDs, video.ID)
+		}
+		download.Status = models.DownloadStatusCompleted
+		download.Progress = 100.0
+		download.DownloadedBytes = download.TotalBytes
+		download.Speed = 0
+		download.ETA = 0
+	})
+	for _, video := range videos {
+		s.storage.RecordUsage(models.UsageDownloads, 1, video.FileSize)
+		s.importChecksum(video)
+	}
+
+	s.logger.Info("Torrent download completed and imported",
+		zap.String("id", download.ID),
+		zap.Strings("videoIds", download.VideoIDs),
+	)
+
+	// Clean up from memory
+	s.mu.Lock()
+	delete(s.downloads, download.ID)
+	s.mu.Unlock()
+}
+
+// rollbackTorrent deletes the videos and projects a torrent imported before one of
+// its files failed, so a failed torrent doesn't leave some of its videos behind
+func (s *DownloadService) rollbackTorrent(download *models.Download, videos []*models.Video) {
+	s.mu.Lock()
+	projectIDs := download.ProjectIDs
+	download.ProjectIDs = nil
+	s.mu.Unlock()
+
+	for _, id := range projectIDs {
+		if err := s.storage.DeleteProject(id); err != nil {
+			s.logger.Warn("Failed to delete project of failed torrent", zap.String("projectId", id), zap.Error(err))
+		}
+	}
+	for _, video := range videos {
+		if err := s.storage.DeleteVideo(video.ID); err != nil {
+			s.logger.Warn("Failed to delete video of failed torrent", zap.String("videoId", video.ID), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package services
+
+import "testing"
+
+func TestIsTorrentURL(t *testing.T) {
+	tests := map[string]bool{
+		"magnet:?xt=urn:btih:c9e15763f722f23e98a29decdfae341b98d53056": true,
+		"MAGNET:?xt=urn:btih:abc":                                      true,
+		"https://archive.org/download/film/film_archive.torrent":       true,
+		"https://example.com/film.torrent?token=1":                     true,
+		"https://example.com/film.mp4":                                 false,
+		"https://www.youtube.com/watch?v=abc":                          false,
+	}
+	for raw, want := range tests {
+		if got := isTorrentURL(raw); got != want {
+			t.Errorf("isTorrentURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// watchExtensions are the files the watch folder imports; anything else, e.g. files
+// still being written as .part, is left alone
+var watchExtensions = map[string]bool{
+	".mp4": true, ".mov": true, ".mkv": true, ".webm": true, ".avi": true, ".wmv": true,
+	".flv": true, ".m4v": true, ".3gp": true, ".ts": true, ".m2ts": true, ".mpg": true,
+	".mpeg": true, ".mts": true, ".ogv": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".flac": true, ".wav": true, ".ogg": true,
+	".opus": true,
+}
+
+// importRoots returns the directories videos can be imported from
+func (s *VideoService) importRoots() []string {
+	roots := append([]string(nil), s.config.Import.Paths...)
+	if s.config.Import.WatchDir != "" {
+		roots = append(roots, s.config.Import.WatchDir)
+	}
+	return roots
+}
+
+// resolveImportPath resolves symlinks in an import path and checks that the file it
+// names lies within one of roots, so links can't reach outside them. The path is
+// checked before it is resolved too, so paths outside don't tell whether they exist.
+func resolveImportPath(roots []string, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", invalidf("path must be absolute")
+	}
+	if !inImportRoots(roots, path, false) {
+		return "", forbiddenf("%s is not in a directory configured in import.paths", path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", notFoundf("file not found: %s", path)
+		}
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if !inImportRoots(roots, resolved, true) {
+		return "", forbiddenf("%s is not in a directory configured in import.paths", path)
+	}
+	return resolved, nil
+}
+
+// inImportRoots reports whether path lies below one of roots with their symlinks
+// resolved, or unless resolvedOnly is set, below one of roots as configured
+func inImportRoots(roots []string, path string, resolvedOnly bool) bool {
+	for _, root := range roots {
+		candidates := []string{}
+		if !resolvedOnly {
+			candidates = append(candidates, root)
+		}
+		if resolvedRoot, err := filepath.EvalSymlinks(root); err == nil {
+			candidates = append(candidates, resolvedRoot)
+		}
+		for _, dir := range candidates {
+			rel, err := filepath.Rel(dir, path)
+			if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ImportPath registers a file in one of the import directories as a video without
+// copying it. A file imported before returns its existing video.
+func (s *VideoService) ImportPath(path string) (*models.Video, bool, error) {
+	if len(s.importRoots()) == 0 {
+		return nil, false, forbiddenf("importing from server paths is disabled, configure import.paths")
+	}
+	resolved, err := resolveImportPath(s.importRoots(), path)
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, notFoundf("file not found: %s", path)
+		}
+		return nil, false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, false, invalidf("%s is not a file", path)
+	}
+
+	if video := s.findImported(resolved); video != nil {
+		return video, false, nil
+	}
+	return s.importFile(resolved)
+}
+
+// findImported returns the video already imported from a path, if any
+func (s *VideoService) findImported(path string) *models.Video {
+	video, err := s.storage.FindVideoByPath(path)
+	if err != nil || video == nil || !video.External {
+		return nil
+	}
+	return video
+}
+
+func (s *VideoService) importFile(path string) (*models.Video, bool, error) {
+	video, err := s.createVideo(filepath.Base(path), path, true)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := s.ImportChecksum(video, ""); err != nil {
+		s.logger.Warn("Failed to start import checksum", zap.String("id", video.ID), zap.Error(err))
+	}
+	return video, true, nil
+}
+
+// watchedFile is the size and modification time of a file in the watch folder when
+// it was last scanned
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+}
+
+// scanWatchDir lists the importable files of a watch folder and its subdirectories.
+// Hidden files and directories are skipped.
+func scanWatchDir(dir string) (map[string]watchedFile, error) {
+	files := make(map[string]watchedFile)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Unreadable entries are retried on the next scan
+		}
+		if strings.HasPrefix(entry.Name(), ".") && path != dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !watchExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		files[path] = watchedFile{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
+
+// settledFiles returns the files of a scan that are unchanged since the previous
+// one, i.e. no longer being copied into the watch folder
+func settledFiles(previous, current map[string]watchedFile) []string {
+	var settled []string
+	for path, file := range current {
+		if before, ok := previous[path]; ok && before == file {
+			settled = append(settled, path)
+		}
+	}
+	return settled
+}
+
+// WatchImports imports every file dropped into import.watch_dir once it stopped
+// changing. Each file is imported once, deleting its video doesn't import it again.
+func (s *VideoService) WatchImports(ctx context.Context) {
+	dir := s.config.Import.WatchDir
+	if dir == "" {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(s.config.Import.WatchIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	s.logger.Info("Watching for files to import", zap.String("dir", dir))
+
+	var previous map[string]watchedFile
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			previous = s.importSettled(dir, previous)
+		}
+	}
+}
+
+// importSettled scans the watch folder and imports the files that settled since the
+// previous scan. It returns this scan for the next one.
+func (s *VideoService) importSettled(dir string, previous map[string]watchedFile) map[string]watchedFile {
+	current, err := scanWatchDir(dir)
+	if err != nil {
+		s.logger.Warn("Failed to scan watch folder", zap.String("dir", dir), zap.Error(err))
+		return previous
+	}
+	imported, err := s.storage.WatchImports()
+	if err != nil {
+		s.logger.Warn("Failed to read watch imports", zap.Error(err))
+		return current
+	}
+
+	for _, path := range settledFiles(previous, current) {
+		if imported[path] {
+			continue
+		}
+		video, _, err := s.ImportPath(path)
+		if err != nil {
+			s.logger.Warn("Failed to import watched file", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		if err := s.storage.AddWatchImport(path); err != nil {
+			s.logger.Warn("Failed to record watch import", zap.String("path", path), zap.Error(err))
+		}
+		s.logger.Info("Imported watched file", zap.String("path", path), zap.String("videoId", video.ID))
+	}
+	return current
+}
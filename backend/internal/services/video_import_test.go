@@ -0,0 +1,87 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/storage"
+)
+
+func TestResolveImportPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	for _, path := range []string{filepath.Join(root, "show", "ep1.mkv"), filepath.Join(outside, "secret.mp4")} {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("x"), 0644)
+	}
+	os.Symlink(filepath.Join(outside, "secret.mp4"), filepath.Join(root, "link.mp4"))
+	roots := []string{"/nonexistent", root}
+
+	inside := filepath.Join(root, "show", "ep1.mkv")
+	if got, err := resolveImportPath(roots, inside); err != nil || filepath.Base(got) != "ep1.mkv" {
+		t.Errorf("resolveImportPath(%s) = %q, %v", inside, got, err)
+	}
+
+	tests := map[string]error{
+		filepath.Join(outside, "secret.mp4"):                            ErrForbidden,
+		filepath.Join(root, "link.mp4"):                                 ErrForbidden, // Symlink out of the root
+		filepath.Join(root, "..", filepath.Base(outside), "secret.mp4"): ErrForbidden,
+		filepath.Join(outside, "missing.mp4"):                           ErrForbidden, // Doesn't tell it's missing
+		filepath.Join(root, "missing.mp4"):                              storage.ErrNotFound,
+		"show/ep1.mkv":                                                  ErrInvalid,
+		root:                                                            ErrForbidden,
+	}
+	for path, want := range tests {
+		if got, err := resolveImportPath(roots, path); !errors.Is(err, want) {
+			t.Errorf("resolveImportPath(%s) = %q, %v, want %v", path, got, err, want)
+		}
+	}
+}
+
+func TestScanWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.mp4", "sub/b.MKV", "c.mp4.part", "notes.txt", ".hidden.mp4", ".sync/d.mp4"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("data"), 0644)
+	}
+
+	files, err := scanWatchDir(dir)
+	if err != nil {
+		t.Fatalf("scanWatchDir() = %v", err)
+	}
+	var got []string
+	for path := range files {
+		rel, _ := filepath.Rel(dir, path)
+		got = append(got, rel)
+	}
+	sort.Strings(got)
+	want := []string{"a.mp4", filepath.Join("sub", "b.MKV")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanWatchDir() = %v, want %v", got, want)
+	}
+}
+
+func TestSettledFiles(t *testing.T) {
+	now := time.Now()
+	previous := map[string]watchedFile{
+		"/w/done.mp4":    {size: 100, modTime: now},
+		"/w/growing.mp4": {size: 50, modTime: now},
+	}
+	current := map[string]watchedFile{
+		"/w/done.mp4":    {size: 100, modTime: now},
+		"/w/growing.mp4": {size: 80, modTime: now.Add(time.Second)},
+		"/w/new.mp4":     {size: 10, modTime: now},
+	}
+	if got := settledFiles(previous, current); !reflect.DeepEqual(got, []string{"/w/done.mp4"}) {
+		t.Errorf("settledFiles() = %v, want only the unchanged file", got)
+	}
+	if got := settledFiles(nil, current); len(got) != 0 {
+		t.Errorf("settledFiles() of the first scan = %v, want none", got)
+	}
+}
@@ -1,8 +1,16 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,22 +22,30 @@ import (
 )
 
 type VideoService struct {
-	storage *storage.Manager
-	config  *config.Config
-	logger  *zap.Logger
-	ffmpeg  *ffmpeg.Executor
+	storage    *storage.Manager
+	operations *OperationService // Runs checksums of imported files
+	config     *config.Config
+	logger     *zap.Logger
+	ffmpeg     *ffmpeg.Executor
 }
 
-func NewVideoService(storage *storage.Manager, cfg *config.Config, logger *zap.Logger) *VideoService {
+func NewVideoService(storage *storage.Manager, operations *OperationService, cfg *config.Config, logger *zap.Logger) *VideoService {
 	return &VideoService{
-		storage: storage,
-		config:  cfg,
-		logger:  logger,
-		ffmpeg:  ffmpeg.NewExecutor(cfg.FFmpeg.Path, "ffprobe", logger),
+		storage:    storage,
+		operations: operations,
+		config:     cfg,
+		logger:     logger,
+		ffmpeg:     newFFmpegExecutor(cfg, logger),
 	}
 }
 
 func (s *VideoService) CreateFromUpload(filename string, filepath string) (*models.Video, error) {
+	return s.createVideo(filename, filepath, false)
+}
+
+// createVideo registers a file as a video. External files stay where they are and
+// are never modified or deleted.
+func (s *VideoService) createVideo(filename string, filepath string, external bool) (*models.Video, error) {
 	// Get file size
 	fileSize, err := s.storage.GetFileSize(filepath)
 	if err != nil {
@@ -42,6 +58,7 @@ func (s *VideoService) CreateFromUpload(filename string, filepath string) (*mode
 		FileName:  filename,
 		FilePath:  filepath,
 		FileSize:  fileSize,
+		External:  external,
 		CreatedAt: time.Now(),
 	}
 
@@ -73,6 +90,7 @@ func (s *VideoService) CreateFromUpload(filename string, filepath string) (*mode
 		if metadata := convertProbeToMetadata(probe); metadata != nil {
 			video.Metadata = *metadata
 		}
+		video.Warnings = convertProbeWarnings(probe)
 	}
 
 	// Save video metadata
@@ -81,8 +99,9 @@ func (s *VideoService) CreateFromUpload(filename string, filepath string) (*mode
 		// Don't fail to upload if metadata save fails, just log it
 	}
 
-	s.logger.Info("Created video from upload",
+	s.logger.Info("Created video",
 		zap.String("id", video.ID),
+		zap.Bool("external", external),
 		zap.String("filename", filename),
 		zap.Float64("duration", video.Duration),
 		zap.String("format", video.Format),
@@ -93,6 +112,16 @@ func (s *VideoService) CreateFromUpload(filename string, filepath string) (*mode
 	return video, nil
 }
 
+// ImportChecksum starts computing the checksum of a newly imported video when
+// storage.compute_checksums is enabled or the client sent the expected checksum.
+// It returns nil when no checksum is computed.
+func (s *VideoService) ImportChecksum(video *models.Video, expected string) (*models.Operation, error) {
+	if expected == "" && !s.config.Storage.ComputeChecksums {
+		return nil, nil
+	}
+	return s.operations.ChecksumVideo(video.ID, expected)
+}
+
 func (s *VideoService) GetVideo(id string) (*models.Video, error) {
 	return s.storage.GetVideo(id)
 }
@@ -107,17 +136,181 @@ func (s *VideoService) DeleteVideo(id string) error {
 		return err
 	}
 
-	// Delete physical file
-	if err := s.storage.DeleteFile(video.FilePath); err != nil {
+	// Delete physical file, imported files belong to their directory
+	if video.External {
+		s.logger.Info("Keeping imported video file", zap.String("path", video.FilePath))
+	} else if err := s.storage.DeleteFile(video.FilePath); err != nil {
 		s.logger.Warn("Failed to delete video file", zap.String("path", video.FilePath), zap.Error(err))
 	}
 
 	// Delete metadata
-	return s.storage.DeleteVideo(id)
+	if err := s.storage.DeleteVideo(id); err != nil {
+		return err
+	}
+	s.storage.RecordDeletedVideos(video)
+	return nil
+}
+
+// ValidRotation reports whether degrees is a supported display rotation
+func ValidRotation(degrees int) bool {
+	return ffmpeg.ValidRotation(degrees)
+}
+
+// Rotate sets the display rotation of a video to degrees clockwise by remuxing the
+// file in place, without re-encoding. Thumbnails are regenerated with the new
+// orientation.
+func (s *VideoService) Rotate(ctx context.Context, videoID string, degrees int) (*models.Video, error) {
+	if !ffmpeg.ValidRotation(degrees) {
+		return nil, invalidf("invalid rotation %d, use 0, 90, 180 or 270", degrees)
+	}
+
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	if video.External {
+		return nil, invalidf("imported videos aren't modified in place, export a rotated copy instead")
+	}
+
+	// Remux next to the original and only replace it once complete
+	ext := filepath.Ext(video.FilePath)
+	tempPath := strings.TrimSuffix(video.FilePath, ext) + ".rotating" + ext
+	if err := s.ffmpeg.Rotate(ctx, video.FilePath, tempPath, degrees); err != nil {
+		s.storage.DeleteFile(tempPath)
+		if errors.Is(err, ffmpeg.ErrDisplayRotation) {
+			return nil, invalid(err)
+		}
+		return nil, fmt.Errorf("failed to rotate video: %w", err)
+	}
+	if err := os.Rename(tempPath, video.FilePath); err != nil {
+		s.storage.DeleteFile(tempPath)
+		return nil, fmt.Errorf("failed to replace video file: %w", err)
+	}
+	if err := s.storage.StoreFile(video.FilePath); err != nil {
+		return nil, err
+	}
+
+	video.Rotation = degrees
+	video.SHA256 = "" // The file changed
+	if size, err := s.storage.GetFileSize(video.FilePath); err == nil {
+		video.FileSize = size
+	}
+	if probe, err := s.ffmpeg.Probe(ctx, video.FilePath); err != nil {
+		s.logger.Warn("Failed to probe rotated video", zap.String("videoId", videoID), zap.Error(err))
+	} else {
+		if metadata := convertProbeToMetadata(probe); metadata != nil {
+			video.Metadata = *metadata
+		}
+		video.Warnings = convertProbeWarnings(probe)
+	}
+
+	if err := s.storage.DeleteVideoThumbnails(videoID); err != nil {
+		s.logger.Warn("Failed to delete thumbnails", zap.String("videoId", videoID), zap.Error(err))
+	}
+	if err := s.storage.DeleteWaveforms(video); err != nil {
+		s.logger.Warn("Failed to delete waveforms", zap.String("videoId", videoID), zap.Error(err))
+	}
+	if err := s.storage.SaveVideo(video); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Rotated video",
+		zap.String("videoId", videoID),
+		zap.Int("degrees", degrees),
+	)
+
+	return video, nil
+}
+
+// subtitleExtensions are the sidecar subtitle formats accepted for upload
+var subtitleExtensions = map[string]bool{
+	".srt": true,
+	".vtt": true,
+	".ass": true,
+	".ssa": true,
+}
+
+// languagePattern matches ISO 639 codes with an optional region, e.g. "en", "eng", "pt-BR"
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+
+// AddSubtitle attaches an uploaded sidecar subtitle file to a video
+func (s *VideoService) AddSubtitle(videoID, filename, path, language string) (*models.SubtitleFile, error) {
+	if !subtitleExtensions[strings.ToLower(filepath.Ext(filename))] {
+		return nil, fmt.Errorf("unsupported subtitle format: %s", filepath.Ext(filename))
+	}
+	if language != "" && !languagePattern.MatchString(language) {
+		return nil, fmt.Errorf("invalid language: %s", language)
+	}
+
+	video, err := s.storage.GetVideo(videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	subtitle := models.SubtitleFile{
+		ID:       uuid.New().String(),
+		FileName: filename,
+		FilePath: path,
+		Language: language,
+	}
+	video.Subtitles = append(video.Subtitles, subtitle)
+
+	if err := s.storage.SaveVideo(video); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Added subtitle file",
+		zap.String("videoId", videoID),
+		zap.String("subtitleId", subtitle.ID),
+		zap.String("filename", filename),
+	)
+
+	return &subtitle, nil
+}
+
+// pngSignature starts every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// AddWatermark attaches an uploaded PNG image to a video for use as an export watermark
+func (s *VideoService) AddWatermark(videoID, filename, path string) (*models.ImageFile, error) {
+	header := make([]byte, len(pngSignature))
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.ReadFull(file, header)
+	file.Close()
+	if err != nil || !bytes.Equal(header, pngSignature) {
+		return nil, invalidf("watermark must be a PNG image")
+	}
+
+	watermark := models.ImageFile{
+		ID:       uuid.New().String(),
+		FileName: filename,
+		FilePath: path,
+	}
+	_, err = s.storage.ModifyVideo(videoID, func(video *models.Video) error {
+		if !hasVideoStream(video.Metadata.Streams) {
+			return invalidf("a watermark needs a video stream")
+		}
+		video.Watermarks = append(video.Watermarks, watermark)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Added watermark image",
+		zap.String("videoId", videoID),
+		zap.String("watermarkId", watermark.ID),
+		zap.String("filename", filename),
+	)
+
+	return &watermark, nil
 }
 
 func (s *VideoService) StreamVideo(id string) (string, error) {
-	video, err := s.storage.GetVideo(id)
+	video, err := s.storage.FetchVideo(id)
 	if err != nil {
 		return "", err
 	}
@@ -126,7 +319,7 @@ func (s *VideoService) StreamVideo(id string) (string, error) {
 }
 
 func (s *VideoService) CaptureScreenshot(videoID string, timestamp float64) (string, error) {
-	video, err := s.storage.GetVideo(videoID)
+	video, err := s.storage.FetchVideo(videoID)
 	if err != nil {
 		return "", fmt.Errorf("video not found: %w", err)
 	}
@@ -154,21 +347,99 @@ func (s *VideoService) CaptureScreenshot(videoID string, timestamp float64) (str
 	return screenshotID, nil
 }
 
+// MaxScreenshotBurst is the largest number of frames one burst captures
+const MaxScreenshotBurst = ffmpeg.MaxSnapshotBurst
+
+// ScreenshotFrame is one image of a screenshot burst
+type ScreenshotFrame struct {
+	Filename  string  `json:"filename"`
+	Timestamp float64 `json:"timestamp"` // Approximate time of the frame in the video
+}
+
+// CaptureScreenshotBurst captures count consecutive frames centered on timestamp
+// so the sharpest one near a cut point can be picked. Frames past the end of the
+// video are left out.
+func (s *VideoService) CaptureScreenshotBurst(videoID string, timestamp float64, count int) ([]ScreenshotFrame, error) {
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+
+	burstID := generateVideoID()
+	pattern := s.storage.GetScreenshotPath(burstID + "_%02d")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	times, err := s.ffmpeg.CaptureSnapshotBurst(ctx, video.FilePath, pattern, timestamp, count, 2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture screenshots: %w", err)
+	}
+
+	frames := make([]ScreenshotFrame, 0, len(times))
+	for i, t := range times {
+		filename := fmt.Sprintf("%s_%02d", burstID, i+1)
+		if !s.storage.FileExists(s.storage.GetScreenshotPath(filename)) {
+			break
+		}
+		frames = append(frames, ScreenshotFrame{Filename: filename, Timestamp: t})
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames captured at %.3fs", timestamp)
+	}
+
+	s.logger.Info("Captured screenshot burst",
+		zap.String("videoID", videoID),
+		zap.String("burstID", burstID),
+		zap.Float64("timestamp", timestamp),
+		zap.Int("frames", len(frames)),
+	)
+
+	return frames, nil
+}
+
 func (s *VideoService) GetScreenshotPath(screenshotID string) string {
 	return s.storage.GetScreenshotPath(screenshotID)
 }
 
-func (s *VideoService) GenerateWaveform(videoID string) (string, error) {
-	video, err := s.storage.GetVideo(videoID)
+// WaveformRequest selects the audio a waveform shows
+type WaveformRequest struct {
+	AudioStream   int  `form:"stream"`         // Index among the audio streams, 0 = first
+	SplitChannels bool `form:"split_channels"` // One lane per channel (image only)
+}
+
+// waveformOptions checks a request against the video's audio streams
+func waveformOptions(video *models.Video, req WaveformRequest) (ffmpeg.WaveformOptions, error) {
+	audioStreams := 0
+	for _, stream := range video.Metadata.Streams {
+		if stream.CodecType == "audio" {
+			audioStreams++
+		}
+	}
+	if audioStreams == 0 {
+		return ffmpeg.WaveformOptions{}, fmt.Errorf("video has no audio stream")
+	}
+	if req.AudioStream < 0 || req.AudioStream >= audioStreams {
+		return ffmpeg.WaveformOptions{}, fmt.Errorf("audio stream %d out of range, the video has %d", req.AudioStream, audioStreams)
+	}
+	return ffmpeg.WaveformOptions{AudioStream: req.AudioStream, SplitChannels: req.SplitChannels}, nil
+}
+
+func (s *VideoService) GenerateWaveform(videoID string, req WaveformRequest) (string, error) {
+	video, err := s.storage.FetchVideo(videoID)
 	if err != nil {
 		return "", fmt.Errorf("video not found: %w", err)
 	}
+	opts, err := waveformOptions(video, req)
+	if err != nil {
+		return "", err
+	}
 
-	// Generate waveform path
-	waveformPath := s.storage.GetWaveformPath(videoID + ".png")
+	// Generate waveform path, one per stream and channel layout
+	waveformPath := s.storage.GetVideoWaveformPath(video.ID, opts.AudioStream, opts.SplitChannels, storage.WaveformImage)
 
 	// Check if waveform already exists
-	if s.storage.FileExists(waveformPath) {
+	if s.storage.FetchFile(waveformPath) == nil {
 		return waveformPath, nil
 	}
 
@@ -176,10 +447,13 @@ func (s *VideoService) GenerateWaveform(videoID string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	err = s.ffmpeg.GenerateWaveform(ctx, video.FilePath, waveformPath)
+	err = s.ffmpeg.GenerateWaveform(ctx, video.FilePath, waveformPath, opts)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate waveform: %w", err)
 	}
+	if err := s.storage.StoreFile(waveformPath); err != nil {
+		s.logger.Warn("Failed to store waveform", zap.String("videoID", videoID), zap.Error(err))
+	}
 
 	s.logger.Info("Generated waveform",
 		zap.String("videoID", videoID),
@@ -189,6 +463,130 @@ func (s *VideoService) GenerateWaveform(videoID string) (string, error) {
 	return waveformPath, nil
 }
 
+// GenerateSpectrogram renders the spectrogram of a video's audio, cached like the
+// waveform. It shows what a waveform hides, e.g. music under speech.
+func (s *VideoService) GenerateSpectrogram(ctx context.Context, videoID string, req WaveformRequest) (string, error) {
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return "", fmt.Errorf("video not found: %w", err)
+	}
+	opts, err := waveformOptions(video, req)
+	if err != nil {
+		return "", err
+	}
+
+	spectrogramPath := s.storage.GetVideoWaveformPath(video.ID, opts.AudioStream, opts.SplitChannels, storage.WaveformSpectrogram)
+	if s.storage.FetchFile(spectrogramPath) == nil {
+		return spectrogramPath, nil
+	}
+
+	// The FFT is slower than drawing a waveform
+	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
+	defer cancel()
+
+	if err := s.ffmpeg.GenerateSpectrogram(ctx, video.FilePath, spectrogramPath, opts); err != nil {
+		// Don't leave a partial image to be served as cached
+		s.storage.DeleteFile(spectrogramPath)
+		return "", fmt.Errorf("failed to generate spectrogram: %w", err)
+	}
+	if err := s.storage.StoreFile(spectrogramPath); err != nil {
+		s.logger.Warn("Failed to store spectrogram", zap.String("videoID", videoID), zap.Error(err))
+	}
+
+	s.logger.Info("Generated spectrogram",
+		zap.String("videoID", videoID),
+		zap.String("spectrogramPath", spectrogramPath),
+	)
+
+	return spectrogramPath, nil
+}
+
+// WaveformZoom are the peaks of a video's audio at one zoom level
+type WaveformZoom struct {
+	Zoom     int       `json:"zoom"`   // Number of peaks
+	Levels   []int     `json:"levels"` // Available zoom levels
+	Duration float64   `json:"duration"`
+	Min      []float64 `json:"min"`
+	Max      []float64 `json:"max"`
+}
+
+// ValidateWaveformZoom checks zoom is one of the computed zoom levels
+func ValidateWaveformZoom(zoom int) error {
+	for _, level := range ffmpeg.WaveformZoomLevels {
+		if zoom == level {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported zoom level %d, use one of %v", zoom, ffmpeg.WaveformZoomLevels)
+}
+
+// WaveformPeaks returns the min/max peaks of a video's audio at a zoom level. All
+// levels are computed on first request and cached. Peaks always mix the channels.
+func (s *VideoService) WaveformPeaks(ctx context.Context, videoID string, req WaveformRequest, zoom int) (*WaveformZoom, error) {
+	if err := ValidateWaveformZoom(zoom); err != nil {
+		return nil, err
+	}
+
+	video, err := s.storage.FetchVideo(videoID)
+	if err != nil {
+		return nil, fmt.Errorf("video not found: %w", err)
+	}
+	opts, err := waveformOptions(video, WaveformRequest{AudioStream: req.AudioStream})
+	if err != nil {
+		return nil, err
+	}
+
+	waveform, err := s.loadWaveformPeaks(ctx, video, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	peaks, ok := waveform.Levels[zoom]
+	if !ok {
+		return nil, fmt.Errorf("waveform has no zoom level %d", zoom)
+	}
+	return &WaveformZoom{
+		Zoom:     zoom,
+		Levels:   ffmpeg.WaveformZoomLevels,
+		Duration: waveform.Duration,
+		Min:      peaks.Min,
+		Max:      peaks.Max,
+	}, nil
+}
+
+// loadWaveformPeaks reads a video's cached waveform peaks, generating them if needed
+func (s *VideoService) loadWaveformPeaks(ctx context.Context, video *models.Video, opts ffmpeg.WaveformOptions) (*ffmpeg.Waveform, error) {
+	peaksPath := s.storage.GetVideoWaveformPath(video.ID, opts.AudioStream, false, storage.WaveformPeaks)
+
+	var waveform ffmpeg.Waveform
+	if s.storage.FetchFile(peaksPath) == nil {
+		if data, err := os.ReadFile(peaksPath); err == nil && json.Unmarshal(data, &waveform) == nil {
+			return &waveform, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	generated, err := s.ffmpeg.GenerateWaveformPeaks(ctx, video.FilePath, opts.AudioStream, video.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate waveform peaks: %w", err)
+	}
+
+	data, err := json.Marshal(generated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal waveform peaks: %w", err)
+	}
+	if err := os.WriteFile(peaksPath, data, 0644); err != nil {
+		s.logger.Warn("Failed to cache waveform peaks", zap.String("videoID", video.ID), zap.Error(err))
+	} else if err := s.storage.StoreFile(peaksPath); err != nil {
+		s.logger.Warn("Failed to store waveform peaks", zap.String("videoID", video.ID), zap.Error(err))
+	}
+
+	s.logger.Info("Generated waveform peaks", zap.String("videoID", video.ID))
+	return generated, nil
+}
+
 func generateVideoID() string {
 	return uuid.New().String()
 }
@@ -228,11 +626,13 @@ func convertProbeToMetadata(probe *ffmpeg.ProbeResult) *models.VideoMetadata {
 	// Copy stream info
 	for _, stream := range probe.Streams {
 		streamInfo := models.Stream{
-			Index:     stream.Index,
-			CodecName: stream.CodecName,
-			CodecType: stream.CodecType,
-			Width:     stream.Width,
-			Height:    stream.Height,
+			Index:      stream.Index,
+			CodecName:  stream.CodecName,
+			CodecType:  stream.CodecType,
+			Width:      stream.Width,
+			Height:     stream.Height,
+			FieldOrder: stream.FieldOrder,
+			Rotation:   stream.DisplayRotation(),
 		}
 
 		// Parse duration if available
@@ -287,3 +687,21 @@ func parseSize(sizeStr string) (int64, error) {
 	_, err := fmt.Sscanf(sizeStr, "%d", &size)
 	return size, err
 }
+
+// convertProbeWarnings converts FFprobe findings to the warnings shown on a video
+func convertProbeWarnings(probe *ffmpeg.ProbeResult) []models.MediaWarning {
+	var warnings []models.MediaWarning
+	for _, w := range probe.Warnings() {
+		warning := models.MediaWarning{
+			Code:       w.Code,
+			Message:    w.Message,
+			Suggestion: w.Suggestion,
+		}
+		if w.Stream >= 0 {
+			stream := w.Stream
+			warning.Stream = &stream
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+func TestWatermark(t *testing.T) {
+	video := &models.Video{Watermarks: []models.ImageFile{{ID: "logo", FilePath: "/data/videos/logo.png"}}}
+
+	mark, err := watermark(video, models.WatermarkOptions{Image: "logo", Position: "top-right", Opacity: 0.8})
+	if err != nil || mark.File != "/data/videos/logo.png" || mark.Position != "top-right" || mark.Opacity != 0.8 {
+		t.Errorf("watermark() = %+v, %v", mark, err)
+	}
+	if _, err := watermark(video, models.WatermarkOptions{Image: "missing"}); err == nil {
+		t.Error("expected an unknown image to fail")
+	}
+
+	if hasVideoStream([]models.Stream{{CodecType: "audio"}}) || !hasVideoStream([]models.Stream{{CodecType: "audio"}, {CodecType: "video"}}) {
+		t.Error("hasVideoStream() doesn't find exactly the video streams")
+	}
+}
+
+func TestValidateWatermark(t *testing.T) {
+	request := models.ExportRequest{Watermark: &models.WatermarkOptions{Image: "logo"}}
+	if err := validateExportRequest(request); err != nil || cutMode(request) != CutModeReencode {
+		t.Errorf("watermark: err = %v, cut mode = %s, want re-encode", err, cutMode(request))
+	}
+
+	for _, request := range []models.ExportRequest{
+		{Watermark: &models.WatermarkOptions{}},
+		{Watermark: &models.WatermarkOptions{Image: "logo", Position: "middle"}},
+		{Watermark: &models.WatermarkOptions{Image: "logo", Opacity: 1.5}},
+		{Watermark: &models.WatermarkOptions{Image: "logo"}, CutMode: CutModeSmart},
+		{Watermark: &models.WatermarkOptions{Image: "logo"}, Crossfade: 1},
+	} {
+		if err := validateExportRequest(request); err == nil {
+			t.Errorf("%+v: expected an error", *request.Watermark)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ytdlpReleaseURL serves the assets of the latest yt-dlp release
+const ytdlpReleaseURL = "https://github.com/yt-dlp/yt-dlp/releases/latest/download/"
+
+const (
+	ytdlpVersionTimeout = 15 * time.Second
+	ytdlpUpdateTimeout  = 5 * time.Minute
+)
+
+// YtdlpInfo is the yt-dlp downloads run
+type YtdlpInfo struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Managed bool   `json:"managed"` // Installed into the storage directory by an update
+}
+
+// YtdlpUpdate is the outcome of updating yt-dlp
+type YtdlpUpdate struct {
+	Previous string `json:"previous"`
+	Version  string `json:"version"`
+	Method   string `json:"method"` // "self-update" (yt-dlp -U) or "download" (latest release into the storage directory)
+	Output   string `json:"output,omitempty"`
+}
+
+// ytdlpReleaseAsset returns the standalone yt-dlp release binary for a platform
+func ytdlpReleaseAsset(goos, goarch string) (string, bool) {
+	switch {
+	case goos == "linux" && goarch == "amd64":
+		return "yt-dlp_linux", true
+	case goos == "linux" && goarch == "arm64":
+		return "yt-dlp_linux_aarch64", true
+	case goos == "darwin":
+		return "yt-dlp_macos", true
+	case goos == "windows" && goarch == "amd64":
+		return "yt-dlp.exe", true
+	}
+	return "", false
+}
+
+// releaseChecksum finds an asset's SHA-256 in a release's SHA2-256SUMS file
+func releaseChecksum(sums []byte, asset string) (string, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
+
+// managedYtdlpPath is where an update installs yt-dlp when it can't update itself
+func (s *DownloadService) managedYtdlpPath() string {
+	name := "yt-dlp"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(s.storage.BinDir(), name)
+}
+
+// ytdlpPath returns the yt-dlp downloads run: the one an update installed, or else
+// the configured ytdlp.path
+func (s *DownloadService) ytdlpPath() string {
+	if _, err := os.Stat(s.managedYtdlpPath()); err == nil {
+		return s.managedYtdlpPath()
+	}
+	return s.config.YtDlp.Path
+}
+
+// YtdlpVersion reports the yt-dlp downloads run and its version
+func (s *DownloadService) YtdlpVersion(ctx context.Context) (*YtdlpInfo, error) {
+	path := s.ytdlpPath()
+	version, err := ytdlpVersion(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &YtdlpInfo{Path: path, Version: version, Managed: path == s.managedYtdlpPath()}, nil
+}
+
+func ytdlpVersion(ctx context.Context, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ytdlpVersionTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s --version: %w", path, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// UpdateYtdlp updates yt-dlp, since sites changing break its extractors all the
+// time. A standalone binary updates itself with -U; when that fails, e.g. for pip or
+// distribution packages, the latest release binary is downloaded into the storage
+// directory and used from then on.
+func (s *DownloadService) UpdateYtdlp(ctx context.Context) (*YtdlpUpdate, error) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, ytdlpUpdateTimeout)
+	defer cancel()
+
+	path := s.ytdlpPath()
+	previous, _ := ytdlpVersion(ctx, path)
+
+	output, err := exec.CommandContext(ctx, path, "-U").CombinedOutput()
+	if err == nil {
+		version, err := ytdlpVersion(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		s.logger.Info("Updated yt-dlp", zap.String("previous", previous), zap.String("version", version))
+		return &YtdlpUpdate{Previous: previous, Version: version, Method: "self-update", Output: strings.TrimSpace(string(output))}, nil
+	}
+	s.logger.Info("yt-dlp can't update itself, downloading the latest release",
+		zap.String("path", path),
+		zap.String("output", strings.TrimSpace(string(output))),
+	)
+
+	if err := s.installYtdlpRelease(ctx); err != nil {
+		return nil, err
+	}
+	version, err := ytdlpVersion(ctx, s.managedYtdlpPath())
+	if err != nil {
+		os.Remove(s.managedYtdlpPath())
+		return nil, fmt.Errorf("downloaded yt-dlp doesn't run: %w", err)
+	}
+	s.logger.Info("Installed yt-dlp release", zap.String("previous", previous), zap.String("version", version))
+	return &YtdlpUpdate{Previous: previous, Version: version, Method: "download"}, nil
+}
+
+// installYtdlpRelease downloads the latest yt-dlp release binary for this platform,
+// checks it against the release's checksums and installs it into the storage directory
+func (s *DownloadService) installYtdlpRelease(ctx context.Context) error {
+	asset, ok := ytdlpReleaseAsset(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return fmt.Errorf("no yt-dlp release binary for %s/%s, update it with your package manager", runtime.GOOS, runtime.GOARCH)
+	}
+
+	sums, err := fetchRelease(ctx, ytdlpReleaseURL+"SHA2-256SUMS", 1<<20)
+	if err != nil {
+		return err
+	}
+	want, ok := releaseChecksum(sums, asset)
+	if !ok {
+		return fmt.Errorf("yt-dlp release has no checksum for %s", asset)
+	}
+
+	binary, err := fetchRelease(ctx, ytdlpReleaseURL+asset, 100<<20)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(binary)
+	if got := hex.EncodeToString(sum[:]); got != want {
+		return fmt.Errorf("downloaded %s has SHA-256 %s, the release lists %s", asset, got, want)
+	}
+
+	if err := os.MkdirAll(s.storage.BinDir(), 0755); err != nil {
+		return err
+	}
+	tmp := s.managedYtdlpPath() + ".new"
+	if err := os.WriteFile(tmp, binary, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.managedYtdlpPath()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// fetchRelease downloads a release asset of at most limit bytes
+func fetchRelease(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: HTTP %d", url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
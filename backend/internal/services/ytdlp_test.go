@@ -0,0 +1,40 @@
+package services
+
+import "testing"
+
+func TestYtdlpReleaseAsset(t *testing.T) {
+	tests := []struct {
+		goos, goarch string
+		want         string
+		ok           bool
+	}{
+		{"linux", "amd64", "yt-dlp_linux", true},
+		{"linux", "arm64", "yt-dlp_linux_aarch64", true},
+		{"darwin", "arm64", "yt-dlp_macos", true},
+		{"windows", "amd64", "yt-dlp.exe", true},
+		{"freebsd", "amd64", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ytdlpReleaseAsset(tt.goos, tt.goarch)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ytdlpReleaseAsset(%s, %s) = %q, %v, want %q, %v", tt.goos, tt.goarch, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReleaseChecksum(t *testing.T) {
+	sums := []byte("AB12  yt-dlp\ncd34  yt-dlp_linux\nef56 *yt-dlp.exe\n")
+
+	if got, ok := releaseChecksum(sums, "yt-dlp_linux"); !ok || got != "cd34" {
+		t.Errorf("releaseChecksum(yt-dlp_linux) = %q, %v", got, ok)
+	}
+	if got, ok := releaseChecksum(sums, "yt-dlp"); !ok || got != "ab12" {
+		t.Errorf("releaseChecksum(yt-dlp) = %q, %v", got, ok)
+	}
+	if got, ok := releaseChecksum(sums, "yt-dlp.exe"); !ok || got != "ef56" {
+		t.Errorf("releaseChecksum(yt-dlp.exe) = %q, %v", got, ok)
+	}
+	if _, ok := releaseChecksum(sums, "yt-dlp_macos"); ok {
+		t.Error("releaseChecksum found a missing asset")
+	}
+}
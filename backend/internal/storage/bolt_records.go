@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// Metadata backends of storage.metadata
+const (
+	MetadataJSON = "json" // A JSON file per record
+	MetadataBolt = "bolt" // An embedded bbolt database
+)
+
+// videoPathsBucket indexes video IDs by file path
+var videoPathsBucket = []byte("video_paths")
+
+// boltRecordStore keeps records in a bbolt database, a bucket per kind. Every write
+// is a transaction that also updates the indexes.
+type boltRecordStore struct {
+	db *bbolt.DB
+}
+
+// recordBucket returns the bucket name of a kind, e.g. "videos"
+func recordBucket(kind recordKind) []byte {
+	return []byte(kind + "s")
+}
+
+// openBoltRecordStore opens or creates the database at path
+func openBoltRecordStore(path string) (*boltRecordStore, error) {
+	// Another server using the same base path holds the lock
+	db, err := bbolt.Open(path, 0644, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metadata database: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, kind := range recordKinds {
+			if _, err := tx.CreateBucketIfNotExists(recordBucket(kind)); err != nil {
+				return err
+			}
+		}
+		_, err := tx.CreateBucketIfNotExists(videoPathsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create metadata buckets: %w", err)
+	}
+	return &boltRecordStore{db: db}, nil
+}
+
+func (s *boltRecordStore) Get(kind recordKind, id string) ([]byte, error) {
+	var data []byte
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(recordBucket(kind)).Get([]byte(id))
+		if value == nil {
+			return errRecordNotFound
+		}
+		// Values are only valid during the transaction
+		data = append([]byte(nil), value...)
+		return nil
+	})
+	return data, err
+}
+
+func (s *boltRecordStore) Put(kind recordKind, id string, data []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return putRecord(tx, kind, id, data)
+	})
+}
+
+// putRecord writes a record and updates the path index of videos
+func putRecord(tx *bbolt.Tx, kind recordKind, id string, data []byte) error {
+	bucket := tx.Bucket(recordBucket(kind))
+	if kind == recordVideo {
+		paths := tx.Bucket(videoPathsBucket)
+		if old := videoFilePath(bucket.Get([]byte(id))); old != "" {
+			if err := paths.Delete([]byte(old)); err != nil {
+				return err
+			}
+		}
+		if path := videoFilePath(data); path != "" {
+			if err := paths.Put([]byte(path), []byte(id)); err != nil {
+				return err
+			}
+		}
+	}
+	return bucket.Put([]byte(id), data)
+}
+
+func (s *boltRecordStore) Update(kind recordKind, id string, modify func(data []byte) ([]byte, error)) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		// The current value is only valid during the transaction
+		var data []byte
+		if current := tx.Bucket(recordBucket(kind)).Get([]byte(id)); current != nil {
+			data = append([]byte(nil), current...)
+		}
+		data, err := modify(data)
+		if err != nil {
+			return err
+		}
+		return putRecord(tx, kind, id, data)
+	})
+}
+
+func (s *boltRecordStore) Delete(kind recordKind, id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(recordBucket(kind))
+		if kind == recordVideo {
+			if path := videoFilePath(bucket.Get([]byte(id))); path != "" {
+				if err := tx.Bucket(videoPathsBucket).Delete([]byte(path)); err != nil {
+					return err
+				}
+			}
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (s *boltRecordStore) List(kind recordKind) ([]record, error) {
+	var records []record
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(recordBucket(kind)).ForEach(func(key, value []byte) error {
+			records = append(records, record{ID: string(key), Data: append([]byte(nil), value...)})
+			return nil
+		})
+	})
+	return records, err
+}
+
+func (s *boltRecordStore) FindVideo(path string) (string, error) {
+	var id string
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		id = string(tx.Bucket(videoPathsBucket).Get([]byte(path)))
+		return nil
+	})
+	return id, err
+}
+
+func (s *boltRecordStore) Close() error {
+	return s.db.Close()
+}
+
+// importRecords writes records of several kinds in one transaction. Records already
+// in the database are kept, they are newer than a file imported before.
+func (s *boltRecordStore) importRecords(records map[recordKind][]record) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		for kind, list := range records {
+			for _, rec := range list {
+				if tx.Bucket(recordBucket(kind)).Get([]byte(rec.ID)) != nil {
+					continue
+				}
+				if err := putRecord(tx, kind, rec.ID, rec.Data); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// videoFilePath returns the file path of a stored video record
+func videoFilePath(data []byte) string {
+	if data == nil {
+		return ""
+	}
+	var video struct {
+		FilePath string `json:"file_path"`
+	}
+	json.Unmarshal(data, &video)
+	return video.FilePath
+}
+
+// MetadataDBPath returns the path of the metadata database
+func (m *Manager) MetadataDBPath() string {
+	return filepath.Join(m.basePath, "metadata.db")
+}
+
+// JSONBackupDir returns where JSON records are kept after moving into the database
+func (m *Manager) JSONBackupDir() string {
+	return filepath.Join(m.basePath, "json-backup")
+}
+
+// OpenMetadata selects where video, download and project records are kept: "json"
+// keeps a file per record, "bolt" a database at base_path/metadata.db. JSON records
+// found next to the database, from before switching to it, are imported once and
+// moved to base_path/json-backup. Call it after Initialize, before any other use.
+func (m *Manager) OpenMetadata(backend string) error {
+	if backend != MetadataBolt {
+		return nil
+	}
+
+	store, err := openBoltRecordStore(m.MetadataDBPath())
+	if err != nil {
+		return err
+	}
+	m.records = store
+
+	imported, err := m.importRecordFiles(m.JSONBackupDir())
+	if err != nil {
+		return fmt.Errorf("failed to migrate JSON records: %w", err)
+	}
+	if imported > 0 {
+		m.logger.Info("Migrated JSON records into the metadata database",
+			zap.Int("records", imported),
+			zap.String("backup", m.JSONBackupDir()),
+		)
+	}
+	return nil
+}
+
+// importRecordFiles moves JSON records from the data directories into the database
+// in one transaction, then moves the files to backupDir, or deletes them when it is
+// empty. It returns the number of records imported. Files that couldn't be moved,
+// or were left by a run interrupted after the transaction, are kept and only fill
+// in records missing from the database on the next start.
+func (m *Manager) importRecordFiles(backupDir string) (int, error) {
+	store, ok := m.records.(*boltRecordStore)
+	if !ok {
+		return 0, nil
+	}
+
+	files := &fileRecordStore{m: m}
+	records := make(map[recordKind][]record)
+	count := 0
+	for _, kind := range recordKinds {
+		list, err := files.List(kind)
+		if err != nil {
+			return 0, err
+		}
+		records[kind] = list
+		count += len(list)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if err := store.importRecords(records); err != nil {
+		return 0, err
+	}
+
+	for kind, list := range records {
+		for _, rec := range list {
+			path := m.recordPath(kind, rec.ID)
+			if backupDir == "" {
+				os.Remove(path)
+				continue
+			}
+			rel, _ := filepath.Rel(m.basePath, path)
+			target := filepath.Join(backupDir, rel)
+			err := os.MkdirAll(filepath.Dir(target), 0755)
+			if err == nil {
+				err = os.Rename(path, target)
+			}
+			// Without a backup the file stays, the next start tries again
+			if err != nil {
+				m.logger.Warn("Failed to back up migrated record, keeping it", zap.String("path", path), zap.Error(err))
+			}
+		}
+	}
+	return count, nil
+}
+
+// Close releases the metadata database
+func (m *Manager) Close() error {
+	return m.records.Close()
+}
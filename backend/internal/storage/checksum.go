@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumProgress is called with the fraction of a file hashed so far (0.0 to 1.0)
+type ChecksumProgress func(progress float64)
+
+// progressReader reports how much of a file has been read
+type progressReader struct {
+	reader     io.Reader
+	read       int64
+	total      int64
+	onProgress ChecksumProgress
+	reported   float64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.onProgress != nil && r.total > 0 {
+		// Report in 1% steps
+		if progress := float64(r.read) / float64(r.total); progress-r.reported >= 0.01 || progress >= 1 {
+			r.reported = progress
+			r.onProgress(progress)
+		}
+	}
+	return n, err
+}
+
+// ComputeSHA256 returns the hex SHA-256 of a whole file. Unlike ComputeFileHash,
+// which samples large files, every byte is hashed, so it detects any corruption.
+func (m *Manager) ComputeSHA256(path string, onProgress ChecksumProgress) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for checksum: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file for checksum: %w", err)
+	}
+
+	hash := sha256.New()
+	reader := &progressReader{reader: file, total: info.Size(), onProgress: onProgress}
+	if _, err := io.CopyBuffer(hash, reader, make([]byte, 1024*1024)); err != nil {
+		return "", fmt.Errorf("failed to read file for checksum: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ChecksumSidecarPath returns the path of the checksum file stored next to a file
+func ChecksumSidecarPath(path string) string {
+	return path + ".sha256"
+}
+
+// SaveChecksumSidecar writes a checksum next to a file, in the format of sha256sum
+// so it can also be checked with `sha256sum -c`
+func (m *Manager) SaveChecksumSidecar(path, sum string) error {
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	if err := os.WriteFile(ChecksumSidecarPath(path), []byte(line), 0644); err != nil {
+		return fmt.Errorf("failed to write checksum file: %w", err)
+	}
+	return m.StoreFile(ChecksumSidecarPath(path))
+}
+
+// ReadChecksumSidecar returns the checksum stored next to a file
+func (m *Manager) ReadChecksumSidecar(path string) (string, error) {
+	m.FetchFile(ChecksumSidecarPath(path))
+	data, err := os.ReadFile(ChecksumSidecarPath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no checksum stored for %s", filepath.Base(path))
+		}
+		return "", fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file for %s", filepath.Base(path))
+	}
+	return strings.ToLower(fields[0]), nil
+}
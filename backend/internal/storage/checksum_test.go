@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestChecksumSidecar(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+
+	path := filepath.Join(t.TempDir(), "cut.mp4")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var progress float64
+	sum, err := m.ComputeSHA256(path, func(p float64) { progress = p })
+	if err != nil {
+		t.Fatalf("ComputeSHA256() error = %v", err)
+	}
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; sum != want {
+		t.Errorf("ComputeSHA256() = %s, want %s", sum, want)
+	}
+	if progress != 1 {
+		t.Errorf("final progress = %v, want 1", progress)
+	}
+
+	if err := m.SaveChecksumSidecar(path, sum); err != nil {
+		t.Fatalf("SaveChecksumSidecar() error = %v", err)
+	}
+	data, _ := os.ReadFile(path + ".sha256")
+	if string(data) != sum+"  cut.mp4\n" {
+		t.Errorf("sidecar = %q, want sha256sum format", data)
+	}
+	if stored, err := m.ReadChecksumSidecar(path); err != nil || stored != sum {
+		t.Errorf("ReadChecksumSidecar() = %s, %v, want %s", stored, err, sum)
+	}
+
+	if _, err := m.ReadChecksumSidecar(filepath.Join(t.TempDir(), "missing.mp4")); err == nil {
+		t.Error("ReadChecksumSidecar() of a file without checksum: want error")
+	}
+}
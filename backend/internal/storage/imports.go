@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// importsFile returns the path of the list of files the watch folder imported. It
+// lives outside the data directories, so deleting or clearing videos doesn't make the
+// watch folder import their files again.
+func (m *Manager) importsFile() string {
+	return filepath.Join(m.basePath, "watch_imports.json")
+}
+
+// WatchImports returns the files the watch folder already imported
+func (m *Manager) WatchImports() (map[string]bool, error) {
+	m.importsMu.Lock()
+	defer m.importsMu.Unlock()
+	return m.loadWatchImports()
+}
+
+// AddWatchImport records that the watch folder imported a file
+func (m *Manager) AddWatchImport(path string) error {
+	m.importsMu.Lock()
+	defer m.importsMu.Unlock()
+
+	imported, err := m.loadWatchImports()
+	if err != nil {
+		return err
+	}
+	imported[path] = true
+
+	paths := make([]string, 0, len(imported))
+	for p := range imported {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	data, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.importsFile(), data, 0644); err != nil {
+		return fmt.Errorf("failed to save watch imports: %w", err)
+	}
+	return nil
+}
+
+// loadWatchImports reads the imported files. Callers hold importsMu.
+func (m *Manager) loadWatchImports() (map[string]bool, error) {
+	imported := make(map[string]bool)
+
+	data, err := os.ReadFile(m.importsFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return imported, nil
+		}
+		return nil, fmt.Errorf("failed to read watch imports: %w", err)
+	}
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return nil, fmt.Errorf("failed to parse watch imports: %w", err)
+	}
+	for _, path := range paths {
+		imported[path] = true
+	}
+	return imported, nil
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestWatchImports(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+
+	imported, err := m.WatchImports()
+	if err != nil || len(imported) != 0 {
+		t.Fatalf("WatchImports() before any import = %v, %v", imported, err)
+	}
+
+	for _, path := range []string{"/mnt/nas/a.mp4", "/mnt/nas/b.mkv", "/mnt/nas/a.mp4"} {
+		if err := m.AddWatchImport(path); err != nil {
+			t.Fatalf("AddWatchImport(%s) = %v", path, err)
+		}
+	}
+
+	imported, err = m.WatchImports()
+	if err != nil {
+		t.Fatalf("WatchImports() = %v", err)
+	}
+	if len(imported) != 2 || !imported["/mnt/nas/a.mp4"] || !imported["/mnt/nas/b.mkv"] {
+		t.Errorf("WatchImports() = %v, want both files", imported)
+	}
+}
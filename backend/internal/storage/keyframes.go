@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// GetKeyframeIndexPath returns the path of a video's keyframe index
+func (m *Manager) GetKeyframeIndexPath(videoID string) string {
+	return filepath.Join(m.KeyframesDir(), videoID+".json")
+}
+
+// SaveKeyframeIndex stores the keyframe index of a video
+func (m *Manager) SaveKeyframeIndex(index *models.KeyframeIndex) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal keyframe index: %w", err)
+	}
+
+	if err := os.MkdirAll(m.KeyframesDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create keyframes directory: %w", err)
+	}
+	if err := os.WriteFile(m.GetKeyframeIndexPath(index.VideoID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write keyframe index: %w", err)
+	}
+	return nil
+}
+
+// GetKeyframeIndex returns the keyframe index of a video, or nil if there is none or
+// it was built for a different file hash
+func (m *Manager) GetKeyframeIndex(videoID, fileHash string) (*models.KeyframeIndex, error) {
+	data, err := os.ReadFile(m.GetKeyframeIndexPath(videoID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read keyframe index: %w", err)
+	}
+
+	var index models.KeyframeIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		// A corrupt index is not fatal, it is simply rebuilt
+		m.logger.Warn("Discarding corrupt keyframe index", zap.String("videoId", videoID), zap.Error(err))
+		return nil, nil
+	}
+	if index.FileHash != fileHash {
+		return nil, nil
+	}
+	return &index, nil
+}
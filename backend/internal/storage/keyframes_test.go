@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestKeyframeIndex(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+
+	if index, err := m.GetKeyframeIndex("video1", "hash"); err != nil || index != nil {
+		t.Fatalf("GetKeyframeIndex() without index = %v, %v", index, err)
+	}
+
+	saved := &models.KeyframeIndex{VideoID: "video1", FileHash: "hash", Keyframes: []float64{0, 2.5, 5}}
+	if err := m.SaveKeyframeIndex(saved); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := m.GetKeyframeIndex("video1", "hash")
+	if err != nil || index == nil || !reflect.DeepEqual(index.Keyframes, saved.Keyframes) {
+		t.Fatalf("GetKeyframeIndex() = %+v, %v", index, err)
+	}
+
+	// A changed file invalidates the index
+	if index, err := m.GetKeyframeIndex("video1", "other"); err != nil || index != nil {
+		t.Errorf("GetKeyframeIndex() for another hash = %+v, %v", index, err)
+	}
+
+	// So does a corrupt one
+	if err := os.WriteFile(m.GetKeyframeIndexPath("video1"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if index, err := m.GetKeyframeIndex("video1", "hash"); err != nil || index != nil {
+		t.Errorf("GetKeyframeIndex() of corrupt index = %+v, %v", index, err)
+	}
+}
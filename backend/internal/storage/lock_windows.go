@@ -0,0 +1,15 @@
+//go:build windows
+
+package storage
+
+import "os"
+
+// lockFile is a no-op on Windows, where only the writers of this server are ordered
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op on Windows
+func unlockFile(file *os.File) error {
+	return nil
+}
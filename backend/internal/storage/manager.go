@@ -1,12 +1,17 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,27 +23,51 @@ import (
 type Manager struct {
 	basePath string
 	logger   *zap.Logger
+	cacheMu  sync.Mutex // Guards analysis cache files
+	seqMu    sync.Mutex // Guards the video number counters
+
+	trashMu        sync.Mutex // Guards the trash directory
+	trashRetention time.Duration
+
+	usageMu sync.Mutex // Guards the usage counters
+
+	importsMu sync.Mutex // Guards the list of watch folder imports
+
+	records       recordStore // Videos, downloads and projects
+	recordLocksMu sync.Mutex
+	recordLocks   map[string]*recordLock // Mutex of each record the file store is writing, by kind and ID
+	media         Store                  // Uploads, downloads, outputs and waveforms
 }
 
 // NewManager creates a new storage manager
 func NewManager(basePath string, logger *zap.Logger) *Manager {
-	return &Manager{
-		basePath: basePath,
-		logger:   logger,
+	m := &Manager{
+		basePath:    basePath,
+		logger:      logger,
+		recordLocks: make(map[string]*recordLock),
 	}
+	m.records = &fileRecordStore{m: m}
+	m.media = &localStore{root: basePath}
+	return m
 }
 
 // Initialize creates the storage directory structure
 func (m *Manager) Initialize() error {
 	dirs := []string{
 		m.UploadsDir(),
+		m.SubtitlesDir(),
 		m.ProjectsDir(),
 		m.OutputsDir(),
 		m.TempDir(),
 		m.DownloadsDir(),
 		m.VideosDir(),
 		m.WaveformsDir(),
+		m.KeyframesDir(),
+		m.ThumbnailsDir(),
 		m.ScreenshotsDir(),
+		m.AnalysisDir(),
+		m.CookiesDir(),
+		m.PostersDir(),
 	}
 
 	for _, dir := range dirs {
@@ -56,6 +85,11 @@ func (m *Manager) UploadsDir() string {
 	return filepath.Join(m.basePath, "uploads")
 }
 
+// SubtitlesDir returns the directory of sidecar subtitle files attached to videos
+func (m *Manager) SubtitlesDir() string {
+	return filepath.Join(m.basePath, "subtitles")
+}
+
 // ProjectsDir returns the projects directory path
 func (m *Manager) ProjectsDir() string {
 	return filepath.Join(m.basePath, "projects")
@@ -86,11 +120,57 @@ func (m *Manager) WaveformsDir() string {
 	return filepath.Join(m.basePath, "waveforms")
 }
 
+// KeyframesDir returns the keyframe index cache directory path
+func (m *Manager) KeyframesDir() string {
+	return filepath.Join(m.basePath, "keyframes")
+}
+
+// ThumbnailsDir returns the generated thumbnails (storyboards, filmstrips) directory path
+func (m *Manager) ThumbnailsDir() string {
+	return filepath.Join(m.basePath, "thumbnails")
+}
+
+// GetVideoThumbnailsDir returns the directory holding a video's generated thumbnails
+func (m *Manager) GetVideoThumbnailsDir(videoID string) string {
+	return filepath.Join(m.ThumbnailsDir(), videoID)
+}
+
+// DeleteVideoThumbnails removes a video's generated thumbnails and storyboard, e.g.
+// after the picture changed. They are regenerated on the next request.
+func (m *Manager) DeleteVideoThumbnails(videoID string) error {
+	return os.RemoveAll(m.GetVideoThumbnailsDir(videoID))
+}
+
+// PostersDir returns the directory of thumbnails downloaded from the sites of videos
+func (m *Manager) PostersDir() string {
+	return filepath.Join(m.basePath, "posters")
+}
+
 // ScreenshotsDir returns the screenshots directory path
 func (m *Manager) ScreenshotsDir() string {
 	return filepath.Join(m.basePath, "screenshots")
 }
 
+// AnalysisDir returns the analysis cache directory path
+func (m *Manager) AnalysisDir() string {
+	return filepath.Join(m.basePath, "analysis")
+}
+
+// CookiesDir returns the directory of encrypted download cookies
+func (m *Manager) CookiesDir() string {
+	return filepath.Join(m.basePath, "cookies")
+}
+
+// BinDir returns the directory of tools the server installs itself, e.g. an updated yt-dlp
+func (m *Manager) BinDir() string {
+	return filepath.Join(m.basePath, "bin")
+}
+
+// GetCookieJarPath returns the path of an encrypted cookie jar
+func (m *Manager) GetCookieJarPath(jarID string) string {
+	return filepath.Join(m.CookiesDir(), jarID+".enc")
+}
+
 // GetScreenshotPath returns the full path for a screenshot file
 func (m *Manager) GetScreenshotPath(filename string) string {
 	return filepath.Join(m.ScreenshotsDir(), filename)
@@ -101,14 +181,37 @@ func (m *Manager) GetWaveformPath(filename string) string {
 	return filepath.Join(m.WaveformsDir(), filename)
 }
 
+// GetSubtitlePath returns the full path for a sidecar subtitle file
+func (m *Manager) GetSubtitlePath(filename string) string {
+	return filepath.Join(m.SubtitlesDir(), filename)
+}
+
 // GetVideoPath returns the full path for a video file
 func (m *Manager) GetVideoPath(filename string) string {
 	return filepath.Join(m.UploadsDir(), filename)
 }
 
-// GetNextVideoNumber returns the next sequential video number and increments the counter
+// GetNextVideoNumber returns the next number of the global video sequence
 func (m *Manager) GetNextVideoNumber() int {
-	counterFile := filepath.Join(m.basePath, "video_counter.txt")
+	return m.NextSequence("")
+}
+
+// sequenceFile returns the counter file of a sequence scope. The global sequence
+// keeps its original location.
+func (m *Manager) sequenceFile(scope string) string {
+	if scope == "" {
+		return filepath.Join(m.basePath, "video_counter.txt")
+	}
+	return filepath.Join(m.basePath, "counters", scope+".txt")
+}
+
+// NextSequence returns the next number of a sequence and increments its counter.
+// Each scope (e.g. a session) counts from 1 independently.
+func (m *Manager) NextSequence(scope string) int {
+	m.seqMu.Lock()
+	defer m.seqMu.Unlock()
+
+	counterFile := m.sequenceFile(scope)
 
 	// Read current counter
 	data, err := os.ReadFile(counterFile)
@@ -121,46 +224,83 @@ func (m *Manager) GetNextVideoNumber() int {
 
 	// Increment and save new counter
 	nextNum := currentNum + 1
+	os.MkdirAll(filepath.Dir(counterFile), 0755)
 	os.WriteFile(counterFile, []byte(strconv.Itoa(nextNum)), 0644)
 
-	m.logger.Info("Generated video number", zap.Int("number", currentNum))
+	m.logger.Info("Generated video number", zap.String("scope", scope), zap.Int("number", currentNum))
 	return currentNum
 }
 
-// ResetVideoCounter resets the video counter back to 1
+// ResetVideoCounter resets the global and per-session video counters back to 1
 func (m *Manager) ResetVideoCounter() error {
-	counterFile := filepath.Join(m.basePath, "video_counter.txt")
-	if err := os.WriteFile(counterFile, []byte("1"), 0644); err != nil {
+	m.seqMu.Lock()
+	defer m.seqMu.Unlock()
+
+	if err := os.WriteFile(m.sequenceFile(""), []byte("1"), 0644); err != nil {
 		return fmt.Errorf("failed to reset counter: %w", err)
 	}
+	if err := os.RemoveAll(filepath.Join(m.basePath, "counters")); err != nil {
+		return fmt.Errorf("failed to reset session counters: %w", err)
+	}
 	m.logger.Info("Reset video counter to 1")
 	return nil
 }
 
-// GetProjectPath returns the full path for a project file
-func (m *Manager) GetProjectPath(projectID string) string {
-	return filepath.Join(m.ProjectsDir(), projectID+".llc")
-}
+// sessionCounterRetention is how long the counter of a session without downloads
+// is kept. A session returning later counts from 1 again, names already taken get
+// a suffix.
+const sessionCounterRetention = 30 * 24 * time.Hour
 
-// GetProject loads a project from disk
-func (m *Manager) GetProject(projectID string) (*models.Project, error) {
-	path := m.GetProjectPath(projectID)
-	data, err := os.ReadFile(path)
+// PurgeSessionCounters deletes the counters of sessions that haven't downloaded
+// anything for sessionCounterRetention and returns how many were deleted
+func (m *Manager) PurgeSessionCounters() (int, error) {
+	m.seqMu.Lock()
+	defer m.seqMu.Unlock()
+
+	dir := filepath.Join(m.basePath, "counters")
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("project not found: %s", projectID)
+			return 0, nil
 		}
-		return nil, fmt.Errorf("failed to read project: %w", err)
+		return 0, fmt.Errorf("failed to list session counters: %w", err)
 	}
 
-	var project models.Project
-	if err := json.Unmarshal(data, &project); err != nil {
-		return nil, fmt.Errorf("failed to parse project: %w", err)
+	cutoff := time.Now().Add(-sessionCounterRetention)
+	purged := 0
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			m.logger.Warn("Failed to delete session counter", zap.String("file", entry.Name()), zap.Error(err))
+			continue
+		}
+		purged++
 	}
+	return purged, nil
+}
 
+// GetProjectPath returns the full path for a project file
+func (m *Manager) GetProjectPath(projectID string) string {
+	return filepath.Join(m.ProjectsDir(), projectID+".llc")
+}
+
+// GetProject loads a project
+func (m *Manager) GetProject(projectID string) (*models.Project, error) {
+	var project models.Project
+	if err := m.loadRecord(recordProject, projectID, &project); err != nil {
+		return nil, err
+	}
 	return &project, nil
 }
 
+// SaveProject stores a project
+func (m *Manager) SaveProject(project *models.Project) error {
+	return m.saveRecord(recordProject, project.ID, project)
+}
+
 // GetOutputPath returns the full path for an output file
 func (m *Manager) GetOutputPath(filename string) string {
 	return filepath.Join(m.OutputsDir(), filename)
@@ -171,11 +311,280 @@ func (m *Manager) GetTempPath(filename string) string {
 	return filepath.Join(m.TempDir(), filename)
 }
 
-// DeleteFile removes a file
+// WorkspacesDir returns the directory holding per-operation export workspaces
+func (m *Manager) WorkspacesDir() string {
+	return filepath.Join(m.TempDir(), "workspaces")
+}
+
+// GetWorkspacePath returns the workspace directory of an export operation
+func (m *Manager) GetWorkspacePath(operationID string) string {
+	return filepath.Join(m.WorkspacesDir(), operationID)
+}
+
+// CreateWorkspace creates the workspace directory of an export operation
+func (m *Manager) CreateWorkspace(operationID string) (string, error) {
+	path := m.GetWorkspacePath(operationID)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to create workspace: %w", err)
+	}
+	return path, nil
+}
+
+// DeleteWorkspace removes the workspace of an export operation with all its artifacts
+func (m *Manager) DeleteWorkspace(operationID string) error {
+	if err := os.RemoveAll(m.GetWorkspacePath(operationID)); err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+	return nil
+}
+
+// SaveExportJob stores the parameters of an export in its workspace so it can be retried
+func (m *Manager) SaveExportJob(job *models.ExportJob) error {
+	dir, err := m.CreateWorkspace(job.OperationID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal export job: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "job.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write export job: %w", err)
+	}
+
+	return nil
+}
+
+// GetExportJob retrieves the export job stored in an operation's workspace
+func (m *Manager) GetExportJob(operationID string) (*models.ExportJob, error) {
+	data, err := os.ReadFile(filepath.Join(m.GetWorkspacePath(operationID), "job.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("export job %w: %s", ErrNotFound, operationID)
+		}
+		return nil, fmt.Errorf("failed to read export job: %w", err)
+	}
+
+	var job models.ExportJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse export job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// ListExportJobs returns the export jobs of all workspaces
+func (m *Manager) ListExportJobs() ([]*models.ExportJob, error) {
+	entries, err := os.ReadDir(m.WorkspacesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read workspaces directory: %w", err)
+	}
+
+	var jobs []*models.ExportJob
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		job, err := m.GetExportJob(entry.Name())
+		if err != nil {
+			m.logger.Warn("Failed to load export job", zap.String("operationId", entry.Name()), zap.Error(err))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// ExpireFailedWorkspaces deletes the workspaces of exports that failed more than
+// retention ago, so their completed pieces don't pile up. Expired exports can't be
+// retried anymore. It returns their operation IDs.
+func (m *Manager) ExpireFailedWorkspaces(retention time.Duration) ([]string, error) {
+	jobs, err := m.ListExportJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []string
+	cutoff := time.Now().Add(-retention)
+	for _, job := range jobs {
+		if job.Status != models.OperationStatusFailed {
+			continue
+		}
+		// Jobs that failed before the time was recorded expire by their creation
+		failedAt := job.CreatedAt
+		if job.FailedAt != nil {
+			failedAt = *job.FailedAt
+		}
+		if failedAt.After(cutoff) {
+			continue
+		}
+		if err := m.DeleteWorkspace(job.OperationID); err != nil {
+			m.logger.Warn("Failed to delete expired workspace", zap.String("operationId", job.OperationID), zap.Error(err))
+			continue
+		}
+		expired = append(expired, job.OperationID)
+	}
+	return expired, nil
+}
+
+// CleanupInterrupted removes what interrupted work leaves behind: temp files, partial
+// workspace pieces, workspaces without a job, half written records, concat lists
+// next to outputs, yt-dlp partial downloads and downloaded files no video refers to. Partial files and
+// cookie jars of downloads that can still be resumed are kept, those of downloads
+// that failed more than failedRetention ago (0 keeps them) are removed. It must
+// only run while no export or download is in progress, i.e. on startup. It returns
+// the number of removed files.
+func (m *Manager) CleanupInterrupted(failedRetention time.Duration) (int, error) {
+	var orphans []string
+
+	// Temp files, except export workspaces which hold completed pieces for retries
+	if entries, err := os.ReadDir(m.TempDir()); err == nil {
+		for _, entry := range entries {
+			if entry.Name() != filepath.Base(m.WorkspacesDir()) {
+				orphans = append(orphans, filepath.Join(m.TempDir(), entry.Name()))
+			}
+		}
+	}
+
+	if entries, err := os.ReadDir(m.WorkspacesDir()); err == nil {
+		for _, entry := range entries {
+			workspace := filepath.Join(m.WorkspacesDir(), entry.Name())
+			if !m.FileExists(filepath.Join(workspace, "job.json")) {
+				orphans = append(orphans, workspace)
+				continue
+			}
+			partials, _ := filepath.Glob(filepath.Join(workspace, "*.partial.*"))
+			orphans = append(orphans, partials...)
+		}
+	}
+
+	concatLists, _ := filepath.Glob(filepath.Join(m.OutputsDir(), "*.concat.txt"))
+	orphans = append(orphans, concatLists...)
+
+	// Record files a crash left half written, those of downloads are orphans below
+	for _, dir := range []string{m.VideosDir(), m.ProjectsDir()} {
+		partials, _ := filepath.Glob(filepath.Join(dir, ".tmp-*"))
+		orphans = append(orphans, partials...)
+	}
+
+	// Decrypted cookies of downloads that were running
+	cookieFiles, _ := filepath.Glob(filepath.Join(m.CookiesDir(), "run-*.txt"))
+	orphans = append(orphans, cookieFiles...)
+
+	// Downloads dir: metadata JSON, imported videos and leftovers of interrupted downloads
+	referenced := make(map[string]bool)
+	videos, err := m.ListVideos()
+	if err != nil {
+		return 0, err
+	}
+	for _, video := range videos {
+		referenced[video.FilePath] = true
+		// Older downloads attached their subtitles where yt-dlp wrote them
+		for _, subtitle := range video.Subtitles {
+			referenced[subtitle.FilePath] = true
+		}
+		for _, watermark := range video.Watermarks {
+			referenced[watermark.FilePath] = true
+		}
+	}
+
+	// Partial files of resumable downloads: the direct download's file, yt-dlp's
+	// name.* files
+	var partialPrefixes []string
+	jars := make(map[string]bool)
+	downloads, err := m.ListDownloads()
+	if err != nil {
+		return 0, err
+	}
+	var expired []*models.Download
+	failedBefore := time.Now().Add(-failedRetention)
+	for _, download := range downloads {
+		if !download.Status.Resumable() {
+			continue
+		}
+		if download.Status == models.DownloadStatusFailed && failedRetention > 0 && download.UpdatedAt.Before(failedBefore) {
+			expired = append(expired, download)
+			continue
+		}
+		if download.FilePath != "" {
+			referenced[download.FilePath] = true
+		}
+		if download.OutputName != "" {
+			partialPrefixes = append(partialPrefixes, download.OutputName+".")
+		}
+		if download.CookieJar != "" {
+			jars[download.CookieJar+".enc"] = true
+		}
+	}
+	isPartial := func(name string) bool {
+		for _, prefix := range partialPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if entries, err := os.ReadDir(m.DownloadsDir()); err == nil {
+		for _, entry := range entries {
+			path := filepath.Join(m.DownloadsDir(), entry.Name())
+			if entry.IsDir() || filepath.Ext(entry.Name()) == ".json" || referenced[path] || isPartial(entry.Name()) {
+				continue
+			}
+			orphans = append(orphans, path)
+		}
+	}
+
+	// Cookie jars no download can use anymore
+	cookieJars, _ := filepath.Glob(filepath.Join(m.CookiesDir(), "*.enc"))
+	for _, path := range cookieJars {
+		if !jars[filepath.Base(path)] {
+			orphans = append(orphans, path)
+		}
+	}
+
+	// Expired failed downloads start over if retried, without their removed jar
+	for _, download := range expired {
+		if download.CookieJar == "" || jars[download.CookieJar+".enc"] {
+			continue
+		}
+		if _, err := m.ModifyDownload(download.ID, func(download *models.Download) error {
+			download.CookieJar = ""
+			return nil
+		}); err != nil {
+			m.logger.Warn("Failed to clear cookie jar of expired download", zap.String("id", download.ID), zap.Error(err))
+		}
+	}
+
+	removed := 0
+	for _, path := range orphans {
+		if err := os.RemoveAll(path); err != nil {
+			m.logger.Warn("Failed to remove orphaned file", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// DeleteFile removes a file, and its copy in the media store for media files
 func (m *Manager) DeleteFile(path string) error {
 	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete file %s: %w", path, err)
 	}
+	if key, ok := m.mediaKey(path); ok {
+		if err := m.media.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete stored file %s: %w", path, err)
+		}
+	}
 	return nil
 }
 
@@ -217,63 +626,54 @@ func (m *Manager) CreateDownload(download *models.Download) error {
 
 // GetDownload retrieves a download by ID
 func (m *Manager) GetDownload(id string) (*models.Download, error) {
-	path := m.GetDownloadMetadataPath(id)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("download not found: %s", id)
-		}
-		return nil, fmt.Errorf("failed to read download: %w", err)
-	}
-
 	var download models.Download
-	if err := json.Unmarshal(data, &download); err != nil {
-		return nil, fmt.Errorf("failed to parse download: %w", err)
+	if err := m.loadRecord(recordDownload, id, &download); err != nil {
+		return nil, err
 	}
-
 	return &download, nil
 }
 
-// UpdateDownload updates a download record
+// UpdateDownload updates a download record. It is stamped and written under the
+// record's lock, so concurrent updates land in the order they were stamped.
+// Changes that depend on the stored download go through ModifyDownload.
 func (m *Manager) UpdateDownload(download *models.Download) error {
-	download.UpdatedAt = time.Now()
+	return m.records.Update(recordDownload, download.ID, func([]byte) ([]byte, error) {
+		download.UpdatedAt = time.Now()
+		return json.MarshalIndent(download, "", "  ")
+	})
+}
 
-	data, err := json.MarshalIndent(download, "", "  ")
+// ModifyDownload applies modify to the stored download and saves it, with no other
+// write to the download in between. An error from modify leaves it unchanged.
+func (m *Manager) ModifyDownload(id string, modify func(download *models.Download) error) (*models.Download, error) {
+	var download models.Download
+	err := m.updateRecord(recordDownload, id, &download, func() error {
+		if err := modify(&download); err != nil {
+			return err
+		}
+		download.UpdatedAt = time.Now()
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal download: %w", err)
-	}
-
-	path := m.GetDownloadMetadataPath(download.ID)
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write download file: %w", err)
+		return nil, err
 	}
-
-	return nil
+	return &download, nil
 }
 
 // ListDownloads returns all downloads
 func (m *Manager) ListDownloads() ([]*models.Download, error) {
-	downloadsDir := m.DownloadsDir()
-	entries, err := os.ReadDir(downloadsDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read downloads directory: %w", err)
-	}
-
 	var downloads []*models.Download
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
-
-		downloadID := entry.Name()[:len(entry.Name())-5] // Remove .json extension
-		download, err := m.GetDownload(downloadID)
-		if err != nil {
-			m.logger.Warn("Failed to load download", zap.String("id", downloadID), zap.Error(err))
-			continue
+	err := m.listRecords(recordDownload, func(data []byte) error {
+		var download models.Download
+		if err := json.Unmarshal(data, &download); err != nil {
+			return err
 		}
-		downloads = append(downloads, download)
+		downloads = append(downloads, &download)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return downloads, nil
 }
 
@@ -292,67 +692,177 @@ func (m *Manager) DeleteDownload(id string) error {
 	}
 
 	// Delete metadata
-	metadataPath := m.GetDownloadMetadataPath(id)
-	return m.DeleteFile(metadataPath)
+	return m.records.Delete(recordDownload, id)
 }
 
-// ClearAllDownloads deletes all download records and their files
-func (m *Manager) ClearAllDownloads() error {
+// ClearAllDownloads moves all download records and their files to the trash
+func (m *Manager) ClearAllDownloads() (*models.TrashEntry, error) {
 	downloads, err := m.ListDownloads()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	m.trashMu.Lock()
+	defer m.trashMu.Unlock()
+
+	entry, dir, err := m.newTrashEntry("clear-downloads")
+	if err != nil {
+		return nil, err
 	}
 
 	for _, download := range downloads {
-		if err := m.DeleteDownload(download.ID); err != nil {
-			m.logger.Warn("Failed to delete download", zap.String("id", download.ID), zap.Error(err))
+		if download.FilePath != "" {
+			if err := m.moveToTrash(dir, download.FilePath); err != nil {
+				m.logger.Warn("Failed to trash download file", zap.String("path", download.FilePath), zap.Error(err))
+			}
+		}
+		if err := m.trashRecord(dir, recordDownload, download.ID); err != nil {
+			m.logger.Warn("Failed to trash download", zap.String("id", download.ID), zap.Error(err))
 		}
 	}
 
-	return nil
+	return m.finishTrashEntry(dir, entry)
 }
 
-// ClearEverything deletes all videos, downloads, projects, outputs, and resets counter
-func (m *Manager) ClearEverything() error {
-	m.logger.Info("Starting complete cleanup of all data")
+// TrashOldMedia moves the videos added before cutoff whose projects weren't changed
+// since to the trash, together with those projects, and the outputs written before
+// cutoff. Imported video files stay in their directory. It returns nil if nothing was
+// old or the data was deleted.
+func (m *Manager) TrashOldMedia(cutoff time.Time) (*models.TrashEntry, error) {
+	projects, err := m.ListProjects()
+	if err != nil {
+		return nil, err
+	}
+	videos, err := m.ListVideos()
+	if err != nil {
+		return nil, err
+	}
 
-	// Delete all downloads
-	downloads, err := m.ListDownloads()
-	if err == nil {
-		for _, download := range downloads {
-			if err := m.DeleteDownload(download.ID); err != nil {
-				m.logger.Warn("Failed to delete download", zap.String("id", download.ID), zap.Error(err))
-			}
+	// A video stays while any of its projects is in use
+	videoProjects := make(map[string][]string)
+	inUse := make(map[string]bool)
+	for _, project := range projects {
+		videoProjects[project.VideoID] = append(videoProjects[project.VideoID], project.ID)
+		if project.UpdatedAt.After(cutoff) {
+			inUse[project.VideoID] = true
 		}
 	}
 
-	// Delete all video metadata
-	videos, err := m.ListVideos()
-	if err == nil {
-		for _, video := range videos {
-			if err := m.DeleteVideo(video.ID); err != nil {
-				m.logger.Warn("Failed to delete video", zap.String("id", video.ID), zap.Error(err))
+	m.trashMu.Lock()
+	defer m.trashMu.Unlock()
+
+	entry, dir, err := m.newTrashEntry("auto-cleanup")
+	if err != nil {
+		return nil, err
+	}
+
+	trash := func(path string) {
+		if path == "" {
+			return
+		}
+		if err := m.moveToTrash(dir, path); err != nil {
+			m.logger.Warn("Failed to trash old file", zap.String("path", path), zap.Error(err))
+		}
+	}
+	var trashed []*models.Video
+	for _, video := range videos {
+		if video.CreatedAt.After(cutoff) || inUse[video.ID] {
+			continue
+		}
+		if !video.External {
+			trash(video.FilePath)
+		}
+		for _, subtitle := range video.Subtitles {
+			trash(subtitle.FilePath)
+		}
+		for _, watermark := range video.Watermarks {
+			trash(watermark.FilePath)
+		}
+		if video.Source != nil {
+			trash(video.Source.ThumbnailPath)
+		}
+		if err := m.trashRecord(dir, recordVideo, video.ID); err != nil {
+			m.logger.Warn("Failed to trash old video", zap.String("id", video.ID), zap.Error(err))
+			continue
+		}
+		for _, projectID := range videoProjects[video.ID] {
+			if err := m.trashRecord(dir, recordProject, projectID); err != nil {
+				m.logger.Warn("Failed to trash project of old video", zap.String("id", projectID), zap.Error(err))
 			}
 		}
+		// Caches are rebuilt if the video is restored
+		m.DeleteAnalysisCache(video.ID)
+		m.DeleteFile(m.GetKeyframeIndexPath(video.ID))
+		m.DeleteVideoThumbnails(video.ID)
+		m.DeleteWaveforms(video)
+		trashed = append(trashed, video)
 	}
 
-	// Delete all projects
-	projects, err := m.ListProjects()
-	if err == nil {
-		for _, project := range projects {
-			if err := m.DeleteProject(project.ID); err != nil {
-				m.logger.Warn("Failed to delete project", zap.String("id", project.ID), zap.Error(err))
-			}
+	outputs := 0
+	entries, _ := os.ReadDir(m.OutputsDir())
+	for _, dirEntry := range entries {
+		info, err := dirEntry.Info()
+		if err != nil || info.IsDir() || info.ModTime().After(cutoff) {
+			continue
 		}
+		trash(filepath.Join(m.OutputsDir(), dirEntry.Name()))
+		outputs++
 	}
 
-	// Clear output directory
-	outputDir := m.OutputsDir()
-	if entries, err := os.ReadDir(outputDir); err == nil {
-		for _, entry := range entries {
-			path := filepath.Join(outputDir, entry.Name())
-			if err := os.Remove(path); err != nil {
-				m.logger.Warn("Failed to delete output file", zap.String("path", path), zap.Error(err))
+	m.RecordDeletedVideos(trashed...)
+	if len(trashed) > 0 || outputs > 0 {
+		m.logger.Info("Cleaned up old media", zap.Int("videos", len(trashed)), zap.Int("outputs", outputs), zap.Time("before", cutoff))
+	}
+	return m.finishTrashEntry(dir, entry)
+}
+
+// trashedDirs are the data directories ClearEverything moves to the trash
+func (m *Manager) trashedDirs() []string {
+	return []string{
+		m.DownloadsDir(),
+		m.VideosDir(),
+		m.ProjectsDir(),
+		m.UploadsDir(),
+		m.SubtitlesDir(),
+		m.OutputsDir(),
+		m.WaveformsDir(),
+		m.KeyframesDir(),
+		m.ThumbnailsDir(),
+		m.PostersDir(),
+		m.ScreenshotsDir(),
+		m.AnalysisDir(),
+	}
+}
+
+// ClearEverything moves all videos, downloads, projects and outputs to a timestamped
+// trash folder, deletes temp files and resets the counter. The returned entry can be
+// restored until the trash retention expires; it is nil if the data was deleted.
+func (m *Manager) ClearEverything() (*models.TrashEntry, error) {
+	m.logger.Info("Starting complete cleanup of all data")
+
+	m.trashMu.Lock()
+	defer m.trashMu.Unlock()
+
+	entry, trashDir, err := m.newTrashEntry("clear-all")
+	if err != nil {
+		return nil, err
+	}
+
+	videos, err := m.ListVideos()
+	if err != nil {
+		m.logger.Warn("Failed to list videos to count deletions", zap.Error(err))
+	}
+	m.trashAllRecords(trashDir)
+
+	for _, dir := range m.trashedDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, dirEntry := range entries {
+			path := filepath.Join(dir, dirEntry.Name())
+			if err := m.moveToTrash(trashDir, path); err != nil {
+				m.logger.Warn("Failed to trash file", zap.String("path", path), zap.Error(err))
 			}
 		}
 	}
@@ -362,7 +872,7 @@ func (m *Manager) ClearEverything() error {
 	if entries, err := os.ReadDir(tempDir); err == nil {
 		for _, entry := range entries {
 			path := filepath.Join(tempDir, entry.Name())
-			if err := os.Remove(path); err != nil {
+			if err := os.RemoveAll(path); err != nil {
 				m.logger.Warn("Failed to delete temp file", zap.String("path", path), zap.Error(err))
 			}
 		}
@@ -372,73 +882,54 @@ func (m *Manager) ClearEverything() error {
 	if err := m.ResetVideoCounter(); err != nil {
 		m.logger.Warn("Failed to reset video counter", zap.Error(err))
 	}
+	m.RecordDeletedVideos(videos...)
 
-	m.logger.Info("Complete cleanup finished")
-	return nil
+	entry, err = m.finishTrashEntry(trashDir, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Complete cleanup finished", zap.Bool("trashed", entry != nil))
+	return entry, nil
 }
 
 // ListVideos returns all video metadata
 func (m *Manager) ListVideos() ([]*models.Video, error) {
-	entries, err := os.ReadDir(m.VideosDir())
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []*models.Video{}, nil
-		}
-		return nil, fmt.Errorf("failed to read videos directory: %w", err)
-	}
-
 	videos := make([]*models.Video, 0)
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
-
-		videoID := strings.TrimSuffix(entry.Name(), ".json")
-		video, err := m.GetVideo(videoID)
-		if err != nil {
-			m.logger.Warn("Failed to load video", zap.String("id", videoID), zap.Error(err))
-			continue
+	err := m.listRecords(recordVideo, func(data []byte) error {
+		var video models.Video
+		if err := json.Unmarshal(data, &video); err != nil {
+			return err
 		}
-
-		videos = append(videos, video)
+		videos = append(videos, &video)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return videos, nil
 }
 
 // ListProjects returns all projects
 func (m *Manager) ListProjects() ([]*models.Project, error) {
-	entries, err := os.ReadDir(m.ProjectsDir())
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []*models.Project{}, nil
-		}
-		return nil, fmt.Errorf("failed to read projects directory: %w", err)
-	}
-
 	projects := make([]*models.Project, 0)
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".llc" {
-			continue
-		}
-
-		projectID := strings.TrimSuffix(entry.Name(), ".llc")
-		project, err := m.GetProject(projectID)
-		if err != nil {
-			m.logger.Warn("Failed to load project", zap.String("id", projectID), zap.Error(err))
-			continue
+	err := m.listRecords(recordProject, func(data []byte) error {
+		var project models.Project
+		if err := json.Unmarshal(data, &project); err != nil {
+			return err
 		}
-
-		projects = append(projects, project)
+		projects = append(projects, &project)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return projects, nil
 }
 
-// DeleteProject deletes a project file
+// DeleteProject deletes a project
 func (m *Manager) DeleteProject(projectID string) error {
-	projectPath := m.GetProjectPath(projectID)
-	return m.DeleteFile(projectPath)
+	return m.records.Delete(recordProject, projectID)
 }
 
 // GetVideoMetadataPath returns the path for video metadata JSON
@@ -448,35 +939,25 @@ func (m *Manager) GetVideoMetadataPath(videoID string) string {
 
 // SaveVideo stores video metadata
 func (m *Manager) SaveVideo(video *models.Video) error {
-	data, err := json.MarshalIndent(video, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal video: %w", err)
-	}
+	return m.saveRecord(recordVideo, video.ID, video)
+}
 
-	path := m.GetVideoMetadataPath(video.ID)
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write video metadata: %w", err)
+// ModifyVideo applies modify to the stored video and saves it, with no other write
+// to the video in between. An error from modify leaves it unchanged.
+func (m *Manager) ModifyVideo(id string, modify func(video *models.Video) error) (*models.Video, error) {
+	var video models.Video
+	if err := m.updateRecord(recordVideo, id, &video, func() error { return modify(&video) }); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return &video, nil
 }
 
 // GetVideo retrieves video metadata by ID
 func (m *Manager) GetVideo(id string) (*models.Video, error) {
-	path := m.GetVideoMetadataPath(id)
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("video not found: %s", id)
-		}
-		return nil, fmt.Errorf("failed to read video: %w", err)
-	}
-
 	var video models.Video
-	if err := json.Unmarshal(data, &video); err != nil {
-		return nil, fmt.Errorf("failed to parse video: %w", err)
+	if err := m.loadRecord(recordVideo, id, &video); err != nil {
+		return nil, err
 	}
-
 	return &video, nil
 }
 
@@ -487,14 +968,231 @@ func (m *Manager) DeleteVideo(id string) error {
 		return err
 	}
 
-	// Delete video file if exists
-	if video.FilePath != "" {
+	// Delete video file if exists, imported files belong to their directory
+	if video.FilePath != "" && !video.External {
 		if err := m.DeleteFile(video.FilePath); err != nil {
 			m.logger.Warn("Failed to delete video file", zap.String("path", video.FilePath), zap.Error(err))
 		}
 	}
 
+	// Delete sidecar subtitle files
+	for _, subtitle := range video.Subtitles {
+		if err := m.DeleteFile(subtitle.FilePath); err != nil {
+			m.logger.Warn("Failed to delete subtitle file", zap.String("path", subtitle.FilePath), zap.Error(err))
+		}
+	}
+
+	for _, watermark := range video.Watermarks {
+		if err := m.DeleteFile(watermark.FilePath); err != nil {
+			m.logger.Warn("Failed to delete watermark file", zap.String("path", watermark.FilePath), zap.Error(err))
+		}
+	}
+
+	if video.Source != nil && video.Source.ThumbnailPath != "" {
+		if err := m.DeleteFile(video.Source.ThumbnailPath); err != nil {
+			m.logger.Warn("Failed to delete poster", zap.String("path", video.Source.ThumbnailPath), zap.Error(err))
+		}
+	}
+
+	// Delete cached analysis results
+	if err := m.DeleteAnalysisCache(id); err != nil {
+		m.logger.Warn("Failed to delete analysis cache", zap.String("id", id), zap.Error(err))
+	}
+	if err := m.DeleteFile(m.GetKeyframeIndexPath(id)); err != nil {
+		m.logger.Warn("Failed to delete keyframe index", zap.String("id", id), zap.Error(err))
+	}
+	if err := m.DeleteVideoThumbnails(id); err != nil {
+		m.logger.Warn("Failed to delete thumbnails", zap.String("id", id), zap.Error(err))
+	}
+	if err := m.DeleteWaveforms(video); err != nil {
+		m.logger.Warn("Failed to delete waveforms", zap.String("id", id), zap.Error(err))
+	}
+
 	// Delete metadata
-	metadataPath := m.GetVideoMetadataPath(id)
-	return m.DeleteFile(metadataPath)
+	return m.records.Delete(recordVideo, id)
+}
+
+// maxAnalysisCacheEntries caps the cached results per video. Loudness is cached per
+// range, so measuring many segments would otherwise grow the cache without bound.
+const maxAnalysisCacheEntries = 100
+
+// analysisCacheFile is the on-disk format of a video's cached analysis results
+type analysisCacheFile struct {
+	VideoID  string                     `json:"video_id"`
+	FileHash string                     `json:"file_hash"`
+	Entries  map[string]json.RawMessage `json:"entries"`
+	Order    []string                   `json:"order,omitempty"` // Keys of Entries, least recently saved first
+}
+
+// put stores an entry as the most recently saved, evicting the least recently saved
+// entries beyond maxAnalysisCacheEntries
+func (c *analysisCacheFile) put(key string, entry json.RawMessage) {
+	for i, k := range c.Order {
+		if k == key {
+			c.Order = append(c.Order[:i], c.Order[i+1:]...)
+			break
+		}
+	}
+	c.Entries[key] = entry
+	c.Order = append(c.Order, key)
+
+	for len(c.Order) > maxAnalysisCacheEntries {
+		delete(c.Entries, c.Order[0])
+		c.Order = c.Order[1:]
+	}
+}
+
+// hashSampleSize is how much of the start and end of a file is hashed
+const hashSampleSize = 4 * 1024 * 1024
+
+// ComputeFileHash returns a content hash of a media file. To stay fast on multi-GB
+// files it hashes the file size plus the first and last 4MB, which changes whenever
+// the file is replaced, re-downloaded or re-encoded.
+func (m *Manager) ComputeFileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for hashing: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file for hashing: %w", err)
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d:", info.Size())
+
+	if _, err := io.CopyN(hash, file, hashSampleSize); err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	if info.Size() > 2*hashSampleSize {
+		if _, err := file.Seek(-hashSampleSize, io.SeekEnd); err != nil {
+			return "", fmt.Errorf("failed to seek file for hashing: %w", err)
+		}
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// GetAnalysisCachePath returns the path of a video's analysis cache file
+func (m *Manager) GetAnalysisCachePath(videoID string) string {
+	return filepath.Join(m.AnalysisDir(), videoID+".json")
+}
+
+// GetAnalysisCache loads a cached analysis result into v. It returns false when there is
+// no entry for key or the cache was built for a different file hash.
+func (m *Manager) GetAnalysisCache(videoID, fileHash, key string, v interface{}) (bool, error) {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+
+	cache, err := m.readAnalysisCache(videoID)
+	if err != nil || cache.FileHash != fileHash {
+		return false, err
+	}
+
+	data, ok := cache.Entries[key]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse cached analysis: %w", err)
+	}
+
+	return true, nil
+}
+
+// SaveAnalysisCache stores an analysis result for a video. Entries stored for a
+// different file hash are discarded.
+func (m *Manager) SaveAnalysisCache(videoID, fileHash, key string, v interface{}) error {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+
+	cache, err := m.readAnalysisCache(videoID)
+	if err != nil {
+		return err
+	}
+
+	if cache.FileHash != fileHash {
+		if cache.FileHash != "" {
+			m.logger.Info("Video file changed, invalidating analysis cache", zap.String("videoId", videoID))
+		}
+		cache = &analysisCacheFile{
+			VideoID:  videoID,
+			FileHash: fileHash,
+			Entries:  make(map[string]json.RawMessage),
+		}
+	}
+
+	entry, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis result: %w", err)
+	}
+	cache.put(key, entry)
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal analysis cache: %w", err)
+	}
+
+	if err := os.WriteFile(m.GetAnalysisCachePath(videoID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write analysis cache: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAnalysisCache removes all cached analysis results for a video
+func (m *Manager) DeleteAnalysisCache(videoID string) error {
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+
+	return m.DeleteFile(m.GetAnalysisCachePath(videoID))
+}
+
+// readAnalysisCache reads a video's cache file, returning an empty cache if none exists
+func (m *Manager) readAnalysisCache(videoID string) (*analysisCacheFile, error) {
+	data, err := os.ReadFile(m.GetAnalysisCachePath(videoID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &analysisCacheFile{VideoID: videoID}, nil
+		}
+		return nil, fmt.Errorf("failed to read analysis cache: %w", err)
+	}
+
+	var cache analysisCacheFile
+	if err := json.Unmarshal(data, &cache); err != nil {
+		// A corrupt cache is not fatal, it is simply rebuilt
+		m.logger.Warn("Discarding corrupt analysis cache", zap.String("videoId", videoID), zap.Error(err))
+		return &analysisCacheFile{VideoID: videoID}, nil
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]json.RawMessage)
+	}
+	// Caches written before entries were ordered list them all as the oldest
+	if len(cache.Order) != len(cache.Entries) {
+		ordered := make(map[string]bool, len(cache.Order))
+		order := make([]string, 0, len(cache.Entries))
+		for _, key := range cache.Order {
+			if _, ok := cache.Entries[key]; ok && !ordered[key] {
+				ordered[key] = true
+				order = append(order, key)
+			}
+		}
+		var unordered []string
+		for key := range cache.Entries {
+			if !ordered[key] {
+				unordered = append(unordered, key)
+			}
+		}
+		sort.Strings(unordered)
+		cache.Order = append(unordered, order...)
+	}
+
+	return &cache, nil
 }
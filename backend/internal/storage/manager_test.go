@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestCleanupInterruptedKeepsResumableDownloads(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path string) string {
+		if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	direct := write(filepath.Join(m.DownloadsDir(), "video1.mp4"))
+	ytdlpPart := write(filepath.Join(m.DownloadsDir(), "video2.f137.mp4.part"))
+	cancelled := write(filepath.Join(m.DownloadsDir(), "video3.mp4.part"))
+	orphan := write(filepath.Join(m.DownloadsDir(), "video4.mp4"))
+	jar := write(m.GetCookieJarPath("jar1"))
+	staleJar := write(m.GetCookieJarPath("jar2"))
+
+	downloads := []*models.Download{
+		{Status: models.DownloadStatusDownloading, FilePath: direct, CookieJar: "jar1"},
+		{Status: models.DownloadStatusInterrupted, OutputName: "video2"},
+		{Status: models.DownloadStatusCancelled, OutputName: "video3", CookieJar: "jar2"},
+	}
+	for _, download := range downloads {
+		if err := m.CreateDownload(download); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := m.CleanupInterrupted(0); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{direct, ytdlpPart, jar} {
+		if !m.FileExists(path) {
+			t.Errorf("%s of a resumable download was removed", filepath.Base(path))
+		}
+	}
+	for _, path := range []string{cancelled, orphan, staleJar} {
+		if m.FileExists(path) {
+			t.Errorf("%s was kept", filepath.Base(path))
+		}
+	}
+}
+
+func TestCleanupInterruptedKeepsVideoFiles(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, name := range []string{"clip.mp4", "clip.en.vtt", "logo.png"} {
+		path := filepath.Join(m.DownloadsDir(), name)
+		if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	video := &models.Video{
+		ID:         "v1",
+		FilePath:   paths[0],
+		Subtitles:  []models.SubtitleFile{{ID: "s1", FilePath: paths[1]}},
+		Watermarks: []models.ImageFile{{ID: "w1", FilePath: paths[2]}},
+	}
+	if err := m.SaveVideo(video); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.CleanupInterrupted(0); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if !m.FileExists(path) {
+			t.Errorf("%s of a video was removed", filepath.Base(path))
+		}
+	}
+}
+
+func TestExpireFailedWorkspaces(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now()
+	jobs := []*models.ExportJob{
+		{OperationID: "old", Status: models.OperationStatusFailed, FailedAt: &old},
+		{OperationID: "recent", Status: models.OperationStatusFailed, FailedAt: &recent},
+		{OperationID: "legacy", Status: models.OperationStatusFailed, CreatedAt: old},
+		{OperationID: "interrupted", CreatedAt: old},
+	}
+	for _, job := range jobs {
+		if err := m.SaveExportJob(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expired, err := m.ExpireFailedWorkspaces(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(expired)
+	if strings.Join(expired, ",") != "legacy,old" {
+		t.Errorf("expired = %v, want legacy and old", expired)
+	}
+	for _, job := range jobs {
+		_, err := m.GetExportJob(job.OperationID)
+		if gone := errors.Is(err, ErrNotFound); gone != (job.OperationID == "old" || job.OperationID == "legacy") {
+			t.Errorf("GetExportJob(%s) = %v", job.OperationID, err)
+		}
+	}
+}
+
+func TestPurgeSessionCounters(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if n, err := m.PurgeSessionCounters(); err != nil || n != 0 {
+		t.Fatalf("PurgeSessionCounters() without counters = %d, %v", n, err)
+	}
+
+	m.NextSequence("idle")
+	m.NextSequence("active")
+	old := time.Now().Add(-sessionCounterRetention - time.Hour)
+	if err := os.Chtimes(m.sequenceFile("idle"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := m.PurgeSessionCounters(); err != nil || n != 1 {
+		t.Fatalf("PurgeSessionCounters() = %d, %v, want 1", n, err)
+	}
+	if m.FileExists(m.sequenceFile("idle")) || !m.FileExists(m.sequenceFile("active")) {
+		t.Error("expected only the idle session's counter to be deleted")
+	}
+	if got := m.NextSequence("idle"); got != 1 {
+		t.Errorf("NextSequence() of a purged session = %d, want 1", got)
+	}
+}
+
+func TestCleanupInterruptedExpiresFailedDownloads(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	part := filepath.Join(m.DownloadsDir(), "video1.f137.mp4.part")
+	jar := m.GetCookieJarPath("jar1")
+	for _, path := range []string{part, jar} {
+		if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	download := &models.Download{Status: models.DownloadStatusFailed, OutputName: "video1", CookieJar: "jar1"}
+	if err := m.CreateDownload(download); err != nil {
+		t.Fatal(err)
+	}
+
+	// Within the retention the download can still be retried
+	if _, err := m.CleanupInterrupted(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if !m.FileExists(part) || !m.FileExists(jar) {
+		t.Fatal("files of a recently failed download were removed")
+	}
+
+	time.Sleep(time.Millisecond)
+	if _, err := m.CleanupInterrupted(time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	if m.FileExists(part) || m.FileExists(jar) {
+		t.Error("files of an expired failed download were kept")
+	}
+	if saved, err := m.GetDownload(download.ID); err != nil || saved.CookieJar != "" {
+		t.Errorf("expired download = %+v, %v, want its cookie jar cleared", saved, err)
+	}
+}
+
+func TestAnalysisCacheEviction(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i <= maxAnalysisCacheEntries; i++ {
+		if err := m.SaveAnalysisCache("v1", "hash", fmt.Sprintf("loudness:%d", i), i); err != nil {
+			t.Fatal(err)
+		}
+		// Saving again keeps the first entry the most recent one
+		if i > 0 {
+			if err := m.SaveAnalysisCache("v1", "hash", "loudness:0", 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var v int
+	if found, _ := m.GetAnalysisCache("v1", "hash", "loudness:1", &v); found {
+		t.Error("least recently saved entry wasn't evicted")
+	}
+	for _, key := range []string{"loudness:0", "loudness:2", fmt.Sprintf("loudness:%d", maxAnalysisCacheEntries)} {
+		if found, err := m.GetAnalysisCache("v1", "hash", key, &v); !found || err != nil {
+			t.Errorf("GetAnalysisCache(%s) = %v, %v, want it kept", key, found, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// Media backends of storage.media
+const (
+	MediaLocal = "local" // Only the directories below base_path
+	MediaS3    = "s3"    // Also a bucket on S3 or an S3-compatible store
+)
+
+// Store keeps media files under keys, their slash separated path below base_path,
+// e.g. "uploads/<id>.mp4"
+type Store interface {
+	// Put copies the local file at path to key
+	Put(key, path string) error
+	// Get copies key to the local file at path, failing with an error wrapping
+	// os.ErrNotExist if there is no such key
+	Get(key, path string) error
+	// Delete removes key, a missing key is not an error
+	Delete(key string) error
+}
+
+// localStore keeps media on the local disk below base_path, where the files are
+// written in the first place
+type localStore struct {
+	root string
+}
+
+func (s *localStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+func (s *localStore) Put(key, path string) error {
+	if s.path(key) == path {
+		return nil
+	}
+	return copyFile(path, s.path(key))
+}
+
+func (s *localStore) Get(key, path string) error {
+	if s.path(key) == path {
+		_, err := os.Stat(path)
+		return err
+	}
+	return copyFile(s.path(key), path)
+}
+
+func (s *localStore) Delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// copyFile copies src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFileAtomic(dst, in)
+}
+
+// writeFileAtomic writes r to a temporary file next to path and renames it into
+// place once complete and synced, so readers and crashes never leave a partial file
+func writeFileAtomic(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// OpenMedia selects where uploads, downloads, outputs and waveforms are kept. With "s3" every
+// file is written to the bucket once complete and fetched from it by instances
+// that don't have it, e.g. a new container, so the local directories only cache
+// them. Call it after Initialize, before any other use.
+func (m *Manager) OpenMedia(backend string, cfg config.S3Config, bucket, prefix string) error {
+	if backend != MediaS3 {
+		return nil
+	}
+
+	store, err := newS3Store(cfg, bucket, prefix)
+	if err != nil {
+		return err
+	}
+	m.media = store
+	m.logger.Info("Keeping media in S3", zap.String("bucket", bucket), zap.String("prefix", prefix))
+	return nil
+}
+
+// mediaDirs are the directories whose files are kept in the media store
+func (m *Manager) mediaDirs() []string {
+	return []string{m.UploadsDir(), m.SubtitlesDir(), m.DownloadsDir(), m.OutputsDir(), m.WaveformsDir()}
+}
+
+// mediaKey returns the media store key of a file, false for files that aren't media.
+// Downloaded videos are imported in place, the download records and info sidecars
+// next to them aren't media.
+func (m *Manager) mediaKey(path string) (string, bool) {
+	for _, dir := range m.mediaDirs() {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if dir == m.DownloadsDir() && strings.EqualFold(filepath.Ext(rel), ".json") {
+			return "", false
+		}
+		return filepath.Base(dir) + "/" + filepath.ToSlash(rel), true
+	}
+	return "", false
+}
+
+// StoreFile writes a complete media file to the media store. Files outside the
+// media directories are ignored.
+func (m *Manager) StoreFile(path string) error {
+	key, ok := m.mediaKey(path)
+	if !ok {
+		return nil
+	}
+	if err := m.media.Put(key, path); err != nil {
+		return fmt.Errorf("failed to store %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// StoreFiles writes several media files to the media store, returning the first
+// error after trying all of them
+func (m *Manager) StoreFiles(paths []string) error {
+	var first error
+	for _, path := range paths {
+		if err := m.StoreFile(path); err != nil {
+			m.logger.Warn("Failed to store media file", zap.String("path", path), zap.Error(err))
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// FetchFile makes sure a file is on local disk, fetching media files this instance
+// doesn't have from the media store. It fails like os.Stat for missing files.
+func (m *Manager) FetchFile(path string) error {
+	_, err := os.Stat(path)
+	if err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	key, ok := m.mediaKey(path)
+	if !ok {
+		return err
+	}
+	if err := m.media.Get(key, path); err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", filepath.Base(path), err)
+	}
+	m.logger.Info("Fetched media file", zap.String("key", key))
+	return nil
+}
+
+// FetchVideo loads a video like GetVideo and makes sure its file, subtitles and
+// watermarks are on local disk to be read. Files that can't be fetched are logged
+// and left to fail where they are read.
+func (m *Manager) FetchVideo(id string) (*models.Video, error) {
+	video, err := m.GetVideo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{video.FilePath}
+	for _, subtitle := range video.Subtitles {
+		paths = append(paths, subtitle.FilePath)
+	}
+	for _, watermark := range video.Watermarks {
+		paths = append(paths, watermark.FilePath)
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := m.FetchFile(path); err != nil {
+			m.logger.Warn("Video file is unavailable", zap.String("videoId", id), zap.String("path", path), zap.Error(err))
+		}
+	}
+	return video, nil
+}
+
+// deleteTrashedMedia deletes the stored copies of the media files in a trash folder
+// that is about to be deleted. The local store keeps them in the folder itself.
+func (m *Manager) deleteTrashedMedia(dir string) {
+	if _, ok := m.media.(*localStore); ok {
+		return
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		// A file created at the same path since then owns the key now
+		original := filepath.Join(m.basePath, rel)
+		key, ok := m.mediaKey(original)
+		if !ok || m.FileExists(original) {
+			return nil
+		}
+		if err := m.media.Delete(key); err != nil {
+			m.logger.Warn("Failed to delete stored media", zap.String("key", key), zap.Error(err))
+		}
+		return nil
+	})
+}
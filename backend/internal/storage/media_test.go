@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"go.uber.org/zap"
+)
+
+// fakeS3 keeps objects PUT to it in memory, by request path
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Media(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.S3Config{Endpoint: server.URL, PathStyle: true, AccessKeyID: "minio", SecretAccessKey: "minio123"}
+	if err := m.OpenMedia(MediaS3, cfg, "media", "lc/"); err != nil {
+		t.Fatal(err)
+	}
+
+	upload := m.GetVideoPath("a b.mp4")
+	download := filepath.Join(m.DownloadsDir(), "video1.mp4")
+	for _, path := range []string{upload, download} {
+		if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.StoreFile(path); err != nil {
+			t.Fatalf("StoreFile(%s) = %v", path, err)
+		}
+	}
+	if len(fake.objects) != 2 || string(fake.objects["/media/lc/uploads/a b.mp4"]) != "video" ||
+		string(fake.objects["/media/lc/downloads/video1.mp4"]) != "video" {
+		t.Fatalf("stored objects = %v, want the upload and the download", fake.objects)
+	}
+
+	// A new instance fetches files it doesn't have
+	os.Remove(upload)
+	if err := m.FetchFile(upload); err != nil {
+		t.Fatalf("FetchFile() = %v", err)
+	}
+	if data, err := os.ReadFile(upload); err != nil || string(data) != "video" {
+		t.Errorf("fetched file = %q, %v", data, err)
+	}
+	if err := m.FetchFile(m.GetOutputPath("missing.mp4")); err == nil {
+		t.Error("FetchFile() of a missing output succeeded")
+	}
+
+	for _, path := range []string{upload, download} {
+		if err := m.DeleteFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("objects after DeleteFile() = %v", fake.objects)
+	}
+}
+
+func TestMediaKey(t *testing.T) {
+	m := NewManager(filepath.FromSlash("/data"), zap.NewNop())
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{m.GetVideoPath("a.mp4"), "uploads/a.mp4"},
+		{m.GetSubtitlePath("s1.srt"), "subtitles/s1.srt"},
+		{m.GetOutputPath("a_cut.mp4"), "outputs/a_cut.mp4"},
+		{m.GetWaveformPath("v1.png"), "waveforms/v1.png"},
+		{filepath.Join(m.DownloadsDir(), "d1.mp4"), "downloads/d1.mp4"},
+		{m.GetDownloadMetadataPath("d1"), ""},
+		{filepath.Join(m.DownloadsDir(), "d1.info.json"), ""},
+		{m.UploadsDir(), ""},
+		{filepath.Join(m.UploadsDir(), "..", "videos", "v1.json"), ""},
+	}
+	for _, tt := range tests {
+		key, ok := m.mediaKey(tt.path)
+		if key != tt.key || ok != (tt.key != "") {
+			t.Errorf("mediaKey(%s) = %q, %v, want %q", tt.path, key, ok, tt.key)
+		}
+	}
+}
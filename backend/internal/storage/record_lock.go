@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LocksDir returns the directory of the lock files of records written by the file
+// store
+func (m *Manager) LocksDir() string {
+	return filepath.Join(m.basePath, "locks")
+}
+
+// recordLockPath returns the lock file of a record
+func (m *Manager) recordLockPath(kind recordKind, id string) string {
+	return filepath.Join(m.LocksDir(), string(kind)+"-"+id+".lock")
+}
+
+// recordLock orders the writers of one record in this server
+type recordLock struct {
+	mu   sync.Mutex
+	refs int // Writers holding or waiting for mu, guarded by Manager.recordLocksMu
+}
+
+// lockRecord takes the lock of a record and returns the function releasing it. A
+// mutex orders the writers of this server, an advisory lock on the record's lock
+// file those of other servers sharing the base path. Lock files are kept when their
+// record is deleted: a writer of another server may still hold or wait for the lock,
+// and would no longer exclude new writers if the file were replaced.
+func (m *Manager) lockRecord(kind recordKind, id string) (func(), error) {
+	key := string(kind) + "/" + id
+	m.recordLocksMu.Lock()
+	lock, ok := m.recordLocks[key]
+	if !ok {
+		lock = &recordLock{}
+		m.recordLocks[key] = lock
+	}
+	lock.refs++
+	m.recordLocksMu.Unlock()
+	lock.mu.Lock()
+
+	// The mutex is dropped with its last user, so the map only holds busy records
+	mu := &lock.mu
+	release := func() {
+		mu.Unlock()
+		m.recordLocksMu.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(m.recordLocks, key)
+		}
+		m.recordLocksMu.Unlock()
+	}
+
+	path := m.recordLockPath(kind, id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		release()
+		return nil, fmt.Errorf("failed to create locks directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("failed to open lock of %s %s: %w", kind, id, err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		release()
+		return nil, fmt.Errorf("failed to lock %s %s: %w", kind, id, err)
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+		release()
+	}, nil
+}
@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// recordKind names a type of metadata record
+type recordKind string
+
+const (
+	recordVideo    recordKind = "video"
+	recordDownload recordKind = "download"
+	recordProject  recordKind = "project"
+)
+
+// recordKinds are every kind of record, in migration order
+var recordKinds = []recordKind{recordVideo, recordDownload, recordProject}
+
+// errRecordNotFound is returned by record stores for a record that doesn't exist
+var errRecordNotFound = errors.New("record not found")
+
+// ErrNotFound is wrapped by the errors of reading a video, download, project or export
+// job that doesn't exist, e.g. "video not found: <id>"
+var ErrNotFound = errors.New("not found")
+
+// recordIDPattern matches record IDs, UUIDs in practice. Other JSON files next to
+// the records, e.g. yt-dlp's "<name>.info.json" sidecars, aren't records.
+var recordIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// record is the stored JSON of one video, download or project
+type record struct {
+	ID   string
+	Data []byte
+}
+
+// recordStore keeps the metadata of videos, downloads and projects
+type recordStore interface {
+	Get(kind recordKind, id string) ([]byte, error)
+	Put(kind recordKind, id string, data []byte) error
+	// Update replaces a record with what modify returns for its current data, nil if
+	// there is none yet, with no other write in between
+	Update(kind recordKind, id string, modify func(data []byte) ([]byte, error)) error
+	Delete(kind recordKind, id string) error
+	List(kind recordKind) ([]record, error)
+	// FindVideo returns the ID of the video stored at path, "" if there is none
+	FindVideo(path string) (string, error)
+	Close() error
+}
+
+// recordPath returns the JSON file a record is kept in by the file store, which is
+// also where it goes in the trash
+func (m *Manager) recordPath(kind recordKind, id string) string {
+	switch kind {
+	case recordDownload:
+		return m.GetDownloadMetadataPath(id)
+	case recordProject:
+		return m.GetProjectPath(id)
+	default:
+		return m.GetVideoMetadataPath(id)
+	}
+}
+
+// recordDir returns the directory and file extension of the file store's records
+func (m *Manager) recordDir(kind recordKind) (string, string) {
+	switch kind {
+	case recordDownload:
+		return m.DownloadsDir(), ".json"
+	case recordProject:
+		return m.ProjectsDir(), ".llc"
+	default:
+		return m.VideosDir(), ".json"
+	}
+}
+
+// loadRecord reads a record into v
+func (m *Manager) loadRecord(kind recordKind, id string, v interface{}) error {
+	data, err := m.records.Get(kind, id)
+	if errors.Is(err, errRecordNotFound) {
+		return fmt.Errorf("%s %w: %s", kind, ErrNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", kind, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", kind, err)
+	}
+	return nil
+}
+
+// saveRecord stores v as a record
+func (m *Manager) saveRecord(kind recordKind, id string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", kind, err)
+	}
+	if err := m.records.Put(kind, id, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", kind, err)
+	}
+	return nil
+}
+
+// updateRecord reads a record into v, calls modify and saves v, with no other write
+// to the record in between. An error from modify leaves the record unchanged.
+func (m *Manager) updateRecord(kind recordKind, id string, v interface{}, modify func() error) error {
+	err := m.records.Update(kind, id, func(data []byte) ([]byte, error) {
+		if data == nil {
+			return nil, errRecordNotFound
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", kind, err)
+		}
+		if err := modify(); err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(v, "", "  ")
+	})
+	if errors.Is(err, errRecordNotFound) {
+		return fmt.Errorf("%s %w: %s", kind, ErrNotFound, id)
+	}
+	return err
+}
+
+// listRecords calls add with every record of a kind, skipping those it fails on
+func (m *Manager) listRecords(kind recordKind, add func(data []byte) error) error {
+	records, err := m.records.List(kind)
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		if err := add(rec.Data); err != nil {
+			m.logger.Warn("Failed to load "+string(kind), zap.String("id", rec.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// FindVideoByPath returns the video whose file is at path, or nil if there is none
+func (m *Manager) FindVideoByPath(path string) (*models.Video, error) {
+	id, err := m.records.FindVideo(path)
+	if err != nil || id == "" {
+		return nil, err
+	}
+	return m.GetVideo(id)
+}
+
+// trashRecord moves a record into a trash folder, as the JSON file the file store
+// would keep it in, so restoring it works the same for both stores
+func (m *Manager) trashRecord(dir string, kind recordKind, id string) error {
+	path := m.recordPath(kind, id)
+	if _, ok := m.records.(*fileRecordStore); ok {
+		return m.moveToTrash(dir, path)
+	}
+
+	data, err := m.records.Get(kind, id)
+	if errors.Is(err, errRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(m.basePath, path)
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create trash folder: %w", err)
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return fmt.Errorf("failed to move %s %s to trash: %w", kind, id, err)
+	}
+	return m.records.Delete(kind, id)
+}
+
+// trashAllRecords moves every record kept outside the data directories into a trash
+// folder. The file store's records are trashed with their directories.
+func (m *Manager) trashAllRecords(dir string) {
+	if _, ok := m.records.(*fileRecordStore); ok {
+		return
+	}
+	for _, kind := range recordKinds {
+		records, err := m.records.List(kind)
+		if err != nil {
+			m.logger.Warn("Failed to list records to trash", zap.String("kind", string(kind)), zap.Error(err))
+			continue
+		}
+		for _, rec := range records {
+			if err := m.trashRecord(dir, kind, rec.ID); err != nil {
+				m.logger.Warn("Failed to trash record", zap.String("kind", string(kind)), zap.String("id", rec.ID), zap.Error(err))
+			}
+		}
+	}
+}
+
+// fileRecordStore keeps every record in a JSON file of its own: videos/<id>.json,
+// downloads/<id>.json and projects/<id>.llc
+type fileRecordStore struct {
+	m *Manager
+}
+
+func (s *fileRecordStore) Get(kind recordKind, id string) ([]byte, error) {
+	data, err := os.ReadFile(s.m.recordPath(kind, id))
+	if os.IsNotExist(err) {
+		return nil, errRecordNotFound
+	}
+	return data, err
+}
+
+// Put replaces a record file atomically under the record's lock, so concurrent
+// writers, e.g. progress updates, can't interleave and readers see a whole record
+func (s *fileRecordStore) Put(kind recordKind, id string, data []byte) error {
+	unlock, err := s.m.lockRecord(kind, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeFileAtomic(s.m.recordPath(kind, id), bytes.NewReader(data))
+}
+
+func (s *fileRecordStore) Update(kind recordKind, id string, modify func(data []byte) ([]byte, error)) error {
+	unlock, err := s.m.lockRecord(kind, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	data, err := s.Get(kind, id)
+	if err != nil && !errors.Is(err, errRecordNotFound) {
+		return err
+	}
+	if data, err = modify(data); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.m.recordPath(kind, id), bytes.NewReader(data))
+}
+
+func (s *fileRecordStore) Delete(kind recordKind, id string) error {
+	unlock, err := s.m.lockRecord(kind, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.m.DeleteFile(s.m.recordPath(kind, id))
+}
+
+func (s *fileRecordStore) List(kind recordKind) ([]record, error) {
+	dir, ext := s.m.recordDir(kind)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %ss directory: %w", kind, err)
+	}
+
+	var records []record
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ext {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), ext)
+		if !recordIDPattern.MatchString(id) {
+			continue
+		}
+		data, err := s.Get(kind, id)
+		if err != nil {
+			s.m.logger.Warn("Failed to load "+string(kind), zap.String("id", id), zap.Error(err))
+			continue
+		}
+		records = append(records, record{ID: id, Data: data})
+	}
+	return records, nil
+}
+
+func (s *fileRecordStore) FindVideo(path string) (string, error) {
+	records, err := s.List(recordVideo)
+	if err != nil {
+		return "", err
+	}
+	for _, rec := range records {
+		var video struct {
+			FilePath string `json:"file_path"`
+		}
+		if json.Unmarshal(rec.Data, &video) == nil && video.FilePath == path {
+			return rec.ID, nil
+		}
+	}
+	return "", nil
+}
+
+func (s *fileRecordStore) Close() error {
+	return nil
+}
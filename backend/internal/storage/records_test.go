@@ -0,0 +1,310 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestOpenMetadataMigratesJSON(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Records written with the file store before switching
+	if err := m.SaveVideo(&models.Video{ID: "v1", FilePath: "/media/a.mp4"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SaveProject(&models.Project{ID: "p1", Name: "Cut", VideoID: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateDownload(&models.Download{ID: "d1", URL: "https://example.com/a.mp4"}); err != nil {
+		t.Fatal(err)
+	}
+	sidecar := filepath.Join(m.DownloadsDir(), "a.info.json")
+	if err := os.WriteFile(sidecar, []byte(`{"title": "A"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.OpenMetadata(MetadataBolt); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if video, err := m.GetVideo("v1"); err != nil || video.FilePath != "/media/a.mp4" {
+		t.Errorf("GetVideo() after migration = %+v, %v", video, err)
+	}
+	if projects, err := m.ListProjects(); err != nil || len(projects) != 1 || projects[0].Name != "Cut" {
+		t.Errorf("ListProjects() after migration = %+v, %v", projects, err)
+	}
+	if download, err := m.GetDownload("d1"); err != nil || download.URL != "https://example.com/a.mp4" {
+		t.Errorf("GetDownload() after migration = %+v, %v", download, err)
+	}
+
+	// The JSON files are kept aside, so the migration only runs once
+	if m.FileExists(m.GetVideoMetadataPath("v1")) || !m.FileExists(filepath.Join(m.JSONBackupDir(), "projects", "p1.llc")) {
+		t.Error("migrated JSON files were not moved to the backup folder")
+	}
+	// Sidecars next to the download records aren't records
+	if m.FileExists(filepath.Join(m.JSONBackupDir(), "downloads", "a.info.json")) || !m.FileExists(sidecar) {
+		t.Error("info.json sidecar was migrated as a record")
+	}
+}
+
+func TestImportRecordFilesKeepsUnmovedFiles(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.OpenMetadata(MetadataBolt); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// A record file of an older state reappears, e.g. left by a failed backup
+	if err := m.SaveVideo(&models.Video{ID: "v1", FileName: "new.mp4"}); err != nil {
+		t.Fatal(err)
+	}
+	stale := []byte(`{"id": "v1", "file_name": "old.mp4"}`)
+	if err := os.WriteFile(m.GetVideoMetadataPath("v1"), stale, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A backup folder that can't be created keeps the file in place
+	blocked := filepath.Join(t.TempDir(), "blocked")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.importRecordFiles(blocked); err != nil {
+		t.Fatal(err)
+	}
+	if !m.FileExists(m.GetVideoMetadataPath("v1")) {
+		t.Error("record file was deleted without a backup")
+	}
+	if video, err := m.GetVideo("v1"); err != nil || video.FileName != "new.mp4" {
+		t.Errorf("GetVideo() after importing a stale file = %+v, %v", video, err)
+	}
+}
+
+func TestBoltRecordsVideoPathIndex(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.OpenMetadata(MetadataBolt); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	video := &models.Video{ID: "v1", FilePath: "/media/a.mp4"}
+	m.SaveVideo(video)
+	if found, err := m.FindVideoByPath("/media/a.mp4"); err != nil || found == nil || found.ID != "v1" {
+		t.Fatalf("FindVideoByPath() = %+v, %v", found, err)
+	}
+
+	// Moving the file updates the index
+	video.FilePath = "/media/b.mp4"
+	m.SaveVideo(video)
+	if found, _ := m.FindVideoByPath("/media/a.mp4"); found != nil {
+		t.Errorf("FindVideoByPath() of the old path = %+v, want nil", found)
+	}
+	if found, _ := m.FindVideoByPath("/media/b.mp4"); found == nil {
+		t.Error("FindVideoByPath() of the new path = nil")
+	}
+
+	if err := m.DeleteVideo("v1"); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := m.FindVideoByPath("/media/b.mp4"); found != nil {
+		t.Errorf("FindVideoByPath() after delete = %+v, want nil", found)
+	}
+	if _, err := m.GetVideo("v1"); err == nil {
+		t.Error("GetVideo() after delete: want error")
+	}
+}
+
+func TestBoltRecordsTrash(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.OpenMetadata(MetadataBolt); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.SetTrashRetention(24 * time.Hour)
+
+	path := filepath.Join(m.DownloadsDir(), "video1.mp4")
+	os.WriteFile(path, []byte("video"), 0644)
+	m.CreateDownload(&models.Download{ID: "d1", FilePath: path})
+
+	entry, err := m.ClearAllDownloads()
+	if err != nil || entry == nil || entry.Files != 2 {
+		t.Fatalf("ClearAllDownloads() = %+v, %v", entry, err)
+	}
+	if downloads, _ := m.ListDownloads(); len(downloads) != 0 {
+		t.Fatalf("ListDownloads() after clearing = %+v", downloads)
+	}
+
+	// Restored records go back into the database
+	if restored, _, err := m.RestoreTrash(entry.ID); err != nil || restored != 2 {
+		t.Fatalf("RestoreTrash() = %d, %v", restored, err)
+	}
+	if download, err := m.GetDownload("d1"); err != nil || download.FilePath != path {
+		t.Errorf("GetDownload() after restore = %+v, %v", download, err)
+	}
+	if m.FileExists(m.GetDownloadMetadataPath("d1")) {
+		t.Error("restored download record left as a JSON file")
+	}
+}
+
+func TestFileRecordsConcurrentWriters(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateDownload(&models.Download{ID: "d1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Progress updates of the same download from several goroutines, while it is read
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				download := &models.Download{ID: "d1", Title: fmt.Sprintf("writer %d with a title of varying length %d", i, j*j*j)}
+				if err := m.UpdateDownload(download); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := m.GetDownload("d1"); err != nil {
+					t.Errorf("GetDownload() during writes = %v", err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	downloads, err := m.ListDownloads()
+	if err != nil || len(downloads) != 1 {
+		t.Fatalf("ListDownloads() = %d downloads, %v", len(downloads), err)
+	}
+	// No temporary files are left behind
+	if entries, _ := os.ReadDir(m.DownloadsDir()); len(entries) != 1 {
+		t.Errorf("downloads directory has %d files, want 1", len(entries))
+	}
+}
+
+func TestRecordLockAcrossManagers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("only the writers of one server are ordered on windows")
+	}
+
+	// Two servers sharing a base path
+	basePath := t.TempDir()
+	first := NewManager(basePath, zap.NewNop())
+	second := NewManager(basePath, zap.NewNop())
+
+	unlock, err := first.lockRecord(recordVideo, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := make(chan struct{})
+	go func() {
+		unlockSecond, err := second.lockRecord(recordVideo, "v1")
+		if err != nil {
+			t.Error(err)
+		} else {
+			unlockSecond()
+		}
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("second server took a held record lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second server didn't get the lock after it was released")
+	}
+}
+
+func TestModifyDownloadConcurrent(t *testing.T) {
+	for _, metadata := range []string{MetadataJSON, MetadataBolt} {
+		t.Run(metadata, func(t *testing.T) {
+			m := NewManager(t.TempDir(), zap.NewNop())
+			if err := m.Initialize(); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.OpenMetadata(metadata); err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			if err := m.CreateDownload(&models.Download{ID: "d1"}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Every read-modify-write sees the previous one
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, err := m.ModifyDownload("d1", func(download *models.Download) error {
+						download.RunIDs = append(download.RunIDs, fmt.Sprintf("run-%d", i))
+						return nil
+					})
+					if err != nil {
+						t.Error(err)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			download, err := m.GetDownload("d1")
+			if err != nil || len(download.RunIDs) != 20 {
+				t.Fatalf("GetDownload() = %d run IDs, %v, want 20", len(download.RunIDs), err)
+			}
+			if _, err := m.ModifyDownload("missing", func(*models.Download) error { return nil }); !errors.Is(err, ErrNotFound) {
+				t.Errorf("ModifyDownload() of a missing download = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestRecordLocksPrunedAndKept(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CreateDownload(&models.Download{ID: "d1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DeleteDownload("d1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.recordLocks) != 0 {
+		t.Errorf("record locks after the writes = %d, want 0", len(m.recordLocks))
+	}
+	// Another server may still hold the lock file of a deleted record
+	if !m.FileExists(m.recordLockPath(recordDownload, "d1")) {
+		t.Error("lock file of a deleted record was removed")
+	}
+}
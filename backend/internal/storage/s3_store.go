@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/config"
+	"github.com/mifi/lossless-cut/backend/internal/s3"
+)
+
+// s3PartSize is the part size of multipart uploads, which S3 needs for objects over
+// 5 GB. With at most 10000 parts it allows files up to 640 GB.
+const s3PartSize = 64 << 20
+
+// s3Timeout bounds a whole request, up to a part of a multipart upload or the
+// download of a large object, so a stalled connection doesn't hang an import
+const s3Timeout = time.Hour
+
+// s3Store keeps media as objects in a bucket, below an optional key prefix
+type s3Store struct {
+	cfg    config.S3Config
+	bucket string
+	prefix string
+	client *http.Client
+}
+
+// s3Error is the error document S3 answers failed requests with
+type s3Error struct {
+	XMLName xml.Name
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func newS3Store(cfg config.S3Config, bucket, prefix string) (*s3Store, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("storage.media_bucket must be set to keep media in S3")
+	}
+	if _, err := s3.ObjectURL(cfg, bucket, ""); err != nil {
+		return nil, err
+	}
+	return &s3Store{cfg: cfg, bucket: bucket, prefix: prefix, client: &http.Client{Timeout: s3Timeout}}, nil
+}
+
+// do sends a signed request for key. Bodies are sent unsigned, they're read once.
+// Answers other than 2xx are returned as errors, 404 wrapping os.ErrNotExist.
+func (s *s3Store) do(method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	u, err := s3.ObjectURL(s.cfg, s.bucket, s.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	payloadHash := s3.EmptyPayloadHash
+	if body != nil {
+		req.ContentLength = size
+		if size == 0 {
+			req.Body = http.NoBody
+		}
+		payloadHash = s3.UnsignedPayload
+	}
+	s3.Sign(req, s.cfg, payloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s %s: %w", method, key, os.ErrNotExist)
+	}
+	var doc s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &doc) == nil && doc.Code != "" {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, key, doc.Code, doc.Message)
+	}
+	return nil, fmt.Errorf("%s %s: %s", method, key, resp.Status)
+}
+
+func (s *s3Store) Put(key, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() <= s3PartSize {
+		resp, err := s.do(http.MethodPut, key, nil, io.NopCloser(file), info.Size())
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	return s.putMultipart(key, file, info.Size())
+}
+
+// putMultipart uploads a large file in parts, aborting the upload if a part fails
+// so the bucket doesn't keep them
+func (s *s3Store) putMultipart(key string, file *os.File, size int64) error {
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := s.doXML(http.MethodPost, key, url.Values{"uploads": {""}}, nil, &initiated); err != nil {
+		return err
+	}
+
+	type part struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var complete struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}
+	err := func() error {
+		for offset := int64(0); offset < size; offset += s3PartSize {
+			number := len(complete.Parts) + 1
+			length := size - offset
+			if length > s3PartSize {
+				length = s3PartSize
+			}
+			query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiated.UploadID}}
+			resp, err := s.do(http.MethodPut, key, query, io.NewSectionReader(file, offset, length), length)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			complete.Parts = append(complete.Parts, part{PartNumber: number, ETag: resp.Header.Get("ETag")})
+		}
+
+		body, err := xml.Marshal(complete)
+		if err != nil {
+			return err
+		}
+		return s.doXML(http.MethodPost, key, url.Values{"uploadId": {initiated.UploadID}}, body, nil)
+	}()
+	if err != nil {
+		if resp, abortErr := s.do(http.MethodDelete, key, url.Values{"uploadId": {initiated.UploadID}}, nil, 0); abortErr == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	return nil
+}
+
+// doXML sends a request with an optional XML body and decodes the answer into v.
+// S3 can fail a multipart upload after answering 200, with an error document.
+func (s *s3Store) doXML(method, key string, query url.Values, body []byte, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	resp, err := s.do(method, key, query, reader, int64(len(body)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var doc s3Error
+	if xml.Unmarshal(data, &doc) == nil && doc.XMLName.Local == "Error" {
+		return fmt.Errorf("%s %s: %s: %s", method, key, doc.Code, doc.Message)
+	}
+	if v == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, v)
+}
+
+func (s *s3Store) Get(key, path string) error {
+	resp, err := s.do(http.MethodGet, key, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return writeFileAtomic(path, resp.Body)
+}
+
+func (s *s3Store) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, nil, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
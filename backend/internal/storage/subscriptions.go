@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// SubscriptionsDir returns the feed subscriptions directory path. Subscriptions
+// are settings rather than data, so clearing data keeps them.
+func (m *Manager) SubscriptionsDir() string {
+	return filepath.Join(m.basePath, "subscriptions")
+}
+
+// GetSubscriptionPath returns the path for subscription JSON
+func (m *Manager) GetSubscriptionPath(id string) string {
+	return filepath.Join(m.SubscriptionsDir(), id+".json")
+}
+
+// SaveSubscription stores a feed subscription
+func (m *Manager) SaveSubscription(subscription *models.Subscription) error {
+	data, err := json.MarshalIndent(subscription, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscription: %w", err)
+	}
+
+	if err := os.MkdirAll(m.SubscriptionsDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create subscriptions directory: %w", err)
+	}
+	if err := os.WriteFile(m.GetSubscriptionPath(subscription.ID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write subscription: %w", err)
+	}
+	return nil
+}
+
+// GetSubscription retrieves a feed subscription by ID
+func (m *Manager) GetSubscription(id string) (*models.Subscription, error) {
+	data, err := os.ReadFile(m.GetSubscriptionPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("subscription not found: %s", id)
+		}
+		return nil, fmt.Errorf("failed to read subscription: %w", err)
+	}
+
+	var subscription models.Subscription
+	if err := json.Unmarshal(data, &subscription); err != nil {
+		return nil, fmt.Errorf("failed to parse subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+// ListSubscriptions returns all feed subscriptions
+func (m *Manager) ListSubscriptions() ([]*models.Subscription, error) {
+	entries, err := os.ReadDir(m.SubscriptionsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*models.Subscription{}, nil
+		}
+		return nil, fmt.Errorf("failed to read subscriptions directory: %w", err)
+	}
+
+	subscriptions := make([]*models.Subscription, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		subscription, err := m.GetSubscription(id)
+		if err != nil {
+			m.logger.Warn("Failed to load subscription", zap.String("id", id), zap.Error(err))
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+// DeleteSubscription removes a feed subscription
+func (m *Manager) DeleteSubscription(id string) error {
+	if _, err := m.GetSubscription(id); err != nil {
+		return err
+	}
+	return m.DeleteFile(m.GetSubscriptionPath(id))
+}
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// trashInfoFile holds the TrashEntry of a trash folder
+const trashInfoFile = "trash.json"
+
+// trashIDPattern matches trash entry IDs, which are used as folder names
+var trashIDPattern = regexp.MustCompile(`^\d{8}-\d{6}-\d{3}(-\d+)?$`)
+
+// TrashDir returns the trash directory path
+func (m *Manager) TrashDir() string {
+	return filepath.Join(m.basePath, "trash")
+}
+
+// SetTrashRetention sets how long trashed data can be restored. With 0, clearing
+// deletes data right away.
+func (m *Manager) SetTrashRetention(retention time.Duration) {
+	m.trashMu.Lock()
+	m.trashRetention = retention
+	m.trashMu.Unlock()
+}
+
+// trashEntryPath returns the folder of a trash entry
+func (m *Manager) trashEntryPath(id string) (string, error) {
+	if !trashIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid trash id: %s", id)
+	}
+	return filepath.Join(m.TrashDir(), id), nil
+}
+
+// newTrashEntry creates an empty, timestamped trash folder. Callers hold trashMu.
+func (m *Manager) newTrashEntry(reason string) (*models.TrashEntry, string, error) {
+	now := time.Now()
+	base := now.UTC().Format("20060102-150405") + fmt.Sprintf("-%03d", now.Nanosecond()/int(time.Millisecond))
+
+	id := base
+	for i := 2; m.FileExists(filepath.Join(m.TrashDir(), id)); i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	dir := filepath.Join(m.TrashDir(), id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, "", fmt.Errorf("failed to create trash folder: %w", err)
+	}
+
+	entry := &models.TrashEntry{ID: id, Reason: reason, CreatedAt: now}
+	// Written before anything is moved, so an interrupted clear can still be restored
+	if err := m.saveTrashEntry(dir, entry); err != nil {
+		return nil, "", err
+	}
+	return entry, dir, nil
+}
+
+// saveTrashEntry writes the info file of a trash folder
+func (m *Manager) saveTrashEntry(dir string, entry *models.TrashEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal trash entry: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, trashInfoFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write trash entry: %w", err)
+	}
+	return nil
+}
+
+// moveToTrash moves a file or directory under the base path into a trash folder,
+// keeping its path relative to the base path so it can be restored in place
+func (m *Manager) moveToTrash(dir, path string) error {
+	rel, err := filepath.Rel(m.basePath, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is outside the storage directory", path)
+	}
+
+	target := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create trash folder: %w", err)
+	}
+	if err := os.Rename(path, target); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to move %s to trash: %w", path, err)
+	}
+	return nil
+}
+
+// trashContents counts the files in a trash folder and their total size
+func trashContents(dir string) (int, int64) {
+	files := 0
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path == filepath.Join(dir, trashInfoFile) {
+			return nil
+		}
+		files++
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size
+}
+
+// finishTrashEntry records what a trash folder holds. Without retention, or when
+// nothing was trashed, the folder is deleted and nil is returned.
+func (m *Manager) finishTrashEntry(dir string, entry *models.TrashEntry) (*models.TrashEntry, error) {
+	entry.Files, entry.Size = trashContents(dir)
+	if m.trashRetention <= 0 || entry.Files == 0 {
+		m.deleteTrashedMedia(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, fmt.Errorf("failed to delete trashed data: %w", err)
+		}
+		return nil, nil
+	}
+
+	entry.ExpiresAt = entry.CreatedAt.Add(m.trashRetention)
+	if err := m.saveTrashEntry(dir, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListTrash returns the trash entries, newest first
+func (m *Manager) ListTrash() ([]*models.TrashEntry, error) {
+	m.trashMu.Lock()
+	defer m.trashMu.Unlock()
+
+	entries, err := os.ReadDir(m.TrashDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*models.TrashEntry{}, nil
+		}
+		return nil, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+
+	trash := make([]*models.TrashEntry, 0)
+	for _, dirEntry := range entries {
+		if !dirEntry.IsDir() || !trashIDPattern.MatchString(dirEntry.Name()) {
+			continue
+		}
+		trash = append(trash, m.loadTrashEntry(dirEntry.Name()))
+	}
+
+	sort.Slice(trash, func(i, j int) bool {
+		return trash[i].CreatedAt.After(trash[j].CreatedAt)
+	})
+	return trash, nil
+}
+
+// loadTrashEntry reads the info file of a trash folder, falling back to the folder
+// itself if a clear was interrupted before writing it
+func (m *Manager) loadTrashEntry(id string) *models.TrashEntry {
+	dir := filepath.Join(m.TrashDir(), id)
+
+	var entry models.TrashEntry
+	data, err := os.ReadFile(filepath.Join(dir, trashInfoFile))
+	if err != nil || json.Unmarshal(data, &entry) != nil {
+		entry = models.TrashEntry{ID: id, Reason: "unknown"}
+		if info, err := os.Stat(dir); err == nil {
+			entry.CreatedAt = info.ModTime()
+		}
+		entry.Files, entry.Size = trashContents(dir)
+	}
+	entry.ID = id
+	entry.ExpiresAt = entry.CreatedAt.Add(m.trashRetention)
+	return &entry
+}
+
+// RestoreTrash moves the files of a trash entry back to where they were. Files whose
+// original path has been reused since are left in the trash and returned as skipped.
+func (m *Manager) RestoreTrash(id string) (int, []string, error) {
+	m.trashMu.Lock()
+	defer m.trashMu.Unlock()
+
+	dir, err := m.trashEntryPath(id)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !m.FileExists(dir) {
+		return 0, nil, fmt.Errorf("trash entry not found: %s", id)
+	}
+
+	restored := 0
+	skipped := []string{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path == filepath.Join(dir, trashInfoFile) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		target := filepath.Join(m.basePath, rel)
+		if m.FileExists(target) {
+			skipped = append(skipped, rel)
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(path, target); err != nil {
+			return err
+		}
+		restored++
+		return nil
+	})
+	if err != nil {
+		return restored, skipped, fmt.Errorf("failed to restore trash entry: %w", err)
+	}
+
+	// Restored records are JSON files, the database takes them in
+	if _, err := m.importRecordFiles(""); err != nil {
+		return restored, skipped, fmt.Errorf("failed to restore records: %w", err)
+	}
+
+	if len(skipped) == 0 {
+		if err := os.RemoveAll(dir); err != nil {
+			m.logger.Warn("Failed to remove restored trash folder", zap.String("id", id), zap.Error(err))
+		}
+	} else {
+		entry := m.loadTrashEntry(id)
+		entry.Files, entry.Size = trashContents(dir)
+		m.saveTrashEntry(dir, entry)
+	}
+
+	m.logger.Info("Restored trash entry",
+		zap.String("id", id),
+		zap.Int("restored", restored),
+		zap.Int("skipped", len(skipped)),
+	)
+	return restored, skipped, nil
+}
+
+// DeleteTrash permanently deletes a trash entry
+func (m *Manager) DeleteTrash(id string) error {
+	m.trashMu.Lock()
+	defer m.trashMu.Unlock()
+
+	dir, err := m.trashEntryPath(id)
+	if err != nil {
+		return err
+	}
+	if !m.FileExists(dir) {
+		return fmt.Errorf("trash entry not found: %s", id)
+	}
+	m.deleteTrashedMedia(dir)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete trash entry: %w", err)
+	}
+	return nil
+}
+
+// PurgeTrash permanently deletes trash entries older than the retention window and
+// returns how many were deleted
+func (m *Manager) PurgeTrash() (int, error) {
+	trash, err := m.ListTrash()
+	if err != nil {
+		return 0, err
+	}
+
+	m.trashMu.Lock()
+	defer m.trashMu.Unlock()
+
+	purged := 0
+	now := time.Now()
+	for _, entry := range trash {
+		if now.Before(entry.ExpiresAt) {
+			continue
+		}
+		dir := filepath.Join(m.TrashDir(), entry.ID)
+		m.deleteTrashedMedia(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			m.logger.Warn("Failed to purge trash entry", zap.String("id", entry.ID), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		m.logger.Info("Purged expired trash", zap.Int("count", purged))
+	}
+	return purged, nil
+}
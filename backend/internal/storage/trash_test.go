@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestClearEverythingTrash(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	m.SetTrashRetention(24 * time.Hour)
+
+	videoPath := filepath.Join(m.UploadsDir(), "video1.mp4")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+	if err := m.SaveVideo(&models.Video{ID: "v1", FilePath: videoPath}); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := m.ClearEverything()
+	if err != nil || entry == nil {
+		t.Fatalf("ClearEverything() = %v, %v", entry, err)
+	}
+	if entry.Files != 2 || m.FileExists(videoPath) {
+		t.Fatalf("ClearEverything() trashed %d files, video still present: %v", entry.Files, m.FileExists(videoPath))
+	}
+
+	// A file created at a trashed path since is kept, the rest is restored
+	os.WriteFile(videoPath, []byte("new"), 0644)
+	restored, skipped, err := m.RestoreTrash(entry.ID)
+	if err != nil || restored != 1 || len(skipped) != 1 {
+		t.Fatalf("RestoreTrash() = %d, %v, %v", restored, skipped, err)
+	}
+	if _, err := m.GetVideo("v1"); err != nil {
+		t.Errorf("video metadata not restored: %v", err)
+	}
+
+	trash, _ := m.ListTrash()
+	if len(trash) != 1 || trash[0].Files != 1 {
+		t.Fatalf("ListTrash() after partial restore = %+v", trash)
+	}
+
+	// Without retention the trash is purged and clearing deletes right away
+	m.SetTrashRetention(0)
+	if purged, _ := m.PurgeTrash(); purged != 1 {
+		t.Errorf("PurgeTrash() = %d, want 1", purged)
+	}
+	if entry, err := m.ClearEverything(); err != nil || entry != nil {
+		t.Errorf("ClearEverything() without retention = %v, %v", entry, err)
+	}
+	if _, _, err := m.RestoreTrash("../../etc"); err == nil {
+		t.Error("RestoreTrash() with a path as id: want error")
+	}
+}
+
+func TestTrashOldMedia(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	m.SetTrashRetention(24 * time.Hour)
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -10)
+	cutoff := now.AddDate(0, 0, -7)
+	write := func(path string, modTime time.Time) string {
+		os.WriteFile(path, []byte("data"), 0644)
+		os.Chtimes(path, modTime, modTime)
+		return path
+	}
+
+	oldVideo := write(m.GetVideoPath("video1.mp4"), old)
+	editedVideo := write(m.GetVideoPath("video2.mp4"), old)
+	newVideo := write(m.GetVideoPath("video3.mp4"), now)
+	oldOutput := write(m.GetOutputPath("video1_cut.mp4"), old)
+	newOutput := write(m.GetOutputPath("video3_cut.mp4"), now)
+	for _, video := range []*models.Video{
+		{ID: "old", FilePath: oldVideo, CreatedAt: old},
+		{ID: "edited", FilePath: editedVideo, CreatedAt: old},
+		{ID: "new", FilePath: newVideo, CreatedAt: now},
+	} {
+		if err := m.SaveVideo(video); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, project := range []*models.Project{
+		{ID: "p1", VideoID: "old", UpdatedAt: old},
+		{ID: "p2", VideoID: "edited", UpdatedAt: old},
+		{ID: "p3", VideoID: "edited", UpdatedAt: now},
+	} {
+		if err := m.SaveProject(project); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entry, err := m.TrashOldMedia(cutoff)
+	if err != nil || entry == nil {
+		t.Fatalf("TrashOldMedia() = %v, %v", entry, err)
+	}
+	if _, err := m.GetVideo("old"); err == nil {
+		t.Error("old video was kept")
+	}
+	if _, err := m.GetProject("p1"); err == nil {
+		t.Error("project of the old video was kept")
+	}
+	for _, path := range []string{oldVideo, oldOutput} {
+		if m.FileExists(path) {
+			t.Errorf("%s was kept", filepath.Base(path))
+		}
+	}
+	for _, path := range []string{editedVideo, newVideo, newOutput} {
+		if !m.FileExists(path) {
+			t.Errorf("%s was trashed", filepath.Base(path))
+		}
+	}
+	if _, err := m.GetProject("p2"); err != nil {
+		t.Errorf("old project of a video in use was trashed: %v", err)
+	}
+
+	// Cleaned up media can be restored
+	if restored, _, err := m.RestoreTrash(entry.ID); err != nil || restored == 0 {
+		t.Fatalf("RestoreTrash() = %d, %v", restored, err)
+	}
+	if _, err := m.GetVideo("old"); err != nil || !m.FileExists(oldVideo) {
+		t.Errorf("old video not restored: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// usageRetentionDays is how long daily usage counters are kept
+const usageRetentionDays = 400
+
+// usageDateFormat is the day key of the usage counters
+const usageDateFormat = "2006-01-02"
+
+// usageFile returns the path of the persisted usage counters. It lives outside the
+// data directories, so clearing data keeps the history.
+func (m *Manager) usageFile() string {
+	return filepath.Join(m.basePath, "usage.json")
+}
+
+// loadUsage reads the daily counters by date. Callers hold usageMu.
+func (m *Manager) loadUsage() (map[string]map[models.UsageKind]models.UsageCounter, error) {
+	usage := make(map[string]map[models.UsageKind]models.UsageCounter)
+
+	data, err := os.ReadFile(m.usageFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return usage, nil
+		}
+		return nil, fmt.Errorf("failed to read usage: %w", err)
+	}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("failed to parse usage: %w", err)
+	}
+	return usage, nil
+}
+
+// RecordUsage adds activity to today's counters. Failures are logged, usage
+// tracking never fails the operation being tracked.
+func (m *Manager) RecordUsage(kind models.UsageKind, count int, bytes int64) {
+	if count <= 0 {
+		return
+	}
+
+	m.usageMu.Lock()
+	defer m.usageMu.Unlock()
+
+	usage, err := m.loadUsage()
+	if err != nil {
+		m.logger.Warn("Failed to record usage", zap.Error(err))
+		return
+	}
+
+	now := time.Now().UTC()
+	today := now.Format(usageDateFormat)
+	if usage[today] == nil {
+		usage[today] = make(map[models.UsageKind]models.UsageCounter)
+	}
+	counter := usage[today][kind]
+	counter.Count += int64(count)
+	counter.Bytes += bytes
+	usage[today][kind] = counter
+
+	// Drop days past the retention; dates compare correctly as strings
+	oldest := now.AddDate(0, 0, -usageRetentionDays).Format(usageDateFormat)
+	for date := range usage {
+		if date < oldest {
+			delete(usage, date)
+		}
+	}
+
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err == nil {
+		err = os.WriteFile(m.usageFile(), data, 0644)
+	}
+	if err != nil {
+		m.logger.Warn("Failed to save usage", zap.Error(err))
+	}
+}
+
+// RecordDeletedVideos adds deleted or trashed videos to today's deletions, with the
+// size of their files. Imported files stay in their directory and free nothing.
+func (m *Manager) RecordDeletedVideos(videos ...*models.Video) {
+	var bytes int64
+	for _, video := range videos {
+		if !video.External {
+			bytes += video.FileSize
+		}
+	}
+	m.RecordUsage(models.UsageDeletions, len(videos), bytes)
+}
+
+// UsageHistory returns the counters of the last days, oldest first. Days without
+// activity are included with empty counters so trends can be plotted directly.
+func (m *Manager) UsageHistory(days int) ([]models.UsageDay, error) {
+	m.usageMu.Lock()
+	usage, err := m.loadUsage()
+	m.usageMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	today := time.Now().UTC()
+	history := make([]models.UsageDay, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format(usageDateFormat)
+		counters := usage[date]
+		if counters == nil {
+			counters = map[models.UsageKind]models.UsageCounter{}
+		}
+		history = append(history, models.UsageDay{Date: date, Counters: counters})
+	}
+	return history, nil
+}
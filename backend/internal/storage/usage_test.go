@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestUsageHistory(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+
+	m.RecordUsage(models.UsageUploads, 1, 100)
+	m.RecordUsage(models.UsageUploads, 1, 50)
+	m.RecordUsage(models.UsageExports, 1, 10)
+	m.RecordUsage(models.UsageDeletions, 0, 999) // Nothing deleted, not recorded
+
+	history, err := m.UsageHistory(7)
+	if err != nil {
+		t.Fatalf("UsageHistory() error = %v", err)
+	}
+	if len(history) != 7 {
+		t.Fatalf("UsageHistory(7) returned %d days", len(history))
+	}
+	if len(history[0].Counters) != 0 {
+		t.Errorf("day without activity has counters: %+v", history[0])
+	}
+
+	today := history[6].Counters
+	if today[models.UsageUploads] != (models.UsageCounter{Count: 2, Bytes: 150}) {
+		t.Errorf("uploads today = %+v, want 2 files, 150 bytes", today[models.UsageUploads])
+	}
+	if _, ok := today[models.UsageDeletions]; ok {
+		t.Error("empty deletion was recorded")
+	}
+}
+
+func TestRecordDeletedVideos(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := m.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Imported files aren't deleted, only their video counts
+	m.RecordDeletedVideos(&models.Video{ID: "v1", FileSize: 100}, &models.Video{ID: "v2", FileSize: 50, External: true})
+
+	// Clearing counts the trashed videos, not the files trashed with them
+	videoPath := filepath.Join(m.UploadsDir(), "v3.mp4")
+	os.WriteFile(videoPath, []byte("video"), 0644)
+	if err := m.SaveVideo(&models.Video{ID: "v3", FilePath: videoPath, FileSize: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ClearEverything(); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := m.UsageHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := history[0].Counters[models.UsageDeletions]; got != (models.UsageCounter{Count: 3, Bytes: 105}) {
+		t.Errorf("deletions today = %+v, want 3 videos, 105 bytes", got)
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+)
+
+// Kinds of a video's cached waveform files, one of each per audio stream and
+// channel layout
+const (
+	WaveformImage       = ".png"
+	WaveformSpectrogram = ".spectrogram.png"
+	WaveformPeaks       = ".peaks.json"
+)
+
+// waveformKinds are all kinds of cached waveform files
+var waveformKinds = []string{WaveformImage, WaveformSpectrogram, WaveformPeaks}
+
+// GetVideoWaveformPath returns the cache path of a video's waveform file of a kind,
+// for an audio stream (index among the audio streams) and channel layout
+func (m *Manager) GetVideoWaveformPath(videoID string, audioStream int, split bool, kind string) string {
+	key := videoID
+	if audioStream > 0 {
+		key += fmt.Sprintf("_a%d", audioStream)
+	}
+	if split {
+		key += "_split"
+	}
+	return m.GetWaveformPath(key + kind)
+}
+
+// DeleteWaveforms removes a video's cached waveforms, spectrograms and peaks, e.g.
+// once it is deleted or its file changes. Every path its audio streams can have is
+// deleted, so copies kept in the media store go too.
+func (m *Manager) DeleteWaveforms(video *models.Video) error {
+	audioStreams := 0
+	for _, stream := range video.Metadata.Streams {
+		if stream.CodecType == "audio" {
+			audioStreams++
+		}
+	}
+
+	var firstErr error
+	for audioStream := 0; audioStream < audioStreams; audioStream++ {
+		for _, split := range []bool{false, true} {
+			for _, kind := range waveformKinds {
+				path := m.GetVideoWaveformPath(video.ID, audioStream, split, kind)
+				if err := m.DeleteFile(path); err != nil && firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+	return firstErr
+}
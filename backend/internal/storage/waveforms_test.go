@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mifi/lossless-cut/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+func TestGetVideoWaveformPath(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+
+	tests := []struct {
+		audioStream int
+		split       bool
+		kind        string
+		want        string
+	}{
+		{0, false, WaveformImage, "v1.png"},
+		{1, false, WaveformSpectrogram, "v1_a1.spectrogram.png"},
+		{0, true, WaveformImage, "v1_split.png"},
+		{2, true, WaveformPeaks, "v1_a2_split.peaks.json"},
+	}
+	for _, tt := range tests {
+		got := m.GetVideoWaveformPath("v1", tt.audioStream, tt.split, tt.kind)
+		if got != filepath.Join(m.WaveformsDir(), tt.want) {
+			t.Errorf("GetVideoWaveformPath(%d, %v, %q) = %q, want %q", tt.audioStream, tt.split, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestDeleteWaveforms(t *testing.T) {
+	m := NewManager(t.TempDir(), zap.NewNop())
+	if err := os.MkdirAll(m.WaveformsDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	video := &models.Video{ID: "v1"}
+	video.Metadata.Streams = []models.Stream{{CodecType: "video"}, {CodecType: "audio"}, {CodecType: "audio"}}
+	cached := []string{
+		m.GetVideoWaveformPath("v1", 0, false, WaveformImage),
+		m.GetVideoWaveformPath("v1", 1, true, WaveformImage),
+		m.GetVideoWaveformPath("v1", 1, false, WaveformSpectrogram),
+		m.GetVideoWaveformPath("v1", 0, false, WaveformPeaks),
+	}
+	other := m.GetVideoWaveformPath("v2", 0, false, WaveformImage)
+	for _, path := range append(cached, other) {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.DeleteWaveforms(video); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range cached {
+		if m.FileExists(path) {
+			t.Errorf("%s wasn't deleted", filepath.Base(path))
+		}
+	}
+	if !m.FileExists(other) {
+		t.Error("waveform of another video was deleted")
+	}
+}
@@ -0,0 +1,146 @@
+package subtitles
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Cue is a single subtitle entry
+type Cue struct {
+	Start float64 // Seconds
+	End   float64 // Seconds
+	Text  string  // May contain newlines
+}
+
+// Range is a part of the source timeline placed at Offset in the output timeline
+type Range struct {
+	Start  float64
+	End    float64
+	Offset float64
+}
+
+var timingPattern = regexp.MustCompile(`(\d+):(\d{2}):(\d{2})[,.](\d{1,3})\s*-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{1,3})`)
+
+// ParseSRT parses SubRip subtitles. Malformed blocks are skipped.
+func ParseSRT(data string) []Cue {
+	data = strings.TrimPrefix(data, "\ufeff")
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+
+	var cues []Cue
+	var current *Cue
+	var text []string
+
+	flush := func() {
+		if current != nil {
+			current.Text = strings.Join(text, "\n")
+			cues = append(cues, *current)
+		}
+		current = nil
+		text = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+
+		if matches := timingPattern.FindStringSubmatch(line); matches != nil {
+			flush()
+			current = &Cue{
+				Start: parseTimestamp(matches[1:5]),
+				End:   parseTimestamp(matches[5:9]),
+			}
+			continue
+		}
+
+		if current == nil {
+			continue // Cue number or garbage before the timing line
+		}
+
+		if line == "" {
+			flush()
+			continue
+		}
+
+		text = append(text, line)
+	}
+	flush()
+
+	return cues
+}
+
+// FormatSRT renders cues as SubRip subtitles, numbering them from 1
+func FormatSRT(cues []Cue) string {
+	var b strings.Builder
+	for i, cue := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, formatTimestamp(cue.Start, ","), formatTimestamp(cue.End, ","), cue.Text)
+	}
+	return b.String()
+}
+
+// FormatVTT renders cues as WebVTT subtitles
+func FormatVTT(cues []Cue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", formatTimestamp(cue.Start, "."), formatTimestamp(cue.End, "."), cue.Text)
+	}
+	return b.String()
+}
+
+// Clip keeps the parts of cues that fall inside the ranges and moves them to
+// each range's offset, so subtitles match an output made of those ranges
+func Clip(cues []Cue, ranges []Range) []Cue {
+	var result []Cue
+	for _, r := range ranges {
+		for _, cue := range cues {
+			if cue.End <= r.Start || cue.Start >= r.End {
+				continue
+			}
+
+			start := cue.Start
+			if start < r.Start {
+				start = r.Start
+			}
+			end := cue.End
+			if end > r.End {
+				end = r.End
+			}
+
+			result = append(result, Cue{
+				Start: start - r.Start + r.Offset,
+				End:   end - r.Start + r.Offset,
+				Text:  cue.Text,
+			})
+		}
+	}
+	return result
+}
+
+// parseTimestamp converts hours, minutes, seconds and milliseconds strings to seconds
+func parseTimestamp(parts []string) float64 {
+	hours, _ := strconv.Atoi(parts[0])
+	minutes, _ := strconv.Atoi(parts[1])
+	seconds, _ := strconv.Atoi(parts[2])
+	// Pad fractional part so "5" means 500ms
+	millis, _ := strconv.Atoi((parts[3] + "00")[:3])
+
+	return float64(hours*3600+minutes*60+seconds) + float64(millis)/1000
+}
+
+// formatTimestamp renders seconds as HH:MM:SS<sep>mmm
+func formatTimestamp(t float64, sep string) string {
+	if t < 0 {
+		t = 0
+	}
+	totalMillis := int64(t*1000 + 0.5)
+	hours := totalMillis / 3600000
+	minutes := (totalMillis % 3600000) / 60000
+	seconds := (totalMillis % 60000) / 1000
+	millis := totalMillis % 1000
+
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", hours, minutes, seconds, sep, millis)
+}
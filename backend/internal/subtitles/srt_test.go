@@ -0,0 +1,51 @@
+package subtitles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSRT(t *testing.T) {
+	input := "\ufeff1\r\n00:00:01,000 --> 00:00:02,500\r\nHello\r\nworld\r\n\r\n2\r\n00:01:00.5 --> 00:01:02,000\r\nSecond\r\n"
+
+	want := []Cue{
+		{Start: 1, End: 2.5, Text: "Hello\nworld"},
+		{Start: 60.5, End: 62, Text: "Second"},
+	}
+
+	if got := ParseSRT(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSRT() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFormatSRT(t *testing.T) {
+	cues := []Cue{{Start: 3661.25, End: 3662, Text: "Hi"}}
+	want := "1\n01:01:01,250 --> 01:01:02,000\nHi\n\n"
+
+	if got := FormatSRT(cues); got != want {
+		t.Errorf("FormatSRT() = %q, want %q", got, want)
+	}
+}
+
+func TestClip(t *testing.T) {
+	cues := []Cue{
+		{Start: 1, End: 3, Text: "before"},
+		{Start: 9, End: 12, Text: "overlaps start"},
+		{Start: 14, End: 15, Text: "inside"},
+		{Start: 30, End: 31, Text: "second range"},
+	}
+	ranges := []Range{
+		{Start: 10, End: 20, Offset: 0},
+		{Start: 28, End: 32, Offset: 10},
+	}
+
+	want := []Cue{
+		{Start: 0, End: 2, Text: "overlaps start"},
+		{Start: 4, End: 5, Text: "inside"},
+		{Start: 12, End: 13, Text: "second range"},
+	}
+
+	if got := Clip(cues, ranges); !reflect.DeepEqual(got, want) {
+		t.Errorf("Clip() = %+v, want %+v", got, want)
+	}
+}
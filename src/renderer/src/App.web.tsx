@@ -97,10 +97,14 @@ export default function App() {
   };
 
   const handleClearAll = async () => {
-    if (!confirm('DELETE ALL DATA?\n\nThis cannot be undone!')) return;
+    if (!confirm('DELETE ALL DATA?\n\nIt is moved to the trash and can be restored until the trash retention expires.')) return;
     setIsClearing(true);
     try {
-      await fetch('/api/system/clear-all', { method: 'DELETE' });
+      const response = await fetch('/api/system/clear-all', { method: 'DELETE' });
+      if (!response.ok) {
+        const data = await response.json().catch(() => ({}));
+        throw new Error(data.error || `HTTP ${response.status}`);
+      }
       loadStats();
     } catch (error: any) {
       alert(`Failed: ${error.message}`);
@@ -455,6 +459,7 @@ export default function App() {
         isOpen={showDownloadModal}
         onClose={() => setShowDownloadModal(false)}
         onDownloadComplete={handleDownloadComplete}
+        sessionId={sessionIdRef.current}
       />
 
       {showEditor && (
@@ -11,9 +11,30 @@ export interface Video {
   height: number;
   codec: string;
   format: string;
+  rotation?: number;
+  warnings?: MediaWarning[];
+  external?: boolean; // Imported in place from a server directory
+  source?: VideoSource;
   created_at: string;
 }
 
+// What the site a video was downloaded from reports about it
+export interface VideoSource {
+  webpage_url?: string;
+  uploader?: string;
+  upload_date?: string; // YYYY-MM-DD
+  description?: string;
+  thumbnail?: string;
+  thumbnail_path?: string; // Set when the poster was downloaded, see getPosterUrl
+}
+
+export interface MediaWarning {
+  code: 'variable_frame_rate' | 'missing_duration' | 'data_stream';
+  stream?: number;
+  message: string;
+  suggestion: 'smart_cut' | 'remux';
+}
+
 export interface Project {
   id: string;
   name: string;
@@ -38,23 +59,185 @@ export interface Download {
   url: string;
   title?: string;
   duration?: number;
-  status: 'pending' | 'downloading' | 'completed' | 'failed' | 'cancelled';
+  status: 'scheduled' | 'queued' | 'pending' | 'downloading' | 'retrying' | 'completed' | 'failed' | 'cancelled' | 'interrupted';
   progress: number;
   file_path?: string;
   video_id?: string;
+  video_ids?: string[];
+  playlist?: boolean;
+  child_ids?: string[]; // Item downloads of a playlist
+  parent_id?: string;
+  subtitles?: DownloadSubtitles;
+  sponsorblock?: string[]; // SponsorBlock categories left out of the created project
+  chapter_project?: boolean;
+  create_project?: boolean;
+  project_name?: string;
+  project_ids?: string[]; // Projects created from SponsorBlock segments, chapters or create_project
+  rate_limit?: string;
+  downloaded_bytes?: number;
+  total_bytes?: number;
+  speed?: number; // Bytes per second while downloading
+  eta?: number; // Seconds left while downloading
+  priority?: number;
+  queue_position?: number; // 1-based, while queued
+  attempts?: { error: string; failed_at: string }[]; // Failed attempts that were retried
+  next_retry_at?: string;
+  start_at?: string; // When a scheduled download starts or a recurring one runs next
+  cron?: string;
+  run_ids?: string[]; // Downloads started by a recurring schedule
+  schedule_id?: string;
+  section?: DownloadSection;
+  sha256?: string; // Expected checksum of a direct download
+  md5?: string;
   error?: string;
   created_at: string;
   updated_at: string;
 }
 
+// Time range of a video to download, in seconds
+export interface DownloadSection {
+  start: number;
+  end?: number; // Default: to the end
+  accurate?: boolean; // Cut exactly instead of at the keyframe before start
+}
+
+// Subtitles downloaded with a video and attached to it
+export interface DownloadSubtitles {
+  languages?: string[]; // e.g. ['en', 'de'] or ['all'], default English
+  auto?: boolean; // Include automatically generated subtitles
+}
+
+// Client settings for sites that block the default downloader
+export interface DownloadClient {
+  impersonate?: string; // yt-dlp target, e.g. 'chrome'
+  user_agent?: string;
+  headers?: Record<string, string>;
+  cookies_from_browser?: string; // Browser on the server, e.g. 'firefox' (admin only)
+  extra_args?: string[]; // Allowlisted yt-dlp options, e.g. ['--extractor-args', 'youtube:player_client=android']
+  cookies?: string; // Netscape cookies.txt, stored encrypted until the download ends
+}
+
+export interface FFmpegCodec {
+  name: string;
+  type: 'video' | 'audio' | 'subtitle' | 'data';
+  description: string;
+}
+
+// What the server's FFmpeg build can do, to hide export options it can't perform
+export interface FFmpegCapabilities {
+  ffmpeg_version: string;
+  ffprobe_version: string;
+  encoders: FFmpegCodec[];
+  decoders: FFmpegCodec[];
+  hwaccels: string[];
+  filters: string[];
+}
+
+export interface YtdlpInfo {
+  path: string;
+  version: string;
+  managed: boolean; // Installed into the storage directory by an update
+}
+
+export interface YtdlpUpdate {
+  previous: string;
+  version: string;
+  method: 'self-update' | 'download';
+  output?: string;
+}
+
+// Min/max audio peaks (-1 to 1) of equal parts of a video
+export interface WaveformPeaks {
+  zoom: number;
+  levels: number[];
+  duration: number;
+  min: number[];
+  max: number[];
+}
+
+export interface Thumbnail {
+  time: number;
+  url: string;
+}
+
+// Filmstrip of evenly spaced frames
+export interface ThumbnailSet {
+  interval: number;
+  width: number;
+  thumbnails: Thumbnail[];
+}
+
+// Subtitle file extracted to the outputs directory
+export interface ExtractedSubtitle {
+  file: string;
+  url: string;
+  cues: number;
+}
+
+// Image uploaded to a video, referenced by exports' watermark option
+export interface Watermark {
+  id: string;
+  file_name: string;
+}
+
+// Keyframes around a timestamp, for snapping cut points
+export interface KeyframeSnap {
+  time: number;
+  previous?: number;
+  next?: number;
+  nearest?: number;
+}
+
+// Range found by scene, black frame or silence detection
+export interface DetectedScene {
+  start: number;
+  end: number;
+  duration: number;
+  type: 'cut' | 'black' | 'silent';
+  confidence?: number;
+}
+
+export interface DetectionOptions {
+  mode?: 'scene' | 'black' | 'silence' | 'all';
+  preset?: string;
+  threshold?: number;
+  min_scene_length?: number;
+  min_duration?: number;
+  noise_db?: number;
+  project_id?: string; // Add the detected ranges to this project as segments
+  apply?: 'append' | 'replace';
+}
+
+export interface DetectionResult {
+  video_id: string;
+  mode: string;
+  scenes: DetectedScene[];
+  cached: boolean;
+  segments?: Segment[];
+}
+
+export interface LoudnessAnalysis {
+  start: number;
+  end?: number;
+  integrated: number;
+  true_peak: number;
+  range: number;
+  threshold: number;
+}
+
 export interface Operation {
   id: string;
   type: string;
   project_id: string;
-  status: 'pending' | 'processing' | 'completed' | 'failed';
+  status: 'pending' | 'processing' | 'completed' | 'completed_with_warnings' | 'failed' | 'interrupted' | 'cancelled';
   progress: number;
+  speed?: number; // Encoding speed, multiple of realtime
+  eta?: number; // Seconds remaining
   output_files?: string[];
+  manifest?: string; // Export manifest describing the output files
   error?: string;
+  warnings?: string[];
+  plan?: { args: string[]; concat_list?: string }[]; // Dry run exports only
   created_at: string;
   completed_at?: string;
 }
@@ -71,11 +254,67 @@ class ApiClient {
     return response.json();
   }
 
-  async startDownload(url: string, format = 'best'): Promise<Download> {
+  // Register a file in one of the server's import directories without uploading it
+  async importVideo(path: string) {
+    const response = await fetch('/api/videos/import', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ path }),
+    });
+    if (!response.ok) throw new Error('Import failed');
+    return response.json();
+  }
+
+  async uploadWatermark(videoId: string, file: File): Promise<Watermark> {
+    const formData = new FormData();
+    formData.append('file', file);
+    const response = await fetch(`/api/videos/${videoId}/watermarks`, {
+      method: 'POST',
+      body: formData,
+    });
+    if (!response.ok) throw new Error('Watermark upload failed');
+    return response.json();
+  }
+
+  async startDownload(
+    url: string,
+    format = 'best',
+    sessionId?: string,
+    client?: DownloadClient,
+    playlist = false,
+    subtitles?: DownloadSubtitles,
+    sponsorblockCategories?: string[],
+    chapterProject = false,
+    rateLimit?: string, // e.g. '2M' bytes per second, can't exceed the server's cap
+    priority = 0, // Higher leaves the queue first
+    schedule?: { startAt?: Date; cron?: string }, // Start later, or repeatedly on a cron schedule
+    section?: DownloadSection,
+    project?: { name?: string }, // Create a project for each downloaded video, named from a template like '{uploader} - {title}'
+    checksum?: { sha256?: string; md5?: string }, // Expected checksum of a direct download
+  ): Promise<Download> {
     const response = await fetch('/api/downloads', {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({ url, format }),
+      body: JSON.stringify({
+        url,
+        format,
+        session_id: sessionId,
+        playlist,
+        subtitles,
+        sponsorblock: sponsorblockCategories !== undefined,
+        sponsorblock_categories: sponsorblockCategories?.length ? sponsorblockCategories : undefined,
+        chapter_project: chapterProject,
+        rate_limit: rateLimit,
+        priority,
+        start_at: schedule?.startAt?.toISOString(),
+        cron: schedule?.cron,
+        section,
+        create_project: project !== undefined,
+        project_name: project?.name,
+        sha256: checksum?.sha256,
+        md5: checksum?.md5,
+        ...client,
+      }),
     });
     if (!response.ok) throw new Error('Download start failed');
     return response.json();
@@ -87,6 +326,12 @@ class ApiClient {
     return response.json();
   }
 
+  async resumeDownload(id: string): Promise<Download> {
+    const response = await fetch(`/api/downloads/${id}/resume`, { method: 'POST' });
+    if (!response.ok) throw new Error('Failed to resume download');
+    return response.json();
+  }
+
   async listDownloads() {
     const response = await fetch('/api/downloads');
     if (!response.ok) throw new Error('Failed to list downloads');
@@ -137,10 +382,209 @@ class ApiClient {
     return response.json();
   }
 
+  async cancelOperation(operationId: string): Promise<Operation> {
+    const response = await fetch(`/api/operations/${operationId}/cancel`, { method: 'POST' });
+    if (!response.ok) throw new Error('Failed to cancel operation');
+    return response.json();
+  }
+
+  async getFFmpegCapabilities(): Promise<FFmpegCapabilities> {
+    const response = await fetch('/api/system/ffmpeg');
+    if (!response.ok) throw new Error('Failed to get FFmpeg capabilities');
+    return response.json();
+  }
+
+  async getYtdlpInfo(): Promise<YtdlpInfo> {
+    const response = await fetch('/api/system/ytdlp');
+    if (!response.ok) throw new Error('Failed to get yt-dlp version');
+    return response.json();
+  }
+
+  async updateYtdlp(): Promise<YtdlpUpdate> {
+    const response = await fetch('/api/system/ytdlp/update', { method: 'POST' });
+    if (!response.ok) throw new Error('Failed to update yt-dlp');
+    return response.json();
+  }
+
+  getWaveformUrl(videoId: string, options: { stream?: number; splitChannels?: boolean } = {}): string {
+    return this.audioImageUrl(videoId, 'waveform', options);
+  }
+
+  getSpectrogramUrl(videoId: string, options: { stream?: number; splitChannels?: boolean } = {}): string {
+    return this.audioImageUrl(videoId, 'spectrogram', options);
+  }
+
+  private audioImageUrl(videoId: string, kind: string, options: { stream?: number; splitChannels?: boolean }): string {
+    const params = new URLSearchParams();
+    if (options.stream) params.set('stream', String(options.stream));
+    if (options.splitChannels) params.set('split_channels', 'true');
+    const query = params.toString();
+    return `/api/videos/${videoId}/${kind}${query ? `?${query}` : ''}`;
+  }
+
+  async getWaveformPeaks(videoId: string, zoom = 1000, stream = 0): Promise<WaveformPeaks> {
+    const response = await fetch(`/api/videos/${videoId}/waveform?format=json&zoom=${zoom}&stream=${stream}`);
+    if (!response.ok) throw new Error('Failed to get waveform');
+    return response.json();
+  }
+
+  getPosterUrl(videoId: string): string {
+    return `/api/videos/${videoId}/poster`;
+  }
+
+  async getThumbnails(videoId: string, options: { count?: number; interval?: number; width?: number } = {}): Promise<ThumbnailSet> {
+    const params = new URLSearchParams();
+    Object.entries(options).forEach(([key, value]) => {
+      if (value !== undefined) params.set(key, String(value));
+    });
+    const response = await fetch(`/api/videos/${videoId}/thumbnails?${params}`);
+    if (!response.ok) throw new Error('Failed to get thumbnails');
+    return response.json();
+  }
+
+  async getKeyframes(videoId: string): Promise<number[]> {
+    const response = await fetch(`/api/videos/${videoId}/keyframes`);
+    if (!response.ok) throw new Error('Failed to get keyframes');
+    return (await response.json()).keyframes;
+  }
+
+  async snapToKeyframe(videoId: string, time: number): Promise<KeyframeSnap> {
+    const response = await fetch(`/api/videos/${videoId}/keyframes?near=${time}`);
+    if (!response.ok) throw new Error('Failed to snap to keyframe');
+    return response.json();
+  }
+
+  async detectScenes(videoId: string, options: DetectionOptions = {}): Promise<DetectionResult> {
+    const response = await fetch(`/api/videos/${videoId}/detect`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(options),
+    });
+    if (!response.ok) throw new Error('Failed to detect scenes');
+    return response.json();
+  }
+
+  async removeSilence(
+    projectId: string,
+    options: {
+      preset?: string;
+      noise_db?: number;
+      min_silence?: number;
+      padding_before?: number;
+      padding_after?: number;
+      min_segment_length?: number;
+      apply?: 'append' | 'replace';
+    } = {}
+  ): Promise<Segment[]> {
+    const response = await fetch(`/api/projects/${projectId}/remove-silence`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(options),
+    });
+    if (!response.ok) throw new Error('Failed to remove silence');
+    return (await response.json()).segments;
+  }
+
+  async splitVideo(
+    videoId: string,
+    options:
+      | { by: 'black'; preset?: string; min_duration?: number; min_segment_length?: number; name?: string }
+      | { by: 'interval'; interval: number; name?: string }
+      | { by: 'size'; max_size_mb: number; name?: string }
+  ): Promise<Project> {
+    const response = await fetch(`/api/videos/${videoId}/split`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(options),
+    });
+    if (!response.ok) throw new Error('Failed to split video');
+    return response.json();
+  }
+
+  async getLoudness(videoId: string, start?: number, end?: number): Promise<LoudnessAnalysis> {
+    const params = new URLSearchParams();
+    if (start !== undefined) params.set('start', String(start));
+    if (end !== undefined) params.set('end', String(end));
+    const response = await fetch(`/api/videos/${videoId}/loudness?${params}`);
+    if (!response.ok) throw new Error('Failed to analyze loudness');
+    return response.json();
+  }
+
+  async getSegmentLoudness(projectId: string, segmentId: string): Promise<LoudnessAnalysis> {
+    const response = await fetch(`/api/projects/${projectId}/segments/${segmentId}/loudness`);
+    if (!response.ok) throw new Error('Failed to analyze loudness');
+    return response.json();
+  }
+
+  async extractSubtitles(
+    videoId: string,
+    streamIndex: number,
+    options: { format?: 'srt' | 'vtt'; project_id?: string; segment_ids?: string[]; separate?: boolean } = {}
+  ): Promise<ExtractedSubtitle[]> {
+    const response = await fetch(`/api/videos/${videoId}/subtitles/${streamIndex}/extract`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(options),
+    });
+    if (!response.ok) throw new Error('Failed to extract subtitles');
+    const data = await response.json();
+    return data.files;
+  }
+
   getVideoStreamUrl(videoId: string): string {
     return `/api/videos/${videoId}/stream`;
   }
 
+  // WebVTT thumbnails track for seek bar hover previews
+  getStoryboardUrl(videoId: string): string {
+    return `/api/videos/${videoId}/storyboard`;
+  }
+
+  getExportPreviewUrl(projectId: string, segmentIds: string[] = [], height = 360): string {
+    const params = new URLSearchParams({ height: String(height) });
+    if (segmentIds.length > 0) params.set('segments', segmentIds.join(','));
+    return `/api/projects/${projectId}/preview?${params}`;
+  }
+
+  async remuxVideo(videoId: string, format: 'mp4' | 'm4v' | 'mov' | 'mkv' | 'webm' | 'ts' | 'm2ts'): Promise<Operation> {
+    const response = await fetch(`/api/videos/${videoId}/remux`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ format }),
+    });
+    if (!response.ok) throw new Error('Failed to start remux');
+    return response.json();
+  }
+
+  async extractAudio(
+    videoId: string,
+    options: {
+      codec?: 'copy' | 'mp3' | 'aac' | 'flac' | 'opus' | 'pcm' | 'pcm24';
+      bitrate?: string;
+      stream?: number;
+      project_id?: string;
+      segment_ids?: string[];
+    } = {}
+  ): Promise<Operation> {
+    const response = await fetch(`/api/videos/${videoId}/extract-audio`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify(options),
+    });
+    if (!response.ok) throw new Error('Failed to start audio extraction');
+    return response.json();
+  }
+
+  async rotateVideo(videoId: string, rotation: 0 | 90 | 180 | 270): Promise<Video> {
+    const response = await fetch(`/api/videos/${videoId}/rotate`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ rotation }),
+    });
+    if (!response.ok) throw new Error('Failed to rotate video');
+    return response.json();
+  }
+
   async captureScreenshot(videoId: string, timestamp: number, quality = 2): Promise<{ filename: string; url: string }> {
     const response = await fetch(`/api/videos/${videoId}/screenshot`, {
       method: 'POST',
@@ -150,6 +594,20 @@ class ApiClient {
     if (!response.ok) throw new Error('Screenshot capture failed');
     return response.json();
   }
+
+  async captureScreenshotBurst(
+    videoId: string,
+    timestamp: number,
+    count: number,
+  ): Promise<{ files: { filename: string; url: string; timestamp: number }[] }> {
+    const response = await fetch(`/api/videos/${videoId}/screenshot`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ timestamp, count }),
+    });
+    if (!response.ok) throw new Error('Screenshot capture failed');
+    return response.json();
+  }
 }
 
 export const apiClient = new ApiClient();
@@ -7,6 +7,7 @@ interface Props {
   isOpen: boolean;
   onClose: () => void;
   onDownloadComplete?: (download: Download) => void;
+  sessionId?: string;
 }
 
 // Neobrutalist color palette
@@ -199,7 +200,7 @@ const styles: Record<string, CSSProperties> = {
   },
 };
 
-export default function DownloadModal({ isOpen, onClose, onDownloadComplete }: Props) {
+export default function DownloadModal({ isOpen, onClose, onDownloadComplete, sessionId }: Props) {
   const [url, setUrl] = useState('');
   const [downloads, setDownloads] = useState<Download[]>([]);
   const [isLoading, setIsLoading] = useState(false);
@@ -243,7 +244,7 @@ export default function DownloadModal({ isOpen, onClose, onDownloadComplete }: P
 
     setIsLoading(true);
     try {
-      const download = await apiClient.startDownload(url);
+      const download = await apiClient.startDownload(url, 'best', sessionId);
       setDownloads((prev) => [download, ...prev]);
       setUrl('');
     } catch (error: any) {
@@ -253,6 +254,15 @@ export default function DownloadModal({ isOpen, onClose, onDownloadComplete }: P
     }
   };
 
+  const handleResume = async (id: string) => {
+    try {
+      const resumed = await apiClient.resumeDownload(id);
+      setDownloads((prev) => prev.map((d) => (d.id === id ? resumed : d)));
+    } catch (error: any) {
+      alert(`Failed to resume download: ${error.message}`);
+    }
+  };
+
   const handleClearAll = async () => {
     if (!confirm('Delete all download history? Downloaded videos will be removed.')) return;
 
@@ -271,6 +281,7 @@ export default function DownloadModal({ isOpen, onClose, onDownloadComplete }: P
       case 'completed':
         return <IoMdCheckmark style={{ ...iconStyle, color: colors.accent1 }} />;
       case 'failed':
+      case 'interrupted':
         return <IoMdWarning style={{ ...iconStyle, color: colors.danger }} />;
       case 'downloading':
         return <IoMdDownload style={{ ...iconStyle, color: colors.accent4 }} />;
@@ -439,7 +450,11 @@ export default function DownloadModal({ isOpen, onClose, onDownloadComplete }: P
                     {download.status === 'downloading' && (
                       <div>
                         <div style={styles.progressText}>
-                          <span>Downloading...</span>
+                          <span>
+                            Downloading...
+                            {download.speed ? ` ${(download.speed / 1048576).toFixed(1)} MiB/s` : ''}
+                            {download.eta ? `, ${Math.floor(download.eta / 60)}:${String(Math.round(download.eta % 60)).padStart(2, '0')} left` : ''}
+                          </span>
                           <span>{download.progress.toFixed(1)}%</span>
                         </div>
                         <div style={styles.progressBar}>
@@ -453,15 +468,53 @@ export default function DownloadModal({ isOpen, onClose, onDownloadComplete }: P
                       </div>
                     )}
 
+                    {download.status === 'scheduled' && download.start_at && (
+                      <div style={styles.progressText}>
+                        <span>{download.cron ? 'Next run' : 'Starts'} {new Date(download.start_at).toLocaleString()}</span>
+                      </div>
+                    )}
+
+                    {download.status === 'retrying' && (
+                      <div style={styles.progressText}>
+                        <span>Retrying (attempt {(download.attempts?.length ?? 0) + 1}): {download.error}</span>
+                      </div>
+                    )}
+
+                    {download.status === 'queued' && (
+                      <div style={styles.progressText}>
+                        <span>Queued{download.queue_position ? ` (#${download.queue_position})` : ''}</span>
+                      </div>
+                    )}
+
                     {download.status === 'completed' && (
                       <div style={styles.successText}>Download Complete</div>
                     )}
 
-                    {download.status === 'failed' && (
+                    {(download.status === 'failed' || download.status === 'interrupted') && (
                       <div style={styles.errorText}>
                         Error: {download.error || 'Download failed'}
                       </div>
                     )}
+
+                    {(download.status === 'failed' || download.status === 'interrupted') && !download.parent_id && !download.cron && (
+                      <button
+                        onClick={() => handleResume(download.id)}
+                        style={{
+                          background: 'transparent',
+                          border: `1px solid ${colors.accent4}`,
+                          color: colors.accent4,
+                          cursor: 'pointer',
+                          fontSize: '10px',
+                          fontWeight: '700',
+                          textTransform: 'uppercase' as const,
+                          letterSpacing: '1px',
+                          padding: '4px 10px',
+                          marginTop: '6px',
+                        }}
+                      >
+                        Resume
+                      </button>
+                    )}
                   </div>
                 </div>
               </div>
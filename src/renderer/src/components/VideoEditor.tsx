@@ -194,13 +194,13 @@ export default function VideoEditor({ onClose, initialVideoId }: Props) {
 
   // Poll export
   useEffect(() => {
-    if (!currentOperation || ['completed', 'failed'].includes(currentOperation.status)) return;
+    if (!currentOperation || ['completed', 'completed_with_warnings', 'failed', 'interrupted'].includes(currentOperation.status)) return;
     const poll = setInterval(async () => {
       try {
         const op = await apiClient.getOperation(currentOperation.id);
         setCurrentOperation(op);
         setExportProgress(op.progress);
-        if (['completed', 'failed'].includes(op.status)) {
+        if (['completed', 'completed_with_warnings', 'failed', 'interrupted'].includes(op.status)) {
           clearInterval(poll);
           setIsExporting(false);
         }
@@ -772,8 +772,8 @@ export default function VideoEditor({ onClose, initialVideoId }: Props) {
               {/* Export */}
               {segments.length > 0 && (
                 <div style={{ display: 'flex', gap: '10px' }}>
-                  {currentOperation?.status === 'completed' && (
-                    <button onClick={handleDownload} style={btn(colors.primary)}>
+                  {(currentOperation?.status === 'completed' || currentOperation?.status === 'completed_with_warnings') && (
+                    <button onClick={handleDownload} style={btn(colors.primary)} title={currentOperation.warnings?.join('\n')}>
                       <IoMdDownload size={20} /> Download Video
                     </button>
                   )}